@@ -4,11 +4,16 @@ import (
 	"bufio"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io"
+	"maps"
+	"net/mail"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -20,14 +25,19 @@ import (
 	"github.com/sevlyar/go-daemon"
 	"golang.org/x/term"
 
+	"github.com/roelfdiedericks/goclaw/internal/audit"
 	"github.com/roelfdiedericks/goclaw/internal/auth"
 	"github.com/roelfdiedericks/goclaw/internal/browser"
 	"github.com/roelfdiedericks/goclaw/internal/bus"
 	"github.com/roelfdiedericks/goclaw/internal/sandbox"
 	"github.com/roelfdiedericks/goclaw/internal/sandbox/bwrap"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox/egress"
 	"github.com/roelfdiedericks/goclaw/internal/channels"
 	goclawhttp "github.com/roelfdiedericks/goclaw/internal/channels/http"
 	httpconfig "github.com/roelfdiedericks/goclaw/internal/channels/http/config"
+	"github.com/roelfdiedericks/goclaw/internal/channels/bridge"
+	"github.com/roelfdiedericks/goclaw/internal/channels/discord"
+	"github.com/roelfdiedericks/goclaw/internal/channels/matrix"
 	"github.com/roelfdiedericks/goclaw/internal/channels/telegram"
 	telegramconfig "github.com/roelfdiedericks/goclaw/internal/channels/telegram/config"
 	"github.com/roelfdiedericks/goclaw/internal/channels/tui"
@@ -38,11 +48,13 @@ import (
 	"github.com/roelfdiedericks/goclaw/internal/embeddings"
 	"github.com/roelfdiedericks/goclaw/internal/gateway"
 	"github.com/roelfdiedericks/goclaw/internal/hass"
+	"github.com/roelfdiedericks/goclaw/internal/history"
 	"github.com/roelfdiedericks/goclaw/internal/llm"
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
 	"github.com/roelfdiedericks/goclaw/internal/media"
 	"github.com/roelfdiedericks/goclaw/internal/metrics"
 	"github.com/roelfdiedericks/goclaw/internal/paths"
+	"github.com/roelfdiedericks/goclaw/internal/retrieval"
 	"github.com/roelfdiedericks/goclaw/internal/session"
 	"github.com/roelfdiedericks/goclaw/internal/setup"
 	"github.com/roelfdiedericks/goclaw/internal/skills"
@@ -52,6 +64,9 @@ import (
 	toolcron "github.com/roelfdiedericks/goclaw/internal/tools/cron"
 	"github.com/roelfdiedericks/goclaw/internal/tools/edit"
 	"github.com/roelfdiedericks/goclaw/internal/tools/exec"
+	"github.com/roelfdiedericks/goclaw/internal/tools/script"
+	"github.com/roelfdiedericks/goclaw/internal/tools/tooloutput"
+	"github.com/roelfdiedericks/goclaw/internal/tools/workspacehistory"
 	toolhass "github.com/roelfdiedericks/goclaw/internal/tools/hass"
 	"github.com/roelfdiedericks/goclaw/internal/tools/jq"
 	"github.com/roelfdiedericks/goclaw/internal/tools/memoryget"
@@ -119,6 +134,7 @@ type CLI struct {
 	Version    VersionCmd    `cmd:"" help:"Show version"`
 	Update     UpdateCmd     `cmd:"" help:"Check for and install updates"`
 	Cron       CronCmd       `cmd:"" help:"Manage cron jobs"`
+	Audit      AuditCmd      `cmd:"" help:"Query and verify the audit log"`
 	User       UserCmd       `cmd:"" help:"Manage users"`
 	Whatsapp   WhatsAppCmd   `cmd:"" help:"Manage WhatsApp connection"`
 	Browser    BrowserCmd    `cmd:"" help:"Manage browser (download, profiles, setup)"`
@@ -128,6 +144,8 @@ type CLI struct {
 	Onboard    OnboardCmd    `cmd:"" help:"Run onboarding wizard"`
 	Cfg        ConfigCmd     `cmd:"config" help:"View configuration"`
 	TUI        TUICmd        `cmd:"tui" help:"Run gateway with interactive TUI"`
+
+	EgressRelay EgressRelayCmd `cmd:"" hidden:"" help:"Relay a sandbox's loopback proxy port to the egress proxy socket"`
 }
 
 // GatewayCmd runs gateway in foreground
@@ -332,6 +350,19 @@ func (v *VersionCmd) Run(ctx *Context) error {
 	return nil
 }
 
+// EgressRelayCmd runs inside a network-less exec sandbox: it serves the
+// egress proxy socket on loopback and runs the sandboxed command.
+type EgressRelayCmd struct {
+	Socket  string   `help:"Egress proxy unix socket" required:""`
+	Listen  string   `help:"Loopback address to listen on" default:"127.0.0.1:3128"`
+	Command []string `arg:"" help:"Command to run (after --)"`
+}
+
+func (e *EgressRelayCmd) Run(ctx *Context) error {
+	os.Exit(egress.Relay(e.Socket, e.Listen, e.Command))
+	return nil
+}
+
 // UpdateCmd checks for and installs updates
 type UpdateCmd struct {
 	Check     bool   `help:"Check for updates without installing"`
@@ -445,6 +476,7 @@ func (c *CronAddCmd) Run(ctx *Context) error {
 	if err := store.AddJob(job); err != nil {
 		return fmt.Errorf("failed to add job: %w", err)
 	}
+	recordCronCLI("add", job)
 
 	fmt.Printf("Job created successfully.\n")
 	fmt.Printf("ID: %s\n", job.ID)
@@ -488,6 +520,7 @@ func (c *CronEditCmd) Run(ctx *Context) error {
 	if err := store.UpdateJob(job); err != nil {
 		return fmt.Errorf("failed to update job: %w", err)
 	}
+	recordCronCLI("edit", job)
 
 	fmt.Printf("Job updated: %s\n", job.Name)
 	return nil
@@ -513,6 +546,7 @@ func (c *CronRemoveCmd) Run(ctx *Context) error {
 	if err := store.DeleteJob(c.ID); err != nil {
 		return fmt.Errorf("failed to remove job: %w", err)
 	}
+	recordCronCLI("remove", job)
 
 	fmt.Printf("Job '%s' removed.\n", name)
 	return nil
@@ -590,12 +624,182 @@ func (c *CronKillCmd) Run(ctx *Context) error {
 	if err := store.UpdateJob(job); err != nil {
 		return fmt.Errorf("failed to update job: %w", err)
 	}
+	recordCronCLI("kill", job)
 
 	fmt.Printf("Cleared running state for job '%s' (was running for %s).\n", job.Name, runningFor.Round(time.Second))
 	fmt.Printf("Note: If the job is actually still executing, it will continue until completion or timeout.\n")
 	return nil
 }
 
+// recordCronCLI writes a cron change made from the CLI to the audit log.
+// Changes made by the agent are recorded by the tool registry.
+func recordCronCLI(action string, job *cron.CronJob) {
+	recordCLIAudit(audit.Event{
+		Type:    "cron." + action,
+		Purpose: "goclaw cron " + action,
+		Inputs: map[string]any{
+			"id":       job.ID,
+			"name":     job.Name,
+			"enabled":  job.Enabled,
+			"schedule": formatCronSchedule(&job.Schedule),
+			"message":  job.Payload.Message,
+		},
+	})
+}
+
+// recordCLIAudit appends an event to the audit log from a CLI command.
+// The gateway may be running; appends are serialized with a file lock.
+func recordCLIAudit(e audit.Event) {
+	dir, err := audit.DefaultDir()
+	if err != nil {
+		return
+	}
+	l, err := audit.Open(filepath.Join(dir, audit.FileName))
+	if err != nil {
+		L_warn("audit: failed to open log", "error", err)
+		return
+	}
+	defer l.Close() //nolint:errcheck // best effort
+	e.Actor = "cli"
+	if err := l.Append(e); err != nil {
+		L_warn("audit: failed to write event", "type", e.Type, "error", err)
+	}
+}
+
+// AuditCmd queries and verifies the audit log
+type AuditCmd struct {
+	Query  AuditQueryCmd  `cmd:"" default:"withargs" help:"Show audit events"`
+	Verify AuditVerifyCmd `cmd:"" help:"Verify the audit log hash chain"`
+}
+
+// AuditQueryCmd shows audit events
+type AuditQueryCmd struct {
+	Type    string `help:"Event type or type prefix (e.g. hass, tool.exec, cron)"`
+	Actor   string `help:"Actor user ID (or cli, system)"`
+	Session string `help:"Session key"`
+	Since   string `help:"Only events after this time (duration like 12h, or 2006-01-02, or RFC3339)"`
+	Until   string `help:"Only events before this time (same formats as --since)"`
+	Grep    string `help:"Case-insensitive text match anywhere in the event"`
+	Limit   int    `help:"Show the newest N matching events (0 = all)" default:"50"`
+	JSON    bool   `help:"Print events as JSON lines" name:"json"`
+}
+
+func (a *AuditQueryCmd) Run(ctx *Context) error {
+	filter := audit.Filter{
+		Type:    a.Type,
+		Actor:   a.Actor,
+		Session: a.Session,
+		Text:    a.Grep,
+		Limit:   a.Limit,
+	}
+	var err error
+	if filter.Since, err = parseAuditTime(a.Since); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.Until, err = parseAuditTime(a.Until); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	path, err := auditLogPath()
+	if err != nil {
+		return err
+	}
+	events, err := audit.Query(path, filter)
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	if len(events) == 0 {
+		fmt.Println("No matching audit events.")
+		return nil
+	}
+
+	for _, e := range events {
+		if a.JSON {
+			data, err := json.Marshal(e)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			continue
+		}
+		fmt.Printf("#%d %s  %s  actor=%s", e.Seq, e.Time.Local().Format("2006-01-02 15:04:05"), e.Type, e.Actor)
+		if e.Session != "" {
+			fmt.Printf(" session=%s", e.Session)
+		}
+		fmt.Println()
+		if e.Purpose != "" {
+			fmt.Printf("    purpose: %s\n", e.Purpose)
+		}
+		if len(e.Inputs) > 0 {
+			data, _ := json.Marshal(e.Inputs)
+			fmt.Printf("    inputs:  %s\n", truncateAuditText(string(data), 300))
+		}
+		if e.Error != "" {
+			fmt.Printf("    error:   %s\n", truncateAuditText(e.Error, 300))
+		} else if e.Result != "" {
+			fmt.Printf("    result:  %s\n", truncateAuditText(e.Result, 300))
+		}
+	}
+	return nil
+}
+
+// AuditVerifyCmd verifies the audit log hash chain
+type AuditVerifyCmd struct{}
+
+func (a *AuditVerifyCmd) Run(ctx *Context) error {
+	path, err := auditLogPath()
+	if err != nil {
+		return err
+	}
+	res, err := audit.Verify(path)
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	if !res.OK {
+		fmt.Printf("Audit log TAMPERED: %s\n", path)
+		fmt.Printf("First problem at line %d: %s\n", res.BreakAt, res.Problem)
+		fmt.Printf("%d records verified before the break.\n", res.Records)
+		return fmt.Errorf("audit log verification failed")
+	}
+	fmt.Printf("Audit log OK: %s\n", path)
+	fmt.Printf("Records: %d\n", res.Records)
+	if res.LastHash != "" {
+		fmt.Printf("Head: #%d %s\n", res.LastSeq, res.LastHash)
+	}
+	return nil
+}
+
+func truncateAuditText(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func auditLogPath() (string, error) {
+	dir, err := audit.DefaultDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, audit.FileName), nil
+}
+
+// parseAuditTime parses a relative duration (12h, 7d), a date or an RFC3339
+// time. Empty input yields the zero time.
+func parseAuditTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := cron.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
 func buildScheduleFromFlags(every, at, cronExpr, tz string) (cron.Schedule, error) {
 	if every != "" {
 		dur, err := cron.ParseDuration(every)
@@ -653,7 +857,13 @@ type UserCmd struct {
 	Delete      UserDeleteCmd   `cmd:"" help:"Delete a user"`
 	SetTelegram UserTelegramCmd `cmd:"set-telegram" help:"Set Telegram ID"`
 	SetWhatsapp UserWhatsAppCmd `cmd:"" help:"Set WhatsApp ID"`
+	SetMatrix   UserMatrixCmd   `cmd:"set-matrix" help:"Set Matrix user ID"`
+	SetDiscord  UserDiscordCmd  `cmd:"set-discord" help:"Set Discord user ID"`
+	SetEmail    UserEmailCmd    `cmd:"set-email" help:"Set email address"`
+	SetIdentity UserIdentityCmd `cmd:"set-identity" help:"Set a user's ID on a bridge channel"`
 	SetPassword UserPasswordCmd `cmd:"set-password" help:"Set HTTP password"`
+	SetCert     UserCertCmd     `cmd:"set-cert" help:"Map a TLS client certificate subject to a user"`
+	Token       UserTokenCmd    `cmd:"" help:"Manage scoped API tokens"`
 }
 
 // UserAddCmd adds a new user
@@ -720,9 +930,24 @@ func (u *UserListCmd) Run(ctx *Context) error {
 		if entry.WhatsAppID != "" {
 			fmt.Printf("  WhatsApp: %s\n", entry.WhatsAppID)
 		}
+		if entry.MatrixID != "" {
+			fmt.Printf("  Matrix: %s\n", entry.MatrixID)
+		}
+		if entry.DiscordID != "" {
+			fmt.Printf("  Discord: %s\n", entry.DiscordID)
+		}
+		if entry.Email != "" {
+			fmt.Printf("  Email: %s\n", entry.Email)
+		}
+		for _, provider := range slices.Sorted(maps.Keys(entry.Identities)) {
+			fmt.Printf("  %s: %s\n", provider, entry.Identities[provider])
+		}
 		if entry.HTTPPasswordHash != "" {
 			fmt.Printf("  HTTP: configured\n")
 		}
+		for _, subject := range entry.CertSubjects {
+			fmt.Printf("  Certificate: %s\n", subject)
+		}
 		fmt.Println()
 	}
 	return nil
@@ -784,6 +1009,143 @@ func (u *UserWhatsAppCmd) Run(ctx *Context) error {
 	return nil
 }
 
+// UserMatrixCmd sets a user's Matrix ID
+type UserMatrixCmd struct {
+	Username string `arg:"" help:"Username"`
+	MatrixID string `arg:"" help:"Matrix user ID (e.g. @alice:example.org)"`
+}
+
+func (u *UserMatrixCmd) Run(ctx *Context) error {
+	if !strings.HasPrefix(u.MatrixID, "@") || !strings.Contains(u.MatrixID, ":") {
+		return fmt.Errorf("invalid Matrix ID %q: expected @user:server", u.MatrixID)
+	}
+
+	users, err := user.LoadUsers()
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+
+	entry, exists := users[u.Username]
+	if !exists {
+		return fmt.Errorf("user %q not found", u.Username)
+	}
+
+	entry.MatrixID = u.MatrixID
+
+	path := user.GetUsersFilePath()
+	if err := user.SaveUsers(users, path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Matrix ID set for user %q.\n", u.Username)
+	return nil
+}
+
+// UserDiscordCmd sets a user's Discord ID
+type UserDiscordCmd struct {
+	Username  string `arg:"" help:"Username"`
+	DiscordID string `arg:"" help:"Discord user ID (numeric, from Copy User ID in developer mode)"`
+}
+
+func (u *UserDiscordCmd) Run(ctx *Context) error {
+	if _, err := strconv.ParseUint(u.DiscordID, 10, 64); err != nil {
+		return fmt.Errorf("invalid Discord ID %q: expected a numeric user ID", u.DiscordID)
+	}
+
+	users, err := user.LoadUsers()
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+
+	entry, exists := users[u.Username]
+	if !exists {
+		return fmt.Errorf("user %q not found", u.Username)
+	}
+
+	entry.DiscordID = u.DiscordID
+
+	path := user.GetUsersFilePath()
+	if err := user.SaveUsers(users, path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Discord ID set for user %q.\n", u.Username)
+	return nil
+}
+
+// UserEmailCmd sets a user's email address
+type UserEmailCmd struct {
+	Username string `arg:"" help:"Username"`
+	Email    string `arg:"" help:"Email address the user sends mail from"`
+}
+
+func (u *UserEmailCmd) Run(ctx *Context) error {
+	addr, err := mail.ParseAddress(u.Email)
+	if err != nil {
+		return fmt.Errorf("invalid email address %q: %w", u.Email, err)
+	}
+
+	users, err := user.LoadUsers()
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+
+	entry, exists := users[u.Username]
+	if !exists {
+		return fmt.Errorf("user %q not found", u.Username)
+	}
+
+	entry.Email = addr.Address
+
+	path := user.GetUsersFilePath()
+	if err := user.SaveUsers(users, path); err != nil {
+		return err
+	}
+
+	fmt.Printf("Email set for user %q.\n", u.Username)
+	return nil
+}
+
+// UserIdentityCmd sets a user's ID on a bridge channel
+type UserIdentityCmd struct {
+	Username string `arg:"" help:"Username"`
+	Bridge   string `arg:"" help:"Bridge channel name (e.g. signal)"`
+	ID       string `arg:"" optional:"" help:"User ID as the bridge reports it (omit to remove)"`
+}
+
+func (u *UserIdentityCmd) Run(ctx *Context) error {
+	users, err := user.LoadUsers()
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+
+	entry, exists := users[u.Username]
+	if !exists {
+		return fmt.Errorf("user %q not found", u.Username)
+	}
+
+	if u.ID == "" {
+		delete(entry.Identities, u.Bridge)
+	} else {
+		if entry.Identities == nil {
+			entry.Identities = make(map[string]string)
+		}
+		entry.Identities[u.Bridge] = u.ID
+	}
+
+	path := user.GetUsersFilePath()
+	if err := user.SaveUsers(users, path); err != nil {
+		return err
+	}
+
+	if u.ID == "" {
+		fmt.Printf("%s identity removed from user %q.\n", u.Bridge, u.Username)
+	} else {
+		fmt.Printf("%s identity set for user %q.\n", u.Bridge, u.Username)
+	}
+	return nil
+}
+
 // UserPasswordCmd sets a user's HTTP password
 type UserPasswordCmd struct {
 	Username string `arg:"" help:"Username"`
@@ -843,6 +1205,203 @@ func (u *UserPasswordCmd) Run(ctx *Context) error {
 	return nil
 }
 
+// UserCertCmd maps TLS client certificate subjects to a user
+type UserCertCmd struct {
+	Username string `arg:"" help:"Username"`
+	Subject  string `arg:"" help:"Certificate subject: 'CN=alice' or a full DN like 'CN=alice,O=Home'"`
+	Remove   bool   `help:"Remove the subject instead of adding it"`
+}
+
+func (u *UserCertCmd) Run(ctx *Context) error {
+	users, err := user.LoadUsers()
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+
+	entry, exists := users[u.Username]
+	if !exists {
+		return fmt.Errorf("user %q not found", u.Username)
+	}
+
+	subject := strings.TrimSpace(u.Subject)
+	if !strings.Contains(subject, "=") {
+		return fmt.Errorf("subject %q should look like 'CN=%s'", subject, subject)
+	}
+
+	idx := slices.Index(entry.CertSubjects, subject)
+	if u.Remove {
+		if idx < 0 {
+			return fmt.Errorf("user %q has no certificate subject %q", u.Username, subject)
+		}
+		entry.CertSubjects = slices.Delete(entry.CertSubjects, idx, idx+1)
+	} else {
+		if idx >= 0 {
+			fmt.Printf("Certificate subject %q already set for user %q.\n", subject, u.Username)
+			return nil
+		}
+		for other, e := range users {
+			if other != u.Username && slices.Contains(e.CertSubjects, subject) {
+				return fmt.Errorf("certificate subject %q is already mapped to user %q", subject, other)
+			}
+		}
+		entry.CertSubjects = append(entry.CertSubjects, subject)
+	}
+
+	path := user.GetUsersFilePath()
+	if err := user.SaveUsers(users, path); err != nil {
+		return err
+	}
+
+	if u.Remove {
+		fmt.Printf("Certificate subject %q removed from user %q.\n", subject, u.Username)
+	} else {
+		fmt.Printf("Certificate subject %q set for user %q. Requires channels.http.tls with a clientCAFile.\n", subject, u.Username)
+	}
+	return nil
+}
+
+// UserTokenCmd manages a user's API tokens
+type UserTokenCmd struct {
+	Create UserTokenCreateCmd `cmd:"" help:"Create an API token (shown once)"`
+	List   UserTokenListCmd   `cmd:"" help:"List API tokens"`
+	Revoke UserTokenRevokeCmd `cmd:"" help:"Revoke an API token"`
+}
+
+// UserTokenCreateCmd creates an API token
+type UserTokenCreateCmd struct {
+	Username string `arg:"" help:"Username"`
+	Name     string `help:"Token name (what it's for)" required:""`
+	Scopes   string `help:"Comma-separated scopes: chat, read-history, supervise, admin-config, metrics, webhook (or 'all')" default:"chat"`
+	Expires  string `help:"Lifetime, e.g. 90d or 12h (empty = never expires)" default:"90d"`
+}
+
+func (c *UserTokenCreateCmd) Run(ctx *Context) error {
+	scopes, err := user.ParseScopes(c.Scopes)
+	if err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if c.Expires != "" {
+		if ttl, err = cron.ParseDuration(c.Expires); err != nil {
+			return fmt.Errorf("invalid --expires: %w", err)
+		}
+	}
+
+	users, err := user.LoadUsers()
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+	entry, exists := users[c.Username]
+	if !exists {
+		return fmt.Errorf("user %q not found", c.Username)
+	}
+
+	plain, token, err := entry.AddAPIToken(c.Name, scopes, ttl)
+	if err != nil {
+		return err
+	}
+	if err := user.SaveUsers(users, user.GetUsersFilePath()); err != nil {
+		return err
+	}
+	recordCLIAudit(audit.Event{
+		Type:    "token.create",
+		Purpose: "goclaw user token create",
+		Inputs:  map[string]any{"username": c.Username, "id": token.ID, "name": token.Name, "scopes": token.Scopes},
+	})
+
+	fmt.Printf("Token %q created for %s (id %s, scopes: %s).\n", token.Name, c.Username, token.ID, strings.Join(token.Scopes, ","))
+	if token.ExpiresAt != nil {
+		fmt.Printf("Expires: %s\n", token.ExpiresAt.Local().Format("2006-01-02 15:04"))
+	}
+	fmt.Printf("\n%s\n\nCopy it now - it is not shown again. Use it as 'Authorization: Bearer <token>'.\n", plain)
+	return nil
+}
+
+// UserTokenListCmd lists API tokens
+type UserTokenListCmd struct {
+	Username string `arg:"" optional:"" help:"Username (omit for all users)"`
+}
+
+func (c *UserTokenListCmd) Run(ctx *Context) error {
+	users, err := user.LoadUsers()
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+	if c.Username != "" {
+		if _, exists := users[c.Username]; !exists {
+			return fmt.Errorf("user %q not found", c.Username)
+		}
+	}
+
+	names := make([]string, 0, len(users))
+	for username := range users {
+		if c.Username == "" || username == c.Username {
+			names = append(names, username)
+		}
+	}
+	sort.Strings(names)
+
+	usage := user.LoadTokenUsage(user.TokenUsagePath())
+	now := time.Now()
+	count := 0
+	for _, username := range names {
+		for _, t := range users[username].APITokens {
+			expires := "never"
+			if t.ExpiresAt != nil {
+				expires = t.ExpiresAt.Local().Format("2006-01-02")
+				if t.Expired(now) {
+					expires += " (expired)"
+				}
+			}
+			lastUsed := "never"
+			if at := usage.LastUsed(t); at != nil {
+				lastUsed = at.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%s  %-12s %-20s scopes=%s expires=%s last-used=%s\n",
+				t.ID, username, t.Name, strings.Join(t.Scopes, ","), expires, lastUsed)
+			count++
+		}
+	}
+	if count == 0 {
+		fmt.Println("No API tokens.")
+	}
+	return nil
+}
+
+// UserTokenRevokeCmd revokes an API token
+type UserTokenRevokeCmd struct {
+	Username string `arg:"" help:"Username"`
+	Token    string `arg:"" help:"Token ID or name"`
+}
+
+func (c *UserTokenRevokeCmd) Run(ctx *Context) error {
+	users, err := user.LoadUsers()
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+	entry, exists := users[c.Username]
+	if !exists {
+		return fmt.Errorf("user %q not found", c.Username)
+	}
+
+	token, err := entry.RevokeAPIToken(c.Token)
+	if err != nil {
+		return err
+	}
+	if err := user.SaveUsers(users, user.GetUsersFilePath()); err != nil {
+		return err
+	}
+	recordCLIAudit(audit.Event{
+		Type:    "token.revoke",
+		Purpose: "goclaw user token revoke",
+		Inputs:  map[string]any{"username": c.Username, "id": token.ID, "name": token.Name},
+	})
+
+	fmt.Printf("Token %q (%s) revoked for %s.\n", token.Name, token.ID, c.Username)
+	fmt.Println("A running gateway picks this up when users.json is reloaded (restart, or any change from the web Users page).")
+	return nil
+}
+
 // UserDeleteCmd deletes a user
 type UserDeleteCmd struct {
 	Username string `arg:"" help:"Username to delete"`
@@ -2126,8 +2685,24 @@ func runGateway(ctx *Context, useTUI bool, devMode bool) error {
 	llm.SetGlobalRegistry(llmRegistry)
 	L_info("LLM registry created", "providers", len(cfg.LLM.Providers))
 
+	// Audit log for privileged actions
+	if auditDir, err := audit.DefaultDir(); err != nil {
+		L_warn("audit: log disabled", "error", err)
+	} else if auditLog, err := audit.InitLog(auditDir); err != nil {
+		L_warn("audit: log disabled", "error", err)
+	} else {
+		defer auditLog.Close() //nolint:errcheck // shutdown cleanup
+	}
+
 	// Initialize browser manager for web_fetch fallback and browser tool
 	if cfg.Tools.Browser.Enabled {
+		_, browserProfile := cfg.Sandbox.Profile(sandbox.ProfileBrowser)
+		var browserProxy string
+		if browserProfile.Egress.Enabled {
+			if browserProxy, err = egress.ProxyURL(sandbox.ProfileBrowser, browserProfile.Egress); err != nil {
+				return fmt.Errorf("browser egress proxy: %w", err)
+			}
+		}
 		browserCfg := browser.ToolsConfigAdapter{
 			Dir:            cfg.Tools.Browser.Dir,
 			AutoDownload:   cfg.Tools.Browser.AutoDownload,
@@ -2146,6 +2721,8 @@ func runGateway(ctx *Context, useTUI bool, devMode bool) error {
 			BubblewrapGPU:     cfg.Tools.Browser.Bubblewrap.GPU,
 			ExtraRoBind:       cfg.Tools.Browser.Bubblewrap.ExtraRoBind,
 			ExtraBind:         cfg.Tools.Browser.Bubblewrap.ExtraBind,
+			BubblewrapLimits:  browserProfile.Limits,
+			EgressProxy:       browserProxy,
 		}.ToConfig()
 
 		browserMgr, err := browser.InitManager(browserCfg)
@@ -2214,6 +2791,7 @@ func runGateway(ctx *Context, useTUI bool, devMode bool) error {
 	auth.RegisterCommands()
 	gateway.RegisterCommands()
 	transcript.RegisterCommands()
+	retrieval.RegisterCommands()
 	llm.RegisterCommands()
 	stt.RegisterCommands()
 	L_debug("config commands registered")
@@ -2246,6 +2824,11 @@ func runGateway(ctx *Context, useTUI bool, devMode bool) error {
 		if transcriptMgr != nil {
 			transcriptMgr.Stop()
 		}
+		// Close persistent exec shells (and anything still running in them)
+		if shells := exec.GetShellManager(); shells != nil {
+			shells.CloseAll()
+		}
+		egress.CloseAll()
 		metrics.GetInstance().Close() //nolint:errcheck // shutdown cleanup
 		gw.Shutdown()
 	}()
@@ -2265,6 +2848,20 @@ func runGateway(ctx *Context, useTUI bool, devMode bool) error {
 	bus.SubscribeEvent("channels.telegram.stopped", func(event bus.Event) {
 		messageTool.RemoveChannel("telegram")
 	})
+	bus.SubscribeEvent("channels.telegram.named.started", func(event bus.Event) {
+		name, _ := event.Data.(string)
+		if bot := chanMgr.GetTelegramNamed()[name]; bot != nil {
+			if mediaStore := gw.MediaStore(); mediaStore != nil {
+				adapter := telegram.NewMessageChannelAdapter(bot, mediaStore.BaseDir())
+				messageTool.SetChannel(bot.Name(), adapter)
+			}
+		}
+	})
+	bus.SubscribeEvent("channels.telegram.named.stopped", func(event bus.Event) {
+		if name, ok := event.Data.(string); ok {
+			messageTool.RemoveChannel("telegram:" + name)
+		}
+	})
 	bus.SubscribeEvent("channels.whatsapp.started", func(event bus.Event) {
 		if bot := chanMgr.GetWhatsApp(); bot != nil {
 			if mediaStore := gw.MediaStore(); mediaStore != nil {
@@ -2276,6 +2873,42 @@ func runGateway(ctx *Context, useTUI bool, devMode bool) error {
 	bus.SubscribeEvent("channels.whatsapp.stopped", func(event bus.Event) {
 		messageTool.RemoveChannel("whatsapp")
 	})
+	bus.SubscribeEvent("channels.matrix.started", func(event bus.Event) {
+		if bot := chanMgr.GetMatrix(); bot != nil {
+			if mediaStore := gw.MediaStore(); mediaStore != nil {
+				adapter := matrix.NewMessageChannelAdapter(bot, mediaStore.BaseDir())
+				messageTool.SetChannel("matrix", adapter)
+			}
+		}
+	})
+	bus.SubscribeEvent("channels.matrix.stopped", func(event bus.Event) {
+		messageTool.RemoveChannel("matrix")
+	})
+	bus.SubscribeEvent("channels.discord.started", func(event bus.Event) {
+		if bot := chanMgr.GetDiscord(); bot != nil {
+			if mediaStore := gw.MediaStore(); mediaStore != nil {
+				adapter := discord.NewMessageChannelAdapter(bot, mediaStore.BaseDir())
+				messageTool.SetChannel("discord", adapter)
+			}
+		}
+	})
+	bus.SubscribeEvent("channels.discord.stopped", func(event bus.Event) {
+		messageTool.RemoveChannel("discord")
+	})
+	bus.SubscribeEvent("channels.bridge.started", func(event bus.Event) {
+		name, _ := event.Data.(string)
+		if bot := chanMgr.GetBridges()[name]; bot != nil {
+			if mediaStore := gw.MediaStore(); mediaStore != nil {
+				adapter := bridge.NewMessageChannelAdapter(bot, mediaStore.BaseDir())
+				messageTool.SetChannel(bot.Name(), adapter)
+			}
+		}
+	})
+	bus.SubscribeEvent("channels.bridge.stopped", func(event bus.Event) {
+		if name, ok := event.Data.(string); ok {
+			messageTool.RemoveChannel(name)
+		}
+	})
 	bus.SubscribeEvent("channels.http.started", func(event bus.Event) {
 		if srv := chanMgr.GetHTTP(); srv != nil {
 			adapter := goclawhttp.NewMessageChannelAdapter(srv.Channel(), "/api/media")
@@ -2529,6 +3162,23 @@ func registerTools(reg *tools.Registry, cfg *config.Config, gw *gateway.Gateway,
 	reg.Register(write.NewTool(cfg.Gateway.WorkingDir))
 	reg.Register(edit.NewTool(cfg.Gateway.WorkingDir))
 
+	// Workspace change history (undo for write/edit)
+	if cfg.Tools.History.Enabled {
+		historyDir := cfg.Tools.History.Dir
+		var err error
+		if historyDir == "" {
+			historyDir, err = paths.DataPath("history")
+		}
+		if err == nil {
+			_, err = history.InitStore(historyDir)
+		}
+		if err != nil {
+			L_warn("tools: workspace history disabled", "error", err)
+		} else {
+			reg.Register(workspacehistory.NewTool(cfg.Gateway.WorkingDir))
+		}
+	}
+
 	// Exec tool
 	execTimeout := 30 * time.Minute
 	if cfg.Tools.Exec.Timeout > 0 {
@@ -2548,6 +3198,12 @@ func registerTools(reg *tools.Registry, cfg *config.Config, gw *gateway.Gateway,
 		},
 	})
 	reg.Register(exec.NewToolWithRunner(execRunner))
+	if cfg.Tools.Exec.Sessions.Enabled {
+		exec.InitShellManager(execRunner, exec.ShellConfig{
+			IdleTimeout: time.Duration(cfg.Tools.Exec.Sessions.IdleTimeout) * time.Minute,
+			MaxShells:   cfg.Tools.Exec.Sessions.MaxSessions,
+		})
+	}
 
 	// JQ tool (shares exec runner for sandbox)
 	reg.Register(jq.NewTool(cfg.Gateway.WorkingDir, execRunner))
@@ -2569,7 +3225,7 @@ func registerTools(reg *tools.Registry, cfg *config.Config, gw *gateway.Gateway,
 	}))
 
 	// Cron tool
-	reg.Register(toolcron.NewTool())
+	reg.Register(toolcron.NewTool(&cfg.Agents))
 
 	// GoClaw update tool
 	reg.Register(toolupdate.NewTool(version))
@@ -2585,8 +3241,8 @@ func registerTools(reg *tools.Registry, cfg *config.Config, gw *gateway.Gateway,
 
 	// Memory tools
 	if memMgr := gw.MemoryManager(); memMgr != nil {
-		reg.Register(memorysearch.NewTool(memMgr))
-		reg.Register(memoryget.NewTool(memMgr))
+		reg.Register(memorysearch.NewToolWithResolver(memMgr, gw.MemoryManagerFor))
+		reg.Register(memoryget.NewToolWithResolver(memMgr, gw.MemoryManagerFor))
 	}
 
 	// Memory graph tools
@@ -2664,10 +3320,50 @@ func registerTools(reg *tools.Registry, cfg *config.Config, gw *gateway.Gateway,
 				transcriptMgr.Start()
 				transcriptMgr.RegisterOperationalCommands()
 				reg.Register(tooltranscript.NewTool(transcriptMgr))
+				gw.SetTranscriptManager(transcriptMgr)
 			}
 		}
 	}
 
+	// Large result spill-over and the tool that reads spilled results
+	if cfg.Tools.Spillover.Enabled {
+		var spillStore *tools.SpillStore
+		spillDir, err := paths.DataPath("tool-output")
+		if err == nil {
+			spillStore, err = tools.NewSpillStore(spillDir, time.Duration(cfg.Tools.Spillover.TTL)*time.Hour)
+		}
+		if err != nil {
+			L_warn("tools: spill-over disabled", "error", err)
+		} else {
+			reg.SetSpillover(tools.NewSpillover(tools.SpillConfig{
+				Budget:       cfg.Tools.Spillover.Budget,
+				Budgets:      cfg.Tools.Spillover.Tools,
+				PreviewBytes: cfg.Tools.Spillover.PreviewBytes,
+			}, spillStore))
+			reg.Register(tooloutput.NewTool(spillStore))
+		}
+	}
+
+	// Workspace script tools (last, so names clashing with built-ins are skipped)
+	if cfg.Tools.Script.Enabled {
+		scriptDir := cfg.Tools.Script.Dir
+		if scriptDir == "" {
+			scriptDir = "tools"
+		}
+		if !filepath.IsAbs(scriptDir) {
+			scriptDir = filepath.Join(cfg.Gateway.WorkingDir, scriptDir)
+		}
+		scriptTimeout := 60 * time.Second
+		if cfg.Tools.Script.Timeout > 0 {
+			scriptTimeout = time.Duration(cfg.Tools.Script.Timeout) * time.Second
+		}
+		scriptMgr := script.NewManager(scriptDir, cfg.Tools.Script.AllowRoles, reg, execRunner, gw.MediaStore(), scriptTimeout)
+		scriptMgr.Load()
+		if err := scriptMgr.StartWatching(0); err != nil {
+			L_warn("script tools: watcher not started", "dir", scriptDir, "error", err)
+		}
+	}
+
 	L_info("tools: registered", "count", reg.Count())
 	return messageTool, transcriptMgr
 }
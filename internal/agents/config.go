@@ -0,0 +1,116 @@
+// Package agents defines named agent personas and the routing rules that select them.
+//
+// The implicit "main" agent is always available and is backed by the top-level
+// agent identity, gateway workspace and llm.agent model chain. Additional agents
+// are declared under "agents.list" and picked per inbound message by "agents.routes".
+package agents
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MainAgentID is the ID of the implicit default agent.
+const MainAgentID = "main"
+
+// Config configures named agents and their routing rules.
+type Config struct {
+	Default string                 `json:"default,omitempty"` // Agent used when no route matches (default: "main")
+	List    map[string]AgentConfig `json:"list,omitempty"`    // Named agents keyed by ID
+	Routes  []Route                `json:"routes,omitempty"`  // Routing rules, evaluated in order (first match wins)
+}
+
+// AgentConfig configures a single named agent.
+// Empty fields inherit from the main agent.
+type AgentConfig struct {
+	Name   string `json:"name"`             // Display name (default: agent ID)
+	Emoji  string `json:"emoji,omitempty"`  // Optional emoji prefix
+	Typing string `json:"typing,omitempty"` // Custom typing indicator text
+
+	Workspace string   `json:"workspace,omitempty"` // Workspace directory holding SOUL.md, AGENTS.md, memory/ (default: gateway workspace)
+	Models    []string `json:"models,omitempty"`    // Model chain, first = primary (default: llm.agent models)
+	Tools     []string `json:"tools,omitempty"`     // Tool allowlist, intersected with the user's role (empty = no extra restriction)
+	Skills    []string `json:"skills,omitempty"`    // Skill allowlist, intersected with the user's role (empty = no extra restriction)
+
+	MemoryDB string `json:"memoryDb,omitempty"` // Memory search database (default: shared memory.db when workspace is shared, else <workspace>/.goclaw/memory.db)
+
+	Heartbeat *HeartbeatConfig `json:"heartbeat,omitempty"` // Per-agent heartbeat (nil = no heartbeat for this agent)
+}
+
+// HeartbeatConfig configures a per-agent heartbeat.
+type HeartbeatConfig struct {
+	Enabled         bool   `json:"enabled"`
+	IntervalMinutes int    `json:"intervalMinutes"` // Interval in minutes (default: 30)
+	Prompt          string `json:"prompt,omitempty"`
+}
+
+// Route selects an agent for inbound messages.
+// Every non-empty field must match; empty fields match anything.
+type Route struct {
+	Agent   string `json:"agent"`             // Target agent ID
+	Channel string `json:"channel,omitempty"` // Channel source: "telegram", "whatsapp", "http", "tui"
+	Bot     string `json:"bot,omitempty"`     // Named bot within the channel (e.g. a second Telegram bot)
+	ChatID  string `json:"chatId,omitempty"`  // Channel-specific chat ID (group or DM)
+	User    string `json:"user,omitempty"`    // User ID from users.json
+}
+
+// RouteInput describes an inbound message for routing purposes.
+type RouteInput struct {
+	Channel string
+	Bot     string
+	ChatID  string
+	UserID  string
+}
+
+// DefaultAgent returns the agent used when no route matches.
+func (c *Config) DefaultAgent() string {
+	if c.Default != "" {
+		return c.Default
+	}
+	return MainAgentID
+}
+
+// Has returns true if the agent ID is known (main is always known).
+func (c *Config) Has(id string) bool {
+	if id == MainAgentID {
+		return true
+	}
+	_, ok := c.List[id]
+	return ok
+}
+
+// IDs returns all agent IDs, main first, the rest sorted.
+func (c *Config) IDs() []string {
+	ids := make([]string, 0, len(c.List)+1)
+	ids = append(ids, MainAgentID)
+	var named []string
+	for id := range c.List {
+		if id != MainAgentID {
+			named = append(named, id)
+		}
+	}
+	sort.Strings(named)
+	return append(ids, named...)
+}
+
+// Validate checks agent IDs and route targets.
+func (c *Config) Validate() error {
+	for id := range c.List {
+		if id == "" || strings.ContainsAny(id, ": /") {
+			return fmt.Errorf("agents: invalid agent ID %q (must be non-empty, no ':', '/' or spaces)", id)
+		}
+	}
+	if !c.Has(c.DefaultAgent()) {
+		return fmt.Errorf("agents: default agent %q is not defined", c.DefaultAgent())
+	}
+	for i, r := range c.Routes {
+		if r.Agent == "" {
+			return fmt.Errorf("agents: route %d has no agent", i)
+		}
+		if !c.Has(r.Agent) {
+			return fmt.Errorf("agents: route %d targets unknown agent %q", i, r.Agent)
+		}
+	}
+	return nil
+}
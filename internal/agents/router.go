@@ -0,0 +1,58 @@
+package agents
+
+// Resolve returns the agent ID for an inbound message.
+// Routes are evaluated in order; the first route whose non-empty fields all
+// match wins. Falls back to the default agent.
+func (c *Config) Resolve(in RouteInput) string {
+	for _, r := range c.Routes {
+		if r.matches(in) {
+			return r.Agent
+		}
+	}
+	return c.DefaultAgent()
+}
+
+// ServesChannel reports whether a channel (optionally a named bot) can carry
+// conversations for the given agent. Used to keep cron delivery, heartbeats
+// and mirroring from leaking across agents.
+//
+// A route with only channel/bot set binds that channel to one agent. Routes
+// that also constrain chat or user make the channel shared.
+func (c *Config) ServesChannel(channel, bot, agentID string) bool {
+	bound := ""
+	for _, r := range c.Routes {
+		if !matchField(r.Channel, channel) || !matchField(r.Bot, bot) {
+			continue
+		}
+		if r.ChatID == "" && r.User == "" {
+			if bound == "" {
+				bound = r.Agent
+			}
+			continue
+		}
+		if r.Agent == agentID {
+			return true
+		}
+	}
+	if bound == "" {
+		// Unbound named bots only serve agents that route to them explicitly
+		if bot != "" {
+			return false
+		}
+		bound = c.DefaultAgent()
+	}
+	return bound == agentID
+}
+
+// matches reports whether every non-empty route field matches the input.
+func (r *Route) matches(in RouteInput) bool {
+	return matchField(r.Channel, in.Channel) &&
+		matchField(r.Bot, in.Bot) &&
+		matchField(r.ChatID, in.ChatID) &&
+		matchField(r.User, in.UserID)
+}
+
+// matchField returns true if the pattern is empty or equals the value.
+func matchField(pattern, value string) bool {
+	return pattern == "" || pattern == value
+}
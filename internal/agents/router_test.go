@@ -0,0 +1,89 @@
+package agents
+
+import "testing"
+
+func testConfig() *Config {
+	return &Config{
+		List: map[string]AgentConfig{
+			"home": {Name: "Homey"},
+			"work": {Name: "Worker"},
+		},
+		Routes: []Route{
+			{Agent: "home", Channel: "whatsapp"},
+			{Agent: "work", Channel: "telegram", Bot: "work"},
+			{Agent: "work", Channel: "telegram", ChatID: "-100123"},
+		},
+	}
+}
+
+func TestResolve(t *testing.T) {
+	cfg := testConfig()
+
+	tests := []struct {
+		name string
+		in   RouteInput
+		want string
+	}{
+		{"whatsapp goes home", RouteInput{Channel: "whatsapp", UserID: "alice"}, "home"},
+		{"second telegram bot goes to work", RouteInput{Channel: "telegram", Bot: "work"}, "work"},
+		{"work group on primary bot", RouteInput{Channel: "telegram", ChatID: "-100123"}, "work"},
+		{"primary telegram falls back to main", RouteInput{Channel: "telegram", ChatID: "42"}, MainAgentID},
+		{"tui falls back to main", RouteInput{Channel: "tui"}, MainAgentID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.Resolve(tt.in); got != tt.want {
+				t.Errorf("Resolve(%+v) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	cfg.Default = "home"
+	if got := cfg.Resolve(RouteInput{Channel: "tui"}); got != "home" {
+		t.Errorf("Resolve with default = %q, want %q", got, "home")
+	}
+}
+
+func TestServesChannel(t *testing.T) {
+	cfg := testConfig()
+
+	tests := []struct {
+		channel, bot, agent string
+		want                bool
+	}{
+		{"whatsapp", "", "home", true},
+		{"whatsapp", "", MainAgentID, false},
+		{"telegram", "work", "work", true},
+		{"telegram", "work", MainAgentID, false},
+		{"telegram", "", MainAgentID, true},
+		{"telegram", "", "work", true}, // shared via chat route
+		{"telegram", "", "home", false},
+		{"telegram", "other", MainAgentID, false},
+		{"http", "", MainAgentID, true},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.ServesChannel(tt.channel, tt.bot, tt.agent); got != tt.want {
+			t.Errorf("ServesChannel(%q, %q, %q) = %v, want %v", tt.channel, tt.bot, tt.agent, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := testConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+
+	cfg.Routes = append(cfg.Routes, Route{Agent: "missing"})
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject route to unknown agent")
+	}
+
+	cfg = testConfig()
+	cfg.List["bad:id"] = AgentConfig{}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() should reject agent ID containing ':'")
+	}
+}
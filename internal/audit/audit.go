@@ -0,0 +1,283 @@
+// Package audit records privileged and security-relevant actions (tool
+// executions, supervision, role elevation, config applies, cron changes,
+// Home Assistant service calls, blocked sandbox egress) to an append-only,
+// hash-chained JSONL log.
+//
+// Each line is {"hash": ..., "event": {...}}. The event carries the hash of
+// the previous line in "prev", and "hash" is the SHA-256 of the event bytes as
+// written, so editing, removing or reordering lines breaks the chain (see Verify).
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/paths"
+)
+
+// FileName is the audit log file name inside the audit directory.
+const FileName = "audit.jsonl"
+
+// maxResultLen caps the stored result text.
+const maxResultLen = 1000
+
+// Event is one audit record.
+type Event struct {
+	Seq     int64          `json:"seq"`
+	Time    time.Time      `json:"time"`
+	Type    string         `json:"type"`              // dotted, e.g. "tool.exec", "sandbox.egress.blocked"
+	Actor   string         `json:"actor,omitempty"`   // user ID, or "cli"/"system"
+	Session string         `json:"session,omitempty"` // session key
+	Purpose string         `json:"purpose,omitempty"` // why: run purpose and triggering message
+	Inputs  map[string]any `json:"inputs,omitempty"`  // redacted inputs
+	Result  string         `json:"result,omitempty"`
+	Error   string         `json:"error,omitempty"`
+	Prev    string         `json:"prev"` // hash of the previous record ("" for the first)
+}
+
+// record is one line of the log file.
+type record struct {
+	Hash  string          `json:"hash"`
+	Event json.RawMessage `json:"event"`
+}
+
+// Log is an append-only audit log file. Appends are serialized across
+// processes (gateway and CLI) with a file lock.
+type Log struct {
+	mu       sync.Mutex
+	path     string
+	f        *os.File
+	lastHash string
+	lastSeq  int64
+	size     int64 // file size after our last read or write
+}
+
+var (
+	global   *Log
+	globalMu sync.RWMutex
+)
+
+// DefaultDir returns the default audit directory (~/.goclaw/audit).
+func DefaultDir() (string, error) {
+	return paths.DataPath("audit")
+}
+
+// InitLog opens the audit log in dir and makes it the global log.
+func InitLog(dir string) (*Log, error) {
+	l, err := Open(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, err
+	}
+	globalMu.Lock()
+	global = l
+	globalMu.Unlock()
+	L_info("audit: log opened", "path", l.path, "seq", l.lastSeq)
+	return l, nil
+}
+
+// Get returns the global audit log, or nil if not initialized.
+func Get() *Log {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return global
+}
+
+// Open opens (or creates) an audit log file.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l := &Log{path: path, f: f}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock audit log: %w", err)
+	}
+	defer unlockFile(f) //nolint:errcheck // best effort
+	if err := l.syncTail(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return l, nil
+}
+
+// Path returns the log file path.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Append chains and writes an event. Seq and Prev are assigned here; Time
+// defaults to now.
+func (l *Log) Append(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Inputs = Redact(e.Inputs)
+	e.Result = truncate(e.Result, maxResultLen)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := lockFile(l.f); err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+	defer unlockFile(l.f) //nolint:errcheck // best effort
+
+	// Another process may have appended since our last write
+	if info, err := l.f.Stat(); err != nil {
+		return err
+	} else if info.Size() != l.size {
+		if err := l.syncTail(); err != nil {
+			return err
+		}
+	}
+
+	e.Seq = l.lastSeq + 1
+	e.Prev = l.lastHash
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	hash := hashEvent(payload)
+	line, err := json.Marshal(record{Hash: hash, Event: payload})
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if _, err := l.f.Write(line); err != nil {
+		return err
+	}
+
+	l.lastHash = hash
+	l.lastSeq = e.Seq
+	l.size += int64(len(line))
+	return nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// syncTail reads the last record to pick up the chain head. Caller holds the
+// file lock.
+func (l *Log) syncTail() error {
+	info, err := l.f.Stat()
+	if err != nil {
+		return err
+	}
+	l.size = info.Size()
+	l.lastHash, l.lastSeq = "", 0
+	if l.size == 0 {
+		return nil
+	}
+
+	last, err := lastLine(l.f, l.size)
+	if err != nil {
+		return fmt.Errorf("failed to read audit log tail: %w", err)
+	}
+	var rec record
+	var ev Event
+	if err := json.Unmarshal(last, &rec); err != nil || json.Unmarshal(rec.Event, &ev) != nil {
+		// Keep appending; verify will report where the chain broke
+		L_error("audit: last record unreadable, chain will not verify", "path", l.path)
+		return nil
+	}
+	l.lastHash = rec.Hash
+	l.lastSeq = ev.Seq
+	return nil
+}
+
+// lastLine returns the last non-empty line of a file of the given size.
+func lastLine(f *os.File, size int64) ([]byte, error) {
+	const chunk = 64 * 1024
+	var buf []byte
+	for offset := size; offset > 0; {
+		n := int64(chunk)
+		if offset < n {
+			n = offset
+		}
+		offset -= n
+		part := make([]byte, n)
+		if _, err := f.ReadAt(part, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		buf = append(part, buf...)
+		trimmed := bytes.TrimRight(buf, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+		if offset == 0 {
+			return trimmed, nil
+		}
+	}
+	return nil, nil
+}
+
+func hashEvent(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Record appends an event to the global log, filling actor, session and
+// purpose from the session context when not set. Events are mirrored to the
+// debug log, so nothing is lost if the audit log isn't open.
+func Record(ctx context.Context, e Event) {
+	fillFromContext(ctx, &e)
+	L_debug("audit: "+e.Type, "actor", e.Actor, "session", e.Session, "result", truncate(e.Result, 80), "error", e.Error)
+	l := Get()
+	if l == nil {
+		return
+	}
+	if err := l.Append(e); err != nil {
+		L_error("audit: failed to write event", "type", e.Type, "error", err)
+	}
+}
+
+// scanRecords calls fn for each line of the log at path, in order.
+func scanRecords(path string, fn func(lineNo int, line []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if err := fn(lineNo, line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeEvents(t *testing.T, path string, types ...string) {
+	t.Helper()
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for _, typ := range types {
+		if err := l.Append(Event{Type: typ, Actor: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestChainVerifies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	writeEvents(t, path, "tool.exec", "hass.call_service")
+	// Reopening continues the chain
+	writeEvents(t, path, "cron.add")
+
+	res, err := Verify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.OK || res.Records != 3 || res.LastSeq != 3 {
+		t.Fatalf("verify = %+v, want 3 intact records", res)
+	}
+
+	events, err := Query(path, Filter{Type: "hass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != "hass.call_service" || events[0].Seq != 2 {
+		t.Fatalf("query type=hass returned %+v", events)
+	}
+	events, _ = Query(path, Filter{Limit: 2})
+	if len(events) != 2 || events[0].Seq != 2 {
+		t.Fatalf("query limit=2 should keep newest events, got %+v", events)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	writeEvents(t, path, "tool.exec", "hass.call_service", "cron.add")
+	original, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := bytes.SplitAfter(original, []byte("\n"))
+
+	tests := []struct {
+		name    string
+		content []byte
+		breakAt int
+	}{
+		{"edited", bytes.Replace(original, []byte(`"actor":"alice"`), []byte(`"actor":"mallory"`), 1), 1},
+		{"removed", append(append([]byte{}, lines[0]...), lines[2]...), 2},
+		{"reordered", append(append(append([]byte{}, lines[1]...), lines[0]...), lines[2]...), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, tt.content, 0600); err != nil {
+				t.Fatal(err)
+			}
+			res, err := Verify(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.OK || res.BreakAt != tt.breakAt {
+				t.Fatalf("verify = %+v, want break at line %d", res, tt.breakAt)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	in := map[string]any{
+		"command":     "ls",
+		"api_key":     "sk-123",
+		"credentials": map[string]any{"phone": "555", "pin": "1234"},
+		"config":      map[string]any{"token": "abc", "url": "http://x"},
+		"content":     strings.Repeat("a", maxInputLen+10),
+	}
+	out := Redact(in)
+
+	if out["command"] != "ls" || out["api_key"] != Redacted {
+		t.Errorf("top level: %v", out)
+	}
+	creds := out["credentials"].(map[string]any)
+	if creds["phone"] != Redacted || creds["pin"] != Redacted {
+		t.Errorf("values under a secret key should all be masked: %v", creds)
+	}
+	cfg := out["config"].(map[string]any)
+	if cfg["token"] != Redacted || cfg["url"] != "http://x" {
+		t.Errorf("nested: %v", cfg)
+	}
+	if s := out["content"].(string); len(s) > maxInputLen+3 {
+		t.Errorf("long string not truncated: %d", len(s))
+	}
+	if in["api_key"] != "sk-123" {
+		t.Error("Redact must not modify its input")
+	}
+}
@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/roelfdiedericks/goclaw/internal/types"
+)
+
+// fillFromContext sets actor, session and purpose from the session context
+// when the caller didn't.
+func fillFromContext(ctx context.Context, e *Event) {
+	if ctx == nil {
+		return
+	}
+	sc := types.GetSessionContext(ctx)
+	if sc == nil {
+		return
+	}
+	if e.Actor == "" && sc.User != nil {
+		e.Actor = sc.User.ID
+	}
+	if e.Session == "" {
+		e.Session = sc.SessionKey
+	}
+	if e.Purpose == "" {
+		e.Purpose = sc.Purpose
+	}
+}
+
+// maxTriggerLen caps the triggering message quoted in a purpose.
+const maxTriggerLen = 200
+
+// DescribePurpose builds the purpose recorded for a run, e.g.
+// `agent via telegram: "turn on the heater"`.
+func DescribePurpose(purpose, source, trigger string) string {
+	desc := purpose
+	if source != "" {
+		desc += " via " + source
+	}
+	if trigger != "" {
+		desc += fmt.Sprintf(": %q", truncate(trigger, maxTriggerLen))
+	}
+	return desc
+}
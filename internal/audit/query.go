@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Filter selects events for Query. Zero fields match everything.
+type Filter struct {
+	Type    string    // type prefix, e.g. "hass" or "tool.exec"
+	Actor   string    // exact actor
+	Session string    // exact session key
+	Since   time.Time // inclusive
+	Until   time.Time // exclusive
+	Text    string    // case-insensitive substring of the event JSON
+	Limit   int       // keep the newest N matches (0 = all)
+}
+
+func (f Filter) match(e *Event, raw []byte) bool {
+	if f.Type != "" && e.Type != f.Type && !strings.HasPrefix(e.Type, f.Type+".") {
+		return false
+	}
+	if f.Actor != "" && e.Actor != f.Actor {
+		return false
+	}
+	if f.Session != "" && e.Session != f.Session {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !e.Time.Before(f.Until) {
+		return false
+	}
+	if f.Text != "" && !strings.Contains(strings.ToLower(string(raw)), strings.ToLower(f.Text)) {
+		return false
+	}
+	return true
+}
+
+// Query returns matching events from the log at path, oldest first.
+// A missing log yields no events.
+func Query(path string, f Filter) ([]Event, error) {
+	var events []Event
+	err := scanRecords(path, func(lineNo int, line []byte) error {
+		var rec record
+		var e Event
+		if json.Unmarshal(line, &rec) != nil || json.Unmarshal(rec.Event, &e) != nil {
+			return nil // unreadable lines are reported by Verify
+		}
+		if !f.match(&e, rec.Event) {
+			return nil
+		}
+		events = append(events, e)
+		if f.Limit > 0 && len(events) > f.Limit {
+			events = events[1:]
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return events, err
+}
+
+// VerifyResult describes the integrity of a log.
+type VerifyResult struct {
+	Records  int    // records checked
+	OK       bool   // whole chain intact
+	BreakAt  int    // line number of the first problem (0 if OK)
+	Problem  string // description of the first problem
+	LastSeq  int64
+	LastHash string
+}
+
+// Verify walks the log at path and checks every record's hash, its link to
+// the previous record and sequence continuity. It stops at the first break.
+func Verify(path string) (*VerifyResult, error) {
+	res := &VerifyResult{OK: true}
+	prevHash := ""
+	var prevSeq int64
+
+	errBroken := errors.New("broken")
+	fail := func(lineNo int, format string, args ...any) error {
+		res.OK = false
+		res.BreakAt = lineNo
+		res.Problem = fmt.Sprintf(format, args...)
+		return errBroken
+	}
+
+	err := scanRecords(path, func(lineNo int, line []byte) error {
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return fail(lineNo, "unreadable record: %v", err)
+		}
+		if got := hashEvent(rec.Event); got != rec.Hash {
+			return fail(lineNo, "hash mismatch: record was modified")
+		}
+		var e Event
+		if err := json.Unmarshal(rec.Event, &e); err != nil {
+			return fail(lineNo, "unreadable event: %v", err)
+		}
+		if e.Prev != prevHash {
+			return fail(lineNo, "chain broken: prev does not match previous record (seq %d)", e.Seq)
+		}
+		if e.Seq != prevSeq+1 {
+			return fail(lineNo, "sequence gap: expected %d, got %d", prevSeq+1, e.Seq)
+		}
+		res.Records++
+		prevHash, prevSeq = rec.Hash, e.Seq
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBroken) {
+		return nil, err
+	}
+	res.LastSeq, res.LastHash = prevSeq, prevHash
+	return res, nil
+}
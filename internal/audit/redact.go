@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// Redacted replaces secret values in audit inputs.
+const Redacted = "[REDACTED]"
+
+// maxInputLen caps individual string inputs (file contents, long commands).
+const maxInputLen = 2000
+
+// secretKeyRe matches input keys whose values must never be logged.
+var secretKeyRe = regexp.MustCompile(`(?i)(passw(or)?d|secret|token|api[_-]?key|authorization|credential|private[_-]?key|cookie)`)
+
+// Redact returns a copy of inputs with secret-looking keys masked and long
+// strings truncated. Nested maps and arrays are handled too.
+func Redact(inputs map[string]any) map[string]any {
+	if inputs == nil {
+		return nil
+	}
+	out := make(map[string]any, len(inputs))
+	for k, v := range inputs {
+		if secretKeyRe.MatchString(k) {
+			out[k] = maskValue(v)
+			continue
+		}
+		out[k] = redactValue(v)
+	}
+	return out
+}
+
+func redactValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		return Redact(val)
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = redactValue(item)
+		}
+		return out
+	case string:
+		return truncate(val, maxInputLen)
+	default:
+		return v
+	}
+}
+
+// maskValue masks every string leaf under a secret key, keeping structure.
+func maskValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = maskValue(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = maskValue(item)
+		}
+		return out
+	case nil:
+		return nil
+	default:
+		return Redacted
+	}
+}
+
+// InputsFromJSON decodes raw tool input into an inputs map. Non-object input
+// is kept under "input".
+func InputsFromJSON(raw json.RawMessage) map[string]any {
+	if len(raw) == 0 {
+		return nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err == nil {
+		return m
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err == nil {
+		return map[string]any{"input": v}
+	}
+	return map[string]any{"input": string(raw)}
+}
+
+// InputsFrom converts any JSON-serializable value (a config struct, a
+// payload) into an inputs map.
+func InputsFrom(v any) map[string]any {
+	if m, ok := v.(map[string]any); ok {
+		return m
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return InputsFromJSON(data)
+}
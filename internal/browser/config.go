@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/go-rod/rod/lib/devices"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox"
 )
 
 // ConfigFromToolsConfig creates a browser.Config from the tools config structure.
@@ -24,12 +25,15 @@ type ToolsConfigAdapter struct {
 	ProfileDomains map[string]string
 
 	// Bubblewrap sandboxing
-	Workspace         string   // Workspace directory for sandbox
-	BubblewrapEnabled bool     // Enable bubblewrap sandboxing
-	BubblewrapPath    string   // Path to bwrap binary (empty = search PATH)
-	BubblewrapGPU     bool     // Allow GPU access in sandbox
-	ExtraRoBind       []string // Extra read-only bind mounts
-	ExtraBind         []string // Extra read-write bind mounts
+	Workspace         string         // Workspace directory for sandbox
+	BubblewrapEnabled bool           // Enable bubblewrap sandboxing
+	BubblewrapPath    string         // Path to bwrap binary (empty = search PATH)
+	BubblewrapGPU     bool           // Allow GPU access in sandbox
+	ExtraRoBind       []string       // Extra read-only bind mounts
+	ExtraBind         []string       // Extra read-write bind mounts
+	BubblewrapLimits  sandbox.Limits // Resource limits for the sandboxed browser
+
+	EgressProxy string // Proxy enforcing the "browser" sandbox profile's egress allowlist ("" = none)
 }
 
 // ToConfig converts the adapter to a BrowserConfig
@@ -62,7 +66,9 @@ func (a ToolsConfigAdapter) ToConfig() BrowserConfig {
 		GPU:         a.BubblewrapGPU,
 		ExtraRoBind: a.ExtraRoBind,
 		ExtraBind:   a.ExtraBind,
+		Limits:      a.BubblewrapLimits,
 	}
+	cfg.EgressProxy = a.EgressProxy
 
 	return cfg
 }
@@ -83,8 +89,9 @@ type BrowserConfig struct {
 	AllowAgentProfiles bool              `json:"allowAgentProfiles"` // Allow agent to specify any profile (default: false, only "chrome" honored)
 
 	// Bubblewrap sandboxing (set at runtime, not persisted to JSON)
-	Workspace  string                  `json:"-"` // Workspace directory for sandbox
-	Bubblewrap BrowserBubblewrapConfig `json:"-"` // Bubblewrap config
+	Workspace   string                  `json:"-"` // Workspace directory for sandbox
+	Bubblewrap  BrowserBubblewrapConfig `json:"-"` // Bubblewrap config
+	EgressProxy string                  `json:"-"` // Egress filtering proxy URL ("" = none)
 }
 
 // DefaultBrowserConfig returns the default browser configuration
@@ -83,6 +83,15 @@ func (m *Manager) createLauncher(binPath, profileDir string, headless bool) (*la
 		l = l.Set("disable-blink-features", "AutomationControlled")
 	}
 
+	// Egress allowlist: route everything through the filtering proxy,
+	// including loopback (bypassed by default) and WebRTC UDP
+	if m.config.EgressProxy != "" {
+		l = l.Set("proxy-server", m.config.EgressProxy).
+			Set("proxy-bypass-list", "<-loopback>").
+			Set("force-webrtc-ip-handling-policy", "disable_non_proxied_udp")
+		L_debug("browser: egress filtered", "proxy", m.config.EgressProxy)
+	}
+
 	// Note: We no longer add --no-sandbox. The passthrough wrapper provides
 	// a clean environment, and Chrome can use its native sandbox.
 
@@ -6,9 +6,10 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/roelfdiedericks/goclaw/internal/sandbox/bwrap"
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
 	"github.com/roelfdiedericks/goclaw/internal/paths"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox/bwrap"
 )
 
 // BrowserBubblewrapConfig holds bubblewrap settings for browser sandboxing
@@ -18,6 +19,7 @@ type BrowserBubblewrapConfig struct {
 	ExtraRoBind []string
 	ExtraBind   []string
 	GPU         bool
+	Limits      sandbox.Limits // Resource limits from the "browser" sandbox profile
 }
 
 // IsSandboxAvailable returns true if bubblewrap sandboxing is available for the browser.
@@ -54,6 +56,15 @@ func CreateSandboxedLauncher(browserBin, workspace, profileDir string, cfg Brows
 	browserBaseDir := filepath.Dir(browserBinDir) // bin directory
 	b.RoBind(browserBaseDir)
 
+	// Chromium reserves far more address space than it uses, so an rlimit
+	// on it stops the browser from starting. Memory is only capped by cgroup.
+	limits := cfg.Limits
+	if limits.MemoryMB > 0 && !limits.Cgroup {
+		L_warn("browser sandbox: memoryMB needs cgroup: true in the browser profile, not applied", "memoryMB", limits.MemoryMB)
+		limits.MemoryMB = 0
+	}
+	b.Limits(limits)
+
 	// Add extra read-only binds
 	for _, path := range cfg.ExtraRoBind {
 		b.RoBind(path)
@@ -109,6 +120,7 @@ func CreateSandboxedLauncher(browserBin, workspace, profileDir string, cfg Brows
 		"browser", browserBin,
 		"browserBinDir", browserBaseDir,
 		"gpu", cfg.GPU,
+		"limits", !cfg.Limits.IsZero(),
 	)
 
 	return wrapperPath, nil
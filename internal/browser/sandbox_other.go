@@ -2,7 +2,10 @@
 
 package browser
 
-import . "github.com/roelfdiedericks/goclaw/internal/logging"
+import (
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox"
+)
 
 // BrowserBubblewrapConfig holds bubblewrap settings for browser sandboxing
 type BrowserBubblewrapConfig struct {
@@ -11,6 +14,7 @@ type BrowserBubblewrapConfig struct {
 	ExtraRoBind []string
 	ExtraBind   []string
 	GPU         bool
+	Limits      sandbox.Limits // Resource limits from the "browser" sandbox profile
 }
 
 // IsSandboxAvailable returns false on non-Linux platforms.
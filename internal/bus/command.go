@@ -4,11 +4,13 @@
 package bus
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"sync"
 	"time"
 
+	"github.com/roelfdiedericks/goclaw/internal/audit"
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
 )
 
@@ -215,6 +217,10 @@ func dispatchCommand(cmd Command) {
 		result = handler(cmd)
 	}
 
+	if cmd.Name == "apply" {
+		recordApply(cmd, result)
+	}
+
 	// Send result if channel provided
 	if cmd.Result != nil {
 		select {
@@ -228,6 +234,26 @@ func dispatchCommand(cmd Command) {
 	}
 }
 
+// recordApply writes a config apply to the audit log. Secrets in the
+// payload are redacted.
+func recordApply(cmd Command, result CommandResult) {
+	actor := cmd.UserID
+	if actor == "" {
+		actor = cmd.Source
+	}
+	event := audit.Event{
+		Type:    "config.apply",
+		Actor:   actor,
+		Purpose: "apply " + cmd.Component + " from " + cmd.Source,
+		Inputs:  map[string]any{"component": cmd.Component, "config": audit.InputsFrom(cmd.Payload)},
+		Result:  result.Message,
+	}
+	if result.Error != nil {
+		event.Error = result.Error.Error()
+	}
+	audit.Record(context.Background(), event)
+}
+
 // --- Introspection ---
 
 // ListComponents returns all registered component names
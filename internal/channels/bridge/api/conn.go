@@ -0,0 +1,347 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// HandshakeTimeout is how long a bridge has to answer hello
+	HandshakeTimeout = 10 * time.Second
+	// RequestTimeout is how long a bridge has to answer a request
+	RequestTimeout = 30 * time.Second
+)
+
+var (
+	// ErrClosed is returned for requests on a closed connection
+	ErrClosed = errors.New("bridge connection closed")
+	// ErrUnsupported is returned for features the bridge didn't announce
+	ErrUnsupported = errors.New("not supported by the bridge")
+)
+
+// Conn is an established connection to a bridge
+type Conn struct {
+	t         Transport
+	remote    Hello
+	caps      []string // Negotiated capabilities
+	onMessage func(*Conn, *Message)
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan *Response
+	nextID  uint64
+
+	done    chan struct{} // Closed when the read loop exits
+	err     error         // Why the connection ended
+	errOnce sync.Once
+}
+
+// Connect performs the handshake over a transport: GoClaw sends hello with
+// everything it supports, the bridge answers with its own hello, and the
+// capabilities both announced are used. onMessage is called on the read
+// loop for each incoming message, possibly before Connect returns. If the
+// handshake fails, the transport is closed.
+func Connect(ctx context.Context, t Transport, name string, onMessage func(*Conn, *Message)) (*Conn, error) {
+	c := &Conn{
+		t:         t,
+		onMessage: onMessage,
+		pending:   make(map[string]chan *Response),
+		done:      make(chan struct{}),
+	}
+
+	handshook := make(chan struct{})
+	go c.readLoop(handshook)
+
+	if err := c.write(&Hello{Type: TypeHello, Protocol: ProtocolVersion, Name: name, Capabilities: Capabilities}); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("send hello: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, HandshakeTimeout)
+	defer cancel()
+
+	select {
+	case <-handshook:
+		return c, nil
+	case <-c.done:
+		return nil, fmt.Errorf("handshake failed: %w", c.err)
+	case <-ctx.Done():
+		_ = c.Close()
+		return nil, errors.New("handshake failed: bridge did not answer hello")
+	}
+}
+
+// Remote returns the bridge's hello
+func (c *Conn) Remote() Hello {
+	return c.remote
+}
+
+// Capabilities returns the negotiated capabilities
+func (c *Conn) Capabilities() []string {
+	return slices.Clone(c.caps)
+}
+
+// Has reports whether a capability was negotiated
+func (c *Conn) Has(capability string) bool {
+	return slices.Contains(c.caps, capability)
+}
+
+// Done is closed when the connection ends
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection ended, once Done is closed
+func (c *Conn) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Close closes the connection and waits for the read loop to finish
+func (c *Conn) Close() error {
+	c.fail(ErrClosed)
+	err := c.t.Close()
+	<-c.done
+	return err
+}
+
+// fail records why the connection ended (the first reason wins)
+func (c *Conn) fail(err error) {
+	c.errOnce.Do(func() { c.err = err })
+}
+
+// readLoop reads frames until the transport fails. The first frame must be
+// the bridge's hello; handshook is closed once it has been accepted.
+func (c *Conn) readLoop(handshook chan<- struct{}) {
+	defer func() {
+		c.mu.Lock()
+		for id, ch := range c.pending {
+			close(ch)
+			delete(c.pending, id)
+		}
+		c.mu.Unlock()
+		close(c.done)
+	}()
+
+	accepted := false
+	for {
+		data, err := c.t.ReadFrame()
+		if err != nil {
+			c.fail(err)
+			return
+		}
+
+		var frame struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &frame); err != nil {
+			c.fail(fmt.Errorf("invalid frame: %w", err))
+			_ = c.t.Close()
+			return
+		}
+
+		if !accepted {
+			if frame.Type != TypeHello {
+				c.fail(fmt.Errorf("expected hello, got %q", frame.Type))
+				_ = c.t.Close()
+				return
+			}
+			if err := json.Unmarshal(data, &c.remote); err != nil {
+				c.fail(fmt.Errorf("invalid hello: %w", err))
+				_ = c.t.Close()
+				return
+			}
+			if c.remote.Protocol != ProtocolVersion {
+				c.fail(fmt.Errorf("bridge speaks protocol version %d, GoClaw speaks %d", c.remote.Protocol, ProtocolVersion))
+				_ = c.t.Close()
+				return
+			}
+			c.caps = Negotiate(Capabilities, c.remote.Capabilities)
+			accepted = true
+			close(handshook)
+			continue
+		}
+
+		switch frame.Type {
+		case TypeResponse:
+			var resp Response
+			if err := json.Unmarshal(data, &resp); err != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch := c.pending[resp.ID]
+			delete(c.pending, resp.ID)
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- &resp
+			}
+
+		case TypeMessage:
+			var msg Message
+			if err := json.Unmarshal(data, &msg); err != nil || msg.ChatID == "" || msg.UserID == "" {
+				continue
+			}
+			if c.onMessage != nil {
+				c.onMessage(c, &msg)
+			}
+		}
+		// Unknown frame types are ignored, so bridges can be newer than GoClaw
+	}
+}
+
+// write sends one frame
+func (c *Conn) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.t.WriteFrame(data)
+}
+
+// newID returns the next request ID
+func (c *Conn) newID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	return strconv.FormatUint(c.nextID, 10)
+}
+
+// call sends a request and waits for its response
+func (c *Conn) call(ctx context.Context, id string, req any) (*Response, error) {
+	ch := make(chan *Response, 1)
+	c.mu.Lock()
+	select {
+	case <-c.done:
+		c.mu.Unlock()
+		return nil, ErrClosed
+	default:
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	forget := func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}
+
+	if err := c.write(req); err != nil {
+		forget()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, RequestTimeout)
+	defer cancel()
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, ErrClosed
+		}
+		if !resp.OK {
+			if resp.Error == "" {
+				resp.Error = "request failed"
+			}
+			return resp, errors.New(resp.Error)
+		}
+		return resp, nil
+	case <-ctx.Done():
+		forget()
+		return nil, fmt.Errorf("bridge did not answer: %w", ctx.Err())
+	}
+}
+
+// Send sends a message and returns the bridge's ID for it. Media needs the
+// media capability. Media given by path is sent as a path with localFiles,
+// and read and sent as data without.
+func (c *Conn) Send(ctx context.Context, s *Send) (string, error) {
+	if s.Media != nil {
+		if !c.Has(CapMedia) {
+			return "", ErrUnsupported
+		}
+		if s.Media.Path != "" {
+			m := *s.Media
+			if c.Has(CapLocalFiles) {
+				m.Data = nil
+			} else if m.Data == nil {
+				data, err := os.ReadFile(m.Path)
+				if err != nil {
+					return "", err
+				}
+				m.Data = data
+				m.Path = ""
+			} else {
+				m.Path = ""
+			}
+			s.Media = &m
+		}
+	}
+	s.Type = TypeSend
+	s.ID = c.newID()
+	resp, err := c.call(ctx, s.ID, s)
+	if err != nil {
+		return "", err
+	}
+	return resp.MessageID, nil
+}
+
+// Edit replaces the text of a sent message
+func (c *Conn) Edit(ctx context.Context, chatID, messageID, text string) error {
+	if !c.Has(CapEdit) {
+		return ErrUnsupported
+	}
+	id := c.newID()
+	_, err := c.call(ctx, id, &Edit{Type: TypeEdit, ID: id, ChatID: chatID, MessageID: messageID, Text: text})
+	return err
+}
+
+// Delete deletes a sent message
+func (c *Conn) Delete(ctx context.Context, chatID, messageID string) error {
+	if !c.Has(CapDelete) {
+		return ErrUnsupported
+	}
+	id := c.newID()
+	_, err := c.call(ctx, id, &Delete{Type: TypeDelete, ID: id, ChatID: chatID, MessageID: messageID})
+	return err
+}
+
+// React adds an emoji reaction to a message
+func (c *Conn) React(ctx context.Context, chatID, messageID, emoji string) error {
+	if !c.Has(CapReact) {
+		return ErrUnsupported
+	}
+	id := c.newID()
+	_, err := c.call(ctx, id, &React{Type: TypeReact, ID: id, ChatID: chatID, MessageID: messageID, Emoji: emoji})
+	return err
+}
+
+// Typing shows a typing indicator. Without the typing capability it does nothing.
+func (c *Conn) Typing(chatID string) error {
+	if !c.Has(CapTyping) {
+		return nil
+	}
+	return c.write(&Typing{Type: TypeTyping, ChatID: chatID})
+}
+
+// Event reports agent progress. Without the stream capability it does nothing.
+func (c *Conn) Event(ev *Event) error {
+	if !c.Has(CapStream) {
+		return nil
+	}
+	ev.Type = TypeEvent
+	return c.write(ev)
+}
@@ -0,0 +1,237 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/channels/bridge/api"
+	"github.com/roelfdiedericks/goclaw/internal/channels/bridge/bridgetest"
+)
+
+// TestMain doubles as a stdio bridge when run as a child process
+func TestMain(m *testing.M) {
+	if os.Getenv("BRIDGETEST_STDIO") == "1" {
+		fmt.Fprintln(os.Stderr, "fake bridge starting")
+		b := bridgetest.New("stdio-fake", api.CapMedia, api.CapTyping, "holograms")
+		b.Echo = true
+		if err := b.Serve(os.Stdin, os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func newServer(t *testing.T, capabilities ...string) *bridgetest.Server {
+	t.Helper()
+	srv := bridgetest.NewServer("fake", "secret", capabilities...)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func connect(t *testing.T, srv *bridgetest.Server, onMessage func(*api.Conn, *api.Message)) *api.Conn {
+	t.Helper()
+	ws, err := api.DialWebSocket(context.Background(), srv.URL(), "secret")
+	if err != nil {
+		t.Fatalf("DialWebSocket: %v", err)
+	}
+	conn, err := api.Connect(context.Background(), ws, "signal", onMessage)
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestNegotiate(t *testing.T) {
+	got := api.Negotiate(api.Capabilities, []string{"stream", "unknown", "edit"})
+	if !slices.Equal(got, []string{"edit", "stream"}) {
+		t.Errorf("Negotiate = %v", got)
+	}
+	if got := api.Negotiate(api.Capabilities, nil); got != nil {
+		t.Errorf("Negotiate(nil) = %v", got)
+	}
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	srv := newServer(t, api.CapEdit, api.CapReact, "holograms")
+	conn := connect(t, srv, nil)
+
+	if got := conn.Capabilities(); !slices.Equal(got, []string{api.CapEdit, api.CapReact}) {
+		t.Errorf("Capabilities = %v", got)
+	}
+	if conn.Remote().Name != "fake" || !conn.Has(api.CapEdit) || conn.Has(api.CapDelete) {
+		t.Errorf("Remote = %+v", conn.Remote())
+	}
+	hello := srv.Hello()
+	if hello == nil || hello.Name != "signal" || hello.Protocol != api.ProtocolVersion || !slices.Equal(hello.Capabilities, api.Capabilities) {
+		t.Errorf("GoClaw hello = %+v", hello)
+	}
+}
+
+func TestWebSocketUnauthorized(t *testing.T) {
+	srv := newServer(t)
+	if _, err := api.DialWebSocket(context.Background(), srv.URL(), "wrong"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("DialWebSocket with wrong token: %v", err)
+	}
+}
+
+func TestRequests(t *testing.T) {
+	srv := newServer(t, api.CapEdit, api.CapTyping, api.CapStream)
+	conn := connect(t, srv, nil)
+	ctx := context.Background()
+
+	id, err := conn.Send(ctx, &api.Send{ChatID: "+2782", Text: "**hi**", ReplyTo: "x1"})
+	if err != nil || id == "" {
+		t.Fatalf("Send = %q, %v", id, err)
+	}
+	if err := conn.Edit(ctx, "+2782", id, "hello"); err != nil {
+		t.Errorf("Edit: %v", err)
+	}
+	if err := conn.Delete(ctx, "+2782", id); !errors.Is(err, api.ErrUnsupported) {
+		t.Errorf("Delete without capability: %v", err)
+	}
+	if _, err := conn.Send(ctx, &api.Send{ChatID: "+2782", Media: &api.Media{Type: api.MediaFile, Data: []byte("x")}}); !errors.Is(err, api.ErrUnsupported) {
+		t.Errorf("Send media without capability: %v", err)
+	}
+
+	srv.Fail(api.TypeSend, "rate limited")
+	if _, err := conn.Send(ctx, &api.Send{ChatID: "+2782", Text: "again"}); err == nil || err.Error() != "rate limited" {
+		t.Errorf("failed Send: %v", err)
+	}
+
+	_ = conn.Typing("+2782")
+	_ = conn.Event(&api.Event{ChatID: "+2782", Event: api.EventText, Text: "he"})
+	if _, err := srv.WaitFrames(api.TypeEvent, 1, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	var send api.Send
+	sends := srv.Frames(api.TypeSend)
+	if len(sends) != 2 || sends[0].Decode(&send) != nil || send.Text != "**hi**" || send.ReplyTo != "x1" || send.ID == "" {
+		t.Errorf("sends = %+v", sends)
+	}
+	var edit api.Edit
+	if edits := srv.Frames(api.TypeEdit); len(edits) != 1 || edits[0].Decode(&edit) != nil || edit.MessageID != id || edit.Text != "hello" {
+		t.Errorf("edit = %+v", edit)
+	}
+	if n := len(srv.Frames(api.TypeTyping)); n != 1 {
+		t.Errorf("typing frames = %d", n)
+	}
+}
+
+func TestIncomingMessages(t *testing.T) {
+	srv := newServer(t)
+	got := make(chan *api.Message, 2)
+	connect(t, srv, func(_ *api.Conn, msg *api.Message) { got <- msg })
+
+	// Messages without chat or user are dropped
+	_ = srv.Deliver(api.Message{ChatID: "g1", Text: "no user"})
+	if err := srv.Deliver(api.Message{
+		ID: "42", ChatID: "g1", UserID: "+2782", UserName: "Alice", IsGroup: true, Mentioned: true, Text: "look",
+		Media: []api.Media{{Type: api.MediaImage, MimeType: "image/png", Data: []byte{0x89, 'P', 'N', 'G'}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case msg := <-got:
+		if msg.UserID != "+2782" || !msg.IsGroup || !msg.Mentioned || msg.Text != "look" {
+			t.Errorf("message = %+v", msg)
+		}
+		if len(msg.Media) != 1 || string(msg.Media[0].Data) != "\x89PNG" {
+			t.Errorf("media = %+v", msg.Media)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message")
+	}
+}
+
+func TestClosePendingRequests(t *testing.T) {
+	srv := newServer(t)
+	conn := connect(t, srv, nil)
+
+	srv.Close()
+	select {
+	case <-conn.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection not closed")
+	}
+	if conn.Err() == nil {
+		t.Error("Err() = nil after the bridge went away")
+	}
+	if _, err := conn.Send(context.Background(), &api.Send{ChatID: "c", Text: "x"}); !errors.Is(err, api.ErrClosed) {
+		t.Errorf("Send after close: %v", err)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	var stderr []string
+	lines := make(chan string, 10)
+	proc, err := api.StartProcess(api.ProcessSpec{
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^$"},
+		Env:     map[string]string{"BRIDGETEST_STDIO": "1"},
+	}, func(line string) { lines <- line })
+	if err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+
+	got := make(chan *api.Message, 1)
+	conn, err := api.Connect(context.Background(), proc, "sms", func(_ *api.Conn, msg *api.Message) { got <- msg })
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if !slices.Equal(conn.Capabilities(), []string{api.CapMedia, api.CapTyping}) {
+		t.Errorf("Capabilities = %v", conn.Capabilities())
+	}
+
+	// Without localFiles, media given by path is sent inline
+	file := filepath.Join(t.TempDir(), "note.txt")
+	if err := os.WriteFile(file, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Send(context.Background(), &api.Send{ChatID: "+2782", Text: "ping", Media: &api.Media{Type: api.MediaFile, Path: file}}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	select {
+	case msg := <-got:
+		if msg.Text != "echo: ping" || msg.UserID != "+2782" {
+			t.Errorf("echo = %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no echo")
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	close(lines)
+	for line := range lines {
+		stderr = append(stderr, line)
+	}
+	if !slices.Contains(stderr, "fake bridge starting") {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
+
+func TestProcessWithoutHello(t *testing.T) {
+	proc, err := api.StartProcess(api.ProcessSpec{Command: "sh", Args: []string{"-c", "echo '{\"type\":\"message\"}'; sleep 30"}}, nil)
+	if err != nil {
+		t.Skipf("sh not available: %v", err)
+	}
+	start := time.Now()
+	if _, err := api.Connect(context.Background(), proc, "sms", nil); err == nil || !strings.Contains(err.Error(), "expected hello") {
+		t.Errorf("Connect = %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Error("failed handshake did not stop the process promptly")
+	}
+}
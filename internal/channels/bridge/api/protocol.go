@@ -0,0 +1,169 @@
+// Package api implements the GoClaw bridge protocol: JSON frames exchanged
+// with an external program that connects GoClaw to a messaging network.
+// Frames are one JSON object per line on a child process's stdin/stdout, or
+// one per text message on a WebSocket. See docs/bridge.md for the reference.
+package api
+
+import (
+	"encoding/json"
+	"slices"
+)
+
+// ProtocolVersion is the protocol version GoClaw speaks
+const ProtocolVersion = 1
+
+// MaxFrameSize limits a single frame (media is sent inline as base64)
+const MaxFrameSize = 32 << 20
+
+// Frame types
+const (
+	TypeHello    = "hello"    // Both directions, first frame: version and capabilities
+	TypeMessage  = "message"  // Bridge → GoClaw: a user wrote something
+	TypeResponse = "response" // Bridge → GoClaw: result of a request
+	TypeSend     = "send"     // GoClaw → bridge: send a message (request)
+	TypeEdit     = "edit"     // GoClaw → bridge: edit a sent message (request)
+	TypeDelete   = "delete"   // GoClaw → bridge: delete a sent message (request)
+	TypeReact    = "react"    // GoClaw → bridge: react to a message (request)
+	TypeTyping   = "typing"   // GoClaw → bridge: show a typing indicator
+	TypeEvent    = "event"    // GoClaw → bridge: agent progress while a reply is generated
+)
+
+// Capabilities are optional features a bridge announces in its hello.
+// GoClaw uses a feature only if both sides announced it.
+const (
+	CapEdit       = "edit"       // Edit sent messages
+	CapDelete     = "delete"     // Delete sent messages
+	CapReact      = "react"      // React to messages with an emoji
+	CapMedia      = "media"      // Send files
+	CapTyping     = "typing"     // Show a typing indicator
+	CapStream     = "stream"     // Receive agent events while a reply is generated
+	CapLocalFiles = "localFiles" // Read outgoing files from GoClaw's disk (path instead of data)
+)
+
+// Capabilities lists every capability GoClaw supports, in the order offered
+var Capabilities = []string{CapEdit, CapDelete, CapReact, CapMedia, CapTyping, CapStream, CapLocalFiles}
+
+// Media types of attachments
+const (
+	MediaImage = "image"
+	MediaAudio = "audio"
+	MediaFile  = "file"
+)
+
+// Agent event kinds carried by event frames
+const (
+	EventText      = "text"       // Reply text delta
+	EventThinking  = "thinking"   // Reasoning text delta
+	EventToolStart = "tool_start" // A tool started (tool, input)
+	EventToolEnd   = "tool_end"   // A tool finished (tool, error)
+	EventEnd       = "end"        // The reply is complete (text); a send follows
+	EventError     = "error"      // The run failed (text)
+)
+
+// Hello opens the connection in both directions
+type Hello struct {
+	Type         string   `json:"type"`
+	Protocol     int      `json:"protocol"`
+	Name         string   `json:"name,omitempty"`    // Channel name (GoClaw) or bridge name
+	Version      string   `json:"version,omitempty"` // Free-form bridge version, for logs
+	Capabilities []string `json:"capabilities"`
+}
+
+// Message is an incoming message from a user
+type Message struct {
+	Type      string  `json:"type"`
+	ID        string  `json:"id,omitempty"` // Bridge's message ID (for replies and reactions)
+	ChatID    string  `json:"chatId"`       // Conversation; for direct chats, the user's ID
+	UserID    string  `json:"userId"`       // Sender, matched against users' identities
+	UserName  string  `json:"userName,omitempty"`
+	IsGroup   bool    `json:"isGroup,omitempty"`
+	Mentioned bool    `json:"mentioned,omitempty"` // The bot was mentioned or replied to (groups)
+	Text      string  `json:"text,omitempty"`
+	ReplyTo   string  `json:"replyTo,omitempty"` // ID of the message this answers
+	Media     []Media `json:"media,omitempty"`
+}
+
+// Media is an attachment. Incoming media always carries data; outgoing
+// media carries a path instead when the bridge supports localFiles.
+type Media struct {
+	Type     string `json:"type"` // image, audio or file
+	MimeType string `json:"mimeType,omitempty"`
+	Filename string `json:"filename,omitempty"`
+	Data     []byte `json:"data,omitempty"` // base64 in JSON
+	Path     string `json:"path,omitempty"` // Absolute path on GoClaw's machine
+	Caption  string `json:"caption,omitempty"`
+}
+
+// Response answers a request frame with the same ID
+type Response struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	OK        bool   `json:"ok"`
+	MessageID string `json:"messageId,omitempty"` // ID of the sent message (send)
+	Error     string `json:"error,omitempty"`
+}
+
+// Send asks the bridge to send a message
+type Send struct {
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	ChatID     string `json:"chatId"`
+	Text       string `json:"text,omitempty"` // Markdown as written by the agent
+	ReplyTo    string `json:"replyTo,omitempty"`
+	Media      *Media `json:"media,omitempty"`
+	Ghostwrite bool   `json:"ghostwrite,omitempty"` // Written by a supervisor, not the agent
+}
+
+// Edit asks the bridge to replace the text of a sent message
+type Edit struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	ChatID    string `json:"chatId"`
+	MessageID string `json:"messageId"`
+	Text      string `json:"text"`
+}
+
+// Delete asks the bridge to delete a sent message
+type Delete struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	ChatID    string `json:"chatId"`
+	MessageID string `json:"messageId"`
+}
+
+// React asks the bridge to react to a message
+type React struct {
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	ChatID    string `json:"chatId"`
+	MessageID string `json:"messageId"`
+	Emoji     string `json:"emoji"`
+}
+
+// Typing shows a typing indicator in a chat. It is not answered.
+type Typing struct {
+	Type   string `json:"type"`
+	ChatID string `json:"chatId"`
+}
+
+// Event reports agent progress in a chat. It is not answered.
+type Event struct {
+	Type   string          `json:"type"`
+	ChatID string          `json:"chatId"`
+	Event  string          `json:"event"`
+	Text   string          `json:"text,omitempty"`
+	Tool   string          `json:"tool,omitempty"`
+	Input  json.RawMessage `json:"input,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Negotiate returns the capabilities in both lists, in the order of offered
+func Negotiate(offered, accepted []string) []string {
+	var out []string
+	for _, c := range offered {
+		if slices.Contains(accepted, c) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Transport carries frames to and from a bridge
+type Transport interface {
+	// ReadFrame blocks until the next frame arrives or the transport closes
+	ReadFrame() ([]byte, error)
+	// WriteFrame sends one frame. Callers serialize writes.
+	WriteFrame(data []byte) error
+	// Close shuts the transport down and unblocks ReadFrame
+	Close() error
+}
+
+// --- Child process (stdio) ---
+
+// stopGrace is how long a bridge process gets to exit after its stdin closes
+const stopGrace = 3 * time.Second
+
+// Process runs a bridge as a child process, exchanging one frame per line
+// on its stdin and stdout
+type Process struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	out   *bufio.Scanner
+
+	exited  chan struct{} // Closed when the process has been waited for
+	waitErr error
+	once    sync.Once
+}
+
+// ProcessSpec describes the bridge program to run
+type ProcessSpec struct {
+	Command string
+	Args    []string
+	Env     map[string]string // Added to GoClaw's environment
+	Dir     string
+}
+
+// StartProcess starts a bridge program. Lines it writes to stderr are passed
+// to stderr (may be nil).
+func StartProcess(spec ProcessSpec, stderr func(line string)) (*Process, error) {
+	cmd := exec.Command(spec.Command, spec.Args...)
+	cmd.Dir = spec.Dir
+	cmd.Env = os.Environ()
+	for k, v := range spec.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	cmd.Stderr = &lineWriter{fn: stderr}
+	// Children of the bridge may keep its output open after it exits
+	cmd.WaitDelay = time.Second
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", spec.Command, err)
+	}
+
+	out := bufio.NewScanner(stdout)
+	out.Buffer(make([]byte, 64*1024), MaxFrameSize)
+
+	p := &Process{cmd: cmd, stdin: stdin, out: out, exited: make(chan struct{})}
+	go func() {
+		p.waitErr = cmd.Wait()
+		close(p.exited)
+	}()
+
+	return p, nil
+}
+
+// ReadFrame returns the next non-empty line from the process's stdout
+func (p *Process) ReadFrame() ([]byte, error) {
+	for p.out.Scan() {
+		line := bytes.TrimSpace(p.out.Bytes())
+		if len(line) > 0 {
+			return bytes.Clone(line), nil
+		}
+	}
+	if err := p.out.Err(); err != nil {
+		return nil, err
+	}
+	// stdout closed: report why the process went away
+	select {
+	case <-p.exited:
+		if p.waitErr != nil {
+			return nil, fmt.Errorf("bridge process exited: %w", p.waitErr)
+		}
+	case <-time.After(stopGrace):
+	}
+	return nil, errors.New("bridge process closed its output")
+}
+
+// WriteFrame writes a frame as one line to the process's stdin
+func (p *Process) WriteFrame(data []byte) error {
+	_, err := p.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the process's stdin, the signal for a bridge to exit, and
+// kills it if it is still running after a grace period
+func (p *Process) Close() error {
+	p.once.Do(func() {
+		_ = p.stdin.Close()
+		select {
+		case <-p.exited:
+		case <-time.After(stopGrace):
+			_ = p.cmd.Process.Kill()
+			<-p.exited
+		}
+	})
+	return nil
+}
+
+// lineWriter passes complete lines to fn
+type lineWriter struct {
+	fn  func(line string)
+	buf []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if line := strings.TrimRight(string(w.buf[:i]), "\r"); line != "" && w.fn != nil {
+			w.fn(line)
+		}
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) > 64*1024 {
+		w.buf = w.buf[:0] // Drop overlong lines rather than grow forever
+	}
+	return len(p), nil
+}
+
+// --- WebSocket ---
+
+const (
+	// pingInterval keeps the connection alive through proxies
+	pingInterval = 30 * time.Second
+	// pongWait is how long the bridge has to answer a ping
+	pongWait = 75 * time.Second
+)
+
+// WebSocket connects to a bridge that serves the protocol over a WebSocket,
+// one frame per text message
+type WebSocket struct {
+	conn *websocket.Conn
+	done chan struct{}
+	once sync.Once
+}
+
+// DialWebSocket connects to a bridge at url (ws:// or wss://). A non-empty
+// token is sent as a Bearer Authorization header.
+func DialWebSocket(ctx context.Context, url, token string) (*WebSocket, error) {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, url, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("dial %s: %w (HTTP %d)", url, err, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("dial %s: %w", url, err)
+	}
+	conn.SetReadLimit(MaxFrameSize)
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	ws := &WebSocket{conn: conn, done: make(chan struct{})}
+	go ws.pingLoop()
+	return ws, nil
+}
+
+func (ws *WebSocket) pingLoop() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ws.done:
+			return
+		case <-ticker.C:
+			if err := ws.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ReadFrame returns the next text message
+func (ws *WebSocket) ReadFrame() ([]byte, error) {
+	for {
+		msgType, data, err := ws.conn.ReadMessage()
+		if err != nil {
+			return nil, err
+		}
+		// Any traffic shows the bridge is alive
+		_ = ws.conn.SetReadDeadline(time.Now().Add(pongWait))
+		if msgType == websocket.TextMessage {
+			return data, nil
+		}
+	}
+}
+
+// WriteFrame sends a frame as a text message
+func (ws *WebSocket) WriteFrame(data []byte) error {
+	return ws.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// Close sends a close message and closes the connection
+func (ws *WebSocket) Close() error {
+	ws.once.Do(func() {
+		close(ws.done)
+		_ = ws.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		_ = ws.conn.Close()
+	})
+	return nil
+}
@@ -0,0 +1,797 @@
+// Package bridge provides bridge channels: GoClaw's side of a JSON protocol
+// that external programs speak to connect networks GoClaw has no built-in
+// channel for (Signal via signal-cli, SMS gateways, custom hardware).
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/bus"
+	"github.com/roelfdiedericks/goclaw/internal/channels/bridge/api"
+	"github.com/roelfdiedericks/goclaw/internal/channels/bridge/config"
+	chtypes "github.com/roelfdiedericks/goclaw/internal/channels/types"
+	"github.com/roelfdiedericks/goclaw/internal/commands"
+	"github.com/roelfdiedericks/goclaw/internal/gateway"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/media"
+	"github.com/roelfdiedericks/goclaw/internal/session"
+	itypes "github.com/roelfdiedericks/goclaw/internal/types"
+	"github.com/roelfdiedericks/goclaw/internal/user"
+)
+
+const (
+	// typingRefresh re-sends typing while a reply takes long; most networks
+	// show the indicator for 5-15 seconds
+	typingRefresh = 8 * time.Second
+	// maxMedia is how many attachments of one message are taken
+	maxMedia = 10
+	// minBackoff and maxBackoff bound the wait between reconnects
+	minBackoff = 2 * time.Second
+	maxBackoff = 2 * time.Minute
+	// stableAfter is how long a connection must last to reset the backoff,
+	// so a bridge that crashes right after the handshake isn't restarted in a loop
+	stableAfter = time.Minute
+)
+
+// errNotConnected is returned while the bridge is down
+var errNotConnected = errors.New("bridge not connected")
+
+// Bot is one bridge channel
+type Bot struct {
+	gateway *gateway.Gateway
+	users   *user.Registry
+	config  *config.BridgeConfig
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{} // Closed when the connection loop exits
+
+	mu         sync.RWMutex
+	conn       *api.Conn // nil while reconnecting
+	running    bool
+	startedAt  time.Time
+	lastError  error
+	reconnects int
+}
+
+// New creates a bridge channel
+func New(cfg *config.BridgeConfig, gw *gateway.Gateway, users *user.Registry) (*Bot, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, fmt.Errorf("bridge: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Bot{
+		gateway: gw,
+		users:   users,
+		config:  cfg,
+		ctx:     ctx,
+		cancel:  cancel,
+	}, nil
+}
+
+// Start starts the bridge program or connects to it and completes the
+// handshake (implements ManagedChannel). If the connection drops later,
+// the bridge is restarted or redialed with backoff.
+func (b *Bot) Start(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.running {
+		return nil
+	}
+
+	conn, err := b.connect()
+	if err != nil {
+		b.lastError = err
+		return fmt.Errorf("bridge %s: %w", b.config.Name, err)
+	}
+
+	b.conn = conn
+	b.running = true
+	b.startedAt = time.Now()
+	b.lastError = nil
+	b.done = make(chan struct{})
+	go b.connectionLoop(conn, b.done)
+
+	remote := conn.Remote()
+	L_info("bridge: connected", "bridge", b.config.Name, "remote", remote.Name, "version", remote.Version, "capabilities", conn.Capabilities())
+	return nil
+}
+
+// connect opens the transport and performs the handshake
+func (b *Bot) connect() (*api.Conn, error) {
+	ctx, cancel := context.WithTimeout(b.ctx, api.HandshakeTimeout)
+	defer cancel()
+
+	t, err := config.Open(ctx, b.config)
+	if err != nil {
+		return nil, err
+	}
+	return api.Connect(ctx, t, b.config.Name, b.onMessage)
+}
+
+// connectionLoop waits for the connection to end and reconnects until the
+// bot is stopped
+func (b *Bot) connectionLoop(conn *api.Conn, done chan struct{}) {
+	defer close(done)
+
+	backoff := minBackoff
+	connectedAt := time.Now()
+	for {
+		select {
+		case <-b.ctx.Done():
+			_ = conn.Close()
+			return
+		case <-conn.Done():
+		}
+
+		err := conn.Err()
+		L_warn("bridge: connection lost, reconnecting", "bridge", b.config.Name, "error", err)
+		b.mu.Lock()
+		b.conn = nil
+		b.lastError = err
+		b.mu.Unlock()
+
+		if time.Since(connectedAt) > stableAfter {
+			backoff = minBackoff
+		}
+
+		for {
+			select {
+			case <-b.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxBackoff)
+
+			c, err := b.connect()
+			if err != nil {
+				L_warn("bridge: reconnect failed", "bridge", b.config.Name, "error", err, "nextRetry", backoff)
+				b.mu.Lock()
+				b.lastError = err
+				b.mu.Unlock()
+				continue
+			}
+			conn = c
+			break
+		}
+
+		connectedAt = time.Now()
+		b.mu.Lock()
+		b.conn = conn
+		b.lastError = nil
+		b.reconnects++
+		b.mu.Unlock()
+		L_info("bridge: reconnected", "bridge", b.config.Name, "capabilities", conn.Capabilities())
+	}
+}
+
+// connection returns the current connection
+func (b *Bot) connection() (*api.Conn, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.conn == nil {
+		return nil, errNotConnected
+	}
+	return b.conn, nil
+}
+
+// RegisterOperationalCommands registers runtime commands for this bridge
+func (b *Bot) RegisterOperationalCommands() {
+	bus.RegisterCommand(b.Name(), "status", b.handleStatusCommand)
+}
+
+func (b *Bot) handleStatusCommand(cmd bus.Command) bus.CommandResult {
+	b.mu.RLock()
+	conn := b.conn
+	reconnects := b.reconnects
+	b.mu.RUnlock()
+
+	data := map[string]any{
+		"connected":  conn != nil,
+		"transport":  b.transport(),
+		"reconnects": reconnects,
+	}
+	if conn == nil {
+		return bus.CommandResult{Success: true, Message: fmt.Sprintf("Bridge %s reconnecting", b.config.Name), Data: data}
+	}
+	remote := conn.Remote()
+	data["remote"] = remote.Name
+	data["version"] = remote.Version
+	data["capabilities"] = conn.Capabilities()
+	return bus.CommandResult{
+		Success: true,
+		Message: fmt.Sprintf("Bridge %s connected to %s", b.config.Name, remote.Name),
+		Data:    data,
+	}
+}
+
+// transport describes how the bridge is reached
+func (b *Bot) transport() string {
+	if b.config.URL != "" {
+		return "websocket"
+	}
+	return "stdio"
+}
+
+// Stop disconnects from the bridge, stopping its program (implements ManagedChannel)
+func (b *Bot) Stop() error {
+	b.mu.Lock()
+	if !b.running {
+		b.mu.Unlock()
+		return nil
+	}
+	L_info("bridge: stopping", "bridge", b.config.Name)
+	b.cancel()
+	b.running = false
+	done := b.done
+	b.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		L_warn("bridge: did not stop in time", "bridge", b.config.Name)
+	}
+
+	b.mu.Lock()
+	b.conn = nil
+	b.mu.Unlock()
+	return nil
+}
+
+// Reload applies new configuration (implements ManagedChannel)
+func (b *Bot) Reload(cfg any) error {
+	newCfg, ok := cfg.(*config.BridgeConfig)
+	if !ok {
+		return fmt.Errorf("expected *bridge.BridgeConfig, got %T", cfg)
+	}
+	if err := newCfg.Check(); err != nil {
+		return fmt.Errorf("bridge: %w", err)
+	}
+
+	b.mu.Lock()
+	wasRunning := b.running
+	b.mu.Unlock()
+
+	if wasRunning {
+		if err := b.Stop(); err != nil {
+			return fmt.Errorf("failed to stop for reload: %w", err)
+		}
+	}
+
+	b.config = newCfg
+
+	if wasRunning {
+		b.ctx, b.cancel = context.WithCancel(context.Background())
+		return b.Start(b.ctx)
+	}
+
+	return nil
+}
+
+// Status returns current channel status (implements ManagedChannel)
+func (b *Bot) Status() chtypes.ChannelStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	info := b.transport()
+	if b.conn != nil {
+		if name := b.conn.Remote().Name; name != "" {
+			info = name + " (" + info + ")"
+		}
+	}
+	return chtypes.ChannelStatus{
+		Running:   b.running,
+		Connected: b.running && b.conn != nil,
+		Error:     b.lastError,
+		StartedAt: b.startedAt,
+		Info:      info,
+	}
+}
+
+// Name returns the channel name, which is the bridge's name (implements gateway.Channel)
+func (b *Bot) Name() string {
+	return b.config.Name
+}
+
+// Send sends a message to the owner's direct chat (implements gateway.Channel)
+func (b *Bot) Send(ctx context.Context, msg string) error {
+	chatID := b.users.Owner().Identity(b.Name())
+	if chatID == "" {
+		return nil
+	}
+	_, err := b.deliver(chatID, msg, "", false)
+	return err
+}
+
+// SendMirror sends a cross-channel mirror summary to the owner (implements gateway.Channel)
+func (b *Bot) SendMirror(ctx context.Context, source, userMsg, response string) error {
+	owner := b.users.Owner()
+	chatID := owner.Identity(b.Name())
+	if chatID == "" {
+		return nil
+	}
+
+	agentName := b.gateway.AgentIdentityFor(b.gateway.ResolveAgent(b.Name(), "", chatID, owner)).Name
+	mirror := fmt.Sprintf("**%s**\n\n**You:** %s\n\n**%s:** %s", source, truncate(userMsg, 300), agentName, truncate(response, 3000))
+
+	_, err := b.sendText(chatID, mirror, "", false)
+	if err != nil {
+		L_error("bridge: failed to send mirror", "bridge", b.Name(), "error", err)
+	}
+	return err
+}
+
+// HasUser returns true if the user has an identity on this bridge (implements gateway.Channel)
+func (b *Bot) HasUser(u *user.User) bool {
+	return u.Identity(b.Name()) != ""
+}
+
+// StreamEvent forwards an agent event to the user's direct chat if the
+// bridge supports streaming. The final text follows as a send, so bridges
+// never have to assemble the reply from events (implements gateway.Channel).
+func (b *Bot) StreamEvent(u *user.User, event gateway.AgentEvent) bool {
+	chatID := u.Identity(b.Name())
+	conn, err := b.connection()
+	if chatID == "" || err != nil || !conn.Has(api.CapStream) {
+		return false
+	}
+	b.forward(conn, chatID, event)
+	if e, ok := event.(gateway.EventAgentEnd); ok && e.FinalText != "" {
+		if _, err := b.deliver(chatID, e.FinalText, "", false); err != nil {
+			L_error("bridge: failed to deliver streamed reply", "bridge", b.Name(), "error", err)
+		}
+	}
+	return true
+}
+
+// DeliverGhostwrite sends a ghostwritten message with typing simulation (implements gateway.Channel)
+func (b *Bot) DeliverGhostwrite(ctx context.Context, u *user.User, message string) error {
+	chatID := u.Identity(b.Name())
+	if chatID == "" {
+		return nil
+	}
+
+	L_info("bridge: ghostwrite", "bridge", b.Name(), "user", u.ID, "messageLen", len(message))
+
+	b.typing(chatID)
+
+	typingDelay := 500 * time.Millisecond
+	if b.gateway != nil {
+		if cfg := b.gateway.Config(); cfg != nil && cfg.Supervision.Ghostwriting.TypingDelayMs > 0 {
+			typingDelay = time.Duration(cfg.Supervision.Ghostwriting.TypingDelayMs) * time.Millisecond
+		}
+	}
+	time.Sleep(typingDelay)
+
+	if _, err := b.deliver(chatID, message, "", true); err != nil {
+		return fmt.Errorf("failed to send ghostwrite: %w", err)
+	}
+
+	L_info("bridge: ghostwrite delivered", "bridge", b.Name(), "user", u.ID, "messageLen", len(message))
+	return nil
+}
+
+// --- Incoming messages ---
+
+// onMessage receives messages on the connection's read loop
+func (b *Bot) onMessage(_ *api.Conn, msg *api.Message) {
+	// Agent runs take a while; don't hold up the connection
+	go b.handleMessage(msg)
+}
+
+// handleMessage processes an incoming message
+func (b *Bot) handleMessage(msg *api.Message) {
+	u := b.users.FromIdentity(b.Name(), msg.UserID)
+	if u == nil {
+		if msg.IsGroup {
+			L_trace("bridge: message from unknown user ignored", "bridge", b.Name(), "user", msg.UserID, "chat", msg.ChatID)
+		} else {
+			L_warn("bridge: unknown user ignored", "bridge", b.Name(), "user", msg.UserID, "name", msg.UserName)
+		}
+		return
+	}
+
+	replyTo := ""
+	if msg.IsGroup {
+		if b.config.MentionRequired() && !msg.Mentioned {
+			return
+		}
+		replyTo = msg.ID
+	}
+
+	contentBlocks := b.saveMedia(msg, u)
+	text := strings.TrimSpace(msg.Text)
+	if text == "" {
+		if len(contentBlocks) == 0 {
+			return
+		}
+		switch contentBlocks[0].Type {
+		case "image":
+			text = "<media:image>"
+		case "audio":
+			text = "[Voice note received]"
+		default:
+			text = "[File received]"
+		}
+	}
+
+	L_info("bridge: authenticated message", "bridge", b.Name(), "user", u.Name, "role", u.Role, "chat", msg.ChatID, "isGroup", msg.IsGroup)
+
+	// Check for panic phrase (emergency stop) before commands
+	// Always attempt cancel and confirm - avoids race conditions where session just finished
+	if commands.IsPanicPhrase(text) {
+		b.gateway.StopAllUserSessions(u.ID)
+		b.sendPlain(msg.ChatID, "Stopping all tasks.")
+		return
+	}
+
+	if commands.IsCommand(text) {
+		b.handleCommand(u, msg.ChatID, text)
+		return
+	}
+
+	b.typing(msg.ChatID)
+
+	chatID := msg.ChatID
+	req := gateway.AgentRequest{
+		User:           u,
+		Source:         b.Name(),
+		ChatID:         chatID,
+		IsGroup:        msg.IsGroup,
+		UserMsg:        text,
+		ContentBlocks:  contentBlocks,
+		EnableThinking: u.Thinking,
+		ThinkingLevel:  u.ThinkingLevel,
+		OnMediaToSend: func(path, caption string) error {
+			_, err := b.sendMediaFile(chatID, path, caption)
+			return err
+		},
+	}
+
+	evChan := make(chan gateway.AgentEvent, 100)
+
+	go func() {
+		if err := b.gateway.RunAgent(b.ctx, req, evChan); err != nil {
+			L_error("bridge: agent error", "bridge", b.Name(), "error", err)
+		}
+	}()
+
+	b.respond(chatID, replyTo, evChan)
+}
+
+// handleCommand routes commands to the global command manager
+func (b *Bot) handleCommand(u *user.User, chatID, text string) {
+	if !b.canUserUseCommands(u) {
+		L_debug("bridge: commands disabled for user", "bridge", b.Name(), "user", u.Name, "command", text)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := commands.GetManager().Execute(ctx, text, b.getSessionKey(u), u.ID)
+	if _, err := b.sendText(chatID, result.Markdown, "", false); err != nil {
+		L_error("bridge: failed to send command result", "bridge", b.Name(), "error", err)
+	}
+}
+
+// respond consumes agent events: with the stream capability they are
+// forwarded as they arrive, and the final reply is always sent as a message
+func (b *Bot) respond(chatID, replyTo string, evChan <-chan gateway.AgentEvent) {
+	var response strings.Builder
+	lastTyping := time.Now()
+
+	for event := range evChan {
+		if conn, err := b.connection(); err == nil {
+			b.forward(conn, chatID, event)
+		}
+
+		switch e := event.(type) {
+		case gateway.EventTextDelta:
+			response.WriteString(e.Delta)
+
+		case gateway.EventToolStart:
+			if time.Since(lastTyping) > typingRefresh {
+				b.typing(chatID)
+				lastTyping = time.Now()
+			}
+
+		case gateway.EventAgentEnd:
+			finalText := e.FinalText
+			if finalText == "" {
+				finalText = response.String()
+			}
+			if finalText == "" {
+				finalText = "(No response)"
+			}
+			if _, err := b.deliver(chatID, finalText, replyTo, false); err != nil {
+				L_error("bridge: failed to send reply", "bridge", b.Name(), "chat", chatID, "error", err)
+			}
+
+		case gateway.EventAgentMerged:
+			// Reply arrives through the run the message was merged into
+			L_debug("bridge: message merged into running turn", "bridge", b.Name(), "mode", e.Mode)
+			b.sendPlain(chatID, e.Notice())
+
+		case gateway.EventAgentError:
+			L_error("bridge: agent error", "bridge", b.Name(), "error", e.Error)
+			b.sendPlain(chatID, fmt.Sprintf("Error: %s", e.Error))
+		}
+	}
+}
+
+// forward sends an agent event to a bridge that negotiated streaming
+func (b *Bot) forward(conn *api.Conn, chatID string, event gateway.AgentEvent) {
+	if !conn.Has(api.CapStream) {
+		return
+	}
+	ev := &api.Event{ChatID: chatID}
+	switch e := event.(type) {
+	case gateway.EventTextDelta:
+		ev.Event, ev.Text = api.EventText, e.Delta
+	case gateway.EventThinkingDelta:
+		ev.Event, ev.Text = api.EventThinking, e.Delta
+	case gateway.EventToolStart:
+		ev.Event, ev.Tool, ev.Input = api.EventToolStart, e.ToolName, e.Input
+	case gateway.EventToolEnd:
+		ev.Event, ev.Tool, ev.Error = api.EventToolEnd, e.ToolName, e.Error
+	case gateway.EventAgentEnd:
+		ev.Event, ev.Text = api.EventEnd, e.FinalText
+	case gateway.EventAgentError:
+		ev.Event, ev.Text = api.EventError, e.Error
+	default:
+		return
+	}
+	if err := conn.Event(ev); err != nil {
+		L_trace("bridge: event not delivered", "bridge", b.Name(), "error", err)
+	}
+}
+
+// saveMedia stores a message's attachments and returns content blocks:
+// images and audio for the model, other files as their saved path
+func (b *Bot) saveMedia(msg *api.Message, u *user.User) []itypes.ContentBlock {
+	if len(msg.Media) == 0 {
+		return nil
+	}
+	var store *media.MediaStore
+	if b.gateway != nil {
+		store = b.gateway.MediaStore()
+	}
+	if store == nil {
+		L_warn("bridge: no media store, attachments dropped", "bridge", b.Name(), "count", len(msg.Media))
+		return nil
+	}
+
+	var blocks []itypes.ContentBlock
+	for i, m := range msg.Media {
+		if i == maxMedia {
+			L_warn("bridge: too many attachments, rest dropped", "bridge", b.Name(), "total", len(msg.Media))
+			break
+		}
+		if len(m.Data) == 0 {
+			L_debug("bridge: attachment without data ignored", "bridge", b.Name(), "filename", m.Filename)
+			continue
+		}
+
+		mimeType := strings.ToLower(strings.TrimSpace(strings.Split(m.MimeType, ";")[0]))
+		if mimeType == "" || mimeType == "application/octet-stream" {
+			if detected := media.DetectMIME(m.Data); detected != "" {
+				mimeType = strings.TrimSpace(strings.Split(detected, ";")[0])
+			}
+		}
+
+		mediaType := "document"
+		switch {
+		case m.Type == api.MediaImage || strings.HasPrefix(mimeType, "image/"):
+			mediaType = "image"
+		case m.Type == api.MediaAudio || strings.HasPrefix(mimeType, "audio/"):
+			mediaType = "voice"
+		}
+
+		absPath, _, err := store.SaveUpload(m.Data, mimeToExt(mimeType, m.Filename), media.UploadContext{
+			Channel:       b.Name(),
+			User:          u,
+			ChannelUserID: msg.UserID,
+			ChatID:        msg.ChatID,
+			MediaType:     mediaType,
+			Caption:       m.Caption,
+		})
+		if err != nil {
+			L_warn("bridge: failed to save attachment", "bridge", b.Name(), "filename", m.Filename, "error", err)
+			continue
+		}
+
+		switch mediaType {
+		case "image":
+			blocks = append(blocks, itypes.ContentBlock{Type: "image", FilePath: absPath, MimeType: mimeType, Source: b.Name()})
+		case "voice":
+			blocks = append(blocks, itypes.ContentBlock{Type: "audio", FilePath: absPath, MimeType: mimeType, Source: b.Name()})
+		default:
+			name := m.Filename
+			if name == "" {
+				name = "unnamed"
+			}
+			blocks = append(blocks, itypes.ContentBlock{
+				Type: "text",
+				Text: fmt.Sprintf("[Attachment %s (%s, %d bytes) saved to: %s]", name, mimeType, len(m.Data), absPath),
+			})
+		}
+	}
+	return blocks
+}
+
+// --- Outgoing messages ---
+
+// deliver sends a reply, splitting out inline media references, and
+// returns the ID of the first message sent
+func (b *Bot) deliver(chatID, text, replyTo string, ghostwrite bool) (string, error) {
+	if !media.ContainsMediaRefs(text) {
+		return b.sendText(chatID, text, replyTo, ghostwrite)
+	}
+
+	var mediaRoot string
+	if b.gateway != nil && b.gateway.MediaStore() != nil {
+		mediaRoot = b.gateway.MediaStore().BaseDir()
+	}
+
+	firstID := ""
+	remember := func(id string) {
+		if firstID == "" {
+			firstID = id
+			replyTo = ""
+		}
+	}
+	for _, seg := range media.SplitMediaSegments(text) {
+		if !seg.IsMedia {
+			if strings.TrimSpace(seg.Text) == "" {
+				continue
+			}
+			id, err := b.sendText(chatID, seg.Text, replyTo, ghostwrite)
+			if err != nil {
+				return firstID, err
+			}
+			remember(id)
+			continue
+		}
+
+		if strings.HasPrefix(seg.Mime, "error/") {
+			errType := strings.TrimPrefix(seg.Mime, "error/")
+			b.sendPlain(chatID, fmt.Sprintf("[Media %s: %s]", errType, seg.Path))
+			continue
+		}
+
+		absPath, err := media.ResolveMediaPath(mediaRoot, seg.Path)
+		if err != nil {
+			L_warn("bridge: failed to resolve media path", "bridge", b.Name(), "path", seg.Path, "error", err)
+			continue
+		}
+		id, err := b.sendMediaFile(chatID, absPath, "")
+		if err != nil {
+			L_warn("bridge: failed to send media", "bridge", b.Name(), "path", absPath, "error", err)
+			continue
+		}
+		remember(id)
+	}
+	return firstID, nil
+}
+
+// sendText sends a markdown message and returns its ID
+func (b *Bot) sendText(chatID, text, replyTo string, ghostwrite bool) (string, error) {
+	conn, err := b.connection()
+	if err != nil {
+		return "", err
+	}
+	return conn.Send(b.ctx, &api.Send{ChatID: chatID, Text: text, ReplyTo: replyTo, Ghostwrite: ghostwrite})
+}
+
+// sendPlain sends a short message, logging failures
+func (b *Bot) sendPlain(chatID, text string) {
+	if _, err := b.sendText(chatID, text, "", false); err != nil {
+		L_error("bridge: failed to send message", "bridge", b.Name(), "chat", chatID, "error", err)
+	}
+}
+
+// sendMediaFile sends a file with an optional caption and returns the message ID
+func (b *Bot) sendMediaFile(chatID, filePath, caption string) (string, error) {
+	conn, err := b.connection()
+	if err != nil {
+		return "", err
+	}
+
+	mimeType, _ := media.DetectMimeType(filePath)
+	mediaType := api.MediaFile
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		mediaType = api.MediaImage
+	case strings.HasPrefix(mimeType, "audio/"):
+		mediaType = api.MediaAudio
+	}
+
+	return conn.Send(b.ctx, &api.Send{
+		ChatID: chatID,
+		Media: &api.Media{
+			Type:     mediaType,
+			MimeType: mimeType,
+			Filename: filepath.Base(filePath),
+			Path:     filePath,
+			Caption:  caption,
+		},
+	})
+}
+
+// typing shows the typing indicator if the bridge supports it
+func (b *Bot) typing(chatID string) {
+	conn, err := b.connection()
+	if err != nil {
+		return
+	}
+	if err := conn.Typing(chatID); err != nil {
+		L_trace("bridge: typing failed", "bridge", b.Name(), "chat", chatID, "error", err)
+	}
+}
+
+// getSessionKey returns the session key for a user
+func (b *Bot) getSessionKey(u *user.User) string {
+	if u.Role == "owner" {
+		return session.PrimarySession
+	}
+	return fmt.Sprintf("user:%s", u.ID)
+}
+
+// canUserUseCommands checks if the user has permission to use commands
+func (b *Bot) canUserUseCommands(u *user.User) bool {
+	if u == nil {
+		return false
+	}
+	resolvedRole, err := b.users.ResolveUserRole(u)
+	if err != nil {
+		L_warn("bridge: failed to resolve role for command check", "user", u.Name, "error", err)
+		return false
+	}
+	return resolvedRole.CanUseCommands()
+}
+
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
+
+// mimeToExt picks a file extension for a saved attachment, preferring the sender's file name
+func mimeToExt(mimeType, filename string) string {
+	if ext := filepath.Ext(filename); ext != "" && len(ext) <= 6 {
+		return strings.ToLower(ext)
+	}
+	switch mimeType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "audio/ogg":
+		return ".ogg"
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/mp4":
+		return ".m4a"
+	case "application/pdf":
+		return ".pdf"
+	case "text/plain":
+		return ".txt"
+	default:
+		return ".bin"
+	}
+}
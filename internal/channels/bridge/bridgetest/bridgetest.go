@@ -0,0 +1,304 @@
+// Package bridgetest provides a fake bridge for tests and development. It
+// speaks the bridge side of the protocol over stdin/stdout (Serve) or a
+// WebSocket (NewServer): it answers hello with its capabilities and every
+// request with success. Incoming messages are scripted with Deliver; what
+// GoClaw sent is read back with Frames and WaitFrames.
+package bridgetest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/roelfdiedericks/goclaw/internal/channels/bridge/api"
+)
+
+// Frame is a frame the bridge received from GoClaw
+type Frame struct {
+	Type string
+	Raw  json.RawMessage
+}
+
+// Decode unmarshals the frame into v (e.g. *api.Send)
+func (f Frame) Decode(v any) error {
+	return json.Unmarshal(f.Raw, v)
+}
+
+// Bridge is a fake bridge
+type Bridge struct {
+	name string
+	caps []string
+
+	// Echo makes the bridge answer each send by delivering its text back
+	// as "echo: <text>" from the chat's user (the chat ID)
+	Echo bool
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	hello   *api.Hello
+	frames  []Frame
+	write   func([]byte) error // Current connection, nil when disconnected
+	nextID  int
+	failing map[string]string // Frame type -> error for the next request
+}
+
+// New creates a fake bridge announcing the given capabilities
+func New(name string, capabilities ...string) *Bridge {
+	b := &Bridge{name: name, caps: capabilities, failing: make(map[string]string)}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+// Serve speaks the protocol over r and w, one frame per line, until r ends
+func (b *Bridge) Serve(r io.Reader, w io.Writer) error {
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 64*1024), api.MaxFrameSize)
+	var wmu sync.Mutex
+	return b.serve(func() ([]byte, error) {
+		for lines.Scan() {
+			if line := strings.TrimSpace(lines.Text()); line != "" {
+				return []byte(line), nil
+			}
+		}
+		if err := lines.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}, func(data []byte) error {
+		wmu.Lock()
+		defer wmu.Unlock()
+		_, err := w.Write(append(data, '\n'))
+		return err
+	})
+}
+
+// serve runs one connection
+func (b *Bridge) serve(read func() ([]byte, error), write func([]byte) error) error {
+	data, err := read()
+	if err != nil {
+		return err
+	}
+	var hello api.Hello
+	if err := json.Unmarshal(data, &hello); err != nil || hello.Type != api.TypeHello {
+		return fmt.Errorf("expected hello, got %s", data)
+	}
+	reply, _ := json.Marshal(&api.Hello{Type: api.TypeHello, Protocol: api.ProtocolVersion, Name: b.name, Capabilities: b.caps})
+	if err := write(reply); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.hello = &hello
+	b.write = write
+	b.cond.Broadcast()
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.write = nil
+		b.mu.Unlock()
+	}()
+
+	for {
+		data, err := read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		var req struct {
+			Type   string `json:"type"`
+			ID     string `json:"id"`
+			ChatID string `json:"chatId"`
+			Text   string `json:"text"`
+		}
+		if err := json.Unmarshal(data, &req); err != nil {
+			return fmt.Errorf("invalid frame: %w", err)
+		}
+
+		b.mu.Lock()
+		b.frames = append(b.frames, Frame{Type: req.Type, Raw: append(json.RawMessage(nil), data...)})
+		b.cond.Broadcast()
+		failure, fail := b.failing[req.Type]
+		delete(b.failing, req.Type)
+		b.nextID++
+		n := b.nextID
+		b.mu.Unlock()
+
+		if req.ID == "" {
+			continue // typing and event frames aren't answered
+		}
+		resp := api.Response{Type: api.TypeResponse, ID: req.ID, OK: !fail, Error: failure}
+		if req.Type == api.TypeSend && !fail {
+			resp.MessageID = fmt.Sprintf("m%d", n)
+		}
+		out, _ := json.Marshal(&resp)
+		if err := write(out); err != nil {
+			return err
+		}
+
+		if b.Echo && req.Type == api.TypeSend && !fail {
+			echo, _ := json.Marshal(&api.Message{
+				Type: api.TypeMessage, ID: fmt.Sprintf("e%d", n),
+				ChatID: req.ChatID, UserID: req.ChatID, Text: "echo: " + req.Text,
+			})
+			if err := write(echo); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Hello returns GoClaw's hello, or nil before the handshake
+func (b *Bridge) Hello() *api.Hello {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.hello
+}
+
+// Deliver sends an incoming message to GoClaw
+func (b *Bridge) Deliver(msg api.Message) error {
+	msg.Type = api.TypeMessage
+	data, err := json.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	write := b.write
+	b.mu.Unlock()
+	if write == nil {
+		return errors.New("bridgetest: not connected")
+	}
+	return write(data)
+}
+
+// Fail makes the next request of a type (send, edit, ...) fail with msg
+func (b *Bridge) Fail(frameType, msg string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failing[frameType] = msg
+}
+
+// Frames returns the received frames of a type, or all frames for ""
+func (b *Bridge) Frames(frameType string) []Frame {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.framesLocked(frameType)
+}
+
+func (b *Bridge) framesLocked(frameType string) []Frame {
+	var out []Frame
+	for _, f := range b.frames {
+		if frameType == "" || f.Type == frameType {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// WaitFrames waits until at least n frames of a type arrived and returns them
+func (b *Bridge) WaitFrames(frameType string, n int, timeout time.Duration) ([]Frame, error) {
+	deadline := time.Now().Add(timeout)
+	timer := time.AfterFunc(timeout, func() {
+		b.mu.Lock()
+		b.cond.Broadcast()
+		b.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		frames := b.framesLocked(frameType)
+		if len(frames) >= n {
+			return frames, nil
+		}
+		if !time.Now().Before(deadline) {
+			return frames, fmt.Errorf("bridgetest: got %d %q frames, want %d", len(frames), frameType, n)
+		}
+		b.cond.Wait()
+	}
+}
+
+// --- WebSocket ---
+
+// Server serves a fake bridge over a WebSocket on a loopback port
+type Server struct {
+	*Bridge
+	srv      *httptest.Server
+	upgrader websocket.Upgrader
+	token    string
+
+	connMu sync.Mutex
+	conns  map[*websocket.Conn]bool
+}
+
+// NewServer starts a WebSocket server for a new fake bridge. A non-empty
+// token is required as a Bearer Authorization header.
+func NewServer(name, token string, capabilities ...string) *Server {
+	s := &Server{Bridge: New(name, capabilities...), token: token, conns: make(map[*websocket.Conn]bool)}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// URL returns the ws:// URL of the server
+func (s *Server) URL() string {
+	return "ws" + strings.TrimPrefix(s.srv.URL, "http")
+}
+
+// Disconnect drops the current connections, as a crashing bridge would
+func (s *Server) Disconnect() {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	for ws := range s.conns {
+		_ = ws.Close()
+	}
+}
+
+// Close stops the server and drops connections
+func (s *Server) Close() {
+	s.Disconnect()
+	s.srv.Close()
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.connMu.Lock()
+	s.conns[ws] = true
+	s.connMu.Unlock()
+	defer func() {
+		s.connMu.Lock()
+		delete(s.conns, ws)
+		s.connMu.Unlock()
+		_ = ws.Close()
+	}()
+
+	var wmu sync.Mutex
+	_ = s.serve(func() ([]byte, error) {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			return nil, io.EOF
+		}
+		return data, nil
+	}, func(data []byte) error {
+		wmu.Lock()
+		defer wmu.Unlock()
+		return ws.WriteMessage(websocket.TextMessage, data)
+	})
+}
@@ -0,0 +1,233 @@
+// Package config defines the bridge channel configuration.
+// Separate package to avoid import cycles with gateway.
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/roelfdiedericks/goclaw/internal/bus"
+	"github.com/roelfdiedericks/goclaw/internal/channels/bridge/api"
+	"github.com/roelfdiedericks/goclaw/internal/config/forms"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// Config holds the bridge channels
+type Config struct {
+	Enabled bool           `json:"enabled"`
+	Bridges []BridgeConfig `json:"bridges,omitempty"`
+}
+
+// BridgeConfig configures one bridge. Each bridge runs as its own channel,
+// named after the bridge, and reaches its program either over the stdio of
+// a child process (command) or over a WebSocket (url).
+type BridgeConfig struct {
+	Name           string            `json:"name"`                     // Channel name, also the key in users' identities (e.g. "signal")
+	Command        string            `json:"command,omitempty"`        // Program to run; frames on its stdin/stdout
+	Args           []string          `json:"args,omitempty"`           // Arguments for command
+	Env            map[string]string `json:"env,omitempty"`            // Extra environment for command
+	Dir            string            `json:"dir,omitempty"`            // Working directory for command
+	URL            string            `json:"url,omitempty"`            // ws:// or wss:// URL of a bridge serving the protocol
+	Token          string            `json:"token,omitempty"`          // Sent as a Bearer token when connecting to url
+	RequireMention *bool             `json:"requireMention,omitempty"` // In groups, only answer when mentioned (default true)
+}
+
+// reserved are channel and source names a bridge can't take
+var reserved = []string{"telegram", "whatsapp", "matrix", "discord", "email", "http", "tui", "cron", "heartbeat", "guidance", "cert", "bridge"}
+
+// validName is what a bridge name may look like
+var validName = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+// MentionRequired reports whether groups need a mention (default true)
+func (b *BridgeConfig) MentionRequired() bool {
+	return b.RequireMention == nil || *b.RequireMention
+}
+
+// Check validates one bridge's settings
+func (b *BridgeConfig) Check() error {
+	if !validName.MatchString(b.Name) {
+		return fmt.Errorf("bridge name %q must be lowercase letters, digits, - or _, starting with a letter", b.Name)
+	}
+	if slices.Contains(reserved, b.Name) {
+		return fmt.Errorf("bridge name %q is reserved", b.Name)
+	}
+	switch {
+	case b.Command != "" && b.URL != "":
+		return fmt.Errorf("bridge %s: set either command or url, not both", b.Name)
+	case b.Command == "" && b.URL == "":
+		return fmt.Errorf("bridge %s: command or url is required", b.Name)
+	case b.URL != "":
+		u, err := url.Parse(b.URL)
+		if err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+			return fmt.Errorf("bridge %s: url must be a ws:// or wss:// URL", b.Name)
+		}
+	}
+	return nil
+}
+
+// Check validates the settings: every bridge, and that names are unique
+func (c *Config) Check() error {
+	seen := make(map[string]bool)
+	for i := range c.Bridges {
+		b := &c.Bridges[i]
+		if err := b.Check(); err != nil {
+			return err
+		}
+		if seen[b.Name] {
+			return fmt.Errorf("bridge name %q is used twice", b.Name)
+		}
+		seen[b.Name] = true
+	}
+	return nil
+}
+
+// Open starts the bridge's program or connects to its WebSocket. Lines the
+// program writes to stderr are logged.
+func Open(ctx context.Context, b *BridgeConfig) (api.Transport, error) {
+	if b.URL != "" {
+		return api.DialWebSocket(ctx, b.URL, b.Token)
+	}
+	name := b.Name
+	return api.StartProcess(api.ProcessSpec{Command: b.Command, Args: b.Args, Env: b.Env, Dir: b.Dir}, func(line string) {
+		logging.L_debug("bridge: stderr", "bridge", name, "line", line)
+	})
+}
+
+// ConfigFormDef returns the form definition for editing bridge config
+func ConfigFormDef() forms.FormDef {
+	return forms.FormDef{
+		Title:       "Bridges",
+		Description: "External programs that connect GoClaw to other networks (Signal, SMS, ...) over the bridge protocol. Bridges are listed under channels.bridge.bridges in goclaw.json.",
+		Sections: []forms.Section{
+			{
+				Title: "Bridges",
+				Fields: []forms.Field{
+					{Name: "enabled", Title: "Enabled", Desc: "Run the configured bridges", Type: forms.Toggle},
+				},
+			},
+		},
+		Actions: []forms.ActionDef{
+			{
+				Name:  "test",
+				Label: "Test Bridges",
+				Desc:  "Connect to each bridge and show what it supports",
+			},
+			{
+				Name:  "apply",
+				Label: "Apply Now",
+				Desc:  "Apply changes to running bridges (requires gateway)",
+			},
+		},
+	}
+}
+
+const configPath = "channels.bridge"
+
+// RegisterCommands registers bridge config command handlers
+func RegisterCommands() {
+	bus.RegisterCommand(configPath, "test", handleTest)
+	bus.RegisterCommand(configPath, "apply", handleApply)
+}
+
+// UnregisterCommands unregisters bridge config command handlers
+func UnregisterCommands() {
+	bus.UnregisterComponent(configPath)
+}
+
+func handleApply(cmd bus.Command) bus.CommandResult {
+	cfg, ok := cmd.Payload.(*Config)
+	if !ok {
+		return bus.CommandResult{
+			Error:   fmt.Errorf("invalid payload type: expected *Config, got %T", cmd.Payload),
+			Message: "Internal error: invalid config type",
+		}
+	}
+	if err := cfg.Check(); err != nil {
+		return bus.CommandResult{Error: err, Message: err.Error()}
+	}
+
+	logging.L_info("bridge: config applied", "enabled", cfg.Enabled, "bridges", len(cfg.Bridges))
+	bus.PublishEvent(configPath+".config.applied", cfg)
+
+	return bus.CommandResult{
+		Success: true,
+		Message: "Config applied - bridges will restart",
+	}
+}
+
+// handleTest connects to each bridge and reports its capabilities
+func handleTest(cmd bus.Command) bus.CommandResult {
+	cfg, ok := cmd.Payload.(*Config)
+	if !ok {
+		return bus.CommandResult{
+			Error:   fmt.Errorf("invalid payload type"),
+			Message: "Internal error: invalid config type",
+		}
+	}
+
+	if err := cfg.Check(); err != nil {
+		return bus.CommandResult{Error: err, Message: err.Error()}
+	}
+	if len(cfg.Bridges) == 0 {
+		return bus.CommandResult{Error: fmt.Errorf("no bridges configured"), Message: "No bridges configured in channels.bridge.bridges"}
+	}
+
+	var lines []string
+	var firstErr error
+	for i := range cfg.Bridges {
+		b := &cfg.Bridges[i]
+		hello, caps, err := TestBridge(b)
+		if err != nil {
+			logging.L_warn("bridge: test failed", "bridge", b.Name, "error", err)
+			lines = append(lines, fmt.Sprintf("%s: %s", b.Name, err))
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		logging.L_info("bridge: test successful", "bridge", b.Name, "remote", hello.Name, "capabilities", caps)
+		lines = append(lines, fmt.Sprintf("%s: connected to %s (%s)", b.Name, describe(hello), strings.Join(caps, ", ")))
+	}
+
+	return bus.CommandResult{
+		Success: firstErr == nil,
+		Error:   firstErr,
+		Message: strings.Join(lines, "\n"),
+	}
+}
+
+// TestBridge connects to a bridge, completes the handshake and disconnects.
+// It returns the bridge's hello and the negotiated capabilities.
+func TestBridge(b *BridgeConfig) (*api.Hello, []string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), api.HandshakeTimeout)
+	defer cancel()
+
+	t, err := Open(ctx, b)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := api.Connect(ctx, t, b.Name, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = conn.Close() }()
+
+	hello := conn.Remote()
+	return &hello, conn.Capabilities(), nil
+}
+
+// describe names a bridge by its hello
+func describe(h *api.Hello) string {
+	name := h.Name
+	if name == "" {
+		name = "bridge"
+	}
+	if h.Version != "" {
+		name += " " + h.Version
+	}
+	return name
+}
@@ -0,0 +1,83 @@
+package bridge
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// MessageChannelAdapter adapts a bridge Bot to the MessageChannel interface.
+// Chat and message IDs are whatever the bridge uses. Editing, deleting and
+// reacting work if the bridge announced the capability.
+type MessageChannelAdapter struct {
+	bot       *Bot
+	mediaBase string
+}
+
+// NewMessageChannelAdapter creates a new adapter for a bridge
+func NewMessageChannelAdapter(bot *Bot, mediaBase string) *MessageChannelAdapter {
+	return &MessageChannelAdapter{
+		bot:       bot,
+		mediaBase: mediaBase,
+	}
+}
+
+// SendText sends a markdown message to a chat on the bridge
+func (a *MessageChannelAdapter) SendText(chatID string, text string) (string, error) {
+	return a.bot.sendText(chatID, text, "", false)
+}
+
+// SendMedia sends a file to a chat on the bridge
+func (a *MessageChannelAdapter) SendMedia(chatID string, filePath string, caption string) (string, error) {
+	absPath := a.resolveMediaPath(filePath)
+	L_debug("bridge: sending media", "bridge", a.bot.Name(), "chat", chatID, "path", absPath)
+	return a.bot.sendMediaFile(chatID, absPath, caption)
+}
+
+// EditMessage replaces the text of a sent message
+func (a *MessageChannelAdapter) EditMessage(chatID string, messageID string, text string) error {
+	conn, err := a.bot.connection()
+	if err != nil {
+		return err
+	}
+	if err := conn.Edit(a.bot.ctx, chatID, messageID, text); err != nil {
+		return fmt.Errorf("failed to edit message: %w", err)
+	}
+	return nil
+}
+
+// DeleteMessage deletes a message
+func (a *MessageChannelAdapter) DeleteMessage(chatID string, messageID string) error {
+	conn, err := a.bot.connection()
+	if err != nil {
+		return err
+	}
+	if err := conn.Delete(a.bot.ctx, chatID, messageID); err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+	L_debug("bridge: deleted message", "bridge", a.bot.Name(), "chat", chatID, "message", messageID)
+	return nil
+}
+
+// React adds a reaction emoji to a message
+func (a *MessageChannelAdapter) React(chatID string, messageID string, emoji string) error {
+	conn, err := a.bot.connection()
+	if err != nil {
+		return err
+	}
+	if err := conn.React(a.bot.ctx, chatID, messageID, emoji); err != nil {
+		return fmt.Errorf("failed to add reaction: %w", err)
+	}
+	L_debug("bridge: reaction sent", "bridge", a.bot.Name(), "chat", chatID, "message", messageID, "emoji", emoji)
+	return nil
+}
+
+func (a *MessageChannelAdapter) resolveMediaPath(path string) string {
+	if strings.HasPrefix(path, "./media/") {
+		subpath := strings.TrimPrefix(path, "./media/")
+		return filepath.Join(a.mediaBase, subpath)
+	}
+	return path
+}
@@ -0,0 +1,344 @@
+// Package api is a small client for the Discord bot API, covering what the
+// Discord channel needs: the REST endpoints for messages, edits, reactions,
+// typing, DMs, attachments, slash commands and interaction responses, and a
+// Gateway connection (gateway.go) that delivers events with heartbeats,
+// resume and reconnect.
+//
+// BaseURL is configurable so tests can point it at discordtest.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the Discord REST API root
+	DefaultBaseURL = "https://discord.com/api/v10"
+	// APIVersion is the gateway protocol version matching DefaultBaseURL
+	APIVersion = 10
+
+	// MaxMessageLength is the character limit of a message's content
+	MaxMessageLength = 2000
+	// MaxDownloadSize limits attachment downloads
+	MaxDownloadSize = 50 << 20
+
+	// maxRateLimitRetries is how often a request is retried after a 429
+	maxRateLimitRetries = 3
+	// maxRetryAfter caps how long a rate-limited request waits
+	maxRetryAfter = 30 * time.Second
+
+	userAgent = "DiscordBot (https://github.com/roelfdiedericks/goclaw, 1.0)"
+)
+
+// Client is a Discord REST client for one bot token
+type Client struct {
+	BaseURL string // API root, e.g. https://discord.com/api/v10
+	Token   string // Bot token (without the "Bot " prefix)
+	HTTP    *http.Client
+}
+
+// NewClient creates a client. An empty baseURL means DefaultBaseURL.
+func NewClient(baseURL, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		HTTP:    &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Error is an error response from the API
+type Error struct {
+	StatusCode int     `json:"-"`
+	Code       int     `json:"code"` // JSON error code, e.g. 50001 Missing Access
+	Message    string  `json:"message"`
+	RetryAfter float64 `json:"retry_after,omitempty"` // Seconds, on 429
+	Global     bool    `json:"global,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Code == 0 {
+		return fmt.Sprintf("discord: HTTP %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("discord: HTTP %d: %s (code %d)", e.StatusCode, e.Message, e.Code)
+}
+
+// IsStatus reports whether err is an API error with the given HTTP status
+func IsStatus(err error, status int) bool {
+	var dErr *Error
+	return errors.As(err, &dErr) && dErr.StatusCode == status
+}
+
+// do sends a JSON request and decodes the JSON response into out (if not nil).
+// Rate-limited requests are retried after the delay the server asks for.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("discord: encode request: %w", err)
+		}
+	}
+	return c.send(ctx, method, path, "application/json", payload, out)
+}
+
+// send issues a request with a prepared body, retrying on 429
+func (c *Client) send(ctx context.Context, method, path, contentType string, payload []byte, out any) error {
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if payload != nil {
+			reader = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+		if err != nil {
+			return fmt.Errorf("discord: %w", err)
+		}
+		if payload != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("Authorization", "Bot "+c.Token)
+		req.Header.Set("User-Agent", userAgent)
+
+		resp, err := c.HTTP.Do(req)
+		if err != nil {
+			return fmt.Errorf("discord: %s %s: %w", method, path, err)
+		}
+		err = decodeResponse(resp, out)
+		var dErr *Error
+		if errors.As(err, &dErr) && dErr.StatusCode == http.StatusTooManyRequests && attempt < maxRateLimitRetries {
+			wait := min(time.Duration(dErr.RetryAfter*float64(time.Second)), maxRetryAfter)
+			if wait <= 0 {
+				wait = time.Second
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+		return err
+	}
+}
+
+// decodeResponse decodes a JSON response or returns the API's error
+func decodeResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		dErr := &Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(data, dErr) != nil || dErr.Message == "" {
+			dErr.Message = strings.TrimSpace(string(data))
+		}
+		if dErr.RetryAfter == 0 {
+			if secs, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil {
+				dErr.RetryAfter = secs
+			}
+		}
+		return dErr
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("discord: decode response: %w", err)
+	}
+	return nil
+}
+
+// CurrentUser returns the bot's own user, which also checks the token
+func (c *Client) CurrentUser(ctx context.Context) (*User, error) {
+	var u User
+	if err := c.do(ctx, http.MethodGet, "/users/@me", nil, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// CurrentApplication returns the bot's application
+func (c *Client) CurrentApplication(ctx context.Context) (*Application, error) {
+	var app Application
+	if err := c.do(ctx, http.MethodGet, "/applications/@me", nil, &app); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// GatewayBot returns the gateway URL to connect to
+func (c *Client) GatewayBot(ctx context.Context) (*GatewayInfo, error) {
+	var info GatewayInfo
+	if err := c.do(ctx, http.MethodGet, "/gateway/bot", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// GetChannel returns a channel, thread or DM by ID
+func (c *Client) GetChannel(ctx context.Context, channelID string) (*Channel, error) {
+	var ch Channel
+	if err := c.do(ctx, http.MethodGet, "/channels/"+url.PathEscape(channelID), nil, &ch); err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+// CreateDM opens (or returns the existing) DM channel with a user
+func (c *Client) CreateDM(ctx context.Context, userID string) (*Channel, error) {
+	var ch Channel
+	if err := c.do(ctx, http.MethodPost, "/users/@me/channels", map[string]string{"recipient_id": userID}, &ch); err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+// SendMessage posts a message to a channel
+func (c *Client) SendMessage(ctx context.Context, channelID string, msg *MessageSend) (*Message, error) {
+	var m Message
+	if err := c.do(ctx, http.MethodPost, "/channels/"+url.PathEscape(channelID)+"/messages", msg, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// SendFile posts a message with one attached file
+func (c *Client) SendFile(ctx context.Context, channelID string, msg *MessageSend, filename, contentType string, data []byte) (*Message, error) {
+	if msg == nil {
+		msg = &MessageSend{}
+	}
+	withFile := *msg
+	withFile.Attachments = []Attachment{{ID: "0", Filename: filename}}
+	payloadJSON, err := json.Marshal(&withFile)
+	if err != nil {
+		return nil, fmt.Errorf("discord: encode request: %w", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="payload_json"`)
+	header.Set("Content-Type", "application/json")
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return nil, fmt.Errorf("discord: %w", err)
+	}
+	_, _ = part.Write(payloadJSON)
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header = make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="files[0]"; filename=%q`, filename))
+	header.Set("Content-Type", contentType)
+	if part, err = mw.CreatePart(header); err != nil {
+		return nil, fmt.Errorf("discord: %w", err)
+	}
+	_, _ = part.Write(data)
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("discord: %w", err)
+	}
+
+	var m Message
+	path := "/channels/" + url.PathEscape(channelID) + "/messages"
+	if err := c.send(ctx, http.MethodPost, path, mw.FormDataContentType(), body.Bytes(), &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// EditMessage replaces the content of one of the bot's messages
+func (c *Client) EditMessage(ctx context.Context, channelID, messageID string, msg *MessageSend) (*Message, error) {
+	var m Message
+	path := "/channels/" + url.PathEscape(channelID) + "/messages/" + url.PathEscape(messageID)
+	if err := c.do(ctx, http.MethodPatch, path, msg, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// DeleteMessage deletes a message (the bot's own, or others' with Manage Messages)
+func (c *Client) DeleteMessage(ctx context.Context, channelID, messageID string) error {
+	path := "/channels/" + url.PathEscape(channelID) + "/messages/" + url.PathEscape(messageID)
+	return c.do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+// AddReaction reacts to a message. emoji is a Unicode emoji or "name:id"
+// for a custom one.
+func (c *Client) AddReaction(ctx context.Context, channelID, messageID, emoji string) error {
+	path := "/channels/" + url.PathEscape(channelID) + "/messages/" + url.PathEscape(messageID) +
+		"/reactions/" + url.PathEscape(emoji) + "/@me"
+	return c.do(ctx, http.MethodPut, path, nil, nil)
+}
+
+// TriggerTyping shows the typing indicator for about 10 seconds
+func (c *Client) TriggerTyping(ctx context.Context, channelID string) error {
+	return c.do(ctx, http.MethodPost, "/channels/"+url.PathEscape(channelID)+"/typing", nil, nil)
+}
+
+// BulkOverwriteCommands replaces the application's global slash commands
+func (c *Client) BulkOverwriteCommands(ctx context.Context, applicationID string, commands []ApplicationCommand) ([]ApplicationCommand, error) {
+	var out []ApplicationCommand
+	path := "/applications/" + url.PathEscape(applicationID) + "/commands"
+	if err := c.do(ctx, http.MethodPut, path, commands, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RespondInteraction sends the initial response to an interaction. It must
+// happen within 3 seconds; use ResponseDeferredChannelMessage for slower work
+// and follow up with EditInteractionResponse.
+func (c *Client) RespondInteraction(ctx context.Context, interactionID, token string, resp *InteractionResponse) error {
+	path := "/interactions/" + url.PathEscape(interactionID) + "/" + url.PathEscape(token) + "/callback"
+	return c.do(ctx, http.MethodPost, path, resp, nil)
+}
+
+// EditInteractionResponse replaces the original interaction response
+func (c *Client) EditInteractionResponse(ctx context.Context, applicationID, token string, msg *MessageSend) (*Message, error) {
+	var m Message
+	path := "/webhooks/" + url.PathEscape(applicationID) + "/" + url.PathEscape(token) + "/messages/@original"
+	if err := c.do(ctx, http.MethodPatch, path, msg, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Download fetches an attachment from its CDN URL and returns the data and
+// content type. The bot token is not sent: attachment URLs are signed.
+func (c *Client) Download(ctx context.Context, rawURL string) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("discord: %w", err)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("discord: download: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, "", decodeResponse(resp, nil)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxDownloadSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("discord: download: %w", err)
+	}
+	if len(data) > MaxDownloadSize {
+		return nil, "", fmt.Errorf("discord: attachment larger than %d bytes", MaxDownloadSize)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
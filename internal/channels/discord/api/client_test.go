@@ -0,0 +1,233 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/roelfdiedericks/goclaw/internal/channels/discord/api"
+	"github.com/roelfdiedericks/goclaw/internal/channels/discord/discordtest"
+)
+
+func newServer(t *testing.T) (*discordtest.Server, *api.Client) {
+	t.Helper()
+	srv := discordtest.NewServer()
+	t.Cleanup(srv.Close)
+	return srv, api.NewClient(srv.APIURL(), srv.Token())
+}
+
+func TestCurrentUser(t *testing.T) {
+	srv, client := newServer(t)
+	ctx := context.Background()
+
+	me, err := client.CurrentUser(ctx)
+	if err != nil {
+		t.Fatalf("CurrentUser: %v", err)
+	}
+	if me.ID != srv.Bot().ID || !me.Bot {
+		t.Errorf("CurrentUser = %+v, want bot %s", me, srv.Bot().ID)
+	}
+	if app, err := client.CurrentApplication(ctx); err != nil || app.ID != srv.ApplicationID() {
+		t.Errorf("CurrentApplication = %+v, %v", app, err)
+	}
+
+	bad := api.NewClient(srv.APIURL(), "wrong")
+	if _, err := bad.CurrentUser(ctx); !api.IsStatus(err, http.StatusUnauthorized) {
+		t.Errorf("bad token: err = %v, want 401", err)
+	}
+}
+
+func TestMessagesEditsReactions(t *testing.T) {
+	srv, client := newServer(t)
+	ctx := context.Background()
+	channel := srv.CreateChannel("900", "general")
+
+	sent, err := client.SendMessage(ctx, channel, &api.MessageSend{Content: "hello"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if sent.ChannelID != channel || sent.Author == nil || sent.Author.ID != srv.Bot().ID {
+		t.Fatalf("sent = %+v", sent)
+	}
+
+	if _, err := client.EditMessage(ctx, channel, sent.ID, &api.MessageSend{Content: "hello, edited"}); err != nil {
+		t.Fatalf("EditMessage: %v", err)
+	}
+	if msgs := srv.Messages(channel); len(msgs) != 1 || msgs[0].Content != "hello, edited" || msgs[0].EditedTimestamp == "" {
+		t.Fatalf("after edit = %+v", msgs)
+	}
+
+	if err := client.AddReaction(ctx, channel, sent.ID, "👍"); err != nil {
+		t.Fatalf("AddReaction: %v", err)
+	}
+	if got := srv.Reactions(sent.ID); len(got) != 1 || got[0] != "👍" {
+		t.Errorf("reactions = %q", got)
+	}
+
+	if err := client.TriggerTyping(ctx, channel); err != nil || srv.Typing(channel) != 1 {
+		t.Errorf("typing = %d, %v", srv.Typing(channel), err)
+	}
+
+	if err := client.DeleteMessage(ctx, channel, sent.ID); err != nil {
+		t.Fatalf("DeleteMessage: %v", err)
+	}
+	if msgs := srv.Messages(channel); len(msgs) != 0 {
+		t.Errorf("after delete = %+v", msgs)
+	}
+	if err := client.DeleteMessage(ctx, channel, sent.ID); !api.IsStatus(err, http.StatusNotFound) {
+		t.Errorf("second delete: err = %v, want 404", err)
+	}
+
+	// Over-long content is rejected like Discord does
+	long := bytes.Repeat([]byte("x"), api.MaxMessageLength+1)
+	var dErr *api.Error
+	if _, err := client.SendMessage(ctx, channel, &api.MessageSend{Content: string(long)}); !errors.As(err, &dErr) || dErr.Code != 50035 {
+		t.Errorf("long message: err = %v, want 50035", err)
+	}
+}
+
+func TestChannelsAndDMs(t *testing.T) {
+	srv, client := newServer(t)
+	ctx := context.Background()
+	alice := srv.AddUser("alice")
+	parent := srv.CreateChannel("900", "general")
+	thread := srv.CreateThread(parent, "ideas")
+
+	ch, err := client.GetChannel(ctx, thread)
+	if err != nil {
+		t.Fatalf("GetChannel: %v", err)
+	}
+	if !ch.IsThread() || ch.ParentID != parent || ch.GuildID != "900" {
+		t.Errorf("thread = %+v", ch)
+	}
+	if _, err := client.GetChannel(ctx, "1"); !api.IsStatus(err, http.StatusNotFound) {
+		t.Errorf("unknown channel: err = %v", err)
+	}
+
+	dm, err := client.CreateDM(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("CreateDM: %v", err)
+	}
+	if !dm.IsDM() || len(dm.Recipients) != 1 || dm.Recipients[0].ID != alice.ID {
+		t.Errorf("dm = %+v", dm)
+	}
+	again, _ := client.CreateDM(ctx, alice.ID)
+	if again == nil || again.ID != dm.ID {
+		t.Errorf("CreateDM is not idempotent: %+v vs %+v", again, dm)
+	}
+}
+
+func TestRateLimitRetry(t *testing.T) {
+	srv, client := newServer(t)
+	channel := srv.CreateChannel("900", "general")
+
+	srv.RateLimit(2)
+	if _, err := client.SendMessage(context.Background(), channel, &api.MessageSend{Content: "eventually"}); err != nil {
+		t.Fatalf("SendMessage after rate limit: %v", err)
+	}
+
+	srv.RateLimit(10)
+	_, err := client.SendMessage(context.Background(), channel, &api.MessageSend{Content: "never"})
+	if !api.IsStatus(err, http.StatusTooManyRequests) {
+		t.Errorf("persistent rate limit: err = %v, want 429", err)
+	}
+}
+
+func TestSendFileAndDownload(t *testing.T) {
+	srv, client := newServer(t)
+	ctx := context.Background()
+	channel := srv.CreateChannel("900", "general")
+	data := []byte("\x89PNG\r\n\x1a\nnot really")
+
+	sent, err := client.SendFile(ctx, channel, &api.MessageSend{Content: "a picture"}, "pic.png", "image/png", data)
+	if err != nil {
+		t.Fatalf("SendFile: %v", err)
+	}
+	if sent.Content != "a picture" || len(sent.Attachments) != 1 {
+		t.Fatalf("sent = %+v", sent)
+	}
+	att := sent.Attachments[0]
+	if att.Filename != "pic.png" || att.ContentType != "image/png" || att.Size != len(data) {
+		t.Errorf("attachment = %+v", att)
+	}
+
+	got, contentType, err := client.Download(ctx, att.URL)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if !bytes.Equal(got, data) || contentType != "image/png" {
+		t.Errorf("Download = %q (%s)", got, contentType)
+	}
+}
+
+func TestBulkOverwriteCommands(t *testing.T) {
+	srv, client := newServer(t)
+	ctx := context.Background()
+	appID := srv.ApplicationID()
+
+	cmds := []api.ApplicationCommand{
+		{Type: api.CommandChatInput, Name: "status", Description: "Show session status"},
+		{Type: api.CommandChatInput, Name: "thinking", Description: "Set thinking level", Options: []api.CommandOption{
+			{Type: api.OptionString, Name: "level", Description: "off, low, medium or high"},
+		}},
+	}
+	registered, err := client.BulkOverwriteCommands(ctx, appID, cmds)
+	if err != nil {
+		t.Fatalf("BulkOverwriteCommands: %v", err)
+	}
+	if len(registered) != 2 || registered[0].ID == "" || len(srv.Commands()) != 2 {
+		t.Errorf("registered = %+v", registered)
+	}
+	if _, err := client.BulkOverwriteCommands(ctx, appID, []api.ApplicationCommand{{Name: "Bad Name", Description: "x"}}); !api.IsStatus(err, http.StatusBadRequest) {
+		t.Errorf("invalid name: err = %v, want 400", err)
+	}
+}
+
+func TestStringOption(t *testing.T) {
+	data := &api.InteractionData{Name: "thinking", Options: []api.InteractionOption{
+		{Name: "level", Type: api.OptionString, Value: json.RawMessage(`"high"`)},
+	}}
+	if got := data.StringOption("level"); got != "high" {
+		t.Errorf("StringOption(level) = %q", got)
+	}
+	if got := data.StringOption("missing"); got != "" {
+		t.Errorf("StringOption(missing) = %q", got)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	const bot = "42"
+	tests := []struct {
+		name string
+		msg  api.Message
+		want bool
+	}{
+		{"mention", api.Message{Content: "<@42> hi", Mentions: []api.User{{ID: bot}}}, true},
+		{"nickname mention", api.Message{Content: "hi <@!42>"}, true},
+		{"other user", api.Message{Content: "<@7> hi", Mentions: []api.User{{ID: "7"}}}, false},
+		{"reply to bot", api.Message{Content: "and then?", ReferencedMessage: &api.Message{Author: &api.User{ID: bot}}}, true},
+		{"everyone", api.Message{Content: "@everyone hi", MentionEveryone: true}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.msg.MentionsUser(bot); got != tt.want {
+			t.Errorf("%s: MentionsUser = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	strip := map[string]string{
+		"<@42> what's up?":            "what's up?",
+		"<@!42>, help me":             "help me",
+		"hey <@42> there":             "hey there",
+		"<@42>":                       "",
+		"<@7> and <@42> look":         "<@7> and look",
+		"<@42>\n```\n  indented\n```": "```\n  indented\n```",
+	}
+	for in, want := range strip {
+		if got := api.StripMention(in, bot); got != want {
+			t.Errorf("StripMention(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Gateway opcodes
+const (
+	OpDispatch       = 0
+	OpHeartbeat      = 1
+	OpIdentify       = 2
+	OpResume         = 6
+	OpReconnect      = 7
+	OpInvalidSession = 9
+	OpHello          = 10
+	OpHeartbeatACK   = 11
+)
+
+// Gateway close codes that mean reconnecting won't help
+const (
+	CloseAuthenticationFailed = 4004
+	CloseInvalidShard         = 4010
+	CloseShardingRequired     = 4011
+	CloseInvalidAPIVersion    = 4012
+	CloseInvalidIntents       = 4013
+	CloseDisallowedIntents    = 4014
+)
+
+// Close codes after which the session can't be resumed
+const (
+	closeInvalidSeq     = 4007
+	closeSessionTimeout = 4009
+)
+
+// Payload is a gateway frame
+type Payload struct {
+	Op   int             `json:"op"`
+	Data json.RawMessage `json:"d"`
+	Seq  *int64          `json:"s,omitempty"`
+	Type string          `json:"t,omitempty"`
+}
+
+// FatalError is a gateway close that reconnecting won't fix (bad token,
+// intents not enabled for the application, ...)
+type FatalError struct {
+	Code   int
+	Reason string
+}
+
+func (e *FatalError) Error() string {
+	hint := ""
+	switch e.Code {
+	case CloseAuthenticationFailed:
+		hint = " (invalid bot token)"
+	case CloseDisallowedIntents:
+		hint = " (enable the Message Content intent for the bot in the Discord Developer Portal)"
+	}
+	return fmt.Sprintf("discord: gateway closed with %d %s%s", e.Code, e.Reason, hint)
+}
+
+// errReconnect ends a connection that should be resumed on a new one
+var errReconnect = errors.New("discord: gateway asked to reconnect")
+
+// EventHandler receives dispatch events in order. It runs on the gateway's
+// read loop, so it must not block for long.
+type EventHandler func(eventType string, data json.RawMessage)
+
+// Gateway is a connection to the Discord gateway that identifies, keeps the
+// heartbeat, and reconnects (resuming the session when possible) until its
+// context is cancelled.
+type Gateway struct {
+	client  *Client
+	intents int
+	handler EventHandler
+	dialer  *websocket.Dialer
+
+	// Backoff bounds between reconnects
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	mu         sync.Mutex
+	gatewayURL string
+	sessionID  string
+	resumeURL  string
+	seq        int64
+	user       *User
+
+	connected atomic.Bool
+}
+
+// NewGateway creates a gateway connection for the client's bot token
+func NewGateway(client *Client, intents int, handler EventHandler) *Gateway {
+	return &Gateway{
+		client:     client,
+		intents:    intents,
+		handler:    handler,
+		dialer:     &websocket.Dialer{HandshakeTimeout: 30 * time.Second},
+		MinBackoff: time.Second,
+		MaxBackoff: 2 * time.Minute,
+	}
+}
+
+// Connected reports whether the gateway has an identified (or resumed) session
+func (g *Gateway) Connected() bool {
+	return g.connected.Load()
+}
+
+// User returns the bot user from READY (nil before the first READY)
+func (g *Gateway) User() *User {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.user
+}
+
+// Run connects and processes events until ctx is cancelled (returns nil) or
+// the gateway closes with a fatal code (returns a *FatalError).
+func (g *Gateway) Run(ctx context.Context) error {
+	backoff := g.MinBackoff
+	for {
+		start := time.Now()
+		err := g.runConnection(ctx)
+		g.connected.Store(false)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var fatal *FatalError
+		if errors.As(err, &fatal) {
+			return err
+		}
+
+		// A connection that lasted a while resets the backoff
+		if time.Since(start) > time.Minute {
+			backoff = g.MinBackoff
+		}
+		wait := backoff
+		if errors.Is(err, errReconnect) {
+			wait = 0
+		} else {
+			backoff = min(backoff*2, g.MaxBackoff)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+// runConnection handles one websocket connection from dial to close
+func (g *Gateway) runConnection(ctx context.Context) error {
+	g.mu.Lock()
+	resuming := g.sessionID != "" && g.resumeURL != ""
+	target := g.gatewayURL
+	if resuming {
+		target = g.resumeURL
+	}
+	g.mu.Unlock()
+
+	if target == "" {
+		info, err := g.client.GatewayBot(ctx)
+		if err != nil {
+			if IsStatus(err, 401) {
+				return &FatalError{Code: CloseAuthenticationFailed, Reason: "unauthorized"}
+			}
+			return err
+		}
+		g.mu.Lock()
+		g.gatewayURL = info.URL
+		g.mu.Unlock()
+		target = info.URL
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("discord: bad gateway URL %q: %w", target, err)
+	}
+	q := u.Query()
+	q.Set("v", strconv.Itoa(APIVersion))
+	q.Set("encoding", "json")
+	u.RawQuery = q.Encode()
+
+	conn, _, err := g.dialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("discord: gateway dial: %w", err)
+	}
+	defer conn.Close()
+
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-connCtx.Done()
+		if ctx.Err() != nil {
+			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		}
+		conn.Close()
+	}()
+
+	var writeMu sync.Mutex
+	write := func(op int, data any) error {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_ = conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteJSON(&Payload{Op: op, Data: raw})
+	}
+
+	// HELLO carries the heartbeat interval
+	var hello Payload
+	if err := conn.ReadJSON(&hello); err != nil {
+		return g.closeError(err)
+	}
+	if hello.Op != OpHello {
+		return fmt.Errorf("discord: expected HELLO, got op %d", hello.Op)
+	}
+	var helloData struct {
+		HeartbeatInterval int64 `json:"heartbeat_interval"`
+	}
+	if err := json.Unmarshal(hello.Data, &helloData); err != nil || helloData.HeartbeatInterval <= 0 {
+		return fmt.Errorf("discord: bad HELLO payload")
+	}
+	interval := time.Duration(helloData.HeartbeatInterval) * time.Millisecond
+
+	if resuming {
+		g.mu.Lock()
+		resume := map[string]any{"token": g.client.Token, "session_id": g.sessionID, "seq": g.seq}
+		g.mu.Unlock()
+		err = write(OpResume, resume)
+	} else {
+		err = write(OpIdentify, map[string]any{
+			"token":   g.client.Token,
+			"intents": g.intents,
+			"properties": map[string]string{
+				"os":      runtime.GOOS,
+				"browser": "goclaw",
+				"device":  "goclaw",
+			},
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("discord: gateway write: %w", err)
+	}
+
+	heartbeat := func() error {
+		g.mu.Lock()
+		seq := g.seq
+		g.mu.Unlock()
+		if seq == 0 {
+			return write(OpHeartbeat, nil)
+		}
+		return write(OpHeartbeat, seq)
+	}
+
+	// Heartbeat loop: the first beat is jittered, and a missing ACK since the
+	// previous beat means the connection is dead
+	var acked atomic.Bool
+	acked.Store(true)
+	go func() {
+		timer := time.NewTimer(time.Duration(rand.Float64() * float64(interval)))
+		defer timer.Stop()
+		for {
+			select {
+			case <-connCtx.Done():
+				return
+			case <-timer.C:
+			}
+			if !acked.Swap(false) {
+				// Not a normal closure, so the session stays resumable
+				_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(4000, "heartbeat timeout"), time.Now().Add(time.Second))
+				conn.Close()
+				return
+			}
+			if err := heartbeat(); err != nil {
+				conn.Close()
+				return
+			}
+			timer.Reset(interval)
+		}
+	}()
+
+	for {
+		var p Payload
+		if err := conn.ReadJSON(&p); err != nil {
+			return g.closeError(err)
+		}
+		switch p.Op {
+		case OpDispatch:
+			g.dispatch(&p)
+		case OpHeartbeat:
+			if err := heartbeat(); err != nil {
+				return fmt.Errorf("discord: gateway write: %w", err)
+			}
+		case OpHeartbeatACK:
+			acked.Store(true)
+		case OpReconnect:
+			return errReconnect
+		case OpInvalidSession:
+			var resumable bool
+			_ = json.Unmarshal(p.Data, &resumable)
+			if !resumable {
+				g.resetSession()
+			}
+			// Discord asks for a 1-5 second pause before identifying again
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second + time.Duration(rand.Float64()*float64(4*time.Second))):
+			}
+			return errReconnect
+		}
+	}
+}
+
+// dispatch records the sequence number and session details, then hands the
+// event to the handler
+func (g *Gateway) dispatch(p *Payload) {
+	g.mu.Lock()
+	if p.Seq != nil {
+		g.seq = *p.Seq
+	}
+	switch p.Type {
+	case EventReady:
+		var ready Ready
+		if err := json.Unmarshal(p.Data, &ready); err == nil {
+			g.sessionID = ready.SessionID
+			g.resumeURL = ready.ResumeGatewayURL
+			g.user = &ready.User
+		}
+		g.connected.Store(true)
+	case EventResumed:
+		g.connected.Store(true)
+	}
+	g.mu.Unlock()
+
+	if g.handler != nil {
+		g.handler(p.Type, p.Data)
+	}
+}
+
+func (g *Gateway) resetSession() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.sessionID = ""
+	g.resumeURL = ""
+	g.seq = 0
+}
+
+// closeError turns a read error into a *FatalError for fatal close codes and
+// drops the session for codes that rule out resuming
+func (g *Gateway) closeError(err error) error {
+	var ce *websocket.CloseError
+	if !errors.As(err, &ce) {
+		return fmt.Errorf("discord: gateway read: %w", err)
+	}
+	switch ce.Code {
+	case CloseAuthenticationFailed, CloseInvalidShard, CloseShardingRequired,
+		CloseInvalidAPIVersion, CloseInvalidIntents, CloseDisallowedIntents:
+		return &FatalError{Code: ce.Code, Reason: ce.Text}
+	case closeInvalidSeq, closeSessionTimeout:
+		g.resetSession()
+	}
+	return fmt.Errorf("discord: gateway closed: %w", err)
+}
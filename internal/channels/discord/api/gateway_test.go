@@ -0,0 +1,192 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/channels/discord/api"
+	"github.com/roelfdiedericks/goclaw/internal/channels/discord/discordtest"
+)
+
+type event struct {
+	Type string
+	Data json.RawMessage
+}
+
+// startGateway runs a gateway against srv and returns its event stream
+func startGateway(t *testing.T, srv *discordtest.Server, client *api.Client) (*api.Gateway, <-chan event, <-chan error) {
+	t.Helper()
+	events := make(chan event, 64)
+	gw := api.NewGateway(client, api.IntentGuildMessages|api.IntentDirectMessages|api.IntentMessageContent,
+		func(eventType string, data json.RawMessage) {
+			events <- event{Type: eventType, Data: data}
+		})
+	gw.MinBackoff = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	stopped := make(chan struct{})
+	go func() {
+		done <- gw.Run(ctx)
+		close(stopped)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-stopped
+	})
+	return gw, events, done
+}
+
+// waitEvent returns the next event of the given type, skipping others
+func waitEvent(t *testing.T, events <-chan event, eventType string) event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Type == eventType {
+				return ev
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %s", eventType)
+		}
+	}
+}
+
+func TestGatewayIdentifyAndDispatch(t *testing.T) {
+	srv, client := newServer(t)
+	srv.SetHeartbeatInterval(20 * time.Millisecond)
+	gw, events, _ := startGateway(t, srv, client)
+
+	ready := waitEvent(t, events, api.EventReady)
+	var r api.Ready
+	if err := json.Unmarshal(ready.Data, &r); err != nil || r.Application.ID != srv.ApplicationID() {
+		t.Fatalf("READY = %s, %v", ready.Data, err)
+	}
+	if u := gw.User(); u == nil || u.ID != srv.Bot().ID || !gw.Connected() {
+		t.Fatalf("after READY: user %+v, connected %v", u, gw.Connected())
+	}
+
+	alice := srv.AddUser("alice")
+	channel := srv.CreateChannel("900", "general")
+	bot := srv.Bot()
+	srv.Post(channel, alice.ID, bot.Mention()+" hello")
+
+	ev := waitEvent(t, events, api.EventMessageCreate)
+	var msg api.Message
+	if err := json.Unmarshal(ev.Data, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Author.ID != alice.ID || msg.GuildID != "900" || !msg.MentionsUser(srv.Bot().ID) {
+		t.Errorf("MESSAGE_CREATE = %+v", msg)
+	}
+
+	// Several heartbeat intervals pass without the connection being dropped
+	time.Sleep(100 * time.Millisecond)
+	if srv.Identifies() != 1 || srv.Resumes() != 0 || !gw.Connected() {
+		t.Errorf("identifies %d, resumes %d, connected %v", srv.Identifies(), srv.Resumes(), gw.Connected())
+	}
+}
+
+func TestGatewayResume(t *testing.T) {
+	srv, client := newServer(t)
+	_, events, _ := startGateway(t, srv, client)
+	waitEvent(t, events, api.EventReady)
+
+	alice := srv.AddUser("alice")
+	channel := srv.CreateChannel("900", "general")
+
+	// Events sent while disconnected are replayed after the resume
+	srv.Disconnect()
+	srv.Post(channel, alice.ID, "missed while away")
+	ev := waitEvent(t, events, api.EventMessageCreate)
+	var msg api.Message
+	_ = json.Unmarshal(ev.Data, &msg)
+	if msg.Content != "missed while away" {
+		t.Errorf("replayed message = %q", msg.Content)
+	}
+	waitEvent(t, events, api.EventResumed)
+	if srv.Resumes() != 1 || srv.Identifies() != 1 {
+		t.Errorf("identifies %d, resumes %d", srv.Identifies(), srv.Resumes())
+	}
+
+	// An op 7 reconnect request also resumes
+	srv.RequestReconnect()
+	waitEvent(t, events, api.EventResumed)
+	srv.Post(channel, alice.ID, "after reconnect")
+	ev = waitEvent(t, events, api.EventMessageCreate)
+	_ = json.Unmarshal(ev.Data, &msg)
+	if msg.Content != "after reconnect" {
+		t.Errorf("message after reconnect = %q", msg.Content)
+	}
+}
+
+func TestGatewayFatalClose(t *testing.T) {
+	srv, _ := newServer(t)
+
+	_, _, done := startGateway(t, srv, api.NewClient(srv.APIURL(), "wrong"))
+	var fatal *api.FatalError
+	select {
+	case err := <-done:
+		if !errors.As(err, &fatal) || fatal.Code != api.CloseAuthenticationFailed {
+			t.Errorf("bad token: err = %v, want 4004", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("gateway kept retrying with a bad token")
+	}
+}
+
+func TestGatewayDisallowedIntents(t *testing.T) {
+	srv, client := newServer(t)
+	srv.DisallowMessageContent()
+
+	_, _, done := startGateway(t, srv, client)
+	var fatal *api.FatalError
+	select {
+	case err := <-done:
+		if !errors.As(err, &fatal) || fatal.Code != api.CloseDisallowedIntents {
+			t.Errorf("err = %v, want 4014", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("gateway kept retrying with disallowed intents")
+	}
+}
+
+func TestInteractionResponses(t *testing.T) {
+	srv, client := newServer(t)
+	ctx := context.Background()
+	_, events, _ := startGateway(t, srv, client)
+	waitEvent(t, events, api.EventReady)
+
+	alice := srv.AddUser("alice")
+	channel := srv.CreateChannel("900", "general")
+	token := srv.Interact(channel, alice.ID, "thinking", map[string]string{"level": "high"})
+
+	ev := waitEvent(t, events, api.EventInteractionCreate)
+	var in api.Interaction
+	if err := json.Unmarshal(ev.Data, &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Token != token || in.Caller() == nil || in.Caller().ID != alice.ID || in.Data.StringOption("level") != "high" {
+		t.Fatalf("interaction = %+v", in)
+	}
+
+	deferred := &api.InteractionResponse{Type: api.ResponseDeferredChannelMessage}
+	if err := client.RespondInteraction(ctx, in.ID, in.Token, deferred); err != nil {
+		t.Fatalf("RespondInteraction: %v", err)
+	}
+	if err := client.RespondInteraction(ctx, in.ID, in.Token, deferred); err == nil {
+		t.Error("second response was accepted")
+	}
+	if _, err := client.EditInteractionResponse(ctx, in.ApplicationID, in.Token, &api.MessageSend{Content: "Thinking: high"}); err != nil {
+		t.Fatalf("EditInteractionResponse: %v", err)
+	}
+
+	responses, content := srv.InteractionResponses(token)
+	if len(responses) != 1 || responses[0].Type != api.ResponseDeferredChannelMessage || content != "Thinking: high" {
+		t.Errorf("responses = %+v, content %q", responses, content)
+	}
+}
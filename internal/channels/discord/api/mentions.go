@@ -0,0 +1,39 @@
+package api
+
+import "strings"
+
+// MentionsUser reports whether the message addresses userID: a user mention
+// (<@id> or the legacy nickname form <@!id>) or a reply to one of their
+// messages. @everyone and role mentions don't count.
+func (m *Message) MentionsUser(userID string) bool {
+	if userID == "" {
+		return false
+	}
+	for _, u := range m.Mentions {
+		if u.ID == userID {
+			return true
+		}
+	}
+	if strings.Contains(m.Content, "<@"+userID+">") || strings.Contains(m.Content, "<@!"+userID+">") {
+		return true
+	}
+	ref := m.ReferencedMessage
+	return ref != nil && ref.Author != nil && ref.Author.ID == userID
+}
+
+// StripMention removes mentions of userID from the content and tidies the
+// space left behind, so "<@123> what's up?" becomes "what's up?".
+func StripMention(content, userID string) string {
+	if userID == "" {
+		return strings.TrimSpace(content)
+	}
+	for _, tag := range []string{"<@" + userID + ">", "<@!" + userID + ">"} {
+		// Take one following space with the tag so "hey <@1> there" reads
+		// "hey there"; other whitespace (code indentation) is left alone
+		content = strings.ReplaceAll(content, tag+" ", "")
+		content = strings.ReplaceAll(content, tag, "")
+	}
+	content = strings.TrimSpace(content)
+	content = strings.TrimLeft(content, ",:")
+	return strings.TrimSpace(content)
+}
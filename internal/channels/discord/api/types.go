@@ -0,0 +1,269 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Gateway intents (https://discord.com/developers/docs/topics/gateway#gateway-intents)
+const (
+	IntentGuilds                 = 1 << 0
+	IntentGuildMessages          = 1 << 9
+	IntentGuildMessageReactions  = 1 << 10
+	IntentDirectMessages         = 1 << 12
+	IntentDirectMessageReactions = 1 << 13
+	IntentMessageContent         = 1 << 15 // Privileged: enable in the Developer Portal
+)
+
+// Channel types
+const (
+	ChannelGuildText          = 0
+	ChannelDM                 = 1
+	ChannelGuildVoice         = 2
+	ChannelGroupDM            = 3
+	ChannelGuildAnnouncement  = 5
+	ChannelAnnouncementThread = 10
+	ChannelPublicThread       = 11
+	ChannelPrivateThread      = 12
+	ChannelGuildForum         = 15
+)
+
+// Message types and flags
+const (
+	MessageDefault = 0
+	MessageReply   = 19
+
+	MessageFlagEphemeral = 1 << 6
+	MessageFlagVoice     = 1 << 13
+)
+
+// Interaction types and response types
+const (
+	InteractionPing               = 1
+	InteractionApplicationCommand = 2
+
+	ResponsePong                   = 1
+	ResponseChannelMessage         = 4
+	ResponseDeferredChannelMessage = 5
+)
+
+// Application command types and option types
+const (
+	CommandChatInput = 1
+
+	OptionString = 3
+)
+
+// Dispatch event names the channel handles
+const (
+	EventReady             = "READY"
+	EventResumed           = "RESUMED"
+	EventMessageCreate     = "MESSAGE_CREATE"
+	EventInteractionCreate = "INTERACTION_CREATE"
+	EventThreadDelete      = "THREAD_DELETE"
+)
+
+// User is a Discord user (or bot)
+type User struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name,omitempty"`
+	Bot        bool   `json:"bot,omitempty"`
+}
+
+// DisplayName returns the user's display name, falling back to the username
+func (u *User) DisplayName() string {
+	if u.GlobalName != "" {
+		return u.GlobalName
+	}
+	return u.Username
+}
+
+// Mention returns the mention markup for the user ("<@id>")
+func (u *User) Mention() string {
+	return "<@" + u.ID + ">"
+}
+
+// Member is a guild member; User is set except in MESSAGE_CREATE
+type Member struct {
+	User *User  `json:"user,omitempty"`
+	Nick string `json:"nick,omitempty"`
+}
+
+// Channel is a guild channel, thread or DM
+type Channel struct {
+	ID         string `json:"id"`
+	Type       int    `json:"type"`
+	GuildID    string `json:"guild_id,omitempty"`
+	ParentID   string `json:"parent_id,omitempty"` // Category, or the channel a thread belongs to
+	Name       string `json:"name,omitempty"`
+	Recipients []User `json:"recipients,omitempty"` // DM participants (excluding the bot)
+}
+
+// IsThread reports whether the channel is a thread
+func (c *Channel) IsThread() bool {
+	return c.Type == ChannelAnnouncementThread || c.Type == ChannelPublicThread || c.Type == ChannelPrivateThread
+}
+
+// IsDM reports whether the channel is a direct message channel
+func (c *Channel) IsDM() bool {
+	return c.Type == ChannelDM
+}
+
+// Attachment is a file attached to a message
+type Attachment struct {
+	ID           string  `json:"id"`
+	Filename     string  `json:"filename"`
+	ContentType  string  `json:"content_type,omitempty"`
+	Size         int     `json:"size"`
+	URL          string  `json:"url"`
+	Width        int     `json:"width,omitempty"`
+	Height       int     `json:"height,omitempty"`
+	DurationSecs float64 `json:"duration_secs,omitempty"` // Voice messages
+}
+
+// MessageReference points at the message a reply answers
+type MessageReference struct {
+	MessageID       string `json:"message_id,omitempty"`
+	ChannelID       string `json:"channel_id,omitempty"`
+	GuildID         string `json:"guild_id,omitempty"`
+	FailIfNotExists *bool  `json:"fail_if_not_exists,omitempty"`
+}
+
+// Message is a channel message
+type Message struct {
+	ID                string            `json:"id"`
+	ChannelID         string            `json:"channel_id"`
+	GuildID           string            `json:"guild_id,omitempty"`
+	Author            *User             `json:"author,omitempty"`
+	Member            *Member           `json:"member,omitempty"`
+	Content           string            `json:"content"`
+	Timestamp         string            `json:"timestamp,omitempty"`
+	EditedTimestamp   string            `json:"edited_timestamp,omitempty"`
+	Type              int               `json:"type"`
+	Flags             int               `json:"flags,omitempty"`
+	WebhookID         string            `json:"webhook_id,omitempty"`
+	Attachments       []Attachment      `json:"attachments"`
+	Mentions          []User            `json:"mentions"`
+	MentionEveryone   bool              `json:"mention_everyone,omitempty"`
+	MessageReference  *MessageReference `json:"message_reference,omitempty"`
+	ReferencedMessage *Message          `json:"referenced_message,omitempty"`
+}
+
+// AllowedMentions controls who a sent message may ping
+type AllowedMentions struct {
+	Parse       []string `json:"parse"` // "users", "roles", "everyone"
+	Users       []string `json:"users,omitempty"`
+	RepliedUser bool     `json:"replied_user,omitempty"`
+}
+
+// MessageSend is the body of a create or edit message request
+type MessageSend struct {
+	Content          string            `json:"content"`
+	Flags            int               `json:"flags,omitempty"`
+	AllowedMentions  *AllowedMentions  `json:"allowed_mentions,omitempty"`
+	MessageReference *MessageReference `json:"message_reference,omitempty"`
+	Attachments      []Attachment      `json:"attachments,omitempty"` // Upload metadata for multipart sends
+}
+
+// Application is the bot's application
+type Application struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// GatewayInfo is the result of GET /gateway/bot
+type GatewayInfo struct {
+	URL    string `json:"url"`
+	Shards int    `json:"shards"`
+}
+
+// Ready is the READY dispatch payload
+type Ready struct {
+	Version          int         `json:"v"`
+	User             User        `json:"user"`
+	SessionID        string      `json:"session_id"`
+	ResumeGatewayURL string      `json:"resume_gateway_url"`
+	Application      Application `json:"application"`
+}
+
+// ApplicationCommand is a slash command definition
+type ApplicationCommand struct {
+	ID          string          `json:"id,omitempty"`
+	Type        int             `json:"type,omitempty"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Options     []CommandOption `json:"options,omitempty"`
+}
+
+// CommandOption is a slash command parameter
+type CommandOption struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// Interaction is an INTERACTION_CREATE payload
+type Interaction struct {
+	ID            string           `json:"id"`
+	ApplicationID string           `json:"application_id"`
+	Type          int              `json:"type"`
+	Data          *InteractionData `json:"data,omitempty"`
+	GuildID       string           `json:"guild_id,omitempty"`
+	ChannelID     string           `json:"channel_id,omitempty"`
+	Member        *Member          `json:"member,omitempty"` // Set in guilds
+	User          *User            `json:"user,omitempty"`   // Set in DMs
+	Token         string           `json:"token"`
+}
+
+// Caller returns the user who triggered the interaction
+func (i *Interaction) Caller() *User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
+// InteractionData is the invoked command and its options
+type InteractionData struct {
+	ID      string              `json:"id,omitempty"`
+	Name    string              `json:"name"`
+	Type    int                 `json:"type,omitempty"`
+	Options []InteractionOption `json:"options,omitempty"`
+}
+
+// InteractionOption is one supplied command option
+type InteractionOption struct {
+	Name  string          `json:"name"`
+	Type  int             `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// StringOption returns the value of a string option ("" if absent)
+func (d *InteractionData) StringOption(name string) string {
+	for _, opt := range d.Options {
+		if opt.Name != name {
+			continue
+		}
+		var s string
+		if json.Unmarshal(opt.Value, &s) == nil {
+			return s
+		}
+		return strings.Trim(string(opt.Value), `"`)
+	}
+	return ""
+}
+
+// InteractionResponse answers an interaction
+type InteractionResponse struct {
+	Type int                      `json:"type"`
+	Data *InteractionCallbackData `json:"data,omitempty"`
+}
+
+// InteractionCallbackData is the message content of an interaction response
+type InteractionCallbackData struct {
+	Content         string           `json:"content,omitempty"`
+	Flags           int              `json:"flags,omitempty"`
+	AllowedMentions *AllowedMentions `json:"allowed_mentions,omitempty"`
+}
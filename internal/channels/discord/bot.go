@@ -0,0 +1,1110 @@
+// Package discord provides the Discord channel adapter for GoClaw.
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/bus"
+	"github.com/roelfdiedericks/goclaw/internal/channels/discord/api"
+	"github.com/roelfdiedericks/goclaw/internal/channels/discord/config"
+	chtypes "github.com/roelfdiedericks/goclaw/internal/channels/types"
+	"github.com/roelfdiedericks/goclaw/internal/commands"
+	"github.com/roelfdiedericks/goclaw/internal/gateway"
+	"github.com/roelfdiedericks/goclaw/internal/llm"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/media"
+	"github.com/roelfdiedericks/goclaw/internal/session"
+	itypes "github.com/roelfdiedericks/goclaw/internal/types"
+	"github.com/roelfdiedericks/goclaw/internal/user"
+)
+
+const (
+	// streamInterval is how often a streaming reply is edited. Discord allows
+	// about five edits per five seconds in a channel.
+	streamInterval = 1500 * time.Millisecond
+	// typingRefresh re-triggers typing before Discord's ~10s indicator runs out
+	typingRefresh = 8 * time.Second
+	// readyTimeout is how long Start waits for the gateway to identify
+	readyTimeout = 30 * time.Second
+
+	// intents are the gateway events the bot needs. Message content is a
+	// privileged intent that has to be enabled in the Developer Portal.
+	intents = api.IntentGuilds | api.IntentGuildMessages | api.IntentDirectMessages | api.IntentMessageContent
+
+	thinkingUsage = "Usage: /thinking [on|off|toggle|status|minimal|low|medium|high|xhigh]"
+)
+
+// noMassMentions lets the bot mention users but never @everyone or roles
+var noMassMentions = &api.AllowedMentions{Parse: []string{"users"}}
+
+// slashName is what Discord accepts as a slash command name
+var slashName = regexp.MustCompile(`^[-_\p{Ll}\p{N}]{1,32}$`)
+
+// ChatPreferences stores per-channel preferences (mirrors Telegram)
+type ChatPreferences struct {
+	ShowThinking  bool
+	ThinkingLevel string
+}
+
+// Bot represents the Discord channel
+type Bot struct {
+	client  *api.Client
+	conn    *api.Gateway
+	gateway *gateway.Gateway
+	users   *user.Registry
+	config  *config.Config
+
+	botUser *api.User
+	appID   string
+	ready   chan struct{} // Signalled on each READY
+
+	chatPrefs sync.Map // channel ID -> *ChatPreferences
+
+	chMu          sync.Mutex
+	channels      map[string]*api.Channel // Guild channel and thread info cache
+	dmChannels    map[string]string       // Discord user ID -> DM channel ID
+	activeThreads map[string]bool         // Threads the bot was mentioned in
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{} // Closed when the gateway loop exits
+
+	mu        sync.RWMutex
+	running   bool
+	startedAt time.Time
+	lastError error
+}
+
+// New creates a new Discord bot
+func New(cfg *config.Config, gw *gateway.Gateway, users *user.Registry) (*Bot, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, fmt.Errorf("discord: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Bot{
+		client:        api.NewClient(cfg.APIURL, cfg.Token),
+		gateway:       gw,
+		users:         users,
+		config:        cfg,
+		channels:      make(map[string]*api.Channel),
+		dmChannels:    make(map[string]string),
+		activeThreads: make(map[string]bool),
+		ctx:           ctx,
+		cancel:        cancel,
+	}, nil
+}
+
+// Start checks the token, registers slash commands and connects to the
+// gateway (implements ManagedChannel). It returns once the gateway is ready.
+func (b *Bot) Start(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.running {
+		return nil
+	}
+
+	startCtx, cancel := context.WithTimeout(b.ctx, readyTimeout)
+	defer cancel()
+
+	me, err := b.client.CurrentUser(startCtx)
+	if err != nil {
+		b.lastError = err
+		return fmt.Errorf("discord: token check failed: %w", err)
+	}
+	app, err := b.client.CurrentApplication(startCtx)
+	if err != nil {
+		b.lastError = err
+		return fmt.Errorf("discord: failed to get application: %w", err)
+	}
+	b.botUser = me
+	b.appID = app.ID
+
+	if b.config.SlashCommandsEnabled() {
+		b.registerSlashCommands(startCtx)
+	}
+
+	b.ready = make(chan struct{}, 1)
+	b.conn = api.NewGateway(b.client, intents, b.handleEvent)
+	done := make(chan struct{})
+	runErr := make(chan error, 1)
+	go func() {
+		defer close(done)
+		err := b.conn.Run(b.ctx)
+		runErr <- err
+		if err != nil {
+			L_error("discord: gateway stopped", "error", err)
+			b.mu.Lock()
+			b.lastError = err
+			b.mu.Unlock()
+		}
+	}()
+
+	select {
+	case <-b.ready:
+	case err := <-runErr:
+		b.cancel()
+		b.lastError = err
+		return err
+	case <-startCtx.Done():
+		b.cancel()
+		b.lastError = errors.New("discord: timed out connecting to the gateway")
+		return b.lastError
+	}
+
+	b.running = true
+	b.startedAt = time.Now()
+	b.lastError = nil
+	b.done = done
+
+	L_info("discord: connected", "bot", me.Username, "id", me.ID, "application", app.ID)
+	return nil
+}
+
+// registerSlashCommands publishes the built-in commands as global slash
+// commands. Failure is logged, not fatal: text commands still work.
+func (b *Bot) registerSlashCommands(ctx context.Context) {
+	cmds := slashCommands(commands.GetManager().List())
+	registered, err := b.client.BulkOverwriteCommands(ctx, b.appID, cmds)
+	if err != nil {
+		L_warn("discord: failed to register slash commands", "error", err)
+		return
+	}
+	L_debug("discord: slash commands registered", "count", len(registered))
+}
+
+// slashCommands builds Discord slash commands from the command manager's
+// built-ins, plus the channel's own /thinking. Commands with a usage string
+// get an optional free-text "args" option.
+func slashCommands(list []*commands.Command) []api.ApplicationCommand {
+	var out []api.ApplicationCommand
+	for _, cmd := range list {
+		name := strings.ToLower(strings.TrimPrefix(cmd.Name, "/"))
+		if !slashName.MatchString(name) || name == "thinking" {
+			L_debug("discord: command not usable as a slash command", "command", cmd.Name)
+			continue
+		}
+		desc := cmd.Description
+		if desc == "" {
+			desc = "Run " + cmd.Name
+		}
+		ac := api.ApplicationCommand{
+			Type:        api.CommandChatInput,
+			Name:        name,
+			Description: truncateRunes(desc, 100),
+		}
+		if cmd.Usage != "" {
+			ac.Options = []api.CommandOption{{
+				Type:        api.OptionString,
+				Name:        "args",
+				Description: truncateRunes(cmd.Usage, 100),
+			}}
+		}
+		out = append(out, ac)
+	}
+	return append(out, api.ApplicationCommand{
+		Type:        api.CommandChatInput,
+		Name:        "thinking",
+		Description: "Show or set thinking output for this channel",
+		Options: []api.CommandOption{{
+			Type:        api.OptionString,
+			Name:        "level",
+			Description: "on, off, toggle, status, or a level: minimal, low, medium, high, xhigh",
+		}},
+	})
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// RegisterOperationalCommands registers runtime commands for this bot instance
+func (b *Bot) RegisterOperationalCommands() {
+	bus.RegisterCommand("discord", "status", b.handleStatusCommand)
+}
+
+func (b *Bot) handleStatusCommand(cmd bus.Command) bus.CommandResult {
+	b.chMu.Lock()
+	threads := len(b.activeThreads)
+	b.chMu.Unlock()
+
+	return bus.CommandResult{
+		Success: true,
+		Message: fmt.Sprintf("Discord connected as %s", b.botUser.Username),
+		Data: map[string]any{
+			"connected":     b.conn != nil && b.conn.Connected(),
+			"botId":         b.botUser.ID,
+			"username":      b.botUser.Username,
+			"applicationId": b.appID,
+			"activeThreads": threads,
+		},
+	}
+}
+
+// Stop disconnects from the gateway (implements ManagedChannel)
+func (b *Bot) Stop() error {
+	b.mu.Lock()
+	if !b.running {
+		b.mu.Unlock()
+		return nil
+	}
+	L_info("discord: stopping")
+	b.cancel()
+	b.running = false
+	done := b.done
+	b.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		L_warn("discord: gateway did not stop in time")
+	}
+	return nil
+}
+
+// Reload applies new configuration (implements ManagedChannel)
+func (b *Bot) Reload(cfg any) error {
+	newCfg, ok := cfg.(*config.Config)
+	if !ok {
+		return fmt.Errorf("expected *discord.Config, got %T", cfg)
+	}
+	if err := newCfg.Check(); err != nil {
+		return fmt.Errorf("discord: %w", err)
+	}
+
+	b.mu.Lock()
+	wasRunning := b.running
+	b.mu.Unlock()
+
+	if wasRunning {
+		if err := b.Stop(); err != nil {
+			return fmt.Errorf("failed to stop for reload: %w", err)
+		}
+	}
+
+	b.config = newCfg
+	b.client = api.NewClient(newCfg.APIURL, newCfg.Token)
+
+	if wasRunning && newCfg.Enabled {
+		b.ctx, b.cancel = context.WithCancel(context.Background())
+		return b.Start(b.ctx)
+	}
+
+	return nil
+}
+
+// Status returns current channel status (implements ManagedChannel)
+func (b *Bot) Status() chtypes.ChannelStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	info := ""
+	if b.botUser != nil {
+		info = b.botUser.Username
+	}
+	return chtypes.ChannelStatus{
+		Running:   b.running,
+		Connected: b.running && b.conn != nil && b.conn.Connected(),
+		Error:     b.lastError,
+		StartedAt: b.startedAt,
+		Info:      info,
+	}
+}
+
+// Name returns the channel name (implements gateway.Channel)
+func (b *Bot) Name() string {
+	return "discord"
+}
+
+// Send sends a message to the owner's Discord DM (implements gateway.Channel)
+func (b *Bot) Send(ctx context.Context, msg string) error {
+	owner := b.users.Owner()
+	if owner == nil || owner.DiscordID == "" {
+		return nil
+	}
+	channelID, err := b.dmChannel(ctx, owner.DiscordID)
+	if err != nil {
+		return err
+	}
+	_, err = b.sendText(channelID, msg, "")
+	return err
+}
+
+// SendMirror sends a cross-channel mirror summary to the owner (implements gateway.Channel)
+func (b *Bot) SendMirror(ctx context.Context, source, userMsg, response string) error {
+	owner := b.users.Owner()
+	if owner == nil || owner.DiscordID == "" {
+		return nil
+	}
+	channelID, err := b.dmChannel(ctx, owner.DiscordID)
+	if err != nil {
+		return err
+	}
+
+	agentName := b.gateway.AgentIdentityFor(b.gateway.ResolveAgent("discord", "", channelID, owner)).Name
+	mirror := fmt.Sprintf("**%s**\n\n**You:** %s\n\n**%s:** %s",
+		source, truncate(userMsg, 300), agentName, truncate(response, api.MaxMessageLength-400))
+
+	_, err = b.sendText(channelID, mirror, "")
+	if err != nil {
+		L_error("discord: failed to send mirror", "error", err)
+	}
+	return err
+}
+
+// HasUser returns true if the user has a Discord identity (implements gateway.Channel)
+func (b *Bot) HasUser(u *user.User) bool {
+	return u.HasDiscordAuth()
+}
+
+// StreamEvent returns false — replies stream through edits, not gateway events (implements gateway.Channel)
+func (b *Bot) StreamEvent(u *user.User, event gateway.AgentEvent) bool {
+	return false
+}
+
+// DeliverGhostwrite sends a ghostwritten message with typing simulation (implements gateway.Channel)
+func (b *Bot) DeliverGhostwrite(ctx context.Context, u *user.User, message string) error {
+	if u == nil || u.DiscordID == "" {
+		return nil
+	}
+	channelID, err := b.dmChannel(ctx, u.DiscordID)
+	if err != nil {
+		return err
+	}
+
+	L_info("discord: ghostwrite", "user", u.ID, "channel", channelID, "messageLen", len(message))
+
+	b.triggerTyping(channelID)
+
+	typingDelay := 500 * time.Millisecond
+	if b.gateway != nil {
+		if cfg := b.gateway.Config(); cfg != nil && cfg.Supervision.Ghostwriting.TypingDelayMs > 0 {
+			typingDelay = time.Duration(cfg.Supervision.Ghostwriting.TypingDelayMs) * time.Millisecond
+		}
+	}
+	time.Sleep(typingDelay)
+
+	if _, err := b.sendText(channelID, message, ""); err != nil {
+		return fmt.Errorf("failed to send ghostwrite: %w", err)
+	}
+
+	L_info("discord: ghostwrite delivered", "user", u.ID, "messageLen", len(message))
+	return nil
+}
+
+// dmChannel returns the DM channel with a Discord user, opening it if needed
+func (b *Bot) dmChannel(ctx context.Context, discordID string) (string, error) {
+	b.chMu.Lock()
+	channelID := b.dmChannels[discordID]
+	b.chMu.Unlock()
+	if channelID != "" {
+		return channelID, nil
+	}
+
+	ch, err := b.client.CreateDM(ctx, discordID)
+	if err != nil {
+		return "", fmt.Errorf("failed to open DM with %s: %w", discordID, err)
+	}
+	b.chMu.Lock()
+	b.dmChannels[discordID] = ch.ID
+	b.chMu.Unlock()
+	return ch.ID, nil
+}
+
+// channelInfo returns a guild channel or thread, cached after the first lookup
+func (b *Bot) channelInfo(channelID string) (*api.Channel, error) {
+	b.chMu.Lock()
+	ch := b.channels[channelID]
+	b.chMu.Unlock()
+	if ch != nil {
+		return ch, nil
+	}
+
+	ch, err := b.client.GetChannel(b.ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	b.chMu.Lock()
+	b.channels[channelID] = ch
+	b.chMu.Unlock()
+	return ch, nil
+}
+
+// channelAllowed reports whether the bot answers in a guild channel: its
+// server or the channel itself is listed (threads follow their parent)
+func (b *Bot) channelAllowed(guildID string, ch *api.Channel) bool {
+	if slices.Contains(b.config.Guilds, guildID) || slices.Contains(b.config.Channels, ch.ID) {
+		return true
+	}
+	return ch.IsThread() && ch.ParentID != "" && slices.Contains(b.config.Channels, ch.ParentID)
+}
+
+// --- Gateway events ---
+
+// handleEvent receives gateway dispatches on the gateway's read loop
+func (b *Bot) handleEvent(eventType string, data json.RawMessage) {
+	switch eventType {
+	case api.EventReady:
+		L_debug("discord: gateway ready")
+		select {
+		case b.ready <- struct{}{}:
+		default:
+		}
+
+	case api.EventResumed:
+		L_debug("discord: gateway session resumed")
+
+	case api.EventMessageCreate:
+		var msg api.Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			L_debug("discord: unparseable message", "error", err)
+			return
+		}
+		// Never answer bots (ourselves included) or webhooks, so two bots
+		// in a channel don't loop
+		if msg.Author == nil || msg.Author.Bot || msg.WebhookID != "" {
+			return
+		}
+		if msg.Type != api.MessageDefault && msg.Type != api.MessageReply {
+			return
+		}
+		// Agent runs take a while; don't hold up the gateway
+		go b.handleMessage(&msg)
+
+	case api.EventInteractionCreate:
+		var in api.Interaction
+		if err := json.Unmarshal(data, &in); err != nil {
+			L_debug("discord: unparseable interaction", "error", err)
+			return
+		}
+		go b.handleInteraction(&in)
+
+	case api.EventThreadDelete:
+		var ch api.Channel
+		if json.Unmarshal(data, &ch) == nil {
+			b.chMu.Lock()
+			delete(b.channels, ch.ID)
+			delete(b.activeThreads, ch.ID)
+			b.chMu.Unlock()
+		}
+	}
+}
+
+// handleMessage processes an incoming Discord message
+func (b *Bot) handleMessage(msg *api.Message) {
+	u := b.users.FromDiscordID(msg.Author.ID)
+	isGroup := msg.GuildID != ""
+	if u == nil {
+		if isGroup {
+			L_trace("discord: message from unknown user ignored", "author", msg.Author.ID, "channel", msg.ChannelID)
+		} else {
+			L_warn("discord: unknown user ignored", "author", msg.Author.ID, "username", msg.Author.Username)
+		}
+		return
+	}
+
+	text := msg.Content
+	replyTo := ""
+	if isGroup {
+		ch, err := b.channelInfo(msg.ChannelID)
+		if err != nil {
+			L_warn("discord: failed to look up channel", "channel", msg.ChannelID, "error", err)
+			return
+		}
+		if !b.channelAllowed(msg.GuildID, ch) {
+			L_debug("discord: ignoring message in channel not in allowlist", "guild", msg.GuildID, "channel", msg.ChannelID)
+			return
+		}
+
+		mentioned := msg.MentionsUser(b.botUser.ID)
+		if mentioned && ch.IsThread() {
+			b.chMu.Lock()
+			b.activeThreads[ch.ID] = true
+			b.chMu.Unlock()
+		}
+		if b.config.MentionRequired() && !mentioned {
+			// Once mentioned in a thread, the bot follows the rest of it
+			b.chMu.Lock()
+			active := ch.IsThread() && b.activeThreads[ch.ID]
+			b.chMu.Unlock()
+			if !active {
+				return
+			}
+		}
+		text = api.StripMention(text, b.botUser.ID)
+		replyTo = msg.ID
+	} else {
+		b.chMu.Lock()
+		b.dmChannels[msg.Author.ID] = msg.ChannelID
+		b.chMu.Unlock()
+	}
+
+	var contentBlocks []itypes.ContentBlock
+	placeholder := ""
+	for _, att := range msg.Attachments {
+		category := ""
+		switch {
+		case strings.HasPrefix(att.ContentType, "image/"):
+			category = "image"
+			placeholder = "<media:image>"
+		case strings.HasPrefix(att.ContentType, "audio/"):
+			category = "voice"
+			placeholder = "[Voice note received]"
+		default:
+			L_debug("discord: unsupported attachment, ignoring", "filename", att.Filename, "type", att.ContentType)
+			continue
+		}
+		block, err := b.downloadAttachment(&att, category)
+		if err != nil {
+			L_error("discord: failed to download attachment", "filename", att.Filename, "error", err)
+			continue
+		}
+		contentBlocks = append(contentBlocks, *block)
+	}
+	if text == "" {
+		if len(contentBlocks) == 0 {
+			return
+		}
+		text = placeholder
+	}
+
+	L_info("discord: authenticated message", "user", u.Name, "role", u.Role, "channel", msg.ChannelID, "isGroup", isGroup)
+
+	// Check for panic phrase (emergency stop) before commands
+	// Always attempt cancel and confirm - avoids race conditions where session just finished
+	if commands.IsPanicPhrase(text) {
+		b.gateway.StopAllUserSessions(u.ID)
+		b.sendPlain(msg.ChannelID, "Stopping all tasks.")
+		return
+	}
+
+	// Check for /thinking (channel-specific)
+	if strings.HasPrefix(text, "/thinking") {
+		if b.canUserUseCommands(u) {
+			b.sendPlain(msg.ChannelID, b.thinkingCommand(u, msg.ChannelID, strings.TrimPrefix(text, "/thinking")))
+		}
+		return
+	}
+
+	// Check for commands
+	if commands.IsCommand(text) {
+		b.handleCommand(u, msg.ChannelID, text)
+		return
+	}
+
+	b.triggerTyping(msg.ChannelID)
+
+	prefs := b.getChatPrefs(msg.ChannelID, u)
+
+	req := gateway.AgentRequest{
+		User:           u,
+		Source:         "discord",
+		ChatID:         msg.ChannelID,
+		IsGroup:        isGroup,
+		UserMsg:        text,
+		ContentBlocks:  contentBlocks,
+		EnableThinking: prefs.ShowThinking,
+		ThinkingLevel:  prefs.ThinkingLevel,
+		OnMediaToSend: func(path, caption string) error {
+			_, err := b.sendMediaFile(msg.ChannelID, path, caption)
+			return err
+		},
+	}
+
+	evChan := make(chan gateway.AgentEvent, 100)
+
+	go func() {
+		if err := b.gateway.RunAgent(b.ctx, req, evChan); err != nil {
+			L_error("discord: agent error", "error", err)
+		}
+	}()
+
+	b.streamResponse(msg.ChannelID, replyTo, evChan, prefs)
+}
+
+// handleCommand routes commands to the global command manager
+func (b *Bot) handleCommand(u *user.User, channelID, text string) {
+	if !b.canUserUseCommands(u) {
+		L_debug("discord: commands disabled for user", "user", u.Name, "command", text)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := commands.GetManager().Execute(ctx, text, b.getSessionKey(u), u.ID)
+	if _, err := b.sendText(channelID, result.Markdown, ""); err != nil {
+		L_error("discord: failed to send command result", "error", err)
+	}
+}
+
+// handleInteraction answers a slash command. Output is only visible to the
+// caller in servers, and the response is deferred so slow commands don't
+// miss Discord's three-second deadline.
+func (b *Bot) handleInteraction(in *api.Interaction) {
+	if in.Type != api.InteractionApplicationCommand || in.Data == nil {
+		return
+	}
+	caller := in.Caller()
+	if caller == nil {
+		return
+	}
+
+	flags := 0
+	if in.GuildID != "" {
+		flags = api.MessageFlagEphemeral
+	}
+
+	u := b.users.FromDiscordID(caller.ID)
+	if u == nil || !b.canUserUseCommands(u) {
+		L_debug("discord: slash command refused", "user", caller.ID, "command", in.Data.Name)
+		b.respondInteraction(in, "You're not allowed to use my commands.", api.MessageFlagEphemeral)
+		return
+	}
+
+	if in.Data.Name == "thinking" {
+		b.respondInteraction(in, b.thinkingCommand(u, in.ChannelID, in.Data.StringOption("level")), flags)
+		return
+	}
+
+	deferred := &api.InteractionResponse{
+		Type: api.ResponseDeferredChannelMessage,
+		Data: &api.InteractionCallbackData{Flags: flags},
+	}
+	if err := b.client.RespondInteraction(b.ctx, in.ID, in.Token, deferred); err != nil {
+		L_error("discord: failed to acknowledge slash command", "command", in.Data.Name, "error", err)
+		return
+	}
+
+	text := "/" + in.Data.Name
+	if args := in.Data.StringOption("args"); args != "" {
+		text += " " + args
+	}
+	L_info("discord: slash command", "user", u.Name, "command", text)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result := commands.GetManager().Execute(ctx, text, b.getSessionKey(u), u.ID)
+
+	content := truncate(FormatMessage(result.Markdown), api.MaxMessageLength-10)
+	if _, err := b.client.EditInteractionResponse(b.ctx, in.ApplicationID, in.Token, &api.MessageSend{Content: content, AllowedMentions: noMassMentions}); err != nil {
+		L_error("discord: failed to send slash command result", "command", in.Data.Name, "error", err)
+	}
+}
+
+// respondInteraction answers an interaction immediately with a message
+func (b *Bot) respondInteraction(in *api.Interaction, content string, flags int) {
+	resp := &api.InteractionResponse{
+		Type: api.ResponseChannelMessage,
+		Data: &api.InteractionCallbackData{Content: content, Flags: flags, AllowedMentions: noMassMentions},
+	}
+	if err := b.client.RespondInteraction(b.ctx, in.ID, in.Token, resp); err != nil {
+		L_error("discord: failed to respond to interaction", "error", err)
+	}
+}
+
+// thinkingCommand applies a /thinking argument to a channel's preferences
+// and returns the reply
+func (b *Bot) thinkingCommand(u *user.User, channelID, arg string) string {
+	prefs := b.getChatPrefs(channelID, u)
+
+	arg = strings.ToLower(strings.TrimSpace(arg))
+
+	switch arg {
+	case "on":
+		prefs.ShowThinking = true
+		if prefs.ThinkingLevel == "" || prefs.ThinkingLevel == "off" {
+			prefs.ThinkingLevel = llm.DefaultThinkingLevel.String()
+		}
+		return fmt.Sprintf("Thinking output enabled (level: %s).", prefs.ThinkingLevel)
+	case "off":
+		prefs.ShowThinking = false
+		prefs.ThinkingLevel = "off"
+		return "Thinking output disabled."
+	case "toggle", "":
+		prefs.ShowThinking = !prefs.ShowThinking
+		if prefs.ShowThinking {
+			if prefs.ThinkingLevel == "" || prefs.ThinkingLevel == "off" {
+				prefs.ThinkingLevel = llm.DefaultThinkingLevel.String()
+			}
+			return fmt.Sprintf("Thinking output enabled (level: %s).", prefs.ThinkingLevel)
+		}
+		return "Thinking output disabled."
+	case "status":
+		if prefs.ShowThinking {
+			level := prefs.ThinkingLevel
+			if level == "" {
+				level = llm.DefaultThinkingLevel.String()
+			}
+			return fmt.Sprintf("Thinking output: ON, level: %s", level)
+		}
+		return "Thinking output: OFF"
+	default:
+		if !llm.IsValidThinkingLevel(arg) {
+			return thinkingUsage
+		}
+		prefs.ThinkingLevel = arg
+		if arg == "off" {
+			prefs.ShowThinking = false
+			return "Thinking disabled."
+		}
+		prefs.ShowThinking = true
+		return fmt.Sprintf("Thinking level set to %s (output enabled).", arg)
+	}
+}
+
+// streamResponse consumes agent events, streaming the reply into one
+// message that is edited as text arrives. In servers the reply answers
+// the triggering message (replyTo).
+func (b *Bot) streamResponse(channelID, replyTo string, evChan <-chan gateway.AgentEvent, prefs *ChatPreferences) {
+	var response strings.Builder
+	var thinkingBuf strings.Builder
+	var currentMsg string // ID of the streaming message
+	var lastUpdate, lastTyping time.Time
+	toolsActive := false
+
+	for event := range evChan {
+		switch e := event.(type) {
+		case gateway.EventTextDelta:
+			response.WriteString(e.Delta)
+
+			// With thinking shown, hold text back while tools run so the
+			// tool messages and the reply stay in order
+			if prefs.ShowThinking && toolsActive {
+				continue
+			}
+			if time.Since(lastUpdate) < streamInterval {
+				continue
+			}
+			preview := truncate(response.String(), api.MaxMessageLength-10)
+			if currentMsg == "" {
+				m, err := b.post(channelID, &api.MessageSend{Content: preview, MessageReference: replyRef(replyTo)})
+				if err != nil {
+					L_error("discord: failed to send initial message", "error", err)
+					continue
+				}
+				currentMsg = m.ID
+			} else if _, err := b.client.EditMessage(b.ctx, channelID, currentMsg, &api.MessageSend{Content: preview, AllowedMentions: noMassMentions}); err != nil {
+				L_trace("discord: edit failed", "error", err)
+			}
+			lastUpdate = time.Now()
+			if time.Since(lastTyping) > typingRefresh {
+				b.triggerTyping(channelID)
+				lastTyping = time.Now()
+			}
+
+		case gateway.EventThinkingDelta:
+			if prefs.ShowThinking {
+				thinkingBuf.WriteString(e.Delta)
+			}
+
+		case gateway.EventThinking:
+			if prefs.ShowThinking && e.Content != "" {
+				b.sendThinking(channelID, e.Content)
+				thinkingBuf.Reset()
+			}
+
+		case gateway.EventToolStart:
+			toolsActive = true
+			if time.Since(lastTyping) > typingRefresh {
+				b.triggerTyping(channelID)
+				lastTyping = time.Now()
+			}
+			if prefs.ShowThinking {
+				// Flush accumulated thinking before tools
+				if thinkingBuf.Len() > 0 {
+					b.sendThinking(channelID, thinkingBuf.String())
+					thinkingBuf.Reset()
+				}
+
+				inputStr := string(e.Input)
+				if len(inputStr) > 1024 {
+					inputStr = inputStr[:1024] + "..."
+				}
+				toolMsg := fmt.Sprintf("⚙️ **%s**\n```\n%s\n```", e.ToolName, inputStr)
+				_, _ = b.post(channelID, &api.MessageSend{Content: toolMsg})
+			}
+
+		case gateway.EventToolEnd:
+			toolsActive = false
+
+		case gateway.EventAgentEnd:
+			finalText := e.FinalText
+			if finalText == "" {
+				finalText = response.String()
+			}
+			if finalText == "" {
+				finalText = "(No response)"
+			}
+
+			if media.ContainsMediaRefs(finalText) {
+				// Send text and media in order; drop the streamed copy
+				if currentMsg != "" {
+					_ = b.client.DeleteMessage(b.ctx, channelID, currentMsg)
+				}
+				b.sendWithMediaRefs(channelID, finalText)
+				continue
+			}
+
+			chunks := splitMessage(FormatMessage(finalText), api.MaxMessageLength)
+			for i, chunk := range chunks {
+				var err error
+				switch {
+				case i == 0 && currentMsg != "":
+					_, err = b.client.EditMessage(b.ctx, channelID, currentMsg, &api.MessageSend{Content: chunk, AllowedMentions: noMassMentions})
+				case i == 0:
+					_, err = b.post(channelID, &api.MessageSend{Content: chunk, MessageReference: replyRef(replyTo)})
+				default:
+					_, err = b.post(channelID, &api.MessageSend{Content: chunk})
+				}
+				if err != nil {
+					L_error("discord: failed to send message chunk", "error", err, "chunk", i+1)
+				}
+			}
+
+		case gateway.EventAgentMerged:
+			// Reply arrives through the run the message was merged into
+			L_debug("discord: message merged into running turn", "mode", e.Mode)
+			b.sendPlain(channelID, e.Notice())
+
+		case gateway.EventAgentError:
+			L_error("discord: agent error", "error", e.Error)
+			errMsg := truncate(fmt.Sprintf("Error: %s", e.Error), api.MaxMessageLength-10)
+			if currentMsg != "" {
+				_, _ = b.client.EditMessage(b.ctx, channelID, currentMsg, &api.MessageSend{Content: errMsg, AllowedMentions: noMassMentions})
+			} else {
+				b.sendPlain(channelID, errMsg)
+			}
+		}
+	}
+}
+
+// replyRef references the message being answered, without failing if it
+// was deleted meanwhile
+func replyRef(messageID string) *api.MessageReference {
+	if messageID == "" {
+		return nil
+	}
+	failIfMissing := false
+	return &api.MessageReference{MessageID: messageID, FailIfNotExists: &failIfMissing}
+}
+
+// post sends one message without pinging @everyone, roles or the replied-to user
+func (b *Bot) post(channelID string, msg *api.MessageSend) (*api.Message, error) {
+	msg.AllowedMentions = noMassMentions
+	return b.client.SendMessage(b.ctx, channelID, msg)
+}
+
+// sendText formats and sends a message, split into as many as needed, and
+// returns the ID of the first one
+func (b *Bot) sendText(channelID, text, replyTo string) (string, error) {
+	firstID := ""
+	for i, chunk := range splitMessage(FormatMessage(text), api.MaxMessageLength) {
+		msg := &api.MessageSend{Content: chunk}
+		if i == 0 {
+			msg.MessageReference = replyRef(replyTo)
+		}
+		m, err := b.post(channelID, msg)
+		if err != nil {
+			return firstID, err
+		}
+		if firstID == "" {
+			firstID = m.ID
+		}
+	}
+	return firstID, nil
+}
+
+// sendPlain sends a short unformatted message, logging failures
+func (b *Bot) sendPlain(channelID, text string) {
+	if _, err := b.post(channelID, &api.MessageSend{Content: text}); err != nil {
+		L_error("discord: failed to send message", "channel", channelID, "error", err)
+	}
+}
+
+// sendThinking sends thinking output as an italic message
+func (b *Bot) sendThinking(channelID, thinking string) {
+	thinking = truncate(strings.TrimSpace(thinking), api.MaxMessageLength-20)
+	_, _ = b.post(channelID, &api.MessageSend{Content: "💭 *" + thinking + "*"})
+}
+
+// triggerTyping shows the typing indicator, ignoring errors
+func (b *Bot) triggerTyping(channelID string) {
+	if err := b.client.TriggerTyping(b.ctx, channelID); err != nil {
+		L_trace("discord: typing failed", "channel", channelID, "error", err)
+	}
+}
+
+// downloadAttachment downloads an attachment, saves it, and returns a ContentBlock
+func (b *Bot) downloadAttachment(att *api.Attachment, category string) (*itypes.ContentBlock, error) {
+	if att.Size > api.MaxDownloadSize {
+		return nil, fmt.Errorf("attachment too large (%d bytes)", att.Size)
+	}
+
+	data, contentType, err := b.client.Download(b.ctx, att.URL)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+
+	mimeType := att.ContentType
+	if mimeType == "" {
+		mimeType = contentType
+	}
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		if detected := media.DetectMIME(data); detected != "" {
+			mimeType = detected
+		}
+	}
+	mimeType = strings.TrimSpace(strings.Split(mimeType, ";")[0])
+
+	L_debug("discord: attachment downloaded", "category", category, "size", len(data), "mime", mimeType)
+
+	if b.gateway == nil || b.gateway.MediaStore() == nil {
+		return nil, fmt.Errorf("no media store available")
+	}
+
+	absPath, _, err := b.gateway.MediaStore().Save(data, category, mimeToExt(mimeType, att.Filename))
+	if err != nil {
+		return nil, fmt.Errorf("save failed: %w", err)
+	}
+
+	blockType := "audio"
+	if strings.HasPrefix(mimeType, "image/") {
+		blockType = "image"
+	}
+
+	return &itypes.ContentBlock{
+		Type:     blockType,
+		FilePath: absPath,
+		MimeType: mimeType,
+		Source:   "discord",
+	}, nil
+}
+
+// sendMediaFile uploads a file to a channel, returning the message ID
+func (b *Bot) sendMediaFile(channelID, filePath, caption string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+
+	mimeType, _ := media.DetectMimeType(filePath)
+
+	msg := &api.MessageSend{Content: truncate(caption, api.MaxMessageLength-10), AllowedMentions: noMassMentions}
+	m, err := b.client.SendFile(b.ctx, channelID, msg, filepath.Base(filePath), mimeType, data)
+	if err != nil {
+		return "", fmt.Errorf("upload: %w", err)
+	}
+	return m.ID, nil
+}
+
+// sendWithMediaRefs parses and sends text with inline media references
+func (b *Bot) sendWithMediaRefs(channelID, text string) {
+	segments := media.SplitMediaSegments(text)
+
+	var mediaRoot string
+	if b.gateway != nil && b.gateway.MediaStore() != nil {
+		mediaRoot = b.gateway.MediaStore().BaseDir()
+	}
+
+	for _, seg := range segments {
+		if !seg.IsMedia {
+			if strings.TrimSpace(seg.Text) != "" {
+				_, _ = b.sendText(channelID, seg.Text, "")
+			}
+			continue
+		}
+
+		if strings.HasPrefix(seg.Mime, "error/") {
+			errType := strings.TrimPrefix(seg.Mime, "error/")
+			b.sendPlain(channelID, fmt.Sprintf("[Media %s: %s]", errType, seg.Path))
+			continue
+		}
+
+		absPath, err := media.ResolveMediaPath(mediaRoot, seg.Path)
+		if err != nil {
+			L_warn("discord: failed to resolve media path", "path", seg.Path, "error", err)
+			continue
+		}
+
+		if _, err := b.sendMediaFile(channelID, absPath, ""); err != nil {
+			L_warn("discord: failed to send media", "path", absPath, "error", err)
+		}
+	}
+}
+
+// getChatPrefs returns preferences for a channel, initializing from user prefs if needed
+func (b *Bot) getChatPrefs(channelID string, u *user.User) *ChatPreferences {
+	if prefs, ok := b.chatPrefs.Load(channelID); ok {
+		return prefs.(*ChatPreferences) //nolint:errcheck // type assertion safe
+	}
+	prefs := &ChatPreferences{}
+	if u != nil {
+		prefs.ShowThinking = u.Thinking
+		prefs.ThinkingLevel = u.ThinkingLevel
+	}
+	actual, _ := b.chatPrefs.LoadOrStore(channelID, prefs)
+	return actual.(*ChatPreferences) //nolint:errcheck // type assertion safe
+}
+
+// getSessionKey returns the session key for a user
+func (b *Bot) getSessionKey(u *user.User) string {
+	if u.Role == "owner" {
+		return session.PrimarySession
+	}
+	return fmt.Sprintf("user:%s", u.ID)
+}
+
+// canUserUseCommands checks if the user has permission to use slash commands
+func (b *Bot) canUserUseCommands(u *user.User) bool {
+	if u == nil {
+		return false
+	}
+	resolvedRole, err := b.users.ResolveUserRole(u)
+	if err != nil {
+		L_warn("discord: failed to resolve role for command check", "user", u.Name, "error", err)
+		return false
+	}
+	return resolvedRole.CanUseCommands()
+}
+
+// mimeToExt returns a file extension for downloaded media, preferring the sender's file name
+func mimeToExt(mimeType, filename string) string {
+	if ext := filepath.Ext(filename); ext != "" && len(ext) <= 6 {
+		return strings.ToLower(ext)
+	}
+	switch mimeType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "audio/ogg":
+		return ".ogg"
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/mp4":
+		return ".m4a"
+	default:
+		return ".bin"
+	}
+}
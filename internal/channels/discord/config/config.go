@@ -0,0 +1,173 @@
+// Package config defines the Discord channel configuration.
+// Separate package to avoid import cycles with gateway.
+package config
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/bus"
+	"github.com/roelfdiedericks/goclaw/internal/channels/discord/api"
+	"github.com/roelfdiedericks/goclaw/internal/config/forms"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// Config holds the Discord channel configuration
+type Config struct {
+	Enabled        bool     `json:"enabled"`
+	Token          string   `json:"token"`                    // Bot token from the Developer Portal
+	Guilds         []string `json:"guilds,omitempty"`         // Servers (guild IDs) the bot answers in; DMs are always allowed
+	Channels       []string `json:"channels,omitempty"`       // Individual channels the bot answers in (threads follow their parent)
+	RequireMention *bool    `json:"requireMention,omitempty"` // In servers, only answer when mentioned (default true)
+	SlashCommands  *bool    `json:"slashCommands,omitempty"`  // Register slash commands for the built-in commands (default true)
+	APIURL         string   `json:"apiUrl,omitempty"`         // REST API root (default https://discord.com/api/v10; for tests)
+}
+
+// MentionRequired reports whether server channels need a mention (default true)
+func (c *Config) MentionRequired() bool {
+	return c.RequireMention == nil || *c.RequireMention
+}
+
+// SlashCommandsEnabled reports whether slash commands are registered (default true)
+func (c *Config) SlashCommandsEnabled() bool {
+	return c.SlashCommands == nil || *c.SlashCommands
+}
+
+// Check validates the settings
+func (c *Config) Check() error {
+	if c.Token == "" {
+		return fmt.Errorf("bot token is required")
+	}
+	for _, id := range c.Guilds {
+		if !isSnowflake(id) {
+			return fmt.Errorf("guilds must be numeric server IDs, got %q", id)
+		}
+	}
+	for _, id := range c.Channels {
+		if !isSnowflake(id) {
+			return fmt.Errorf("channels must be numeric channel IDs, got %q", id)
+		}
+	}
+	return nil
+}
+
+func isSnowflake(id string) bool {
+	_, err := strconv.ParseUint(id, 10, 64)
+	return err == nil
+}
+
+// ConfigFormDef returns the form definition for editing Discord config
+func ConfigFormDef() forms.FormDef {
+	return forms.FormDef{
+		Title:       "Discord",
+		Description: "Configure the Discord bot",
+		Sections: []forms.Section{
+			{
+				Title: "Connection",
+				Fields: []forms.Field{
+					{Name: "enabled", Title: "Enabled", Desc: "Enable the Discord channel", Type: forms.Toggle},
+					{Name: "token", Title: "Bot Token", Desc: "Token from the Bot page of your application in the Discord Developer Portal", Type: forms.Secret},
+				},
+			},
+			{
+				Title: "Servers",
+				Fields: []forms.Field{
+					{Name: "guilds", Title: "Servers", Desc: "Server (guild) IDs the bot answers in (DMs from known users are always allowed)", Type: forms.StringList},
+					{Name: "channels", Title: "Channels", Desc: "Individual channel IDs the bot answers in, for servers not listed above", Type: forms.StringList},
+					{Name: "requireMention", Title: "Require Mention", Desc: "In servers, only answer when the bot is mentioned or replied to", Type: forms.Toggle, Default: true},
+					{Name: "slashCommands", Title: "Slash Commands", Desc: "Register /status, /help and the other built-in commands with Discord", Type: forms.Toggle, Default: true},
+				},
+			},
+		},
+		Actions: []forms.ActionDef{
+			{
+				Name:  "test",
+				Label: "Test Connection",
+				Desc:  "Check the bot token with Discord",
+			},
+			{
+				Name:  "apply",
+				Label: "Apply Now",
+				Desc:  "Apply changes to running Discord channel (requires gateway)",
+			},
+		},
+	}
+}
+
+const configPath = "channels.discord"
+
+// RegisterCommands registers discord config command handlers
+func RegisterCommands() {
+	bus.RegisterCommand(configPath, "test", handleTest)
+	bus.RegisterCommand(configPath, "apply", handleApply)
+}
+
+// UnregisterCommands unregisters discord config command handlers
+func UnregisterCommands() {
+	bus.UnregisterComponent(configPath)
+}
+
+func handleApply(cmd bus.Command) bus.CommandResult {
+	cfg, ok := cmd.Payload.(*Config)
+	if !ok {
+		return bus.CommandResult{
+			Error:   fmt.Errorf("invalid payload type: expected *Config, got %T", cmd.Payload),
+			Message: "Internal error: invalid config type",
+		}
+	}
+
+	logging.L_info("discord: config applied", "enabled", cfg.Enabled)
+	bus.PublishEvent(configPath+".config.applied", cfg)
+
+	return bus.CommandResult{
+		Success: true,
+		Message: "Config applied - channel will restart if needed",
+	}
+}
+
+// handleTest checks the bot token with Discord
+func handleTest(cmd bus.Command) bus.CommandResult {
+	cfg, ok := cmd.Payload.(*Config)
+	if !ok {
+		return bus.CommandResult{
+			Error:   fmt.Errorf("invalid payload type"),
+			Message: "Internal error: invalid config type",
+		}
+	}
+
+	if err := cfg.Check(); err != nil {
+		return bus.CommandResult{Error: err, Message: err.Error()}
+	}
+
+	bot, err := TestToken(cfg)
+	if err != nil {
+		logging.L_warn("discord: test connection failed", "error", err)
+		return bus.CommandResult{
+			Error:   err,
+			Message: fmt.Sprintf("Connection failed: %s", err),
+		}
+	}
+
+	logging.L_info("discord: test connection successful", "bot", bot.Username, "id", bot.ID)
+	return bus.CommandResult{
+		Success: true,
+		Message: fmt.Sprintf("Connected as %s (%s)", bot.Username, bot.ID),
+	}
+}
+
+// TestToken checks the bot token and returns the bot user
+func TestToken(cfg *Config) (*api.User, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	bot, err := api.NewClient(cfg.APIURL, cfg.Token).CurrentUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !bot.Bot {
+		return nil, fmt.Errorf("token belongs to %s, which is not a bot account", bot.Username)
+	}
+	return bot, nil
+}
@@ -0,0 +1,938 @@
+// Package discordtest provides an in-process fake Discord API for tests and
+// development: the REST endpoints the Discord channel uses and a gateway
+// websocket that identifies, heartbeats, dispatches and resumes.
+// Users, channels, threads and incoming messages are scripted with AddUser,
+// CreateChannel, CreateThread, DM, Post and Interact; what the bot did is read
+// back with Messages, Reactions, Typing, Commands and InteractionResponses.
+package discordtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/roelfdiedericks/goclaw/internal/channels/discord/api"
+	"github.com/roelfdiedericks/goclaw/internal/channels/discord/config"
+)
+
+const apiPrefix = "/api/v10"
+
+var mentionPattern = regexp.MustCompile(`<@!?(\d+)>`)
+
+// Server is a fake Discord API and gateway.
+type Server struct {
+	srv      *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu              sync.Mutex
+	nextID          int64
+	token           string
+	bot             api.User
+	app             api.Application
+	users           map[string]*api.User
+	channels        map[string]*api.Channel
+	messages        map[string][]*api.Message // channel ID -> messages, oldest first
+	reactions       map[string][]string       // message ID -> emoji the bot added
+	typing          map[string]int            // channel ID -> typing triggers
+	commands        []api.ApplicationCommand
+	interactions    map[string]*interaction // token -> interaction
+	files           map[string]file         // attachment path -> content
+	sessions        map[string]*session
+	heartbeat       time.Duration
+	rateLimit       int
+	noContentIntent bool
+	identifies      int
+	resumes         int
+}
+
+type session struct {
+	id   string
+	seq  int64
+	log  []api.Payload // Dispatches, replayed on resume
+	conn *gwConn
+}
+
+type gwConn struct {
+	ws  *websocket.Conn
+	wmu sync.Mutex
+}
+
+func (c *gwConn) send(p api.Payload) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.ws.WriteJSON(&p)
+}
+
+func (c *gwConn) close(code int, text string) {
+	c.wmu.Lock()
+	_ = c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(time.Second))
+	c.wmu.Unlock()
+	c.ws.Close()
+}
+
+type interaction struct {
+	id        string
+	responses []api.InteractionResponse
+	original  string // Content after the latest response or edit
+}
+
+type file struct {
+	data        []byte
+	contentType string
+}
+
+// NewServer starts a fake Discord with a bot account and no users or channels.
+func NewServer() *Server {
+	s := &Server{
+		nextID:       1100000000000000000,
+		token:        "discordtest-bot-token",
+		users:        make(map[string]*api.User),
+		channels:     make(map[string]*api.Channel),
+		messages:     make(map[string][]*api.Message),
+		reactions:    make(map[string][]string),
+		typing:       make(map[string]int),
+		interactions: make(map[string]*interaction),
+		files:        make(map[string]file),
+		sessions:     make(map[string]*session),
+		heartbeat:    41250 * time.Millisecond,
+	}
+	s.bot = api.User{ID: s.newID(), Username: "goclaw", Bot: true}
+	s.app = api.Application{ID: s.newID(), Name: "GoClaw"}
+	s.users[s.bot.ID] = &s.bot
+
+	const p = apiPrefix
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /gateway", s.handleGateway)
+	mux.HandleFunc("GET "+p+"/gateway/bot", s.authed(s.handleGatewayBot))
+	mux.HandleFunc("GET "+p+"/users/@me", s.authed(s.handleCurrentUser))
+	mux.HandleFunc("GET "+p+"/applications/@me", s.authed(s.handleCurrentApplication))
+	mux.HandleFunc("POST "+p+"/users/@me/channels", s.authed(s.handleCreateDM))
+	mux.HandleFunc("GET "+p+"/channels/{channel}", s.authed(s.handleGetChannel))
+	mux.HandleFunc("POST "+p+"/channels/{channel}/messages", s.authed(s.handleCreateMessage))
+	mux.HandleFunc("PATCH "+p+"/channels/{channel}/messages/{message}", s.authed(s.handleEditMessage))
+	mux.HandleFunc("DELETE "+p+"/channels/{channel}/messages/{message}", s.authed(s.handleDeleteMessage))
+	mux.HandleFunc("PUT "+p+"/channels/{channel}/messages/{message}/reactions/{emoji}/@me", s.authed(s.handleReaction))
+	mux.HandleFunc("POST "+p+"/channels/{channel}/typing", s.authed(s.handleTyping))
+	mux.HandleFunc("PUT "+p+"/applications/{app}/commands", s.authed(s.handleCommands))
+	mux.HandleFunc("POST "+p+"/interactions/{id}/{token}/callback", s.handleInteractionCallback)
+	mux.HandleFunc("PATCH "+p+"/webhooks/{app}/{token}/messages/@original", s.handleEditOriginal)
+	mux.HandleFunc("GET /attachments/{channel}/{id}/{name}", s.handleAttachment)
+
+	s.srv = httptest.NewServer(mux)
+	return s
+}
+
+// URL returns the server root, e.g. http://127.0.0.1:41234
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// APIURL returns the REST API root to use as the client's base URL
+func (s *Server) APIURL() string {
+	return s.srv.URL + apiPrefix
+}
+
+// Token returns the bot token
+func (s *Server) Token() string {
+	return s.token
+}
+
+// Bot returns the bot user
+func (s *Server) Bot() api.User {
+	return s.bot
+}
+
+// ApplicationID returns the bot's application ID
+func (s *Server) ApplicationID() string {
+	return s.app.ID
+}
+
+// Config returns a channel config for the bot pointed at this server.
+func (s *Server) Config() config.Config {
+	return config.Config{
+		Enabled: true,
+		Token:   s.token,
+		APIURL:  s.APIURL(),
+	}
+}
+
+// Close stops the server and drops gateway connections.
+func (s *Server) Close() {
+	s.Disconnect()
+	s.srv.Close()
+}
+
+// newID returns a fresh snowflake; callers may or may not hold mu
+func (s *Server) newID() string {
+	s.nextID++
+	return strconv.FormatInt(s.nextID, 10)
+}
+
+// SetHeartbeatInterval changes the interval sent in HELLO to new connections.
+func (s *Server) SetHeartbeatInterval(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.heartbeat = d
+}
+
+// RateLimit makes the next n REST requests fail with 429.
+func (s *Server) RateLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = n
+}
+
+// DisallowMessageContent rejects identifies that ask for the privileged
+// message content intent, as Discord does when it isn't enabled for the app.
+func (s *Server) DisallowMessageContent() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noContentIntent = true
+}
+
+// AddUser creates a user and returns it.
+func (s *Server) AddUser(username string) api.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := &api.User{ID: s.newID(), Username: username}
+	s.users[u.ID] = u
+	return *u
+}
+
+// CreateChannel creates a guild text channel and returns its ID.
+func (s *Server) CreateChannel(guildID, name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := &api.Channel{ID: s.newID(), Type: api.ChannelGuildText, GuildID: guildID, Name: name}
+	s.channels[ch.ID] = ch
+	return ch.ID
+}
+
+// CreateThread creates a public thread in a guild channel and returns its ID.
+func (s *Server) CreateThread(parentID, name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	parent := s.channels[parentID]
+	ch := &api.Channel{ID: s.newID(), Type: api.ChannelPublicThread, ParentID: parentID, Name: name}
+	if parent != nil {
+		ch.GuildID = parent.GuildID
+	}
+	s.channels[ch.ID] = ch
+	return ch.ID
+}
+
+// DM returns the DM channel between the bot and a user, creating it if needed.
+func (s *Server) DM(userID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dmLocked(userID).ID
+}
+
+func (s *Server) dmLocked(userID string) *api.Channel {
+	for _, ch := range s.channels {
+		if ch.Type == api.ChannelDM && len(ch.Recipients) == 1 && ch.Recipients[0].ID == userID {
+			return ch
+		}
+	}
+	ch := &api.Channel{ID: s.newID(), Type: api.ChannelDM}
+	if u := s.users[userID]; u != nil {
+		ch.Recipients = []api.User{*u}
+	} else {
+		ch.Recipients = []api.User{{ID: userID}}
+	}
+	s.channels[ch.ID] = ch
+	return ch
+}
+
+// PostOption customizes a scripted message
+type PostOption func(s *Server, m *api.Message)
+
+// WithReply makes the message a reply to an earlier one.
+func WithReply(messageID string) PostOption {
+	return func(s *Server, m *api.Message) {
+		m.Type = api.MessageReply
+		m.MessageReference = &api.MessageReference{MessageID: messageID, ChannelID: m.ChannelID, GuildID: m.GuildID}
+		if ref := s.findLocked(m.ChannelID, messageID); ref != nil {
+			copied := *ref
+			copied.ReferencedMessage = nil
+			m.ReferencedMessage = &copied
+		}
+	}
+}
+
+// WithAttachment attaches a file, served by the fake CDN.
+func WithAttachment(filename, contentType string, data []byte) PostOption {
+	return func(s *Server, m *api.Message) {
+		m.Attachments = append(m.Attachments, s.storeFileLocked(m.ChannelID, filename, contentType, data))
+	}
+}
+
+// WithFlags sets message flags (e.g. api.MessageFlagVoice).
+func WithFlags(flags int) PostOption {
+	return func(s *Server, m *api.Message) {
+		m.Flags |= flags
+	}
+}
+
+// Post sends a message as authorID and dispatches MESSAGE_CREATE. Mentions
+// are taken from <@id> markup in the content.
+func (s *Server) Post(channelID, authorID, content string, opts ...PostOption) api.Message {
+	s.mu.Lock()
+	m := s.createMessageLocked(channelID, authorID, content)
+	for _, opt := range opts {
+		opt(s, m)
+	}
+	copied := *m
+	s.mu.Unlock()
+
+	s.Dispatch(api.EventMessageCreate, &copied)
+	return copied
+}
+
+func (s *Server) createMessageLocked(channelID, authorID, content string) *api.Message {
+	m := &api.Message{
+		ID:          s.newID(),
+		ChannelID:   channelID,
+		Content:     content,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Attachments: []api.Attachment{},
+		Mentions:    []api.User{},
+	}
+	if ch := s.channels[channelID]; ch != nil {
+		m.GuildID = ch.GuildID
+	}
+	if u := s.users[authorID]; u != nil {
+		author := *u
+		m.Author = &author
+	} else {
+		m.Author = &api.User{ID: authorID}
+	}
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		if u := s.users[match[1]]; u != nil {
+			m.Mentions = append(m.Mentions, *u)
+		}
+	}
+	m.MentionEveryone = strings.Contains(content, "@everyone")
+	s.messages[channelID] = append(s.messages[channelID], m)
+	return m
+}
+
+func (s *Server) findLocked(channelID, messageID string) *api.Message {
+	for _, m := range s.messages[channelID] {
+		if m.ID == messageID {
+			return m
+		}
+	}
+	return nil
+}
+
+func (s *Server) storeFileLocked(channelID, filename, contentType string, data []byte) api.Attachment {
+	id := s.newID()
+	path := "/attachments/" + channelID + "/" + id + "/" + filename
+	s.files[path] = file{data: data, contentType: contentType}
+	return api.Attachment{
+		ID:          id,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        len(data),
+		URL:         s.srv.URL + path + "?ex=signed",
+	}
+}
+
+// Interact invokes a slash command as userID in a channel and dispatches
+// INTERACTION_CREATE. It returns the interaction token.
+func (s *Server) Interact(channelID, userID, command string, options map[string]string) string {
+	s.mu.Lock()
+	in := &api.Interaction{
+		ID:            s.newID(),
+		ApplicationID: s.app.ID,
+		Type:          api.InteractionApplicationCommand,
+		ChannelID:     channelID,
+		Token:         "itoken-" + s.newID(),
+		Data:          &api.InteractionData{ID: s.newID(), Name: command, Type: api.CommandChatInput},
+	}
+	for _, name := range sortedKeys(options) {
+		value, _ := json.Marshal(options[name])
+		in.Data.Options = append(in.Data.Options, api.InteractionOption{Name: name, Type: api.OptionString, Value: value})
+	}
+	user := api.User{ID: userID}
+	if u := s.users[userID]; u != nil {
+		user = *u
+	}
+	if ch := s.channels[channelID]; ch != nil && ch.GuildID != "" {
+		in.GuildID = ch.GuildID
+		in.Member = &api.Member{User: &user}
+	} else {
+		in.User = &user
+	}
+	s.interactions[in.Token] = &interaction{id: in.ID}
+	s.mu.Unlock()
+
+	s.Dispatch(api.EventInteractionCreate, in)
+	return in.Token
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// Messages returns a channel's messages in their current (edited) state, oldest first.
+func (s *Server) Messages(channelID string) []api.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]api.Message, 0, len(s.messages[channelID]))
+	for _, m := range s.messages[channelID] {
+		out = append(out, *m)
+	}
+	return out
+}
+
+// BotMessages returns the messages the bot posted in a channel.
+func (s *Server) BotMessages(channelID string) []api.Message {
+	var out []api.Message
+	for _, m := range s.Messages(channelID) {
+		if m.Author != nil && m.Author.ID == s.bot.ID {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Reactions returns the emoji the bot added to a message.
+func (s *Server) Reactions(messageID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.reactions[messageID])
+}
+
+// Typing returns how often the bot triggered typing in a channel.
+func (s *Server) Typing(channelID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.typing[channelID]
+}
+
+// Commands returns the registered slash commands.
+func (s *Server) Commands() []api.ApplicationCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.commands)
+}
+
+// InteractionResponses returns the callbacks sent for an interaction, and
+// the response content after any edits of the original.
+func (s *Server) InteractionResponses(token string) ([]api.InteractionResponse, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	in := s.interactions[token]
+	if in == nil {
+		return nil, ""
+	}
+	return slices.Clone(in.responses), in.original
+}
+
+// File returns the content of an attachment by URL.
+func (s *Server) File(rawURL string) ([]byte, string, bool) {
+	path := strings.TrimPrefix(rawURL, s.srv.URL)
+	path, _, _ = strings.Cut(path, "?")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, ok := s.files[path]
+	return f.data, f.contentType, ok
+}
+
+// Identifies returns how many times a client identified (new sessions).
+func (s *Server) Identifies() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.identifies
+}
+
+// Resumes returns how many sessions were resumed.
+func (s *Server) Resumes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.resumes
+}
+
+// Connected reports whether any session has a live connection.
+func (s *Server) Connected() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sess := range s.sessions {
+		if sess.conn != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatch sends an event to every session. Sessions without a connection
+// queue it for replay on resume.
+func (s *Server) Dispatch(eventType string, data any) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sess := range s.sessions {
+		s.dispatchLocked(sess, eventType, raw)
+	}
+}
+
+func (s *Server) dispatchLocked(sess *session, eventType string, raw json.RawMessage) {
+	sess.seq++
+	seq := sess.seq
+	p := api.Payload{Op: api.OpDispatch, Type: eventType, Seq: &seq, Data: raw}
+	sess.log = append(sess.log, p)
+	if sess.conn != nil {
+		_ = sess.conn.send(p)
+	}
+}
+
+// Disconnect drops every gateway connection with a resumable close code.
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	var conns []*gwConn
+	for _, sess := range s.sessions {
+		if sess.conn != nil {
+			conns = append(conns, sess.conn)
+			sess.conn = nil
+		}
+	}
+	s.mu.Unlock()
+	for _, c := range conns {
+		c.close(4000, "unknown error")
+	}
+}
+
+// RequestReconnect sends op 7 (Reconnect) to every connection.
+func (s *Server) RequestReconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sess := range s.sessions {
+		if sess.conn != nil {
+			_ = sess.conn.send(api.Payload{Op: api.OpReconnect, Data: json.RawMessage("null")})
+		}
+	}
+}
+
+// InvalidateSessions forgets all sessions, so resumes fail and clients identify again.
+func (s *Server) InvalidateSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]*session)
+}
+
+func (s *Server) handleGateway(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &gwConn{ws: ws}
+	defer ws.Close()
+
+	s.mu.Lock()
+	interval := s.heartbeat
+	s.mu.Unlock()
+	hello, _ := json.Marshal(map[string]int64{"heartbeat_interval": interval.Milliseconds()})
+	if c.send(api.Payload{Op: api.OpHello, Data: hello}) != nil {
+		return
+	}
+
+	var first api.Payload
+	_ = ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if err := ws.ReadJSON(&first); err != nil {
+		return
+	}
+	_ = ws.SetReadDeadline(time.Time{})
+
+	var sess *session
+	switch first.Op {
+	case api.OpIdentify:
+		var identify struct {
+			Token   string `json:"token"`
+			Intents int    `json:"intents"`
+		}
+		_ = json.Unmarshal(first.Data, &identify)
+		s.mu.Lock()
+		if identify.Token != s.token {
+			s.mu.Unlock()
+			c.close(api.CloseAuthenticationFailed, "Authentication failed.")
+			return
+		}
+		if s.noContentIntent && identify.Intents&api.IntentMessageContent != 0 {
+			s.mu.Unlock()
+			c.close(api.CloseDisallowedIntents, "Disallowed intent(s).")
+			return
+		}
+		s.identifies++
+		sess = &session{id: "session-" + s.newID(), conn: c}
+		s.sessions[sess.id] = sess
+		ready, _ := json.Marshal(&api.Ready{
+			Version:          api.APIVersion,
+			User:             s.bot,
+			SessionID:        sess.id,
+			ResumeGatewayURL: s.wsURL(),
+			Application:      api.Application{ID: s.app.ID},
+		})
+		s.dispatchLocked(sess, api.EventReady, ready)
+		s.mu.Unlock()
+
+	case api.OpResume:
+		var resume struct {
+			Token     string `json:"token"`
+			SessionID string `json:"session_id"`
+			Seq       int64  `json:"seq"`
+		}
+		_ = json.Unmarshal(first.Data, &resume)
+		s.mu.Lock()
+		sess = s.sessions[resume.SessionID]
+		if sess == nil || resume.Token != s.token {
+			s.mu.Unlock()
+			_ = c.send(api.Payload{Op: api.OpInvalidSession, Data: json.RawMessage("false")})
+			// The client reconnects and identifies; wait for it to hang up
+			for {
+				if _, _, err := ws.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}
+		s.resumes++
+		sess.conn = c
+		for _, p := range sess.log {
+			if *p.Seq > resume.Seq {
+				_ = c.send(p)
+			}
+		}
+		s.dispatchLocked(sess, api.EventResumed, json.RawMessage("{}"))
+		s.mu.Unlock()
+
+	default:
+		c.close(4003, "Not authenticated.")
+		return
+	}
+
+	for {
+		var p api.Payload
+		if err := ws.ReadJSON(&p); err != nil {
+			break
+		}
+		if p.Op == api.OpHeartbeat {
+			_ = c.send(api.Payload{Op: api.OpHeartbeatACK, Data: json.RawMessage("null")})
+		}
+	}
+
+	s.mu.Lock()
+	if sess.conn == c {
+		sess.conn = nil
+	}
+	s.mu.Unlock()
+}
+
+func (s *Server) wsURL() string {
+	return "ws" + strings.TrimPrefix(s.srv.URL, "http") + "/gateway"
+}
+
+// authed checks the bot token and applies injected rate limits
+func (s *Server) authed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		limited := s.rateLimit > 0
+		if limited {
+			s.rateLimit--
+		}
+		s.mu.Unlock()
+		if limited {
+			writeJSON(w, http.StatusTooManyRequests, map[string]any{
+				"message": "You are being rate limited.", "retry_after": 0.01, "global": false,
+			})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bot "+s.token {
+			writeError(w, http.StatusUnauthorized, 0, "401: Unauthorized")
+			return
+		}
+		h(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status, code int, message string) {
+	writeJSON(w, status, map[string]any{"code": code, "message": message})
+}
+
+func (s *Server) handleGatewayBot(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &api.GatewayInfo{URL: s.wsURL(), Shards: 1})
+}
+
+func (s *Server) handleCurrentUser(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &s.bot)
+}
+
+func (s *Server) handleCurrentApplication(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &s.app)
+}
+
+func (s *Server) handleCreateDM(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RecipientID string `json:"recipient_id"`
+	}
+	if json.NewDecoder(r.Body).Decode(&body) != nil || body.RecipientID == "" {
+		writeError(w, http.StatusBadRequest, 50035, "Invalid Form Body")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.users[body.RecipientID] == nil {
+		writeError(w, http.StatusBadRequest, 50033, "Invalid Recipient(s)")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.dmLocked(body.RecipientID))
+}
+
+func (s *Server) handleGetChannel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := s.channels[r.PathValue("channel")]
+	if ch == nil {
+		writeError(w, http.StatusNotFound, 10003, "Unknown Channel")
+		return
+	}
+	writeJSON(w, http.StatusOK, ch)
+}
+
+func (s *Server) handleCreateMessage(w http.ResponseWriter, r *http.Request) {
+	channelID := r.PathValue("channel")
+	var body api.MessageSend
+	var upload *file
+	var uploadName string
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				writeError(w, http.StatusBadRequest, 50035, "Invalid Form Body")
+				return
+			}
+			data, _ := io.ReadAll(part)
+			switch part.FormName() {
+			case "payload_json":
+				if json.Unmarshal(data, &body) != nil {
+					writeError(w, http.StatusBadRequest, 50035, "Invalid Form Body")
+					return
+				}
+			case "files[0]":
+				upload = &file{data: data, contentType: part.Header.Get("Content-Type")}
+				uploadName = part.FileName()
+			}
+		}
+	} else if json.NewDecoder(r.Body).Decode(&body) != nil {
+		writeError(w, http.StatusBadRequest, 50035, "Invalid Form Body")
+		return
+	}
+
+	if body.Content == "" && upload == nil {
+		writeError(w, http.StatusBadRequest, 50006, "Cannot send an empty message")
+		return
+	}
+	if len([]rune(body.Content)) > api.MaxMessageLength {
+		writeError(w, http.StatusBadRequest, 50035, "Invalid Form Body: content must be 2000 or fewer in length")
+		return
+	}
+
+	s.mu.Lock()
+	if s.channels[channelID] == nil {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, 10003, "Unknown Channel")
+		return
+	}
+	m := s.createMessageLocked(channelID, s.bot.ID, body.Content)
+	if upload != nil {
+		m.Attachments = append(m.Attachments, s.storeFileLocked(channelID, uploadName, upload.contentType, upload.data))
+	}
+	if body.MessageReference != nil {
+		m.Type = api.MessageReply
+		m.MessageReference = body.MessageReference
+	}
+	m.Flags = body.Flags
+	copied := *m
+	s.mu.Unlock()
+
+	// Discord echoes the bot's own messages over the gateway
+	s.Dispatch(api.EventMessageCreate, &copied)
+	writeJSON(w, http.StatusOK, &copied)
+}
+
+func (s *Server) handleEditMessage(w http.ResponseWriter, r *http.Request) {
+	var body api.MessageSend
+	if json.NewDecoder(r.Body).Decode(&body) != nil {
+		writeError(w, http.StatusBadRequest, 50035, "Invalid Form Body")
+		return
+	}
+	if len([]rune(body.Content)) > api.MaxMessageLength {
+		writeError(w, http.StatusBadRequest, 50035, "Invalid Form Body: content must be 2000 or fewer in length")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m := s.findLocked(r.PathValue("channel"), r.PathValue("message"))
+	if m == nil {
+		writeError(w, http.StatusNotFound, 10008, "Unknown Message")
+		return
+	}
+	if m.Author == nil || m.Author.ID != s.bot.ID {
+		writeError(w, http.StatusForbidden, 50005, "Cannot edit a message authored by another user")
+		return
+	}
+	m.Content = body.Content
+	m.EditedTimestamp = time.Now().UTC().Format(time.RFC3339)
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (s *Server) handleDeleteMessage(w http.ResponseWriter, r *http.Request) {
+	channelID := r.PathValue("channel")
+	messageID := r.PathValue("message")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msgs := s.messages[channelID]
+	idx := slices.IndexFunc(msgs, func(m *api.Message) bool { return m.ID == messageID })
+	if idx < 0 {
+		writeError(w, http.StatusNotFound, 10008, "Unknown Message")
+		return
+	}
+	s.messages[channelID] = slices.Delete(msgs, idx, idx+1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleReaction(w http.ResponseWriter, r *http.Request) {
+	messageID := r.PathValue("message")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.findLocked(r.PathValue("channel"), messageID) == nil {
+		writeError(w, http.StatusNotFound, 10008, "Unknown Message")
+		return
+	}
+	emoji := r.PathValue("emoji")
+	if !slices.Contains(s.reactions[messageID], emoji) {
+		s.reactions[messageID] = append(s.reactions[messageID], emoji)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleTyping(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.typing[r.PathValue("channel")]++
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var commandName = regexp.MustCompile(`^[-_\p{Ll}\p{N}]{1,32}$`)
+
+func (s *Server) handleCommands(w http.ResponseWriter, r *http.Request) {
+	if r.PathValue("app") != s.app.ID {
+		writeError(w, http.StatusForbidden, 50001, "Missing Access")
+		return
+	}
+	var cmds []api.ApplicationCommand
+	if json.NewDecoder(r.Body).Decode(&cmds) != nil {
+		writeError(w, http.StatusBadRequest, 50035, "Invalid Form Body")
+		return
+	}
+	for _, cmd := range cmds {
+		if !commandName.MatchString(cmd.Name) || cmd.Description == "" || len([]rune(cmd.Description)) > 100 {
+			writeError(w, http.StatusBadRequest, 50035, fmt.Sprintf("Invalid Form Body: command %q", cmd.Name))
+			return
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range cmds {
+		cmds[i].ID = s.newID()
+	}
+	s.commands = cmds
+	writeJSON(w, http.StatusOK, cmds)
+}
+
+func (s *Server) handleInteractionCallback(w http.ResponseWriter, r *http.Request) {
+	var resp api.InteractionResponse
+	if json.NewDecoder(r.Body).Decode(&resp) != nil {
+		writeError(w, http.StatusBadRequest, 50035, "Invalid Form Body")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	in := s.interactions[r.PathValue("token")]
+	if in == nil || in.id != r.PathValue("id") {
+		writeError(w, http.StatusNotFound, 10062, "Unknown interaction")
+		return
+	}
+	if len(in.responses) > 0 {
+		writeError(w, http.StatusBadRequest, 40060, "Interaction has already been acknowledged.")
+		return
+	}
+	in.responses = append(in.responses, resp)
+	if resp.Data != nil {
+		in.original = resp.Data.Content
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleEditOriginal(w http.ResponseWriter, r *http.Request) {
+	var body api.MessageSend
+	if json.NewDecoder(r.Body).Decode(&body) != nil {
+		writeError(w, http.StatusBadRequest, 50035, "Invalid Form Body")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	in := s.interactions[r.PathValue("token")]
+	if in == nil || r.PathValue("app") != s.app.ID || len(in.responses) == 0 {
+		writeError(w, http.StatusNotFound, 10015, "Unknown Webhook")
+		return
+	}
+	in.original = body.Content
+	writeJSON(w, http.StatusOK, &api.Message{ID: in.id, Content: body.Content, Author: &s.bot})
+}
+
+func (s *Server) handleAttachment(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	f, ok := s.files[r.URL.Path]
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if f.contentType != "" {
+		w.Header().Set("Content-Type", f.contentType)
+	}
+	_, _ = w.Write(f.data)
+}
@@ -0,0 +1,122 @@
+package discord
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// FormatMessage adapts agent markdown to what Discord renders. Discord
+// understands most markdown itself (bold, italics, code, lists, links, quotes,
+// headings up to ###), but not tables or deeper headings: tables are put in a
+// code block so their columns line up, and #### and below become bold lines.
+func FormatMessage(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	inFence := false
+	inTable := false
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			if inTable {
+				out = append(out, "```")
+				inTable = false
+			}
+			inFence = !inFence
+			out = append(out, line)
+			continue
+		}
+		if inFence {
+			out = append(out, line)
+			continue
+		}
+
+		isTableRow := strings.HasPrefix(trimmed, "|") && strings.Count(trimmed, "|") >= 2
+		switch {
+		case isTableRow && !inTable:
+			out = append(out, "```", line)
+			inTable = true
+			continue
+		case isTableRow:
+			out = append(out, line)
+			continue
+		case inTable:
+			out = append(out, "```")
+			inTable = false
+		}
+
+		if strings.HasPrefix(trimmed, "####") {
+			if heading := strings.TrimSpace(strings.TrimLeft(trimmed, "#")); heading != "" {
+				line = "**" + heading + "**"
+			}
+		}
+		out = append(out, line)
+	}
+	if inTable {
+		out = append(out, "```")
+	}
+	return strings.Join(out, "\n")
+}
+
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	for maxLen > 0 && !utf8.RuneStart(s[maxLen]) {
+		maxLen--
+	}
+	return s[:maxLen] + "..."
+}
+
+// splitMessage splits a message into chunks of at most maxLen bytes (which
+// keeps them under Discord's character limit). A code block cut in two is
+// closed at the end of one chunk and reopened at the start of the next.
+func splitMessage(text string, maxLen int) []string {
+	if len(text) <= maxLen {
+		return []string{text}
+	}
+
+	// Room for a closing fence, and for the reopening fence with its language
+	const fenceRoom = 24
+	var chunks []string
+	reopen := ""
+	for len(text) > 0 {
+		limit := maxLen - fenceRoom - len(reopen)
+		end := min(limit, len(text))
+		// Try to split at a newline
+		if end < len(text) {
+			if idx := strings.LastIndex(text[:end], "\n"); idx > end/2 {
+				end = idx + 1
+			} else {
+				for end > 0 && !utf8.RuneStart(text[end]) {
+					end--
+				}
+			}
+		}
+		chunk := reopen + text[:end]
+		text = text[end:]
+
+		reopen = ""
+		if fence, open := openFence(chunk); open && len(text) > 0 {
+			chunk = strings.TrimRight(chunk, "\n") + "\n```"
+			reopen = fence + "\n"
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// openFence reports whether text ends inside a code block, and returns the
+// fence line that opened it (e.g. "```go")
+func openFence(text string) (string, bool) {
+	fence := ""
+	open := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			open = !open
+			fence = trimmed
+		}
+	}
+	return fence, open
+}
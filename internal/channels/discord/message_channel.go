@@ -0,0 +1,75 @@
+package discord
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/roelfdiedericks/goclaw/internal/channels/discord/api"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// MessageChannelAdapter adapts the Discord Bot to the MessageChannel interface.
+// Chat IDs are channel IDs (DM, guild channel or thread); message IDs are
+// Discord message IDs.
+type MessageChannelAdapter struct {
+	bot       *Bot
+	mediaBase string
+}
+
+// NewMessageChannelAdapter creates a new adapter for the Discord bot
+func NewMessageChannelAdapter(bot *Bot, mediaBase string) *MessageChannelAdapter {
+	return &MessageChannelAdapter{
+		bot:       bot,
+		mediaBase: mediaBase,
+	}
+}
+
+// SendText sends a formatted text message to a Discord channel. Long text is
+// split over several messages; the first one's ID is returned.
+func (a *MessageChannelAdapter) SendText(chatID string, text string) (string, error) {
+	return a.bot.sendText(chatID, text, "")
+}
+
+// SendMedia uploads a file to a Discord channel
+func (a *MessageChannelAdapter) SendMedia(chatID string, filePath string, caption string) (string, error) {
+	absPath := a.resolveMediaPath(filePath)
+	L_debug("discord: sending media", "channel", chatID, "path", absPath)
+	return a.bot.sendMediaFile(chatID, absPath, caption)
+}
+
+// EditMessage replaces the text of one of the bot's messages
+func (a *MessageChannelAdapter) EditMessage(chatID string, messageID string, text string) error {
+	msg := &api.MessageSend{Content: truncate(FormatMessage(text), api.MaxMessageLength-10), AllowedMentions: noMassMentions}
+	if _, err := a.bot.client.EditMessage(a.bot.ctx, chatID, messageID, msg); err != nil {
+		return fmt.Errorf("failed to edit message: %w", err)
+	}
+	return nil
+}
+
+// DeleteMessage deletes a message (the bot's own, or others' with Manage Messages)
+func (a *MessageChannelAdapter) DeleteMessage(chatID string, messageID string) error {
+	if err := a.bot.client.DeleteMessage(a.bot.ctx, chatID, messageID); err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+	L_debug("discord: deleted message", "channel", chatID, "message", messageID)
+	return nil
+}
+
+// React adds a reaction emoji to a Discord message. Custom emoji are given
+// as name:id.
+func (a *MessageChannelAdapter) React(chatID string, messageID string, emoji string) error {
+	if err := a.bot.client.AddReaction(a.bot.ctx, chatID, messageID, emoji); err != nil {
+		return fmt.Errorf("failed to add reaction: %w", err)
+	}
+	L_debug("discord: reaction sent", "channel", chatID, "message", messageID, "emoji", emoji)
+	return nil
+}
+
+func (a *MessageChannelAdapter) resolveMediaPath(path string) string {
+	if strings.HasPrefix(path, "./media/") {
+		subpath := strings.TrimPrefix(path, "./media/")
+		return filepath.Join(a.mediaBase, subpath)
+	}
+	return path
+}
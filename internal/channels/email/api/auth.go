@@ -0,0 +1,162 @@
+package api
+
+import (
+	"strings"
+)
+
+// Authenticated reports whether the mail server identified by authservID
+// verified the From address: its Authentication-Results header records
+// dmarc=pass for the From domain, or dkim=pass for a signing domain aligned
+// with it.
+//
+// Only the topmost header written by that server counts. Senders can add
+// Authentication-Results headers of their own, but the receiving server
+// adds its header above everything the message arrived with. Mail without
+// a header from the server is not authenticated.
+func (m *Message) Authenticated(authservID string) bool {
+	if authservID == "" || m.From == nil {
+		return false
+	}
+	_, fromDomain, ok := strings.Cut(strings.ToLower(m.From.Address), "@")
+	if !ok || fromDomain == "" {
+		return false
+	}
+
+	for _, header := range m.AuthResults {
+		id, results := parseAuthResults(header)
+		if !strings.EqualFold(id, authservID) {
+			continue
+		}
+		for _, r := range results {
+			if r.result != "pass" {
+				continue
+			}
+			switch r.method {
+			case "dmarc":
+				if from := r.props["header.from"]; from == "" || from == fromDomain {
+					return true
+				}
+			case "dkim":
+				if aligned(r.props["header.d"], fromDomain) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// authResult is one method's result in an Authentication-Results header
+type authResult struct {
+	method string            // e.g. dkim, spf, dmarc
+	result string            // e.g. pass, fail, none
+	props  map[string]string // e.g. header.d, header.from (lowercased)
+}
+
+// parseAuthResults parses an Authentication-Results header (RFC 8601) into
+// the authserv-id and the results it lists
+func parseAuthResults(header string) (string, []authResult) {
+	parts := splitUnquoted(stripComments(header), ';')
+	if len(parts) == 0 {
+		return "", nil
+	}
+	// authserv-id, optionally followed by a version
+	fields := strings.Fields(parts[0])
+	if len(fields) == 0 {
+		return "", nil
+	}
+	id := fields[0]
+
+	var results []authResult
+	for _, part := range parts[1:] {
+		tokens := splitUnquoted(part, ' ', '\t', '\r', '\n')
+		if len(tokens) == 0 {
+			continue
+		}
+		method, result, ok := strings.Cut(tokens[0], "=")
+		if !ok {
+			continue // "none": no results
+		}
+		method, _, _ = strings.Cut(method, "/") // Method version
+		r := authResult{
+			method: strings.ToLower(method),
+			result: strings.ToLower(result),
+			props:  make(map[string]string),
+		}
+		for _, t := range tokens[1:] {
+			if k, v, ok := strings.Cut(t, "="); ok {
+				r.props[strings.ToLower(k)] = strings.ToLower(strings.Trim(v, `"`))
+			}
+		}
+		results = append(results, r)
+	}
+	return id, results
+}
+
+// aligned reports relaxed DMARC alignment of a signing domain with the From
+// domain, approximated as one being the other or a subdomain of it
+func aligned(signing, from string) bool {
+	if signing == "" || !strings.Contains(signing, ".") {
+		return false
+	}
+	return signing == from || strings.HasSuffix(from, "."+signing) || strings.HasSuffix(signing, "."+from)
+}
+
+// stripComments removes (comments), which may nest, outside quoted strings
+func stripComments(s string) string {
+	var b strings.Builder
+	depth, quoted := 0, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && (quoted || depth > 0):
+			if depth == 0 {
+				b.WriteByte(c)
+				b.WriteByte(s[i+1])
+			}
+			i++
+		case depth == 0 && c == '"':
+			quoted = !quoted
+			b.WriteByte(c)
+		case !quoted && c == '(':
+			depth++
+		case !quoted && c == ')' && depth > 0:
+			depth--
+			if depth == 0 {
+				b.WriteByte(' ')
+			}
+		case depth == 0:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// splitUnquoted splits s at any of seps outside quoted strings, dropping
+// empty parts
+func splitUnquoted(s string, seps ...byte) []string {
+	var parts []string
+	var cur strings.Builder
+	quoted := false
+	flush := func() {
+		if p := strings.TrimSpace(cur.String()); p != "" {
+			parts = append(parts, p)
+		}
+		cur.Reset()
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			quoted = !quoted
+			cur.WriteByte(c)
+		case !quoted && strings.IndexByte(string(seps), c) >= 0:
+			flush()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return parts
+}
@@ -0,0 +1,42 @@
+package api_test
+
+import (
+	"testing"
+)
+
+func TestAuthenticated(t *testing.T) {
+	const server = "mx.example.org"
+	tests := []struct {
+		name    string
+		headers string
+		from    string
+		want    bool
+	}{
+		{"no header", "", "alice@example.com", false},
+		{"dmarc pass", "Authentication-Results: mx.example.org; spf=pass smtp.mailfrom=example.com; dmarc=pass (p=reject) header.from=example.com\n", "alice@example.com", true},
+		{"dmarc pass other domain", "Authentication-Results: mx.example.org; dmarc=pass header.from=evil.test\n", "alice@example.com", false},
+		{"dmarc none", "Authentication-Results: mx.example.org; dmarc=none header.from=example.com\n", "alice@example.com", false},
+		{"dmarc fail", "Authentication-Results: mx.example.org; dmarc=fail header.from=example.com\n", "alice@example.com", false},
+		{"spf only", "Authentication-Results: mx.example.org; spf=pass smtp.mailfrom=example.com\n", "alice@example.com", false},
+		{"aligned dkim", "Authentication-Results: mx.example.org 1; dkim=pass header.d=mail.example.com header.s=s1\n", "alice@example.com", true},
+		{"unaligned dkim", "Authentication-Results: mx.example.org; dkim=pass header.d=evil.test\n", "alice@example.com", false},
+		{"folded", "Authentication-Results: mx.example.org;\n\tdkim=pass (2048-bit key; secure) header.d=example.com;\n\tdmarc=none\n", "alice@example.com", true},
+		{"other server", "Authentication-Results: mx.evil.test; dmarc=pass header.from=example.com\n", "alice@example.com", false},
+		{"forged below real", "Authentication-Results: mx.example.org; dmarc=fail header.from=example.com\nAuthentication-Results: mx.example.org; dmarc=pass header.from=example.com\n", "alice@example.com", false},
+		{"case", "Authentication-Results: MX.Example.org; DMARC=Pass header.from=Example.COM\n", "Alice@EXAMPLE.com", true},
+		{"quoted reason", "Authentication-Results: mx.example.org; dkim=fail reason=\"bad; sig\" header.d=example.com; dmarc=pass header.from=example.com\n", "alice@example.com", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := mustParse(t, tt.headers+"From: "+tt.from+"\n\nbody\n")
+			if got := msg.Authenticated(server); got != tt.want {
+				t.Errorf("Authenticated = %v, want %v (results %q)", got, tt.want, msg.AuthResults)
+			}
+		})
+	}
+
+	msg := mustParse(t, "Authentication-Results: mx.example.org; dmarc=pass header.from=example.com\nFrom: alice@example.com\n\nbody\n")
+	if msg.Authenticated("") {
+		t.Error("authenticated without a configured server")
+	}
+}
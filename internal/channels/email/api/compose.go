@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Outgoing is a message to send
+type Outgoing struct {
+	From        *mail.Address
+	To          []*mail.Address
+	Subject     string
+	MessageID   string   // Without angle brackets; generated by Bytes if empty
+	InReplyTo   string   // Message ID being answered
+	References  []string // Thread ancestry, oldest first
+	Text        string   // Plain text body
+	HTML        string   // Optional HTML version of the body
+	Attachments []Attachment
+
+	// AutoReplied marks the message as an automatic response (RFC 3834), so
+	// other auto-responders don't answer it
+	AutoReplied bool
+}
+
+// NewMessageID returns a unique message ID in the sender's domain
+func NewMessageID(fromAddress string) string {
+	domain := "goclaw.local"
+	if _, d, ok := strings.Cut(fromAddress, "@"); ok && d != "" {
+		domain = d
+	}
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return fmt.Sprintf("%s.goclaw@%s", hex.EncodeToString(b[:]), domain)
+}
+
+// ReplySubject prefixes a subject with "Re: " unless it already has it
+func ReplySubject(subject string) string {
+	subject = strings.TrimSpace(subject)
+	if len(subject) >= 3 && strings.EqualFold(subject[:3], "re:") {
+		return subject
+	}
+	if subject == "" {
+		return "Re: your message"
+	}
+	return "Re: " + subject
+}
+
+// Bytes renders the message as RFC 5322 with CRLF line endings
+func (o *Outgoing) Bytes() ([]byte, error) {
+	if o.From == nil || len(o.To) == 0 {
+		return nil, fmt.Errorf("email: message needs a sender and a recipient")
+	}
+	if o.MessageID == "" {
+		o.MessageID = NewMessageID(o.From.Address)
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("From", o.From.String())
+	header("To", formatAddresses(o.To))
+	header("Subject", mime.QEncoding.Encode("utf-8", o.Subject))
+	header("Message-ID", "<"+o.MessageID+">")
+	if o.InReplyTo != "" {
+		header("In-Reply-To", "<"+o.InReplyTo+">")
+	}
+	if len(o.References) > 0 {
+		refs := make([]string, len(o.References))
+		for i, id := range o.References {
+			refs[i] = "<" + id + ">"
+		}
+		// Fold so no line gets near the 998 character limit
+		header("References", strings.Join(refs, "\r\n "))
+	}
+	if o.AutoReplied {
+		header("Auto-Submitted", "auto-replied")
+	}
+	header("MIME-Version", "1.0")
+
+	if len(o.Attachments) == 0 {
+		if err := o.writeBody(&buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	header("Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()}))
+	buf.WriteString("\r\n")
+
+	var body bytes.Buffer
+	if err := o.writeBody(&body); err != nil {
+		return nil, err
+	}
+	bodyHeader, bodyContent, _ := bytes.Cut(body.Bytes(), []byte("\r\n\r\n"))
+	h := make(textproto.MIMEHeader)
+	for _, line := range strings.Split(string(bodyHeader), "\r\n") {
+		if name, value, ok := strings.Cut(line, ": "); ok {
+			h.Set(name, value)
+		}
+	}
+	w, err := mw.CreatePart(h)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(bodyContent); err != nil {
+		return nil, err
+	}
+
+	for _, att := range o.Attachments {
+		ah := make(textproto.MIMEHeader)
+		contentType := att.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		ah.Set("Content-Type", contentType)
+		ah.Set("Content-Transfer-Encoding", "base64")
+		ah.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": att.Filename}))
+		w, err := mw.CreatePart(ah)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(w, att.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBody writes the Content-Type header(s), a blank line and the text
+// body, as text/plain or multipart/alternative with HTML
+func (o *Outgoing) writeBody(buf *bytes.Buffer) error {
+	if o.HTML == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		return writeQP(buf, o.Text)
+	}
+
+	mw := multipart.NewWriter(buf)
+	fmt.Fprintf(buf, "Content-Type: %s\r\n\r\n", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": mw.Boundary()}))
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", o.Text},
+		{"text/html; charset=utf-8", o.HTML},
+	} {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Type", part.contentType)
+		h.Set("Content-Transfer-Encoding", "quoted-printable")
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		var qp bytes.Buffer
+		if err := writeQP(&qp, part.content); err != nil {
+			return err
+		}
+		if _, err := w.Write(qp.Bytes()); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+func writeQP(buf *bytes.Buffer, text string) error {
+	qp := quotedprintable.NewWriter(buf)
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeBase64 writes base64 in 76 character lines
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := w.Write([]byte(encoded[:n] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
@@ -0,0 +1,704 @@
+// Package api implements the mail protocols the email channel needs: a small
+// IMAP client (login, select, search, fetch, flags, IDLE), SMTP submission,
+// and parsing and composing of MIME messages.
+package api
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Security is how a connection to a mail server is protected
+type Security string
+
+// Connection security modes
+const (
+	SecurityTLS      Security = "tls"      // TLS from the start (IMAP 993, SMTP 465)
+	SecurityStartTLS Security = "starttls" // Plain connection upgraded with STARTTLS (IMAP 143, SMTP 587)
+	SecurityNone     Security = "none"     // Unencrypted (local test servers only)
+)
+
+// commandTimeout bounds a single IMAP command, including large fetches
+const commandTimeout = 2 * time.Minute
+
+// Response is one IMAP server response. Status responses (OK, NO, BAD, BYE,
+// PREAUTH) carry a response code and text; data responses carry parsed
+// fields, where each field is a string (atom, quoted string or literal),
+// a []any (parenthesized list) or nil (NIL).
+type Response struct {
+	Tag    string // "*" for untagged, "+" for continuation requests, or the command tag
+	Status string // OK, NO, BAD, BYE or PREAUTH; empty for data responses
+	Code   string // Response code without brackets, e.g. "UIDVALIDITY 3"
+	Text   string // Human-readable text of status and continuation responses
+	Fields []any  // Data response fields, e.g. ["12", "EXISTS"]
+}
+
+// StatusError is a NO or BAD completion of an IMAP command
+type StatusError struct {
+	Command string
+	Status  string
+	Text    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("imap: %s failed: %s %s", e.Command, e.Status, e.Text)
+}
+
+// IMAPClient is a connection to an IMAP server. It is not safe for
+// concurrent use, except that Close may be called from any goroutine.
+type IMAPClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+	host string
+	tag  int
+	caps map[string]bool
+
+	closeOnce sync.Once
+}
+
+// MailboxStatus is what SELECT reports about a mailbox
+type MailboxStatus struct {
+	Exists      uint32
+	UIDValidity uint32
+	UIDNext     uint32
+}
+
+// DialIMAP connects to an IMAP server at addr (host:port) and reads its
+// greeting. With SecurityStartTLS the connection is upgraded before return.
+func DialIMAP(ctx context.Context, addr string, security Security) (*IMAPClient, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("imap: bad address %q: %w", addr, err)
+	}
+
+	conn, err := dial(ctx, addr, host, security)
+	if err != nil {
+		return nil, fmt.Errorf("imap: %w", err)
+	}
+
+	c := &IMAPClient{conn: conn, host: host}
+	c.setConn(conn)
+
+	_ = conn.SetDeadline(time.Now().Add(30 * time.Second))
+	greeting, err := c.readResponse()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("imap: reading greeting: %w", err)
+	}
+	if greeting.Status != "OK" && greeting.Status != "PREAUTH" {
+		conn.Close()
+		return nil, fmt.Errorf("imap: server refused connection: %s %s", greeting.Status, greeting.Text)
+	}
+
+	if err := c.capability(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if security == SecurityStartTLS {
+		if !c.caps["STARTTLS"] {
+			conn.Close()
+			return nil, errors.New("imap: server does not support STARTTLS")
+		}
+		if _, err := c.command("STARTTLS", nil); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("imap: TLS handshake: %w", err)
+		}
+		c.setConn(tlsConn)
+		// Capabilities change after STARTTLS (e.g. LOGINDISABLED goes away)
+		if err := c.capability(); err != nil {
+			tlsConn.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// dial opens a TCP connection, wrapped in TLS for SecurityTLS
+func dial(ctx context.Context, addr, host string, security Security) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	switch security {
+	case SecurityTLS:
+		td := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}}
+		return td.DialContext(ctx, "tcp", addr)
+	case SecurityStartTLS, SecurityNone, "":
+		return dialer.DialContext(ctx, "tcp", addr)
+	default:
+		return nil, fmt.Errorf("unknown security mode %q", security)
+	}
+}
+
+func (c *IMAPClient) setConn(conn net.Conn) {
+	c.conn = conn
+	c.r = bufio.NewReader(conn)
+	c.w = bufio.NewWriter(conn)
+}
+
+// Close closes the connection without logging out
+func (c *IMAPClient) Close() error {
+	var err error
+	c.closeOnce.Do(func() { err = c.conn.Close() })
+	return err
+}
+
+// HasCapability reports whether the server advertised a capability (e.g. "IDLE")
+func (c *IMAPClient) HasCapability(name string) bool {
+	return c.caps[strings.ToUpper(name)]
+}
+
+func (c *IMAPClient) capability() error {
+	caps := make(map[string]bool)
+	_, err := c.command("CAPABILITY", func(r *Response) {
+		if len(r.Fields) > 0 && strings.EqualFold(fieldString(r.Fields[0]), "CAPABILITY") {
+			for _, f := range r.Fields[1:] {
+				caps[strings.ToUpper(fieldString(f))] = true
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	c.caps = caps
+	return nil
+}
+
+// Login authenticates with AUTHENTICATE PLAIN when the server offers it
+// (any characters work), and with LOGIN otherwise
+func (c *IMAPClient) Login(username, password string) error {
+	if c.caps["AUTH=PLAIN"] {
+		ir := base64.StdEncoding.EncodeToString([]byte("\x00" + username + "\x00" + password))
+		_, err := c.commandWithContinuation("AUTHENTICATE PLAIN", ir)
+		if err != nil {
+			return err
+		}
+	} else {
+		if c.caps["LOGINDISABLED"] {
+			return errors.New("imap: server disabled LOGIN on this connection (use TLS or STARTTLS)")
+		}
+		u, err := quote(username)
+		if err != nil {
+			return err
+		}
+		p, err := quote(password)
+		if err != nil {
+			return err
+		}
+		if _, err := c.command("LOGIN "+u+" "+p, nil); err != nil {
+			return err
+		}
+	}
+	// Servers may advertise more capabilities once authenticated
+	return c.capability()
+}
+
+// Select opens a mailbox read-write
+func (c *IMAPClient) Select(mailbox string) (*MailboxStatus, error) {
+	name, err := quote(mailbox)
+	if err != nil {
+		return nil, err
+	}
+	status := &MailboxStatus{}
+	_, err = c.command("SELECT "+name, func(r *Response) {
+		if r.Status == "OK" && r.Code != "" {
+			key, value, _ := strings.Cut(r.Code, " ")
+			n, _ := strconv.ParseUint(value, 10, 32)
+			switch strings.ToUpper(key) {
+			case "UIDVALIDITY":
+				status.UIDValidity = uint32(n)
+			case "UIDNEXT":
+				status.UIDNext = uint32(n)
+			}
+			return
+		}
+		if len(r.Fields) == 2 && strings.EqualFold(fieldString(r.Fields[1]), "EXISTS") {
+			n, _ := strconv.ParseUint(fieldString(r.Fields[0]), 10, 32)
+			status.Exists = uint32(n)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// SearchUnseen returns the UIDs of unread messages that arrived on or after
+// the day of since (IMAP dates have no time of day). A zero since searches
+// all unread messages.
+func (c *IMAPClient) SearchUnseen(since time.Time) ([]uint32, error) {
+	criteria := "UNSEEN"
+	if !since.IsZero() {
+		criteria += " SINCE " + since.Format("2-Jan-2006")
+	}
+	var uids []uint32
+	_, err := c.command("UID SEARCH "+criteria, func(r *Response) {
+		if len(r.Fields) == 0 || !strings.EqualFold(fieldString(r.Fields[0]), "SEARCH") {
+			return
+		}
+		for _, f := range r.Fields[1:] {
+			if n, err := strconv.ParseUint(fieldString(f), 10, 32); err == nil {
+				uids = append(uids, uint32(n))
+			}
+		}
+	})
+	return uids, err
+}
+
+// Fetch returns the full raw message with the given UID without marking it
+// read. It returns nil and no error if the message no longer exists.
+func (c *IMAPClient) Fetch(uid uint32) ([]byte, error) {
+	var body []byte
+	_, err := c.command(fmt.Sprintf("UID FETCH %d (UID BODY.PEEK[])", uid), func(r *Response) {
+		if len(r.Fields) < 3 || !strings.EqualFold(fieldString(r.Fields[1]), "FETCH") {
+			return
+		}
+		items, _ := r.Fields[2].([]any)
+		var gotUID uint32
+		var data []byte
+		for i := 0; i+1 < len(items); i += 2 {
+			switch strings.ToUpper(fieldString(items[i])) {
+			case "UID":
+				n, _ := strconv.ParseUint(fieldString(items[i+1]), 10, 32)
+				gotUID = uint32(n)
+			case "BODY[]":
+				data = []byte(fieldString(items[i+1]))
+			}
+		}
+		// Servers may send unsolicited FETCH responses for other messages
+		if gotUID == uid && data != nil {
+			body = data
+		}
+	})
+	return body, err
+}
+
+// MarkSeen sets the \Seen flag on a message
+func (c *IMAPClient) MarkSeen(uid uint32) error {
+	_, err := c.command(fmt.Sprintf(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid), nil)
+	return err
+}
+
+// Noop keeps the connection alive and lets the server report changes
+func (c *IMAPClient) Noop() error {
+	_, err := c.command("NOOP", nil)
+	return err
+}
+
+// Logout ends the session and closes the connection
+func (c *IMAPClient) Logout() error {
+	_, err := c.command("LOGOUT", nil)
+	c.Close()
+	return err
+}
+
+// Idle waits in IMAP IDLE until the server reports new mail (returns true),
+// or until timeout or ctx ends (returns false). Servers drop idle clients
+// after 30 minutes, so timeout should be below that.
+func (c *IMAPClient) Idle(ctx context.Context, timeout time.Duration) (bool, error) {
+	if !c.caps["IDLE"] {
+		return false, errors.New("imap: server does not support IDLE")
+	}
+
+	c.tag++
+	tag := "A" + strconv.Itoa(c.tag)
+	_ = c.conn.SetDeadline(time.Now().Add(30 * time.Second))
+	if err := c.writeLine(tag + " IDLE"); err != nil {
+		return false, err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return false, err
+	}
+	if resp.Tag != "+" {
+		if resp.Tag == tag {
+			return false, &StatusError{Command: "IDLE", Status: resp.Status, Text: resp.Text}
+		}
+		return false, fmt.Errorf("imap: unexpected response to IDLE: %s %s", resp.Status, resp.Text)
+	}
+
+	// Read responses in the background while waiting; the reader finishes
+	// at the tagged completion that follows DONE
+	_ = c.conn.SetDeadline(time.Time{})
+	responses := make(chan *Response)
+	readErr := make(chan error, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			r, err := c.readResponse()
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case responses <- r:
+			case <-stop:
+				return
+			}
+			if r.Tag == tag {
+				return
+			}
+		}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	newMail := false
+	done := false
+	sendDone := func() error {
+		if done {
+			return nil
+		}
+		done = true
+		_ = c.conn.SetDeadline(time.Now().Add(30 * time.Second))
+		return c.writeLine("DONE")
+	}
+
+	ctxDone := ctx.Done()
+	for {
+		select {
+		case <-ctxDone:
+			ctxDone = nil
+			if err := sendDone(); err != nil {
+				return false, err
+			}
+			// Don't wait long on a server that doesn't answer once we're stopping
+			_ = c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+		case <-timer.C:
+			if err := sendDone(); err != nil {
+				return false, err
+			}
+		case err := <-readErr:
+			return false, err
+		case r := <-responses:
+			switch {
+			case r.Tag == tag:
+				_ = c.conn.SetDeadline(time.Time{})
+				if r.Status != "OK" {
+					return newMail, &StatusError{Command: "IDLE", Status: r.Status, Text: r.Text}
+				}
+				return newMail, nil
+			case r.Status == "BYE":
+				return false, fmt.Errorf("imap: server closed connection: %s", r.Text)
+			case len(r.Fields) == 2 && (strings.EqualFold(fieldString(r.Fields[1]), "EXISTS") || strings.EqualFold(fieldString(r.Fields[1]), "RECENT")):
+				newMail = true
+				if err := sendDone(); err != nil {
+					return false, err
+				}
+			}
+		}
+	}
+}
+
+// command runs a command and waits for its tagged completion. Untagged
+// responses are passed to handle (if not nil).
+func (c *IMAPClient) command(cmd string, handle func(*Response)) (*Response, error) {
+	return c.run(cmd, "", handle)
+}
+
+// commandWithContinuation runs a command that expects one continuation
+// request, answered with line (used for AUTHENTICATE)
+func (c *IMAPClient) commandWithContinuation(cmd, line string) (*Response, error) {
+	return c.run(cmd, line, nil)
+}
+
+func (c *IMAPClient) run(cmd, continuation string, handle func(*Response)) (*Response, error) {
+	c.tag++
+	tag := "A" + strconv.Itoa(c.tag)
+	name, _, _ := strings.Cut(cmd, " ")
+	if name == "UID" {
+		name = strings.Join(strings.Fields(cmd)[:2], " ")
+	}
+
+	_ = c.conn.SetDeadline(time.Now().Add(commandTimeout))
+	defer func() { _ = c.conn.SetDeadline(time.Time{}) }()
+
+	if err := c.writeLine(tag + " " + cmd); err != nil {
+		return nil, err
+	}
+	for {
+		r, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		switch r.Tag {
+		case tag:
+			if r.Status != "OK" {
+				return r, &StatusError{Command: name, Status: r.Status, Text: r.Text}
+			}
+			return r, nil
+		case "+":
+			if continuation == "" {
+				return nil, fmt.Errorf("imap: unexpected continuation request for %s", name)
+			}
+			// Answer the continuation request; cancel if asked again
+			if err := c.writeLine(continuation); err != nil {
+				return nil, err
+			}
+			continuation = "*"
+		default:
+			if r.Status == "BYE" && name != "LOGOUT" {
+				return nil, fmt.Errorf("imap: server closed connection: %s", r.Text)
+			}
+			if handle != nil {
+				handle(r)
+			}
+		}
+	}
+}
+
+func (c *IMAPClient) writeLine(line string) error {
+	if _, err := c.w.WriteString(line + "\r\n"); err != nil {
+		return fmt.Errorf("imap: write: %w", err)
+	}
+	if err := c.w.Flush(); err != nil {
+		return fmt.Errorf("imap: write: %w", err)
+	}
+	return nil
+}
+
+// quote returns s as an IMAP quoted string
+func quote(s string) (string, error) {
+	if strings.ContainsAny(s, "\r\n\x00") {
+		return "", errors.New("imap: line breaks are not allowed in strings")
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`, nil
+}
+
+// fieldString returns a field as a string ("" for lists and NIL)
+func fieldString(f any) string {
+	s, _ := f.(string)
+	return s
+}
+
+// --- Response parsing ---
+
+// readResponse reads one complete response, including any literals
+func (c *IMAPClient) readResponse() (*Response, error) {
+	return ReadResponse(c.r)
+}
+
+// ReadResponse reads one IMAP response from r. Exported for the test server.
+func ReadResponse(r *bufio.Reader) (*Response, error) {
+	tag, err := readAtom(r)
+	if err != nil {
+		return nil, err
+	}
+	resp := &Response{Tag: tag}
+
+	if tag == "+" {
+		text, err := readRestOfLine(r)
+		resp.Text = text
+		return resp, err
+	}
+	if err := skipSpace(r); err != nil {
+		return nil, err
+	}
+
+	first, err := readAtom(r)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToUpper(first) {
+	case "OK", "NO", "BAD", "BYE", "PREAUTH":
+		resp.Status = strings.ToUpper(first)
+		text, err := readRestOfLine(r)
+		if err != nil {
+			return nil, err
+		}
+		text = strings.TrimPrefix(text, " ")
+		if strings.HasPrefix(text, "[") {
+			if end := strings.Index(text, "]"); end > 0 {
+				resp.Code = text[1:end]
+				text = strings.TrimPrefix(text[end+1:], " ")
+			}
+		}
+		resp.Text = text
+		return resp, nil
+	}
+
+	rest, err := readFields(r, '\n')
+	if err != nil {
+		return nil, err
+	}
+	resp.Fields = append([]any{first}, rest...)
+	return resp, nil
+}
+
+// readFields parses fields until the end of the line (end '\n') or the
+// closing parenthesis of a list (end ')')
+func readFields(r *bufio.Reader, end byte) ([]any, error) {
+	var fields []any
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch b {
+		case ' ':
+			continue
+		case '\r':
+			if b, err = r.ReadByte(); err != nil {
+				return nil, err
+			}
+			if b != '\n' {
+				return nil, fmt.Errorf("imap: expected LF after CR")
+			}
+			fallthrough
+		case '\n':
+			if end != '\n' {
+				return nil, fmt.Errorf("imap: unterminated list")
+			}
+			return fields, nil
+		case ')':
+			if end != ')' {
+				return nil, fmt.Errorf("imap: unexpected )")
+			}
+			return fields, nil
+		case '(':
+			list, err := readFields(r, ')')
+			if err != nil {
+				return nil, err
+			}
+			if list == nil {
+				list = []any{}
+			}
+			fields = append(fields, list)
+		case '"':
+			s, err := readQuoted(r)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, s)
+		case '{':
+			s, err := readLiteral(r)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, s)
+		default:
+			if err := r.UnreadByte(); err != nil {
+				return nil, err
+			}
+			atom, err := readAtom(r)
+			if err != nil {
+				return nil, err
+			}
+			if strings.EqualFold(atom, "NIL") {
+				fields = append(fields, nil)
+			} else {
+				fields = append(fields, atom)
+			}
+		}
+	}
+}
+
+// readAtom reads an atom. Brackets are kept together with their contents,
+// so BODY[HEADER.FIELDS (FROM)] is one atom.
+func readAtom(r *bufio.Reader) (string, error) {
+	var sb strings.Builder
+	depth := 0
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if err == io.EOF && sb.Len() > 0 {
+				return sb.String(), nil
+			}
+			return "", err
+		}
+		switch {
+		case b == '[':
+			depth++
+		case b == ']' && depth > 0:
+			depth--
+		case depth == 0 && (b == ' ' || b == '(' || b == ')' || b == '\r' || b == '\n'):
+			if err := r.UnreadByte(); err != nil {
+				return "", err
+			}
+			if sb.Len() == 0 {
+				return "", fmt.Errorf("imap: expected atom, got %q", b)
+			}
+			return sb.String(), nil
+		}
+		sb.WriteByte(b)
+	}
+}
+
+func readQuoted(r *bufio.Reader) (string, error) {
+	var sb strings.Builder
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch b {
+		case '"':
+			return sb.String(), nil
+		case '\\':
+			if b, err = r.ReadByte(); err != nil {
+				return "", err
+			}
+		case '\r', '\n':
+			return "", fmt.Errorf("imap: line break in quoted string")
+		}
+		sb.WriteByte(b)
+	}
+}
+
+// readLiteral reads "{n}\r\n" (the "{" already consumed) and n bytes
+func readLiteral(r *bufio.Reader) (string, error) {
+	spec, err := r.ReadString('}')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(spec, "}"), "+"))
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("imap: bad literal length %q", spec)
+	}
+	if line, err := r.ReadString('\n'); err != nil || strings.TrimRight(line, "\r\n") != "" {
+		return "", fmt.Errorf("imap: expected CRLF after literal length")
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func readRestOfLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func skipSpace(r *bufio.Reader) error {
+	b, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if b != ' ' {
+		return r.UnreadByte()
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/channels/email/api"
+	"github.com/roelfdiedericks/goclaw/internal/channels/email/emailtest"
+)
+
+const plainMessage = `From: Alice <alice@example.com>
+To: bot@example.org
+Subject: Hello
+Message-ID: <one@example.com>
+
+Hi there
+`
+
+func newServer(t *testing.T) *emailtest.Server {
+	t.Helper()
+	srv := emailtest.NewServer()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dialIMAP(t *testing.T, srv *emailtest.Server) *api.IMAPClient {
+	t.Helper()
+	client, err := api.DialIMAP(context.Background(), srv.IMAPAddr(), api.SecurityNone)
+	if err != nil {
+		t.Fatalf("DialIMAP: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestIMAPLogin(t *testing.T) {
+	srv := newServer(t)
+
+	client := dialIMAP(t, srv)
+	if !client.HasCapability("auth=plain") {
+		t.Fatal("AUTH=PLAIN not advertised")
+	}
+	if err := client.Login(emailtest.Address, "wrong"); err == nil {
+		t.Fatal("Login with a wrong password succeeded")
+	} else {
+		var se *api.StatusError
+		if !errors.As(err, &se) || se.Status != "NO" {
+			t.Errorf("err = %v, want a NO StatusError", err)
+		}
+	}
+	if err := client.Login(emailtest.Address, emailtest.Password); err != nil {
+		t.Fatalf("AUTHENTICATE PLAIN: %v", err)
+	}
+
+	srv.DisableAuthPlain()
+	client = dialIMAP(t, srv)
+	if err := client.Login(emailtest.Address, emailtest.Password); err != nil {
+		t.Fatalf("LOGIN: %v", err)
+	}
+	if err := client.Logout(); err != nil {
+		t.Errorf("Logout: %v", err)
+	}
+	if srv.Logins() != 2 {
+		t.Errorf("logins = %d, want 2", srv.Logins())
+	}
+}
+
+func TestIMAPSearchFetchMarkSeen(t *testing.T) {
+	srv := newServer(t)
+	old := srv.DeliverOld(plainMessage, time.Now().AddDate(0, 0, -10))
+	uid := srv.Deliver(plainMessage)
+
+	client := dialIMAP(t, srv)
+	if err := client.Login(emailtest.Address, emailtest.Password); err != nil {
+		t.Fatal(err)
+	}
+	status, err := client.Select("INBOX")
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if status.Exists != 2 || status.UIDValidity != 1 || status.UIDNext != 3 {
+		t.Errorf("status = %+v", status)
+	}
+	if _, err := client.Select("Missing"); err == nil {
+		t.Error("Select of a missing mailbox succeeded")
+	}
+	if _, err := client.Select("INBOX"); err != nil {
+		t.Fatal(err)
+	}
+
+	uids, err := client.SearchUnseen(time.Now().AddDate(0, 0, -3))
+	if err != nil {
+		t.Fatalf("SearchUnseen: %v", err)
+	}
+	if len(uids) != 1 || uids[0] != uid {
+		t.Errorf("recent unseen = %v, want [%d]", uids, uid)
+	}
+	if uids, _ = client.SearchUnseen(time.Time{}); len(uids) != 2 || uids[0] != old {
+		t.Errorf("all unseen = %v", uids)
+	}
+
+	raw, err := client.Fetch(uid)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if !strings.Contains(string(raw), "Subject: Hello\r\n") || !strings.HasSuffix(string(raw), "Hi there\r\n") {
+		t.Errorf("fetched %q", raw)
+	}
+	if srv.Seen(uid) {
+		t.Error("Fetch set \\Seen (should use BODY.PEEK)")
+	}
+
+	if err := client.MarkSeen(uid); err != nil {
+		t.Fatalf("MarkSeen: %v", err)
+	}
+	if !srv.Seen(uid) {
+		t.Error("MarkSeen did not set \\Seen")
+	}
+	if uids, _ = client.SearchUnseen(time.Time{}); len(uids) != 1 || uids[0] != old {
+		t.Errorf("unseen after MarkSeen = %v", uids)
+	}
+	if err := client.Noop(); err != nil {
+		t.Errorf("Noop: %v", err)
+	}
+}
+
+func TestIMAPIdle(t *testing.T) {
+	srv := newServer(t)
+	client := dialIMAP(t, srv)
+	if err := client.Login(emailtest.Address, emailtest.Password); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Select("INBOX"); err != nil {
+		t.Fatal(err)
+	}
+	if !client.HasCapability("IDLE") {
+		t.Fatal("IDLE not advertised")
+	}
+
+	// Timeout without mail
+	newMail, err := client.Idle(context.Background(), 100*time.Millisecond)
+	if err != nil || newMail {
+		t.Fatalf("Idle timeout = %v, %v; want false, nil", newMail, err)
+	}
+
+	// Woken by a delivery
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		srv.Deliver(plainMessage)
+	}()
+	newMail, err = client.Idle(context.Background(), 5*time.Second)
+	if err != nil || !newMail {
+		t.Fatalf("Idle with delivery = %v, %v; want true, nil", newMail, err)
+	}
+
+	// Cancelled: IDLE is ended cleanly
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	newMail, err = client.Idle(ctx, 5*time.Second)
+	if newMail || err != nil || time.Since(start) > 2*time.Second {
+		t.Fatalf("cancelled Idle = %v, %v", newMail, err)
+	}
+
+	// The connection is still usable afterwards
+	if uids, err := client.SearchUnseen(time.Time{}); err != nil || len(uids) != 1 {
+		t.Errorf("SearchUnseen after IDLE = %v, %v", uids, err)
+	}
+}
+
+func TestIMAPConnectionDropped(t *testing.T) {
+	srv := newServer(t)
+	client := dialIMAP(t, srv)
+	if err := client.Login(emailtest.Address, emailtest.Password); err != nil {
+		t.Fatal(err)
+	}
+	srv.DropConnections()
+	if err := client.Noop(); err == nil {
+		t.Error("Noop on a dropped connection succeeded")
+	}
+}
+
+func TestSMTPSend(t *testing.T) {
+	srv := newServer(t)
+	ctx := context.Background()
+
+	server := &api.SMTPServer{Addr: srv.SMTPAddr(), Security: api.SecurityNone, Username: emailtest.Address, Password: emailtest.Password}
+	if err := server.Verify(ctx); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	msg := "Subject: test\r\n\r\nbody\r\n.leading dot\r\n"
+	if err := server.Send(ctx, emailtest.Address, []string{"alice@example.com"}, []byte(msg)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	sent := srv.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	if sent[0].From != emailtest.Address || len(sent[0].To) != 1 || sent[0].To[0] != "alice@example.com" {
+		t.Errorf("envelope = %s -> %v", sent[0].From, sent[0].To)
+	}
+	if string(sent[0].Data) != msg {
+		t.Errorf("data = %q, want %q", sent[0].Data, msg)
+	}
+
+	bad := &api.SMTPServer{Addr: srv.SMTPAddr(), Security: api.SecurityNone, Username: emailtest.Address, Password: "wrong"}
+	if err := bad.Verify(ctx); err == nil {
+		t.Error("Verify with a wrong password succeeded")
+	}
+	if err := server.Send(ctx, emailtest.Address, nil, []byte(msg)); err == nil {
+		t.Error("Send without recipients succeeded")
+	}
+}
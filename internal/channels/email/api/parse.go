@@ -0,0 +1,452 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+
+	htmltomd "github.com/JohannesKaufmann/html-to-markdown/v2"
+	"golang.org/x/net/html/charset"
+)
+
+// maxDepth limits nesting of multiparts and forwarded messages
+const maxDepth = 10
+
+// Message is a parsed incoming email
+type Message struct {
+	MessageID  string   // Without angle brackets
+	InReplyTo  string   // Message ID this one answers
+	References []string // Thread ancestry, oldest first
+	From       *mail.Address
+	ReplyTo    *mail.Address
+	To         []*mail.Address
+	Cc         []*mail.Address
+	Subject    string
+	Date       time.Time
+
+	// Text is the readable body: the plain text part, or the HTML part
+	// converted to markdown. Forwarded messages are appended with their
+	// headers.
+	Text        string
+	Attachments []Attachment
+
+	// AutoSubmitted is set for auto-replies, bounces and bulk mail, which
+	// must never be answered
+	AutoSubmitted bool
+	// AuthResults are the Authentication-Results headers, topmost (most
+	// recently added) first. See Authenticated.
+	AuthResults []string
+}
+
+// Attachment is a file attached to (or embedded in) a message
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+	Inline      bool // Embedded in the body (e.g. images of an HTML mail)
+}
+
+var (
+	wordDecoder = &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+	addrParser  = &mail.AddressParser{WordDecoder: wordDecoder}
+	msgIDRe     = regexp.MustCompile(`<([^<>\s]+)>`)
+)
+
+// Parse parses a raw RFC 5322 message
+func Parse(raw []byte) (*Message, error) {
+	return parse(raw, 0)
+}
+
+func parse(raw []byte, depth int) (*Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("email: %w", err)
+	}
+	h := m.Header
+
+	msg := &Message{
+		MessageID:  firstMessageID(h.Get("Message-Id")),
+		InReplyTo:  firstMessageID(h.Get("In-Reply-To")),
+		References: messageIDs(h.Get("References")),
+		From:       parseAddress(h.Get("From")),
+		ReplyTo:    parseAddress(h.Get("Reply-To")),
+		To:         parseAddressList(h.Get("To")),
+		Cc:         parseAddressList(h.Get("Cc")),
+		Subject:    decodeHeader(h.Get("Subject")),
+	}
+	if date, err := mail.ParseDate(h.Get("Date")); err == nil {
+		msg.Date = date
+	}
+	msg.AutoSubmitted = isAutomatic(h)
+	msg.AuthResults = h["Authentication-Results"]
+
+	p := &partWalker{msg: msg, depth: depth}
+	text, err := p.walk(textproto.MIMEHeader(h), m.Body, depth)
+	if err != nil {
+		return nil, err
+	}
+	msg.Text = strings.TrimSpace(text)
+	return msg, nil
+}
+
+// ThreadID identifies the conversation a message belongs to: a hash of the
+// thread's first message ID, so every reply in a thread gets the same ID
+func (m *Message) ThreadID() string {
+	root := m.MessageID
+	switch {
+	case len(m.References) > 0:
+		root = m.References[0]
+	case m.InReplyTo != "":
+		root = m.InReplyTo
+	case root == "":
+		// No IDs at all (rare); fall back to what identifies this message
+		from := ""
+		if m.From != nil {
+			from = m.From.Address
+		}
+		root = from + "\x00" + m.Subject + "\x00" + m.Date.String()
+	}
+	sum := sha256.Sum256([]byte(root))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ReplyAddress is where a reply goes: Reply-To if set, else From
+func (m *Message) ReplyAddress() *mail.Address {
+	if m.ReplyTo != nil {
+		return m.ReplyTo
+	}
+	return m.From
+}
+
+// ReplyReferences returns the References for a reply: this message's
+// references plus its own ID. Long threads keep the first and the latest
+// IDs, as RFC 5322 suggests.
+func (m *Message) ReplyReferences() []string {
+	refs := append([]string{}, m.References...)
+	if len(refs) == 0 && m.InReplyTo != "" {
+		refs = append(refs, m.InReplyTo)
+	}
+	if m.MessageID != "" {
+		refs = append(refs, m.MessageID)
+	}
+	if len(refs) > 10 {
+		refs = append(refs[:1], refs[len(refs)-9:]...)
+	}
+	return refs
+}
+
+// partWalker collects the text and attachments of a MIME tree
+type partWalker struct {
+	msg   *Message
+	depth int
+}
+
+// walk returns the readable text of a part and records its attachments
+func (p *partWalker) walk(h textproto.MIMEHeader, body io.Reader, depth int) (string, error) {
+	if depth > maxDepth+p.depth {
+		return "", nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || mediaType == "" {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := decodeHeader(dparams["filename"])
+	if filename == "" {
+		filename = decodeHeader(params["name"])
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		return p.walkMultipart(mediaType, params["boundary"], body, depth)
+	}
+
+	data, err := io.ReadAll(decodeTransfer(h.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return "", fmt.Errorf("email: reading %s part: %w", mediaType, err)
+	}
+
+	isAttachment := disposition == "attachment" || (filename != "" && !strings.HasPrefix(mediaType, "text/"))
+	switch {
+	case mediaType == "message/rfc822":
+		return p.forwarded(data, depth)
+	case mediaType == "application/pgp-signature" || mediaType == "application/pkcs7-signature":
+		return "", nil
+	case mediaType == "text/plain" && !isAttachment:
+		return decodeCharset(data, params["charset"]), nil
+	case mediaType == "text/html" && !isAttachment:
+		return htmlToText(decodeCharset(data, params["charset"])), nil
+	}
+
+	p.msg.Attachments = append(p.msg.Attachments, Attachment{
+		Filename:    filename,
+		ContentType: mediaType,
+		Data:        data,
+		Inline:      disposition != "attachment" && h.Get("Content-Id") != "",
+	})
+	return "", nil
+}
+
+func (p *partWalker) walkMultipart(mediaType, boundary string, body io.Reader, depth int) (string, error) {
+	if boundary == "" {
+		return "", nil
+	}
+	mr := multipart.NewReader(body, boundary)
+
+	// In multipart/alternative only the best version counts: plain text if
+	// there is any, otherwise the last (richest) one
+	if mediaType == "multipart/alternative" {
+		var chosenText string
+		var chosenAtts []Attachment
+		havePlain := false
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return chosenText, nil // Keep what was readable
+			}
+			sub := &partWalker{msg: &Message{}, depth: p.depth}
+			text, err := sub.walk(part.Header, part, depth+1)
+			if err != nil || strings.TrimSpace(text) == "" || havePlain {
+				continue
+			}
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			chosenText, chosenAtts = text, sub.msg.Attachments
+			havePlain = partType == "text/plain"
+		}
+		p.msg.Attachments = append(p.msg.Attachments, chosenAtts...)
+		return chosenText, nil
+	}
+
+	var texts []string
+	for {
+		part, err := mr.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			break
+		}
+		text, err := p.walk(part.Header, part, depth+1)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(text) != "" {
+			texts = append(texts, strings.TrimSpace(text))
+		}
+	}
+	return strings.Join(texts, "\n\n"), nil
+}
+
+// forwarded renders an attached message (a forward "as attachment") with
+// its headers, and collects its attachments
+func (p *partWalker) forwarded(raw []byte, depth int) (string, error) {
+	if depth > maxDepth+p.depth {
+		return "", nil
+	}
+	inner, err := parse(raw, depth+1)
+	if err != nil {
+		// Unparseable: keep it as a file
+		p.msg.Attachments = append(p.msg.Attachments, Attachment{Filename: "forwarded.eml", ContentType: "message/rfc822", Data: raw})
+		return "", nil
+	}
+	p.msg.Attachments = append(p.msg.Attachments, inner.Attachments...)
+
+	var sb strings.Builder
+	sb.WriteString("---------- Forwarded message ----------\n")
+	if inner.From != nil {
+		fmt.Fprintf(&sb, "From: %s\n", inner.From.String())
+	}
+	if !inner.Date.IsZero() {
+		fmt.Fprintf(&sb, "Date: %s\n", inner.Date.Format(time.RFC1123Z))
+	}
+	if inner.Subject != "" {
+		fmt.Fprintf(&sb, "Subject: %s\n", inner.Subject)
+	}
+	if len(inner.To) > 0 {
+		fmt.Fprintf(&sb, "To: %s\n", formatAddresses(inner.To))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(inner.Text)
+	return sb.String(), nil
+}
+
+// StripQuotedReply removes the quoted previous message that mail clients
+// append to replies ("On ... wrote:" followed by "> " lines, or Outlook's
+// "-----Original Message-----"). The thread's history is already in the
+// session, so only the new text matters.
+func StripQuotedReply(text string) string {
+	lines := strings.Split(text, "\n")
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "-----Original Message-----" && i > 0 {
+			lines = lines[:i]
+			break
+		}
+	}
+
+	end := len(lines)
+	quoted := false
+	for end > 0 {
+		trimmed := strings.TrimSpace(lines[end-1])
+		if trimmed == "" {
+			end--
+			continue
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			quoted = true
+			end--
+			continue
+		}
+		break
+	}
+	if !quoted {
+		return strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+
+	// The attribution line may be wrapped over two lines
+	for i := end - 1; i >= 0 && i >= end-2; i-- {
+		if strings.HasSuffix(strings.TrimSpace(lines[i]), "wrote:") {
+			end = i
+			if i > 0 && !strings.HasPrefix(strings.TrimSpace(lines[i]), "On ") && strings.HasPrefix(strings.TrimSpace(lines[i-1]), "On ") {
+				end = i - 1
+			}
+			break
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines[:end], "\n"))
+}
+
+// --- Helpers ---
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, &base64Cleaner{r: r})
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// base64Cleaner drops characters that aren't part of the base64 alphabet
+// (line breaks, stray spaces) that some mailers leave in
+type base64Cleaner struct {
+	r io.Reader
+}
+
+func (c *base64Cleaner) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	j := 0
+	for _, b := range p[:n] {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '+' || b == '/' || b == '=' {
+			p[j] = b
+			j++
+		}
+	}
+	return j, err
+}
+
+// decodeCharset converts text in the given charset to UTF-8
+func decodeCharset(data []byte, label string) string {
+	label = strings.ToLower(strings.TrimSpace(label))
+	if label != "" && label != "utf-8" && label != "us-ascii" {
+		if r, err := charset.NewReaderLabel(label, bytes.NewReader(data)); err == nil {
+			if decoded, err := io.ReadAll(r); err == nil {
+				return string(decoded)
+			}
+		}
+	}
+	return strings.ToValidUTF8(string(data), "�")
+}
+
+func htmlToText(html string) string {
+	md, err := htmltomd.ConvertString(html)
+	if err != nil {
+		return html
+	}
+	return md
+}
+
+func decodeHeader(s string) string {
+	if decoded, err := wordDecoder.DecodeHeader(s); err == nil {
+		return decoded
+	}
+	return s
+}
+
+func parseAddress(s string) *mail.Address {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	addr, err := addrParser.Parse(s)
+	if err != nil {
+		return nil
+	}
+	return addr
+}
+
+func parseAddressList(s string) []*mail.Address {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	list, _ := addrParser.ParseList(s)
+	return list
+}
+
+func formatAddresses(list []*mail.Address) string {
+	parts := make([]string, len(list))
+	for i, a := range list {
+		parts[i] = a.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+func messageIDs(s string) []string {
+	var ids []string
+	for _, m := range msgIDRe.FindAllStringSubmatch(s, -1) {
+		ids = append(ids, m[1])
+	}
+	return ids
+}
+
+func firstMessageID(s string) string {
+	if ids := messageIDs(s); len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+// isAutomatic reports whether a message was sent by a machine: auto-replies
+// (RFC 3834), bounces, and bulk or list mail
+func isAutomatic(h mail.Header) bool {
+	if v := strings.ToLower(strings.TrimSpace(h.Get("Auto-Submitted"))); v != "" && v != "no" {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(h.Get("Precedence"))) {
+	case "bulk", "junk", "list", "auto_reply":
+		return true
+	}
+	if h.Get("X-Autoreply") != "" || h.Get("X-Autorespond") != "" || h.Get("List-Id") != "" {
+		return true
+	}
+	if strings.TrimSpace(h.Get("Return-Path")) == "<>" {
+		return true
+	}
+	mediaType, params, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	return mediaType == "multipart/report" && strings.EqualFold(params["report-type"], "delivery-status")
+}
@@ -0,0 +1,291 @@
+package api_test
+
+import (
+	"bytes"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/roelfdiedericks/goclaw/internal/channels/email/api"
+)
+
+func crlf(s string) []byte {
+	return []byte(strings.ReplaceAll(s, "\n", "\r\n"))
+}
+
+func mustParse(t *testing.T, raw string) *api.Message {
+	t.Helper()
+	msg, err := api.Parse(crlf(raw))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return msg
+}
+
+func TestParseHeaders(t *testing.T) {
+	msg := mustParse(t, `From: =?utf-8?q?J=C3=BCrgen?= <Juergen@Example.com>
+Reply-To: replies@example.com
+To: bot@example.org, Other <other@example.org>
+Subject: =?iso-8859-1?q?Gr=FC=DFe?=
+Date: Mon, 12 Oct 2026 09:30:00 +0200
+Message-ID: <c@example.com>
+In-Reply-To: <b@example.com>
+References: <a@example.com>
+ <b@example.com>
+
+Hello
+`)
+	if msg.From.Name != "Jürgen" || msg.From.Address != "Juergen@Example.com" {
+		t.Errorf("From = %+v", msg.From)
+	}
+	if msg.Subject != "Grüße" {
+		t.Errorf("Subject = %q", msg.Subject)
+	}
+	if len(msg.To) != 2 || msg.To[1].Name != "Other" {
+		t.Errorf("To = %v", msg.To)
+	}
+	if msg.MessageID != "c@example.com" || msg.InReplyTo != "b@example.com" {
+		t.Errorf("IDs = %q, %q", msg.MessageID, msg.InReplyTo)
+	}
+	if got := strings.Join(msg.References, " "); got != "a@example.com b@example.com" {
+		t.Errorf("References = %q", got)
+	}
+	if msg.Date.Day() != 12 || msg.Text != "Hello" {
+		t.Errorf("Date = %v, Text = %q", msg.Date, msg.Text)
+	}
+	if msg.ReplyAddress().Address != "replies@example.com" {
+		t.Errorf("ReplyAddress = %v", msg.ReplyAddress())
+	}
+	if got := strings.Join(msg.ReplyReferences(), " "); got != "a@example.com b@example.com c@example.com" {
+		t.Errorf("ReplyReferences = %q", got)
+	}
+}
+
+func TestThreadID(t *testing.T) {
+	first := &api.Message{MessageID: "a@example.com"}
+	reply := &api.Message{MessageID: "b@example.com", InReplyTo: "a@example.com"}
+	later := &api.Message{MessageID: "c@example.com", InReplyTo: "b@example.com", References: []string{"a@example.com", "b@example.com"}}
+	other := &api.Message{MessageID: "x@example.com"}
+
+	if first.ThreadID() != reply.ThreadID() || reply.ThreadID() != later.ThreadID() {
+		t.Errorf("thread IDs differ: %s %s %s", first.ThreadID(), reply.ThreadID(), later.ThreadID())
+	}
+	if first.ThreadID() == other.ThreadID() {
+		t.Error("different threads share an ID")
+	}
+	if len(first.ThreadID()) != 16 {
+		t.Errorf("ThreadID = %q, want 16 hex characters", first.ThreadID())
+	}
+}
+
+func TestReplyReferencesCapped(t *testing.T) {
+	msg := &api.Message{MessageID: "m12"}
+	for i := 0; i < 12; i++ {
+		msg.References = append(msg.References, "m"+string(rune('a'+i)))
+	}
+	refs := msg.ReplyReferences()
+	if len(refs) != 10 || refs[0] != "ma" || refs[9] != "m12" || refs[1] != "me" {
+		t.Errorf("ReplyReferences = %v", refs)
+	}
+}
+
+func TestParseAlternativePrefersPlain(t *testing.T) {
+	msg := mustParse(t, `From: a@example.com
+Content-Type: multipart/alternative; boundary="b1"
+
+--b1
+Content-Type: text/plain; charset=utf-8
+
+Plain version
+--b1
+Content-Type: text/html; charset=utf-8
+
+<p>HTML version</p>
+--b1--
+`)
+	if msg.Text != "Plain version" {
+		t.Errorf("Text = %q", msg.Text)
+	}
+}
+
+func TestParseHTMLOnly(t *testing.T) {
+	msg := mustParse(t, `From: news@example.com
+Content-Type: text/html; charset=iso-8859-1
+Content-Transfer-Encoding: quoted-printable
+
+<html><body><h1>Weekly</h1><p>Read <a href=3D"https://example.com/a">the =
+article</a> for <b>caf=E9</b> news.</p><ul><li>One</li><li>Two</li></ul></body></html>
+`)
+	for _, want := range []string{"# Weekly", "[the article](https://example.com/a)", "**café**", "- One"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("Text missing %q:\n%s", want, msg.Text)
+		}
+	}
+}
+
+func TestParseAttachments(t *testing.T) {
+	msg := mustParse(t, `From: a@example.com
+Subject: receipt
+Content-Type: multipart/mixed; boundary="mixed"
+
+--mixed
+Content-Type: multipart/related; boundary="rel"
+
+--rel
+Content-Type: text/html
+
+<p>See attached</p><img src="cid:logo">
+--rel
+Content-Type: image/png
+Content-ID: <logo>
+Content-Transfer-Encoding: base64
+
+iVBORw0K
+--rel--
+--mixed
+Content-Type: application/pdf; name="receipt.pdf"
+Content-Disposition: attachment; filename="receipt.pdf"
+Content-Transfer-Encoding: base64
+
+JVBERi0x
+LjQK
+--mixed
+Content-Type: application/pgp-signature
+
+-----BEGIN PGP SIGNATURE-----
+--mixed--
+`)
+	if !strings.HasPrefix(msg.Text, "See attached") {
+		t.Errorf("Text = %q", msg.Text)
+	}
+	if len(msg.Attachments) != 2 {
+		t.Fatalf("attachments = %+v, want logo and receipt", msg.Attachments)
+	}
+	logo, pdf := msg.Attachments[0], msg.Attachments[1]
+	if logo.ContentType != "image/png" || !logo.Inline || !bytes.Equal(logo.Data, []byte("\x89PNG\r\n")) {
+		t.Errorf("logo = %+v", logo)
+	}
+	if pdf.Filename != "receipt.pdf" || pdf.Inline || string(pdf.Data) != "%PDF-1.4\n" {
+		t.Errorf("pdf = %+v", pdf)
+	}
+}
+
+func TestParseForwarded(t *testing.T) {
+	msg := mustParse(t, `From: me@example.com
+Subject: Fwd: Your order
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: text/plain
+
+Can you file this?
+--outer
+Content-Type: message/rfc822
+
+From: Shop <orders@shop.example>
+To: me@example.com
+Subject: Your order
+Date: Tue, 13 Oct 2026 10:00:00 +0000
+Content-Type: multipart/mixed; boundary="inner"
+
+--inner
+Content-Type: text/plain
+
+Total: 42.00
+--inner
+Content-Type: application/pdf
+Content-Disposition: attachment; filename=invoice.pdf
+
+%PDF
+--inner--
+--outer--
+`)
+	for _, want := range []string{"Can you file this?", "---------- Forwarded message ----------", "From: \"Shop\" <orders@shop.example>", "Subject: Your order", "Total: 42.00"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("Text missing %q:\n%s", want, msg.Text)
+		}
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].Filename != "invoice.pdf" {
+		t.Errorf("attachments = %+v", msg.Attachments)
+	}
+}
+
+func TestParseAutomatic(t *testing.T) {
+	for name, header := range map[string]string{
+		"auto-submitted": "Auto-Submitted: auto-replied",
+		"precedence":     "Precedence: bulk",
+		"list":           "List-Id: <news.example.com>",
+		"bounce":         "Return-Path: <>",
+	} {
+		msg := mustParse(t, header+"\nFrom: a@example.com\n\nbody\n")
+		if !msg.AutoSubmitted {
+			t.Errorf("%s: not detected as automatic", name)
+		}
+	}
+	if msg := mustParse(t, "Auto-Submitted: no\nFrom: a@example.com\n\nbody\n"); msg.AutoSubmitted {
+		t.Error("Auto-Submitted: no detected as automatic")
+	}
+}
+
+func TestStripQuotedReply(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain", "Just text\n> not trailing\nmore", "Just text\n> not trailing\nmore"},
+		{"gmail", "Thanks!\n\nOn Mon, 12 Oct 2026 at 09:30, Bot <bot@example.org> wrote:\n> Earlier\n> text\n", "Thanks!"},
+		{"wrapped attribution", "Yes\n\nOn Mon, 12 Oct 2026 at 09:30, Some Long Name\n<bot@example.org> wrote:\n\n> Earlier\n", "Yes"},
+		{"outlook", "Sure\n\n-----Original Message-----\nFrom: Bot\nSent: Monday\n\nEarlier", "Sure"},
+	}
+	for _, tt := range tests {
+		if got := api.StripQuotedReply(tt.in); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestComposeRoundTrip(t *testing.T) {
+	out := &api.Outgoing{
+		From:        &mail.Address{Name: "GoClaw", Address: "bot@example.org"},
+		To:          []*mail.Address{{Address: "alice@example.com"}},
+		Subject:     api.ReplySubject("Grüße"),
+		InReplyTo:   "b@example.com",
+		References:  []string{"a@example.com", "b@example.com"},
+		Text:        "Hello **there**, a long line " + strings.Repeat("x", 100),
+		HTML:        "<p>Hello <strong>there</strong></p>",
+		Attachments: []api.Attachment{{Filename: "chart.png", ContentType: "image/png", Data: bytes.Repeat([]byte{0, 1, 2}, 100)}},
+		AutoReplied: true,
+	}
+	raw, err := out.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: %v", err)
+	}
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > 998 {
+			t.Fatalf("line longer than 998 characters: %q", line[:80])
+		}
+	}
+
+	msg, err := api.Parse(raw)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if msg.Subject != "Re: Grüße" || msg.MessageID != out.MessageID || msg.InReplyTo != "b@example.com" {
+		t.Errorf("headers: subject %q, id %q (want %q), in-reply-to %q", msg.Subject, msg.MessageID, out.MessageID, msg.InReplyTo)
+	}
+	if len(msg.References) != 2 || !msg.AutoSubmitted {
+		t.Errorf("References = %v, AutoSubmitted = %v", msg.References, msg.AutoSubmitted)
+	}
+	if msg.Text != out.Text {
+		t.Errorf("Text = %q", msg.Text)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].Filename != "chart.png" || !bytes.Equal(msg.Attachments[0].Data, out.Attachments[0].Data) {
+		t.Errorf("attachments = %+v", msg.Attachments)
+	}
+	if !strings.HasSuffix(out.MessageID, "@example.org") {
+		t.Errorf("MessageID = %q, want the sender's domain", out.MessageID)
+	}
+
+	if api.ReplySubject("RE: x") != "RE: x" || api.ReplySubject("") != "Re: your message" {
+		t.Error("ReplySubject")
+	}
+}
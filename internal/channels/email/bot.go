@@ -0,0 +1,870 @@
+// Package email provides the email channel adapter for GoClaw: it watches an
+// IMAP mailbox (IDLE or polling) and answers over SMTP.
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/bus"
+	"github.com/roelfdiedericks/goclaw/internal/channels/email/api"
+	"github.com/roelfdiedericks/goclaw/internal/channels/email/config"
+	chtypes "github.com/roelfdiedericks/goclaw/internal/channels/types"
+	"github.com/roelfdiedericks/goclaw/internal/commands"
+	"github.com/roelfdiedericks/goclaw/internal/gateway"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/media"
+	itypes "github.com/roelfdiedericks/goclaw/internal/types"
+	"github.com/roelfdiedericks/goclaw/internal/user"
+)
+
+const (
+	// idleTimeout restarts IDLE before servers drop idle clients (RFC 2177: 29 minutes)
+	idleTimeout = 25 * time.Minute
+	// maxAge limits which unread mail is picked up, so enabling the channel
+	// on a mailbox with a backlog doesn't answer old mail
+	maxAge = 24 * time.Hour
+	// maxPerCheck bounds how many messages one check handles; the rest wait
+	// for the next round
+	maxPerCheck = 20
+	// maxAttachments bounds the attachments taken from one message
+	maxAttachments = 10
+	// minInlineImage skips tiny inline images (tracking pixels, spacers)
+	minInlineImage = 2048
+	// rejectInterval limits refusals to the same unknown sender
+	rejectInterval = time.Hour
+	// maxReplyAttachment keeps reply attachments under common provider limits
+	maxReplyAttachment = 20 << 20
+
+	rejectText = "This address only answers mail from registered users, so your message was not read.\n\n" +
+		"If you think this is a mistake, ask the owner to add your address."
+)
+
+// Bot represents the email channel
+type Bot struct {
+	gateway *gateway.Gateway
+	users   *user.Registry
+	config  *config.Config
+	smtp    *api.SMTPServer
+
+	handledMu   sync.Mutex
+	uidValidity uint32
+	handled     map[uint32]bool      // UIDs already taken from the mailbox
+	rejected    map[string]time.Time // Unknown sender -> last refusal
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{} // Closed when the watch loop exits
+
+	mu        sync.RWMutex
+	running   bool
+	connected bool
+	startedAt time.Time
+	lastError error
+}
+
+// New creates a new email bot
+func New(cfg *config.Config, gw *gateway.Gateway, users *user.Registry) (*Bot, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, fmt.Errorf("email: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Bot{
+		gateway:  gw,
+		users:    users,
+		config:   cfg,
+		smtp:     cfg.SMTPServer(),
+		handled:  make(map[uint32]bool),
+		rejected: make(map[string]time.Time),
+		ctx:      ctx,
+		cancel:   cancel,
+	}, nil
+}
+
+// Start logs in to the IMAP server and starts watching the mailbox
+// (implements ManagedChannel)
+func (b *Bot) Start(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.running {
+		return nil
+	}
+
+	if b.config.UnknownSenderPolicy() == config.UnknownRole {
+		if _, err := user.ResolveRole(b.config.UnknownSenderRole(), b.users.GetRolesConfig()); err != nil {
+			b.lastError = err
+			return fmt.Errorf("email: unknown sender role: %w", err)
+		}
+	}
+
+	if b.config.AuthServID == "" {
+		L_warn("email: authservId not set, senders can't be verified and are all treated as unknown")
+	}
+
+	connectCtx, cancel := context.WithTimeout(b.ctx, 30*time.Second)
+	defer cancel()
+
+	client, err := b.connect(connectCtx)
+	if err != nil {
+		b.lastError = err
+		return fmt.Errorf("email: %w", err)
+	}
+
+	b.running = true
+	b.connected = true
+	b.startedAt = time.Now()
+	b.lastError = nil
+	b.done = make(chan struct{})
+	go b.watchLoop(b.ctx, client, b.done)
+
+	L_info("email: connected", "address", b.config.Address, "mailbox", b.config.MailboxName(),
+		"idle", b.config.IdleEnabled() && client.HasCapability("IDLE"))
+	return nil
+}
+
+// connect logs in and selects the mailbox. A new UIDVALIDITY means UIDs were
+// reassigned, so the record of handled messages is dropped.
+func (b *Bot) connect(ctx context.Context) (*api.IMAPClient, error) {
+	client, err := config.Connect(ctx, b.config)
+	if err != nil {
+		return nil, err
+	}
+	status, err := client.Select(b.config.MailboxName())
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	b.handledMu.Lock()
+	if status.UIDValidity != b.uidValidity {
+		b.uidValidity = status.UIDValidity
+		b.handled = make(map[uint32]bool)
+	}
+	b.handledMu.Unlock()
+	return client, nil
+}
+
+// RegisterOperationalCommands registers runtime commands for this bot instance
+func (b *Bot) RegisterOperationalCommands() {
+	bus.RegisterCommand("email", "status", b.handleStatusCommand)
+}
+
+func (b *Bot) handleStatusCommand(cmd bus.Command) bus.CommandResult {
+	b.mu.RLock()
+	connected := b.connected
+	b.mu.RUnlock()
+	b.handledMu.Lock()
+	handled := len(b.handled)
+	b.handledMu.Unlock()
+
+	return bus.CommandResult{
+		Success: true,
+		Message: fmt.Sprintf("Email watching %s for %s", b.config.MailboxName(), b.config.Address),
+		Data: map[string]any{
+			"connected": connected,
+			"address":   b.config.Address,
+			"mailbox":   b.config.MailboxName(),
+			"handled":   handled,
+		},
+	}
+}
+
+// Stop stops watching the mailbox (implements ManagedChannel)
+func (b *Bot) Stop() error {
+	b.mu.Lock()
+	if !b.running {
+		b.mu.Unlock()
+		return nil
+	}
+	L_info("email: stopping")
+	b.cancel()
+	b.running = false
+	b.connected = false
+	done := b.done
+	b.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		L_warn("email: watch loop did not stop in time")
+	}
+	return nil
+}
+
+// Reload applies new configuration (implements ManagedChannel)
+func (b *Bot) Reload(cfg any) error {
+	newCfg, ok := cfg.(*config.Config)
+	if !ok {
+		return fmt.Errorf("expected *email.Config, got %T", cfg)
+	}
+	if err := newCfg.Check(); err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+
+	b.mu.Lock()
+	wasRunning := b.running
+	b.mu.Unlock()
+
+	if wasRunning {
+		if err := b.Stop(); err != nil {
+			return fmt.Errorf("failed to stop for reload: %w", err)
+		}
+	}
+
+	b.config = newCfg
+	b.smtp = newCfg.SMTPServer()
+
+	if wasRunning && newCfg.Enabled {
+		b.ctx, b.cancel = context.WithCancel(context.Background())
+		return b.Start(b.ctx)
+	}
+
+	return nil
+}
+
+// Status returns current channel status (implements ManagedChannel)
+func (b *Bot) Status() chtypes.ChannelStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return chtypes.ChannelStatus{
+		Running:   b.running,
+		Connected: b.connected,
+		Error:     b.lastError,
+		StartedAt: b.startedAt,
+		Info:      b.config.Address,
+	}
+}
+
+// Name returns the channel name (implements gateway.Channel)
+func (b *Bot) Name() string {
+	return "email"
+}
+
+// Send emails a message to the owner when notifications are enabled
+// (implements gateway.Channel)
+func (b *Bot) Send(ctx context.Context, msg string) error {
+	owner := b.users.Owner()
+	if !b.HasUser(owner) {
+		return nil
+	}
+	return b.sendNew(ctx, owner, b.agentName(owner)+" notification", msg)
+}
+
+// SendMirror emails a cross-channel mirror summary to the owner when
+// notifications are enabled (implements gateway.Channel)
+func (b *Bot) SendMirror(ctx context.Context, source, userMsg, response string) error {
+	owner := b.users.Owner()
+	if !b.HasUser(owner) {
+		return nil
+	}
+	agentName := b.agentName(owner)
+	mirror := fmt.Sprintf("**You:** %s\n\n**%s:** %s", truncate(userMsg, 2000), agentName, response)
+	err := b.sendNew(ctx, owner, fmt.Sprintf("%s conversation on %s", agentName, source), mirror)
+	if err != nil {
+		L_error("email: failed to send mirror", "error", err)
+	}
+	return err
+}
+
+// HasUser returns true if the user has an email address and notifications
+// are enabled (implements gateway.Channel). Without notifications the channel
+// only answers mail.
+func (b *Bot) HasUser(u *user.User) bool {
+	return b.config.Notifications && u.HasEmailAuth()
+}
+
+// StreamEvent returns false — replies are sent whole (implements gateway.Channel)
+func (b *Bot) StreamEvent(u *user.User, event gateway.AgentEvent) bool {
+	return false
+}
+
+// DeliverGhostwrite emails a ghostwritten message (implements gateway.Channel)
+func (b *Bot) DeliverGhostwrite(ctx context.Context, u *user.User, message string) error {
+	if u == nil || u.Email == "" {
+		return nil
+	}
+	L_info("email: ghostwrite", "user", u.ID, "messageLen", len(message))
+	if err := b.sendNew(ctx, u, "Message from "+b.agentName(u), message); err != nil {
+		return fmt.Errorf("failed to send ghostwrite: %w", err)
+	}
+	L_info("email: ghostwrite delivered", "user", u.ID, "messageLen", len(message))
+	return nil
+}
+
+// sendNew starts a new thread with a user
+func (b *Bot) sendNew(ctx context.Context, u *user.User, subject, text string) error {
+	out := &api.Outgoing{
+		From:    b.fromAddress(u),
+		To:      []*mail.Address{{Name: u.Name, Address: u.Email}},
+		Subject: subject,
+	}
+	b.setBody(out, text)
+	return b.send(ctx, out)
+}
+
+// --- Mailbox ---
+
+// watchLoop checks the mailbox until ctx is cancelled, reconnecting with
+// backoff when the connection fails
+func (b *Bot) watchLoop(ctx context.Context, client *api.IMAPClient, done chan struct{}) {
+	defer close(done)
+
+	backoff := time.Second
+	for {
+		if client != nil {
+			err := b.watch(ctx, client)
+			if ctx.Err() != nil {
+				if err := client.Logout(); err != nil {
+					L_trace("email: logout failed", "error", err)
+				}
+				return
+			}
+			client.Close()
+			b.setConnected(false, err)
+			L_warn("email: connection lost", "error", err)
+			backoff = time.Second
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		connectCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		var err error
+		client, err = b.connect(connectCtx)
+		cancel()
+		if err != nil {
+			client = nil
+			if ctx.Err() != nil {
+				return
+			}
+			b.setConnected(false, err)
+			L_warn("email: reconnect failed", "error", err, "retryIn", backoff)
+			backoff = min(backoff*2, 5*time.Minute)
+			continue
+		}
+		L_info("email: reconnected")
+		b.setConnected(true, nil)
+	}
+}
+
+func (b *Bot) setConnected(connected bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.connected = connected
+	b.lastError = err
+}
+
+// watch handles new mail, then waits for more with IDLE or by polling. It
+// returns on a connection error or when ctx ends.
+func (b *Bot) watch(ctx context.Context, client *api.IMAPClient) error {
+	useIdle := b.config.IdleEnabled() && client.HasCapability("IDLE")
+	for {
+		if err := b.check(client); err != nil {
+			return err
+		}
+
+		if useIdle {
+			if _, err := client.Idle(ctx, idleTimeout); err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(b.config.PollEvery()):
+		}
+	}
+}
+
+// check fetches unread mail, marks it read and hands it off. Messages are
+// marked read before they are answered, so a crash never answers twice.
+func (b *Bot) check(client *api.IMAPClient) error {
+	uids, err := client.SearchUnseen(time.Now().Add(-maxAge))
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	for _, uid := range uids {
+		if count == maxPerCheck {
+			L_debug("email: more unread mail than one check handles", "pending", len(uids)-count)
+			break
+		}
+		b.handledMu.Lock()
+		seen := b.handled[uid]
+		b.handled[uid] = true
+		b.handledMu.Unlock()
+		if seen {
+			continue
+		}
+		count++
+
+		raw, err := client.Fetch(uid)
+		if err == nil {
+			err = client.MarkSeen(uid)
+		}
+		if err != nil {
+			// Try again after reconnecting
+			b.handledMu.Lock()
+			delete(b.handled, uid)
+			b.handledMu.Unlock()
+			return err
+		}
+
+		msg, err := api.Parse(raw)
+		if err != nil {
+			L_warn("email: unparseable message skipped", "uid", uid, "error", err)
+			continue
+		}
+		go b.handleMessage(msg)
+	}
+	return nil
+}
+
+// --- Incoming mail ---
+
+// handleMessage answers one incoming message
+func (b *Bot) handleMessage(msg *api.Message) {
+	if msg.From == nil {
+		L_debug("email: message without sender ignored", "messageId", msg.MessageID)
+		return
+	}
+	sender := user.NormalizeEmail(msg.From.Address)
+	if sender == user.NormalizeEmail(b.config.Address) {
+		return
+	}
+	if msg.AutoSubmitted {
+		L_debug("email: automatic message ignored", "from", sender, "subject", msg.Subject)
+		return
+	}
+
+	u := b.users.FromEmail(sender)
+	if u != nil && !msg.Authenticated(b.config.AuthServID) {
+		L_warn("email: sender not verified by the mail server, treating as unknown", "from", sender, "user", u.ID, "authservId", b.config.AuthServID)
+		u = nil
+	}
+	if u == nil {
+		u = b.unknownSender(msg, sender)
+		if u == nil {
+			return
+		}
+	}
+
+	L_info("email: authenticated message", "user", u.Name, "role", u.Role, "from", sender, "subject", msg.Subject)
+
+	text := msg.Text
+	if msg.InReplyTo != "" {
+		text = api.StripQuotedReply(text)
+	}
+
+	// Check for panic phrase (emergency stop) and commands in the body
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	firstLine = strings.TrimSpace(firstLine)
+	if commands.IsPanicPhrase(firstLine) {
+		b.gateway.StopAllUserSessions(u.ID)
+		b.reply(msg, u, "Stopping all tasks.", nil)
+		return
+	}
+	if commands.IsCommand(firstLine) {
+		b.handleCommand(msg, u, firstLine)
+		return
+	}
+
+	contentBlocks := b.saveAttachments(msg, u)
+
+	userMsg := text
+	if msg.Subject != "" {
+		userMsg = fmt.Sprintf("Subject: %s\n\n%s", msg.Subject, text)
+	}
+	if strings.TrimSpace(text) == "" && len(contentBlocks) == 0 {
+		L_debug("email: empty message ignored", "from", sender)
+		return
+	}
+
+	var mediaMu sync.Mutex
+	var outgoing []api.Attachment
+	req := gateway.AgentRequest{
+		User:          u,
+		Source:        "email",
+		ChatID:        sender,
+		SessionID:     b.sessionKey(u, msg),
+		SkipMirror:    true,
+		UserMsg:       userMsg,
+		ContentBlocks: contentBlocks,
+		OnMediaToSend: func(path, caption string) error {
+			att, err := fileAttachment(path)
+			if err != nil {
+				return err
+			}
+			mediaMu.Lock()
+			outgoing = append(outgoing, *att)
+			mediaMu.Unlock()
+			return nil
+		},
+	}
+
+	evChan := make(chan gateway.AgentEvent, 100)
+	go func() {
+		if err := b.gateway.RunAgent(b.ctx, req, evChan); err != nil {
+			L_error("email: agent error", "error", err)
+		}
+	}()
+
+	var response strings.Builder
+	for event := range evChan {
+		switch e := event.(type) {
+		case gateway.EventTextDelta:
+			response.WriteString(e.Delta)
+
+		case gateway.EventAgentEnd:
+			finalText := e.FinalText
+			if finalText == "" {
+				finalText = response.String()
+			}
+			if finalText == "" {
+				finalText = "(No response)"
+			}
+			mediaMu.Lock()
+			attachments := outgoing
+			mediaMu.Unlock()
+			b.reply(msg, u, finalText, attachments)
+
+		case gateway.EventAgentMerged:
+			// Reply arrives through the run the message was merged into,
+			// as an answer to the earlier email
+			L_debug("email: message merged into running turn", "mode", e.Mode)
+			b.reply(msg, u, e.Notice(), nil)
+
+		case gateway.EventAgentError:
+			L_error("email: agent error", "error", e.Error)
+			b.reply(msg, u, fmt.Sprintf("Sorry, something went wrong: %s", e.Error), nil)
+		}
+	}
+}
+
+// unknownSender applies the unknown sender policy. It returns a transient
+// user when unknown senders are answered with a restricted role.
+func (b *Bot) unknownSender(msg *api.Message, sender string) *user.User {
+	switch b.config.UnknownSenderPolicy() {
+	case config.UnknownRole:
+		name := msg.From.Name
+		if name == "" {
+			name = sender
+		}
+		L_info("email: unknown sender answered with restricted role", "from", sender, "role", b.config.UnknownSenderRole())
+		return &user.User{
+			ID:      sender,
+			Name:    name,
+			Role:    user.Role(b.config.UnknownSenderRole()),
+			Email:   sender,
+			Sandbox: true,
+		}
+
+	case config.UnknownReject:
+		b.handledMu.Lock()
+		last, recent := b.rejected[sender]
+		recent = recent && time.Since(last) < rejectInterval
+		if !recent {
+			b.rejected[sender] = time.Now()
+		}
+		b.handledMu.Unlock()
+		// A known user's address that failed verification is likely forged;
+		// a refusal would land in the real user's inbox
+		if recent || b.users.FromEmail(sender) != nil {
+			L_debug("email: unknown sender ignored", "from", sender)
+			return nil
+		}
+		L_warn("email: unknown sender rejected", "from", sender, "subject", msg.Subject)
+		b.reply(msg, nil, rejectText, nil)
+		return nil
+
+	default:
+		L_warn("email: unknown sender ignored", "from", sender, "subject", msg.Subject)
+		return nil
+	}
+}
+
+// sessionKey gives each thread its own session, per user so nobody can
+// join another user's conversation by replying into it
+func (b *Bot) sessionKey(u *user.User, msg *api.Message) string {
+	return fmt.Sprintf("email:%s:%s", u.ID, msg.ThreadID())
+}
+
+// handleCommand runs a command from the first line of a message and replies
+// with the result
+func (b *Bot) handleCommand(msg *api.Message, u *user.User, text string) {
+	if !b.canUserUseCommands(u) {
+		L_debug("email: commands disabled for user", "user", u.Name, "command", text)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := commands.GetManager().Execute(ctx, text, b.sessionKey(u, msg), u.ID)
+	b.reply(msg, u, result.Markdown, nil)
+}
+
+// canUserUseCommands checks if the user has permission to use slash commands
+func (b *Bot) canUserUseCommands(u *user.User) bool {
+	resolvedRole, err := b.users.ResolveUserRole(u)
+	if err != nil || resolvedRole == nil {
+		L_warn("email: failed to resolve role for command check", "user", u.Name, "error", err)
+		return false
+	}
+	return resolvedRole.CanUseCommands()
+}
+
+// saveAttachments stores attachments in the media store and returns them as
+// content blocks: images and audio for the model to see or hear, anything
+// else as a note with the saved path so tools can open it
+func (b *Bot) saveAttachments(msg *api.Message, u *user.User) []itypes.ContentBlock {
+	if len(msg.Attachments) == 0 {
+		return nil
+	}
+	var store *media.MediaStore
+	if b.gateway != nil {
+		store = b.gateway.MediaStore()
+	}
+	if store == nil {
+		L_warn("email: no media store, attachments dropped", "count", len(msg.Attachments))
+		return nil
+	}
+
+	var blocks []itypes.ContentBlock
+	saved := 0
+	for _, att := range msg.Attachments {
+		mimeType := strings.ToLower(att.ContentType)
+		if mimeType == "" || mimeType == "application/octet-stream" {
+			if detected := media.DetectMIME(att.Data); detected != "" {
+				mimeType = strings.TrimSpace(strings.Split(detected, ";")[0])
+			}
+		}
+		isImage := strings.HasPrefix(mimeType, "image/")
+		if isImage && att.Inline && len(att.Data) < minInlineImage {
+			continue
+		}
+		if saved == maxAttachments {
+			L_warn("email: too many attachments, rest dropped", "from", msg.From.Address, "total", len(msg.Attachments))
+			break
+		}
+
+		mediaType := "document"
+		switch {
+		case isImage:
+			mediaType = "image"
+		case strings.HasPrefix(mimeType, "audio/"):
+			mediaType = "voice"
+		}
+		absPath, _, err := store.SaveUpload(att.Data, mimeToExt(mimeType, att.Filename), media.UploadContext{
+			Channel:       "email",
+			User:          u,
+			ChannelUserID: msg.From.Address,
+			ChatID:        msg.ThreadID(),
+			MediaType:     mediaType,
+			Caption:       att.Filename,
+		})
+		if err != nil {
+			L_warn("email: failed to save attachment", "filename", att.Filename, "error", err)
+			continue
+		}
+		saved++
+
+		switch mediaType {
+		case "image":
+			blocks = append(blocks, itypes.ContentBlock{Type: "image", FilePath: absPath, MimeType: mimeType, Source: "email"})
+		case "voice":
+			blocks = append(blocks, itypes.ContentBlock{Type: "audio", FilePath: absPath, MimeType: mimeType, Source: "email"})
+		default:
+			name := att.Filename
+			if name == "" {
+				name = "unnamed"
+			}
+			blocks = append(blocks, itypes.ContentBlock{
+				Type: "text",
+				Text: fmt.Sprintf("[Attachment %s (%s, %d bytes) saved to: %s]", name, mimeType, len(att.Data), absPath),
+			})
+		}
+	}
+	return blocks
+}
+
+// --- Outgoing mail ---
+
+// reply answers a message in its thread. u is nil for refusals.
+func (b *Bot) reply(msg *api.Message, u *user.User, text string, attachments []api.Attachment) {
+	to := msg.ReplyAddress()
+	out := &api.Outgoing{
+		From:        b.fromAddress(u),
+		To:          []*mail.Address{to},
+		Subject:     api.ReplySubject(msg.Subject),
+		InReplyTo:   msg.MessageID,
+		References:  msg.ReplyReferences(),
+		Attachments: attachments,
+		AutoReplied: u == nil,
+	}
+	b.setBody(out, text)
+
+	if err := b.send(b.ctx, out); err != nil {
+		L_error("email: failed to send reply", "to", to.Address, "error", err)
+		return
+	}
+	L_debug("email: reply sent", "to", to.Address, "subject", out.Subject, "attachments", len(out.Attachments))
+}
+
+// setBody sets the text and HTML parts, turning media references into
+// attachments
+func (b *Bot) setBody(out *api.Outgoing, text string) {
+	if media.ContainsMediaRefs(text) {
+		text, out.Attachments = b.extractMedia(text, out.Attachments)
+	}
+	out.Text = text
+	if hasFormatting(text) {
+		out.HTML = FormatHTML(text)
+	}
+}
+
+// extractMedia replaces media references with attachment names
+func (b *Bot) extractMedia(text string, attachments []api.Attachment) (string, []api.Attachment) {
+	var mediaRoot string
+	if b.gateway != nil && b.gateway.MediaStore() != nil {
+		mediaRoot = b.gateway.MediaStore().BaseDir()
+	}
+
+	var sb strings.Builder
+	for _, seg := range media.SplitMediaSegments(text) {
+		if !seg.IsMedia {
+			sb.WriteString(seg.Text)
+			continue
+		}
+		if strings.HasPrefix(seg.Mime, "error/") {
+			fmt.Fprintf(&sb, "[Media %s: %s]", strings.TrimPrefix(seg.Mime, "error/"), seg.Path)
+			continue
+		}
+		absPath, err := media.ResolveMediaPath(mediaRoot, seg.Path)
+		if err != nil {
+			L_warn("email: failed to resolve media path", "path", seg.Path, "error", err)
+			continue
+		}
+		att, err := fileAttachment(absPath)
+		if err != nil {
+			L_warn("email: failed to attach media", "path", absPath, "error", err)
+			continue
+		}
+		attachments = append(attachments, *att)
+		fmt.Fprintf(&sb, "[Attached: %s]", att.Filename)
+	}
+	return strings.TrimSpace(sb.String()), attachments
+}
+
+// fileAttachment reads a file to attach to a reply
+func fileAttachment(path string) (*api.Attachment, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > maxReplyAttachment {
+		return nil, fmt.Errorf("file too large to email (%d bytes)", info.Size())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mimeType, _ := media.DetectMimeType(path)
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return &api.Attachment{Filename: filepath.Base(path), ContentType: mimeType, Data: data}, nil
+}
+
+// send submits a message over SMTP
+func (b *Bot) send(ctx context.Context, out *api.Outgoing) error {
+	if len(out.To) == 0 || out.To[0] == nil || out.To[0].Address == "" {
+		return errors.New("email: no recipient")
+	}
+	data, err := out.Bytes()
+	if err != nil {
+		return err
+	}
+	rcpts := make([]string, len(out.To))
+	for i, to := range out.To {
+		rcpts[i] = to.Address
+	}
+	return b.smtp.Send(ctx, b.config.Address, rcpts, data)
+}
+
+// fromAddress is the bot's address with the configured or agent name
+func (b *Bot) fromAddress(u *user.User) *mail.Address {
+	name := b.config.Name
+	if name == "" {
+		name = b.agentName(u)
+	}
+	return &mail.Address{Name: name, Address: b.config.Address}
+}
+
+// agentName returns the name of the agent that answers u on this channel
+func (b *Bot) agentName(u *user.User) string {
+	if b.gateway == nil {
+		return "GoClaw"
+	}
+	chatID := ""
+	if u != nil {
+		chatID = u.Email
+	}
+	if identity := b.gateway.AgentIdentityFor(b.gateway.ResolveAgent("email", "", chatID, u)); identity != nil && identity.Name != "" {
+		return identity.Name
+	}
+	return "GoClaw"
+}
+
+// mimeToExt picks a file extension for a saved attachment
+func mimeToExt(mimeType, filename string) string {
+	if ext := filepath.Ext(filename); ext != "" && len(ext) <= 6 {
+		return strings.ToLower(ext)
+	}
+	switch mimeType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "audio/ogg":
+		return ".ogg"
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/mp4":
+		return ".m4a"
+	case "application/pdf":
+		return ".pdf"
+	case "text/plain":
+		return ".txt"
+	case "text/csv":
+		return ".csv"
+	case "text/calendar":
+		return ".ics"
+	default:
+		return ".bin"
+	}
+}
@@ -0,0 +1,356 @@
+// Package config defines the email channel configuration.
+// Separate package to avoid import cycles with gateway.
+package config
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"strconv"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/bus"
+	"github.com/roelfdiedericks/goclaw/internal/channels/email/api"
+	"github.com/roelfdiedericks/goclaw/internal/config/forms"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// What happens to mail from addresses that don't belong to a user
+const (
+	UnknownIgnore = "ignore" // Leave it unanswered (default)
+	UnknownReject = "reject" // Reply with a short refusal
+	UnknownRole   = "role"   // Answer as a transient user with UnknownRole
+)
+
+// Config holds the email channel configuration
+type Config struct {
+	Enabled        bool         `json:"enabled"`
+	Address        string       `json:"address"`                  // The bot's mailbox address
+	Name           string       `json:"name,omitempty"`           // Display name on outgoing mail (default: agent name)
+	IMAP           ServerConfig `json:"imap"`                     // Incoming mail
+	SMTP           ServerConfig `json:"smtp"`                     // Outgoing mail
+	Mailbox        string       `json:"mailbox,omitempty"`        // Folder to watch (default INBOX)
+	Idle           *bool        `json:"idle,omitempty"`           // Use IMAP IDLE when the server supports it (default true)
+	PollInterval   int          `json:"pollInterval,omitempty"`   // Seconds between checks without IDLE (default 60)
+	AuthServID     string       `json:"authservId,omitempty"`     // Mail server whose Authentication-Results are trusted
+	UnknownSenders string       `json:"unknownSenders,omitempty"` // ignore (default), reject or role
+	UnknownRole    string       `json:"unknownRole,omitempty"`    // Role for unknown senders with "role" (default guest)
+	Notifications  bool         `json:"notifications,omitempty"`  // Also deliver heartbeats, cron results and mirrors by email
+}
+
+// ServerConfig is a mail server connection
+type ServerConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port,omitempty"`     // Default depends on protocol and security
+	Security string `json:"security,omitempty"` // tls, starttls or none
+	Username string `json:"username,omitempty"` // Default: the bot address
+	Password string `json:"password,omitempty"`
+}
+
+// MailboxName returns the folder to watch
+func (c *Config) MailboxName() string {
+	if c.Mailbox == "" {
+		return "INBOX"
+	}
+	return c.Mailbox
+}
+
+// IdleEnabled reports whether IMAP IDLE should be used (default true)
+func (c *Config) IdleEnabled() bool {
+	return c.Idle == nil || *c.Idle
+}
+
+// PollEvery returns the interval between mailbox checks without IDLE
+func (c *Config) PollEvery() time.Duration {
+	if c.PollInterval <= 0 {
+		return 60 * time.Second
+	}
+	return time.Duration(c.PollInterval) * time.Second
+}
+
+// UnknownSenderPolicy returns ignore, reject or role
+func (c *Config) UnknownSenderPolicy() string {
+	if c.UnknownSenders == "" {
+		return UnknownIgnore
+	}
+	return c.UnknownSenders
+}
+
+// UnknownSenderRole returns the role given to unknown senders (default guest)
+func (c *Config) UnknownSenderRole() string {
+	if c.UnknownRole == "" {
+		return "guest"
+	}
+	return c.UnknownRole
+}
+
+// IMAPServer returns the IMAP address, security and login (defaults: tls, 993)
+func (c *Config) IMAPServer() (addr string, security api.Security, username, password string) {
+	security = securityOrDefault(c.IMAP.Security, api.SecurityTLS)
+	port := c.IMAP.Port
+	if port == 0 {
+		port = 993
+		if security != api.SecurityTLS {
+			port = 143
+		}
+	}
+	return net.JoinHostPort(c.IMAP.Host, strconv.Itoa(port)), security, c.login(c.IMAP), c.IMAP.Password
+}
+
+// SMTPServer returns the SMTP submission server (defaults: starttls, 587)
+func (c *Config) SMTPServer() *api.SMTPServer {
+	security := securityOrDefault(c.SMTP.Security, api.SecurityStartTLS)
+	port := c.SMTP.Port
+	if port == 0 {
+		switch security {
+		case api.SecurityTLS:
+			port = 465
+		case api.SecurityNone:
+			port = 25
+		default:
+			port = 587
+		}
+	}
+	server := &api.SMTPServer{
+		Addr:     net.JoinHostPort(c.SMTP.Host, strconv.Itoa(port)),
+		Security: security,
+	}
+	if c.SMTP.Password != "" {
+		server.Username = c.login(c.SMTP)
+		server.Password = c.SMTP.Password
+	}
+	return server
+}
+
+func (c *Config) login(s ServerConfig) string {
+	if s.Username != "" {
+		return s.Username
+	}
+	return c.Address
+}
+
+func securityOrDefault(s string, def api.Security) api.Security {
+	if s == "" {
+		return def
+	}
+	return api.Security(s)
+}
+
+// Check validates the settings
+func (c *Config) Check() error {
+	if c.Address == "" {
+		return fmt.Errorf("bot email address is required")
+	}
+	if _, err := mail.ParseAddress(c.Address); err != nil {
+		return fmt.Errorf("invalid email address %q: %w", c.Address, err)
+	}
+	if c.IMAP.Host == "" {
+		return fmt.Errorf("IMAP host is required")
+	}
+	if c.IMAP.Password == "" {
+		return fmt.Errorf("IMAP password is required")
+	}
+	if c.SMTP.Host == "" {
+		return fmt.Errorf("SMTP host is required")
+	}
+	for name, s := range map[string]string{"IMAP": c.IMAP.Security, "SMTP": c.SMTP.Security} {
+		switch api.Security(s) {
+		case "", api.SecurityTLS, api.SecurityStartTLS, api.SecurityNone:
+		default:
+			return fmt.Errorf("%s security must be tls, starttls or none, got %q", name, s)
+		}
+	}
+	switch c.UnknownSenderPolicy() {
+	case UnknownIgnore, UnknownReject:
+	case UnknownRole:
+		if c.UnknownSenderRole() == "owner" {
+			return fmt.Errorf("unknown senders cannot be given the owner role")
+		}
+	default:
+		return fmt.Errorf("unknownSenders must be ignore, reject or role, got %q", c.UnknownSenders)
+	}
+	return nil
+}
+
+func securityOptions() []forms.Option {
+	return []forms.Option{
+		{Label: "TLS", Value: string(api.SecurityTLS)},
+		{Label: "STARTTLS", Value: string(api.SecurityStartTLS)},
+		{Label: "None (local servers only)", Value: string(api.SecurityNone)},
+	}
+}
+
+func serverFormDef(defaultSecurity api.Security, portDesc string) *forms.FormDef {
+	return &forms.FormDef{
+		Sections: []forms.Section{
+			{
+				Fields: []forms.Field{
+					{Name: "host", Title: "Host", Desc: "Server hostname", Type: forms.Text},
+					{Name: "port", Title: "Port", Desc: portDesc, Type: forms.Number, Min: 0, Max: 65535},
+					{Name: "security", Title: "Security", Desc: "How the connection is encrypted", Type: forms.Select, Default: string(defaultSecurity), Options: securityOptions()},
+					{Name: "username", Title: "Username", Desc: "Login name (empty = the bot address)", Type: forms.Text},
+					{Name: "password", Title: "Password", Desc: "Password or app password", Type: forms.Secret},
+				},
+			},
+		},
+	}
+}
+
+// ConfigFormDef returns the form definition for editing email config
+func ConfigFormDef() forms.FormDef {
+	return forms.FormDef{
+		Title:       "Email",
+		Description: "Configure the email channel (IMAP in, SMTP out)",
+		Sections: []forms.Section{
+			{
+				Title: "Mailbox",
+				Fields: []forms.Field{
+					{Name: "enabled", Title: "Enabled", Desc: "Enable the email channel", Type: forms.Toggle},
+					{Name: "address", Title: "Address", Desc: "The bot's email address, e.g. goclaw@example.org", Type: forms.Text},
+					{Name: "name", Title: "Display Name", Desc: "Sender name on outgoing mail (empty = agent name)", Type: forms.Text},
+					{Name: "mailbox", Title: "Folder", Desc: "IMAP folder to watch", Type: forms.Text, Default: "INBOX"},
+					{Name: "idle", Title: "Use IDLE", Desc: "Get new mail pushed instead of polling, when the server supports it", Type: forms.Toggle, Default: true},
+					{Name: "pollInterval", Title: "Poll Interval", Desc: "Seconds between checks when not using IDLE", Type: forms.Number, Default: 60, Min: 10, Max: 3600},
+				},
+			},
+			{
+				Title:     "IMAP",
+				Desc:      "Incoming mail server",
+				FieldName: "IMAP",
+				Nested:    serverFormDef(api.SecurityTLS, "Default 993 (TLS) or 143"),
+			},
+			{
+				Title:     "SMTP",
+				Desc:      "Outgoing mail server",
+				FieldName: "SMTP",
+				Nested:    serverFormDef(api.SecurityStartTLS, "Default 587 (STARTTLS), 465 (TLS) or 25"),
+			},
+			{
+				Title: "Senders",
+				Fields: []forms.Field{
+					{Name: "authservId", Title: "Trusted Mail Server", Desc: "authserv-id of your provider, the first word of the Authentication-Results headers it adds (e.g. mx.google.com). Senders are only recognised when this server verified their address.", Type: forms.Text},
+					{Name: "unknownSenders", Title: "Unknown Senders", Desc: "Mail from addresses no user has set as their email", Type: forms.Select, Default: UnknownIgnore, Options: []forms.Option{
+						{Label: "Ignore", Value: UnknownIgnore},
+						{Label: "Reply with a refusal", Value: UnknownReject},
+						{Label: "Answer with a restricted role", Value: UnknownRole},
+					}},
+					{Name: "unknownRole", Title: "Unknown Sender Role", Desc: "Role used for unknown senders (must not be owner)", Type: forms.Text, Default: "guest"},
+					{Name: "notifications", Title: "Notifications", Desc: "Also email heartbeats, cron results and mirrored replies to users with an email address", Type: forms.Toggle},
+				},
+			},
+		},
+		Actions: []forms.ActionDef{
+			{
+				Name:  "test",
+				Label: "Test Connection",
+				Desc:  "Log in to the IMAP and SMTP servers with these settings",
+			},
+			{
+				Name:  "apply",
+				Label: "Apply Now",
+				Desc:  "Apply changes to running email channel (requires gateway)",
+			},
+		},
+	}
+}
+
+const configPath = "channels.email"
+
+// RegisterCommands registers email config command handlers
+func RegisterCommands() {
+	bus.RegisterCommand(configPath, "test", handleTest)
+	bus.RegisterCommand(configPath, "apply", handleApply)
+}
+
+// UnregisterCommands unregisters email config command handlers
+func UnregisterCommands() {
+	bus.UnregisterComponent(configPath)
+}
+
+func handleApply(cmd bus.Command) bus.CommandResult {
+	cfg, ok := cmd.Payload.(*Config)
+	if !ok {
+		return bus.CommandResult{
+			Error:   fmt.Errorf("invalid payload type: expected *Config, got %T", cmd.Payload),
+			Message: "Internal error: invalid config type",
+		}
+	}
+
+	logging.L_info("email: config applied", "enabled", cfg.Enabled, "address", cfg.Address)
+	bus.PublishEvent(configPath+".config.applied", cfg)
+
+	return bus.CommandResult{
+		Success: true,
+		Message: "Config applied - channel will restart if needed",
+	}
+}
+
+// handleTest logs in to both servers
+func handleTest(cmd bus.Command) bus.CommandResult {
+	cfg, ok := cmd.Payload.(*Config)
+	if !ok {
+		return bus.CommandResult{
+			Error:   fmt.Errorf("invalid payload type"),
+			Message: "Internal error: invalid config type",
+		}
+	}
+
+	if err := cfg.Check(); err != nil {
+		return bus.CommandResult{Error: err, Message: err.Error()}
+	}
+
+	exists, err := TestConnection(cfg)
+	if err != nil {
+		logging.L_warn("email: test connection failed", "error", err)
+		return bus.CommandResult{
+			Error:   err,
+			Message: fmt.Sprintf("Connection failed: %s", err),
+		}
+	}
+
+	logging.L_info("email: test connection successful", "address", cfg.Address)
+	return bus.CommandResult{
+		Success: true,
+		Message: fmt.Sprintf("Connected: %d messages in %s, SMTP login OK", exists, cfg.MailboxName()),
+	}
+}
+
+// TestConnection logs in to IMAP, selects the mailbox and checks the SMTP
+// login. It returns the number of messages in the mailbox.
+func TestConnection(cfg *Config) (uint32, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := Connect(ctx, cfg)
+	if err != nil {
+		return 0, err
+	}
+	status, err := client.Select(cfg.MailboxName())
+	if err != nil {
+		client.Close()
+		return 0, err
+	}
+	if err := client.Logout(); err != nil {
+		logging.L_debug("email: test logout failed", "error", err)
+	}
+
+	if err := cfg.SMTPServer().Verify(ctx); err != nil {
+		return 0, err
+	}
+	return status.Exists, nil
+}
+
+// Connect dials the IMAP server and logs in
+func Connect(ctx context.Context, cfg *Config) (*api.IMAPClient, error) {
+	addr, security, username, password := cfg.IMAPServer()
+	client, err := api.DialIMAP(ctx, addr, security)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Login(username, password); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
@@ -0,0 +1,608 @@
+// Package emailtest provides in-process IMAP and SMTP servers for testing the
+// email channel. The IMAP server has one mailbox (INBOX) and implements what
+// the channel uses: LOGIN, AUTHENTICATE PLAIN, SELECT, UID SEARCH, UID FETCH,
+// UID STORE and IDLE. The SMTP server accepts authenticated submissions and
+// records them.
+package emailtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/channels/email/api"
+	"github.com/roelfdiedericks/goclaw/internal/channels/email/config"
+)
+
+// Credentials accepted by both servers
+const (
+	Address  = "bot@example.org"
+	Password = "secret"
+)
+
+// Sent is a message received by the SMTP server
+type Sent struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+type message struct {
+	uid      uint32
+	raw      []byte
+	seen     bool
+	received time.Time
+}
+
+// Server is a fake IMAP and SMTP server pair
+type Server struct {
+	imapLn net.Listener
+	smtpLn net.Listener
+
+	mu        sync.Mutex
+	messages  []*message
+	nextUID   uint32
+	sent      []Sent
+	logins    int
+	noIdle    bool
+	noPlain   bool
+	newMail   chan struct{} // Closed and replaced on each delivery
+	conns     map[net.Conn]bool
+	closed    bool
+	waitGroup sync.WaitGroup
+}
+
+// NewServer starts both servers on loopback ports
+func NewServer() *Server {
+	imapLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("emailtest: listen: %v", err))
+	}
+	smtpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("emailtest: listen: %v", err))
+	}
+	s := &Server{
+		imapLn:  imapLn,
+		smtpLn:  smtpLn,
+		nextUID: 1,
+		newMail: make(chan struct{}),
+		conns:   make(map[net.Conn]bool),
+	}
+	s.waitGroup.Add(2)
+	go s.accept(imapLn, s.serveIMAP)
+	go s.accept(smtpLn, s.serveSMTP)
+	return s
+}
+
+// IMAPAddr returns the IMAP server's host:port
+func (s *Server) IMAPAddr() string {
+	return s.imapLn.Addr().String()
+}
+
+// SMTPAddr returns the SMTP server's host:port
+func (s *Server) SMTPAddr() string {
+	return s.smtpLn.Addr().String()
+}
+
+// Config returns an enabled email channel config pointing at the servers
+func (s *Server) Config() *config.Config {
+	imapHost, imapPort := splitAddr(s.IMAPAddr())
+	smtpHost, smtpPort := splitAddr(s.SMTPAddr())
+	return &config.Config{
+		Enabled: true,
+		Address: Address,
+		IMAP: config.ServerConfig{
+			Host:     imapHost,
+			Port:     imapPort,
+			Security: string(api.SecurityNone),
+			Username: Address,
+			Password: Password,
+		},
+		SMTP: config.ServerConfig{
+			Host:     smtpHost,
+			Port:     smtpPort,
+			Security: string(api.SecurityNone),
+			Username: Address,
+			Password: Password,
+		},
+	}
+}
+
+func splitAddr(addr string) (string, int) {
+	host, port, _ := net.SplitHostPort(addr)
+	n, _ := strconv.Atoi(port)
+	return host, n
+}
+
+// DisableIdle stops advertising IDLE, so clients have to poll
+func (s *Server) DisableIdle() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noIdle = true
+}
+
+// DisableAuthPlain stops advertising AUTH=PLAIN, so IMAP clients use LOGIN
+func (s *Server) DisableAuthPlain() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noPlain = true
+}
+
+// Deliver adds a raw message to the INBOX and wakes idling clients. It
+// returns the message's UID.
+func (s *Server) Deliver(raw string) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	raw = strings.ReplaceAll(strings.ReplaceAll(raw, "\r\n", "\n"), "\n", "\r\n")
+	m := &message{uid: s.nextUID, raw: []byte(raw), received: time.Now()}
+	s.nextUID++
+	s.messages = append(s.messages, m)
+	close(s.newMail)
+	s.newMail = make(chan struct{})
+	return m.uid
+}
+
+// DeliverOld adds a message that arrived at the given time, without waking clients
+func (s *Server) DeliverOld(raw string, received time.Time) uint32 {
+	uid := s.Deliver(raw)
+	s.mu.Lock()
+	s.messages[len(s.messages)-1].received = received
+	s.mu.Unlock()
+	return uid
+}
+
+// Seen reports whether a message has the \Seen flag
+func (s *Server) Seen(uid uint32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if m := s.find(uid); m != nil {
+		return m.seen
+	}
+	return false
+}
+
+// Sent returns the messages submitted over SMTP
+func (s *Server) Sent() []Sent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Sent(nil), s.sent...)
+}
+
+// WaitSent waits until at least n messages were submitted and returns them
+func (s *Server) WaitSent(n int, timeout time.Duration) []Sent {
+	deadline := time.Now().Add(timeout)
+	for {
+		sent := s.Sent()
+		if len(sent) >= n || time.Now().After(deadline) {
+			return sent
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Logins returns the number of successful IMAP logins
+func (s *Server) Logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logins
+}
+
+// DropConnections closes all open client connections
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+// Close stops both servers
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.imapLn.Close()
+	s.smtpLn.Close()
+	s.DropConnections()
+	s.waitGroup.Wait()
+}
+
+func (s *Server) accept(ln net.Listener, serve func(net.Conn)) {
+	defer s.waitGroup.Done()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.mu.Unlock()
+
+		s.waitGroup.Add(1)
+		go func() {
+			defer s.waitGroup.Done()
+			defer func() {
+				s.mu.Lock()
+				delete(s.conns, conn)
+				s.mu.Unlock()
+				conn.Close()
+			}()
+			serve(conn)
+		}()
+	}
+}
+
+func (s *Server) find(uid uint32) *message {
+	for _, m := range s.messages {
+		if m.uid == uid {
+			return m
+		}
+	}
+	return nil
+}
+
+// --- IMAP ---
+
+type imapConn struct {
+	s        *Server
+	r        *bufio.Reader
+	w        *bufio.Writer
+	authed   bool
+	selected bool
+	reported int // Message count last reported to the client
+}
+
+func (c *imapConn) send(format string, args ...any) {
+	fmt.Fprintf(c.w, format+"\r\n", args...)
+	c.w.Flush()
+}
+
+func (s *Server) serveIMAP(conn net.Conn) {
+	c := &imapConn{s: s, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	c.send("* OK fake IMAP4rev1 ready")
+
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd, err := api.ReadResponse(bufio.NewReader(strings.NewReader(line)))
+		if err != nil || len(cmd.Fields) == 0 {
+			c.send("* BAD unparseable command")
+			continue
+		}
+		tag := cmd.Tag
+		name := strings.ToUpper(str(cmd.Fields[0]))
+		args := cmd.Fields[1:]
+
+		switch {
+		case name == "CAPABILITY":
+			s.mu.Lock()
+			caps := "IMAP4rev1"
+			if !s.noIdle {
+				caps += " IDLE"
+			}
+			if !s.noPlain {
+				caps += " AUTH=PLAIN"
+			}
+			s.mu.Unlock()
+			c.send("* CAPABILITY %s", caps)
+			c.send("%s OK CAPABILITY completed", tag)
+		case name == "LOGIN" && len(args) == 2:
+			c.login(tag, str(args[0]), str(args[1]))
+		case name == "AUTHENTICATE" && len(args) == 1 && strings.EqualFold(str(args[0]), "PLAIN"):
+			c.send("+ ")
+			resp, err := c.r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			decoded, _ := base64.StdEncoding.DecodeString(strings.TrimSpace(resp))
+			parts := strings.Split(string(decoded), "\x00")
+			if len(parts) != 3 {
+				c.send("%s NO [AUTHENTICATIONFAILED] bad PLAIN response", tag)
+				continue
+			}
+			c.login(tag, parts[1], parts[2])
+		case name == "LOGOUT":
+			c.send("* BYE logging out")
+			c.send("%s OK LOGOUT completed", tag)
+			return
+		case name == "NOOP":
+			if c.selected {
+				c.reportNew()
+			}
+			c.send("%s OK NOOP completed", tag)
+		case !c.authed:
+			c.send("%s NO not authenticated", tag)
+		case name == "SELECT" && len(args) == 1:
+			if !strings.EqualFold(str(args[0]), "INBOX") {
+				c.send("%s NO [NONEXISTENT] no such mailbox", tag)
+				continue
+			}
+			s.mu.Lock()
+			exists, next := len(s.messages), s.nextUID
+			s.mu.Unlock()
+			c.selected = true
+			c.reported = exists
+			c.send("* %d EXISTS", exists)
+			c.send("* OK [UIDVALIDITY 1] UIDs valid")
+			c.send("* OK [UIDNEXT %d] predicted next UID", next)
+			c.send("%s OK [READ-WRITE] SELECT completed", tag)
+		case !c.selected:
+			c.send("%s BAD no mailbox selected", tag)
+		case name == "UID" && len(args) >= 1:
+			c.uidCommand(tag, strings.ToUpper(str(args[0])), args[1:])
+		case name == "IDLE":
+			if !c.idle(tag) {
+				return
+			}
+		default:
+			c.send("%s BAD unknown command %s", tag, name)
+		}
+	}
+}
+
+func (c *imapConn) login(tag, username, password string) {
+	if username != Address || password != Password {
+		c.send("%s NO [AUTHENTICATIONFAILED] invalid credentials", tag)
+		return
+	}
+	c.s.mu.Lock()
+	c.s.logins++
+	c.s.mu.Unlock()
+	c.authed = true
+	c.send("%s OK logged in", tag)
+}
+
+// reportNew sends EXISTS when messages arrived since the last report
+func (c *imapConn) reportNew() {
+	c.s.mu.Lock()
+	n := len(c.s.messages)
+	c.s.mu.Unlock()
+	if n != c.reported {
+		c.reported = n
+		c.send("* %d EXISTS", n)
+	}
+}
+
+func (c *imapConn) uidCommand(tag, sub string, args []any) {
+	s := c.s
+	switch sub {
+	case "SEARCH":
+		var since time.Time
+		unseen := false
+		for i := 0; i < len(args); i++ {
+			switch strings.ToUpper(str(args[i])) {
+			case "UNSEEN":
+				unseen = true
+			case "SINCE":
+				if i+1 < len(args) {
+					since, _ = time.Parse("2-Jan-2006", str(args[i+1]))
+					i++
+				}
+			}
+		}
+		var uids []string
+		s.mu.Lock()
+		for _, m := range s.messages {
+			day := time.Date(m.received.Year(), m.received.Month(), m.received.Day(), 0, 0, 0, 0, time.UTC)
+			if (unseen && m.seen) || (!since.IsZero() && day.Before(since)) {
+				continue
+			}
+			uids = append(uids, strconv.FormatUint(uint64(m.uid), 10))
+		}
+		s.mu.Unlock()
+		c.send("* SEARCH %s", strings.Join(uids, " "))
+		c.send("%s OK SEARCH completed", tag)
+
+	case "FETCH":
+		if len(args) < 2 {
+			c.send("%s BAD FETCH needs a set and items", tag)
+			return
+		}
+		peek := strings.Contains(strings.ToUpper(fmt.Sprint(args[1])), "PEEK")
+		s.mu.Lock()
+		for seq, m := range s.messages {
+			if !inSet(m.uid, str(args[0])) {
+				continue
+			}
+			if !peek {
+				m.seen = true
+			}
+			fmt.Fprintf(c.w, "* %d FETCH (UID %d BODY[] {%d}\r\n", seq+1, m.uid, len(m.raw))
+			c.w.Write(m.raw)
+			c.w.WriteString(")\r\n")
+		}
+		s.mu.Unlock()
+		c.send("%s OK FETCH completed", tag)
+
+	case "STORE":
+		if len(args) < 3 {
+			c.send("%s BAD STORE needs a set, an item and flags", tag)
+			return
+		}
+		item := strings.ToUpper(str(args[1]))
+		flags, _ := args[2].([]any)
+		setSeen := false
+		for _, f := range flags {
+			if strings.EqualFold(str(f), `\Seen`) {
+				setSeen = true
+			}
+		}
+		s.mu.Lock()
+		for seq, m := range s.messages {
+			if !inSet(m.uid, str(args[0])) || !setSeen {
+				continue
+			}
+			m.seen = strings.HasPrefix(item, "+")
+			if !strings.HasSuffix(item, ".SILENT") {
+				fmt.Fprintf(c.w, "* %d FETCH (UID %d FLAGS (%s))\r\n", seq+1, m.uid, map[bool]string{true: `\Seen`}[m.seen])
+			}
+		}
+		s.mu.Unlock()
+		c.send("%s OK STORE completed", tag)
+
+	default:
+		c.send("%s BAD unknown UID command %s", tag, sub)
+	}
+}
+
+// idle waits for DONE, reporting new mail meanwhile. It returns false when
+// the connection is gone.
+func (c *imapConn) idle(tag string) bool {
+	c.send("+ idling")
+
+	done := make(chan error, 1)
+	go func() {
+		line, err := c.r.ReadString('\n')
+		if err == nil && !strings.EqualFold(strings.TrimSpace(line), "DONE") {
+			err = fmt.Errorf("expected DONE, got %q", line)
+		}
+		done <- err
+	}()
+
+	for {
+		c.s.mu.Lock()
+		wake := c.s.newMail
+		c.s.mu.Unlock()
+		c.reportNew()
+
+		select {
+		case err := <-done:
+			if err != nil {
+				return false
+			}
+			c.send("%s OK IDLE terminated", tag)
+			return true
+		case <-wake:
+		}
+	}
+}
+
+// inSet reports whether uid is in an IMAP sequence set like "1,4:7,9:*"
+func inSet(uid uint32, set string) bool {
+	for _, r := range strings.Split(set, ",") {
+		lo, hi, isRange := strings.Cut(r, ":")
+		from, _ := strconv.ParseUint(lo, 10, 32)
+		if !isRange {
+			if uint32(from) == uid {
+				return true
+			}
+			continue
+		}
+		to := uint64(^uint32(0))
+		if hi != "*" {
+			to, _ = strconv.ParseUint(hi, 10, 32)
+		}
+		if uint64(uid) >= from && uint64(uid) <= to {
+			return true
+		}
+	}
+	return false
+}
+
+func str(f any) string {
+	s, _ := f.(string)
+	return s
+}
+
+// --- SMTP ---
+
+func (s *Server) serveSMTP(conn net.Conn) {
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 fake ESMTP ready")
+
+	authed := false
+	var from string
+	var to []string
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250-fake.example.org\r\n250-8BITMIME\r\n250 AUTH PLAIN")
+		case "AUTH":
+			mech, ir, _ := strings.Cut(arg, " ")
+			if !strings.EqualFold(mech, "PLAIN") {
+				_ = tp.PrintfLine("504 unsupported mechanism")
+				continue
+			}
+			if ir == "" {
+				_ = tp.PrintfLine("334 ")
+				if ir, err = tp.ReadLine(); err != nil {
+					return
+				}
+			}
+			decoded, _ := base64.StdEncoding.DecodeString(ir)
+			parts := strings.Split(string(decoded), "\x00")
+			if len(parts) != 3 || parts[1] != Address || parts[2] != Password {
+				_ = tp.PrintfLine("535 authentication failed")
+				continue
+			}
+			authed = true
+			_ = tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			if !authed {
+				_ = tp.PrintfLine("530 authentication required")
+				continue
+			}
+			from = addrArg(arg)
+			to = nil
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			to = append(to, addrArg(arg))
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			if from == "" || len(to) == 0 {
+				_ = tp.PrintfLine("503 need MAIL and RCPT first")
+				continue
+			}
+			_ = tp.PrintfLine("354 end with <CRLF>.<CRLF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.sent = append(s.sent, Sent{From: from, To: to, Data: bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))})
+			s.mu.Unlock()
+			from, to = "", nil
+			_ = tp.PrintfLine("250 queued")
+		case "RSET":
+			from, to = "", nil
+			_ = tp.PrintfLine("250 OK")
+		case "NOOP":
+			_ = tp.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+// addrArg extracts the address from "FROM:<a@b>" or "TO:<a@b> SIZE=..."
+func addrArg(arg string) string {
+	if start := strings.Index(arg, "<"); start >= 0 {
+		if end := strings.Index(arg[start:], ">"); end > 0 {
+			return arg[start+1 : start+end]
+		}
+	}
+	return arg
+}
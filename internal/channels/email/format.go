@@ -0,0 +1,53 @@
+package email
+
+import (
+	"bytes"
+	"html"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+)
+
+// markdown converts agent markdown to HTML for the HTML part of replies.
+// Raw HTML in the markdown is escaped.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(gmhtml.WithHardWraps()),
+)
+
+// htmlTemplate wraps converted markdown in a minimal document. Mail clients
+// ignore most CSS, so styling is limited to what renders everywhere.
+const htmlTemplate = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"></head>
+<body style="font-family: sans-serif; line-height: 1.4;">
+%s
+</body></html>
+`
+
+// FormatHTML converts markdown to an HTML email body.
+// If conversion fails, returns the escaped markdown in a <pre> block.
+func FormatHTML(text string) string {
+	var buf bytes.Buffer
+	body := ""
+	if err := markdown.Convert([]byte(text), &buf); err != nil {
+		body = "<pre>" + html.EscapeString(text) + "</pre>"
+	} else {
+		body = strings.TrimSpace(buf.String())
+	}
+	return strings.Replace(htmlTemplate, "%s", body, 1)
+}
+
+// hasFormatting reports whether markdown renders to more than plain paragraphs,
+// i.e. whether an HTML part adds anything
+func hasFormatting(text string) bool {
+	return strings.ContainsAny(text, "*_`#[|>") || strings.Contains(text, "\n- ") || strings.HasPrefix(text, "- ")
+}
+
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
@@ -0,0 +1,127 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/audit"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// auditPageLimit caps the number of events listed on the audit page
+const auditPageLimit = 200
+
+// AuditRow is one event on the audit page
+type AuditRow struct {
+	Seq     int64
+	Time    time.Time
+	Type    string
+	Actor   string
+	Session string
+	Purpose string
+	Inputs  string
+	Result  string
+	Error   string
+}
+
+// handleAudit handles GET /audit - the privileged action audit log (owner only).
+// Filters: ?type=, ?actor=, ?session=, ?q=, ?since= (duration, e.g. 24h).
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if err := s.reloadTemplatesIfDev(); err != nil {
+		logging.L_error("http: template reload error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+
+	u := getUserFromContext(r)
+	if u == nil {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	if !u.IsOwner() {
+		http.Error(w, "Forbidden - owner only", http.StatusForbidden)
+		return
+	}
+
+	q := r.URL.Query()
+	data := struct {
+		Title   string
+		User    *UserTemplateData
+		Enabled bool
+		Rows    []AuditRow
+		Filter  map[string]string
+		Verify  *audit.VerifyResult
+		Error   string
+	}{
+		Title: "GoClaw - Audit Log",
+		User:  &UserTemplateData{Name: u.Name, Username: u.ID, Role: string(u.Role), IsOwner: u.IsOwner()},
+		Filter: map[string]string{
+			"type":    q.Get("type"),
+			"actor":   q.Get("actor"),
+			"session": q.Get("session"),
+			"q":       q.Get("q"),
+			"since":   q.Get("since"),
+		},
+	}
+
+	if log := audit.Get(); log != nil {
+		data.Enabled = true
+		filter := audit.Filter{
+			Type:    q.Get("type"),
+			Actor:   q.Get("actor"),
+			Session: q.Get("session"),
+			Text:    q.Get("q"),
+			Limit:   auditPageLimit,
+		}
+		if since := q.Get("since"); since != "" {
+			if d, err := time.ParseDuration(since); err == nil {
+				filter.Since = time.Now().Add(-d)
+			} else {
+				data.Error = "Invalid since duration (use e.g. 24h)"
+			}
+		}
+
+		events, err := audit.Query(log.Path(), filter)
+		if err != nil {
+			data.Error = "Failed to read audit log: " + err.Error()
+		}
+		// Newest first
+		for i := len(events) - 1; i >= 0; i-- {
+			data.Rows = append(data.Rows, auditRow(events[i]))
+		}
+
+		if q.Get("verify") != "" {
+			if res, err := audit.Verify(log.Path()); err != nil {
+				data.Error = "Failed to verify audit log: " + err.Error()
+			} else {
+				data.Verify = res
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates.ExecuteTemplate(w, "audit.html", data); err != nil {
+		logging.L_error("http: template error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+	}
+}
+
+func auditRow(e audit.Event) AuditRow {
+	row := AuditRow{
+		Seq:     e.Seq,
+		Time:    e.Time,
+		Type:    e.Type,
+		Actor:   e.Actor,
+		Session: e.Session,
+		Purpose: e.Purpose,
+		Result:  e.Result,
+		Error:   e.Error,
+	}
+	if len(e.Inputs) > 0 {
+		if data, err := json.MarshalIndent(e.Inputs, "", "  "); err == nil {
+			row.Inputs = string(data)
+		}
+	}
+	return row
+}
@@ -2,7 +2,9 @@ package http
 
 import (
 	"context"
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/roelfdiedericks/goclaw/internal/logging"
@@ -19,8 +21,18 @@ const sessionCookieName = "goclaw_session"
 // basicAuth middleware enforces HTTP Basic Authentication and manages session cookies
 func (s *Server) basicAuth(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// A verified client certificate mapped to a user logs in without a password
+		if subject, cn := clientCertSubject(r); subject != "" {
+			if u := s.users.FromCertSubject(subject, cn); u != nil {
+				logging.L_trace("http: client certificate auth success", "username", u.ID, "subject", subject)
+				s.serveAuthenticated(w, r, u, handler)
+				return
+			}
+			logging.L_debug("http: client certificate not mapped to a user", "subject", subject)
+		}
+
 		// Get client IP for rate limiting
-		clientIP := getClientIP(r)
+		clientIP := s.getClientIP(r)
 
 		// Check if rate limited
 		if s.rateLimiter.IsLimited(clientIP) {
@@ -59,47 +71,79 @@ func (s *Server) basicAuth(handler http.HandlerFunc) http.HandlerFunc {
 
 		// Success - clear any rate limit
 		s.rateLimiter.ClearFailure(clientIP)
+		logging.L_trace("http: auth success", "username", username, "ip", clientIP)
 
-		// Get or create session ID from cookie
-		sessionID := getSessionID(r)
-		if sessionID == "" {
-			sessionID = uuid.New().String()
-			http.SetCookie(w, &http.Cookie{
-				Name:     sessionCookieName,
-				Value:    sessionID,
-				Path:     "/",
-				HttpOnly: true,
-				SameSite: http.SameSiteStrictMode,
-				MaxAge:   86400 * 30, // 30 days
-			})
-			logging.L_debug("http: new session created", "session", sessionID, "user", username)
-		}
-
-		logging.L_trace("http: auth success", "username", username, "session", sessionID[:8]+"...", "ip", clientIP)
+		s.serveAuthenticated(w, r, u, handler)
+	}
+}
 
-		// Store user and session in request context
-		ctx := r.Context()
-		ctx = setUserInContext(ctx, u)
-		ctx = setSessionInContext(ctx, sessionID)
-		r = r.WithContext(ctx)
+// serveAuthenticated runs handler for an authenticated user, with a
+// session ID from the session cookie (set if missing). State-changing
+// requests from other sites are rejected.
+func (s *Server) serveAuthenticated(w http.ResponseWriter, r *http.Request, u *user.User, handler http.HandlerFunc) {
+	// The browser sent these credentials, possibly for another site's page
+	if !checkSameOrigin(w, r) {
+		return
+	}
 
-		handler(w, r)
+	sessionID := getSessionID(r)
+	if sessionID == "" {
+		sessionID = uuid.New().String()
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookieName,
+			Value:    sessionID,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteStrictMode,
+			MaxAge:   86400 * 30, // 30 days
+		})
+		logging.L_debug("http: new session created", "session", sessionID, "user", u.ID)
 	}
+
+	// Store user and session in request context
+	ctx := r.Context()
+	ctx = setUserInContext(ctx, u)
+	ctx = setSessionInContext(ctx, sessionID)
+	handler(w, r.WithContext(ctx))
 }
 
-// getClientIP extracts the client IP from the request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For first (if behind reverse proxy)
+// getClientIP returns the client IP for rate limiting and logging.
+// X-Forwarded-For and X-Real-IP are only believed when the connection
+// comes from a trusted proxy; otherwise any client could pick its own IP
+// (and dodge the rate limiter). The port is dropped, so reconnecting
+// doesn't reset the limit either.
+func (s *Server) getClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	s.mu.RLock()
+	trusted := s.trustedProxies
+	s.mu.RUnlock()
+	if !isTrustedProxy(net.ParseIP(host), trusted) {
+		return host
+	}
+
+	// Walk X-Forwarded-For from the right: the first address that isn't
+	// one of our proxies is the client
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP in the list
-		return xff
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			ip := net.ParseIP(hop)
+			if ip == nil {
+				break
+			}
+			if i == 0 || !isTrustedProxy(ip, trusted) {
+				return hop
+			}
+		}
 	}
-	// Check X-Real-IP
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(xri) != nil {
 		return xri
 	}
-	// Fall back to RemoteAddr
-	return r.RemoteAddr
+	return host
 }
 
 // getUserFromContext retrieves the authenticated user from request context
@@ -72,6 +72,7 @@ type BufferedEvent struct {
 type SSEEvent struct {
 	Event string      `json:"event"`
 	Data  interface{} `json:"data"`
+	ID    int         `json:"-"` // Buffer ID, set by SendEvent (0 = not buffered)
 }
 
 // NewHTTPChannel creates a new HTTP channel
@@ -350,6 +351,7 @@ func (s *SSESession) SendEvent(event SSEEvent) {
 	s.nextEventID++
 
 	// Add to buffer
+	event.ID = eventID
 	buffered := BufferedEvent{ID: eventID, Event: event}
 	s.eventBuffer = append(s.eventBuffer, buffered)
 
@@ -373,6 +375,21 @@ func (s *SSESession) SendEvent(event SSEEvent) {
 	}
 }
 
+// SendTransient sends an event to the active connection without buffering
+// it for replay (typing indicators and other short-lived state)
+func (s *SSESession) SendTransient(event SSEEvent) {
+	s.connMu.Lock()
+	conn := s.activeConn
+	s.connMu.Unlock()
+
+	if conn != nil {
+		select {
+		case conn.Events <- event:
+		default:
+		}
+	}
+}
+
 // GetEventsSince returns buffered events since the given ID
 func (s *SSESession) GetEventsSince(lastEventID int) []BufferedEvent {
 	s.bufferMu.Lock()
@@ -442,8 +459,9 @@ func (c *HTTPChannel) GetSessionsInfo() []SessionInfo {
 	return sessions
 }
 
-// RunAgentRequest runs an agent request and streams events to the session
-func (c *HTTPChannel) RunAgentRequest(ctx context.Context, sessionID string, u *user.User, message string, contentBlocks []types.ContentBlock) error {
+// RunAgentRequest runs an agent request and streams events to the session.
+// sessionKey selects a gateway session; empty uses the user's default.
+func (c *HTTPChannel) RunAgentRequest(ctx context.Context, sessionID, sessionKey string, u *user.User, message string, contentBlocks []types.ContentBlock) error {
 	if c.gateway == nil {
 		return fmt.Errorf("gateway not configured")
 	}
@@ -458,6 +476,7 @@ func (c *HTTPChannel) RunAgentRequest(ctx context.Context, sessionID string, u *
 	req := gateway.AgentRequest{
 		User:           u,
 		Source:         "http",
+		SessionID:      sessionKey,
 		UserMsg:        message,
 		ContentBlocks:  contentBlocks,
 		EnableThinking: sess.ShowThinking,  // Extended thinking based on session preference
@@ -540,6 +559,10 @@ func (c *HTTPChannel) convertEvent(event gateway.AgentEvent) *SSEEvent {
 			"finalText": e.FinalText,
 		}}
 
+	case gateway.EventAgentMerged:
+		// Reply arrives through the run the message was merged into
+		return &SSEEvent{Event: "merged", Data: e}
+
 	case gateway.EventAgentError:
 		return &SSEEvent{Event: "agent_error", Data: map[string]string{
 			"runId": e.RunID,
@@ -0,0 +1,350 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/session"
+	"github.com/roelfdiedericks/goclaw/internal/transcript"
+	"github.com/roelfdiedericks/goclaw/internal/user"
+)
+
+// webSessionInfix separates a user's default session key from the ID of a
+// session started in the web chat: "primary:web:1a2b3c4d",
+// "user:alice:web:1a2b3c4d"
+const webSessionInfix = ":web:"
+
+// chatHistoryLimit is the default number of messages returned by /api/chat/history
+const chatHistoryLimit = 200
+
+// SessionBrowserGateway extends SupervisionGateway with what the chat's
+// session browser needs. Implemented by the Gateway.
+type SessionBrowserGateway interface {
+	SupervisionGateway
+
+	// DeleteSession deletes a session with its messages and transcript index entries
+	DeleteSession(ctx context.Context, sessionKey string) error
+
+	// TranscriptManager returns the transcript index (nil if disabled)
+	TranscriptManager() *transcript.Manager
+}
+
+// ChatSessionRow is one session in the chat's session browser
+type ChatSessionRow struct {
+	Key          string    `json:"key"`
+	Title        string    `json:"title,omitempty"`
+	Messages     int       `json:"messages"`
+	TotalTokens  int       `json:"totalTokens"`
+	MaxTokens    int       `json:"maxTokens,omitempty"`
+	ContextUsage float64   `json:"contextUsage,omitempty"`
+	Compactions  int       `json:"compactions"`
+	Archived     bool      `json:"archived,omitempty"`
+	Live         bool      `json:"live"`    // Loaded in the gateway (can be supervised)
+	Running      bool      `json:"running"` // Agent run in progress
+	Supervised   bool      `json:"supervised,omitempty"`
+	Own          bool      `json:"own"`     // The user can chat in it
+	Default      bool      `json:"default"` // The user's default session
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// ChatHistoryMessage is one message returned by /api/chat/history
+type ChatHistoryMessage struct {
+	Role             string    `json:"role"`
+	Content          string    `json:"content"`
+	Source           string    `json:"source,omitempty"`
+	Supervisor       string    `json:"supervisor,omitempty"`
+	InterventionType string    `json:"interventionType,omitempty"`
+	Timestamp        time.Time `json:"timestamp"`
+}
+
+// defaultSessionKey returns the gateway session a user's chat goes to by default
+func defaultSessionKey(u *user.User) string {
+	if u.IsOwner() {
+		return session.PrimarySession
+	}
+	return "user:" + u.ID
+}
+
+// ownsSessionKey reports whether a user can chat in a session: their
+// default session and sessions they started under it
+func ownsSessionKey(u *user.User, key string) bool {
+	base := defaultSessionKey(u)
+	return key == base || strings.HasPrefix(key, base+webSessionInfix)
+}
+
+// canSeeSession reports whether a user can see a session in the browser.
+// The owner sees every session; other users see their own.
+func canSeeSession(u *user.User, key string) bool {
+	return u.IsOwner() || session.KeyUserID(key) == u.ID
+}
+
+func (s *Server) sessionBrowser() SessionBrowserGateway {
+	if s.channel == nil {
+		return nil
+	}
+	gw, _ := s.channel.gateway.(SessionBrowserGateway)
+	return gw
+}
+
+// handleChatSessions handles /api/chat/sessions:
+//
+//	GET  ?archived=1                         - list sessions (archived included)
+//	POST {"action": "new", "title": "..."}   - start a named session
+//	POST {"action": "rename|archive|unarchive|delete", "key": "...", "title": "..."}
+func (s *Server) handleChatSessions(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromContext(r)
+	if u == nil {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	gw := s.sessionBrowser()
+	if gw == nil {
+		http.Error(w, "Session browser not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		rows := s.listChatSessions(r.Context(), gw, u, r.URL.Query().Get("archived") == "1")
+		writeJSON(w, http.StatusOK, map[string]any{"sessions": rows, "default": defaultSessionKey(u)})
+		return
+	}
+
+	var req struct {
+		Action string `json:"action"`
+		Key    string `json:"key"`
+		Title  string `json:"title"`
+	}
+	if !decodeJSONPost(w, r, &req) {
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+
+	store := gw.SessionManager().GetStore()
+	if store == nil {
+		http.Error(w, "Session storage is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	if req.Action == "new" {
+		req.Key = defaultSessionKey(u) + webSessionInfix + uuid.New().String()[:8]
+		if req.Title == "" {
+			req.Title = "New session"
+		}
+	} else if req.Key == "" || !canSeeSession(u, req.Key) || (!u.IsOwner() && !ownsSessionKey(u, req.Key)) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	ctx := r.Context()
+	var err error
+	switch req.Action {
+	case "new", "rename":
+		err = store.SetSessionTitle(ctx, req.Key, req.Title)
+	case "archive", "unarchive":
+		err = store.SetSessionArchived(ctx, req.Key, req.Action == "archive")
+	case "delete":
+		err = gw.DeleteSession(ctx, req.Key)
+	default:
+		http.Error(w, "Unknown action: "+req.Action, http.StatusBadRequest)
+		return
+	}
+	recordWebAudit(r, u, "session."+req.Action, map[string]any{"session": req.Key, "title": req.Title}, err)
+	if err != nil {
+		logging.L_warn("http: session action failed", "action", req.Action, "session", req.Key, "user", u.ID, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logging.L_info("http: session action", "action", req.Action, "session", req.Key, "user", u.ID)
+
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "key": req.Key})
+}
+
+// listChatSessions merges stored sessions (titles, archive flags,
+// compactions) with the gateway's live ones (current token usage)
+func (s *Server) listChatSessions(ctx context.Context, gw SessionBrowserGateway, u *user.User, includeArchived bool) []ChatSessionRow {
+	byKey := make(map[string]*ChatSessionRow)
+	row := func(key string) *ChatSessionRow {
+		if r, ok := byKey[key]; ok {
+			return r
+		}
+		r := &ChatSessionRow{Key: key, Own: ownsSessionKey(u, key), Default: key == defaultSessionKey(u)}
+		byKey[key] = r
+		return r
+	}
+
+	mgr := gw.SessionManager()
+	if store := mgr.GetStore(); store != nil {
+		stored, err := store.ListSessions(ctx)
+		if err != nil {
+			logging.L_warn("http: failed to list stored sessions", "error", err)
+		}
+		for _, info := range stored {
+			if !canSeeSession(u, info.Key) {
+				continue
+			}
+			r := row(info.Key)
+			r.Title = info.Title
+			r.Archived = info.Archived
+			r.Messages = info.MessageCount
+			r.TotalTokens = info.TotalTokens
+			r.Compactions = info.CompactionCount
+			r.UpdatedAt = info.UpdatedAt
+		}
+	}
+
+	for _, info := range gw.Sessions() {
+		if !canSeeSession(u, info.Key) {
+			continue
+		}
+		r := row(info.Key)
+		r.Live = true
+		r.Messages = info.MessageCount
+		r.TotalTokens = info.TotalTokens
+		r.MaxTokens = info.MaxTokens
+		r.ContextUsage = info.ContextUsage
+		if info.Compactions > r.Compactions {
+			r.Compactions = info.Compactions
+		}
+		if t := parseTime(info.UpdatedAt); t.After(r.UpdatedAt) {
+			r.UpdatedAt = t
+		}
+		if sess := mgr.GetIfExists(info.Key); sess != nil {
+			r.Running = sess.IsRunning()
+			if supervision := sess.GetSupervision(); supervision != nil {
+				r.Supervised = supervision.IsSupervised()
+			}
+		}
+	}
+
+	// The default session is always listed, even before its first message
+	row(defaultSessionKey(u))
+
+	rows := make([]ChatSessionRow, 0, len(byKey))
+	for _, r := range byKey {
+		if r.Archived && !includeArchived {
+			continue
+		}
+		rows = append(rows, *r)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Default != rows[j].Default {
+			return rows[i].Default
+		}
+		return rows[i].UpdatedAt.After(rows[j].UpdatedAt)
+	})
+	return rows
+}
+
+// handleChatHistory handles GET /api/chat/history?session=<key>&limit=N -
+// the latest user and assistant messages of a session
+func (s *Server) handleChatHistory(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromContext(r)
+	if u == nil {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	gw := s.sessionBrowser()
+	if gw == nil {
+		http.Error(w, "Session browser not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	key := r.URL.Query().Get("session")
+	if key == "" {
+		key = defaultSessionKey(u)
+	}
+	if !canSeeSession(u, key) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	limit := chatHistoryLimit
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+		limit = n
+	}
+
+	var messages []ChatHistoryMessage
+	if store := gw.SessionManager().GetStore(); store != nil {
+		stored, err := store.GetMessages(r.Context(), key, session.MessageQueryOpts{RolesOnly: []string{"user", "assistant"}})
+		if err != nil {
+			logging.L_warn("http: failed to load session history", "session", key, "error", err)
+			http.Error(w, "Failed to load history", http.StatusInternalServerError)
+			return
+		}
+		for _, m := range stored {
+			messages = append(messages, ChatHistoryMessage{
+				Role:             m.Role,
+				Content:          m.Content,
+				Source:           m.Source,
+				Supervisor:       m.Supervisor,
+				InterventionType: m.InterventionType,
+				Timestamp:        m.Timestamp,
+			})
+		}
+	} else if history, err := gw.History(key); err == nil {
+		for _, m := range history {
+			if m.Role != "user" && m.Role != "assistant" {
+				continue
+			}
+			messages = append(messages, ChatHistoryMessage{Role: m.Role, Content: m.Content, Source: m.Source, Timestamp: m.Timestamp})
+		}
+	}
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"session": key, "messages": messages})
+}
+
+// handleChatSearch handles GET /api/chat/search?q=... - full-text search
+// across the sessions the user can see, using the transcript index
+func (s *Server) handleChatSearch(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromContext(r)
+	if u == nil {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	gw := s.sessionBrowser()
+	if gw == nil || gw.TranscriptManager() == nil {
+		http.Error(w, "Transcript search is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Missing q parameter", http.StatusBadRequest)
+		return
+	}
+
+	results, err := gw.TranscriptManager().Search(r.Context(), query, u.ID, u.IsOwner(), transcript.SearchOptions{
+		MaxResults:      20,
+		ExactBoost:      true,
+		ExactBoostQuery: query,
+	})
+	if err != nil {
+		logging.L_warn("http: session search failed", "user", u.ID, "error", err)
+		http.Error(w, "Search failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	visible := make([]transcript.SearchResult, 0, len(results))
+	for _, res := range results {
+		if canSeeSession(u, res.SessionKey) {
+			visible = append(visible, res)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"query": query, "results": visible})
+}
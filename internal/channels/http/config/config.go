@@ -3,8 +3,11 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
+	"os"
 	"strings"
 	"sync"
 
@@ -15,8 +18,69 @@ import (
 
 // Config holds configuration for the HTTP server.
 type Config struct {
-	Enabled *bool  `json:"enabled,omitempty"` // Enable HTTP server (default: true if users have passwords)
-	Listen  string `json:"listen"`            // Address to listen on (e.g., ":1337", "127.0.0.1:1337")
+	Enabled        *bool     `json:"enabled,omitempty"`        // Enable HTTP server (default: true if users have passwords)
+	Listen         string    `json:"listen"`                   // Address to listen on (e.g., ":1337", "127.0.0.1:1337")
+	TLS            TLSConfig `json:"tls"`                      // Native HTTPS (optional)
+	TrustedProxies []string  `json:"trustedProxies,omitempty"` // IPs/CIDRs allowed to set X-Forwarded-For (default: loopback)
+}
+
+// TLSConfig configures native HTTPS and client certificate (mTLS) auth.
+// Cert and key files are reloaded when they change on disk, so renewals
+// (e.g. certbot) don't need a restart.
+type TLSConfig struct {
+	Enabled      bool   `json:"enabled"`
+	CertFile     string `json:"certFile,omitempty"`     // PEM certificate chain (empty = self-signed)
+	KeyFile      string `json:"keyFile,omitempty"`      // PEM private key
+	ClientCAFile string `json:"clientCAFile,omitempty"` // PEM CA bundle for client certificates (enables mTLS)
+	ClientAuth   string `json:"clientAuth,omitempty"`   // "optional" (default) or "require" a client certificate
+}
+
+// Client certificate modes
+const (
+	ClientAuthOptional = "optional" // Verify a certificate if one is presented; passwords and tokens still work
+	ClientAuthRequire  = "require"  // Refuse connections without a valid client certificate
+)
+
+// Check validates the TLS settings and that the files load
+func (c *TLSConfig) Check() error {
+	if !c.Enabled {
+		return nil
+	}
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("certFile and keyFile must both be set (or both empty for a self-signed certificate)")
+	}
+	if c.CertFile != "" {
+		if _, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile); err != nil {
+			return fmt.Errorf("failed to load certificate: %w", err)
+		}
+	}
+	switch c.ClientAuth {
+	case "", ClientAuthOptional, ClientAuthRequire:
+	default:
+		return fmt.Errorf("invalid clientAuth %q (must be %q or %q)", c.ClientAuth, ClientAuthOptional, ClientAuthRequire)
+	}
+	if c.ClientAuth == ClientAuthRequire && c.ClientCAFile == "" {
+		return fmt.Errorf("clientAuth %q requires clientCAFile", ClientAuthRequire)
+	}
+	if c.ClientCAFile != "" {
+		if _, err := LoadCertPool(c.ClientCAFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadCertPool loads a PEM CA bundle
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in client CA file %s", path)
+	}
+	return pool, nil
 }
 
 const configPath = "channels.http"
@@ -50,6 +114,29 @@ func ConfigFormDef() forms.FormDef {
 				Fields: []forms.Field{
 					{Name: "Enabled", Title: "Enabled", Type: forms.Toggle, Default: true, Desc: "Enable HTTP server"},
 					{Name: "Listen", Title: "Listen Address", Type: forms.Text, Default: ":1337", Desc: "Address to listen on (e.g., :1337 or 127.0.0.1:1337)"},
+					{Name: "TrustedProxies", Title: "Trusted Proxies", Type: forms.StringList, Desc: "Reverse proxy IPs/CIDRs whose X-Forwarded-For is believed (empty = loopback only)"},
+				},
+			},
+			{
+				Title:     "TLS",
+				Desc:      "Serve HTTPS directly (restart required)",
+				Collapsed: true,
+				FieldName: "TLS",
+				Nested: &forms.FormDef{
+					Sections: []forms.Section{
+						{
+							Fields: []forms.Field{
+								{Name: "Enabled", Title: "Enabled", Type: forms.Toggle, Desc: "Serve HTTPS instead of HTTP"},
+								{Name: "CertFile", Title: "Certificate File", Type: forms.Text, Desc: "PEM certificate chain (empty = generate a self-signed certificate)"},
+								{Name: "KeyFile", Title: "Key File", Type: forms.Text, Desc: "PEM private key"},
+								{Name: "ClientCAFile", Title: "Client CA File", Type: forms.Text, Desc: "CA bundle for client certificates (enables mTLS login)"},
+								{Name: "ClientAuth", Title: "Client Certificates", Type: forms.Select, Default: ClientAuthOptional, Desc: "Whether a client certificate is required", Options: []forms.Option{
+									{Label: "Optional (passwords and tokens still work)", Value: ClientAuthOptional},
+									{Label: "Required", Value: ClientAuthRequire},
+								}},
+							},
+						},
+					},
 				},
 			},
 		},
@@ -113,6 +200,21 @@ func handleTest(cmd bus.Command) bus.CommandResult {
 		}
 	}
 
+	if err := cfg.TLS.Check(); err != nil {
+		return bus.CommandResult{
+			Success: false,
+			Message: fmt.Sprintf("TLS: %v", err),
+		}
+	}
+	for _, p := range cfg.TrustedProxies {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			return bus.CommandResult{
+				Success: false,
+				Message: fmt.Sprintf("Invalid trusted proxy %q (must be an IP or CIDR)", p),
+			}
+		}
+	}
+
 	// Check if we're already bound to this address (skip port availability check)
 	currentAddr := GetCurrentListenAddr()
 	if currentAddr != "" && normalizeAddr(currentAddr) == normalizeAddr(listen) {
@@ -156,7 +258,7 @@ func handleApply(cmd bus.Command) bus.CommandResult {
 	}
 
 	enabled := cfg.Enabled == nil || *cfg.Enabled
-	logging.L_info("http: config applied", "enabled", enabled, "listen", cfg.Listen)
+	logging.L_info("http: config applied", "enabled", enabled, "listen", cfg.Listen, "tls", cfg.TLS.Enabled)
 	bus.PublishEvent(configPath+".config.applied", cfg)
 
 	return bus.CommandResult{Success: true, Message: "Config applied"}
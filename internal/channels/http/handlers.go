@@ -3,6 +3,7 @@ package http
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -17,6 +18,7 @@ import (
 	"github.com/roelfdiedericks/goclaw/internal/media"
 	"github.com/roelfdiedericks/goclaw/internal/metrics"
 	"github.com/roelfdiedericks/goclaw/internal/types"
+	"github.com/roelfdiedericks/goclaw/internal/user"
 )
 
 // handleIndex serves the dashboard page
@@ -97,6 +99,12 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 		superviseSession = ""
 	}
 
+	// Session from the session browser ("" = the user's default session)
+	chatSession := r.URL.Query().Get("session")
+	if chatSession == defaultSessionKey(u) || !canSeeSession(u, chatSession) {
+		chatSession = ""
+	}
+
 	data := struct {
 		Title            string
 		User             *UserTemplateData
@@ -105,6 +113,9 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 		Timestamp        time.Time
 		SuperviseSession string
 		IsSupervising    bool
+		ChatSession      string
+		ReadOnly         bool // Viewing another user's session
+		CanBrowse        bool // Session browser available
 	}{
 		Title:            "GoClaw - Chat",
 		User:             &UserTemplateData{Name: u.Name, Username: u.ID, Role: string(u.Role), IsOwner: u.IsOwner()},
@@ -113,6 +124,9 @@ func (s *Server) handleChat(w http.ResponseWriter, r *http.Request) {
 		Timestamp:        time.Now(),
 		SuperviseSession: superviseSession,
 		IsSupervising:    isSupervising,
+		ChatSession:      chatSession,
+		ReadOnly:         chatSession != "" && !ownsSessionKey(u, chatSession),
+		CanBrowse:        s.sessionBrowser() != nil,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -130,6 +144,26 @@ type UserTemplateData struct {
 	IsOwner  bool
 }
 
+// chatImage is an image attached to a chat message
+type chatImage struct {
+	Data     string `json:"data"`     // Base64-encoded image data
+	MimeType string `json:"mimeType"` // MIME type (e.g., "image/png")
+}
+
+// sendResult is the reply to a chat message, from /api/send or a WebSocket ack
+type sendResult struct {
+	ID      string `json:"id,omitempty"`
+	Status  string `json:"status"`
+	Command string `json:"command,omitempty"`
+	Message string `json:"message"`
+	Error   string `json:"error,omitempty"`
+}
+
+var (
+	errEmptyMessage   = errors.New("message or image required")
+	errNotYourSession = errors.New("forbidden - not your session")
+)
+
 // handleSend handles POST /api/send - send message to agent
 func (s *Server) handleSend(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -147,11 +181,12 @@ func (s *Server) handleSend(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var req struct {
-		Message string `json:"message"`
-		Images  []struct {
-			Data     string `json:"data"`     // Base64-encoded image data
-			MimeType string `json:"mimeType"` // MIME type (e.g., "image/png")
-		} `json:"images"`
+		Message string      `json:"message"`
+		Session string      `json:"session"` // Gateway session key ("" = the user's default)
+		Images  []chatImage `json:"images"`
+	}
+	if !requireJSON(w, r) {
+		return
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logging.L_warn("http: send - invalid JSON", "user", u.ID, "error", err)
@@ -159,13 +194,6 @@ func (s *Server) handleSend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Need either message or images
-	if req.Message == "" && len(req.Images) == 0 {
-		logging.L_warn("http: send - empty message and no images", "user", u.ID)
-		http.Error(w, "Message or image required", http.StatusBadRequest)
-		return
-	}
-
 	sessionID := getSessionFromContext(r)
 	if sessionID == "" {
 		logging.L_error("http: send failed - no session in context", "user", u.ID)
@@ -173,9 +201,48 @@ func (s *Server) handleSend(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	resp, err := s.sendChatMessage(r.Context(), sessionID, u, req.Session, req.Message, req.Images)
+	switch {
+	case errors.Is(err, errEmptyMessage):
+		http.Error(w, "Message or image required", http.StatusBadRequest)
+		return
+	case errors.Is(err, errNotYourSession):
+		http.Error(w, "Forbidden - not your session", http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, fmt.Sprintf("Failed to process: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logging.L_warn("http: failed to encode response", "error", err)
+	}
+}
+
+// sendChatMessage handles a chat message from /api/send or the WebSocket:
+// panic phrases, /thinking, built-in commands, or an agent run whose
+// events stream to the channel session. sessionKey selects a gateway
+// session ("" = the user's default).
+func (s *Server) sendChatMessage(ctx context.Context, sessionID string, u *user.User, sessionKey, message string, images []chatImage) (*sendResult, error) {
+	// Need either message or images
+	if message == "" && len(images) == 0 {
+		logging.L_warn("http: send - empty message and no images", "user", u.ID)
+		return nil, errEmptyMessage
+	}
+
+	// Users can only chat in their own sessions (others are supervised)
+	if sessionKey == defaultSessionKey(u) {
+		sessionKey = ""
+	}
+	if sessionKey != "" && !ownsSessionKey(u, sessionKey) {
+		logging.L_warn("http: send denied - not the user's session", "user", u.ID, "session", sessionKey)
+		return nil, errNotYourSession
+	}
+
 	// Convert images to ContentBlocks
 	var contentBlocks []types.ContentBlock
-	for _, img := range req.Images {
+	for _, img := range images {
 		contentBlocks = append(contentBlocks, types.ContentBlock{
 			Type:     "image",
 			Data:     img.Data,
@@ -184,68 +251,53 @@ func (s *Server) handleSend(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	logging.L_info("http: message received", "user", u.ID, "session", sessionID[:8]+"...", "length", len(req.Message), "images", len(contentBlocks))
+	logging.L_info("http: message received", "user", u.ID, "session", sessionID[:8]+"...", "length", len(message), "images", len(contentBlocks))
 
 	// Check for panic phrase (emergency stop) before anything else
 	// Always attempt cancel and confirm - avoids race conditions where session just finished
-	if commands.IsPanicPhrase(req.Message) {
+	if commands.IsPanicPhrase(message) {
 		if s.channel.gateway != nil {
 			s.channel.gateway.StopAllUserSessions(u.ID)
 		}
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		json.NewEncoder(w).Encode(map[string]string{"status": "ok", "message": "Stopping all tasks."}) //nolint:errcheck
-		return
+		return &sendResult{Status: "ok", Message: "Stopping all tasks."}, nil
 	}
 
 	// Handle /thinking command locally (channel-specific preference)
-	if strings.HasPrefix(strings.TrimSpace(req.Message), "/thinking") {
-		s.handleThinkingCommand(w, sessionID, req.Message)
-		return
+	if strings.HasPrefix(strings.TrimSpace(message), "/thinking") {
+		return s.handleThinkingCommand(sessionID, message)
 	}
 
 	// Handle built-in commands (/status, /compact, /clear, /help, etc.)
-	trimmedMsg := strings.TrimSpace(req.Message)
+	trimmedMsg := strings.TrimSpace(message)
 	if strings.HasPrefix(trimmedMsg, "/") {
 		cmdMgr := commands.GetManager()
 		// Parse command name (first word)
 		cmdName := strings.Fields(trimmedMsg)[0]
 		if cmd := cmdMgr.Get(cmdName); cmd != nil {
-			s.handleBuiltinCommand(w, r.Context(), sessionID, u.ID, trimmedMsg, cmd)
-			return
+			return s.handleBuiltinCommand(ctx, sessionID, sessionKey, u.ID, trimmedMsg, cmd)
 		}
 	}
 
 	// Run agent request (will stream via SSE)
 	msgID := fmt.Sprintf("msg_%d", time.Now().UnixNano())
-	err := s.channel.RunAgentRequest(r.Context(), sessionID, u, req.Message, contentBlocks)
+	err := s.channel.RunAgentRequest(ctx, sessionID, sessionKey, u, message, contentBlocks)
 	if err != nil {
 		logging.L_error("http: failed to run agent", "user", u.ID, "error", err)
-		http.Error(w, fmt.Sprintf("Failed to process: %v", err), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	resp := struct {
-		ID      string `json:"id"`
-		Status  string `json:"status"`
-		Message string `json:"message"`
-	}{
+	return &sendResult{
 		ID:      msgID,
 		Status:  "processing",
 		Message: "Message sent to agent",
-	}
-
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		logging.L_warn("http: failed to encode response", "error", err)
-	}
+	}, nil
 }
 
 // handleThinkingCommand handles the /thinking command for toggling tool visibility
-func (s *Server) handleThinkingCommand(w http.ResponseWriter, sessionID string, message string) {
+func (s *Server) handleThinkingCommand(sessionID string, message string) (*sendResult, error) {
 	sess := s.channel.GetSession(sessionID)
 	if sess == nil {
-		http.Error(w, "Session not found", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("session not found")
 	}
 
 	// Parse subcommand
@@ -321,22 +373,15 @@ func (s *Server) handleThinkingCommand(w http.ResponseWriter, sessionID string,
 		},
 	})
 
-	// Respond to HTTP request
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	if err := json.NewEncoder(w).Encode(map[string]string{
-		"status":  "ok",
-		"message": resultMsg,
-	}); err != nil {
-		logging.L_warn("http: failed to encode response", "error", err)
-	}
+	return &sendResult{Status: "ok", Message: resultMsg}, nil
 }
 
-// handleBuiltinCommand handles built-in slash commands (/status, /compact, /clear, etc.)
-func (s *Server) handleBuiltinCommand(w http.ResponseWriter, ctx context.Context, sessionID string, userID string, message string, cmd *commands.Command) {
+// handleBuiltinCommand handles built-in slash commands (/status, /compact, /clear, etc.).
+// sessionKey is the gateway session the chat is in ("" = the user's default).
+func (s *Server) handleBuiltinCommand(ctx context.Context, sessionID, sessionKey string, userID string, message string, cmd *commands.Command) (*sendResult, error) {
 	sess := s.channel.GetSession(sessionID)
 	if sess == nil {
-		http.Error(w, "Session not found", http.StatusInternalServerError)
-		return
+		return nil, fmt.Errorf("session not found")
 	}
 
 	logging.L_info("http: handling command", "command", cmd.Name, "session", sessionID[:8]+"...")
@@ -353,7 +398,11 @@ func (s *Server) handleBuiltinCommand(w http.ResponseWriter, ctx context.Context
 
 	// Execute command via manager (which has the provider wired up)
 	mgr := commands.GetManager()
-	result := mgr.Execute(ctx, message, sessionID, userID)
+	cmdSession := sessionID
+	if sessionKey != "" {
+		cmdSession = sessionKey
+	}
+	result := mgr.Execute(ctx, message, cmdSession, userID)
 
 	// Determine message to show
 	responseText := result.Text
@@ -369,20 +418,16 @@ func (s *Server) handleBuiltinCommand(w http.ResponseWriter, ctx context.Context
 		},
 	})
 
-	// Respond to HTTP request
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	resp := map[string]interface{}{
-		"status":  "ok",
-		"command": cmd.Name,
-		"message": responseText,
+	resp := &sendResult{
+		Status:  "ok",
+		Command: cmd.Name,
+		Message: responseText,
 	}
 	if result.Error != nil {
-		resp["status"] = "error"
-		resp["error"] = result.Error.Error()
-	}
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		logging.L_warn("http: failed to encode response", "error", err)
+		resp.Status = "error"
+		resp.Error = result.Error.Error()
 	}
+	return resp, nil
 }
 
 // handleEvents handles GET /api/events - SSE stream
@@ -489,11 +534,12 @@ func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
 				logging.L_error("http: failed to marshal event", "error", err)
 				continue
 			}
-			// Get current event ID from session
-			sess.bufferMu.Lock()
-			eventID := sess.nextEventID - 1 // Last assigned ID
-			sess.bufferMu.Unlock()
-			fmt.Fprintf(w, "event: %s\nid: %d\ndata: %s\n\n", event.Event, eventID, data)
+			if event.ID == 0 {
+				// Transient event - no ID, so it isn't replayed
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Event, data)
+			} else {
+				fmt.Fprintf(w, "event: %s\nid: %d\ndata: %s\n\n", event.Event, event.ID, data)
+			}
 			flusher.Flush()
 		case <-ticker.C:
 			// Send heartbeat comment (doesn't need ID)
@@ -0,0 +1,169 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/history"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// historyPageLimit caps the number of changes listed on the history page
+const historyPageLimit = 200
+
+// HistoryRow is one change on the history page
+type HistoryRow struct {
+	ID         int64
+	Time       time.Time
+	Path       string
+	Kind       string // created, modified, deleted
+	Tool       string
+	User       string
+	SessionKey string
+	RunID      string
+	Reverts    int64
+	Reverted   bool
+}
+
+// handleHistory handles GET /history - workspace change history (owner only).
+// ?id=N shows the diff for one change.
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if err := s.reloadTemplatesIfDev(); err != nil {
+		logging.L_error("http: template reload error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+
+	u := getUserFromContext(r)
+	if u == nil {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	if !u.IsOwner() {
+		http.Error(w, "Forbidden - owner only", http.StatusForbidden)
+		return
+	}
+
+	data := struct {
+		Title    string
+		User     *UserTemplateData
+		Enabled  bool
+		Rows     []HistoryRow
+		Selected *HistoryRow
+		Diff     string
+		Error    string
+	}{
+		Title: "GoClaw - Workspace History",
+		User:  &UserTemplateData{Name: u.Name, Username: u.ID, Role: string(u.Role), IsOwner: u.IsOwner()},
+	}
+
+	store := history.Get()
+	if store != nil {
+		data.Enabled = true
+		filter := history.Filter{Path: r.URL.Query().Get("path"), SessionKey: r.URL.Query().Get("session"), Limit: historyPageLimit}
+		for _, e := range store.List(filter) {
+			data.Rows = append(data.Rows, historyRow(store, e))
+		}
+
+		if idStr := r.URL.Query().Get("id"); idStr != "" {
+			id, _ := strconv.ParseInt(idStr, 10, 64)
+			if e, ok := store.Get(id); ok {
+				row := historyRow(store, e)
+				data.Selected = &row
+				before, errB := store.Object(e.Before)
+				after, errA := store.Object(e.After)
+				if errB != nil || errA != nil {
+					data.Error = "Stored content is missing"
+				} else {
+					data.Diff = history.UnifiedDiff("before", "after", before, after)
+				}
+			} else {
+				data.Error = "No change with that ID"
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates.ExecuteTemplate(w, "history.html", data); err != nil {
+		logging.L_error("http: template error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+	}
+}
+
+// handleHistoryRestore handles POST /api/history/restore - write a stored
+// version of a file back to disk (owner only)
+func (s *Server) handleHistoryRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	u := getUserFromContext(r)
+	if u == nil {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	if !u.IsOwner() {
+		logging.L_warn("http: history restore denied - not owner", "user", u.ID)
+		http.Error(w, "Forbidden - owner only", http.StatusForbidden)
+		return
+	}
+
+	store := history.Get()
+	if store == nil {
+		http.Error(w, "Workspace history is disabled", http.StatusNotFound)
+		return
+	}
+
+	var req struct {
+		ID      int64  `json:"id"`
+		Version string `json:"version"` // "before" (undo) or "after"
+	}
+	if !requireJSON(w, r) {
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	recorded, err := store.Restore(req.ID, req.Version, history.Entry{Tool: "web", User: u.ID})
+	if err != nil {
+		logging.L_warn("http: history restore failed", "id", req.ID, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logging.L_info("http: history restored", "id", req.ID, "version", req.Version, "user", u.ID)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "restored",
+		"change": recorded.ID,
+	}); err != nil {
+		logging.L_warn("http: failed to encode response", "error", err)
+	}
+}
+
+func historyRow(store *history.Store, e history.Entry) HistoryRow {
+	kind := "modified"
+	switch {
+	case e.Before == "":
+		kind = "created"
+	case e.After == "":
+		kind = "deleted"
+	}
+	return HistoryRow{
+		ID:         e.ID,
+		Time:       e.Time,
+		Path:       e.Path,
+		Kind:       kind,
+		Tool:       e.Tool,
+		User:       e.User,
+		SessionKey: e.SessionKey,
+		RunID:      e.RunID,
+		Reverts:    e.Reverts,
+		Reverted:   store.IsReverted(e.ID),
+	}
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/roelfdiedericks/goclaw/internal/audit"
+	"github.com/roelfdiedericks/goclaw/internal/hass"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/skills"
+	"github.com/roelfdiedericks/goclaw/internal/user"
+)
+
+// ManagementGateway extends GatewayRunner with the managers behind the owner
+// management pages. This interface is implemented by the Gateway.
+type ManagementGateway interface {
+	GatewayRunner
+
+	// HassManager returns the Home Assistant subscription manager (nil if not configured)
+	HassManager() *hass.Manager
+
+	// SkillManager returns the skill manager (nil if skills are disabled)
+	SkillManager() *skills.Manager
+}
+
+// managementGateway returns the gateway's management interface, or nil
+func (s *Server) managementGateway() ManagementGateway {
+	gw, ok := s.channel.gateway.(ManagementGateway)
+	if !ok {
+		return nil
+	}
+	return gw
+}
+
+// requireOwner returns the authenticated user if they are the owner.
+// Otherwise it writes 401/403 and returns nil.
+func requireOwner(w http.ResponseWriter, r *http.Request) *user.User {
+	u := getUserFromContext(r)
+	if u == nil {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return nil
+	}
+	if !u.IsOwner() {
+		logging.L_warn("http: management denied - not owner", "user", u.ID, "path", r.URL.Path)
+		http.Error(w, "Forbidden - owner only", http.StatusForbidden)
+		return nil
+	}
+	return u
+}
+
+// ownerTemplateData returns the header data for an owner page
+func ownerTemplateData(u *user.User) *UserTemplateData {
+	return &UserTemplateData{Name: u.Name, Username: u.ID, Role: string(u.Role), IsOwner: u.IsOwner()}
+}
+
+// decodeJSONPost decodes a POST body into v. The body must be sent as
+// application/json. On failure it writes the error response and returns false.
+func decodeJSONPost(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if !requireJSON(w, r) {
+		return false
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// recordWebAudit appends an audit event for a change made from the web UI
+func recordWebAudit(r *http.Request, u *user.User, eventType string, inputs map[string]any, err error) {
+	e := audit.Event{
+		Type:    eventType,
+		Actor:   u.ID,
+		Purpose: "web " + r.URL.Path,
+		Inputs:  audit.Redact(inputs),
+	}
+	if t := getAPITokenFromContext(r); t != nil {
+		e.Purpose += " (token " + t.Name + ")"
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	audit.Record(r.Context(), e)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.L_warn("http: failed to encode response", "error", err)
+	}
+}
@@ -0,0 +1,469 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/cron"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// cronRunHistoryLimit is how many runs the job detail view shows
+const cronRunHistoryLimit = 50
+
+// CronJobRow is one job in the cron jobs table
+type CronJobRow struct {
+	ID         string
+	Name       string
+	Enabled    bool
+	Schedule   string
+	Target     string
+	NextRun    *time.Time
+	LastRun    *time.Time
+	LastStatus string
+	LastError  string
+	Running    bool
+}
+
+// CronRunRow is one entry of a job's run history
+type CronRunRow struct {
+	Time     time.Time
+	Status   string
+	Duration time.Duration
+	Summary  string
+	Error    string
+}
+
+// cronJobForm is the editable form of a job, as sent to and from the page.
+// Durations use cron.ParseDuration syntax ("30m", "2h", "1d").
+type cronJobForm struct {
+	ID          string `json:"id"` // empty = create
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	AgentID     string `json:"agentId"`
+
+	ScheduleType string `json:"scheduleType"` // at, every, cron, hass, job, webhook, file
+	At           string `json:"at"`           // RFC3339, unix ms or relative ("+30m")
+	Every        string `json:"every"`
+	CronExpr     string `json:"cronExpr"`
+	Timezone     string `json:"timezone"`
+	Entity       string `json:"entity"`
+	EntityRegex  string `json:"entityRegex"`
+	State        string `json:"state"`
+	AfterJob     string `json:"afterJob"`
+	On           string `json:"on"`
+	Path         string `json:"path"`
+
+	SessionTarget string `json:"sessionTarget"`
+	Message       string `json:"message"`
+	Deliver       bool   `json:"deliver"`
+	Timeout       string `json:"timeout"`
+
+	MaxRetries   int    `json:"maxRetries"`
+	RetryBackoff string `json:"retryBackoff"`
+	Misfire      string `json:"misfire"`
+	Concurrency  string `json:"concurrency"`
+	Jitter       string `json:"jitter"`
+
+	WebhookSecret string `json:"webhookSecret,omitempty"` // read-only, shown for webhook jobs
+}
+
+// handleCron handles GET /cron - cron job list and run history (owner only).
+// ?id=JOB shows that job's run history.
+func (s *Server) handleCron(w http.ResponseWriter, r *http.Request) {
+	if err := s.reloadTemplatesIfDev(); err != nil {
+		logging.L_error("http: template reload error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+
+	u := requireOwner(w, r)
+	if u == nil {
+		return
+	}
+
+	data := struct {
+		Title    string
+		User     *UserTemplateData
+		Running  bool
+		Jobs     []CronJobRow
+		Selected *CronJobRow
+		Runs     []CronRunRow
+		Error    string
+	}{
+		Title: "GoClaw - Cron Jobs",
+		User:  ownerTemplateData(u),
+	}
+
+	service := cron.GetService()
+	if service == nil {
+		data.Error = "The cron service is not running (cron.enabled is off)."
+	} else {
+		data.Running = service.IsRunning()
+		jobs := service.Store().GetAllJobs()
+		sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+		for _, job := range jobs {
+			data.Jobs = append(data.Jobs, cronJobRow(job))
+		}
+
+		if id := r.URL.Query().Get("id"); id != "" {
+			job := service.Store().GetJob(id)
+			if job == nil {
+				data.Error = "Job not found: " + id
+			} else {
+				row := cronJobRow(job)
+				data.Selected = &row
+				runs, err := service.History().GetRuns(id, cronRunHistoryLimit)
+				if err != nil {
+					data.Error = "Failed to read run history: " + err.Error()
+				}
+				// Newest first
+				for i := len(runs) - 1; i >= 0; i-- {
+					e := runs[i]
+					data.Runs = append(data.Runs, CronRunRow{
+						Time:     time.UnixMilli(e.Ts),
+						Status:   e.Status,
+						Duration: time.Duration(e.DurationMs) * time.Millisecond,
+						Summary:  e.Summary,
+						Error:    e.Error,
+					})
+				}
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates.ExecuteTemplate(w, "cron.html", data); err != nil {
+		logging.L_error("http: template error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+	}
+}
+
+// handleCronJobsAPI handles /api/cron/jobs (owner only).
+//
+// GET lists jobs, or returns one job's edit form with ?id=.
+// POST creates a job (empty id) or replaces an existing job's settings.
+func (s *Server) handleCronJobsAPI(w http.ResponseWriter, r *http.Request) {
+	u := requireOwner(w, r)
+	if u == nil {
+		return
+	}
+
+	service := cron.GetService()
+	if service == nil {
+		http.Error(w, "Cron service not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		if id := r.URL.Query().Get("id"); id != "" {
+			job := service.Store().GetJob(id)
+			if job == nil {
+				http.Error(w, "Job not found", http.StatusNotFound)
+				return
+			}
+			writeJSON(w, http.StatusOK, cronFormFromJob(job))
+			return
+		}
+		writeJSON(w, http.StatusOK, service.Store().GetAllJobs())
+		return
+	}
+
+	var form cronJobForm
+	if !decodeJSONPost(w, r, &form) {
+		return
+	}
+
+	var job *cron.CronJob
+	action := "add"
+	if form.ID == "" {
+		job = &cron.CronJob{Payload: cron.Payload{Kind: cron.PayloadKindAgentTurn}}
+	} else {
+		stored := service.Store().GetJob(form.ID)
+		if stored == nil {
+			http.Error(w, "Job not found", http.StatusNotFound)
+			return
+		}
+		// Edit a copy so a rejected edit leaves the stored job untouched
+		edited := *stored
+		job = &edited
+		action = "edit"
+	}
+
+	err := form.applyTo(job)
+	if err == nil {
+		if action == "add" {
+			err = service.AddJob(job)
+		} else {
+			err = service.UpdateJob(job)
+		}
+	}
+	recordWebAudit(r, u, "cron."+action, cronAuditInputs(job), err)
+	if err != nil {
+		logging.L_warn("http: cron job save failed", "action", action, "id", job.ID, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logging.L_info("http: cron job saved", "action", action, "id", job.ID, "name", job.Name, "user", u.ID)
+
+	writeJSON(w, http.StatusOK, map[string]any{"status": "saved", "id": job.ID})
+}
+
+// handleCronActionAPI handles POST /api/cron/action - enable, disable, run or
+// remove a job (owner only)
+func (s *Server) handleCronActionAPI(w http.ResponseWriter, r *http.Request) {
+	u := requireOwner(w, r)
+	if u == nil {
+		return
+	}
+
+	var req struct {
+		ID     string `json:"id"`
+		Action string `json:"action"` // enable, disable, run, remove
+	}
+	if !decodeJSONPost(w, r, &req) {
+		return
+	}
+
+	service := cron.GetService()
+	if service == nil {
+		http.Error(w, "Cron service not running", http.StatusServiceUnavailable)
+		return
+	}
+	stored := service.Store().GetJob(req.ID)
+	if stored == nil {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	job := *stored
+
+	var err error
+	switch req.Action {
+	case "enable", "disable":
+		job.Enabled = req.Action == "enable"
+		err = service.UpdateJob(&job)
+	case "run":
+		// The run outlives the request
+		err = service.RunNow(context.Background(), job.ID)
+	case "remove":
+		err = service.RemoveJob(job.ID)
+	default:
+		http.Error(w, "Unknown action: "+req.Action, http.StatusBadRequest)
+		return
+	}
+	recordWebAudit(r, u, "cron."+req.Action, cronAuditInputs(&job), err)
+	if err != nil {
+		logging.L_warn("http: cron action failed", "action", req.Action, "id", job.ID, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logging.L_info("http: cron action", "action", req.Action, "id", job.ID, "name", job.Name, "user", u.ID)
+
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "id": job.ID})
+}
+
+func cronJobRow(job *cron.CronJob) CronJobRow {
+	row := CronJobRow{
+		ID:         job.ID,
+		Name:       job.Name,
+		Enabled:    job.Enabled,
+		Schedule:   describeCronSchedule(&job.Schedule),
+		Target:     job.SessionTarget,
+		LastStatus: job.State.LastStatus,
+		LastError:  job.State.LastError,
+		Running:    job.State.RunningAtMs != nil,
+	}
+	if job.State.NextRunAtMs != nil {
+		t := time.UnixMilli(*job.State.NextRunAtMs)
+		row.NextRun = &t
+	}
+	if job.State.LastRunAtMs != nil {
+		t := time.UnixMilli(*job.State.LastRunAtMs)
+		row.LastRun = &t
+	}
+	if job.AgentID != "" {
+		row.Target += " (" + job.AgentID + ")"
+	}
+	return row
+}
+
+func describeCronSchedule(s *cron.Schedule) string {
+	switch s.Kind {
+	case cron.ScheduleKindAt:
+		return "at " + time.UnixMilli(s.AtMs).Format("2006-01-02 15:04")
+	case cron.ScheduleKindEvery:
+		return "every " + formatWebDuration(time.Duration(s.EveryMs)*time.Millisecond)
+	case cron.ScheduleKindCron:
+		if s.Tz != "" {
+			return fmt.Sprintf("cron %s (%s)", s.Expr, s.Tz)
+		}
+		return "cron " + s.Expr
+	default:
+		return cron.FormatTrigger(s)
+	}
+}
+
+// formatWebDuration formats a duration without trailing zero units ("1h", not "1h0m0s")
+func formatWebDuration(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+func cronAuditInputs(job *cron.CronJob) map[string]any {
+	return map[string]any{
+		"id":       job.ID,
+		"name":     job.Name,
+		"enabled":  job.Enabled,
+		"schedule": describeCronSchedule(&job.Schedule),
+		"message":  job.Payload.Message,
+	}
+}
+
+func cronFormFromJob(job *cron.CronJob) cronJobForm {
+	s := job.Schedule
+	f := cronJobForm{
+		ID:            job.ID,
+		Name:          job.Name,
+		Description:   job.Description,
+		Enabled:       job.Enabled,
+		AgentID:       job.AgentID,
+		ScheduleType:  s.Kind,
+		CronExpr:      s.Expr,
+		Timezone:      s.Tz,
+		Entity:        s.Entity,
+		EntityRegex:   s.EntityRegex,
+		State:         s.State,
+		AfterJob:      s.AfterJob,
+		On:            s.On,
+		Path:          s.Path,
+		SessionTarget: job.SessionTarget,
+		Message:       job.Payload.GetPrompt(),
+		Deliver:       job.Payload.Deliver,
+		Timeout:       formatWebDuration(time.Duration(job.Payload.TimeoutSeconds) * time.Second),
+		Misfire:       job.Misfire,
+		Concurrency:   job.Concurrency,
+		Jitter:        formatWebDuration(time.Duration(job.JitterSeconds) * time.Second),
+		WebhookSecret: s.Secret,
+	}
+	if s.Kind == cron.ScheduleKindAt {
+		f.At = time.UnixMilli(s.AtMs).Format(time.RFC3339)
+	}
+	if s.Kind == cron.ScheduleKindEvery {
+		f.Every = formatWebDuration(time.Duration(s.EveryMs) * time.Millisecond)
+	}
+	if job.Retry != nil {
+		f.MaxRetries = job.Retry.MaxRetries
+		f.RetryBackoff = formatWebDuration(time.Duration(job.Retry.BackoffSeconds) * time.Second)
+	}
+	return f
+}
+
+// applyTo sets every editable field of job from the form. The schedule's
+// webhook secret is kept when the trigger kind doesn't change.
+func (f cronJobForm) applyTo(job *cron.CronJob) error {
+	if strings.TrimSpace(f.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if strings.TrimSpace(f.Message) == "" {
+		return fmt.Errorf("message is required")
+	}
+
+	schedule, err := f.schedule()
+	if err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	if schedule.Kind == job.Schedule.Kind {
+		schedule.Secret = job.Schedule.Secret
+	}
+
+	job.Name = strings.TrimSpace(f.Name)
+	job.Description = f.Description
+	job.Enabled = f.Enabled
+	job.AgentID = strings.TrimSpace(f.AgentID)
+	job.Schedule = schedule
+	job.SessionTarget = cron.SessionTargetMain
+	if f.SessionTarget == cron.SessionTargetIsolated {
+		job.SessionTarget = cron.SessionTargetIsolated
+	}
+	job.Payload.Message = f.Message
+	job.Payload.Text = ""
+	job.Payload.Deliver = f.Deliver
+	job.Misfire = f.Misfire
+	job.Concurrency = f.Concurrency
+
+	if job.Payload.TimeoutSeconds, err = formSeconds(f.Timeout); err != nil {
+		return fmt.Errorf("invalid timeout: %w", err)
+	}
+	if job.JitterSeconds, err = formSeconds(f.Jitter); err != nil {
+		return fmt.Errorf("invalid jitter: %w", err)
+	}
+
+	job.Retry = nil
+	if f.MaxRetries > 0 {
+		backoff, err := formSeconds(f.RetryBackoff)
+		if err != nil {
+			return fmt.Errorf("invalid retry backoff: %w", err)
+		}
+		job.Retry = &cron.RetryPolicy{MaxRetries: f.MaxRetries, BackoffSeconds: backoff}
+	}
+	return nil
+}
+
+// schedule builds the job schedule from the form's trigger fields
+func (f cronJobForm) schedule() (cron.Schedule, error) {
+	switch f.ScheduleType {
+	case cron.ScheduleKindAt:
+		t, err := cron.ParseAt(f.At, time.Now())
+		if err != nil {
+			return cron.Schedule{}, err
+		}
+		return cron.Schedule{Kind: cron.ScheduleKindAt, AtMs: t.UnixMilli()}, nil
+	case cron.ScheduleKindEvery:
+		d, err := cron.ParseDuration(f.Every)
+		if err != nil {
+			return cron.Schedule{}, err
+		}
+		return cron.Schedule{Kind: cron.ScheduleKindEvery, EveryMs: d.Milliseconds()}, nil
+	case cron.ScheduleKindCron:
+		if strings.TrimSpace(f.CronExpr) == "" {
+			return cron.Schedule{}, fmt.Errorf("cron expression is required")
+		}
+		return cron.Schedule{Kind: cron.ScheduleKindCron, Expr: strings.TrimSpace(f.CronExpr), Tz: strings.TrimSpace(f.Timezone)}, nil
+	case cron.ScheduleKindHass:
+		return cron.Schedule{Kind: cron.ScheduleKindHass, Entity: f.Entity, EntityRegex: f.EntityRegex, State: f.State}, nil
+	case cron.ScheduleKindJob:
+		return cron.Schedule{Kind: cron.ScheduleKindJob, AfterJob: f.AfterJob, On: f.On}, nil
+	case cron.ScheduleKindWebhook:
+		return cron.Schedule{Kind: cron.ScheduleKindWebhook}, nil
+	case cron.ScheduleKindFile:
+		return cron.Schedule{Kind: cron.ScheduleKindFile, Path: f.Path}, nil
+	default:
+		return cron.Schedule{}, fmt.Errorf("unknown schedule type %q", f.ScheduleType)
+	}
+}
+
+// formSeconds parses an optional duration field into whole seconds
+func formSeconds(s string) (int, error) {
+	if strings.TrimSpace(s) == "" {
+		return 0, nil
+	}
+	d, err := cron.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	return int(d.Seconds()), nil
+}
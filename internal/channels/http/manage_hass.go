@@ -0,0 +1,411 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/roelfdiedericks/goclaw/internal/hass"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// hassTestLimit caps the matches returned when testing against all current states
+const hassTestLimit = 100
+
+// hassSubForm is the editable form of a subscription, as sent to and from the page.
+// Conditions use the hass tool's syntax ("temperature > 28"); For is a duration ("10m").
+type hassSubForm struct {
+	ID         string   `json:"id"` // empty = create
+	Pattern    string   `json:"pattern"`
+	Regex      string   `json:"regex"`
+	Debounce   int      `json:"debounce"`
+	Interval   int      `json:"interval"`
+	Prefix     string   `json:"prefix"`
+	Prompt     string   `json:"prompt"`
+	Full       bool     `json:"full"`
+	Wake       bool     `json:"wake"`
+	Enabled    bool     `json:"enabled"`
+	From       []string `json:"from"`
+	To         []string `json:"to"`
+	Conditions []string `json:"conditions"`
+	For        string   `json:"for"`
+	After      string   `json:"after"`
+	Before     string   `json:"before"`
+}
+
+// hassTestRequest is the body of POST /api/hass/test.
+// Without an entity, the subscription is tested against every current state.
+// Without a new state, the entity's current state is used.
+type hassTestRequest struct {
+	Subscription hassSubForm    `json:"subscription"`
+	EntityID     string         `json:"entityId"`
+	OldState     string         `json:"oldState"`
+	NewState     string         `json:"newState"`
+	Attributes   map[string]any `json:"attributes"`
+}
+
+// hassTestResponse is the reply to POST /api/hass/test
+type hassTestResponse struct {
+	Results []hass.MatchResult `json:"results"`
+	Checked int                `json:"checked"` // entities checked
+	Matched int                `json:"matched"` // entities matching the pattern
+	Message string             `json:"message,omitempty"`
+}
+
+// handleHass handles GET /hass - Home Assistant subscriptions (owner only)
+func (s *Server) handleHass(w http.ResponseWriter, r *http.Request) {
+	if err := s.reloadTemplatesIfDev(); err != nil {
+		logging.L_error("http: template reload error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+
+	u := requireOwner(w, r)
+	if u == nil {
+		return
+	}
+
+	data := struct {
+		Title         string
+		User          *UserTemplateData
+		Available     bool
+		Connected     bool
+		State         string
+		Subscriptions []hass.Subscription
+	}{
+		Title: "GoClaw - Home Assistant",
+		User:  ownerTemplateData(u),
+	}
+
+	if m := s.hassManager(); m != nil {
+		data.Available = true
+		data.Connected = m.IsConnected()
+		data.State = m.GetState()
+		data.Subscriptions = m.GetSubscriptions()
+		sort.Slice(data.Subscriptions, func(i, j int) bool {
+			return data.Subscriptions[i].CreatedAt.Before(data.Subscriptions[j].CreatedAt)
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates.ExecuteTemplate(w, "hass.html", data); err != nil {
+		logging.L_error("http: template error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) hassManager() *hass.Manager {
+	if gw := s.managementGateway(); gw != nil {
+		return gw.HassManager()
+	}
+	return nil
+}
+
+// handleHassSubscriptionsAPI handles /api/hass/subscriptions (owner only).
+//
+// GET lists subscriptions, or returns one subscription's edit form with ?id=.
+// POST creates a subscription (empty id) or updates an existing one.
+func (s *Server) handleHassSubscriptionsAPI(w http.ResponseWriter, r *http.Request) {
+	u := requireOwner(w, r)
+	if u == nil {
+		return
+	}
+
+	m := s.hassManager()
+	if m == nil {
+		http.Error(w, "Home Assistant is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		subs := m.GetSubscriptions()
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			writeJSON(w, http.StatusOK, subs)
+			return
+		}
+		for _, sub := range subs {
+			if sub.ID == id {
+				writeJSON(w, http.StatusOK, hassFormFromSubscription(sub))
+				return
+			}
+		}
+		http.Error(w, "Subscription not found", http.StatusNotFound)
+		return
+	}
+
+	var form hassSubForm
+	if !decodeJSONPost(w, r, &form) {
+		return
+	}
+
+	sub, err := form.subscription()
+	action := "subscribe"
+	if err == nil {
+		if form.ID == "" {
+			sub.ID = uuid.New().String()
+			err = m.Subscribe(*sub)
+		} else {
+			action = "update"
+			sub.ID = form.ID
+			_, err = m.UpdateSubscription(form.ID, hass.SubscriptionUpdates{
+				Pattern:    &sub.Pattern,
+				Regex:      &sub.Regex,
+				Prompt:     &sub.Prompt,
+				Prefix:     &sub.Prefix,
+				Debounce:   &sub.DebounceSeconds,
+				Interval:   &sub.IntervalSeconds,
+				Full:       &sub.Full,
+				Wake:       &sub.Wake,
+				Enabled:    &sub.Enabled,
+				Conditions: conditionsOrEmpty(sub.Conditions),
+			})
+		}
+	}
+	inputs := map[string]any{"id": form.ID, "pattern": form.Pattern, "regex": form.Regex, "prompt": form.Prompt}
+	if sub != nil {
+		inputs["id"] = sub.ID
+	}
+	recordWebAudit(r, u, "hass."+action, inputs, err)
+	if err != nil {
+		logging.L_warn("http: hass subscription save failed", "action", action, "id", form.ID, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logging.L_info("http: hass subscription saved", "action", action, "id", sub.ID, "user", u.ID)
+
+	writeJSON(w, http.StatusOK, map[string]any{"status": "saved", "id": sub.ID})
+}
+
+// handleHassActionAPI handles POST /api/hass/action - enable, disable or
+// remove a subscription (owner only)
+func (s *Server) handleHassActionAPI(w http.ResponseWriter, r *http.Request) {
+	u := requireOwner(w, r)
+	if u == nil {
+		return
+	}
+
+	var req struct {
+		ID     string `json:"id"`
+		Action string `json:"action"` // enable, disable, remove
+	}
+	if !decodeJSONPost(w, r, &req) {
+		return
+	}
+
+	m := s.hassManager()
+	if m == nil {
+		http.Error(w, "Home Assistant is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	var err error
+	switch req.Action {
+	case "enable":
+		err = m.EnableSubscription(req.ID)
+	case "disable":
+		err = m.DisableSubscription(req.ID)
+	case "remove":
+		err = m.Unsubscribe(req.ID)
+	default:
+		http.Error(w, "Unknown action: "+req.Action, http.StatusBadRequest)
+		return
+	}
+	recordWebAudit(r, u, "hass."+req.Action, map[string]any{"id": req.ID}, err)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logging.L_info("http: hass subscription action", "action", req.Action, "id", req.ID, "user", u.ID)
+
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "id": req.ID})
+}
+
+// handleHassTestAPI handles POST /api/hass/test - check a draft subscription
+// against a state change or the live state cache, without saving (owner only)
+func (s *Server) handleHassTestAPI(w http.ResponseWriter, r *http.Request) {
+	u := requireOwner(w, r)
+	if u == nil {
+		return
+	}
+
+	var req hassTestRequest
+	if !decodeJSONPost(w, r, &req) {
+		return
+	}
+
+	sub, err := req.Subscription.subscription()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m := s.hassManager()
+	var states []hass.HAState
+	cached := false
+	if m != nil {
+		states, cached = m.CachedStates()
+	}
+
+	now := time.Now()
+	resp := hassTestResponse{Results: []hass.MatchResult{}}
+
+	if req.EntityID == "" {
+		if !cached {
+			http.Error(w, "No live state cache - Home Assistant is not connected", http.StatusServiceUnavailable)
+			return
+		}
+		for i := range states {
+			event := &hass.HAEvent{
+				EventType: "state_changed",
+				Data:      hass.HAEventData{EntityID: states[i].EntityID, NewState: &states[i]},
+			}
+			result := hass.ExplainMatch(sub, event, now)
+			resp.Checked++
+			if !result.Matched {
+				continue
+			}
+			resp.Matched++
+			if len(resp.Results) < hassTestLimit {
+				resp.Results = append(resp.Results, result)
+			}
+		}
+		resp.Message = fmt.Sprintf("%d of %d current entities match the pattern", resp.Matched, resp.Checked)
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+
+	newState := &hass.HAState{EntityID: req.EntityID, State: req.NewState, Attributes: req.Attributes}
+	if req.NewState == "" {
+		var current hass.HAState
+		found := false
+		if m != nil {
+			current, found = m.CachedState(req.EntityID)
+		}
+		if !found {
+			http.Error(w, "No current state for "+req.EntityID+" - enter a new state to test", http.StatusBadRequest)
+			return
+		}
+		newState = &current
+	}
+	event := &hass.HAEvent{
+		EventType: "state_changed",
+		Data:      hass.HAEventData{EntityID: req.EntityID, NewState: newState},
+	}
+	if req.OldState != "" {
+		event.Data.OldState = &hass.HAState{EntityID: req.EntityID, State: req.OldState}
+	}
+
+	result := hass.ExplainMatch(sub, event, now)
+	resp.Results = append(resp.Results, result)
+	resp.Checked = 1
+	if result.Matched {
+		resp.Matched = 1
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func hassFormFromSubscription(sub hass.Subscription) hassSubForm {
+	f := hassSubForm{
+		ID:       sub.ID,
+		Pattern:  sub.Pattern,
+		Regex:    sub.Regex,
+		Debounce: sub.DebounceSeconds,
+		Interval: sub.IntervalSeconds,
+		Prefix:   sub.Prefix,
+		Prompt:   sub.Prompt,
+		Full:     sub.Full,
+		Wake:     sub.Wake,
+		Enabled:  sub.Enabled,
+	}
+	if c := sub.Conditions; c != nil {
+		f.From, f.To = c.From, c.To
+		for _, ac := range c.Attributes {
+			f.Conditions = append(f.Conditions, ac.String())
+		}
+		f.For = formatWebDuration(time.Duration(c.ForSeconds) * time.Second)
+		f.After, f.Before = c.After, c.Before
+	}
+	return f
+}
+
+// subscription builds and validates a subscription from the form (ID is not set)
+func (f hassSubForm) subscription() (*hass.Subscription, error) {
+	f.Pattern, f.Regex = strings.TrimSpace(f.Pattern), strings.TrimSpace(f.Regex)
+	if f.Pattern == "" && f.Regex == "" {
+		return nil, fmt.Errorf("pattern or regex is required")
+	}
+	if f.Pattern != "" && f.Regex != "" {
+		return nil, fmt.Errorf("pattern and regex are mutually exclusive")
+	}
+	if f.Regex != "" {
+		if _, err := hass.MatchRegex(f.Regex, "test"); err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	if f.Debounce < 0 || f.Interval < 0 {
+		return nil, fmt.Errorf("debounce and interval must not be negative")
+	}
+
+	conds := hass.Conditions{
+		From:   trimList(f.From),
+		To:     trimList(f.To),
+		After:  strings.TrimSpace(f.After),
+		Before: strings.TrimSpace(f.Before),
+	}
+	for _, expr := range trimList(f.Conditions) {
+		ac, err := hass.ParseAttributeCondition(expr)
+		if err != nil {
+			return nil, err
+		}
+		conds.Attributes = append(conds.Attributes, ac)
+	}
+	if strings.TrimSpace(f.For) != "" {
+		d, err := time.ParseDuration(strings.TrimSpace(f.For))
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid for duration %q (expected e.g. 10m)", f.For)
+		}
+		conds.ForSeconds = int(d.Seconds())
+	}
+	if err := conds.Validate(); err != nil {
+		return nil, err
+	}
+
+	sub := hass.NewSubscription("")
+	sub.Pattern = f.Pattern
+	sub.Regex = f.Regex
+	sub.DebounceSeconds = f.Debounce
+	sub.IntervalSeconds = f.Interval
+	sub.Prefix = f.Prefix
+	sub.Prompt = f.Prompt
+	sub.Full = f.Full
+	sub.Wake = f.Wake
+	sub.Enabled = f.Enabled
+	if !conds.IsEmpty() {
+		sub.Conditions = &conds
+	}
+	return &sub, nil
+}
+
+// conditionsOrEmpty returns conditions for an update; empty conditions clear them
+func conditionsOrEmpty(c *hass.Conditions) *hass.Conditions {
+	if c == nil {
+		return &hass.Conditions{}
+	}
+	return c
+}
+
+// trimList trims entries and drops empty ones
+func trimList(in []string) []string {
+	var out []string
+	for _, s := range in {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
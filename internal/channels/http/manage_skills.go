@@ -0,0 +1,178 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/roelfdiedericks/goclaw/internal/bus"
+	"github.com/roelfdiedericks/goclaw/internal/config"
+	"github.com/roelfdiedericks/goclaw/internal/config/forms"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/skills"
+)
+
+// SkillRow is one skill on the skills page
+type SkillRow struct {
+	Name        string
+	Description string
+	Emoji       string
+	Source      string
+	Location    string
+	Eligible    bool
+	Enabled     bool
+	Whitelisted bool
+	Flags       []skills.AuditWarning
+}
+
+// handleSkills handles GET /skills - skills with audit flags (owner only)
+func (s *Server) handleSkills(w http.ResponseWriter, r *http.Request) {
+	if err := s.reloadTemplatesIfDev(); err != nil {
+		logging.L_error("http: template reload error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+
+	u := requireOwner(w, r)
+	if u == nil {
+		return
+	}
+
+	data := struct {
+		Title     string
+		User      *UserTemplateData
+		Available bool
+		Stats     skills.ManagerStats
+		Skills    []SkillRow
+	}{
+		Title: "GoClaw - Skills",
+		User:  ownerTemplateData(u),
+	}
+
+	if m := s.skillManager(); m != nil {
+		data.Available = true
+		data.Stats = m.GetStats()
+		for _, sk := range m.GetAllSkills() {
+			row := SkillRow{
+				Name:        sk.Name,
+				Description: sk.Description,
+				Source:      string(sk.Source),
+				Location:    sk.Location,
+				Eligible:    sk.Eligible,
+				Enabled:     sk.Enabled,
+				Whitelisted: sk.Whitelisted,
+				Flags:       sk.AuditFlags,
+			}
+			if sk.Metadata != nil {
+				row.Emoji = sk.Metadata.Emoji
+			}
+			data.Skills = append(data.Skills, row)
+		}
+		// Flagged skills first, then by name
+		sort.Slice(data.Skills, func(i, j int) bool {
+			a, b := data.Skills[i], data.Skills[j]
+			if (len(a.Flags) > 0) != (len(b.Flags) > 0) {
+				return len(a.Flags) > 0
+			}
+			return a.Name < b.Name
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates.ExecuteTemplate(w, "skills.html", data); err != nil {
+		logging.L_error("http: template error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) skillManager() *skills.Manager {
+	if gw := s.managementGateway(); gw != nil {
+		return gw.SkillManager()
+	}
+	return nil
+}
+
+// handleSkillsAPI handles POST /api/skills - reload skills, or whitelist a
+// flagged skill (owner only). The whitelist is skills.entries.<name>.enabled
+// in goclaw.json; it is saved and applied like a settings change.
+func (s *Server) handleSkillsAPI(w http.ResponseWriter, r *http.Request) {
+	u := requireOwner(w, r)
+	if u == nil {
+		return
+	}
+
+	var req struct {
+		Action string `json:"action"` // reload, whitelist, unwhitelist
+		Name   string `json:"name"`
+	}
+	if !decodeJSONPost(w, r, &req) {
+		return
+	}
+
+	m := s.skillManager()
+	if m == nil {
+		http.Error(w, "Skills are disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	var err error
+	message := ""
+	switch req.Action {
+	case "reload":
+		err = m.Reload()
+		message = fmt.Sprintf("Reloaded %d skills", len(m.GetAllSkills()))
+	case "whitelist", "unwhitelist":
+		if m.GetSkill(req.Name) == nil {
+			http.Error(w, "Skill not found", http.StatusNotFound)
+			return
+		}
+		err = setSkillWhitelist(req.Name, req.Action == "whitelist", u.ID)
+		if err == nil {
+			// Reload here too, in case the apply event had no listener
+			err = m.Reload()
+		}
+		message = "Skill " + req.Name + " " + req.Action + "ed"
+	default:
+		http.Error(w, "Unknown action: "+req.Action, http.StatusBadRequest)
+		return
+	}
+	recordWebAudit(r, u, "skills."+req.Action, map[string]any{"name": req.Name}, err)
+	if err != nil {
+		logging.L_warn("http: skills action failed", "action", req.Action, "skill", req.Name, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	logging.L_info("http: skills action", "action", req.Action, "skill", req.Name, "user", u.ID)
+
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok", "message": message})
+}
+
+// setSkillWhitelist saves skills.entries.<name>.enabled and applies the
+// skills config so the manager picks up the new entry
+func setSkillWhitelist(name string, enabled bool, userID string) error {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	loadResult, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	cfg := loadResult.Config
+	if cfg.Skills.Entries == nil {
+		cfg.Skills.Entries = make(map[string]skills.SkillEntryConfig)
+	}
+	entry := cfg.Skills.Entries[name]
+	entry.Enabled = enabled
+	cfg.Skills.Entries[name] = entry
+
+	if err := config.BackupAndWriteJSON(loadResult.SourcePath, cfg, config.DefaultBackupCount); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	result := forms.ExecuteActionAs("skills", forms.ActionDef{Name: "apply"}, &cfg.Skills, "web", userID)
+	if result.Error != nil && !errors.Is(result.Error, bus.ErrNoHandler) && !errors.Is(result.Error, bus.ErrUnknownCommand) {
+		return fmt.Errorf("saved, but apply failed: %w", result.Error)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/user"
+)
+
+// usersMu serializes load-modify-write cycles of users.json
+var usersMu sync.Mutex
+
+// UserRow is one user on the users page
+type UserRow struct {
+	Username    string
+	Name        string
+	Role        string
+	TelegramID  string
+	WhatsAppID  string
+	MatrixID    string
+	DiscordID   string
+	Email       string
+	Identities  map[string]string // Bridge channel name -> user ID
+	HasPassword bool
+	IsSelf      bool
+}
+
+// usersRequest is the body of POST /api/users
+type usersRequest struct {
+	Action   string `json:"action"` // add, remove, role, password
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	Password string `json:"password"` // empty on "password" = generate one
+}
+
+// handleUsers handles GET /users - user management (owner only)
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	if err := s.reloadTemplatesIfDev(); err != nil {
+		logging.L_error("http: template reload error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+
+	u := requireOwner(w, r)
+	if u == nil {
+		return
+	}
+
+	data := struct {
+		Title     string
+		User      *UserTemplateData
+		UsersPath string
+		Users     []UserRow
+		Roles     []string
+		Error     string
+	}{
+		Title:     "GoClaw - Users",
+		User:      ownerTemplateData(u),
+		UsersPath: user.GetUsersFilePath(),
+		Roles:     s.roleNames(),
+	}
+
+	users, err := user.LoadUsers()
+	if err != nil {
+		data.Error = "Failed to load users: " + err.Error()
+	}
+	for username, entry := range users {
+		data.Users = append(data.Users, UserRow{
+			Username:    username,
+			Name:        entry.Name,
+			Role:        entry.Role,
+			TelegramID:  entry.TelegramID,
+			WhatsAppID:  entry.WhatsAppID,
+			MatrixID:    entry.MatrixID,
+			DiscordID:   entry.DiscordID,
+			Email:       entry.Email,
+			Identities:  entry.Identities,
+			HasPassword: entry.HTTPPasswordHash != "",
+			IsSelf:      username == u.ID,
+		})
+	}
+	sort.Slice(data.Users, func(i, j int) bool { return data.Users[i].Username < data.Users[j].Username })
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates.ExecuteTemplate(w, "users.html", data); err != nil {
+		logging.L_error("http: template error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+	}
+}
+
+// roleNames returns the roles a user can be given: owner plus the roles
+// defined in goclaw.json
+func (s *Server) roleNames() []string {
+	names := []string{string(user.RoleOwner)}
+	for name := range s.users.GetRolesConfig() {
+		if name != string(user.RoleOwner) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// handleUsersAPI handles POST /api/users - add or remove a user, change a
+// role or reset an HTTP password (owner only). Changes are saved to
+// users.json and take effect without a restart.
+func (s *Server) handleUsersAPI(w http.ResponseWriter, r *http.Request) {
+	u := requireOwner(w, r)
+	if u == nil {
+		return
+	}
+
+	var req usersRequest
+	if !decodeJSONPost(w, r, &req) {
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+
+	password, err := s.applyUserChange(req, u)
+	recordWebAudit(r, u, "user."+req.Action, map[string]any{"username": req.Username, "name": req.Name, "role": req.Role}, err)
+	if err != nil {
+		logging.L_warn("http: user change failed", "action", req.Action, "username", req.Username, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logging.L_info("http: user changed", "action", req.Action, "username", req.Username, "by", u.ID)
+
+	resp := map[string]any{"status": "ok", "username": req.Username}
+	if password != "" && req.Password == "" {
+		// Generated password, shown once
+		resp["password"] = password
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// applyUserChange edits users.json and reloads the registry.
+// It returns the password that was set, if any.
+func (s *Server) applyUserChange(req usersRequest, actor *user.User) (string, error) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	users, err := user.LoadUsers()
+	if err != nil {
+		return "", fmt.Errorf("failed to load users: %w", err)
+	}
+	if users == nil {
+		users = user.UsersConfig{}
+	}
+
+	entry, exists := users[req.Username]
+	if req.Action != "add" && !exists {
+		return "", fmt.Errorf("user %q not found", req.Username)
+	}
+
+	password := ""
+	switch req.Action {
+	case "add":
+		if err := user.ValidateUsername(req.Username); err != nil {
+			return "", err
+		}
+		if exists {
+			return "", fmt.Errorf("user %q already exists", req.Username)
+		}
+		if strings.TrimSpace(req.Name) == "" {
+			return "", fmt.Errorf("display name is required")
+		}
+		if err := s.checkRole(req.Role); err != nil {
+			return "", err
+		}
+		entry = &user.UserEntry{Name: strings.TrimSpace(req.Name), Role: req.Role}
+		if req.Password != "" {
+			hash, err := user.HashPassword(req.Password)
+			if err != nil {
+				return "", fmt.Errorf("failed to hash password: %w", err)
+			}
+			entry.HTTPPasswordHash = hash
+			password = req.Password
+		}
+		users[req.Username] = entry
+
+	case "remove":
+		if req.Username == actor.ID {
+			return "", fmt.Errorf("you can't remove yourself")
+		}
+		if entry.Role == string(user.RoleOwner) && countOwners(users) <= 1 {
+			return "", fmt.Errorf("can't remove the only owner")
+		}
+		delete(users, req.Username)
+
+	case "role":
+		if err := s.checkRole(req.Role); err != nil {
+			return "", err
+		}
+		if req.Username == actor.ID && req.Role != entry.Role {
+			return "", fmt.Errorf("you can't change your own role")
+		}
+		if entry.Role == string(user.RoleOwner) && req.Role != entry.Role && countOwners(users) <= 1 {
+			return "", fmt.Errorf("can't demote the only owner")
+		}
+		entry.Role = req.Role
+
+	case "password":
+		password = req.Password
+		if password == "" {
+			if password, err = generatePassword(); err != nil {
+				return "", err
+			}
+		}
+		hash, err := user.HashPassword(password)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		entry.HTTPPasswordHash = hash
+
+	default:
+		return "", fmt.Errorf("unknown action %q", req.Action)
+	}
+
+	if err := user.SaveUsers(users, user.GetUsersFilePath()); err != nil {
+		return "", err
+	}
+	s.users.Reload(users)
+	return password, nil
+}
+
+// checkRole verifies a role can be resolved from the roles config
+func (s *Server) checkRole(role string) error {
+	if role == "" {
+		return fmt.Errorf("role is required")
+	}
+	if _, err := user.ResolveRole(role, s.users.GetRolesConfig()); err != nil {
+		return err
+	}
+	return nil
+}
+
+func countOwners(users user.UsersConfig) int {
+	n := 0
+	for _, entry := range users {
+		if entry.Role == string(user.RoleOwner) {
+			n++
+		}
+	}
+	return n
+}
+
+// generatePassword returns a random password for a reset
+func generatePassword() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -2,11 +2,13 @@
 package http
 
 import (
+	"bufio"
 	"context"
 	"embed"
 	"fmt"
 	"html/template"
 	"io/fs"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -31,6 +33,7 @@ type Server struct {
 	users        *user.Registry
 	templates    *template.Template
 	rateLimiter  *RateLimiter
+	tokenUsage   *user.TokenUsage
 	shutdownChan chan struct{}
 	wg           sync.WaitGroup
 
@@ -48,6 +51,12 @@ type Server struct {
 	config *config.Config
 	listen string
 
+	// Native TLS (nil = plain HTTP)
+	certs *certManager
+
+	// Proxies whose X-Forwarded-For is believed
+	trustedProxies []*net.IPNet
+
 	// State tracking for ManagedChannel interface
 	mu        sync.RWMutex
 	running   bool
@@ -57,9 +66,11 @@ type Server struct {
 
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Listen    string // Address to listen on (e.g., ":1337", "127.0.0.1:1337")
-	DevMode   bool   // Reload templates from disk on each request
-	MediaRoot string // Base directory for media files
+	Listen         string           // Address to listen on (e.g., ":1337", "127.0.0.1:1337")
+	DevMode        bool             // Reload templates from disk on each request
+	MediaRoot      string           // Base directory for media files
+	TLS            config.TLSConfig // Native HTTPS and client certificates
+	TrustedProxies []string         // IPs/CIDRs allowed to set X-Forwarded-For (empty = loopback)
 }
 
 // NewServer creates a new HTTP server instance
@@ -92,10 +103,21 @@ func NewServer(cfg *ServerConfig, users *user.Registry) (*Server, error) {
 	s := &Server{
 		users:        users,
 		rateLimiter:  NewRateLimiter(10 * time.Second),
+		tokenUsage:   user.LoadTokenUsage(user.TokenUsagePath()),
 		shutdownChan: make(chan struct{}),
 		devMode:      cfg.DevMode,
 		mediaRoot:    cfg.MediaRoot,
 		listen:       listen,
+
+		trustedProxies: parseTrustedProxies(cfg.TrustedProxies),
+	}
+
+	if cfg.TLS.Enabled {
+		certs, err := newCertManager(cfg.TLS, listen)
+		if err != nil {
+			return nil, fmt.Errorf("TLS: %w", err)
+		}
+		s.certs = certs
 	}
 
 	// Create HTTP channel
@@ -130,6 +152,9 @@ func NewServer(cfg *ServerConfig, users *user.Registry) (*Server, error) {
 		WriteTimeout: 60 * time.Second, // Longer for SSE
 		IdleTimeout:  120 * time.Second,
 	}
+	if s.certs != nil {
+		s.server.TLSConfig = s.certs.TLSConfig()
+	}
 
 	return s, nil
 }
@@ -153,20 +178,57 @@ func (s *Server) setupRoutes() http.Handler {
 		return s.logRequest(s.stripHeaders(s.rateLimit(s.basicAuth(h))))
 	}
 
+	// API routes also accept a Bearer API token with the given scope
+	api := func(scope string, h http.HandlerFunc) http.HandlerFunc {
+		return s.logRequest(s.stripHeaders(s.rateLimit(s.apiAuth(scope, h))))
+	}
+
 	// API routes
-	mux.HandleFunc("/api/send", wrap(s.handleSend))
-	mux.HandleFunc("/api/events", wrap(s.handleEvents))
-	mux.HandleFunc("/api/status", wrap(s.handleStatus))
-	mux.HandleFunc("/api/media", wrap(s.handleMedia))
-	mux.HandleFunc("/api/metrics", wrap(s.handleMetricsAPI))
+	mux.HandleFunc("/api/send", api(user.ScopeChat, s.handleSend))
+	mux.HandleFunc("/api/events", api(user.ScopeChat, s.handleEvents))
+	mux.HandleFunc("/api/ws", api(user.ScopeChat, s.handleWebSocket))
+	mux.HandleFunc("/api/status", api(user.ScopeChat, s.handleStatus))
+	mux.HandleFunc("/api/media", api(user.ScopeChat, s.handleMedia))
+	mux.HandleFunc("/api/metrics", api(user.ScopeMetrics, s.handleMetricsAPI))
+	mux.HandleFunc("/api/history/restore", api(user.ScopeAdminConfig, s.handleHistoryRestore))
+	mux.HandleFunc("/api/settings", api(user.ScopeAdminConfig, s.handleSettingsAPI))
+	mux.HandleFunc("/api/settings/restore", api(user.ScopeAdminConfig, s.handleSettingsRestore))
+
+	// Cron webhook triggers (authenticated by per-job secret or a webhook-scoped token, not basic auth)
+	mux.HandleFunc("/api/cron/webhook/", s.logRequest(s.stripHeaders(s.handleCronWebhook)))
 
 	// Supervision routes (owner-only, checked in handler)
-	mux.HandleFunc("/api/sessions/", wrap(s.handleSessionsAction))
+	mux.HandleFunc("/api/sessions/", api(user.ScopeSupervise, s.handleSessionsAction))
+
+	// Session browser (web chat)
+	mux.HandleFunc("/api/chat/sessions", api(user.ScopeChat, s.handleChatSessions))
+	mux.HandleFunc("/api/chat/history", api(user.ScopeReadHistory, s.handleChatHistory))
+	mux.HandleFunc("/api/chat/search", api(user.ScopeReadHistory, s.handleChatSearch))
+
+	// Management routes (owner-only, checked in handler)
+	mux.HandleFunc("/api/cron/jobs", api(user.ScopeAdminConfig, s.handleCronJobsAPI))
+	mux.HandleFunc("/api/cron/action", api(user.ScopeAdminConfig, s.handleCronActionAPI))
+	mux.HandleFunc("/api/hass/subscriptions", api(user.ScopeAdminConfig, s.handleHassSubscriptionsAPI))
+	mux.HandleFunc("/api/hass/action", api(user.ScopeAdminConfig, s.handleHassActionAPI))
+	mux.HandleFunc("/api/hass/test", api(user.ScopeAdminConfig, s.handleHassTestAPI))
+	mux.HandleFunc("/api/users", api(user.ScopeAdminConfig, s.handleUsersAPI))
+	mux.HandleFunc("/api/skills", api(user.ScopeAdminConfig, s.handleSkillsAPI))
+
+	// API token management (basic auth only - tokens can't mint tokens)
+	mux.HandleFunc("/api/tokens", wrap(s.handleTokensAPI))
 
 	// Web UI routes
 	mux.HandleFunc("/", wrap(s.handleIndex))
 	mux.HandleFunc("/chat", wrap(s.handleChat))
 	mux.HandleFunc("/metrics", wrap(s.handleMetrics))
+	mux.HandleFunc("/history", wrap(s.handleHistory))
+	mux.HandleFunc("/audit", wrap(s.handleAudit))
+	mux.HandleFunc("/settings", wrap(s.handleSettings))
+	mux.HandleFunc("/cron", wrap(s.handleCron))
+	mux.HandleFunc("/hass", wrap(s.handleHass))
+	mux.HandleFunc("/users", wrap(s.handleUsers))
+	mux.HandleFunc("/skills", wrap(s.handleSkills))
+	mux.HandleFunc("/tokens", wrap(s.handleTokens))
 
 	return mux
 }
@@ -222,12 +284,25 @@ func (s *Server) Start(ctx context.Context) error {
 	// Track current listen address for config test
 	config.SetCurrentListenAddr(s.server.Addr)
 
+	if s.certs != nil {
+		if err := s.certs.Watch(); err != nil {
+			// Still serve; renewals will need a restart
+			logging.L_warn("http: can't watch TLS files, certificate changes need a restart", "error", err)
+		}
+	}
+
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		logging.L_info("http: server starting", "addr", s.server.Addr)
 
-		err := s.server.ListenAndServe()
+		var err error
+		if s.certs != nil {
+			logging.L_info("http: server starting (TLS)", "addr", s.server.Addr, "fingerprint", s.certs.Fingerprint())
+			err = s.server.ListenAndServeTLS("", "")
+		} else {
+			logging.L_info("http: server starting", "addr", s.server.Addr)
+			err = s.server.ListenAndServe()
+		}
 		if err != nil && err != http.ErrServerClosed {
 			logging.L_error("http: server error", "error", err)
 			s.mu.Lock()
@@ -263,6 +338,9 @@ func (s *Server) Stop() error {
 	}
 
 	s.wg.Wait()
+	if s.certs != nil {
+		s.certs.Stop()
+	}
 	logging.L_info("http: server stopped")
 
 	s.mu.Lock()
@@ -287,6 +365,10 @@ func (s *Server) Reload(cfg any) error {
 	if s.running && newCfg.Listen != s.listen && newCfg.Listen != "" {
 		logging.L_warn("http: listen address change requires restart", "old", s.listen, "new", newCfg.Listen)
 	}
+	if s.running && newCfg.TLS.Enabled != (s.certs != nil) {
+		logging.L_warn("http: TLS change requires restart", "tls", newCfg.TLS.Enabled)
+	}
+	s.trustedProxies = parseTrustedProxies(newCfg.TrustedProxies)
 
 	s.config = newCfg
 	return nil
@@ -370,6 +452,16 @@ func (lw *loggingResponseWriter) Flush() {
 	}
 }
 
+// Hijack implements http.Hijacker for WebSocket upgrades
+func (lw *loggingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := lw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijacking not supported")
+	}
+	lw.statusCode = http.StatusSwitchingProtocols
+	return h.Hijack()
+}
+
 // stripHeaders removes fingerprinting headers
 func (s *Server) stripHeaders(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -394,12 +486,26 @@ func (s *Server) handleStatusCommand(cmd bus.Command) bus.CommandResult {
 		addr = ":1337"
 	}
 
+	if s.certs != nil {
+		return bus.CommandResult{
+			Success: true,
+			Message: fmt.Sprintf("HTTPS server listening on %s (certificate SHA-256 %s)", addr, s.certs.Fingerprint()),
+			Data: map[string]any{
+				"listening":   true,
+				"address":     addr,
+				"tls":         true,
+				"fingerprint": s.certs.Fingerprint(),
+			},
+		}
+	}
+
 	return bus.CommandResult{
 		Success: true,
 		Message: fmt.Sprintf("HTTP server listening on %s", addr),
 		Data: map[string]any{
 			"listening": true,
 			"address":   addr,
+			"tls":       false,
 		},
 	}
 }
@@ -0,0 +1,359 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/bus"
+	"github.com/roelfdiedericks/goclaw/internal/config"
+	"github.com/roelfdiedericks/goclaw/internal/config/forms"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// settingsMu serializes load-modify-write cycles of the config file
+var settingsMu sync.Mutex
+
+// SettingsGroup is a heading of components on the settings page
+type SettingsGroup struct {
+	Name       string
+	Components []SettingsLink
+}
+
+// SettingsLink is one component on the settings page
+type SettingsLink struct {
+	ID    string
+	Title string
+}
+
+// SettingsBackup is one config backup on the settings page
+type SettingsBackup struct {
+	Index   int
+	Name    string
+	ModTime time.Time
+	Size    int64
+}
+
+// SettingsForm is a component form on the settings page
+type SettingsForm struct {
+	ID          string
+	Title       string
+	Description string
+	Sections    []forms.WebSection
+	Actions     []forms.ActionDef // actions other than apply (e.g. test)
+}
+
+// settingsRequest is the body of POST /api/settings
+type settingsRequest struct {
+	Component string            `json:"component"`
+	Action    string            `json:"action"` // "validate", "apply", or another form action
+	Values    map[string]string `json:"values"`
+}
+
+// settingsResponse is the reply to POST /api/settings
+type settingsResponse struct {
+	OK              bool              `json:"ok"`
+	Message         string            `json:"message,omitempty"`
+	Errors          map[string]string `json:"errors,omitempty"` // per field key
+	RestartRequired bool              `json:"restartRequired,omitempty"`
+}
+
+// handleSettings handles GET /settings - the config console (owner only).
+// ?component=ID shows that component's form.
+func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
+	if err := s.reloadTemplatesIfDev(); err != nil {
+		logging.L_error("http: template reload error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+
+	u := getUserFromContext(r)
+	if u == nil {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	if !u.IsOwner() {
+		http.Error(w, "Forbidden - owner only", http.StatusForbidden)
+		return
+	}
+
+	data := struct {
+		Title      string
+		User       *UserTemplateData
+		ConfigPath string
+		Groups     []SettingsGroup
+		Backups    []SettingsBackup
+		Form       *SettingsForm
+		Error      string
+	}{
+		Title: "GoClaw - Settings",
+		User:  &UserTemplateData{Name: u.Name, Username: u.ID, Role: string(u.Role), IsOwner: u.IsOwner()},
+	}
+
+	loadResult, err := config.Load()
+	if err != nil {
+		data.Error = "Failed to load config: " + err.Error()
+	} else {
+		data.ConfigPath = loadResult.SourcePath
+		for _, b := range config.ListBackups(loadResult.SourcePath) {
+			name := ".bak"
+			if b.Index > 0 {
+				name = fmt.Sprintf(".bak.%d", b.Index)
+			}
+			data.Backups = append(data.Backups, SettingsBackup{Index: b.Index, Name: name, ModTime: b.ModTime, Size: b.Size})
+		}
+
+		if id := r.URL.Query().Get("component"); id != "" {
+			if c, ok := findSettingsComponent(id); ok {
+				form, err := settingsForm(c, loadResult.Config)
+				if err != nil {
+					data.Error = err.Error()
+				}
+				data.Form = form
+			} else {
+				data.Error = "Unknown component: " + id
+			}
+		}
+	}
+
+	for _, c := range settingsComponents() {
+		if n := len(data.Groups); n == 0 || data.Groups[n-1].Name != c.Group {
+			data.Groups = append(data.Groups, SettingsGroup{Name: c.Group})
+		}
+		g := &data.Groups[len(data.Groups)-1]
+		g.Components = append(g.Components, SettingsLink{ID: c.ID, Title: c.Title})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates.ExecuteTemplate(w, "settings.html", data); err != nil {
+		logging.L_error("http: template error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+	}
+}
+
+func settingsForm(c settingsComponent, cfg *config.Config) (*SettingsForm, error) {
+	def := c.form(cfg)
+	sections, err := forms.WebSections(def, c.load(cfg))
+	if err != nil {
+		return nil, err
+	}
+	form := &SettingsForm{
+		ID:          c.ID,
+		Title:       def.Title,
+		Description: def.Description,
+		Sections:    sections,
+	}
+	if form.Title == "" {
+		form.Title = c.Title
+	}
+	for _, a := range def.Actions {
+		if a.Name != "apply" {
+			form.Actions = append(form.Actions, a)
+		}
+	}
+	return form, nil
+}
+
+// handleSettingsAPI handles GET and POST /api/settings (owner only).
+//
+// GET ?component=ID returns the component's form with current values.
+// POST validates posted values ("validate"), applies and saves them ("apply"),
+// or runs another form action on them without saving (e.g. "test").
+func (s *Server) handleSettingsAPI(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromContext(r)
+	if u == nil {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	if !u.IsOwner() {
+		logging.L_warn("http: settings denied - not owner", "user", u.ID)
+		http.Error(w, "Forbidden - owner only", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		c, ok := findSettingsComponent(r.URL.Query().Get("component"))
+		if !ok {
+			http.Error(w, "Unknown component", http.StatusNotFound)
+			return
+		}
+		loadResult, err := config.Load()
+		if err != nil {
+			http.Error(w, "Failed to load config: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		form, err := settingsForm(c, loadResult.Config)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, form)
+
+	case http.MethodPost:
+		var req settingsRequest
+		if !requireJSON(w, r) {
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		c, ok := findSettingsComponent(req.Component)
+		if !ok {
+			http.Error(w, "Unknown component", http.StatusNotFound)
+			return
+		}
+		resp := s.runSettingsAction(c, req, u.ID)
+		status := http.StatusOK
+		if !resp.OK {
+			status = http.StatusUnprocessableEntity
+		}
+		writeJSON(w, status, resp)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// runSettingsAction loads the config, applies the posted values to a copy of
+// the component's section and runs the requested action on it.
+func (s *Server) runSettingsAction(c settingsComponent, req settingsRequest, userID string) settingsResponse {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	loadResult, err := config.Load()
+	if err != nil {
+		return settingsResponse{Message: "Failed to load config: " + err.Error()}
+	}
+	cfg := loadResult.Config
+
+	value := c.load(cfg)
+	fieldErrs, err := forms.ApplyWebValues(c.form(cfg), value, req.Values)
+	if len(fieldErrs) > 0 {
+		return settingsResponse{Message: "Some fields are invalid", Errors: fieldErrs}
+	}
+	if err != nil {
+		return settingsResponse{Message: "Validation failed: " + err.Error()}
+	}
+
+	switch req.Action {
+	case "validate":
+		return settingsResponse{OK: true, Message: "Valid"}
+
+	case "apply":
+		result := forms.ExecuteActionAs(c.ID, forms.ActionDef{Name: "apply"}, c.actionPayload(value), "web", userID)
+		restart := errors.Is(result.Error, bus.ErrNoHandler) || errors.Is(result.Error, bus.ErrUnknownCommand)
+		if result.Error != nil && !restart {
+			logging.L_warn("http: settings apply failed", "component", c.ID, "error", result.Error)
+			return settingsResponse{Message: "Apply failed: " + result.Error.Error()}
+		}
+
+		c.store(cfg, value)
+		if err := config.BackupAndWriteJSON(loadResult.SourcePath, cfg, config.DefaultBackupCount); err != nil {
+			logging.L_error("http: settings save failed", "path", loadResult.SourcePath, "error", err)
+			return settingsResponse{Message: "Applied, but saving failed: " + err.Error()}
+		}
+		logging.L_info("http: settings saved", "component", c.ID, "user", userID, "path", loadResult.SourcePath)
+
+		if restart {
+			return settingsResponse{OK: true, RestartRequired: true, Message: "Saved. Restart the gateway for the change to take effect."}
+		}
+		msg := result.Message
+		if msg == "" {
+			msg = "Config applied"
+		}
+		return settingsResponse{OK: true, Message: "Saved. " + msg}
+
+	default:
+		action, ok := findFormAction(c.form(cfg), req.Action)
+		if !ok {
+			return settingsResponse{Message: "Unknown action: " + req.Action}
+		}
+		result := forms.ExecuteActionAs(c.ID, action, c.actionPayload(value), "web", userID)
+		if result.Error != nil {
+			return settingsResponse{Message: result.Error.Error()}
+		}
+		return settingsResponse{OK: result.Success, Message: result.Message}
+	}
+}
+
+func findFormAction(def forms.FormDef, name string) (forms.ActionDef, bool) {
+	for _, a := range def.Actions {
+		if a.Name == name && name != "apply" {
+			return a, true
+		}
+	}
+	return forms.ActionDef{}, false
+}
+
+// handleSettingsRestore handles POST /api/settings/restore - restore a config
+// backup and re-apply every component from it (owner only)
+func (s *Server) handleSettingsRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	u := getUserFromContext(r)
+	if u == nil {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	if !u.IsOwner() {
+		logging.L_warn("http: settings restore denied - not owner", "user", u.ID)
+		http.Error(w, "Forbidden - owner only", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Index int `json:"index"`
+	}
+	if !requireJSON(w, r) {
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+
+	loadResult, err := config.Load()
+	if err != nil {
+		http.Error(w, "Failed to load config: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := config.RestoreBackup(loadResult.SourcePath, req.Index); err != nil {
+		logging.L_warn("http: settings restore failed", "index", req.Index, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logging.L_info("http: config backup restored", "index", req.Index, "user", u.ID, "path", loadResult.SourcePath)
+
+	restored, err := config.Load()
+	if err != nil {
+		writeJSON(w, http.StatusOK, settingsResponse{OK: true, RestartRequired: true,
+			Message: "Backup restored, but it failed to load: " + err.Error()})
+		return
+	}
+
+	resp := settingsResponse{OK: true, Message: "Backup restored and applied."}
+	for _, c := range settingsComponents() {
+		result := forms.ExecuteActionAs(c.ID, forms.ActionDef{Name: "apply"}, c.actionPayload(c.load(restored.Config)), "web", u.ID)
+		if errors.Is(result.Error, bus.ErrNoHandler) || errors.Is(result.Error, bus.ErrUnknownCommand) {
+			resp.RestartRequired = true
+		} else if result.Error != nil {
+			logging.L_warn("http: re-apply after restore failed", "component", c.ID, "error", result.Error)
+			resp.RestartRequired = true
+		}
+	}
+	if resp.RestartRequired {
+		resp.Message = "Backup restored. Restart the gateway to apply every setting."
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,191 @@
+package http
+
+import (
+	"github.com/roelfdiedericks/goclaw/internal/auth"
+	bridgeconfig "github.com/roelfdiedericks/goclaw/internal/channels/bridge/config"
+	discordconfig "github.com/roelfdiedericks/goclaw/internal/channels/discord/config"
+	emailconfig "github.com/roelfdiedericks/goclaw/internal/channels/email/config"
+	httpconfig "github.com/roelfdiedericks/goclaw/internal/channels/http/config"
+	matrixconfig "github.com/roelfdiedericks/goclaw/internal/channels/matrix/config"
+	telegramconfig "github.com/roelfdiedericks/goclaw/internal/channels/telegram/config"
+	tuiconfig "github.com/roelfdiedericks/goclaw/internal/channels/tui/config"
+	whatsappconfig "github.com/roelfdiedericks/goclaw/internal/channels/whatsapp/config"
+	"github.com/roelfdiedericks/goclaw/internal/config"
+	"github.com/roelfdiedericks/goclaw/internal/config/forms"
+	"github.com/roelfdiedericks/goclaw/internal/cron"
+	"github.com/roelfdiedericks/goclaw/internal/gateway"
+	"github.com/roelfdiedericks/goclaw/internal/hass"
+	"github.com/roelfdiedericks/goclaw/internal/media"
+	"github.com/roelfdiedericks/goclaw/internal/memory"
+	"github.com/roelfdiedericks/goclaw/internal/retrieval"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox"
+	"github.com/roelfdiedericks/goclaw/internal/session"
+	"github.com/roelfdiedericks/goclaw/internal/skills"
+	"github.com/roelfdiedericks/goclaw/internal/stt"
+	toolsconfig "github.com/roelfdiedericks/goclaw/internal/tools/config"
+	"github.com/roelfdiedericks/goclaw/internal/tools/exec"
+	"github.com/roelfdiedericks/goclaw/internal/transcript"
+)
+
+// settingsComponent is one config section editable from the web settings
+// page. ID is the bus component its "apply" (and other form actions) go to.
+type settingsComponent struct {
+	ID    string
+	Title string
+	Group string
+
+	form    func(cfg *config.Config) forms.FormDef
+	load    func(cfg *config.Config) any        // copy of the section, as a struct pointer
+	store   func(cfg *config.Config, value any) // write an edited copy back
+	payload func(value any) any                 // apply payload, if it differs from the edited value
+}
+
+// settingsSection builds a component for a config section that is a single
+// field of config.Config.
+func settingsSection[T any](id, title, group string, field func(cfg *config.Config) *T, form func(v T) forms.FormDef) settingsComponent {
+	return settingsComponent{
+		ID:    id,
+		Title: title,
+		Group: group,
+		form: func(cfg *config.Config) forms.FormDef {
+			return form(*field(cfg))
+		},
+		load: func(cfg *config.Config) any {
+			v := *field(cfg)
+			return &v
+		},
+		store: func(cfg *config.Config, value any) {
+			*field(cfg) = *value.(*T)
+		},
+	}
+}
+
+// settingsComponents lists the editable sections in the same order as the
+// setup editor menu. LLM providers and users have their own editors.
+func settingsComponents() []settingsComponent {
+	components := []settingsComponent{
+		{
+			ID:    "gateway",
+			Title: "Gateway Settings",
+			Group: "General",
+			form:  func(*config.Config) forms.FormDef { return gateway.ConfigFormDef() },
+			load: func(cfg *config.Config) any {
+				return &gateway.GatewayConfigBundle{
+					Gateway:     cfg.Gateway,
+					Agent:       cfg.Agent,
+					PromptCache: cfg.PromptCache,
+					Supervision: cfg.Supervision,
+				}
+			},
+			store: func(cfg *config.Config, value any) {
+				b := value.(*gateway.GatewayConfigBundle)
+				cfg.Gateway = b.Gateway
+				cfg.Agent = b.Agent
+				cfg.PromptCache = b.PromptCache
+				cfg.Supervision = b.Supervision
+			},
+		},
+		settingsSection("session", "Session Management", "General",
+			func(cfg *config.Config) *session.SessionConfig { return &cfg.Session },
+			func(session.SessionConfig) forms.FormDef { return session.ConfigFormDef() }),
+
+		settingsSection("channels.telegram", "Telegram Bot", "Channels",
+			func(cfg *config.Config) *telegramconfig.Config { return &cfg.Channels.Telegram },
+			func(telegramconfig.Config) forms.FormDef { return telegramconfig.ConfigFormDef() }),
+		settingsSection("channels.whatsapp", "WhatsApp", "Channels",
+			func(cfg *config.Config) *whatsappconfig.Config { return &cfg.Channels.WhatsApp },
+			func(whatsappconfig.Config) forms.FormDef { return whatsappconfig.ConfigFormDef() }),
+		settingsSection("channels.matrix", "Matrix", "Channels",
+			func(cfg *config.Config) *matrixconfig.Config { return &cfg.Channels.Matrix },
+			func(matrixconfig.Config) forms.FormDef { return matrixconfig.ConfigFormDef() }),
+		settingsSection("channels.discord", "Discord", "Channels",
+			func(cfg *config.Config) *discordconfig.Config { return &cfg.Channels.Discord },
+			func(discordconfig.Config) forms.FormDef { return discordconfig.ConfigFormDef() }),
+		settingsSection("channels.email", "Email", "Channels",
+			func(cfg *config.Config) *emailconfig.Config { return &cfg.Channels.Email },
+			func(emailconfig.Config) forms.FormDef { return emailconfig.ConfigFormDef() }),
+		settingsSection("channels.bridge", "Bridges", "Channels",
+			func(cfg *config.Config) *bridgeconfig.Config { return &cfg.Channels.Bridge },
+			func(bridgeconfig.Config) forms.FormDef { return bridgeconfig.ConfigFormDef() }),
+		settingsSection("channels.http", "HTTP Server", "Channels",
+			func(cfg *config.Config) *httpconfig.Config { return &cfg.Channels.HTTP },
+			func(httpconfig.Config) forms.FormDef { return httpconfig.ConfigFormDef() }),
+
+		settingsSection("transcript", "Transcript Indexing", "Services",
+			func(cfg *config.Config) *transcript.TranscriptConfig { return &cfg.Transcript },
+			transcript.ConfigFormDef),
+		settingsSection("memory", "Memory Search", "Services",
+			func(cfg *config.Config) *memory.MemorySearchConfig { return &cfg.Memory },
+			memory.ConfigFormDef),
+		settingsSection("retrieval", "Context Retrieval", "Services",
+			func(cfg *config.Config) *retrieval.Config { return &cfg.Retrieval },
+			func(retrieval.Config) forms.FormDef { return retrieval.ConfigFormDef() }),
+		settingsSection("stt", "Speech-to-Text (STT)", "Services",
+			func(cfg *config.Config) *stt.Config { return &cfg.STT },
+			func(c stt.Config) forms.FormDef { return stt.ConfigFormDef(c.WhisperCpp.ModelsDir) }),
+		settingsSection("skills", "Skills", "Services",
+			func(cfg *config.Config) *skills.SkillsConfig { return &cfg.Skills },
+			func(skills.SkillsConfig) forms.FormDef { return skills.ConfigFormDef() }),
+		settingsSection("cron", "Cron Jobs", "Services",
+			func(cfg *config.Config) *cron.CronConfig { return &cfg.Cron },
+			func(cron.CronConfig) forms.FormDef { return cron.ConfigFormDef() }),
+		settingsSection("homeassistant", "Home Assistant", "Services",
+			func(cfg *config.Config) *hass.HomeAssistantConfig { return &cfg.HomeAssistant },
+			hass.ConfigFormDef),
+
+		settingsSection("media", "Media Storage", "System",
+			func(cfg *config.Config) *media.MediaConfig { return &cfg.Media },
+			func(media.MediaConfig) forms.FormDef { return media.ConfigFormDef() }),
+		settingsSection("channels.tui", "TUI Settings", "System",
+			func(cfg *config.Config) *tuiconfig.Config { return &cfg.Channels.TUI },
+			func(tuiconfig.Config) forms.FormDef { return tuiconfig.ConfigFormDef() }),
+		settingsSection("sandbox", "Sandbox", "System",
+			func(cfg *config.Config) *sandbox.Config { return &cfg.Sandbox },
+			func(sandbox.Config) forms.FormDef { return sandbox.ConfigFormDef() }),
+		settingsSection("auth", "Auth Settings", "System",
+			func(cfg *config.Config) *auth.AuthConfig { return &cfg.Auth },
+			func(auth.AuthConfig) forms.FormDef { return auth.ConfigFormDef() }),
+	}
+
+	// The exec tool's form edits the persisted tools.exec section, but its
+	// apply command takes the tool's own config type
+	execTool := settingsSection("tools.exec", "Exec Tool", "System",
+		func(cfg *config.Config) *toolsconfig.ExecToolsConfig { return &cfg.Tools.Exec },
+		func(toolsconfig.ExecToolsConfig) forms.FormDef { return exec.ConfigFormDef() })
+	execTool.payload = func(value any) any {
+		c := value.(*toolsconfig.ExecToolsConfig)
+		return &exec.ExecConfig{
+			Enabled: true,
+			Timeout: c.Timeout,
+			Bubblewrap: exec.BubblewrapConfig{
+				Enabled:      c.Bubblewrap.Enabled,
+				ExtraRoBind:  c.Bubblewrap.ExtraRoBind,
+				ExtraBind:    c.Bubblewrap.ExtraBind,
+				ExtraEnv:     c.Bubblewrap.ExtraEnv,
+				AllowNetwork: c.Bubblewrap.AllowNetwork,
+				ClearEnv:     c.Bubblewrap.ClearEnv,
+			},
+		}
+	}
+	components = append(components, execTool)
+
+	return components
+}
+
+// findSettingsComponent returns the component with the given ID
+func findSettingsComponent(id string) (settingsComponent, bool) {
+	for _, c := range settingsComponents() {
+		if c.ID == id {
+			return c, true
+		}
+	}
+	return settingsComponent{}, false
+}
+
+// actionPayload returns the payload for a form action on an edited value
+func (c settingsComponent) actionPayload(value any) any {
+	if c.payload != nil {
+		return c.payload(value)
+	}
+	return value
+}
@@ -233,6 +233,9 @@ func (s *Server) handleSessionGuidance(w http.ResponseWriter, r *http.Request, s
 	var req struct {
 		Content string `json:"content"`
 	}
+	if !requireJSON(w, r) {
+		return
+	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
@@ -289,6 +292,9 @@ func (s *Server) handleSessionLLM(w http.ResponseWriter, r *http.Request, sessio
 	var req struct {
 		Enabled bool `json:"enabled"`
 	}
+	if !requireJSON(w, r) {
+		return
+	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
@@ -334,6 +340,9 @@ func (s *Server) handleSessionMessage(w http.ResponseWriter, r *http.Request, se
 	var req struct {
 		Content string `json:"content"`
 	}
+	if !requireJSON(w, r) {
+		return
+	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
@@ -383,7 +392,7 @@ func (s *Server) getGatewaySessionsInfo() []GatewaySessionInfo {
 
 	mgr := gw.SessionManager()
 	for _, info := range sessions {
-		sess := mgr.Get(info.ID)
+		sess := mgr.GetIfExists(info.Key)
 		if sess == nil {
 			continue
 		}
@@ -396,7 +405,7 @@ func (s *Server) getGatewaySessionsInfo() []GatewaySessionInfo {
 		}
 
 		result = append(result, GatewaySessionInfo{
-			Key:          info.ID,
+			Key:          info.Key,
 			Messages:     info.MessageCount,
 			TotalTokens:  info.TotalTokens,
 			MaxTokens:    info.MaxTokens,
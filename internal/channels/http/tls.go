@@ -0,0 +1,382 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/roelfdiedericks/goclaw/internal/channels/http/config"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/paths"
+)
+
+// certReloadDebounce waits for cert renewals (which write several files)
+// to settle before reloading
+const certReloadDebounce = 500 * time.Millisecond
+
+// selfSignedValidity is how long a generated certificate is valid
+const selfSignedValidity = 2 * 365 * 24 * time.Hour
+
+// certManager serves the HTTP server's certificate and client CA pool,
+// reloading them when the files change
+type certManager struct {
+	cfg      config.TLSConfig
+	certFile string
+	keyFile  string
+
+	mu          sync.RWMutex
+	cert        *tls.Certificate
+	clientCAs   *x509.CertPool
+	fingerprint string
+
+	watcher *fsnotify.Watcher
+	timer   *time.Timer
+	stopCh  chan struct{}
+}
+
+// newCertManager loads (or generates) the certificate. With no cert files
+// configured a self-signed certificate is created under the data directory
+// and reused on later starts.
+func newCertManager(cfg config.TLSConfig, listen string) (*certManager, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, err
+	}
+
+	m := &certManager{cfg: cfg, certFile: cfg.CertFile, keyFile: cfg.KeyFile, stopCh: make(chan struct{})}
+	if m.certFile == "" {
+		certFile, keyFile, err := ensureSelfSignedCert(listen)
+		if err != nil {
+			return nil, err
+		}
+		m.certFile, m.keyFile = certFile, keyFile
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// load reads the certificate and client CA files
+func (m *certManager) load() error {
+	cert, err := tls.LoadX509KeyPair(m.certFile, m.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	cert.Leaf = leaf
+
+	var pool *x509.CertPool
+	if m.cfg.ClientCAFile != "" {
+		if pool, err = config.LoadCertPool(m.cfg.ClientCAFile); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	m.cert = &cert
+	m.clientCAs = pool
+	m.fingerprint = certFingerprint(leaf)
+	m.mu.Unlock()
+
+	logging.L_info("http: TLS certificate loaded", "subject", leaf.Subject.String(), "expires", leaf.NotAfter.Format(time.RFC3339), "fingerprint", m.fingerprint)
+	if time.Until(leaf.NotAfter) < 14*24*time.Hour {
+		logging.L_warn("http: TLS certificate expires soon", "expires", leaf.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the current certificate
+func (m *certManager) Fingerprint() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.fingerprint
+}
+
+// TLSConfig returns the server TLS config. Certificates and client CAs are
+// read per handshake, so reloads apply to new connections.
+func (m *certManager) TLSConfig() *tls.Config {
+	clientAuth := tls.NoClientCert
+	if m.cfg.ClientCAFile != "" {
+		clientAuth = tls.VerifyClientCertIfGiven
+		if m.cfg.ClientAuth == config.ClientAuthRequire {
+			clientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			m.mu.RLock()
+			defer m.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*m.cert},
+				ClientAuth:   clientAuth,
+				ClientCAs:    m.clientCAs,
+				NextProtos:   []string{"h2", "http/1.1"},
+			}, nil
+		},
+	}
+}
+
+// Watch reloads the files when they change. The parent directories are
+// watched, as renewals usually replace files (or symlinks) rather than
+// writing them in place.
+func (m *certManager) Watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	files := map[string]bool{}
+	for _, f := range []string{m.certFile, m.keyFile, m.cfg.ClientCAFile} {
+		if f == "" {
+			continue
+		}
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			abs = f
+		}
+		files[filepath.Clean(abs)] = true
+	}
+	dirs := map[string]bool{}
+	for f := range files {
+		dir := filepath.Dir(f)
+		if dirs[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+		dirs[dir] = true
+	}
+	m.watcher = watcher
+
+	go func() {
+		for {
+			select {
+			case <-m.stopCh:
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if files[filepath.Clean(event.Name)] {
+					m.scheduleReload()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logging.L_warn("http: TLS file watcher error", "error", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// scheduleReload reloads once the files have been quiet for certReloadDebounce
+func (m *certManager) scheduleReload() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.timer = time.AfterFunc(certReloadDebounce, func() {
+		if err := m.load(); err != nil {
+			// Keep serving the old certificate
+			logging.L_error("http: TLS reload failed, keeping current certificate", "error", err)
+			return
+		}
+		logging.L_info("http: TLS certificate reloaded")
+	})
+}
+
+// Stop stops watching the files
+func (m *certManager) Stop() {
+	select {
+	case <-m.stopCh:
+		return
+	default:
+	}
+	close(m.stopCh)
+	if m.watcher != nil {
+		m.watcher.Close()
+	}
+	m.mu.Lock()
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.mu.Unlock()
+}
+
+// ensureSelfSignedCert returns the self-signed certificate files, creating
+// them if missing or expired
+func ensureSelfSignedCert(listen string) (string, string, error) {
+	certFile, err := paths.DataPath(filepath.Join("tls", "selfsigned-cert.pem"))
+	if err != nil {
+		return "", "", err
+	}
+	keyFile, err := paths.DataPath(filepath.Join("tls", "selfsigned-key.pem"))
+	if err != nil {
+		return "", "", err
+	}
+
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil && time.Until(leaf.NotAfter) > 7*24*time.Hour {
+			return certFile, keyFile, nil
+		}
+		logging.L_info("http: self-signed certificate expired, generating a new one")
+	}
+
+	if err := paths.EnsureParentDir(certFile); err != nil {
+		return "", "", err
+	}
+	certPEM, keyPEM, err := generateSelfSignedCert(listen)
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return "", "", fmt.Errorf("failed to write key: %w", err)
+	}
+	if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+		return "", "", fmt.Errorf("failed to write certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	leaf, _ := x509.ParseCertificate(block.Bytes)
+	fingerprint := certFingerprint(leaf)
+	logging.L_warn("http: generated a self-signed TLS certificate; check this fingerprint when your browser or client asks",
+		"path", certFile, "sha256", fingerprint)
+	fmt.Printf("\nGenerated self-signed HTTPS certificate: %s\nSHA-256 fingerprint: %s\n\n", certFile, fingerprint)
+	return certFile, keyFile, nil
+}
+
+// generateSelfSignedCert creates an ECDSA P-256 certificate for localhost,
+// this host's name and the listen address
+func generateSelfSignedCert(listen string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate serial: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "GoClaw", Organization: []string{"GoClaw self-signed"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if hostname != "" && hostname != "localhost" {
+		template.DNSNames = append(template.DNSNames, hostname)
+	}
+	if host, _, err := net.SplitHostPort(listen); err == nil {
+		if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() && !ip.IsLoopback() {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal key: %w", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// certFingerprint formats a certificate's SHA-256 fingerprint as AA:BB:...
+func certFingerprint(cert *x509.Certificate) string {
+	if cert == nil {
+		return ""
+	}
+	sum := sha256.Sum256(cert.Raw)
+	hexSum := strings.ToUpper(hex.EncodeToString(sum[:]))
+	parts := make([]string, 0, len(sum))
+	for i := 0; i < len(hexSum); i += 2 {
+		parts = append(parts, hexSum[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
+// clientCertSubject returns the subject of a verified client certificate,
+// or "" if the request didn't present one
+func clientCertSubject(r *http.Request) (subject, commonName string) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", ""
+	}
+	leaf := r.TLS.VerifiedChains[0][0]
+	return leaf.Subject.String(), leaf.Subject.CommonName
+}
+
+// parseTrustedProxies parses IPs and CIDRs; nil means loopback only
+func parseTrustedProxies(entries []string) []*net.IPNet {
+	if len(entries) == 0 {
+		entries = []string{"127.0.0.0/8", "::1/128"}
+	}
+	var nets []*net.IPNet
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if !strings.Contains(e, "/") {
+			if ip := net.ParseIP(e); ip != nil {
+				bits := 128
+				if ip.To4() != nil {
+					bits = 32
+				}
+				e = fmt.Sprintf("%s/%d", e, bits)
+			}
+		}
+		_, n, err := net.ParseCIDR(e)
+		if err != nil {
+			logging.L_warn("http: ignoring invalid trusted proxy", "entry", e, "error", err)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// isTrustedProxy reports whether ip is one of the trusted proxies
+func isTrustedProxy(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,330 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/cron"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/user"
+)
+
+const apiTokenContextKey contextKey = "apiToken"
+
+// tokenTouchInterval limits how often a token's last-used time is saved
+// to the token usage file
+const tokenTouchInterval = time.Minute
+
+// tokenTouched holds when each token's last-used time was last saved
+var tokenTouched sync.Map // token ID -> time.Time
+
+// APITokenRow is one token on the tokens page
+type APITokenRow struct {
+	Username   string     `json:"username"`
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	Expired    bool       `json:"expired"`
+}
+
+// apiAuth authenticates an /api/* request with a Bearer API token that
+// grants scope, or falls back to basic auth. Token requests get a channel
+// session per token, so SSE/WebSocket replay works without cookies.
+func (s *Server) apiAuth(scope string, handler http.HandlerFunc) http.HandlerFunc {
+	basic := s.basicAuth(handler)
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			basic(w, r)
+			return
+		}
+
+		clientIP := s.getClientIP(r)
+		if s.rateLimiter.IsLimited(clientIP) {
+			logging.L_warn("http: rate limited", "ip", clientIP)
+			http.Error(w, "Too many failed attempts. Try again later.", http.StatusTooManyRequests)
+			return
+		}
+
+		u, tok, err := s.users.FromAPIToken(token)
+		if err != nil {
+			s.rateLimiter.RecordFailure(clientIP)
+			logging.L_warn("http: token auth failed", "ip", clientIP, "path", r.URL.Path, "error", err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="GoClaw"`)
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+		s.rateLimiter.ClearFailure(clientIP)
+
+		if !tok.HasScope(scope) {
+			logging.L_warn("http: token lacks scope", "user", u.ID, "token", tok.Name, "scope", scope, "path", r.URL.Path)
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="GoClaw", error="insufficient_scope", scope=%q`, scope))
+			http.Error(w, "Token lacks the "+scope+" scope", http.StatusForbidden)
+			return
+		}
+
+		s.touchAPIToken(u.ID, tok)
+		logging.L_trace("http: token auth success", "user", u.ID, "token", tok.Name, "path", r.URL.Path)
+
+		ctx := setUserInContext(r.Context(), u)
+		ctx = setSessionInContext(ctx, "token-"+tok.ID)
+		ctx = context.WithValue(ctx, apiTokenContextKey, tok)
+		handler(w, r.WithContext(ctx))
+	}
+}
+
+// bearerToken returns the token from an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(auth, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// getAPITokenFromContext returns the API token a request was authenticated
+// with, or nil for basic auth
+func getAPITokenFromContext(r *http.Request) *user.APIToken {
+	if t, ok := r.Context().Value(apiTokenContextKey).(*user.APIToken); ok {
+		return t
+	}
+	return nil
+}
+
+// touchAPIToken records a token's last-used time in the token usage file,
+// at most once per tokenTouchInterval per token. users.json and the
+// registry are left alone.
+func (s *Server) touchAPIToken(username string, tok *user.APIToken) {
+	now := time.Now().UTC().Truncate(time.Second)
+	if last, ok := tokenTouched.Load(tok.ID); ok && now.Sub(last.(time.Time)) < tokenTouchInterval {
+		return
+	}
+	tokenTouched.Store(tok.ID, now)
+
+	go func() {
+		if err := s.tokenUsage.Touch(tok.ID, now); err != nil {
+			logging.L_debug("http: failed to save token last-used time", "user", username, "token", tok.Name, "error", err)
+		}
+	}()
+}
+
+// editUserTokens loads users.json, applies fn to one user's entry, saves
+// and reloads the registry
+func (s *Server) editUserTokens(username string, fn func(entry *user.UserEntry) error) error {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+
+	users, err := user.LoadUsers()
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+	entry, ok := users[username]
+	if !ok {
+		return fmt.Errorf("user %q not found", username)
+	}
+	if err := fn(entry); err != nil {
+		return err
+	}
+	if err := user.SaveUsers(users, user.GetUsersFilePath()); err != nil {
+		return err
+	}
+	s.users.Reload(users)
+	return nil
+}
+
+// listAPITokens returns the tokens u can see: their own, or everyone's for the owner
+func (s *Server) listAPITokens(u *user.User) ([]APITokenRow, error) {
+	users, err := user.LoadUsers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load users: %w", err)
+	}
+	now := time.Now()
+	var rows []APITokenRow
+	for username, entry := range users {
+		if username != u.ID && !u.IsOwner() {
+			continue
+		}
+		for _, t := range entry.APITokens {
+			rows = append(rows, APITokenRow{
+				Username:   username,
+				ID:         t.ID,
+				Name:       t.Name,
+				Scopes:     t.Scopes,
+				CreatedAt:  t.CreatedAt,
+				ExpiresAt:  t.ExpiresAt,
+				LastUsedAt: s.tokenUsage.LastUsed(t),
+				Expired:    t.Expired(now),
+			})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Username != rows[j].Username {
+			return rows[i].Username < rows[j].Username
+		}
+		return rows[i].CreatedAt.Before(rows[j].CreatedAt)
+	})
+	return rows, nil
+}
+
+// handleTokens handles GET /tokens - the user's API tokens (all tokens for the owner)
+func (s *Server) handleTokens(w http.ResponseWriter, r *http.Request) {
+	if err := s.reloadTemplatesIfDev(); err != nil {
+		logging.L_error("http: template reload error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+		return
+	}
+
+	u := getUserFromContext(r)
+	if u == nil {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	data := struct {
+		Title  string
+		User   *UserTemplateData
+		Tokens []APITokenRow
+		Scopes []string
+		Error  string
+	}{
+		Title:  "GoClaw - API Tokens",
+		User:   &UserTemplateData{Name: u.Name, Username: u.ID, Role: string(u.Role), IsOwner: u.IsOwner()},
+		Scopes: user.AllScopes,
+	}
+	rows, err := s.listAPITokens(u)
+	if err != nil {
+		data.Error = err.Error()
+	}
+	data.Tokens = rows
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := s.templates.ExecuteTemplate(w, "tokens.html", data); err != nil {
+		logging.L_error("http: template error", "error", err)
+		http.Error(w, "Template error", http.StatusInternalServerError)
+	}
+}
+
+// handleTokensAPI handles /api/tokens - list, create or revoke API tokens.
+// Basic auth only: a token can't mint or revoke tokens.
+//
+//	GET                                                             - list
+//	POST {"action": "create", "name": "...", "scopes": [...], "expires": "90d"}
+//	POST {"action": "revoke", "id": "...", "username": "..."}      - username: owner only
+func (s *Server) handleTokensAPI(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromContext(r)
+	if u == nil {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		rows, err := s.listAPITokens(u)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"tokens": rows})
+		return
+	}
+
+	var req struct {
+		Action   string   `json:"action"`
+		Username string   `json:"username"`
+		ID       string   `json:"id"`
+		Name     string   `json:"name"`
+		Scopes   []string `json:"scopes"`
+		Expires  string   `json:"expires"` // Duration ("90d", "12h"); empty = never
+	}
+	if !decodeJSONPost(w, r, &req) {
+		return
+	}
+	if req.Username == "" {
+		req.Username = u.ID
+	}
+	if req.Username != u.ID && !u.IsOwner() {
+		http.Error(w, "Forbidden - not your token", http.StatusForbidden)
+		return
+	}
+
+	var plain string
+	var tok *user.APIToken
+	var err error
+	switch req.Action {
+	case "create":
+		var scopes []string
+		var ttl time.Duration
+		scopes, err = user.ParseScopes(strings.Join(req.Scopes, ","))
+		if err == nil && req.Expires != "" {
+			ttl, err = cron.ParseDuration(req.Expires)
+		}
+		if err == nil {
+			err = s.editUserTokens(req.Username, func(entry *user.UserEntry) error {
+				var addErr error
+				plain, tok, addErr = entry.AddAPIToken(req.Name, scopes, ttl)
+				return addErr
+			})
+		}
+	case "revoke":
+		err = s.editUserTokens(req.Username, func(entry *user.UserEntry) error {
+			var revokeErr error
+			tok, revokeErr = entry.RevokeAPIToken(req.ID)
+			return revokeErr
+		})
+		if err == nil {
+			tokenTouched.Delete(tok.ID)
+			if forgetErr := s.tokenUsage.Forget(tok.ID); forgetErr != nil {
+				logging.L_debug("http: failed to drop token last-used time", "token", tok.Name, "error", forgetErr)
+			}
+		}
+	default:
+		http.Error(w, "Unknown action: "+req.Action, http.StatusBadRequest)
+		return
+	}
+
+	inputs := map[string]any{"username": req.Username, "name": req.Name, "id": req.ID}
+	if tok != nil {
+		inputs["id"] = tok.ID
+		inputs["name"] = tok.Name
+		inputs["scopes"] = tok.Scopes
+	}
+	recordWebAudit(r, u, "token."+req.Action, inputs, err)
+	if err != nil {
+		logging.L_warn("http: token action failed", "action", req.Action, "username", req.Username, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logging.L_info("http: token action", "action", req.Action, "username", req.Username, "token", tok.Name, "by", u.ID)
+
+	resp := map[string]any{"status": "ok", "id": tok.ID, "name": tok.Name}
+	if plain != "" {
+		// Shown once
+		resp["token"] = plain
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// webhookTokenAllowed reports whether a request carries a token with the
+// webhook scope, which fires webhook jobs without the per-job secret.
+// Only the owner's tokens qualify, as the owner manages cron jobs.
+func (s *Server) webhookTokenAllowed(r *http.Request) bool {
+	token, ok := bearerToken(r)
+	if !ok {
+		return false
+	}
+	u, tok, err := s.users.FromAPIToken(token)
+	if err != nil || !u.IsOwner() || !tok.HasScope(user.ScopeWebhook) {
+		return false
+	}
+	s.touchAPIToken(u.ID, tok)
+	return true
+}
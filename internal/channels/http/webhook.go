@@ -0,0 +1,66 @@
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/roelfdiedericks/goclaw/internal/cron"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// maxWebhookBody limits the request body passed to a webhook-triggered job
+const maxWebhookBody = 64 * 1024
+
+// handleCronWebhook fires a cron job with a "webhook" trigger.
+// POST /api/cron/webhook/<jobID> with the job's secret in X-Webhook-Secret,
+// or an owner API token with the webhook scope as a Bearer header. The secret
+// isn't accepted in the query string, which ends up in access logs.
+// Not behind basic auth: the per-job secret authenticates the caller.
+func (s *Server) handleCronWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clientIP := s.getClientIP(r)
+	if s.rateLimiter.IsLimited(clientIP) {
+		logging.L_warn("http: webhook rate limited", "ip", clientIP)
+		http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	service := cron.GetService()
+	if service == nil || !service.IsRunning() {
+		http.Error(w, "Cron service not running", http.StatusServiceUnavailable)
+		return
+	}
+
+	jobID := strings.TrimPrefix(r.URL.Path, "/api/cron/webhook/")
+	secret := r.Header.Get("X-Webhook-Secret")
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	if s.webhookTokenAllowed(r) {
+		err = service.TriggerWebhook(jobID, body)
+	} else {
+		err = service.HandleWebhook(jobID, secret, body)
+	}
+	if err != nil {
+		// Same response for unknown job and bad secret, so IDs can't be probed
+		s.rateLimiter.RecordFailure(clientIP)
+		logging.L_warn("http: webhook rejected", "job", jobID, "ip", clientIP, "error", err)
+		http.Error(w, "Webhook rejected", http.StatusForbidden)
+		return
+	}
+
+	logging.L_info("http: webhook accepted", "job", jobID, "ip", clientIP, "bodyLen", len(body))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "accepted"}) //nolint:errcheck
+}
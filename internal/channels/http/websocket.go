@@ -0,0 +1,324 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/user"
+)
+
+// WebSocket protocol (see docs/websocket-api.md). Bump wsProtocolVersion on
+// incompatible frame changes; clients may request it as a subprotocol.
+const (
+	wsProtocolVersion = 1
+	wsSubprotocol     = "goclaw.v1"
+
+	wsWriteWait   = 10 * time.Second
+	wsPongWait    = 60 * time.Second
+	wsPingPeriod  = 25 * time.Second
+	wsMaxFrameLen = 16 << 20 // Attachments are base64 in the frame
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	Subprotocols:    []string{wsSubprotocol},
+	// Default CheckOrigin: browsers must connect from this host (the basic
+	// auth credentials and session cookie would otherwise be usable
+	// cross-site). Native clients send no Origin header.
+}
+
+// wsInbound is a frame from the client
+type wsInbound struct {
+	Type        string      `json:"type"` // message, callback, cancel, stop, typing, ping
+	ID          string      `json:"id,omitempty"`
+	Session     string      `json:"session,omitempty"` // Gateway session key ("" = the user's default)
+	Text        string      `json:"text,omitempty"`
+	Attachments []chatImage `json:"attachments,omitempty"`
+	Data        string      `json:"data,omitempty"`   // Button callback data
+	Active      bool        `json:"active,omitempty"` // Typing state
+}
+
+// wsOutbound is a frame to the client
+type wsOutbound struct {
+	Type    string `json:"type"`         // hello, event, ack, typing, error, pong
+	ID      string `json:"id,omitempty"` // ID of the client frame this answers
+	Event   string `json:"event,omitempty"`
+	EventID int    `json:"eventId,omitempty"`
+	Data    any    `json:"data,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// handleWebSocket handles GET /api/ws - the bidirectional chat API.
+// It carries the same events as /api/events, plus client frames for
+// messages, button callbacks, cancel/stop and typing, each acknowledged.
+// ?lastEventId=N replays buffered events after N, like SSE's Last-Event-ID.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	u := getUserFromContext(r)
+	if u == nil {
+		http.Error(w, "Not authenticated", http.StatusUnauthorized)
+		return
+	}
+	if s.channel == nil {
+		http.Error(w, "Server not ready", http.StatusInternalServerError)
+		return
+	}
+	sessionID := getSessionFromContext(r)
+	if sessionID == "" {
+		http.Error(w, "No session", http.StatusInternalServerError)
+		return
+	}
+
+	lastEventID := 0
+	if v := r.URL.Query().Get("lastEventId"); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil {
+			lastEventID = parsed
+		}
+	}
+
+	ws, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade has already written the error response
+		logging.L_warn("http: websocket upgrade failed", "user", u.ID, "error", err)
+		return
+	}
+	defer ws.Close()
+	ws.SetReadLimit(wsMaxFrameLen)
+
+	sess, conn, replay := s.channel.RegisterConnection(sessionID, u, lastEventID)
+	defer s.channel.UnregisterConnection(sessionID, conn)
+	logging.L_info("http: websocket connection opened", "user", u.ID, "session", sessionID[:8]+"...", "lastEventID", lastEventID)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Replies to client frames are written by this goroutine only
+	// (a websocket.Conn supports one concurrent writer)
+	replies := make(chan wsOutbound, 16)
+	readerDone := make(chan struct{})
+	go func() {
+		defer close(readerDone)
+		s.wsReadLoop(ctx, ws, sess, u, replies)
+	}()
+
+	sess.bufferMu.Lock()
+	currentEventID := sess.nextEventID - 1
+	sess.bufferMu.Unlock()
+	hello := wsOutbound{Type: "hello", Data: map[string]any{
+		"protocol":      wsProtocolVersion,
+		"user":          u.ID,
+		"session":       defaultSessionKey(u),
+		"lastEventId":   currentEventID,
+		"thinking":      sess.ShowThinking,
+		"thinkingLevel": sess.ThinkingLevel,
+	}}
+	if err := wsWrite(ws, hello); err != nil {
+		return
+	}
+	for _, buffered := range replay {
+		if err := s.wsWriteEvent(ws, buffered.Event); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-readerDone:
+			logging.L_debug("http: websocket connection closed", "user", u.ID, "session", sessionID[:8]+"...")
+			return
+		case <-conn.Done:
+			logging.L_info("http: websocket connection replaced", "user", u.ID, "session", sessionID[:8]+"...")
+			ws.WriteControl(websocket.CloseMessage, //nolint:errcheck
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, "replaced by a newer connection"),
+				time.Now().Add(wsWriteWait))
+			return
+		case event := <-conn.Events:
+			if err := s.wsWriteEvent(ws, event); err != nil {
+				return
+			}
+		case reply := <-replies:
+			if err := wsWrite(ws, reply); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// wsReadLoop reads client frames until the connection closes
+func (s *Server) wsReadLoop(ctx context.Context, ws *websocket.Conn, sess *SSESession, u *user.User, replies chan<- wsOutbound) {
+	ws.SetReadDeadline(time.Now().Add(wsPongWait)) //nolint:errcheck
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var frame wsInbound
+		var reply *wsOutbound
+		if err := ws.ReadJSON(&frame); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) {
+				if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					logging.L_debug("http: websocket read error", "user", u.ID, "error", err)
+				}
+				return
+			}
+			// Bad frame, keep the connection
+			reply = &wsOutbound{Type: "error", Error: "invalid frame: " + err.Error()}
+		} else {
+			reply = s.handleWSFrame(ctx, sess, u, frame)
+		}
+		// Any frame counts as activity
+		ws.SetReadDeadline(time.Now().Add(wsPongWait)) //nolint:errcheck
+
+		if reply != nil {
+			select {
+			case replies <- *reply:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// handleWSFrame acts on one client frame and returns the reply, if any
+func (s *Server) handleWSFrame(ctx context.Context, sess *SSESession, u *user.User, frame wsInbound) *wsOutbound {
+	fail := func(err error) *wsOutbound {
+		return &wsOutbound{Type: "error", ID: frame.ID, Error: err.Error()}
+	}
+
+	switch frame.Type {
+	case "message", "callback":
+		text := frame.Text
+		if frame.Type == "callback" {
+			// A button press is the user's reply to the agent
+			if frame.Data == "" {
+				return fail(fmt.Errorf("callback requires data"))
+			}
+			text = frame.Data
+		}
+		result, err := s.sendChatMessage(ctx, sess.SessionID, u, frame.Session, text, frame.Attachments)
+		if err != nil {
+			return fail(err)
+		}
+		return &wsOutbound{Type: "ack", ID: frame.ID, Data: result}
+
+	case "cancel":
+		cancelled, err := s.cancelChatRun(u, frame.Session)
+		if err != nil {
+			return fail(err)
+		}
+		message := "Nothing running."
+		if cancelled {
+			message = "Cancelled."
+		}
+		return &wsOutbound{Type: "ack", ID: frame.ID, Data: &sendResult{Status: "ok", Message: message}}
+
+	case "stop":
+		// Same as the panic phrase: stop every run of this user
+		if s.channel.gateway == nil {
+			return fail(fmt.Errorf("gateway not configured"))
+		}
+		n, err := s.channel.gateway.StopAllUserSessions(u.ID)
+		if err != nil {
+			return fail(err)
+		}
+		return &wsOutbound{Type: "ack", ID: frame.ID, Data: &sendResult{Status: "ok", Message: fmt.Sprintf("Stopped %d tasks.", n)}}
+
+	case "typing":
+		// Relay to the user's other connections (other tabs and devices)
+		typing := SSEEvent{Event: "typing", Data: map[string]any{
+			"from":    "user",
+			"user":    u.ID,
+			"session": frame.Session,
+			"active":  frame.Active,
+		}}
+		for _, other := range s.channel.getSessionsForUser(u) {
+			if other != sess {
+				other.SendTransient(typing)
+			}
+		}
+		return nil
+
+	case "ping":
+		return &wsOutbound{Type: "pong", ID: frame.ID}
+
+	default:
+		return fail(fmt.Errorf("unknown frame type %q", frame.Type))
+	}
+}
+
+// cancelChatRun cancels the running agent turn of one of the user's
+// sessions ("" = the default). It reports whether a run was cancelled.
+func (s *Server) cancelChatRun(u *user.User, sessionKey string) (bool, error) {
+	if sessionKey == "" {
+		sessionKey = defaultSessionKey(u)
+	}
+	if !ownsSessionKey(u, sessionKey) {
+		return false, errNotYourSession
+	}
+	gw, ok := s.channel.gateway.(SupervisionGateway)
+	if !ok {
+		return false, fmt.Errorf("gateway does not support cancelling a single session")
+	}
+	sess := gw.SessionManager().GetIfExists(sessionKey)
+	if sess == nil || !sess.IsRunning() {
+		return false, nil
+	}
+	sess.Cancel()
+	logging.L_info("http: agent run cancelled", "user", u.ID, "session", sessionKey)
+	return true, nil
+}
+
+// wsWriteEvent writes a channel event, plus an agent typing frame for
+// events that start or end a run
+func (s *Server) wsWriteEvent(ws *websocket.Conn, event SSEEvent) error {
+	if event.Event == "typing" {
+		// Already a typing indicator (relayed from another connection)
+		return wsWrite(ws, wsOutbound{Type: "typing", Data: event.Data})
+	}
+	if err := wsWrite(ws, wsOutbound{Type: "event", Event: event.Event, EventID: event.ID, Data: event.Data}); err != nil {
+		return err
+	}
+
+	var active bool
+	switch event.Event {
+	case "start":
+		active = true
+	case "done", "agent_error":
+		active = false
+	default:
+		return nil
+	}
+	typing := map[string]any{"from": "agent", "active": active}
+	if active && s.channel.gateway != nil {
+		if identity := s.channel.gateway.AgentIdentity(); identity != nil {
+			typing["text"] = identity.TypingText()
+		}
+	}
+	return wsWrite(ws, wsOutbound{Type: "typing", Data: typing})
+}
+
+// wsWrite writes one JSON frame with a write deadline
+func wsWrite(ws *websocket.Conn, frame wsOutbound) error {
+	ws.SetWriteDeadline(time.Now().Add(wsWriteWait)) //nolint:errcheck
+	if err := ws.WriteJSON(frame); err != nil {
+		logging.L_debug("http: websocket write failed", "type", frame.Type, "error", err)
+		return err
+	}
+	return nil
+}
@@ -7,8 +7,16 @@ import (
 	"time"
 
 	"github.com/roelfdiedericks/goclaw/internal/bus"
+	"github.com/roelfdiedericks/goclaw/internal/channels/bridge"
+	bridgeconfig "github.com/roelfdiedericks/goclaw/internal/channels/bridge/config"
+	"github.com/roelfdiedericks/goclaw/internal/channels/discord"
+	discordconfig "github.com/roelfdiedericks/goclaw/internal/channels/discord/config"
+	"github.com/roelfdiedericks/goclaw/internal/channels/email"
+	emailconfig "github.com/roelfdiedericks/goclaw/internal/channels/email/config"
 	"github.com/roelfdiedericks/goclaw/internal/channels/http"
 	httpconfig "github.com/roelfdiedericks/goclaw/internal/channels/http/config"
+	"github.com/roelfdiedericks/goclaw/internal/channels/matrix"
+	matrixconfig "github.com/roelfdiedericks/goclaw/internal/channels/matrix/config"
 	"github.com/roelfdiedericks/goclaw/internal/channels/telegram"
 	telegramconfig "github.com/roelfdiedericks/goclaw/internal/channels/telegram/config"
 	"github.com/roelfdiedericks/goclaw/internal/channels/tui"
@@ -49,11 +57,34 @@ type Manager struct {
 	telegramRetrying bool
 	telegramCancel   context.CancelFunc
 
+	// Additional named Telegram bots (channel "telegram:<name>")
+	telegramNamed       map[string]*telegram.Bot
+	telegramNamedCancel context.CancelFunc
+
 	// WhatsApp-specific: bot instance and retry state
 	whatsappBot      *whatsapp.Bot
 	whatsappRetrying bool
 	whatsappCancel   context.CancelFunc
 
+	// Matrix-specific: bot instance and retry state
+	matrixBot      *matrix.Bot
+	matrixRetrying bool
+	matrixCancel   context.CancelFunc
+
+	// Discord-specific: bot instance and retry state
+	discordBot      *discord.Bot
+	discordRetrying bool
+	discordCancel   context.CancelFunc
+
+	// Email-specific: bot instance and retry state
+	emailBot      *email.Bot
+	emailRetrying bool
+	emailCancel   context.CancelFunc
+
+	// Bridge channels, keyed by bridge name (also their channel name)
+	bridges       map[string]*bridge.Bot
+	bridgesCancel context.CancelFunc
+
 	// HTTP server instance
 	httpServer *http.Server
 
@@ -84,6 +115,7 @@ func (m *Manager) StartAll(ctx context.Context, cfg config.ChannelsConfig, opts
 			logging.L_warn("telegram: initial start failed, will retry in background", "error", err)
 			m.startTelegramRetry(ctx, &cfg.Telegram)
 		}
+		m.startTelegramNamed(ctx, &cfg.Telegram)
 	} else {
 		logging.L_info("telegram: disabled by configuration")
 	}
@@ -98,6 +130,43 @@ func (m *Manager) StartAll(ctx context.Context, cfg config.ChannelsConfig, opts
 		logging.L_info("whatsapp: disabled by configuration")
 	}
 
+	// Start Matrix if enabled
+	if cfg.Matrix.Enabled {
+		if err := m.startMatrix(ctx, &cfg.Matrix); err != nil {
+			logging.L_warn("matrix: initial start failed, will retry in background", "error", err)
+			m.startMatrixRetry(ctx, &cfg.Matrix)
+		}
+	} else {
+		logging.L_info("matrix: disabled by configuration")
+	}
+
+	// Start Discord if enabled
+	if cfg.Discord.Enabled {
+		if err := m.startDiscord(ctx, &cfg.Discord); err != nil {
+			logging.L_warn("discord: initial start failed, will retry in background", "error", err)
+			m.startDiscordRetry(ctx, &cfg.Discord)
+		}
+	} else {
+		logging.L_info("discord: disabled by configuration")
+	}
+
+	// Start email if enabled
+	if cfg.Email.Enabled {
+		if err := m.startEmail(ctx, &cfg.Email); err != nil {
+			logging.L_warn("email: initial start failed, will retry in background", "error", err)
+			m.startEmailRetry(ctx, &cfg.Email)
+		}
+	} else {
+		logging.L_info("email: disabled by configuration")
+	}
+
+	// Start bridges if enabled
+	if cfg.Bridge.Enabled {
+		m.startBridges(ctx, &cfg.Bridge)
+	} else {
+		logging.L_info("bridge: disabled by configuration")
+	}
+
 	// Start HTTP if enabled (default: true)
 	httpEnabled := cfg.HTTP.Enabled == nil || *cfg.HTTP.Enabled
 	if httpEnabled {
@@ -141,6 +210,94 @@ func (m *Manager) startTelegram(ctx context.Context, cfg *telegramconfig.Config)
 	return nil
 }
 
+// startTelegramNamed starts the additional named Telegram bots in the background.
+// Each bot retries independently until it connects or the manager stops them.
+func (m *Manager) startTelegramNamed(ctx context.Context, cfg *telegramconfig.Config) {
+	if len(cfg.Bots) == 0 {
+		return
+	}
+
+	namedCtx, cancel := context.WithCancel(ctx)
+	m.mu.Lock()
+	m.telegramNamedCancel = cancel
+	m.mu.Unlock()
+
+	for _, botCfg := range cfg.Bots {
+		if botCfg.Name == "" || botCfg.BotToken == "" {
+			logging.L_warn("telegram: skipping named bot without name or token", "name", botCfg.Name)
+			continue
+		}
+		go m.runTelegramNamed(namedCtx, botCfg)
+	}
+}
+
+// runTelegramNamed connects one named bot, retrying with backoff on failure
+func (m *Manager) runTelegramNamed(ctx context.Context, botCfg telegramconfig.BotConfig) {
+	backoff := 5 * time.Second
+	maxBackoff := 5 * time.Minute
+	channelName := "telegram:" + botCfg.Name
+
+	for attempt := 1; ; attempt++ {
+		bot, err := telegram.NewNamed(botCfg.Name, &telegramconfig.Config{Enabled: true, BotToken: botCfg.BotToken}, m.gw, m.users)
+		if err == nil {
+			err = bot.Start(ctx)
+		}
+		if err == nil {
+			m.mu.Lock()
+			if ctx.Err() != nil {
+				// Stopped or reloaded while connecting
+				m.mu.Unlock()
+				_ = bot.Stop()
+				return
+			}
+			if m.telegramNamed == nil {
+				m.telegramNamed = make(map[string]*telegram.Bot)
+			}
+			m.telegramNamed[botCfg.Name] = bot
+			m.channels[channelName] = bot
+			m.mu.Unlock()
+
+			bot.RegisterOperationalCommands()
+			m.gw.RegisterChannel(bot)
+			bus.PublishEvent("channels.telegram.named.started", botCfg.Name)
+			logging.L_info("telegram: named bot ready and listening", "name", botCfg.Name, "attempts", attempt)
+			return
+		}
+
+		logging.L_warn("telegram: named bot connection failed", "name", botCfg.Name, "error", err, "nextRetry", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// stopTelegramNamed stops all named Telegram bots
+func (m *Manager) stopTelegramNamed() {
+	m.mu.Lock()
+	if m.telegramNamedCancel != nil {
+		m.telegramNamedCancel()
+		m.telegramNamedCancel = nil
+	}
+	named := m.telegramNamed
+	m.telegramNamed = nil
+	for name := range named {
+		delete(m.channels, "telegram:"+name)
+	}
+	m.mu.Unlock()
+
+	for name, bot := range named {
+		_ = bot.Stop()
+		m.gw.UnregisterChannel(bot.Name())
+		bus.PublishEvent("channels.telegram.named.stopped", name)
+	}
+}
+
 // startTelegramRetry starts background retry for telegram connection
 func (m *Manager) startTelegramRetry(ctx context.Context, cfg *telegramconfig.Config) {
 	m.mu.Lock()
@@ -293,6 +450,421 @@ func (m *Manager) reloadWhatsApp(cfg *whatsappconfig.Config) {
 	}
 }
 
+// startMatrix creates and starts the Matrix bot
+func (m *Manager) startMatrix(ctx context.Context, cfg *matrixconfig.Config) error {
+	bot, err := matrix.New(cfg, m.gw, m.users)
+	if err != nil {
+		return err
+	}
+
+	if err := bot.Start(ctx); err != nil {
+		return err
+	}
+
+	bot.RegisterOperationalCommands()
+	m.gw.RegisterChannel(bot)
+
+	m.mu.Lock()
+	m.matrixBot = bot
+	m.channels["matrix"] = bot
+	m.mu.Unlock()
+
+	bus.PublishEvent("channels.matrix.started", nil)
+
+	logging.L_info("matrix: channel ready and listening")
+	return nil
+}
+
+// startMatrixRetry starts background retry for the matrix connection
+func (m *Manager) startMatrixRetry(ctx context.Context, cfg *matrixconfig.Config) {
+	m.mu.Lock()
+	if m.matrixRetrying {
+		m.mu.Unlock()
+		return
+	}
+	m.matrixRetrying = true
+	retryCtx, cancel := context.WithCancel(ctx)
+	m.matrixCancel = cancel
+	m.mu.Unlock()
+
+	go func() {
+		backoff := 5 * time.Second
+		maxBackoff := 5 * time.Minute
+		attempt := 1
+
+		for {
+			select {
+			case <-retryCtx.Done():
+				logging.L_info("matrix: shutdown requested, stopping retry")
+				return
+			case <-time.After(backoff):
+			}
+
+			logging.L_info("matrix: retrying connection", "attempt", attempt, "backoff", backoff)
+
+			if err := m.startMatrix(retryCtx, cfg); err != nil {
+				logging.L_warn("matrix: connection failed", "error", err, "nextRetry", backoff)
+				attempt++
+				backoff *= 2
+				if backoff > maxBackoff {
+					backoff = maxBackoff
+				}
+				continue
+			}
+
+			m.mu.Lock()
+			m.matrixRetrying = false
+			m.mu.Unlock()
+			logging.L_info("matrix: channel ready after retry", "attempts", attempt)
+			return
+		}
+	}()
+}
+
+// reloadMatrix handles matrix config changes
+func (m *Manager) reloadMatrix(cfg *matrixconfig.Config) {
+	m.mu.Lock()
+	bot := m.matrixBot
+	m.mu.Unlock()
+
+	if m.matrixCancel != nil {
+		m.matrixCancel()
+	}
+
+	if bot != nil {
+		logging.L_info("matrix: stopping for config reload")
+		_ = bot.Stop()
+		m.gw.UnregisterChannel("matrix")
+		m.mu.Lock()
+		m.matrixBot = nil
+		delete(m.channels, "matrix")
+		m.mu.Unlock()
+		bus.PublishEvent("channels.matrix.stopped", nil)
+	}
+
+	if !cfg.Enabled {
+		logging.L_info("matrix: disabled by new config")
+		return
+	}
+
+	if err := m.startMatrix(m.ctx, cfg); err != nil {
+		logging.L_error("matrix: failed to start with new config", "error", err)
+		m.startMatrixRetry(m.ctx, cfg)
+	} else {
+		logging.L_info("matrix: reloaded with new config")
+	}
+}
+
+// startDiscord creates and starts the Discord bot
+func (m *Manager) startDiscord(ctx context.Context, cfg *discordconfig.Config) error {
+	bot, err := discord.New(cfg, m.gw, m.users)
+	if err != nil {
+		return err
+	}
+
+	if err := bot.Start(ctx); err != nil {
+		return err
+	}
+
+	bot.RegisterOperationalCommands()
+	m.gw.RegisterChannel(bot)
+
+	m.mu.Lock()
+	m.discordBot = bot
+	m.channels["discord"] = bot
+	m.mu.Unlock()
+
+	bus.PublishEvent("channels.discord.started", nil)
+
+	logging.L_info("discord: channel ready and listening")
+	return nil
+}
+
+// startDiscordRetry starts background retry for the discord connection
+func (m *Manager) startDiscordRetry(ctx context.Context, cfg *discordconfig.Config) {
+	m.mu.Lock()
+	if m.discordRetrying {
+		m.mu.Unlock()
+		return
+	}
+	m.discordRetrying = true
+	retryCtx, cancel := context.WithCancel(ctx)
+	m.discordCancel = cancel
+	m.mu.Unlock()
+
+	go func() {
+		backoff := 5 * time.Second
+		maxBackoff := 5 * time.Minute
+		attempt := 1
+
+		for {
+			select {
+			case <-retryCtx.Done():
+				logging.L_info("discord: shutdown requested, stopping retry")
+				return
+			case <-time.After(backoff):
+			}
+
+			logging.L_info("discord: retrying connection", "attempt", attempt, "backoff", backoff)
+
+			if err := m.startDiscord(retryCtx, cfg); err != nil {
+				logging.L_warn("discord: connection failed", "error", err, "nextRetry", backoff)
+				attempt++
+				backoff *= 2
+				if backoff > maxBackoff {
+					backoff = maxBackoff
+				}
+				continue
+			}
+
+			m.mu.Lock()
+			m.discordRetrying = false
+			m.mu.Unlock()
+			logging.L_info("discord: channel ready after retry", "attempts", attempt)
+			return
+		}
+	}()
+}
+
+// reloadDiscord handles discord config changes
+func (m *Manager) reloadDiscord(cfg *discordconfig.Config) {
+	m.mu.Lock()
+	bot := m.discordBot
+	m.mu.Unlock()
+
+	if m.discordCancel != nil {
+		m.discordCancel()
+	}
+
+	if bot != nil {
+		logging.L_info("discord: stopping for config reload")
+		_ = bot.Stop()
+		m.gw.UnregisterChannel("discord")
+		m.mu.Lock()
+		m.discordBot = nil
+		delete(m.channels, "discord")
+		m.mu.Unlock()
+		bus.PublishEvent("channels.discord.stopped", nil)
+	}
+
+	if !cfg.Enabled {
+		logging.L_info("discord: disabled by new config")
+		return
+	}
+
+	if err := m.startDiscord(m.ctx, cfg); err != nil {
+		logging.L_error("discord: failed to start with new config", "error", err)
+		m.startDiscordRetry(m.ctx, cfg)
+	} else {
+		logging.L_info("discord: reloaded with new config")
+	}
+}
+
+// startEmail creates and starts the email bot
+func (m *Manager) startEmail(ctx context.Context, cfg *emailconfig.Config) error {
+	bot, err := email.New(cfg, m.gw, m.users)
+	if err != nil {
+		return err
+	}
+
+	if err := bot.Start(ctx); err != nil {
+		return err
+	}
+
+	bot.RegisterOperationalCommands()
+	m.gw.RegisterChannel(bot)
+
+	m.mu.Lock()
+	m.emailBot = bot
+	m.channels["email"] = bot
+	m.mu.Unlock()
+
+	bus.PublishEvent("channels.email.started", nil)
+
+	logging.L_info("email: channel ready and listening")
+	return nil
+}
+
+// startEmailRetry starts background retry for the email connection
+func (m *Manager) startEmailRetry(ctx context.Context, cfg *emailconfig.Config) {
+	m.mu.Lock()
+	if m.emailRetrying {
+		m.mu.Unlock()
+		return
+	}
+	m.emailRetrying = true
+	retryCtx, cancel := context.WithCancel(ctx)
+	m.emailCancel = cancel
+	m.mu.Unlock()
+
+	go func() {
+		backoff := 5 * time.Second
+		maxBackoff := 5 * time.Minute
+		attempt := 1
+
+		for {
+			select {
+			case <-retryCtx.Done():
+				logging.L_info("email: shutdown requested, stopping retry")
+				return
+			case <-time.After(backoff):
+			}
+
+			logging.L_info("email: retrying connection", "attempt", attempt, "backoff", backoff)
+
+			if err := m.startEmail(retryCtx, cfg); err != nil {
+				logging.L_warn("email: connection failed", "error", err, "nextRetry", backoff)
+				attempt++
+				backoff *= 2
+				if backoff > maxBackoff {
+					backoff = maxBackoff
+				}
+				continue
+			}
+
+			m.mu.Lock()
+			m.emailRetrying = false
+			m.mu.Unlock()
+			logging.L_info("email: channel ready after retry", "attempts", attempt)
+			return
+		}
+	}()
+}
+
+// reloadEmail handles email config changes
+func (m *Manager) reloadEmail(cfg *emailconfig.Config) {
+	m.mu.Lock()
+	bot := m.emailBot
+	m.mu.Unlock()
+
+	if m.emailCancel != nil {
+		m.emailCancel()
+	}
+
+	if bot != nil {
+		logging.L_info("email: stopping for config reload")
+		_ = bot.Stop()
+		m.gw.UnregisterChannel("email")
+		m.mu.Lock()
+		m.emailBot = nil
+		delete(m.channels, "email")
+		m.mu.Unlock()
+		bus.PublishEvent("channels.email.stopped", nil)
+	}
+
+	if !cfg.Enabled {
+		logging.L_info("email: disabled by new config")
+		return
+	}
+
+	if err := m.startEmail(m.ctx, cfg); err != nil {
+		logging.L_error("email: failed to start with new config", "error", err)
+		m.startEmailRetry(m.ctx, cfg)
+	} else {
+		logging.L_info("email: reloaded with new config")
+	}
+}
+
+// startBridges starts the configured bridges in the background.
+// Each bridge retries independently until it connects or the manager stops them.
+func (m *Manager) startBridges(ctx context.Context, cfg *bridgeconfig.Config) {
+	if len(cfg.Bridges) == 0 {
+		logging.L_info("bridge: enabled but no bridges configured")
+		return
+	}
+	if err := cfg.Check(); err != nil {
+		logging.L_error("bridge: invalid configuration", "error", err)
+		return
+	}
+
+	bridgesCtx, cancel := context.WithCancel(ctx)
+	m.mu.Lock()
+	m.bridgesCancel = cancel
+	m.mu.Unlock()
+
+	for _, bridgeCfg := range cfg.Bridges {
+		go m.runBridge(bridgesCtx, bridgeCfg)
+	}
+}
+
+// runBridge connects one bridge, retrying with backoff on failure
+func (m *Manager) runBridge(ctx context.Context, bridgeCfg bridgeconfig.BridgeConfig) {
+	backoff := 5 * time.Second
+	maxBackoff := 5 * time.Minute
+
+	for attempt := 1; ; attempt++ {
+		bot, err := bridge.New(&bridgeCfg, m.gw, m.users)
+		if err == nil {
+			err = bot.Start(ctx)
+		}
+		if err == nil {
+			m.mu.Lock()
+			if ctx.Err() != nil {
+				// Stopped or reloaded while connecting
+				m.mu.Unlock()
+				_ = bot.Stop()
+				return
+			}
+			if m.bridges == nil {
+				m.bridges = make(map[string]*bridge.Bot)
+			}
+			m.bridges[bridgeCfg.Name] = bot
+			m.channels[bridgeCfg.Name] = bot
+			m.mu.Unlock()
+
+			bot.RegisterOperationalCommands()
+			m.gw.RegisterChannel(bot)
+			bus.PublishEvent("channels.bridge.started", bridgeCfg.Name)
+			logging.L_info("bridge: channel ready and listening", "bridge", bridgeCfg.Name, "attempts", attempt)
+			return
+		}
+
+		logging.L_warn("bridge: connection failed", "bridge", bridgeCfg.Name, "error", err, "nextRetry", backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// stopBridges stops all bridges
+func (m *Manager) stopBridges() {
+	m.mu.Lock()
+	if m.bridgesCancel != nil {
+		m.bridgesCancel()
+		m.bridgesCancel = nil
+	}
+	bridges := m.bridges
+	m.bridges = nil
+	for name := range bridges {
+		delete(m.channels, name)
+	}
+	m.mu.Unlock()
+
+	for name, bot := range bridges {
+		_ = bot.Stop()
+		m.gw.UnregisterChannel(bot.Name())
+		bus.PublishEvent("channels.bridge.stopped", name)
+	}
+}
+
+// reloadBridges handles bridge config changes by restarting all bridges
+func (m *Manager) reloadBridges(cfg *bridgeconfig.Config) {
+	logging.L_info("bridge: restarting for config reload")
+	m.stopBridges()
+
+	if !cfg.Enabled {
+		logging.L_info("bridge: disabled by new config")
+		return
+	}
+	m.startBridges(m.ctx, cfg)
+}
+
 // startHTTP creates and starts the HTTP server
 func (m *Manager) startHTTP(ctx context.Context, cfg *httpconfig.Config) error {
 	listen := cfg.Listen
@@ -301,9 +873,11 @@ func (m *Manager) startHTTP(ctx context.Context, cfg *httpconfig.Config) error {
 	}
 
 	serverCfg := &http.ServerConfig{
-		Listen:    listen,
-		DevMode:   m.opts.DevMode,
-		MediaRoot: "",
+		Listen:         listen,
+		DevMode:        m.opts.DevMode,
+		MediaRoot:      "",
+		TLS:            cfg.TLS,
+		TrustedProxies: cfg.TrustedProxies,
 	}
 
 	if m.gw.MediaStore() != nil {
@@ -333,9 +907,9 @@ func (m *Manager) startHTTP(ctx context.Context, cfg *httpconfig.Config) error {
 	bus.PublishEvent("channels.http.started", nil)
 
 	if m.opts.DevMode {
-		logging.L_info("http: server started (dev mode)", "listen", listen)
+		logging.L_info("http: server started (dev mode)", "listen", listen, "tls", cfg.TLS.Enabled)
 	} else {
-		logging.L_info("http: server started", "listen", listen)
+		logging.L_info("http: server started", "listen", listen, "tls", cfg.TLS.Enabled)
 	}
 	return nil
 }
@@ -362,6 +936,46 @@ func (m *Manager) subscribeConfigEvents() {
 		m.reloadWhatsApp(cfg)
 	})
 
+	// Matrix config reload
+	bus.SubscribeEvent("channels.matrix.config.applied", func(event bus.Event) {
+		cfg, ok := event.Data.(*matrixconfig.Config)
+		if !ok {
+			logging.L_error("matrix: invalid config event data")
+			return
+		}
+		m.reloadMatrix(cfg)
+	})
+
+	// Discord config reload
+	bus.SubscribeEvent("channels.discord.config.applied", func(event bus.Event) {
+		cfg, ok := event.Data.(*discordconfig.Config)
+		if !ok {
+			logging.L_error("discord: invalid config event data")
+			return
+		}
+		m.reloadDiscord(cfg)
+	})
+
+	// Email config reload
+	bus.SubscribeEvent("channels.email.config.applied", func(event bus.Event) {
+		cfg, ok := event.Data.(*emailconfig.Config)
+		if !ok {
+			logging.L_error("email: invalid config event data")
+			return
+		}
+		m.reloadEmail(cfg)
+	})
+
+	// Bridge config reload
+	bus.SubscribeEvent("channels.bridge.config.applied", func(event bus.Event) {
+		cfg, ok := event.Data.(*bridgeconfig.Config)
+		if !ok {
+			logging.L_error("bridge: invalid config event data")
+			return
+		}
+		m.reloadBridges(cfg)
+	})
+
 	// HTTP config reload
 	bus.SubscribeEvent("channels.http.config.applied", func(event bus.Event) {
 		cfg, ok := event.Data.(*httpconfig.Config)
@@ -384,6 +998,9 @@ func (m *Manager) reloadTelegram(cfg *telegramconfig.Config) {
 		m.telegramCancel()
 	}
 
+	// Named bots are restarted from the new config below
+	m.stopTelegramNamed()
+
 	// Stop existing bot
 	if bot != nil {
 		logging.L_info("telegram: stopping for config reload")
@@ -411,6 +1028,7 @@ func (m *Manager) reloadTelegram(cfg *telegramconfig.Config) {
 	} else {
 		logging.L_info("telegram: reloaded with new config")
 	}
+	m.startTelegramNamed(m.ctx, cfg)
 }
 
 // reloadHTTP handles HTTP config changes
@@ -436,6 +1054,23 @@ func (m *Manager) StopAll() {
 	if m.whatsappCancel != nil {
 		m.whatsappCancel()
 	}
+	if m.matrixCancel != nil {
+		m.matrixCancel()
+	}
+	if m.discordCancel != nil {
+		m.discordCancel()
+	}
+	if m.emailCancel != nil {
+		m.emailCancel()
+	}
+	if m.telegramNamedCancel != nil {
+		m.telegramNamedCancel()
+		m.telegramNamedCancel = nil
+	}
+	if m.bridgesCancel != nil {
+		m.bridgesCancel()
+		m.bridgesCancel = nil
+	}
 
 	for name, ch := range m.channels {
 		logging.L_debug("channels: stopping", "channel", name)
@@ -449,7 +1084,12 @@ func (m *Manager) StopAll() {
 	}
 	m.channels = make(map[string]ManagedChannel)
 	m.telegramBot = nil
+	m.telegramNamed = nil
 	m.whatsappBot = nil
+	m.matrixBot = nil
+	m.discordBot = nil
+	m.emailBot = nil
+	m.bridges = nil
 	m.httpServer = nil
 }
 
@@ -499,6 +1139,17 @@ func (m *Manager) GetTelegram() *telegram.Bot {
 	return m.telegramBot
 }
 
+// GetTelegramNamed returns the named Telegram bots (for message tool adapters)
+func (m *Manager) GetTelegramNamed() map[string]*telegram.Bot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make(map[string]*telegram.Bot, len(m.telegramNamed))
+	for name, bot := range m.telegramNamed {
+		result[name] = bot
+	}
+	return result
+}
+
 // GetWhatsApp returns the WhatsApp bot (for message tool adapter)
 func (m *Manager) GetWhatsApp() *whatsapp.Bot {
 	m.mu.RLock()
@@ -506,6 +1157,38 @@ func (m *Manager) GetWhatsApp() *whatsapp.Bot {
 	return m.whatsappBot
 }
 
+// GetMatrix returns the Matrix bot (for message tool adapter)
+func (m *Manager) GetMatrix() *matrix.Bot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.matrixBot
+}
+
+// GetDiscord returns the Discord bot (for message tool adapter)
+func (m *Manager) GetDiscord() *discord.Bot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.discordBot
+}
+
+// GetEmail returns the email bot
+func (m *Manager) GetEmail() *email.Bot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.emailBot
+}
+
+// GetBridges returns the running bridges (for message tool adapters)
+func (m *Manager) GetBridges() map[string]*bridge.Bot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	result := make(map[string]*bridge.Bot, len(m.bridges))
+	for name, bot := range m.bridges {
+		result[name] = bot
+	}
+	return result
+}
+
 // GetHTTP returns the HTTP server
 func (m *Manager) GetHTTP() *http.Server {
 	m.mu.RLock()
@@ -529,6 +1212,10 @@ func (m *Manager) Status() map[string]ChannelStatus {
 func (m *Manager) RegisterCommands() {
 	telegramconfig.RegisterCommands()
 	whatsappconfig.RegisterCommands()
+	matrixconfig.RegisterCommands()
+	discordconfig.RegisterCommands()
+	emailconfig.RegisterCommands()
+	bridgeconfig.RegisterCommands()
 	httpconfig.RegisterCommands()
 	tuiconfig.RegisterCommands()
 }
@@ -537,6 +1224,10 @@ func (m *Manager) RegisterCommands() {
 func (m *Manager) UnregisterCommands() {
 	telegramconfig.UnregisterCommands()
 	whatsappconfig.UnregisterCommands()
+	matrixconfig.UnregisterCommands()
+	discordconfig.UnregisterCommands()
+	emailconfig.UnregisterCommands()
+	bridgeconfig.UnregisterCommands()
 	httpconfig.UnregisterCommands()
 	tuiconfig.UnregisterCommands()
 }
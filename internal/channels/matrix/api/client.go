@@ -0,0 +1,447 @@
+// Package api is a small client for the Matrix client-server API, covering
+// what the Matrix channel needs: password login, long-poll sync, messages,
+// edits, reactions, redactions, typing, receipts, joins and media.
+//
+// It speaks plain HTTPS+JSON and does no end-to-end encryption itself.
+// Encrypted rooms work through an E2EE-aware proxy such as Pantalaimon,
+// which the client talks to as if it were the homeserver.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	clientPrefix = "/_matrix/client/v3"
+	mediaPrefix  = "/_matrix/media/v3"
+
+	// maxRateLimitRetries is how often a request is retried after M_LIMIT_EXCEEDED
+	maxRateLimitRetries = 3
+	// maxRetryAfter caps how long a rate-limited request waits
+	maxRetryAfter = 30 * time.Second
+	// MaxDownloadSize limits media downloads
+	MaxDownloadSize = 50 << 20
+)
+
+// Client is a Matrix client-server API client for one account
+type Client struct {
+	Homeserver  string // Base URL, e.g. https://matrix.example.org
+	AccessToken string
+	UserID      string
+	DeviceID    string
+	HTTP        *http.Client
+
+	txnPrefix string
+	txnID     atomic.Int64
+}
+
+// NewClient creates a client. accessToken may be empty until Login.
+func NewClient(homeserver, accessToken string) *Client {
+	return &Client{
+		Homeserver:  strings.TrimRight(homeserver, "/"),
+		AccessToken: accessToken,
+		// Sync long-polls for up to 30s; the timeout leaves room for that
+		HTTP:      &http.Client{Timeout: 90 * time.Second},
+		txnPrefix: "goclaw" + strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+}
+
+// Error is an error response from the homeserver
+type Error struct {
+	StatusCode   int    `json:"-"`
+	ErrCode      string `json:"errcode"`
+	Message      string `json:"error"`
+	RetryAfterMs int64  `json:"retry_after_ms,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.ErrCode == "" {
+		return fmt.Sprintf("matrix: HTTP %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("matrix: %s: %s", e.ErrCode, e.Message)
+}
+
+// IsErrCode reports whether err is a homeserver error with the given errcode
+func IsErrCode(err error, code string) bool {
+	var mxErr *Error
+	return errors.As(err, &mxErr) && mxErr.ErrCode == code
+}
+
+// nextTxnID returns a transaction ID, unique for this client instance, so
+// retried sends are de-duplicated by the homeserver
+func (c *Client) nextTxnID() string {
+	return c.txnPrefix + "." + strconv.FormatInt(c.txnID.Add(1), 10)
+}
+
+// do sends a JSON request and decodes the JSON response into out (if not nil).
+// Rate-limited requests are retried after the delay the server asks for.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("matrix: encode request: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		var reader io.Reader
+		if payload != nil {
+			reader = bytes.NewReader(payload)
+		}
+		resp, err := c.request(ctx, method, path, query, reader, "application/json")
+		if err != nil {
+			return err
+		}
+		err = decodeResponse(resp, out)
+		var mxErr *Error
+		if errors.As(err, &mxErr) && mxErr.ErrCode == "M_LIMIT_EXCEEDED" && attempt < maxRateLimitRetries {
+			wait := min(time.Duration(mxErr.RetryAfterMs)*time.Millisecond, maxRetryAfter)
+			if wait <= 0 {
+				wait = time.Second
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+		return err
+	}
+}
+
+// request sends an authenticated request; path is already escaped
+func (c *Client) request(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	u := c.Homeserver + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("matrix: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("matrix: %s %s: %w", method, path, err)
+	}
+	return resp, nil
+}
+
+// decodeResponse decodes a JSON response or returns the homeserver's error
+func decodeResponse(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		mxErr := &Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(data, mxErr) != nil || (mxErr.ErrCode == "" && mxErr.Message == "") {
+			mxErr.Message = strings.TrimSpace(string(data))
+		}
+		return mxErr
+	}
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("matrix: decode response: %w", err)
+	}
+	return nil
+}
+
+// LoginResponse is the result of a password login
+type LoginResponse struct {
+	UserID      string `json:"user_id"`
+	AccessToken string `json:"access_token"`
+	DeviceID    string `json:"device_id"`
+}
+
+// Login logs in with a password and stores the access token on the client.
+// Passing the previous deviceID keeps the same device instead of creating one
+// per login.
+func (c *Client) Login(ctx context.Context, userID, password, deviceID, deviceName string) (*LoginResponse, error) {
+	body := map[string]any{
+		"type":       "m.login.password",
+		"identifier": map[string]string{"type": "m.id.user", "user": userID},
+		"password":   password,
+	}
+	if deviceID != "" {
+		body["device_id"] = deviceID
+	}
+	if deviceName != "" {
+		body["initial_device_display_name"] = deviceName
+	}
+
+	var resp LoginResponse
+	if err := c.do(ctx, http.MethodPost, clientPrefix+"/login", nil, body, &resp); err != nil {
+		return nil, err
+	}
+	c.AccessToken = resp.AccessToken
+	c.UserID = resp.UserID
+	c.DeviceID = resp.DeviceID
+	return &resp, nil
+}
+
+// Logout invalidates the access token and its device
+func (c *Client) Logout(ctx context.Context) error {
+	if err := c.do(ctx, http.MethodPost, clientPrefix+"/logout", nil, map[string]any{}, nil); err != nil {
+		return err
+	}
+	c.AccessToken = ""
+	return nil
+}
+
+// WhoamiResponse identifies the access token's account
+type WhoamiResponse struct {
+	UserID   string `json:"user_id"`
+	DeviceID string `json:"device_id,omitempty"`
+}
+
+// Whoami checks the access token and fills in UserID and DeviceID
+func (c *Client) Whoami(ctx context.Context) (*WhoamiResponse, error) {
+	var resp WhoamiResponse
+	if err := c.do(ctx, http.MethodGet, clientPrefix+"/account/whoami", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	c.UserID = resp.UserID
+	if resp.DeviceID != "" {
+		c.DeviceID = resp.DeviceID
+	}
+	return &resp, nil
+}
+
+// DisplayName returns a user's display name ("" if none is set)
+func (c *Client) DisplayName(ctx context.Context, userID string) (string, error) {
+	var resp struct {
+		DisplayName string `json:"displayname"`
+	}
+	path := clientPrefix + "/profile/" + url.PathEscape(userID) + "/displayname"
+	if err := c.do(ctx, http.MethodGet, path, nil, nil, &resp); err != nil {
+		if IsErrCode(err, "M_NOT_FOUND") {
+			return "", nil
+		}
+		return "", err
+	}
+	return resp.DisplayName, nil
+}
+
+// Sync long-polls for new events. since is the previous NextBatch ("" for an
+// initial sync); filter is a filter ID or inline JSON filter.
+func (c *Client) Sync(ctx context.Context, since string, timeout time.Duration, filter string) (*SyncResponse, error) {
+	query := url.Values{}
+	if since != "" {
+		query.Set("since", since)
+	}
+	if filter != "" {
+		query.Set("filter", filter)
+	}
+	query.Set("timeout", strconv.FormatInt(timeout.Milliseconds(), 10))
+
+	var resp SyncResponse
+	if err := c.do(ctx, http.MethodGet, clientPrefix+"/sync", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// SendEvent sends a room event and returns its event ID
+func (c *Client) SendEvent(ctx context.Context, roomID, eventType string, content any) (string, error) {
+	path := clientPrefix + "/rooms/" + url.PathEscape(roomID) + "/send/" + url.PathEscape(eventType) + "/" + url.PathEscape(c.nextTxnID())
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	if err := c.do(ctx, http.MethodPut, path, nil, content, &resp); err != nil {
+		return "", err
+	}
+	return resp.EventID, nil
+}
+
+// SendMessage sends an m.room.message event
+func (c *Client) SendMessage(ctx context.Context, roomID string, content *MessageContent) (string, error) {
+	return c.SendEvent(ctx, roomID, EventMessage, content)
+}
+
+// Edit replaces the content of an earlier message (m.replace). Clients that
+// don't support edits show the fallback body, prefixed with "* ".
+func (c *Client) Edit(ctx context.Context, roomID, eventID string, content *MessageContent) (string, error) {
+	newContent := *content
+	newContent.RelatesTo = nil
+	edit := &MessageContent{
+		MsgType:    content.MsgType,
+		Body:       "* " + content.Body,
+		NewContent: &newContent,
+		RelatesTo:  &RelatesTo{RelType: RelReplace, EventID: eventID},
+	}
+	if content.FormattedBody != "" {
+		edit.Format = content.Format
+		edit.FormattedBody = "* " + content.FormattedBody
+	}
+	return c.SendMessage(ctx, roomID, edit)
+}
+
+// React sends an emoji reaction (m.annotation) to an event
+func (c *Client) React(ctx context.Context, roomID, eventID, key string) (string, error) {
+	content := map[string]any{
+		"m.relates_to": &RelatesTo{RelType: RelAnnotation, EventID: eventID, Key: key},
+	}
+	return c.SendEvent(ctx, roomID, EventReaction, content)
+}
+
+// Redact removes an event's content
+func (c *Client) Redact(ctx context.Context, roomID, eventID, reason string) (string, error) {
+	path := clientPrefix + "/rooms/" + url.PathEscape(roomID) + "/redact/" + url.PathEscape(eventID) + "/" + url.PathEscape(c.nextTxnID())
+	body := map[string]string{}
+	if reason != "" {
+		body["reason"] = reason
+	}
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	if err := c.do(ctx, http.MethodPut, path, nil, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.EventID, nil
+}
+
+// Typing sets or clears the typing notification in a room
+func (c *Client) Typing(ctx context.Context, roomID string, typing bool, timeout time.Duration) error {
+	path := clientPrefix + "/rooms/" + url.PathEscape(roomID) + "/typing/" + url.PathEscape(c.UserID)
+	body := map[string]any{"typing": typing}
+	if typing {
+		body["timeout"] = timeout.Milliseconds()
+	}
+	return c.do(ctx, http.MethodPut, path, nil, body, nil)
+}
+
+// ReadReceipt marks an event as read
+func (c *Client) ReadReceipt(ctx context.Context, roomID, eventID string) error {
+	path := clientPrefix + "/rooms/" + url.PathEscape(roomID) + "/receipt/m.read/" + url.PathEscape(eventID)
+	return c.do(ctx, http.MethodPost, path, nil, map[string]any{}, nil)
+}
+
+// JoinRoom joins a room by ID or alias and returns the room ID
+func (c *Client) JoinRoom(ctx context.Context, roomIDOrAlias string) (string, error) {
+	var resp struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := c.do(ctx, http.MethodPost, clientPrefix+"/join/"+url.PathEscape(roomIDOrAlias), nil, map[string]any{}, &resp); err != nil {
+		return "", err
+	}
+	return resp.RoomID, nil
+}
+
+// CreateDirectRoom creates a private direct-message room with one user
+func (c *Client) CreateDirectRoom(ctx context.Context, userID string) (string, error) {
+	body := map[string]any{
+		"preset":    "trusted_private_chat",
+		"is_direct": true,
+		"invite":    []string{userID},
+	}
+	var resp struct {
+		RoomID string `json:"room_id"`
+	}
+	if err := c.do(ctx, http.MethodPost, clientPrefix+"/createRoom", nil, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.RoomID, nil
+}
+
+// JoinedMembers returns the joined members of a room, keyed by user ID
+func (c *Client) JoinedMembers(ctx context.Context, roomID string) (map[string]Member, error) {
+	var resp struct {
+		Joined map[string]Member `json:"joined"`
+	}
+	if err := c.do(ctx, http.MethodGet, clientPrefix+"/rooms/"+url.PathEscape(roomID)+"/joined_members", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Joined, nil
+}
+
+// Upload stores media on the homeserver and returns its mxc:// URI
+func (c *Client) Upload(ctx context.Context, data []byte, contentType, filename string) (string, error) {
+	query := url.Values{}
+	if filename != "" {
+		query.Set("filename", filename)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	resp, err := c.request(ctx, http.MethodPost, mediaPrefix+"/upload", query, bytes.NewReader(data), contentType)
+	if err != nil {
+		return "", err
+	}
+	var out struct {
+		ContentURI string `json:"content_uri"`
+	}
+	if err := decodeResponse(resp, &out); err != nil {
+		return "", err
+	}
+	return out.ContentURI, nil
+}
+
+// Download fetches media by mxc:// URI and returns the data and content type.
+// It uses the authenticated media endpoint, falling back to the legacy one
+// for homeservers that predate it.
+func (c *Client) Download(ctx context.Context, mxcURI string) ([]byte, string, error) {
+	server, mediaID, err := ParseMXC(mxcURI)
+	if err != nil {
+		return nil, "", err
+	}
+	suffix := "/download/" + url.PathEscape(server) + "/" + url.PathEscape(mediaID)
+
+	data, contentType, err := c.download(ctx, "/_matrix/client/v1/media"+suffix)
+	var mxErr *Error
+	if errors.As(err, &mxErr) && (mxErr.StatusCode == http.StatusNotFound || mxErr.ErrCode == "M_UNRECOGNIZED") {
+		return c.download(ctx, mediaPrefix+suffix)
+	}
+	return data, contentType, err
+}
+
+func (c *Client) download(ctx context.Context, path string) ([]byte, string, error) {
+	resp, err := c.request(ctx, http.MethodGet, path, nil, nil, "")
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, "", decodeResponse(resp, nil)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxDownloadSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("matrix: download: %w", err)
+	}
+	if len(data) > MaxDownloadSize {
+		return nil, "", fmt.Errorf("matrix: media larger than %d bytes", MaxDownloadSize)
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// ParseMXC splits an mxc://server/mediaID URI
+func ParseMXC(uri string) (server, mediaID string, err error) {
+	rest, ok := strings.CutPrefix(uri, "mxc://")
+	if !ok {
+		return "", "", fmt.Errorf("matrix: not an mxc URI: %q", uri)
+	}
+	server, mediaID, ok = strings.Cut(rest, "/")
+	if !ok || server == "" || mediaID == "" || strings.Contains(mediaID, "/") {
+		return "", "", fmt.Errorf("matrix: malformed mxc URI: %q", uri)
+	}
+	return server, mediaID, nil
+}
@@ -0,0 +1,257 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/channels/matrix/api"
+	"github.com/roelfdiedericks/goclaw/internal/channels/matrix/matrixtest"
+)
+
+func newServer(t *testing.T) (*matrixtest.Server, string, string) {
+	t.Helper()
+	srv := matrixtest.NewServer()
+	t.Cleanup(srv.Close)
+	bot := srv.AddUser("goclaw", "botpass", "GoClaw")
+	alice := srv.AddUser("alice", "alicepass", "Alice")
+	return srv, bot, alice
+}
+
+func TestLoginAndWhoami(t *testing.T) {
+	srv, bot, _ := newServer(t)
+	ctx := context.Background()
+
+	client := api.NewClient(srv.URL(), "")
+	if _, err := client.Login(ctx, bot, "wrong", "", "GoClaw"); !api.IsErrCode(err, "M_FORBIDDEN") {
+		t.Fatalf("login with wrong password: err = %v, want M_FORBIDDEN", err)
+	}
+
+	resp, err := client.Login(ctx, "goclaw", "botpass", "DEVICE1", "GoClaw")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if resp.UserID != bot || resp.DeviceID != "DEVICE1" || client.AccessToken == "" {
+		t.Fatalf("login response = %+v, token %q", resp, client.AccessToken)
+	}
+
+	// A fresh client with the token identifies the same account
+	other := api.NewClient(srv.URL(), client.AccessToken)
+	who, err := other.Whoami(ctx)
+	if err != nil || who.UserID != bot {
+		t.Fatalf("whoami = %+v, %v", who, err)
+	}
+
+	if name, err := other.DisplayName(ctx, bot); err != nil || name != "GoClaw" {
+		t.Errorf("DisplayName = %q, %v", name, err)
+	}
+
+	if err := client.Logout(ctx); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if _, err := other.Whoami(ctx); !api.IsErrCode(err, "M_UNKNOWN_TOKEN") {
+		t.Errorf("whoami after logout: err = %v, want M_UNKNOWN_TOKEN", err)
+	}
+}
+
+func TestSyncAndSend(t *testing.T) {
+	srv, bot, alice := newServer(t)
+	ctx := context.Background()
+	client := api.NewClient(srv.URL(), srv.Token(bot))
+	if _, err := client.Whoami(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	room := srv.CreateRoom(bot, alice)
+	first, err := client.Sync(ctx, "", 0, "")
+	if err != nil {
+		t.Fatalf("initial sync: %v", err)
+	}
+	if _, ok := first.Rooms.Join[room]; !ok {
+		t.Fatalf("initial sync missing room %s: %+v", room, first.Rooms.Join)
+	}
+
+	// A long-poll returns as soon as something arrives
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		srv.SendText(room, alice, "hello")
+	}()
+	start := time.Now()
+	next, err := client.Sync(ctx, first.NextBatch, 5*time.Second, "")
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("long-poll did not wake up on a new event")
+	}
+	jr := next.Rooms.Join[room]
+	if len(jr.Timeline.Events) != 1 || jr.Summary.JoinedMemberCount == nil || *jr.Summary.JoinedMemberCount != 2 {
+		t.Fatalf("sync room = %+v", jr)
+	}
+	var msg api.MessageContent
+	if err := jr.Timeline.Events[0].ParseContent(&msg); err != nil || msg.Body != "hello" {
+		t.Fatalf("message = %+v, %v", msg, err)
+	}
+	msgID := jr.Timeline.Events[0].EventID
+
+	// Send, edit, react, redact
+	sent, err := client.SendMessage(ctx, room, &api.MessageContent{MsgType: api.MsgText, Body: "hi"})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if _, err := client.Edit(ctx, room, sent, &api.MessageContent{MsgType: api.MsgText, Body: "hi there", Format: api.FormatHTML, FormattedBody: "<b>hi</b> there"}); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	if _, err := client.React(ctx, room, msgID, "👍"); err != nil {
+		t.Fatalf("react: %v", err)
+	}
+	if _, err := client.Redact(ctx, room, sent, ""); err != nil {
+		t.Fatalf("redact: %v", err)
+	}
+
+	events := srv.EventsFrom(room, bot)
+	if len(events) != 4 {
+		t.Fatalf("bot sent %d events, want 4", len(events))
+	}
+	var edit api.MessageContent
+	_ = events[1].ParseContent(&edit)
+	if edit.RelatesTo == nil || edit.RelatesTo.RelType != api.RelReplace || edit.RelatesTo.EventID != sent ||
+		edit.NewContent == nil || edit.NewContent.Body != "hi there" || edit.Body != "* hi there" ||
+		edit.NewContent.FormattedBody != "<b>hi</b> there" {
+		t.Errorf("edit = %+v", edit)
+	}
+	var reaction struct {
+		RelatesTo api.RelatesTo `json:"m.relates_to"`
+	}
+	_ = events[2].ParseContent(&reaction)
+	if events[2].Type != api.EventReaction || reaction.RelatesTo.Key != "👍" || reaction.RelatesTo.EventID != msgID {
+		t.Errorf("reaction = %s %s", events[2].Type, events[2].Content)
+	}
+	if events[3].Type != "m.room.redaction" {
+		t.Errorf("last event = %s, want redaction", events[3].Type)
+	}
+}
+
+func TestRateLimitRetry(t *testing.T) {
+	srv, bot, alice := newServer(t)
+	client := api.NewClient(srv.URL(), srv.Token(bot))
+	room := srv.CreateRoom(bot, alice)
+
+	srv.RateLimit(2)
+	if _, err := client.SendMessage(context.Background(), room, &api.MessageContent{MsgType: api.MsgText, Body: "x"}); err != nil {
+		t.Fatalf("send after rate limit: %v", err)
+	}
+	if n := len(srv.EventsFrom(room, bot)); n != 1 {
+		t.Errorf("sent %d events, want 1", n)
+	}
+
+	srv.RateLimit(10)
+	_, err := client.SendMessage(context.Background(), room, &api.MessageContent{MsgType: api.MsgText, Body: "y"})
+	if !api.IsErrCode(err, "M_LIMIT_EXCEEDED") {
+		t.Errorf("persistent rate limit: err = %v, want M_LIMIT_EXCEEDED", err)
+	}
+}
+
+func TestMediaRoundTrip(t *testing.T) {
+	srv, bot, _ := newServer(t)
+	ctx := context.Background()
+	client := api.NewClient(srv.URL(), srv.Token(bot))
+
+	mxc, err := client.Upload(ctx, []byte("PNGDATA"), "image/png", "cat.png")
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	data, ctype, err := client.Download(ctx, mxc)
+	if err != nil || string(data) != "PNGDATA" || ctype != "image/png" {
+		t.Fatalf("download = %q %q %v", data, ctype, err)
+	}
+
+	// Older homeservers only have the unauthenticated endpoint
+	srv.DisableAuthenticatedMedia()
+	if data, _, err := client.Download(ctx, mxc); err != nil || string(data) != "PNGDATA" {
+		t.Fatalf("legacy download = %q %v", data, err)
+	}
+
+	if _, _, err := client.Download(ctx, "https://example.org/x"); err == nil {
+		t.Error("expected error for non-mxc URI")
+	}
+}
+
+func TestInviteAndJoin(t *testing.T) {
+	srv, bot, alice := newServer(t)
+	ctx := context.Background()
+	client := api.NewClient(srv.URL(), srv.Token(bot))
+
+	room := srv.CreateRoom(alice)
+	srv.Invite(room, alice, bot)
+	resp, err := client.Sync(ctx, "", 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invite, ok := resp.Rooms.Invite[room]
+	if !ok || len(invite.InviteState.Events) != 1 || invite.InviteState.Events[0].Sender != alice {
+		t.Fatalf("invite = %+v", resp.Rooms.Invite)
+	}
+	if joined, err := client.JoinRoom(ctx, room); err != nil || joined != room {
+		t.Fatalf("join = %q, %v", joined, err)
+	}
+	members, err := client.JoinedMembers(ctx, room)
+	if err != nil || len(members) != 2 {
+		t.Fatalf("members = %v, %v", members, err)
+	}
+
+	dm, err := client.CreateDirectRoom(ctx, alice)
+	if err != nil || len(srv.Members(dm)) != 2 {
+		t.Fatalf("direct room = %q (%v), %v", dm, srv.Members(dm), err)
+	}
+}
+
+func TestMentioned(t *testing.T) {
+	const bot = "@goclaw:example.org"
+	tests := []struct {
+		name    string
+		content api.MessageContent
+		want    bool
+		body    string
+	}{
+		{"intentional mention", api.MessageContent{Body: "GoClaw: what time is it", Mentions: &api.Mentions{UserIDs: []string{bot}}}, true, "what time is it"},
+		{"display name prefix", api.MessageContent{Body: "goclaw, hi"}, true, "hi"},
+		{"full ID prefix", api.MessageContent{Body: "@goclaw:example.org status?"}, true, "status?"},
+		{"pill", api.MessageContent{Body: "hey you", FormattedBody: `<a href="https://matrix.to/#/@goclaw:example.org">GoClaw</a> hey`}, true, "hey you"},
+		{"ID in the middle", api.MessageContent{Body: "ask @goclaw:example.org later"}, true, "ask @goclaw:example.org later"},
+		{"other user mentioned", api.MessageContent{Body: "bob: hi", Mentions: &api.Mentions{UserIDs: []string{"@bob:example.org"}}}, false, "bob: hi"},
+		{"name is a prefix of a word", api.MessageContent{Body: "goclawfans unite"}, false, "goclawfans unite"},
+		{"no mention", api.MessageContent{Body: "lunch?"}, false, "lunch?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, body := tt.content.Mentioned(bot, "GoClaw")
+			if got != tt.want || body != tt.body {
+				t.Errorf("Mentioned() = %v, %q; want %v, %q", got, body, tt.want, tt.body)
+			}
+		})
+	}
+}
+
+func TestParseMXC(t *testing.T) {
+	server, id, err := api.ParseMXC("mxc://example.org/abc123")
+	if err != nil || server != "example.org" || id != "abc123" {
+		t.Errorf("ParseMXC = %q %q %v", server, id, err)
+	}
+	for _, bad := range []string{"", "mxc://", "mxc://example.org", "mxc://example.org/a/b", "http://example.org/a"} {
+		if _, _, err := api.ParseMXC(bad); err == nil {
+			t.Errorf("ParseMXC(%q) should fail", bad)
+		}
+	}
+}
+
+func TestErrorDecoding(t *testing.T) {
+	var e api.Error
+	if err := json.Unmarshal([]byte(`{"errcode":"M_LIMIT_EXCEEDED","error":"slow down","retry_after_ms":500}`), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.RetryAfterMs != 500 || e.Error() != "matrix: M_LIMIT_EXCEEDED: slow down" {
+		t.Errorf("error = %+v (%s)", e, e.Error())
+	}
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Event types
+const (
+	EventMessage    = "m.room.message"
+	EventReaction   = "m.reaction"
+	EventMember     = "m.room.member"
+	EventEncrypted  = "m.room.encrypted"
+	EventEncryption = "m.room.encryption"
+)
+
+// Message types (msgtype)
+const (
+	MsgText   = "m.text"
+	MsgNotice = "m.notice"
+	MsgEmote  = "m.emote"
+	MsgImage  = "m.image"
+	MsgAudio  = "m.audio"
+	MsgVideo  = "m.video"
+	MsgFile   = "m.file"
+)
+
+// Relation types and formats
+const (
+	RelReplace    = "m.replace"
+	RelAnnotation = "m.annotation"
+	FormatHTML    = "org.matrix.custom.html"
+)
+
+// Event is a room event from sync
+type Event struct {
+	Type           string          `json:"type"`
+	EventID        string          `json:"event_id,omitempty"`
+	Sender         string          `json:"sender"`
+	StateKey       *string         `json:"state_key,omitempty"`
+	OriginServerTS int64           `json:"origin_server_ts,omitempty"`
+	Content        json.RawMessage `json:"content"`
+}
+
+// ParseContent decodes the event content into v
+func (e *Event) ParseContent(v any) error {
+	return json.Unmarshal(e.Content, v)
+}
+
+// MessageContent is the content of an m.room.message event
+type MessageContent struct {
+	MsgType       string          `json:"msgtype"`
+	Body          string          `json:"body"`
+	Format        string          `json:"format,omitempty"`
+	FormattedBody string          `json:"formatted_body,omitempty"`
+	URL           string          `json:"url,omitempty"`      // mxc:// URI for media
+	FileName      string          `json:"filename,omitempty"` // Media file name when body is a caption
+	Info          *FileInfo       `json:"info,omitempty"`
+	Mentions      *Mentions       `json:"m.mentions,omitempty"`
+	RelatesTo     *RelatesTo      `json:"m.relates_to,omitempty"`
+	NewContent    *MessageContent `json:"m.new_content,omitempty"`            // Replacement content of an edit
+	Voice         *struct{}       `json:"org.matrix.msc3245.voice,omitempty"` // Marks an m.audio as a voice message
+	File          json.RawMessage `json:"file,omitempty"`                     // Encrypted media (not supported)
+}
+
+// Caption returns the media caption, or "" when the body is just the file name
+func (m *MessageContent) Caption() string {
+	if m.FileName != "" && m.Body != m.FileName {
+		return m.Body
+	}
+	return ""
+}
+
+// FileInfo describes media in a message
+type FileInfo struct {
+	MimeType string `json:"mimetype,omitempty"`
+	Size     int    `json:"size,omitempty"`
+	Width    int    `json:"w,omitempty"`
+	Height   int    `json:"h,omitempty"`
+	Duration int    `json:"duration,omitempty"` // Milliseconds
+}
+
+// Mentions lists who a message intentionally mentions
+type Mentions struct {
+	UserIDs []string `json:"user_ids,omitempty"`
+	Room    bool     `json:"room,omitempty"`
+}
+
+// RelatesTo links an event to another (edits, reactions, replies)
+type RelatesTo struct {
+	RelType   string     `json:"rel_type,omitempty"`
+	EventID   string     `json:"event_id,omitempty"`
+	Key       string     `json:"key,omitempty"` // Reaction emoji
+	InReplyTo *InReplyTo `json:"m.in_reply_to,omitempty"`
+}
+
+// InReplyTo marks a reply
+type InReplyTo struct {
+	EventID string `json:"event_id"`
+}
+
+// MemberContent is the content of an m.room.member event
+type MemberContent struct {
+	Membership  string `json:"membership"` // invite, join, leave, ban
+	DisplayName string `json:"displayname,omitempty"`
+	IsDirect    bool   `json:"is_direct,omitempty"`
+}
+
+// Member is a joined room member
+type Member struct {
+	DisplayName string `json:"display_name,omitempty"`
+}
+
+// SyncResponse is the result of /sync
+type SyncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join   map[string]JoinedRoom      `json:"join,omitempty"`
+		Invite map[string]InvitedRoom     `json:"invite,omitempty"`
+		Leave  map[string]json.RawMessage `json:"leave,omitempty"`
+	} `json:"rooms"`
+}
+
+// JoinedRoom is a joined room's part of a sync response
+type JoinedRoom struct {
+	Summary  RoomSummary `json:"summary"`
+	State    EventList   `json:"state"`
+	Timeline Timeline    `json:"timeline"`
+}
+
+// InvitedRoom is a pending invite's part of a sync response
+type InvitedRoom struct {
+	InviteState EventList `json:"invite_state"`
+}
+
+// RoomSummary carries member counts (sent when they change)
+type RoomSummary struct {
+	JoinedMemberCount  *int `json:"m.joined_member_count,omitempty"`
+	InvitedMemberCount *int `json:"m.invited_member_count,omitempty"`
+}
+
+// EventList is a list of events
+type EventList struct {
+	Events []Event `json:"events"`
+}
+
+// Timeline is a room's new timeline events
+type Timeline struct {
+	Events    []Event `json:"events"`
+	Limited   bool    `json:"limited,omitempty"`
+	PrevBatch string  `json:"prev_batch,omitempty"`
+}
+
+// Localpart returns the user part of a Matrix ID ("@alice:example.org" -> "alice")
+func Localpart(userID string) string {
+	local, _, _ := strings.Cut(strings.TrimPrefix(userID, "@"), ":")
+	return local
+}
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Mentioned reports whether a message mentions userID, and returns the body
+// with a leading mention ("GoClaw: ", "@goclaw:server ") stripped.
+//
+// Clients that support intentional mentions list the user in m.mentions.
+// Older clients only put a pill in formatted_body or the name in the body, so
+// those are checked too: a matrix.to link, the full user ID, the display name
+// or the localpart at the start of the message.
+func (m *MessageContent) Mentioned(userID, displayName string) (bool, string) {
+	body := strings.TrimSpace(m.Body)
+	stripped := stripLeadingMention(body, userID, displayName)
+
+	if m.Mentions != nil && slices.Contains(m.Mentions.UserIDs, userID) {
+		return true, stripped
+	}
+	if strings.Contains(m.FormattedBody, "https://matrix.to/#/"+userID) {
+		return true, stripped
+	}
+	if stripped != body || strings.Contains(body, userID) {
+		return true, stripped
+	}
+	return false, body
+}
+
+// stripLeadingMention removes a mention of the user at the start of body,
+// with the separator clients put after it (": ", ", " or a space)
+func stripLeadingMention(body, userID, displayName string) string {
+	candidates := []string{userID}
+	if displayName != "" {
+		candidates = append(candidates, displayName)
+	}
+	if local := Localpart(userID); local != "" {
+		candidates = append(candidates, "@"+local, local)
+	}
+
+	for _, c := range candidates {
+		if len(body) < len(c) || !strings.EqualFold(body[:len(c)], c) {
+			continue
+		}
+		rest := body[len(c):]
+		if rest == "" {
+			return ""
+		}
+		// The name must end at a word boundary ("goclawfan" is not "goclaw")
+		if sep := mentionSeparator.FindString(rest); sep != "" {
+			return strings.TrimSpace(rest[len(sep):])
+		}
+	}
+	return body
+}
+
+var mentionSeparator = regexp.MustCompile(`^[:,]?\s+|^[:,]$`)
@@ -0,0 +1,1192 @@
+// Package matrix provides the Matrix channel adapter for GoClaw.
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/bus"
+	"github.com/roelfdiedericks/goclaw/internal/channels/matrix/api"
+	"github.com/roelfdiedericks/goclaw/internal/channels/matrix/config"
+	chtypes "github.com/roelfdiedericks/goclaw/internal/channels/types"
+	"github.com/roelfdiedericks/goclaw/internal/commands"
+	"github.com/roelfdiedericks/goclaw/internal/gateway"
+	"github.com/roelfdiedericks/goclaw/internal/llm"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/media"
+	"github.com/roelfdiedericks/goclaw/internal/paths"
+	"github.com/roelfdiedericks/goclaw/internal/session"
+	itypes "github.com/roelfdiedericks/goclaw/internal/types"
+	"github.com/roelfdiedericks/goclaw/internal/user"
+)
+
+const (
+	// maxMatrixMessage keeps body + formatted_body well under the 64 KiB event limit
+	maxMatrixMessage = 16000
+	// streamInterval is how often a streaming reply is edited. Edits are
+	// events, so this is slower than Telegram to stay clear of rate limits.
+	streamInterval = 1500 * time.Millisecond
+	// syncTimeout is the long-poll timeout
+	syncTimeout = 30 * time.Second
+	// typingTimeout is how long a typing notification lasts without a refresh
+	typingTimeout = 30 * time.Second
+
+	// syncFilter limits timelines so a reconnect after downtime doesn't
+	// replay a flood of old messages
+	syncFilter = `{"room":{"timeline":{"limit":50},"state":{"lazy_load_members":true}},"presence":{"not_types":["*"]}}`
+
+	encryptedRoomNotice = "This room is end-to-end encrypted, and I can't read encrypted messages. " +
+		"Talk to me in an unencrypted room, or ask the owner to connect me through Pantalaimon."
+)
+
+// ChatPreferences stores per-room preferences (mirrors Telegram)
+type ChatPreferences struct {
+	ShowThinking  bool
+	ThinkingLevel string
+}
+
+// Bot represents the Matrix channel
+type Bot struct {
+	client  *api.Client
+	gateway *gateway.Gateway
+	users   *user.Registry
+	config  *config.Config
+
+	displayName string
+	chatPrefs   sync.Map // room ID -> *ChatPreferences
+
+	statePath string
+	stateMu   sync.Mutex
+	state     sessionState
+	rooms     map[string]*roomState
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{} // Closed when the sync loop exits
+
+	mu        sync.RWMutex
+	running   bool
+	connected bool
+	startedAt time.Time
+	lastError error
+}
+
+// sessionState is persisted in ~/.goclaw/matrix.json so restarts reuse the
+// device and resume sync where they left off
+type sessionState struct {
+	Homeserver  string            `json:"homeserver"`
+	UserID      string            `json:"userId"`
+	DeviceID    string            `json:"deviceId,omitempty"`
+	AccessToken string            `json:"accessToken,omitempty"` // From a password login only
+	NextBatch   string            `json:"nextBatch,omitempty"`
+	DirectRooms map[string]string `json:"directRooms,omitempty"` // Matrix user ID -> DM room ID
+}
+
+// roomState is what the bot knows about a joined room
+type roomState struct {
+	members   int // Joined members (0 = unknown)
+	encrypted bool
+	warned    bool // Encrypted-room notice already sent
+}
+
+// New creates a new Matrix bot
+func New(cfg *config.Config, gw *gateway.Gateway, users *user.Registry) (*Bot, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, fmt.Errorf("matrix: %w", err)
+	}
+
+	statePath, err := paths.DataPath("matrix.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve matrix state path: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := &Bot{
+		client:    api.NewClient(cfg.Homeserver, ""),
+		gateway:   gw,
+		users:     users,
+		config:    cfg,
+		statePath: statePath,
+		rooms:     make(map[string]*roomState),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	b.loadState()
+	return b, nil
+}
+
+// Start logs in and starts syncing (implements ManagedChannel)
+func (b *Bot) Start(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.running {
+		return nil
+	}
+
+	loginCtx, cancel := context.WithTimeout(b.ctx, 30*time.Second)
+	defer cancel()
+
+	if err := b.login(loginCtx); err != nil {
+		b.lastError = err
+		return fmt.Errorf("matrix: login failed: %w", err)
+	}
+
+	if name, err := b.client.DisplayName(loginCtx, b.client.UserID); err == nil {
+		b.displayName = name
+	}
+
+	b.running = true
+	b.startedAt = time.Now()
+	b.lastError = nil
+	b.done = make(chan struct{})
+	go b.syncLoop(b.ctx, b.done)
+
+	L_info("matrix: connected", "user", b.client.UserID, "device", b.client.DeviceID, "homeserver", b.config.Homeserver)
+	return nil
+}
+
+// login authenticates with the configured token, the cached session or the password
+func (b *Bot) login(ctx context.Context) error {
+	b.stateMu.Lock()
+	st := b.state
+	b.stateMu.Unlock()
+
+	sameAccount := st.Homeserver == b.config.Homeserver && (b.config.UserID == "" || st.UserID == b.config.UserID)
+
+	if b.config.AccessToken != "" {
+		b.client.AccessToken = b.config.AccessToken
+		if _, err := b.client.Whoami(ctx); err != nil {
+			return err
+		}
+	} else {
+		authed := false
+		if sameAccount && st.AccessToken != "" {
+			b.client.AccessToken = st.AccessToken
+			_, err := b.client.Whoami(ctx)
+			switch {
+			case err == nil:
+				authed = true
+			case api.IsErrCode(err, "M_UNKNOWN_TOKEN"):
+				L_info("matrix: cached session expired, logging in again")
+			default:
+				return err
+			}
+		}
+		if !authed {
+			deviceID := ""
+			if sameAccount {
+				deviceID = st.DeviceID
+			}
+			if _, err := b.client.Login(ctx, b.config.UserID, b.config.Password, deviceID, "GoClaw"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if b.config.UserID != "" && b.client.UserID != b.config.UserID {
+		return fmt.Errorf("logged in as %s, expected %s", b.client.UserID, b.config.UserID)
+	}
+
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+	if b.state.Homeserver != b.config.Homeserver || b.state.UserID != b.client.UserID {
+		// Different account: the old sync position and DM rooms don't apply
+		b.state = sessionState{}
+	}
+	b.state.Homeserver = b.config.Homeserver
+	b.state.UserID = b.client.UserID
+	b.state.DeviceID = b.client.DeviceID
+	b.state.AccessToken = ""
+	if b.config.AccessToken == "" {
+		b.state.AccessToken = b.client.AccessToken
+	}
+	b.saveStateLocked()
+	return nil
+}
+
+// RegisterOperationalCommands registers runtime commands for this bot instance
+func (b *Bot) RegisterOperationalCommands() {
+	bus.RegisterCommand("matrix", "status", b.handleStatusCommand)
+}
+
+func (b *Bot) handleStatusCommand(cmd bus.Command) bus.CommandResult {
+	b.mu.RLock()
+	connected := b.connected
+	b.mu.RUnlock()
+	b.stateMu.Lock()
+	rooms := len(b.rooms)
+	b.stateMu.Unlock()
+
+	return bus.CommandResult{
+		Success: true,
+		Message: fmt.Sprintf("Matrix connected as %s", b.client.UserID),
+		Data: map[string]any{
+			"connected":  connected,
+			"userId":     b.client.UserID,
+			"deviceId":   b.client.DeviceID,
+			"homeserver": b.config.Homeserver,
+			"rooms":      rooms,
+		},
+	}
+}
+
+// Stop stops syncing (implements ManagedChannel)
+func (b *Bot) Stop() error {
+	b.mu.Lock()
+	if !b.running {
+		b.mu.Unlock()
+		return nil
+	}
+	L_info("matrix: stopping")
+	b.cancel()
+	b.running = false
+	b.connected = false
+	done := b.done
+	b.mu.Unlock()
+
+	// Wait for the sync loop so a restart doesn't race it on the state file
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		L_warn("matrix: sync loop did not stop in time")
+	}
+	return nil
+}
+
+// Reload applies new configuration (implements ManagedChannel)
+func (b *Bot) Reload(cfg any) error {
+	newCfg, ok := cfg.(*config.Config)
+	if !ok {
+		return fmt.Errorf("expected *matrix.Config, got %T", cfg)
+	}
+	if err := newCfg.Check(); err != nil {
+		return fmt.Errorf("matrix: %w", err)
+	}
+
+	b.mu.Lock()
+	wasRunning := b.running
+	b.mu.Unlock()
+
+	if wasRunning {
+		if err := b.Stop(); err != nil {
+			return fmt.Errorf("failed to stop for reload: %w", err)
+		}
+	}
+
+	b.config = newCfg
+	b.client = api.NewClient(newCfg.Homeserver, "")
+
+	if wasRunning && newCfg.Enabled {
+		b.ctx, b.cancel = context.WithCancel(context.Background())
+		return b.Start(b.ctx)
+	}
+
+	return nil
+}
+
+// Status returns current channel status (implements ManagedChannel)
+func (b *Bot) Status() chtypes.ChannelStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return chtypes.ChannelStatus{
+		Running:   b.running,
+		Connected: b.connected,
+		Error:     b.lastError,
+		StartedAt: b.startedAt,
+		Info:      b.client.UserID,
+	}
+}
+
+// Name returns the channel name (implements gateway.Channel)
+func (b *Bot) Name() string {
+	return "matrix"
+}
+
+// Send sends a message to the owner's Matrix DM (implements gateway.Channel)
+func (b *Bot) Send(ctx context.Context, msg string) error {
+	owner := b.users.Owner()
+	if owner == nil || owner.MatrixID == "" {
+		return nil
+	}
+	roomID, err := b.directRoom(ctx, owner.MatrixID)
+	if err != nil {
+		return err
+	}
+	_, err = b.client.SendMessage(ctx, roomID, textContent(api.MsgText, msg))
+	return err
+}
+
+// SendMirror sends a cross-channel mirror summary to the owner (implements gateway.Channel)
+func (b *Bot) SendMirror(ctx context.Context, source, userMsg, response string) error {
+	owner := b.users.Owner()
+	if owner == nil || owner.MatrixID == "" {
+		return nil
+	}
+	roomID, err := b.directRoom(ctx, owner.MatrixID)
+	if err != nil {
+		return err
+	}
+
+	agentName := b.gateway.AgentIdentityFor(b.gateway.ResolveAgent("matrix", "", roomID, owner)).Name
+	mirror := fmt.Sprintf("**%s**\n\n**You:** %s\n\n**%s:** %s",
+		source, truncate(userMsg, 500), agentName, truncate(response, maxMatrixMessage-600))
+
+	_, err = b.client.SendMessage(ctx, roomID, textContent(api.MsgNotice, mirror))
+	if err != nil {
+		L_error("matrix: failed to send mirror", "error", err)
+	}
+	return err
+}
+
+// HasUser returns true if the user has a Matrix identity (implements gateway.Channel)
+func (b *Bot) HasUser(u *user.User) bool {
+	return u.HasMatrixAuth()
+}
+
+// StreamEvent returns false — replies stream through edits, not gateway events (implements gateway.Channel)
+func (b *Bot) StreamEvent(u *user.User, event gateway.AgentEvent) bool {
+	return false
+}
+
+// DeliverGhostwrite sends a ghostwritten message with typing simulation (implements gateway.Channel)
+func (b *Bot) DeliverGhostwrite(ctx context.Context, u *user.User, message string) error {
+	if u == nil || u.MatrixID == "" {
+		return nil
+	}
+	roomID, err := b.directRoom(ctx, u.MatrixID)
+	if err != nil {
+		return err
+	}
+
+	L_info("matrix: ghostwrite", "user", u.ID, "room", roomID, "messageLen", len(message))
+
+	b.setTyping(roomID, true)
+
+	typingDelay := 500 * time.Millisecond
+	if b.gateway != nil {
+		if cfg := b.gateway.Config(); cfg != nil && cfg.Supervision.Ghostwriting.TypingDelayMs > 0 {
+			typingDelay = time.Duration(cfg.Supervision.Ghostwriting.TypingDelayMs) * time.Millisecond
+		}
+	}
+	time.Sleep(typingDelay)
+
+	_, err = b.client.SendMessage(ctx, roomID, textContent(api.MsgText, message))
+	b.setTyping(roomID, false)
+	if err != nil {
+		return fmt.Errorf("failed to send ghostwrite: %w", err)
+	}
+
+	L_info("matrix: ghostwrite delivered", "user", u.ID, "messageLen", len(message))
+	return nil
+}
+
+// directRoom returns the DM room with a Matrix user, creating one if the
+// user hasn't messaged the bot yet
+func (b *Bot) directRoom(ctx context.Context, matrixID string) (string, error) {
+	b.stateMu.Lock()
+	roomID := b.state.DirectRooms[matrixID]
+	b.stateMu.Unlock()
+	if roomID != "" {
+		return roomID, nil
+	}
+
+	roomID, err := b.client.CreateDirectRoom(ctx, matrixID)
+	if err != nil {
+		return "", fmt.Errorf("failed to create DM with %s: %w", matrixID, err)
+	}
+	L_info("matrix: created DM room", "user", matrixID, "room", roomID)
+	b.rememberDirectRoom(matrixID, roomID)
+	return roomID, nil
+}
+
+// rememberDirectRoom records the room a user DMs the bot in
+func (b *Bot) rememberDirectRoom(matrixID, roomID string) {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+	if b.state.DirectRooms[matrixID] == roomID {
+		return
+	}
+	if b.state.DirectRooms == nil {
+		b.state.DirectRooms = make(map[string]string)
+	}
+	b.state.DirectRooms[matrixID] = roomID
+	b.saveStateLocked()
+}
+
+// --- Sync ---
+
+// syncLoop long-polls the homeserver until ctx is cancelled
+func (b *Bot) syncLoop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	backoff := time.Second
+	for ctx.Err() == nil {
+		b.stateMu.Lock()
+		since := b.state.NextBatch
+		b.stateMu.Unlock()
+
+		resp, err := b.client.Sync(ctx, since, syncTimeout, syncFilter)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			b.mu.Lock()
+			b.connected = false
+			b.lastError = err
+			b.mu.Unlock()
+
+			if api.IsErrCode(err, "M_UNKNOWN_TOKEN") && b.config.AccessToken == "" && b.config.Password != "" {
+				L_warn("matrix: access token rejected, logging in again")
+				b.client.AccessToken = ""
+				if err := b.login(ctx); err == nil {
+					continue
+				}
+			}
+
+			L_warn("matrix: sync failed", "error", err, "retryIn", backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, time.Minute)
+			continue
+		}
+
+		backoff = time.Second
+		b.mu.Lock()
+		if !b.connected {
+			L_debug("matrix: sync connected")
+		}
+		b.connected = true
+		b.lastError = nil
+		b.mu.Unlock()
+
+		b.handleSync(resp, since == "")
+
+		b.stateMu.Lock()
+		b.state.NextBatch = resp.NextBatch
+		b.saveStateLocked()
+		b.stateMu.Unlock()
+	}
+}
+
+// handleSync processes one sync response. On the very first sync the
+// timeline is history, so only room state is taken from it.
+func (b *Bot) handleSync(resp *api.SyncResponse, initial bool) {
+	for roomID, invite := range resp.Rooms.Invite {
+		b.handleInvite(roomID, invite)
+	}
+
+	for roomID := range resp.Rooms.Leave {
+		b.stateMu.Lock()
+		delete(b.rooms, roomID)
+		b.stateMu.Unlock()
+	}
+
+	for roomID, jr := range resp.Rooms.Join {
+		b.stateMu.Lock()
+		rs := b.roomLocked(roomID)
+		if jr.Summary.JoinedMemberCount != nil {
+			rs.members = *jr.Summary.JoinedMemberCount
+		}
+		for _, ev := range slices.Concat(jr.State.Events, jr.Timeline.Events) {
+			switch {
+			case ev.Type == api.EventEncryption:
+				rs.encrypted = true
+			case ev.Type == api.EventMember && jr.Summary.JoinedMemberCount == nil:
+				// Membership changed without a summary: recount on the next message
+				rs.members = 0
+			}
+		}
+		b.stateMu.Unlock()
+
+		if initial {
+			L_debug("matrix: skipping history on initial sync", "room", roomID, "events", len(jr.Timeline.Events))
+			continue
+		}
+		for _, ev := range jr.Timeline.Events {
+			b.handleEvent(roomID, ev)
+		}
+	}
+}
+
+// roomLocked returns the state of a room, creating it (stateMu held)
+func (b *Bot) roomLocked(roomID string) *roomState {
+	rs := b.rooms[roomID]
+	if rs == nil {
+		rs = &roomState{}
+		b.rooms[roomID] = rs
+	}
+	return rs
+}
+
+// handleInvite joins rooms that known users invite the bot to
+func (b *Bot) handleInvite(roomID string, invite api.InvitedRoom) {
+	inviter := ""
+	for _, ev := range invite.InviteState.Events {
+		var member api.MemberContent
+		if ev.Type == api.EventMember && ev.StateKey != nil && *ev.StateKey == b.client.UserID &&
+			ev.ParseContent(&member) == nil && member.Membership == "invite" {
+			inviter = ev.Sender
+		}
+	}
+	if inviter == "" {
+		return
+	}
+
+	if !b.config.AutoJoinEnabled() {
+		L_info("matrix: invite ignored (autoJoin off)", "room", roomID, "inviter", inviter)
+		return
+	}
+	if b.users.FromMatrixID(inviter) == nil {
+		L_warn("matrix: invite from unknown user ignored", "room", roomID, "inviter", inviter)
+		return
+	}
+
+	if _, err := b.client.JoinRoom(b.ctx, roomID); err != nil {
+		L_error("matrix: failed to join room", "room", roomID, "error", err)
+		return
+	}
+	L_info("matrix: joined room", "room", roomID, "inviter", inviter)
+}
+
+// handleEvent dispatches a timeline event
+func (b *Bot) handleEvent(roomID string, ev api.Event) {
+	if ev.Sender == b.client.UserID {
+		return
+	}
+
+	switch ev.Type {
+	case api.EventEncrypted:
+		b.handleEncrypted(roomID, ev)
+
+	case api.EventMessage:
+		var content api.MessageContent
+		if err := ev.ParseContent(&content); err != nil {
+			L_debug("matrix: unparseable message", "room", roomID, "event", ev.EventID, "error", err)
+			return
+		}
+		// Edits of earlier messages are not new input
+		if content.RelatesTo != nil && content.RelatesTo.RelType == api.RelReplace {
+			return
+		}
+		// Agent runs take a while; don't hold up the sync loop
+		go b.handleMessage(roomID, ev, &content)
+	}
+}
+
+// handleEncrypted tells a known user once per room that encrypted messages can't be read
+func (b *Bot) handleEncrypted(roomID string, ev api.Event) {
+	if b.users.FromMatrixID(ev.Sender) == nil {
+		return
+	}
+
+	b.stateMu.Lock()
+	rs := b.roomLocked(roomID)
+	rs.encrypted = true
+	warned := rs.warned
+	rs.warned = true
+	b.stateMu.Unlock()
+	if warned {
+		return
+	}
+
+	L_warn("matrix: encrypted message can't be read (use Pantalaimon for E2EE)", "room", roomID, "sender", ev.Sender)
+	_, _ = b.client.SendMessage(b.ctx, roomID, &api.MessageContent{MsgType: api.MsgNotice, Body: encryptedRoomNotice})
+}
+
+// isGroupRoom reports whether a room has more than the bot and one user
+func (b *Bot) isGroupRoom(roomID string) bool {
+	b.stateMu.Lock()
+	members := b.roomLocked(roomID).members
+	b.stateMu.Unlock()
+
+	if members == 0 {
+		joined, err := b.client.JoinedMembers(b.ctx, roomID)
+		if err != nil {
+			// Err on the side of the stricter group rules
+			L_warn("matrix: failed to count room members", "room", roomID, "error", err)
+			return true
+		}
+		members = len(joined)
+		b.stateMu.Lock()
+		b.roomLocked(roomID).members = members
+		b.stateMu.Unlock()
+	}
+	return members > 2
+}
+
+// handleMessage processes an incoming Matrix message
+func (b *Bot) handleMessage(roomID string, ev api.Event, content *api.MessageContent) {
+	// Bots talk to each other with notices; never answer one
+	if content.MsgType == api.MsgNotice {
+		return
+	}
+
+	u := b.users.FromMatrixID(ev.Sender)
+	if u == nil {
+		L_warn("matrix: unknown user ignored", "sender", ev.Sender, "room", roomID)
+		return
+	}
+
+	isGroup := b.isGroupRoom(roomID)
+	text := content.Body
+	if isGroup {
+		if !slices.Contains(b.config.Rooms, roomID) {
+			L_debug("matrix: ignoring message in room not in allowlist", "room", roomID, "sender", ev.Sender)
+			return
+		}
+		if b.config.MentionRequired() {
+			mentioned, stripped := content.Mentioned(b.client.UserID, b.displayName)
+			if !mentioned {
+				return
+			}
+			text = stripped
+		}
+	} else {
+		b.rememberDirectRoom(ev.Sender, roomID)
+	}
+
+	L_info("matrix: authenticated message", "user", u.Name, "role", u.Role, "room", roomID, "isGroup", isGroup)
+	_ = b.client.ReadReceipt(b.ctx, roomID, ev.EventID)
+
+	var contentBlocks []itypes.ContentBlock
+	switch content.MsgType {
+	case api.MsgText, api.MsgEmote:
+	case api.MsgImage:
+		block, err := b.downloadMedia(content, "image")
+		if err != nil {
+			L_error("matrix: failed to download image", "error", err)
+			return
+		}
+		contentBlocks = append(contentBlocks, *block)
+		text = mediaText(content, text, "<media:image>")
+	case api.MsgAudio:
+		block, err := b.downloadMedia(content, "voice")
+		if err != nil {
+			L_error("matrix: failed to download audio", "error", err)
+			return
+		}
+		contentBlocks = append(contentBlocks, *block)
+		text = mediaText(content, text, "[Voice note received]")
+	default:
+		L_debug("matrix: unsupported message type, ignoring", "msgtype", content.MsgType)
+		return
+	}
+
+	// Check for panic phrase (emergency stop) before commands
+	// Always attempt cancel and confirm - avoids race conditions where session just finished
+	if commands.IsPanicPhrase(text) {
+		b.gateway.StopAllUserSessions(u.ID)
+		b.sendNotice(roomID, "Stopping all tasks.")
+		return
+	}
+
+	// Check for commands
+	if commands.IsCommand(text) {
+		b.handleCommand(u, roomID, text)
+		return
+	}
+
+	// Check for /thinking (channel-specific)
+	if strings.HasPrefix(text, "/thinking") {
+		b.handleThinkingCommand(u, roomID, text)
+		return
+	}
+
+	b.setTyping(roomID, true)
+
+	prefs := b.getChatPrefs(roomID, u)
+
+	req := gateway.AgentRequest{
+		User:           u,
+		Source:         "matrix",
+		ChatID:         roomID,
+		IsGroup:        isGroup,
+		UserMsg:        text,
+		ContentBlocks:  contentBlocks,
+		EnableThinking: prefs.ShowThinking,
+		ThinkingLevel:  prefs.ThinkingLevel,
+		OnMediaToSend: func(path, caption string) error {
+			_, err := b.sendMediaFile(roomID, path, caption)
+			return err
+		},
+	}
+
+	evChan := make(chan gateway.AgentEvent, 100)
+
+	go func() {
+		if err := b.gateway.RunAgent(b.ctx, req, evChan); err != nil {
+			L_error("matrix: agent error", "error", err)
+		}
+	}()
+
+	b.streamResponse(roomID, evChan, prefs)
+}
+
+// mediaText picks the user text for a media message: the caption, else a placeholder
+func mediaText(content *api.MessageContent, text, placeholder string) string {
+	if content.Caption() == "" {
+		return placeholder
+	}
+	return text
+}
+
+// handleCommand routes commands to the global command manager
+func (b *Bot) handleCommand(u *user.User, roomID, text string) {
+	if !b.canUserUseCommands(u) {
+		L_debug("matrix: commands disabled for user", "user", u.Name, "command", text)
+		return
+	}
+
+	sessionKey := b.getSessionKey(u)
+	mgr := commands.GetManager()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result := mgr.Execute(ctx, text, sessionKey, u.ID)
+	if _, err := b.client.SendMessage(b.ctx, roomID, textContent(api.MsgNotice, result.Markdown)); err != nil {
+		L_error("matrix: failed to send command result", "error", err)
+	}
+}
+
+// handleThinkingCommand handles the /thinking channel preference toggle
+func (b *Bot) handleThinkingCommand(u *user.User, roomID, text string) {
+	if !b.canUserUseCommands(u) {
+		return
+	}
+
+	prefs := b.getChatPrefs(roomID, u)
+
+	arg := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(text, "/thinking")))
+
+	var resultMsg string
+	switch arg {
+	case "on":
+		prefs.ShowThinking = true
+		if prefs.ThinkingLevel == "" || prefs.ThinkingLevel == "off" {
+			prefs.ThinkingLevel = llm.DefaultThinkingLevel.String()
+		}
+		resultMsg = fmt.Sprintf("Thinking output enabled (level: %s).", prefs.ThinkingLevel)
+	case "off":
+		prefs.ShowThinking = false
+		prefs.ThinkingLevel = "off"
+		resultMsg = "Thinking output disabled."
+	case "toggle", "":
+		prefs.ShowThinking = !prefs.ShowThinking
+		if prefs.ShowThinking {
+			if prefs.ThinkingLevel == "" || prefs.ThinkingLevel == "off" {
+				prefs.ThinkingLevel = llm.DefaultThinkingLevel.String()
+			}
+			resultMsg = fmt.Sprintf("Thinking output enabled (level: %s).", prefs.ThinkingLevel)
+		} else {
+			resultMsg = "Thinking output disabled."
+		}
+	case "status":
+		if prefs.ShowThinking {
+			level := prefs.ThinkingLevel
+			if level == "" {
+				level = llm.DefaultThinkingLevel.String()
+			}
+			resultMsg = fmt.Sprintf("Thinking output: ON, level: %s", level)
+		} else {
+			resultMsg = "Thinking output: OFF"
+		}
+	default:
+		if llm.IsValidThinkingLevel(arg) {
+			prefs.ThinkingLevel = arg
+			if arg == "off" {
+				prefs.ShowThinking = false
+				resultMsg = "Thinking disabled."
+			} else {
+				prefs.ShowThinking = true
+				resultMsg = fmt.Sprintf("Thinking level set to %s (output enabled).", arg)
+			}
+		} else {
+			resultMsg = "Usage: /thinking [on|off|toggle|status|minimal|low|medium|high|xhigh]"
+		}
+	}
+
+	b.sendNotice(roomID, resultMsg)
+}
+
+// streamResponse consumes agent events, streaming the reply into one
+// message that is edited as text arrives
+func (b *Bot) streamResponse(roomID string, evChan <-chan gateway.AgentEvent, prefs *ChatPreferences) {
+	var response strings.Builder
+	var thinkingBuf strings.Builder
+	var currentMsg string // Event ID of the streaming message
+	var lastUpdate, lastTyping time.Time
+	toolsActive := false
+
+	for event := range evChan {
+		switch e := event.(type) {
+		case gateway.EventTextDelta:
+			response.WriteString(e.Delta)
+
+			// With thinking shown, hold text back while tools run so the
+			// tool notices and the reply stay in timeline order
+			if prefs.ShowThinking && toolsActive {
+				continue
+			}
+			if time.Since(lastUpdate) < streamInterval {
+				continue
+			}
+			if currentMsg == "" {
+				id, err := b.client.SendMessage(b.ctx, roomID, plainContent(response.String()))
+				if err != nil {
+					L_error("matrix: failed to send initial message", "error", err)
+					continue
+				}
+				currentMsg = id
+			} else if _, err := b.client.Edit(b.ctx, roomID, currentMsg, plainContent(response.String())); err != nil {
+				L_trace("matrix: edit failed", "error", err)
+			}
+			lastUpdate = time.Now()
+			if time.Since(lastTyping) > typingTimeout/2 {
+				b.setTyping(roomID, true)
+				lastTyping = time.Now()
+			}
+
+		case gateway.EventThinkingDelta:
+			if prefs.ShowThinking {
+				thinkingBuf.WriteString(e.Delta)
+			}
+
+		case gateway.EventThinking:
+			if prefs.ShowThinking && e.Content != "" {
+				b.sendThinking(roomID, e.Content)
+				thinkingBuf.Reset()
+			}
+
+		case gateway.EventToolStart:
+			toolsActive = true
+			b.setTyping(roomID, true)
+			lastTyping = time.Now()
+			if prefs.ShowThinking {
+				// Flush accumulated thinking before tools
+				if thinkingBuf.Len() > 0 {
+					b.sendThinking(roomID, thinkingBuf.String())
+					thinkingBuf.Reset()
+				}
+
+				inputStr := string(e.Input)
+				if len(inputStr) > 1024 {
+					inputStr = inputStr[:1024] + "..."
+				}
+				toolMsg := fmt.Sprintf("⚙️ **%s**\n```\n%s\n```", e.ToolName, inputStr)
+				_, _ = b.client.SendMessage(b.ctx, roomID, textContent(api.MsgNotice, toolMsg))
+			}
+
+		case gateway.EventToolEnd:
+			toolsActive = false
+
+		case gateway.EventAgentEnd:
+			b.setTyping(roomID, false)
+
+			finalText := e.FinalText
+			if finalText == "" {
+				finalText = response.String()
+			}
+			if finalText == "" {
+				finalText = "(No response)"
+			}
+
+			if media.ContainsMediaRefs(finalText) {
+				// Send text and media in order; drop the streamed copy
+				if currentMsg != "" {
+					_, _ = b.client.Redact(b.ctx, roomID, currentMsg, "")
+				}
+				b.sendWithMediaRefs(roomID, finalText)
+				continue
+			}
+
+			chunks := splitMessage(finalText, maxMatrixMessage)
+			for i, chunk := range chunks {
+				var err error
+				if i == 0 && currentMsg != "" {
+					_, err = b.client.Edit(b.ctx, roomID, currentMsg, textContent(api.MsgText, chunk))
+				} else {
+					_, err = b.client.SendMessage(b.ctx, roomID, textContent(api.MsgText, chunk))
+				}
+				if err != nil {
+					L_error("matrix: failed to send message chunk", "error", err, "chunk", i+1)
+				}
+			}
+
+		case gateway.EventAgentMerged:
+			// Reply arrives through the run the message was merged into
+			b.setTyping(roomID, false)
+			L_debug("matrix: message merged into running turn", "mode", e.Mode)
+			b.sendNotice(roomID, e.Notice())
+
+		case gateway.EventAgentError:
+			b.setTyping(roomID, false)
+			L_error("matrix: agent error", "error", e.Error)
+			errMsg := fmt.Sprintf("Error: %s", e.Error)
+			if currentMsg != "" {
+				_, _ = b.client.Edit(b.ctx, roomID, currentMsg, plainContent(errMsg))
+			} else {
+				b.sendNotice(roomID, errMsg)
+			}
+		}
+	}
+}
+
+// sendThinking sends thinking output as an italic notice
+func (b *Bot) sendThinking(roomID, thinking string) {
+	thinking = truncate(thinking, maxMatrixMessage-30)
+	content := &api.MessageContent{
+		MsgType:       api.MsgNotice,
+		Body:          "💭 " + thinking,
+		Format:        api.FormatHTML,
+		FormattedBody: "💭 <em>" + strings.ReplaceAll(html.EscapeString(thinking), "\n", "<br>") + "</em>",
+	}
+	_, _ = b.client.SendMessage(b.ctx, roomID, content)
+}
+
+// sendNotice sends a plain m.notice
+func (b *Bot) sendNotice(roomID, text string) {
+	if _, err := b.client.SendMessage(b.ctx, roomID, &api.MessageContent{MsgType: api.MsgNotice, Body: text}); err != nil {
+		L_error("matrix: failed to send notice", "room", roomID, "error", err)
+	}
+}
+
+// setTyping turns the typing notification on or off, ignoring errors
+func (b *Bot) setTyping(roomID string, typing bool) {
+	if err := b.client.Typing(b.ctx, roomID, typing, typingTimeout); err != nil {
+		L_trace("matrix: typing failed", "room", roomID, "error", err)
+	}
+}
+
+// downloadMedia downloads message media, saves it, and returns a ContentBlock
+func (b *Bot) downloadMedia(content *api.MessageContent, category string) (*itypes.ContentBlock, error) {
+	if content.URL == "" {
+		if len(content.File) > 0 {
+			return nil, errors.New("encrypted media is not supported")
+		}
+		return nil, errors.New("message has no media URL")
+	}
+
+	data, contentType, err := b.client.Download(b.ctx, content.URL)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+
+	mimeType := contentType
+	if content.Info != nil && content.Info.MimeType != "" {
+		mimeType = content.Info.MimeType
+	}
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		if detected := media.DetectMIME(data); detected != "" {
+			mimeType = detected
+		}
+	}
+
+	L_debug("matrix: media downloaded", "category", category, "size", len(data), "mime", mimeType)
+
+	if b.gateway == nil || b.gateway.MediaStore() == nil {
+		return nil, fmt.Errorf("no media store available")
+	}
+
+	absPath, _, err := b.gateway.MediaStore().Save(data, category, mimeToExt(mimeType, content.FileName))
+	if err != nil {
+		return nil, fmt.Errorf("save failed: %w", err)
+	}
+
+	blockType := "audio"
+	if strings.HasPrefix(mimeType, "image/") {
+		blockType = "image"
+	}
+
+	return &itypes.ContentBlock{
+		Type:     blockType,
+		FilePath: absPath,
+		MimeType: mimeType,
+		Source:   "matrix",
+	}, nil
+}
+
+// sendMediaFile uploads a file and sends it to a room, returning the event ID
+func (b *Bot) sendMediaFile(roomID, filePath, caption string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+
+	mimeType, _ := media.DetectMimeType(filePath)
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	filename := filepath.Base(filePath)
+	mxc, err := b.client.Upload(b.ctx, data, mimeType, filename)
+	if err != nil {
+		return "", fmt.Errorf("upload: %w", err)
+	}
+
+	content := &api.MessageContent{
+		MsgType: mimeToMsgType(mimeType),
+		Body:    filename,
+		URL:     mxc,
+		Info:    &api.FileInfo{MimeType: mimeType, Size: len(data)},
+	}
+	if caption != "" {
+		content.Body = caption
+		content.FileName = filename
+	}
+	return b.client.SendMessage(b.ctx, roomID, content)
+}
+
+// sendWithMediaRefs parses and sends text with inline media references
+func (b *Bot) sendWithMediaRefs(roomID, text string) {
+	segments := media.SplitMediaSegments(text)
+
+	var mediaRoot string
+	if b.gateway != nil && b.gateway.MediaStore() != nil {
+		mediaRoot = b.gateway.MediaStore().BaseDir()
+	}
+
+	for _, seg := range segments {
+		if !seg.IsMedia {
+			if strings.TrimSpace(seg.Text) != "" {
+				_, _ = b.client.SendMessage(b.ctx, roomID, textContent(api.MsgText, seg.Text))
+			}
+			continue
+		}
+
+		if strings.HasPrefix(seg.Mime, "error/") {
+			errType := strings.TrimPrefix(seg.Mime, "error/")
+			b.sendNotice(roomID, fmt.Sprintf("[Media %s: %s]", errType, seg.Path))
+			continue
+		}
+
+		absPath, err := media.ResolveMediaPath(mediaRoot, seg.Path)
+		if err != nil {
+			L_warn("matrix: failed to resolve media path", "path", seg.Path, "error", err)
+			continue
+		}
+
+		if _, err := b.sendMediaFile(roomID, absPath, ""); err != nil {
+			L_warn("matrix: failed to send media", "path", absPath, "error", err)
+		}
+	}
+}
+
+// getChatPrefs returns preferences for a room, initializing from user prefs if needed
+func (b *Bot) getChatPrefs(roomID string, u *user.User) *ChatPreferences {
+	if prefs, ok := b.chatPrefs.Load(roomID); ok {
+		return prefs.(*ChatPreferences) //nolint:errcheck // type assertion safe
+	}
+	prefs := &ChatPreferences{}
+	if u != nil {
+		prefs.ShowThinking = u.Thinking
+		prefs.ThinkingLevel = u.ThinkingLevel
+	}
+	actual, _ := b.chatPrefs.LoadOrStore(roomID, prefs)
+	return actual.(*ChatPreferences) //nolint:errcheck // type assertion safe
+}
+
+// getSessionKey returns the session key for a user
+func (b *Bot) getSessionKey(u *user.User) string {
+	if u.Role == "owner" {
+		return session.PrimarySession
+	}
+	return fmt.Sprintf("user:%s", u.ID)
+}
+
+// canUserUseCommands checks if the user has permission to use slash commands
+func (b *Bot) canUserUseCommands(u *user.User) bool {
+	if u == nil {
+		return false
+	}
+	resolvedRole, err := b.users.ResolveUserRole(u)
+	if err != nil {
+		L_warn("matrix: failed to resolve role for command check", "user", u.Name, "error", err)
+		return false
+	}
+	return resolvedRole.CanUseCommands()
+}
+
+// --- Session state ---
+
+// loadState reads the cached session; a missing or corrupt file starts fresh
+func (b *Bot) loadState() {
+	data, err := os.ReadFile(b.statePath)
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &b.state); err != nil {
+		L_warn("matrix: ignoring corrupt session state", "path", b.statePath, "error", err)
+		b.state = sessionState{}
+	}
+}
+
+// saveStateLocked writes the session state (stateMu held)
+func (b *Bot) saveStateLocked() {
+	data, err := json.MarshalIndent(b.state, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := paths.EnsureParentDir(b.statePath); err != nil {
+		L_warn("matrix: failed to save session state", "error", err)
+		return
+	}
+	// Holds an access token
+	if err := os.WriteFile(b.statePath, data, 0600); err != nil {
+		L_warn("matrix: failed to save session state", "error", err)
+	}
+}
+
+// mimeToMsgType maps a MIME type to a Matrix msgtype
+func mimeToMsgType(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return api.MsgImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return api.MsgVideo
+	case strings.HasPrefix(mimeType, "audio/"):
+		return api.MsgAudio
+	default:
+		return api.MsgFile
+	}
+}
+
+// mimeToExt returns a file extension for downloaded media, preferring the sender's file name
+func mimeToExt(mimeType, filename string) string {
+	if ext := filepath.Ext(filename); ext != "" && len(ext) <= 6 {
+		return strings.ToLower(ext)
+	}
+	switch strings.TrimSpace(strings.Split(mimeType, ";")[0]) {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	case "audio/ogg":
+		return ".ogg"
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/mp4":
+		return ".m4a"
+	default:
+		return ".bin"
+	}
+}
@@ -0,0 +1,193 @@
+// Package config defines the Matrix channel configuration.
+// Separate package to avoid import cycles with gateway.
+package config
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/bus"
+	"github.com/roelfdiedericks/goclaw/internal/channels/matrix/api"
+	"github.com/roelfdiedericks/goclaw/internal/config/forms"
+	"github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// Config holds the Matrix channel configuration.
+// The access token, device ID and sync position obtained from a password
+// login are cached in ~/.goclaw/matrix.json, not here.
+type Config struct {
+	Enabled        bool     `json:"enabled"`
+	Homeserver     string   `json:"homeserver"`               // Client-server API URL (or a Pantalaimon proxy for E2EE)
+	UserID         string   `json:"userId"`                   // Bot account, e.g. @goclaw:example.org
+	AccessToken    string   `json:"accessToken,omitempty"`    // Use this token instead of logging in
+	Password       string   `json:"password,omitempty"`       // Log in with a password when no token is set
+	Rooms          []string `json:"rooms,omitempty"`          // Group rooms (IDs) the bot answers in; DMs are always allowed
+	RequireMention *bool    `json:"requireMention,omitempty"` // In group rooms, only answer when mentioned (default true)
+	AutoJoin       *bool    `json:"autoJoin,omitempty"`       // Accept invites from known users (default true)
+}
+
+// MentionRequired reports whether group rooms need a mention (default true)
+func (c *Config) MentionRequired() bool {
+	return c.RequireMention == nil || *c.RequireMention
+}
+
+// AutoJoinEnabled reports whether invites from known users are accepted (default true)
+func (c *Config) AutoJoinEnabled() bool {
+	return c.AutoJoin == nil || *c.AutoJoin
+}
+
+// Check validates the connection settings
+func (c *Config) Check() error {
+	if c.Homeserver == "" {
+		return fmt.Errorf("homeserver URL is required")
+	}
+	if !strings.HasPrefix(c.Homeserver, "https://") && !strings.HasPrefix(c.Homeserver, "http://") {
+		return fmt.Errorf("homeserver must be an http(s) URL: %s", c.Homeserver)
+	}
+	if c.AccessToken == "" {
+		if c.UserID == "" || c.Password == "" {
+			return fmt.Errorf("either an access token or a user ID and password is required")
+		}
+	}
+	if c.UserID != "" && (!strings.HasPrefix(c.UserID, "@") || !strings.Contains(c.UserID, ":")) {
+		return fmt.Errorf("user ID must look like @name:server, got %q", c.UserID)
+	}
+	for _, room := range c.Rooms {
+		if !strings.HasPrefix(room, "!") {
+			return fmt.Errorf("rooms must be room IDs (!id:server), got %q", room)
+		}
+	}
+	return nil
+}
+
+// ConfigFormDef returns the form definition for editing Matrix config
+func ConfigFormDef() forms.FormDef {
+	return forms.FormDef{
+		Title:       "Matrix",
+		Description: "Configure the Matrix channel",
+		Sections: []forms.Section{
+			{
+				Title: "Connection",
+				Fields: []forms.Field{
+					{Name: "enabled", Title: "Enabled", Desc: "Enable the Matrix channel", Type: forms.Toggle},
+					{Name: "homeserver", Title: "Homeserver", Desc: "Client-server API URL, e.g. https://matrix.example.org (point at Pantalaimon for encrypted rooms)", Type: forms.Text},
+					{Name: "userId", Title: "User ID", Desc: "Bot account, e.g. @goclaw:example.org", Type: forms.Text},
+					{Name: "accessToken", Title: "Access Token", Desc: "Access token for the bot account (leave empty to log in with the password)", Type: forms.Secret},
+					{Name: "password", Title: "Password", Desc: "Password for the bot account, used when no access token is set", Type: forms.Secret},
+				},
+			},
+			{
+				Title: "Rooms",
+				Fields: []forms.Field{
+					{Name: "rooms", Title: "Group Rooms", Desc: "Room IDs the bot answers in (DMs from known users are always allowed)", Type: forms.StringList},
+					{Name: "requireMention", Title: "Require Mention", Desc: "In group rooms, only answer when the bot is mentioned", Type: forms.Toggle, Default: true},
+					{Name: "autoJoin", Title: "Auto-Join", Desc: "Accept room invites from known users", Type: forms.Toggle, Default: true},
+				},
+			},
+		},
+		Actions: []forms.ActionDef{
+			{
+				Name:  "test",
+				Label: "Test Connection",
+				Desc:  "Log in to the homeserver with these credentials",
+			},
+			{
+				Name:  "apply",
+				Label: "Apply Now",
+				Desc:  "Apply changes to running Matrix channel (requires gateway)",
+			},
+		},
+	}
+}
+
+const configPath = "channels.matrix"
+
+// RegisterCommands registers matrix config command handlers
+func RegisterCommands() {
+	bus.RegisterCommand(configPath, "test", handleTest)
+	bus.RegisterCommand(configPath, "apply", handleApply)
+}
+
+// UnregisterCommands unregisters matrix config command handlers
+func UnregisterCommands() {
+	bus.UnregisterComponent(configPath)
+}
+
+func handleApply(cmd bus.Command) bus.CommandResult {
+	cfg, ok := cmd.Payload.(*Config)
+	if !ok {
+		return bus.CommandResult{
+			Error:   fmt.Errorf("invalid payload type: expected *Config, got %T", cmd.Payload),
+			Message: "Internal error: invalid config type",
+		}
+	}
+
+	logging.L_info("matrix: config applied", "enabled", cfg.Enabled, "homeserver", cfg.Homeserver)
+	bus.PublishEvent(configPath+".config.applied", cfg)
+
+	return bus.CommandResult{
+		Success: true,
+		Message: "Config applied - channel will restart if needed",
+	}
+}
+
+// handleTest checks the credentials against the homeserver
+func handleTest(cmd bus.Command) bus.CommandResult {
+	cfg, ok := cmd.Payload.(*Config)
+	if !ok {
+		return bus.CommandResult{
+			Error:   fmt.Errorf("invalid payload type"),
+			Message: "Internal error: invalid config type",
+		}
+	}
+
+	if err := cfg.Check(); err != nil {
+		return bus.CommandResult{Error: err, Message: err.Error()}
+	}
+
+	userID, err := TestLogin(cfg)
+	if err != nil {
+		logging.L_warn("matrix: test connection failed", "error", err)
+		return bus.CommandResult{
+			Error:   err,
+			Message: fmt.Sprintf("Connection failed: %s", err),
+		}
+	}
+
+	logging.L_info("matrix: test connection successful", "user", userID)
+	return bus.CommandResult{
+		Success: true,
+		Message: fmt.Sprintf("Connected as %s", userID),
+	}
+}
+
+// TestLogin checks the access token (whoami) or the password and returns the
+// account's user ID. A password test creates no lasting device: the session
+// it opens is logged out again.
+func TestLogin(cfg *Config) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	client := api.NewClient(cfg.Homeserver, cfg.AccessToken)
+	if cfg.AccessToken != "" {
+		who, err := client.Whoami(ctx)
+		if err != nil {
+			return "", err
+		}
+		if cfg.UserID != "" && who.UserID != cfg.UserID {
+			return "", fmt.Errorf("token belongs to %s, not %s", who.UserID, cfg.UserID)
+		}
+		return who.UserID, nil
+	}
+
+	resp, err := client.Login(ctx, cfg.UserID, cfg.Password, "", "GoClaw (test)")
+	if err != nil {
+		return "", err
+	}
+	if err := client.Logout(ctx); err != nil {
+		logging.L_debug("matrix: test logout failed", "error", err)
+	}
+	return resp.UserID, nil
+}
@@ -0,0 +1,89 @@
+package matrix
+
+import (
+	"bytes"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+
+	"github.com/roelfdiedericks/goclaw/internal/channels/matrix/api"
+)
+
+// markdown converts agent markdown to HTML. Matrix clients render a safe
+// subset of HTML (org.matrix.custom.html), which covers everything goldmark
+// produces for GFM, tables included. Raw HTML in the markdown is escaped.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(gmhtml.WithHardWraps()),
+)
+
+// FormatMessage converts markdown to Matrix HTML.
+// If conversion fails, returns the escaped markdown as fallback.
+func FormatMessage(text string) string {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(text), &buf); err != nil {
+		return html.EscapeString(text)
+	}
+	result := strings.TrimSpace(buf.String())
+	// Drop the wrapping paragraph of single-line messages so they don't get
+	// paragraph spacing in clients
+	if strings.HasPrefix(result, "<p>") && strings.HasSuffix(result, "</p>") && strings.Count(result, "<p>") == 1 {
+		result = strings.TrimSuffix(strings.TrimPrefix(result, "<p>"), "</p>")
+	}
+	return result
+}
+
+// textContent builds a message with the markdown as plain body and, when
+// the markdown has any formatting, an HTML formatted_body
+func textContent(msgType, text string) *api.MessageContent {
+	content := &api.MessageContent{MsgType: msgType, Body: text}
+	if formatted := FormatMessage(text); formatted != html.EscapeString(text) {
+		content.Format = api.FormatHTML
+		content.FormattedBody = formatted
+	}
+	return content
+}
+
+// plainContent builds an unformatted message (used while streaming)
+func plainContent(text string) *api.MessageContent {
+	return &api.MessageContent{MsgType: api.MsgText, Body: text}
+}
+
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen] + "..."
+}
+
+// splitMessage splits a message into chunks that fit the Matrix event size limit
+func splitMessage(text string, maxLen int) []string {
+	if len(text) <= maxLen {
+		return []string{text}
+	}
+
+	var chunks []string
+	for len(text) > 0 {
+		end := maxLen
+		if end > len(text) {
+			end = len(text)
+		}
+		// Try to split at a newline
+		if end < len(text) {
+			if idx := strings.LastIndex(text[:end], "\n"); idx > end/2 {
+				end = idx + 1
+			} else {
+				for end > 0 && !utf8.RuneStart(text[end]) {
+					end--
+				}
+			}
+		}
+		chunks = append(chunks, text[:end])
+		text = text[end:]
+	}
+	return chunks
+}
@@ -0,0 +1,644 @@
+// Package matrixtest provides an in-process fake Matrix homeserver for tests
+// and development. It speaks enough of the client-server API for the Matrix
+// channel: password login, long-poll sync, send (with transaction de-dupe),
+// redact, typing, receipts, joins, room creation, profiles and media.
+// Rooms, invites and messages from other users are scripted with CreateRoom,
+// Invite and Send; what the client did is read back with Events, Typing and
+// Receipt.
+package matrixtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/channels/matrix/api"
+	"github.com/roelfdiedericks/goclaw/internal/channels/matrix/config"
+)
+
+// ServerName is the homeserver's domain part of IDs
+const ServerName = "localhost"
+
+// Server is a fake Matrix homeserver.
+type Server struct {
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	changed   chan struct{} // Closed and replaced on every new log entry
+	seq       int
+	passwords map[string]string // userID -> password
+	names     map[string]string // userID -> display name
+	tokens    map[string]string // access token -> userID
+	rooms     map[string]*room
+	log       []entry
+	txns      map[string]string // token + txn ID -> event ID
+	typing    map[string]map[string]bool
+	receipts  map[string]map[string]string
+	media     map[string]mediaItem
+	rateLimit int
+	noV1Media bool
+}
+
+type room struct {
+	members   map[string]bool
+	encrypted bool
+}
+
+// entry is one position in the sync stream: a room event or an invite
+type entry struct {
+	roomID string
+	event  api.Event
+	invite string // Invited user ID for invite entries
+}
+
+type mediaItem struct {
+	data        []byte
+	contentType string
+}
+
+// NewServer starts a fake homeserver with no users or rooms.
+func NewServer() *Server {
+	s := &Server{
+		changed:   make(chan struct{}),
+		passwords: make(map[string]string),
+		names:     make(map[string]string),
+		tokens:    make(map[string]string),
+		rooms:     make(map[string]*room),
+		txns:      make(map[string]string),
+		typing:    make(map[string]map[string]bool),
+		receipts:  make(map[string]map[string]string),
+		media:     make(map[string]mediaItem),
+	}
+
+	const c = "/_matrix/client/v3"
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+c+"/login", s.handleLogin)
+	mux.HandleFunc("POST "+c+"/logout", s.authed(s.handleLogout))
+	mux.HandleFunc("GET "+c+"/account/whoami", s.authed(s.handleWhoami))
+	mux.HandleFunc("GET "+c+"/profile/{user}/displayname", s.authed(s.handleDisplayName))
+	mux.HandleFunc("GET "+c+"/sync", s.authed(s.handleSync))
+	mux.HandleFunc("PUT "+c+"/rooms/{room}/send/{type}/{txn}", s.authed(s.handleSend))
+	mux.HandleFunc("PUT "+c+"/rooms/{room}/redact/{event}/{txn}", s.authed(s.handleRedact))
+	mux.HandleFunc("PUT "+c+"/rooms/{room}/typing/{user}", s.authed(s.handleTyping))
+	mux.HandleFunc("POST "+c+"/rooms/{room}/receipt/{type}/{event}", s.authed(s.handleReceipt))
+	mux.HandleFunc("GET "+c+"/rooms/{room}/joined_members", s.authed(s.handleJoinedMembers))
+	mux.HandleFunc("POST "+c+"/join/{room}", s.authed(s.handleJoin))
+	mux.HandleFunc("POST "+c+"/createRoom", s.authed(s.handleCreateRoom))
+	mux.HandleFunc("POST /_matrix/media/v3/upload", s.authed(s.handleUpload))
+	mux.HandleFunc("GET /_matrix/client/v1/media/download/{server}/{id}", s.authed(s.handleDownloadV1))
+	mux.HandleFunc("GET /_matrix/media/v3/download/{server}/{id}", s.handleDownload)
+
+	s.srv = httptest.NewServer(mux)
+	return s
+}
+
+// URL returns the base URL, e.g. http://127.0.0.1:41234
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Config returns a channel config that logs in as userID with a password.
+func (s *Server) Config(userID string) config.Config {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return config.Config{
+		Enabled:    true,
+		Homeserver: s.srv.URL,
+		UserID:     userID,
+		Password:   s.passwords[userID],
+	}
+}
+
+// Close stops the server.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// AddUser registers an account and returns its user ID (@localpart:localhost).
+func (s *Server) AddUser(localpart, password, displayName string) string {
+	userID := "@" + localpart + ":" + ServerName
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.passwords[userID] = password
+	s.names[userID] = displayName
+	return userID
+}
+
+// Token issues an access token for an existing user without a login.
+func (s *Server) Token(userID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token := randomID("syt_")
+	s.tokens[token] = userID
+	return token
+}
+
+// CreateRoom creates a room with the given users already joined.
+func (s *Server) CreateRoom(members ...string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createRoomLocked(members...)
+}
+
+func (s *Server) createRoomLocked(members ...string) string {
+	roomID := randomID("!") + ":" + ServerName
+	r := &room{members: make(map[string]bool)}
+	for _, m := range members {
+		r.members[m] = true
+	}
+	s.rooms[roomID] = r
+	return roomID
+}
+
+// SetEncrypted turns on encryption in a room (m.room.encryption state).
+func (s *Server) SetEncrypted(roomID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := s.rooms[roomID]
+	if r == nil {
+		return
+	}
+	r.encrypted = true
+	stateKey := ""
+	for m := range r.members {
+		s.appendLocked(entry{roomID: roomID, event: api.Event{
+			Type:     api.EventEncryption,
+			Sender:   m,
+			StateKey: &stateKey,
+			Content:  json.RawMessage(`{"algorithm":"m.megolm.v1.aes-sha2"}`),
+		}})
+		break
+	}
+}
+
+// Invite invites target to a room on behalf of inviter.
+func (s *Server) Invite(roomID, inviter, target string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.appendLocked(entry{roomID: roomID, invite: target, event: api.Event{
+		Type:     api.EventMember,
+		Sender:   inviter,
+		StateKey: &target,
+		Content:  json.RawMessage(`{"membership":"invite"}`),
+	}})
+}
+
+// Send injects an event from sender into a room and returns its event ID.
+// content is marshalled to JSON (an *api.MessageContent, a map, ...).
+func (s *Server) Send(roomID, sender, eventType string, content any) string {
+	data, err := json.Marshal(content)
+	if err != nil {
+		panic(fmt.Sprintf("matrixtest: marshal content: %v", err))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.appendLocked(entry{roomID: roomID, event: api.Event{
+		Type:    eventType,
+		Sender:  sender,
+		Content: data,
+	}})
+}
+
+// SendText injects a plain m.text message.
+func (s *Server) SendText(roomID, sender, text string) string {
+	return s.Send(roomID, sender, api.EventMessage, &api.MessageContent{MsgType: api.MsgText, Body: text})
+}
+
+// Events returns a room's events (including the client's own), oldest first.
+func (s *Server) Events(roomID string) []api.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var events []api.Event
+	for _, e := range s.log {
+		if e.roomID == roomID && e.invite == "" {
+			events = append(events, e.event)
+		}
+	}
+	return events
+}
+
+// EventsFrom returns a room's events sent by one user.
+func (s *Server) EventsFrom(roomID, sender string) []api.Event {
+	var events []api.Event
+	for _, ev := range s.Events(roomID) {
+		if ev.Sender == sender {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// Members returns the joined members of a room.
+func (s *Server) Members(roomID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var members []string
+	if r := s.rooms[roomID]; r != nil {
+		for m := range r.members {
+			members = append(members, m)
+		}
+	}
+	return members
+}
+
+// Typing reports whether userID's typing notification is on in a room.
+func (s *Server) Typing(roomID, userID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.typing[roomID][userID]
+}
+
+// Receipt returns the event userID last marked as read in a room.
+func (s *Server) Receipt(roomID, userID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.receipts[roomID][userID]
+}
+
+// UploadMedia stores media as if another user uploaded it and returns its mxc:// URI.
+func (s *Server) UploadMedia(data []byte, contentType string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.storeMediaLocked(data, contentType)
+}
+
+// Media returns stored media by mxc:// URI.
+func (s *Server) Media(mxc string) ([]byte, string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.media[mxc]
+	return m.data, m.contentType, ok
+}
+
+// RateLimit makes the next n send requests fail with M_LIMIT_EXCEEDED.
+func (s *Server) RateLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimit = n
+}
+
+// DisableAuthenticatedMedia makes the v1 download endpoint return 404, like
+// homeservers that predate authenticated media.
+func (s *Server) DisableAuthenticatedMedia() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.noV1Media = true
+}
+
+// appendLocked adds an entry to the sync stream and wakes long-polls.
+func (s *Server) appendLocked(e entry) string {
+	s.seq++
+	if e.event.EventID == "" {
+		e.event.EventID = "$" + strconv.Itoa(s.seq) + randomID("") + ":" + ServerName
+	}
+	e.event.OriginServerTS = time.Now().UnixMilli()
+	s.log = append(s.log, e)
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return e.event.EventID
+}
+
+func (s *Server) storeMediaLocked(data []byte, contentType string) string {
+	mxc := "mxc://" + ServerName + "/" + randomID("")
+	s.media[mxc] = mediaItem{data: data, contentType: contentType}
+	return mxc
+}
+
+// --- HTTP handlers ---
+
+// authed checks the bearer token and passes the user ID to the handler.
+func (s *Server) authed(next func(w http.ResponseWriter, r *http.Request, userID string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		s.mu.Lock()
+		userID := s.tokens[token]
+		s.mu.Unlock()
+		if userID == "" {
+			writeError(w, http.StatusUnauthorized, "M_UNKNOWN_TOKEN", "Unknown access token")
+			return
+		}
+		next(w, r, userID)
+	}
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Type       string `json:"type"`
+		Identifier struct {
+			User string `json:"user"`
+		} `json:"identifier"`
+		Password string `json:"password"`
+		DeviceID string `json:"device_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Type != "m.login.password" {
+		writeError(w, http.StatusBadRequest, "M_BAD_JSON", "Bad login request")
+		return
+	}
+	userID := req.Identifier.User
+	if !strings.HasPrefix(userID, "@") {
+		userID = "@" + userID + ":" + ServerName
+	}
+
+	s.mu.Lock()
+	password, ok := s.passwords[userID]
+	s.mu.Unlock()
+	if !ok || password != req.Password {
+		writeError(w, http.StatusForbidden, "M_FORBIDDEN", "Invalid username or password")
+		return
+	}
+
+	deviceID := req.DeviceID
+	if deviceID == "" {
+		deviceID = strings.ToUpper(randomID(""))[:10]
+	}
+	writeJSON(w, map[string]string{
+		"user_id":      userID,
+		"access_token": s.Token(userID),
+		"device_id":    deviceID,
+	})
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request, _ string) {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	delete(s.tokens, token)
+	s.mu.Unlock()
+	writeJSON(w, map[string]any{})
+}
+
+func (s *Server) handleWhoami(w http.ResponseWriter, _ *http.Request, userID string) {
+	writeJSON(w, map[string]string{"user_id": userID})
+}
+
+func (s *Server) handleDisplayName(w http.ResponseWriter, r *http.Request, _ string) {
+	s.mu.Lock()
+	name, ok := s.names[r.PathValue("user")]
+	s.mu.Unlock()
+	if !ok || name == "" {
+		writeError(w, http.StatusNotFound, "M_NOT_FOUND", "Profile not found")
+		return
+	}
+	writeJSON(w, map[string]string{"displayname": name})
+}
+
+func (s *Server) handleSync(w http.ResponseWriter, r *http.Request, userID string) {
+	since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+	timeoutMs, _ := strconv.Atoi(r.URL.Query().Get("timeout"))
+	deadline := time.After(time.Duration(min(timeoutMs, 30000)) * time.Millisecond)
+
+	for {
+		s.mu.Lock()
+		resp, ok := s.syncLocked(userID, since, r.URL.Query().Get("since") == "")
+		changed := s.changed
+		s.mu.Unlock()
+		if ok || timeoutMs == 0 {
+			writeJSON(w, resp)
+			return
+		}
+		select {
+		case <-changed:
+		case <-deadline:
+			writeJSON(w, resp)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// syncLocked builds a sync response from position since. ok is false when
+// there is nothing new for the user.
+func (s *Server) syncLocked(userID string, since int, initial bool) (*api.SyncResponse, bool) {
+	resp := &api.SyncResponse{NextBatch: strconv.Itoa(len(s.log))}
+	resp.Rooms.Join = make(map[string]api.JoinedRoom)
+	resp.Rooms.Invite = make(map[string]api.InvitedRoom)
+
+	for _, e := range s.log[min(since, len(s.log)):] {
+		r := s.rooms[e.roomID]
+		if r == nil {
+			continue
+		}
+		if e.invite != "" {
+			if e.invite == userID && !r.members[userID] {
+				resp.Rooms.Invite[e.roomID] = api.InvitedRoom{InviteState: api.EventList{Events: []api.Event{e.event}}}
+			}
+			continue
+		}
+		if !r.members[userID] {
+			continue
+		}
+		jr := resp.Rooms.Join[e.roomID]
+		jr.Timeline.Events = append(jr.Timeline.Events, e.event)
+		resp.Rooms.Join[e.roomID] = jr
+	}
+
+	if initial {
+		for roomID, r := range s.rooms {
+			if !r.members[userID] {
+				continue
+			}
+			jr := resp.Rooms.Join[roomID]
+			if r.encrypted {
+				stateKey := ""
+				jr.State.Events = append(jr.State.Events, api.Event{Type: api.EventEncryption, StateKey: &stateKey, Content: json.RawMessage(`{"algorithm":"m.megolm.v1.aes-sha2"}`)})
+			}
+			resp.Rooms.Join[roomID] = jr
+		}
+	}
+
+	for roomID, jr := range resp.Rooms.Join {
+		count := len(s.rooms[roomID].members)
+		jr.Summary.JoinedMemberCount = &count
+		resp.Rooms.Join[roomID] = jr
+	}
+	return resp, len(resp.Rooms.Join) > 0 || len(resp.Rooms.Invite) > 0
+}
+
+func (s *Server) handleSend(w http.ResponseWriter, r *http.Request, userID string) {
+	roomID := r.PathValue("room")
+	txnKey := r.Header.Get("Authorization") + "/" + r.PathValue("txn")
+
+	var content json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&content); err != nil {
+		writeError(w, http.StatusBadRequest, "M_BAD_JSON", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rateLimit > 0 {
+		s.rateLimit--
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_ = json.NewEncoder(w).Encode(map[string]any{"errcode": "M_LIMIT_EXCEEDED", "error": "Too many requests", "retry_after_ms": 10})
+		return
+	}
+	if eventID, ok := s.txns[txnKey]; ok {
+		writeJSON(w, map[string]string{"event_id": eventID})
+		return
+	}
+	if rm := s.rooms[roomID]; rm == nil || !rm.members[userID] {
+		writeError(w, http.StatusForbidden, "M_FORBIDDEN", "Not in room")
+		return
+	}
+	eventID := s.appendLocked(entry{roomID: roomID, event: api.Event{
+		Type:    r.PathValue("type"),
+		Sender:  userID,
+		Content: content,
+	}})
+	s.txns[txnKey] = eventID
+	writeJSON(w, map[string]string{"event_id": eventID})
+}
+
+func (s *Server) handleRedact(w http.ResponseWriter, r *http.Request, userID string) {
+	roomID, target := r.PathValue("room"), r.PathValue("event")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := false
+	for i := range s.log {
+		if s.log[i].roomID == roomID && s.log[i].event.EventID == target {
+			s.log[i].event.Content = json.RawMessage(`{}`)
+			found = true
+		}
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "M_NOT_FOUND", "Event not found")
+		return
+	}
+	content, _ := json.Marshal(map[string]string{"redacts": target})
+	eventID := s.appendLocked(entry{roomID: roomID, event: api.Event{Type: "m.room.redaction", Sender: userID, Content: content}})
+	writeJSON(w, map[string]string{"event_id": eventID})
+}
+
+func (s *Server) handleTyping(w http.ResponseWriter, r *http.Request, userID string) {
+	var req struct {
+		Typing bool `json:"typing"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	roomID := r.PathValue("room")
+	s.mu.Lock()
+	if s.typing[roomID] == nil {
+		s.typing[roomID] = make(map[string]bool)
+	}
+	s.typing[roomID][userID] = req.Typing
+	s.mu.Unlock()
+	writeJSON(w, map[string]any{})
+}
+
+func (s *Server) handleReceipt(w http.ResponseWriter, r *http.Request, userID string) {
+	roomID := r.PathValue("room")
+	s.mu.Lock()
+	if s.receipts[roomID] == nil {
+		s.receipts[roomID] = make(map[string]string)
+	}
+	s.receipts[roomID][userID] = r.PathValue("event")
+	s.mu.Unlock()
+	writeJSON(w, map[string]any{})
+}
+
+func (s *Server) handleJoinedMembers(w http.ResponseWriter, r *http.Request, userID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rm := s.rooms[r.PathValue("room")]
+	if rm == nil || !rm.members[userID] {
+		writeError(w, http.StatusForbidden, "M_FORBIDDEN", "Not in room")
+		return
+	}
+	joined := make(map[string]api.Member)
+	for m := range rm.members {
+		joined[m] = api.Member{DisplayName: s.names[m]}
+	}
+	writeJSON(w, map[string]any{"joined": joined})
+}
+
+func (s *Server) handleJoin(w http.ResponseWriter, r *http.Request, userID string) {
+	roomID := r.PathValue("room")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rm := s.rooms[roomID]
+	if rm == nil {
+		writeError(w, http.StatusNotFound, "M_NOT_FOUND", "No such room")
+		return
+	}
+	rm.members[userID] = true
+	s.appendLocked(entry{roomID: roomID, event: api.Event{
+		Type:     api.EventMember,
+		Sender:   userID,
+		StateKey: &userID,
+		Content:  json.RawMessage(`{"membership":"join"}`),
+	}})
+	writeJSON(w, map[string]string{"room_id": roomID})
+}
+
+func (s *Server) handleCreateRoom(w http.ResponseWriter, r *http.Request, userID string) {
+	var req struct {
+		Invite []string `json:"invite"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&req)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Invitees join straight away so tests don't have to accept invites
+	roomID := s.createRoomLocked(append([]string{userID}, req.Invite...)...)
+	writeJSON(w, map[string]string{"room_id": roomID})
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request, _ string) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "M_UNKNOWN", err.Error())
+		return
+	}
+	s.mu.Lock()
+	mxc := s.storeMediaLocked(data, r.Header.Get("Content-Type"))
+	s.mu.Unlock()
+	writeJSON(w, map[string]string{"content_uri": mxc})
+}
+
+func (s *Server) handleDownloadV1(w http.ResponseWriter, r *http.Request, _ string) {
+	s.mu.Lock()
+	disabled := s.noV1Media
+	s.mu.Unlock()
+	if disabled {
+		writeError(w, http.StatusNotFound, "M_UNRECOGNIZED", "Unrecognized request")
+		return
+	}
+	s.handleDownload(w, r)
+}
+
+func (s *Server) handleDownload(w http.ResponseWriter, r *http.Request) {
+	mxc := "mxc://" + r.PathValue("server") + "/" + r.PathValue("id")
+	s.mu.Lock()
+	m, ok := s.media[mxc]
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "M_NOT_FOUND", "Media not found")
+		return
+	}
+	w.Header().Set("Content-Type", m.contentType)
+	_, _ = w.Write(m.data)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"errcode": code, "error": msg})
+}
+
+func randomID(prefix string) string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
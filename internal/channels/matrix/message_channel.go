@@ -0,0 +1,71 @@
+package matrix
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/roelfdiedericks/goclaw/internal/channels/matrix/api"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// MessageChannelAdapter adapts the Matrix Bot to the MessageChannel interface.
+// Chat IDs are room IDs; message IDs are event IDs.
+type MessageChannelAdapter struct {
+	bot       *Bot
+	mediaBase string
+}
+
+// NewMessageChannelAdapter creates a new adapter for the Matrix bot
+func NewMessageChannelAdapter(bot *Bot, mediaBase string) *MessageChannelAdapter {
+	return &MessageChannelAdapter{
+		bot:       bot,
+		mediaBase: mediaBase,
+	}
+}
+
+// SendText sends a formatted text message to a Matrix room
+func (a *MessageChannelAdapter) SendText(chatID string, text string) (string, error) {
+	return a.bot.client.SendMessage(a.bot.ctx, chatID, textContent(api.MsgText, text))
+}
+
+// SendMedia uploads and sends a media file to a Matrix room
+func (a *MessageChannelAdapter) SendMedia(chatID string, filePath string, caption string) (string, error) {
+	absPath := a.resolveMediaPath(filePath)
+	L_debug("matrix: sending media", "room", chatID, "path", absPath)
+	return a.bot.sendMediaFile(chatID, absPath, caption)
+}
+
+// EditMessage replaces the text of one of the bot's messages
+func (a *MessageChannelAdapter) EditMessage(chatID string, messageID string, text string) error {
+	if _, err := a.bot.client.Edit(a.bot.ctx, chatID, messageID, textContent(api.MsgText, text)); err != nil {
+		return fmt.Errorf("failed to edit message: %w", err)
+	}
+	return nil
+}
+
+// DeleteMessage redacts a message (the bot's own, or others' with moderator power)
+func (a *MessageChannelAdapter) DeleteMessage(chatID string, messageID string) error {
+	if _, err := a.bot.client.Redact(a.bot.ctx, chatID, messageID, ""); err != nil {
+		return fmt.Errorf("failed to redact message: %w", err)
+	}
+	L_debug("matrix: redacted message", "room", chatID, "event", messageID)
+	return nil
+}
+
+// React adds a reaction emoji to a Matrix message
+func (a *MessageChannelAdapter) React(chatID string, messageID string, emoji string) error {
+	if _, err := a.bot.client.React(a.bot.ctx, chatID, messageID, emoji); err != nil {
+		return fmt.Errorf("failed to send reaction: %w", err)
+	}
+	L_debug("matrix: reaction sent", "room", chatID, "event", messageID, "emoji", emoji)
+	return nil
+}
+
+func (a *MessageChannelAdapter) resolveMediaPath(path string) string {
+	if strings.HasPrefix(path, "./media/") {
+		subpath := strings.TrimPrefix(path, "./media/")
+		return filepath.Join(a.mediaBase, subpath)
+	}
+	return path
+}
@@ -35,6 +35,7 @@ type ChatPreferences struct {
 // Bot represents the Telegram bot
 type Bot struct {
 	bot     *tele.Bot
+	name    string // Named bot ("" = primary bot)
 	gateway *gateway.Gateway
 	users   *user.Registry
 	config  *config.Config
@@ -75,6 +76,12 @@ func (b *Bot) getChatPrefs(chatID int64, u *user.User) *ChatPreferences {
 
 // New creates a new Telegram bot
 func New(cfg *config.Config, gw *gateway.Gateway, users *user.Registry) (*Bot, error) {
+	return NewNamed("", cfg, gw, users)
+}
+
+// NewNamed creates an additional named Telegram bot. Its channel name is
+// "telegram:<name>" and its requests carry the bot name for agent routing.
+func NewNamed(name string, cfg *config.Config, gw *gateway.Gateway, users *user.Registry) (*Bot, error) {
 	if cfg.BotToken == "" {
 		return nil, fmt.Errorf("telegram bot token not configured")
 	}
@@ -103,6 +110,7 @@ func New(cfg *config.Config, gw *gateway.Gateway, users *user.Registry) (*Bot, e
 
 	b := &Bot{
 		bot:     bot,
+		name:    name,
 		gateway: gw,
 		users:   users,
 		config:  cfg,
@@ -334,6 +342,7 @@ func (b *Bot) handleMessage(c tele.Context) error {
 	req := gateway.AgentRequest{
 		User:           u,
 		Source:         "telegram",
+		Bot:            b.name,
 		ChatID:         fmt.Sprintf("%d", chatID),
 		IsGroup:        isGroup,
 		UserMsg:        c.Text(),
@@ -461,6 +470,7 @@ func (b *Bot) handlePhoto(c tele.Context) error {
 	req := gateway.AgentRequest{
 		User:           u,
 		Source:         "telegram",
+		Bot:            b.name,
 		ChatID:         fmt.Sprintf("%d", chatID),
 		IsGroup:        isGroup,
 		UserMsg:        caption,
@@ -592,6 +602,7 @@ func (b *Bot) handleVoice(c tele.Context) error {
 	req := gateway.AgentRequest{
 		User:           u,
 		Source:         "telegram",
+		Bot:            b.name,
 		ChatID:         fmt.Sprintf("%d", chatID),
 		IsGroup:        isGroup,
 		UserMsg:        caption,
@@ -865,6 +876,11 @@ func (b *Bot) streamResponse(c tele.Context, events <-chan gateway.AgentEvent) e
 				}
 			}
 
+		case gateway.EventAgentMerged:
+			// Reply arrives through the run the message was merged into
+			logging.L_debug("telegram: message merged into running turn", "mode", e.Mode)
+			_, _ = b.bot.Send(c.Chat(), e.Notice())
+
 		case gateway.EventAgentError:
 			logging.L_error("telegram: agent error", "error", e.Error)
 			errMsg := fmt.Sprintf("Error: %s", e.Error)
@@ -899,7 +915,7 @@ func (b *Bot) Start(ctx context.Context) error {
 
 // RegisterOperationalCommands registers runtime commands for this bot instance.
 func (b *Bot) RegisterOperationalCommands() {
-	bus.RegisterCommand("telegram", "status", b.handleStatusCommand)
+	bus.RegisterCommand(b.Name(), "status", b.handleStatusCommand)
 }
 
 // handleStatusCommand returns the current bot status
@@ -997,10 +1013,19 @@ func (b *Bot) Status() chtypes.ChannelStatus {
 }
 
 // Name returns the channel name (implements gateway.Channel)
+// Named bots are "telegram:<name>".
 func (b *Bot) Name() string {
+	if b.name != "" {
+		return "telegram:" + b.name
+	}
 	return "telegram"
 }
 
+// BotName returns the configured bot name ("" for the primary bot)
+func (b *Bot) BotName() string {
+	return b.name
+}
+
 // Send sends a message to the default chat (not implemented for Telegram)
 func (b *Bot) Send(ctx context.Context, msg string) error {
 	// Send to owner's chat
@@ -1317,7 +1342,7 @@ func (b *Bot) SendMirror(ctx context.Context, source, userMsg, response string)
 
 	// Telegram max message is 4096 chars. Reserve space for formatting.
 	const maxTelegramMsg = 4096
-	agentName := b.gateway.AgentIdentity().Name
+	agentName := b.gateway.AgentIdentityFor(b.gateway.ResolveAgent("telegram", b.name, telegramID, nil)).Name
 	headerReserve := 80 + len(agentName) // for "📱 <b>source</b>\n\n<b>You:</b> ...\n\n<b>AgentName:</b> "
 
 	// Calculate available space
@@ -16,7 +16,16 @@ import (
 
 // Config holds the Telegram bot configuration
 type Config struct {
-	Enabled  bool   `json:"enabled"`
+	Enabled  bool        `json:"enabled"`
+	BotToken string      `json:"botToken"`
+	Bots     []BotConfig `json:"bots,omitempty"` // Additional named bots (e.g. one per agent persona)
+}
+
+// BotConfig configures an additional named Telegram bot.
+// Each named bot runs as its own channel "telegram:<name>" and can be
+// routed to a specific agent via agents.routes ("bot": "<name>").
+type BotConfig struct {
+	Name     string `json:"name"`
 	BotToken string `json:"botToken"`
 }
 
@@ -435,6 +435,12 @@ func handleAgentEvent(m *Model, event gateway.AgentEvent) {
 		m.chatLines = append(m.chatLines, "")
 		m.streaming = false
 		m.eventsChan = nil
+	case gateway.EventAgentMerged:
+		// Reply arrives through the run the message was merged into
+		m.finishCurrentLine()
+		m.chatLines = append(m.chatLines, toolStyle.Render("["+e.Notice()+"]"), "")
+		m.streaming = false
+		m.eventsChan = nil
 	case gateway.EventAgentError:
 		m.finishCurrentLine()
 		m.chatLines = append(m.chatLines, errorStyle.Render(fmt.Sprintf("[Error: %s]", e.Error)), "")
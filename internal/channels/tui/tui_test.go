@@ -0,0 +1,26 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/roelfdiedericks/goclaw/internal/gateway"
+)
+
+func TestHandleAgentMerged(t *testing.T) {
+	events := make(chan gateway.AgentEvent)
+	m := &Model{streaming: true, eventsChan: events}
+
+	merged := gateway.EventAgentMerged{SessionKey: "main", Mode: gateway.RunQueueModeSteer}
+	handleAgentEvent(m, merged)
+
+	if m.streaming {
+		t.Error("still streaming after a merged message")
+	}
+	if m.eventsChan != nil {
+		t.Error("events channel kept after a merged message")
+	}
+	if !strings.Contains(m.getChatContent(), merged.Notice()) {
+		t.Errorf("chat does not show the merge notice: %q", m.getChatContent())
+	}
+}
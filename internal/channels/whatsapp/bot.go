@@ -263,7 +263,7 @@ func (b *Bot) SendMirror(ctx context.Context, source, userMsg, response string)
 	}
 	jid := phoneToJID(owner.WhatsAppID)
 
-	agentName := b.gateway.AgentIdentity().Name
+	agentName := b.gateway.AgentIdentityFor(b.gateway.ResolveAgent("whatsapp", "", "", owner)).Name
 	truncatedUser := truncate(userMsg, 500)
 	truncatedResponse := truncate(response, maxWhatsAppMessage-600)
 	formattedResponse := FormatMessage(truncatedResponse)
@@ -682,6 +682,14 @@ func (b *Bot) processEvents(evt *events.Message, evChan <-chan gateway.AgentEven
 				}
 			}
 
+		case gateway.EventAgentMerged:
+			// Reply arrives through the run the message was merged into
+			_ = b.client.SendChatPresence(b.ctx, chatJID, types.ChatPresencePaused, types.ChatPresenceMediaText)
+			L_debug("whatsapp: message merged into running turn", "mode", e.Mode)
+			_, _ = b.client.SendMessage(b.ctx, chatJID, &waE2E.Message{
+				Conversation: proto.String(e.Notice()),
+			})
+
 		case gateway.EventAgentError:
 			_ = b.client.SendChatPresence(b.ctx, chatJID, types.ChatPresencePaused, types.ChatPresenceMediaText)
 			L_error("whatsapp: agent error", "error", e.Error)
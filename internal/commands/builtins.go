@@ -3,8 +3,11 @@ package commands
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/history"
 )
 
 // registerBuiltins registers all built-in commands
@@ -78,6 +81,13 @@ func registerBuiltins(m *Manager) {
 		Usage:       "[status|rebuild]",
 		Handler:     handleEmbeddings,
 	})
+
+	m.Register(&Command{
+		Name:        "/undo",
+		Description: "Undo the last file change made in this session",
+		Usage:       "[id]",
+		Handler:     handleUndo,
+	})
 }
 
 // handleStatus returns session status and compaction health
@@ -98,6 +108,9 @@ func handleStatus(ctx context.Context, args *CommandArgs) *CommandResult {
 	text.WriteString(fmt.Sprintf("  Messages: %d\n", info.Messages))
 	text.WriteString(fmt.Sprintf("  Tokens: %d / %d (%.1f%%)\n", info.TotalTokens, info.MaxTokens, info.UsagePercent))
 	text.WriteString(fmt.Sprintf("  Compactions: %d\n", info.CompactionCount))
+	if info.QueueState != "" {
+		text.WriteString(fmt.Sprintf("  Run queue: %s [%s]\n", info.QueueState, info.QueueMode))
+	}
 
 	text.WriteString("\nCompaction Health\n")
 	if compStatus.ClientAvailable {
@@ -120,6 +133,9 @@ func handleStatus(ctx context.Context, args *CommandArgs) *CommandResult {
 	md.WriteString(fmt.Sprintf("Messages: %d\n", info.Messages))
 	md.WriteString(fmt.Sprintf("Tokens: %d / %d (%.1f%%)\n", info.TotalTokens, info.MaxTokens, info.UsagePercent))
 	md.WriteString(fmt.Sprintf("Compactions: %d\n", info.CompactionCount))
+	if info.QueueState != "" {
+		md.WriteString(fmt.Sprintf("Run queue: %s _[%s]_\n", info.QueueState, info.QueueMode))
+	}
 
 	md.WriteString("\n*Compaction Health*\n")
 	if compStatus.ClientAvailable {
@@ -868,3 +884,71 @@ func embeddingsRebuild(args *CommandArgs) *CommandResult {
 		Markdown: fmt.Sprintf("Rebuild starting. **%d** chunks to process.\nUse `/embeddings status` to monitor.", needsRebuild),
 	}
 }
+
+// handleUndo reverts the newest workspace change from this session, or a
+// specific history entry by ID
+func handleUndo(ctx context.Context, args *CommandArgs) *CommandResult {
+	store := history.Get()
+	if store == nil {
+		return &CommandResult{
+			Text:     "Workspace history is disabled (tools.history.enabled)",
+			Markdown: "Workspace history is disabled (`tools.history.enabled`)",
+		}
+	}
+
+	var entry history.Entry
+	if arg := strings.TrimSpace(args.RawArgs); arg != "" {
+		id, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+		if err != nil {
+			usage := fmt.Sprintf("/undo %s", args.Usage)
+			return &CommandResult{
+				Text:     fmt.Sprintf("Invalid change ID: %s\nUsage: %s", arg, usage),
+				Markdown: fmt.Sprintf("Invalid change ID: `%s`\nUsage: `%s`", arg, usage),
+			}
+		}
+		e, ok := store.Get(id)
+		if !ok || e.SessionKey != args.SessionKey {
+			return &CommandResult{
+				Text:     fmt.Sprintf("No change #%d in this session", id),
+				Markdown: fmt.Sprintf("No change `#%d` in this session", id),
+			}
+		}
+		if store.IsReverted(id) {
+			return &CommandResult{
+				Text:     fmt.Sprintf("Change #%d was already undone", id),
+				Markdown: fmt.Sprintf("Change `#%d` was already undone", id),
+			}
+		}
+		entry = e
+	} else {
+		e, ok := store.LastUndoable(args.SessionKey)
+		if !ok {
+			return &CommandResult{
+				Text:     "Nothing to undo in this session",
+				Markdown: "Nothing to undo in this session",
+			}
+		}
+		entry = e
+	}
+
+	_, err := store.Restore(entry.ID, "before", history.Entry{
+		Tool:       "undo",
+		User:       args.UserID,
+		SessionKey: args.SessionKey,
+	})
+	if err != nil {
+		return &CommandResult{
+			Text:  fmt.Sprintf("Undo failed: %s", err),
+			Error: err,
+		}
+	}
+
+	action := "restored"
+	if entry.Before == "" {
+		action = "removed (it was created by that change)"
+	}
+	return &CommandResult{
+		Text:     fmt.Sprintf("Undid change #%d (%s): %s %s", entry.ID, entry.Tool, entry.Path, action),
+		Markdown: fmt.Sprintf("Undid change `#%d` (%s): `%s` %s", entry.ID, entry.Tool, entry.Path, action),
+	}
+}
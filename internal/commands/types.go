@@ -64,6 +64,9 @@ type SessionInfo struct {
 	UsagePercent    float64
 	CompactionCount int
 	LastCompaction  *session.StoredCompaction
+	QueueMode       string // run queue mode: queue, steer, coalesce
+	QueueState      string // e.g. "running (telegram, 12s), 2 queued"
+	QueueDepth      int    // runs waiting for the session
 }
 
 // CommandResult contains the result of a command execution
@@ -7,8 +7,13 @@ import (
 	"path/filepath"
 
 	"dario.cat/mergo"
+	"github.com/roelfdiedericks/goclaw/internal/agents"
 	"github.com/roelfdiedericks/goclaw/internal/auth"
+	bridgeconfig "github.com/roelfdiedericks/goclaw/internal/channels/bridge/config"
+	discordconfig "github.com/roelfdiedericks/goclaw/internal/channels/discord/config"
+	emailconfig "github.com/roelfdiedericks/goclaw/internal/channels/email/config"
 	httpconfig "github.com/roelfdiedericks/goclaw/internal/channels/http/config"
+	matrixconfig "github.com/roelfdiedericks/goclaw/internal/channels/matrix/config"
 	telegramconfig "github.com/roelfdiedericks/goclaw/internal/channels/telegram/config"
 	tuiconfig "github.com/roelfdiedericks/goclaw/internal/channels/tui/config"
 	whatsappconfig "github.com/roelfdiedericks/goclaw/internal/channels/whatsapp/config"
@@ -21,6 +26,7 @@ import (
 	"github.com/roelfdiedericks/goclaw/internal/memory"
 	"github.com/roelfdiedericks/goclaw/internal/memorygraph"
 	"github.com/roelfdiedericks/goclaw/internal/paths"
+	"github.com/roelfdiedericks/goclaw/internal/retrieval"
 	"github.com/roelfdiedericks/goclaw/internal/sandbox"
 	"github.com/roelfdiedericks/goclaw/internal/session"
 	"github.com/roelfdiedericks/goclaw/internal/skills"
@@ -141,6 +147,10 @@ func splitLines(data []byte) []string {
 type ChannelsConfig struct {
 	Telegram telegramconfig.Config `json:"telegram"`
 	WhatsApp whatsappconfig.Config `json:"whatsapp"`
+	Matrix   matrixconfig.Config   `json:"matrix"`
+	Discord  discordconfig.Config  `json:"discord"`
+	Email    emailconfig.Config    `json:"email"`
+	Bridge   bridgeconfig.Config   `json:"bridge"`
 	HTTP     httpconfig.Config     `json:"http"`
 	TUI      tuiconfig.Config      `json:"tui"`
 }
@@ -149,6 +159,7 @@ type ChannelsConfig struct {
 type Config struct {
 	Gateway       gwtypes.GatewayConfig       `json:"gateway"`
 	Agent         gwtypes.AgentIdentityConfig `json:"agent"`
+	Agents        agents.Config               `json:"agents"` // Named agent personas and routing rules
 	LLM           llm.LLMConfig               `json:"llm"`
 	HomeAssistant hass.HomeAssistantConfig    `json:"homeassistant"` // Top-level Home Assistant config
 	Tools         toolsconfig.ToolsConfig     `json:"tools"`
@@ -157,6 +168,7 @@ type Config struct {
 	Memory        memory.MemorySearchConfig   `json:"memory"`
 	MemoryGraph   memorygraph.Config          `json:"memoryGraph"`
 	Transcript    transcript.TranscriptConfig `json:"transcript"`
+	Retrieval     retrieval.Config            `json:"retrieval"` // Pre-turn context retrieval
 	PromptCache   gwtypes.PromptCacheConfig   `json:"promptCache"`
 	Media         media.MediaConfig           `json:"media"`
 	STT           stt.Config                  `json:"stt"`
@@ -165,9 +177,9 @@ type Config struct {
 	Supervision   gwtypes.SupervisionConfig   `json:"supervision"`
 	Roles         user.RolesConfig            `json:"roles"`    // Role-based access control
 	Auth          auth.AuthConfig             `json:"auth"`     // Role elevation authentication
-	Sandbox       sandbox.Config               `json:"sandbox"`   // Sandbox and bubblewrap configuration
-	Safety        gwtypes.SafetyConfig        `json:"safety"`    // Emergency stop / panic phrase config
-	Security      gwtypes.SecurityConfig      `json:"security"`  // Security policies (tool restrictions per purpose)
+	Sandbox       sandbox.Config              `json:"sandbox"`  // Sandbox and bubblewrap configuration
+	Safety        gwtypes.SafetyConfig        `json:"safety"`   // Emergency stop / panic phrase config
+	Security      gwtypes.SecurityConfig      `json:"security"` // Security policies (tool restrictions per purpose)
 }
 
 // Load reads configuration from goclaw.json.
@@ -278,8 +290,27 @@ func Load() (*LoadResult, error) {
 					AllowNetwork: true, // Network allowed by default
 					ClearEnv:     true, // Clear env by default for security
 				},
+				Sessions: toolsconfig.ExecSessionsConfig{
+					Enabled:     true,
+					IdleTimeout: 30,
+					MaxSessions: 8,
+				},
+			},
+			Script: toolsconfig.ScriptToolsConfig{
+				Enabled: false,
+				Dir:     "tools",
+				Timeout: 60,
 			},
+			Spillover: toolsconfig.SpilloverConfig{
+				Enabled:      true,
+				Budget:       8000,
+				PreviewBytes: 4000,
+				TTL:          24,
 			},
+			History: toolsconfig.HistoryConfig{
+				Enabled: true,
+			},
+		},
 		Sandbox: sandbox.Config{
 			Bubblewrap: sandbox.BubblewrapConfig{
 				Mode:    sandbox.ModeHome,
@@ -353,6 +384,7 @@ func Load() (*LoadResult, error) {
 			Paths: []string{}, // Only memory/ and MEMORY.md by default
 		},
 		MemoryGraph: memorygraph.DefaultConfig(),
+		Retrieval:   retrieval.DefaultConfig(),
 		Transcript: transcript.TranscriptConfig{
 			Enabled:                true,
 			IndexIntervalSeconds:   30,
@@ -562,6 +594,10 @@ func mergeConfigSelective(dst, src *Config, rawMap map[string]interface{}) error
 			return err
 		}
 	}
+	if _, ok := rawMap["agents"]; ok {
+		// Agents holds maps and ordered routes, assign directly
+		dst.Agents = src.Agents
+	}
 	if _, ok := rawMap["llm"]; ok {
 		if err := mergo.Merge(&dst.LLM, src.LLM, mergo.WithOverride); err != nil {
 			return err
@@ -589,6 +625,26 @@ func mergeConfigSelective(dst, src *Config, rawMap map[string]interface{}) error
 				return err
 			}
 		}
+		if _, ok := channelsMap["matrix"]; ok {
+			if err := mergo.Merge(&dst.Channels.Matrix, src.Channels.Matrix, mergo.WithOverride); err != nil {
+				return err
+			}
+		}
+		if _, ok := channelsMap["discord"]; ok {
+			if err := mergo.Merge(&dst.Channels.Discord, src.Channels.Discord, mergo.WithOverride); err != nil {
+				return err
+			}
+		}
+		if _, ok := channelsMap["email"]; ok {
+			if err := mergo.Merge(&dst.Channels.Email, src.Channels.Email, mergo.WithOverride); err != nil {
+				return err
+			}
+		}
+		if _, ok := channelsMap["bridge"]; ok {
+			if err := mergo.Merge(&dst.Channels.Bridge, src.Channels.Bridge, mergo.WithOverride); err != nil {
+				return err
+			}
+		}
 		if _, ok := channelsMap["http"]; ok {
 			if err := mergo.Merge(&dst.Channels.HTTP, src.Channels.HTTP, mergo.WithOverride); err != nil {
 				return err
@@ -619,6 +675,11 @@ func mergeConfigSelective(dst, src *Config, rawMap map[string]interface{}) error
 			return err
 		}
 	}
+	if _, ok := rawMap["retrieval"]; ok {
+		if err := mergo.Merge(&dst.Retrieval, src.Retrieval, mergo.WithOverride); err != nil {
+			return err
+		}
+	}
 	if _, ok := rawMap["promptCache"]; ok {
 		if err := mergo.Merge(&dst.PromptCache, src.PromptCache, mergo.WithOverride); err != nil {
 			return err
@@ -291,5 +291,11 @@ func findFieldByJSONTag(rv reflect.Value, jsonName string) reflect.Value {
 
 // ExecuteAction sends a command through the bus
 func ExecuteAction(component string, action ActionDef, payload any) bus.CommandResult {
-	return bus.SendCommandWithSource(component, action.Name, payload, "tui", "")
+	return ExecuteActionAs(component, action, payload, "tui", "")
+}
+
+// ExecuteActionAs sends a command through the bus on behalf of a user of
+// another renderer (e.g. source "web")
+func ExecuteActionAs(component string, action ActionDef, payload any, source, userID string) bus.CommandResult {
+	return bus.SendCommandWithSource(component, action.Name, payload, source, userID)
 }
@@ -15,6 +15,27 @@ const (
 	StringList                  // Comma-separated string list (for []string fields)
 )
 
+// String returns the lowercase type name, used by the web renderer templates
+func (t FieldType) String() string {
+	switch t {
+	case Toggle:
+		return "toggle"
+	case Text:
+		return "text"
+	case Number:
+		return "number"
+	case Secret:
+		return "secret"
+	case Select:
+		return "select"
+	case TextArea:
+		return "textarea"
+	case StringList:
+		return "stringlist"
+	}
+	return "unknown"
+}
+
 // FormDef defines a form for editing a config struct
 type FormDef struct {
 	Title       string      // Form title
@@ -0,0 +1,323 @@
+package forms
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// WebField is a field prepared for an HTML renderer.
+// Key is the dotted path of the field from the root value; posted values
+// are keyed the same way (see ApplyWebValues).
+type WebField struct {
+	Field
+	Key      string
+	Value    string // current value; StringList is comma-separated, nil *bool Select is "default"
+	Checked  bool   // Toggle state
+	HasValue bool   // Secret has a stored value (Value is never filled for secrets)
+}
+
+// WebSection is a section prepared for an HTML renderer. Nested sections are
+// flattened, with their field keys and ShowWhen prefixed by the nested path.
+type WebSection struct {
+	Title     string
+	Desc      string
+	Collapsed bool
+	ShowWhen  string // condition on a field key: "key=value" (empty = always shown)
+	Visible   bool   // ShowWhen holds for the current value
+	Fields    []WebField
+}
+
+// WebSections walks a FormDef against a struct value and returns the sections
+// with current field values. Fields that don't exist on the value are skipped.
+func WebSections(def FormDef, value any) ([]WebSection, error) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("value must be a struct, got %T", value)
+	}
+
+	var out []WebSection
+	collectWebSections(def.Sections, rv, "", &out)
+	return out, nil
+}
+
+func collectWebSections(sections []Section, rv reflect.Value, prefix string, out *[]WebSection) {
+	for _, section := range sections {
+		ws := WebSection{
+			Title:     section.Title,
+			Desc:      section.Desc,
+			Collapsed: section.Collapsed,
+			Visible:   section.ShowWhen == "" || evaluateShowWhen(section.ShowWhen, rv),
+		}
+		if section.ShowWhen != "" {
+			ws.ShowWhen = prefix + strings.TrimSpace(section.ShowWhen)
+		}
+
+		if section.Nested != nil {
+			fieldName := section.FieldName
+			if fieldName == "" {
+				fieldName = section.Title
+			}
+			nested := findFieldByPath(rv, fieldName)
+			if !nested.IsValid() {
+				continue
+			}
+			if ws.Title != "" || ws.Desc != "" {
+				*out = append(*out, ws)
+			}
+			collectWebSections(section.Nested.Sections, nested, prefix+fieldName+".", out)
+			continue
+		}
+
+		for _, field := range section.Fields {
+			fv := findFieldByPath(rv, field.Name)
+			if !fv.IsValid() {
+				continue
+			}
+			ws.Fields = append(ws.Fields, webField(field, fv, prefix+field.Name))
+		}
+		*out = append(*out, ws)
+	}
+}
+
+func webField(field Field, fv reflect.Value, key string) WebField {
+	wf := WebField{Field: field, Key: key}
+
+	isBoolPtr := fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Bool
+	switch {
+	case field.Type == Toggle && isBoolPtr:
+		wf.Checked = !fv.IsNil() && fv.Elem().Bool()
+	case field.Type == Toggle:
+		wf.Checked = fv.Kind() == reflect.Bool && fv.Bool()
+	case field.Type == Secret:
+		wf.HasValue = fv.Kind() == reflect.String && fv.String() != ""
+	case isBoolPtr:
+		if fv.IsNil() {
+			wf.Value = "default"
+		} else {
+			wf.Value = strconv.FormatBool(fv.Elem().Bool())
+		}
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+		parts := make([]string, fv.Len())
+		for i := 0; i < fv.Len(); i++ {
+			parts[i] = fv.Index(i).String()
+		}
+		wf.Value = strings.Join(parts, ", ")
+	default:
+		wf.Value = fmt.Sprintf("%v", fv.Interface())
+	}
+	return wf
+}
+
+// ApplyWebValues sets fields of value (a struct pointer) from posted values
+// keyed as in WebSections. Missing keys leave the field unchanged, and an
+// empty Secret keeps the stored secret.
+//
+// It returns per-key errors for values that don't parse or break the field's
+// Required, Min/Max or Options constraints. Fields in sections hidden by
+// ShowWhen are not checked. If every field is valid and the value implements
+// Validatable, its Validate error is returned as err.
+func ApplyWebValues(def FormDef, value any, values map[string]string) (fieldErrs map[string]string, err error) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("value must be a struct pointer, got %T", value)
+	}
+
+	fieldErrs = make(map[string]string)
+	applyWebSections(def.Sections, rv.Elem(), "", values, fieldErrs)
+	checkWebSections(def.Sections, rv.Elem(), "", fieldErrs)
+	if len(fieldErrs) > 0 {
+		return fieldErrs, nil
+	}
+
+	if v, ok := value.(Validatable); ok {
+		return nil, v.Validate()
+	}
+	return nil, nil
+}
+
+func applyWebSections(sections []Section, rv reflect.Value, prefix string, values map[string]string, fieldErrs map[string]string) {
+	for _, section := range sections {
+		if section.Nested != nil {
+			fieldName := section.FieldName
+			if fieldName == "" {
+				fieldName = section.Title
+			}
+			if nested := findFieldByPath(rv, fieldName); nested.IsValid() {
+				applyWebSections(section.Nested.Sections, nested, prefix+fieldName+".", values, fieldErrs)
+			}
+			continue
+		}
+
+		for _, field := range section.Fields {
+			key := prefix + field.Name
+			text, ok := values[key]
+			if !ok {
+				continue
+			}
+			fv := findFieldByPath(rv, field.Name)
+			if !fv.IsValid() || !fv.CanSet() {
+				continue
+			}
+			if field.Type == Secret && text == "" {
+				continue
+			}
+			if err := setWebValue(field, fv, text); err != nil {
+				fieldErrs[key] = err.Error()
+			}
+		}
+	}
+}
+
+// checkWebSections enforces field constraints on visible sections, after all
+// values are set so ShowWhen sees the posted state.
+func checkWebSections(sections []Section, rv reflect.Value, prefix string, fieldErrs map[string]string) {
+	for _, section := range sections {
+		if section.ShowWhen != "" && !evaluateShowWhen(section.ShowWhen, rv) {
+			continue
+		}
+		if section.Nested != nil {
+			fieldName := section.FieldName
+			if fieldName == "" {
+				fieldName = section.Title
+			}
+			if nested := findFieldByPath(rv, fieldName); nested.IsValid() {
+				checkWebSections(section.Nested.Sections, nested, prefix+fieldName+".", fieldErrs)
+			}
+			continue
+		}
+
+		for _, field := range section.Fields {
+			key := prefix + field.Name
+			if _, failed := fieldErrs[key]; failed {
+				continue
+			}
+			fv := findFieldByPath(rv, field.Name)
+			if !fv.IsValid() {
+				continue
+			}
+			if msg := checkField(field, fv); msg != "" {
+				fieldErrs[key] = msg
+			}
+		}
+	}
+}
+
+func checkField(field Field, fv reflect.Value) string {
+	if field.Required && fv.IsZero() {
+		return "required"
+	}
+	if field.Type == Number && (field.Min != 0 || field.Max != 0) {
+		var n float64
+		switch fv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(fv.Int())
+		case reflect.Float32, reflect.Float64:
+			n = fv.Float()
+		default:
+			return ""
+		}
+		if n < field.Min {
+			return fmt.Sprintf("must be at least %v", field.Min)
+		}
+		if field.Max != 0 && n > field.Max {
+			return fmt.Sprintf("must be at most %v", field.Max)
+		}
+	}
+	return ""
+}
+
+// setWebValue parses text into fv according to the field type
+func setWebValue(field Field, fv reflect.Value, text string) error {
+	isBoolPtr := fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Bool
+
+	switch field.Type {
+	case Toggle:
+		b := text == "true" || text == "on" || text == "1"
+		if isBoolPtr {
+			fv.Set(reflect.ValueOf(&b))
+		} else if fv.Kind() == reflect.Bool {
+			fv.SetBool(b)
+		}
+		return nil
+
+	case Select:
+		known := false
+		for _, opt := range field.Options {
+			if opt.Value == text {
+				known = true
+				break
+			}
+		}
+		if !known && len(field.Options) > 0 {
+			return fmt.Errorf("unknown option %q", text)
+		}
+		if isBoolPtr {
+			switch text {
+			case "default", "":
+				fv.Set(reflect.Zero(fv.Type()))
+			default:
+				b := text == "true"
+				fv.Set(reflect.ValueOf(&b))
+			}
+			return nil
+		}
+
+	case StringList:
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String {
+			parts := strings.Split(text, ",")
+			result := make([]string, 0, len(parts))
+			for _, p := range parts {
+				if p = strings.TrimSpace(p); p != "" {
+					result = append(result, p)
+				}
+			}
+			fv.Set(reflect.ValueOf(result))
+			return nil
+		}
+	}
+
+	return setScalar(fv, strings.TrimSpace(text), text)
+}
+
+// setScalar sets a string, bool or numeric field. Strings keep their
+// untrimmed text; numbers and bools are parsed from the trimmed text.
+func setScalar(fv reflect.Value, trimmed, raw string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(trimmed)
+		if err != nil {
+			return fmt.Errorf("not a boolean: %q", trimmed)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if trimmed == "" {
+			fv.SetInt(0)
+			return nil
+		}
+		i, err := strconv.ParseInt(trimmed, 10, 64)
+		if err != nil {
+			return fmt.Errorf("not a whole number: %q", trimmed)
+		}
+		fv.SetInt(i)
+	case reflect.Float32, reflect.Float64:
+		if trimmed == "" {
+			fv.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return fmt.Errorf("not a number: %q", trimmed)
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
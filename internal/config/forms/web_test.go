@@ -0,0 +1,139 @@
+package forms
+
+import (
+	"errors"
+	"testing"
+)
+
+type webTestInner struct {
+	Mode    string   `json:"mode"`
+	Volumes []string `json:"volumes"`
+}
+
+type webTestConfig struct {
+	Enabled  bool         `json:"enabled"`
+	Name     string       `json:"name"`
+	Token    string       `json:"token"`
+	Limit    int          `json:"limit"`
+	Score    float64      `json:"score"`
+	Headless *bool        `json:"headless"`
+	Inner    webTestInner `json:"inner"`
+}
+
+func (c *webTestConfig) Validate() error {
+	if c.Name == "invalid" {
+		return errors.New("bad name")
+	}
+	return nil
+}
+
+func webTestDef() FormDef {
+	inner := &FormDef{Sections: []Section{
+		{Title: "Inner", Fields: []Field{
+			{Name: "mode", Title: "Mode", Type: Select, Options: []Option{{Label: "A", Value: "a"}, {Label: "Volumes", Value: "volumes"}}},
+		}},
+		{Title: "Volumes", ShowWhen: "mode=volumes", Fields: []Field{
+			{Name: "volumes", Title: "Volumes", Type: StringList, Required: true},
+		}},
+	}}
+	return FormDef{Sections: []Section{
+		{Title: "General", Fields: []Field{
+			{Name: "enabled", Title: "Enabled", Type: Toggle},
+			{Name: "name", Title: "Name", Type: Text, Required: true},
+			{Name: "token", Title: "Token", Type: Secret},
+			{Name: "limit", Title: "Limit", Type: Number, Min: 1, Max: 10},
+			{Name: "score", Title: "Score", Type: Number},
+			{Name: "headless", Title: "Headless", Type: Select, Options: []Option{{Label: "Default", Value: "default"}, {Label: "Yes", Value: "true"}, {Label: "No", Value: "false"}}},
+			{Name: "missing", Title: "Not on the struct", Type: Text},
+		}},
+		{Title: "Nested", Nested: inner, FieldName: "Inner"},
+	}}
+}
+
+func TestWebSections(t *testing.T) {
+	cfg := &webTestConfig{Enabled: true, Name: "bot", Token: "s3cret", Limit: 5, Inner: webTestInner{Mode: "a", Volumes: []string{"/a", "/b"}}}
+	sections, err := WebSections(webTestDef(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := map[string]WebField{}
+	visible := map[string]WebSection{}
+	for _, s := range sections {
+		visible[s.Title] = s
+		for _, f := range s.Fields {
+			fields[f.Key] = f
+		}
+	}
+
+	if _, ok := fields["missing"]; ok {
+		t.Error("field not on the struct should be skipped")
+	}
+	if !fields["enabled"].Checked {
+		t.Error("toggle should be checked")
+	}
+	if tok := fields["token"]; tok.Value != "" || !tok.HasValue {
+		t.Errorf("secret should not be echoed: %+v", tok)
+	}
+	if got := fields["headless"].Value; got != "default" {
+		t.Errorf("nil *bool select = %q, want default", got)
+	}
+	if got := fields["Inner.volumes"].Value; got != "/a, /b" {
+		t.Errorf("string list = %q", got)
+	}
+	if s := visible["Volumes"]; s.Visible || s.ShowWhen != "Inner.mode=volumes" {
+		t.Errorf("nested ShowWhen section = %+v", s)
+	}
+}
+
+func TestApplyWebValues(t *testing.T) {
+	cfg := &webTestConfig{Name: "bot", Token: "s3cret", Limit: 5, Inner: webTestInner{Mode: "a"}}
+	errs, err := ApplyWebValues(webTestDef(), cfg, map[string]string{
+		"enabled":       "true",
+		"token":         "",
+		"limit":         "7",
+		"score":         "0.5",
+		"headless":      "false",
+		"Inner.mode":    "volumes",
+		"Inner.volumes": " /data , ,/srv",
+	})
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v %v", errs, err)
+	}
+	if !cfg.Enabled || cfg.Limit != 7 || cfg.Score != 0.5 || cfg.Name != "bot" {
+		t.Errorf("values not applied: %+v", cfg)
+	}
+	if cfg.Token != "s3cret" {
+		t.Error("empty secret should keep the stored value")
+	}
+	if cfg.Headless == nil || *cfg.Headless {
+		t.Errorf("headless = %v, want false", cfg.Headless)
+	}
+	if len(cfg.Inner.Volumes) != 2 || cfg.Inner.Volumes[1] != "/srv" {
+		t.Errorf("volumes = %v", cfg.Inner.Volumes)
+	}
+
+	cfg = &webTestConfig{Name: "bot", Inner: webTestInner{Mode: "a"}}
+	errs, _ = ApplyWebValues(webTestDef(), cfg, map[string]string{
+		"limit":      "20",
+		"score":      "abc",
+		"headless":   "maybe",
+		"Inner.mode": "volumes",
+	})
+	for _, key := range []string{"limit", "score", "headless", "Inner.volumes"} {
+		if errs[key] == "" {
+			t.Errorf("expected error for %s, got %v", key, errs)
+		}
+	}
+
+	// Required fields in hidden sections are not checked
+	cfg = &webTestConfig{Name: "bot", Limit: 1, Inner: webTestInner{Mode: "a"}}
+	if errs, _ := ApplyWebValues(webTestDef(), cfg, map[string]string{}); len(errs) > 0 {
+		t.Errorf("hidden section should not be checked: %v", errs)
+	}
+
+	cfg = &webTestConfig{Limit: 1, Inner: webTestInner{Mode: "a"}}
+	if errs, err := ApplyWebValues(webTestDef(), cfg, map[string]string{"name": "invalid"}); len(errs) > 0 || err == nil {
+		t.Errorf("expected Validate error, got %v %v", errs, err)
+	}
+}
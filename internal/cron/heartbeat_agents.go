@@ -0,0 +1,40 @@
+package cron
+
+import (
+	"context"
+	"time"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// AddAgentHeartbeat registers a heartbeat for a named agent.
+// Must be called before Start; each agent heartbeat runs on its own ticker.
+func (s *Service) AddAgentHeartbeat(hb *HeartbeatState) {
+	if hb == nil || !hb.Enabled {
+		return
+	}
+	if hb.IntervalMinutes <= 0 {
+		hb.IntervalMinutes = 30
+	}
+	s.agentHeartbeats = append(s.agentHeartbeats, hb)
+}
+
+// runAgentHeartbeatLoop runs a named agent's heartbeat until the service stops.
+func (s *Service) runAgentHeartbeatLoop(ctx context.Context, hb *HeartbeatState, stopCh <-chan struct{}) {
+	interval := time.Duration(hb.IntervalMinutes) * time.Minute
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	L_info("cron: agent heartbeat enabled", "agent", hb.AgentID, "interval", interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			s.runHeartbeatFor(ctx, hb)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package cron
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// Retry and catch-up defaults
+const (
+	DefaultRetryBackoffSeconds    = 30
+	DefaultRetryMaxBackoffSeconds = 3600
+	MaxCatchUpRuns                = 24 // Upper bound on runs replayed by the "run_all" misfire policy
+)
+
+// MisfirePolicy returns the job's misfire policy (default: skip).
+func (j *CronJob) MisfirePolicy() string {
+	if j.Misfire == "" {
+		return MisfireSkip
+	}
+	return j.Misfire
+}
+
+// ConcurrencyPolicy returns the job's concurrency policy (default: forbid).
+func (j *CronJob) ConcurrencyPolicy() string {
+	if j.Concurrency == "" {
+		return ConcurrencyForbid
+	}
+	return j.Concurrency
+}
+
+// Timeout returns the execution timeout for the job.
+// payload.timeoutSeconds overrides the service default; 0 means no timeout.
+func (j *CronJob) Timeout(defaultMinutes int) time.Duration {
+	if j.Payload.TimeoutSeconds > 0 {
+		return time.Duration(j.Payload.TimeoutSeconds) * time.Second
+	}
+	return time.Duration(defaultMinutes) * time.Minute
+}
+
+// ValidatePolicy checks the execution policy fields.
+func (j *CronJob) ValidatePolicy() error {
+	switch j.Misfire {
+	case "", MisfireSkip, MisfireRunOnce, MisfireRunAll:
+	default:
+		return fmt.Errorf("invalid misfire policy %q (must be skip, run_once or run_all)", j.Misfire)
+	}
+	switch j.Concurrency {
+	case "", ConcurrencyForbid, ConcurrencyQueue:
+	default:
+		return fmt.Errorf("invalid concurrency policy %q (must be forbid or queue)", j.Concurrency)
+	}
+	if j.JitterSeconds < 0 {
+		return fmt.Errorf("jitterSeconds must not be negative")
+	}
+	if j.Retry != nil && (j.Retry.MaxRetries < 0 || j.Retry.BackoffSeconds < 0 || j.Retry.MaxBackoffSeconds < 0) {
+		return fmt.Errorf("retry settings must not be negative")
+	}
+	return nil
+}
+
+// Delay returns the backoff before the given retry attempt (1-based).
+func (r *RetryPolicy) Delay(attempt int) time.Duration {
+	base := r.BackoffSeconds
+	if base <= 0 {
+		base = DefaultRetryBackoffSeconds
+	}
+	maxDelay := time.Duration(r.MaxBackoffSeconds) * time.Second
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMaxBackoffSeconds * time.Second
+	}
+
+	delay := time.Duration(base) * time.Second
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// PlanNextRun returns the next scheduled run with the job's jitter applied.
+// One-shot jobs are never jittered.
+func PlanNextRun(job *CronJob, now time.Time) (*time.Time, error) {
+	next, err := NextRunTime(job, now)
+	if err != nil || next == nil {
+		return next, err
+	}
+	if job.JitterSeconds > 0 && !job.IsOneShot() {
+		jittered := next.Add(rand.N(time.Duration(job.JitterSeconds) * time.Second))
+		next = &jittered
+	}
+	return next, nil
+}
+
+// nextRunAfter decides when a job runs again after a run finished with the given status.
+// Failed runs are retried with backoff, owed runs (queued or catch-up) run immediately,
+// otherwise the regular schedule applies. Returns nil once a one-shot job is done.
+func nextRunAfter(job *CronJob, status string, now time.Time) (*time.Time, error) {
+	if status == StatusError && job.Retry != nil && job.State.RetryAttempt < job.Retry.MaxRetries {
+		job.State.RetryAttempt++
+		next := now.Add(job.Retry.Delay(job.State.RetryAttempt))
+		return &next, nil
+	}
+	job.State.RetryAttempt = 0
+
+	if job.State.PendingRuns > 0 {
+		job.State.PendingRuns--
+		return &now, nil
+	}
+
+	if job.IsOneShot() {
+		return nil, nil
+	}
+	return PlanNextRun(job, now)
+}
+
+// catchUpRun applies the misfire policy to a job whose stored next run passed
+// while the gateway was not running. Returns the time to run the job, or nil
+// if the missed run should be dropped. A pending retry is always honoured.
+func catchUpRun(job *CronJob, now time.Time) *time.Time {
+	if job.IsOneShot() || job.State.NextRunAtMs == nil {
+		return nil
+	}
+	missedAt := time.UnixMilli(*job.State.NextRunAtMs)
+	if !missedAt.Before(now) {
+		return nil
+	}
+	if job.State.RetryAttempt > 0 {
+		L_info("cron: resuming pending retry", "job", job.Name, "attempt", job.State.RetryAttempt, "missedAt", missedAt.Format(time.RFC3339))
+		return &now
+	}
+
+	switch job.MisfirePolicy() {
+	case MisfireRunOnce:
+		L_info("cron: catching up missed run", "job", job.Name, "missedAt", missedAt.Format(time.RFC3339))
+		return &now
+	case MisfireRunAll:
+		missed := missedRuns(job, missedAt, now)
+		if missed-1 > job.State.PendingRuns {
+			job.State.PendingRuns = missed - 1
+		}
+		L_info("cron: catching up missed runs", "job", job.Name, "missed", missed, "missedAt", missedAt.Format(time.RFC3339))
+		return &now
+	default:
+		L_info("cron: skipping missed run", "job", job.Name, "missedAt", missedAt.Format(time.RFC3339))
+		return nil
+	}
+}
+
+// missedRuns counts scheduled occurrences from missedAt up to now (inclusive),
+// capped at MaxCatchUpRuns.
+func missedRuns(job *CronJob, missedAt, now time.Time) int {
+	count := 0
+	for t := missedAt; !t.After(now) && count < MaxCatchUpRuns; count++ {
+		next, err := occurrenceAfter(job, t)
+		if err != nil || next == nil {
+			return count + 1
+		}
+		t = *next
+	}
+	return count
+}
+
+// occurrenceAfter returns the first scheduled occurrence strictly after t,
+// ignoring run state.
+func occurrenceAfter(job *CronJob, t time.Time) (*time.Time, error) {
+	switch job.Schedule.Kind {
+	case ScheduleKindEvery:
+		if job.Schedule.EveryMs <= 0 {
+			return nil, fmt.Errorf("invalid interval: %d", job.Schedule.EveryMs)
+		}
+		next := t.Add(time.Duration(job.Schedule.EveryMs) * time.Millisecond)
+		return &next, nil
+	case ScheduleKindCron:
+		return nextRunCron(job, t)
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,144 @@
+package cron
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func briefingJob(misfire string) *CronJob {
+	missed := time.Date(2026, 3, 2, 6, 30, 0, 0, time.UTC).UnixMilli()
+	return &CronJob{
+		Name:     "morning-briefing",
+		Enabled:  true,
+		Schedule: Schedule{Kind: ScheduleKindCron, Expr: "30 6 * * *", Tz: "UTC"},
+		Misfire:  misfire,
+		State:    JobState{NextRunAtMs: &missed},
+	}
+}
+
+func TestCatchUpRun(t *testing.T) {
+	reboot := time.Date(2026, 3, 2, 6, 55, 0, 0, time.UTC)
+
+	if next := catchUpRun(briefingJob(MisfireSkip), reboot); next != nil {
+		t.Errorf("skip policy should drop the missed run, got %v", next)
+	}
+
+	job := briefingJob(MisfireRunOnce)
+	next := catchUpRun(job, reboot)
+	if next == nil || !next.Equal(reboot) {
+		t.Fatalf("run_once should run at startup, got %v", next)
+	}
+	if job.State.PendingRuns != 0 {
+		t.Errorf("run_once should not owe extra runs, got %d", job.State.PendingRuns)
+	}
+
+	// Down for three days: three missed occurrences, one now plus two owed
+	job = briefingJob(MisfireRunAll)
+	if next := catchUpRun(job, reboot.Add(48*time.Hour)); next == nil {
+		t.Fatal("run_all should run at startup")
+	}
+	if job.State.PendingRuns != 2 {
+		t.Errorf("run_all pending = %d, want 2", job.State.PendingRuns)
+	}
+
+	// Not missed yet
+	if next := catchUpRun(briefingJob(MisfireRunOnce), reboot.Add(-time.Hour)); next != nil {
+		t.Errorf("future run should not be caught up, got %v", next)
+	}
+}
+
+func TestMissedRunsCapped(t *testing.T) {
+	job := &CronJob{Schedule: Schedule{Kind: ScheduleKindEvery, EveryMs: time.Minute.Milliseconds()}}
+	from := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	if got := missedRuns(job, from, from.Add(150*time.Second)); got != 3 {
+		t.Errorf("missedRuns = %d, want 3", got)
+	}
+	if got := missedRuns(job, from, from.Add(24*time.Hour)); got != MaxCatchUpRuns {
+		t.Errorf("missedRuns = %d, want cap %d", got, MaxCatchUpRuns)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	r := &RetryPolicy{MaxRetries: 5, BackoffSeconds: 10, MaxBackoffSeconds: 60}
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, 60 * time.Second, 60 * time.Second}
+	for i, w := range want {
+		if got := r.Delay(i + 1); got != w {
+			t.Errorf("Delay(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+}
+
+func TestNextRunAfter(t *testing.T) {
+	now := time.Date(2026, 3, 2, 7, 0, 0, 0, time.UTC)
+	job := briefingJob("")
+	job.Retry = &RetryPolicy{MaxRetries: 2, BackoffSeconds: 60}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		next, err := nextRunAfter(job, StatusError, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := now.Add(job.Retry.Delay(attempt)); !next.Equal(want) {
+			t.Errorf("retry %d at %v, want %v", attempt, next, want)
+		}
+	}
+
+	// Retries exhausted: back to the regular schedule
+	next, err := nextRunAfter(job, StatusError, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 3, 3, 6, 30, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("next = %v, want %v", next, want)
+	}
+	if job.State.RetryAttempt != 0 {
+		t.Errorf("RetryAttempt = %d, want reset to 0", job.State.RetryAttempt)
+	}
+
+	// Owed runs go first
+	job.State.PendingRuns = 1
+	if next, _ := nextRunAfter(job, StatusOK, now); !next.Equal(now) {
+		t.Errorf("pending run at %v, want now", next)
+	}
+	if job.State.PendingRuns != 0 {
+		t.Errorf("PendingRuns = %d, want 0", job.State.PendingRuns)
+	}
+}
+
+func TestCrashRecoveryCatchesUp(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(filepath.Join(dir, "jobs.json"), filepath.Join(dir, "runs"))
+
+	// The gateway died while the job was running, and its next run passed
+	// while it was down
+	now := time.Now()
+	missed := now.Add(-10 * time.Minute).UnixMilli()
+	running := now.Add(-70 * time.Minute).UnixMilli()
+	job := &CronJob{
+		Name:     "hourly-check",
+		Enabled:  true,
+		Schedule: Schedule{Kind: ScheduleKindEvery, EveryMs: time.Hour.Milliseconds()},
+		Misfire:  MisfireRunOnce,
+		State:    JobState{NextRunAtMs: &missed, RunningAtMs: &running},
+	}
+	if err := store.AddJob(job); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewService(store, nil)
+	s.clearOrphanedRunningState()
+	if job.IsRunning() {
+		t.Fatal("orphaned running marker not cleared")
+	}
+	if job.State.NextRunAtMs == nil || *job.State.NextRunAtMs != missed {
+		t.Fatalf("NextRunAtMs = %v, want the missed run kept", job.State.NextRunAtMs)
+	}
+
+	s.initializeNextRuns()
+	next := job.State.NextRunAtMs
+	if next == nil || time.UnixMilli(*next).After(time.Now()) {
+		t.Fatalf("missed run not caught up at startup, next run %v", next)
+	}
+}
@@ -22,6 +22,8 @@ func NextRunTime(job *CronJob, now time.Time) (*time.Time, error) {
 		return nextRunEvery(job, now)
 	case ScheduleKindCron:
 		return nextRunCron(job, now)
+	case ScheduleKindHass, ScheduleKindJob, ScheduleKindWebhook, ScheduleKindFile:
+		return nil, nil // Event-triggered, no clock schedule
 	default:
 		return nil, fmt.Errorf("unknown schedule kind: %s", job.Schedule.Kind)
 	}
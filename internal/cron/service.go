@@ -11,6 +11,7 @@ import (
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/roelfdiedericks/goclaw/internal/bus"
+	"github.com/roelfdiedericks/goclaw/internal/hass"
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
 )
 
@@ -29,6 +30,7 @@ type HeartbeatState struct {
 	IntervalMinutes int
 	Prompt          string
 	WorkspaceDir    string // For checking HEARTBEAT.md
+	AgentID         string // Agent persona to run (empty = main)
 }
 
 // Package-level singleton
@@ -51,6 +53,7 @@ type AgentRequest struct {
 	SkipMirror     bool   // If true, don't mirror to other channels (caller handles delivery)
 	JobName        string // Name of the cron job (for status messages)
 	Purpose        string // LLM purpose (e.g., "heartbeat", "cron"). Empty = "agent"
+	AgentID        string // Agent persona to run (empty = main)
 }
 
 // AgentEvent is a marker interface for agent events.
@@ -89,6 +92,8 @@ type Channel interface {
 // ChannelProvider provides access to channels for delivery.
 type ChannelProvider interface {
 	Channels() map[string]Channel
+	// ChannelsForAgent returns the channels that carry conversations for an agent.
+	ChannelsForAgent(agentID string) map[string]Channel
 }
 
 // Service manages cron job scheduling and execution.
@@ -103,6 +108,10 @@ type Service struct {
 	stopCh  chan struct{}
 	doneCh  chan struct{}
 
+	// runMu guards job run state (running, pending runs, triggers), which
+	// the scheduler, trigger sources and finishing runs all change
+	runMu sync.Mutex
+
 	timer            *time.Timer       // Timer for next scheduled job
 	backupTicker     *time.Ticker      // Backup tick every BackupTickInterval
 	watcher          *fsnotify.Watcher // File watcher for jobs.json
@@ -116,9 +125,19 @@ type Service struct {
 	heartbeatConfig *HeartbeatState
 	heartbeatTimer  *time.Timer
 	lastHeartbeat   time.Time
+	agentHeartbeats []*HeartbeatState // Heartbeats for named agents (own ticker each)
 
 	// Event subscriptions
 	configEventSub bus.SubscriptionID
+
+	// Event triggers
+	runCtx       context.Context             // Context for runs started outside the scheduler loop
+	hassManager  *hass.Manager               // Source of "hass" triggers (nil = not configured)
+	workspaceFor func(agentID string) string // Workspace lookup for "file" triggers
+	fileWatcher  *fsnotify.Watcher           // Watches directories of "file" triggers
+	fileWatches  map[string]bool             // Directories currently watched
+	fileDebounce map[string]*time.Timer      // Job ID -> pending file trigger
+	triggerMu    sync.Mutex
 }
 
 // NewService creates a new cron service and sets it as the global singleton.
@@ -223,6 +242,17 @@ func (s *Service) Start(ctx context.Context) error {
 		}
 	}
 
+	// Set up watcher for "file" triggers
+	s.runCtx = ctx
+	s.fileWatches = make(map[string]bool)
+	s.fileDebounce = make(map[string]*time.Timer)
+	if fileWatcher, err := fsnotify.NewWatcher(); err != nil {
+		L_warn("cron: failed to create file trigger watcher", "error", err)
+	} else {
+		s.fileWatcher = fileWatcher
+		go s.fileTriggerLoop(ctx, fileWatcher)
+	}
+
 	// Set up backup ticker
 	s.backupTicker = time.NewTicker(BackupTickInterval)
 
@@ -233,6 +263,11 @@ func (s *Service) Start(ctx context.Context) error {
 		L_info("cron: heartbeat enabled", "interval", interval)
 	}
 
+	// Start heartbeats for named agents
+	for _, hb := range s.agentHeartbeats {
+		go s.runAgentHeartbeatLoop(ctx, hb, s.stopCh)
+	}
+
 	// Initialize next run times for all jobs
 	s.initializeNextRuns()
 
@@ -269,6 +304,15 @@ func (s *Service) Stop() {
 		s.heartbeatTimer.Stop()
 		s.heartbeatTimer = nil
 	}
+	s.triggerMu.Lock()
+	for _, t := range s.fileDebounce {
+		t.Stop()
+	}
+	if s.fileWatcher != nil {
+		s.fileWatcher.Close()
+		s.fileWatcher = nil
+	}
+	s.triggerMu.Unlock()
 
 	L_info("cron: service stopped")
 }
@@ -373,7 +417,12 @@ func (s *Service) handleRun(cmd bus.Command) bus.CommandResult {
 	}
 
 	// Run the job asynchronously
-	go s.executeJob(context.Background(), job)
+	if err := s.RunNow(context.Background(), job.ID); err != nil {
+		return bus.CommandResult{
+			Error:   err,
+			Message: err.Error(),
+		}
+	}
 
 	return bus.CommandResult{
 		Success: true,
@@ -390,9 +439,9 @@ func (s *Service) clearOrphanedRunningState() {
 	for _, job := range jobs {
 		if job.IsRunning() {
 			L_warn("cron: clearing orphaned running state", "job", job.Name, "id", job.ID)
+			// Only the running marker goes; NextRunAtMs stays so the missed run
+			// is caught up by initializeNextRuns under the misfire policy
 			job.ClearRunning()
-			// Also clear NextRunAtMs - it will be recalculated by initializeNextRuns
-			job.SetNextRun(nil)
 			if err := s.store.UpdateJob(job); err != nil {
 				L_error("cron: failed to clear orphaned state", "job", job.Name, "error", err)
 			}
@@ -423,10 +472,15 @@ func (s *Service) initializeNextRuns() {
 			continue
 		}
 
-		next, err := NextRunTime(job, now)
-		if err != nil {
-			L_error("cron: failed to calculate next run", "job", job.Name, "id", job.ID, "error", err)
-			continue
+		// Runs missed while we were down are handled by the job's misfire policy
+		next := catchUpRun(job, now)
+		if next == nil {
+			var err error
+			next, err = PlanNextRun(job, now)
+			if err != nil {
+				L_error("cron: failed to calculate next run", "job", job.Name, "id", job.ID, "error", err)
+				continue
+			}
 		}
 		job.SetNextRun(next)
 		if err := s.store.UpdateJob(job); err != nil {
@@ -443,6 +497,8 @@ func (s *Service) initializeNextRuns() {
 
 	// Extend ignore window after all writes complete
 	s.ignoreWatchUntil = time.Now().Add(200 * time.Millisecond)
+
+	s.SyncTriggers()
 }
 
 func formatScheduleLog(s *Schedule) string {
@@ -456,6 +512,8 @@ func formatScheduleLog(s *Schedule) string {
 			return fmt.Sprintf("cron '%s' (%s)", s.Expr, s.Tz)
 		}
 		return fmt.Sprintf("cron '%s'", s.Expr)
+	case ScheduleKindHass, ScheduleKindJob, ScheduleKindWebhook, ScheduleKindFile:
+		return "on " + FormatTrigger(s)
 	default:
 		return "unknown"
 	}
@@ -615,24 +673,73 @@ func (s *Service) runDueJobs(ctx context.Context) {
 	L_debug("cron: checking due jobs", "count", len(dueJobs))
 
 	for _, job := range dueJobs {
-		if job.IsRunning() {
-			L_debug("cron: job already running, skipping", "job", job.Name)
-			continue
-		}
-
 		// IMPORTANT: Clear nextRunAtMs immediately to prevent re-triggering
-		// before the goroutine can mark it as running
+		// before the goroutine can mark it as running. If the job is already
+		// running, that execution reschedules it when it finishes.
+		s.runMu.Lock()
 		job.SetNextRun(nil)
-		job.SetRunning()
-		if err := s.store.UpdateJob(job); err != nil {
-			L_error("cron: failed to mark job starting", "job", job.Name, "error", err)
-			continue
-		}
+		s.runMu.Unlock()
 
 		L_info("cron: starting job execution", "job", job.Name, "id", job.ID, "prompt", truncateLog(job.Payload.GetPrompt(), 100))
-		// Execute in goroutine to not block other jobs
-		go s.executeJob(ctx, job)
+		if err := s.startJob(ctx, job, nil); err != nil {
+			L_info("cron: job not started", "job", job.Name, "error", err)
+		}
+	}
+}
+
+// startJob marks a job running and executes it in a goroutine.
+// If the job is already running, its concurrency policy decides whether the
+// run is queued (returns nil) or dropped (returns an error).
+func (s *Service) startJob(ctx context.Context, job *CronJob, trig *TriggerContext) error {
+	trig, start, err := s.claimRun(job, trig)
+	if !start {
+		return err
+	}
+
+	// Execute in goroutine to not block other jobs
+	go s.executeJob(ctx, job, trig)
+	return nil
+}
+
+// claimRun marks a job running, or queues or drops the run if it already
+// is. It returns the trigger of the run to start and whether to start it.
+// A clock run of an event-triggered job takes the oldest queued event, or
+// reuses the last one for a retry.
+func (s *Service) claimRun(job *CronJob, trig *TriggerContext) (*TriggerContext, bool, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	if job.IsRunning() {
+		if job.ConcurrencyPolicy() == ConcurrencyQueue {
+			job.State.PendingRuns++
+			if trig != nil {
+				job.State.QueuedTriggers = append(job.State.QueuedTriggers, trig)
+			}
+			L_info("cron: job already running, queued run", "job", job.Name, "pending", job.State.PendingRuns)
+			return nil, false, s.store.UpdateJob(job)
+		}
+		return nil, false, fmt.Errorf("job %q is already running", job.Name)
+	}
+
+	if trig == nil && job.Schedule.IsEvent() {
+		if queued := job.State.QueuedTriggers; job.State.RetryAttempt == 0 && len(queued) > 0 {
+			trig = queued[0]
+			job.State.QueuedTriggers = queued[1:]
+		} else {
+			trig = job.State.Trigger
+		}
+	}
+	if trig != nil {
+		job.State.Trigger = trig
 	}
+
+	job.SetRunning()
+	if err := s.store.UpdateJob(job); err != nil {
+		job.ClearRunning()
+		L_error("cron: failed to mark job starting", "job", job.Name, "error", err)
+		return nil, false, fmt.Errorf("failed to mark job running: %w", err)
+	}
+	return trig, true, nil
 }
 
 func truncateLog(s string, max int) string {
@@ -643,14 +750,16 @@ func truncateLog(s string, max int) string {
 }
 
 // executeJob runs a single cron job.
-// Note: job is already marked as running by runDueJobs before this is called.
-func (s *Service) executeJob(ctx context.Context, job *CronJob) {
+// Note: job is already marked as running by startJob before this is called.
+// trig is the event that fired the run (nil for clock runs).
+func (s *Service) executeJob(ctx context.Context, job *CronJob, trig *TriggerContext) {
 	startTime := time.Now()
 
-	// Apply job timeout if configured
-	if s.jobTimeoutMinutes > 0 {
+	// Apply job timeout if configured (per-job payload.timeoutSeconds wins)
+	timeout := job.Timeout(s.jobTimeoutMinutes)
+	if timeout > 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, time.Duration(s.jobTimeoutMinutes)*time.Minute)
+		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 	}
 
@@ -659,7 +768,8 @@ func (s *Service) executeJob(ctx context.Context, job *CronJob) {
 		"id", job.ID,
 		"session", job.SessionTarget,
 		"isolated", job.IsIsolated(),
-		"timeoutMinutes", s.jobTimeoutMinutes,
+		"timeout", timeout,
+		"retryAttempt", job.State.RetryAttempt,
 		"prompt", truncateLog(job.Payload.GetPrompt(), 200))
 
 	// Build agent request
@@ -672,23 +782,31 @@ func (s *Service) executeJob(ctx context.Context, job *CronJob) {
 	userID := s.gateway.GetOwnerUserID()
 	if userID == "" {
 		L_error("cron: no owner user configured, cannot run job", "job", job.Name)
+		s.runMu.Lock()
 		job.SetLastRun(startTime, 0, StatusError, "no owner user configured")
 		job.ClearRunning()
 		if err := s.store.UpdateJob(job); err != nil {
 			L_warn("cron: failed to update job after error", "job", job.Name, "error", err)
 		}
+		s.runMu.Unlock()
 		return
 	}
 
+	prompt := job.Payload.GetPrompt()
+	if trig != nil {
+		prompt = trig.Prompt(prompt)
+	}
+
 	req := AgentRequest{
 		Source:       "cron",
-		UserMsg:      job.Payload.GetPrompt(),
+		UserMsg:      prompt,
 		FreshContext: job.IsIsolated(),
 		SessionID:    sessionID,
 		UserID:       userID,
 		SkipMirror:   true,
 		JobName:      job.Name,
 		Purpose:      "cron",
+		AgentID:      job.AgentID,
 	}
 
 	L_debug("cron: invoking agent",
@@ -743,6 +861,7 @@ func (s *Service) executeJob(ctx context.Context, job *CronJob) {
 			"events", eventCount)
 	}
 
+	s.runMu.Lock()
 	job.SetLastRun(startTime, duration, status, errStr)
 
 	// Log run to history
@@ -751,32 +870,39 @@ func (s *Service) executeJob(ctx context.Context, job *CronJob) {
 		L_error("cron: failed to log run", "job", job.Name, "error", err)
 	}
 
-	// Calculate next run time
-	if job.IsOneShot() {
+	// Calculate next run time (retry, owed run or regular schedule)
+	next, err := nextRunAfter(job, status, time.Now())
+	if err != nil {
+		L_error("cron: failed to calculate next run", "job", job.Name, "error", err)
+	}
+	job.SetNextRun(next)
+	switch {
+	case next == nil && job.IsOneShot():
 		// One-shot job: disable after run
 		job.Enabled = false
-		job.SetNextRun(nil)
 		L_info("cron: one-shot job completed and disabled", "job", job.Name, "id", job.ID)
-	} else {
-		// Recurring job: calculate next run
-		next, err := NextRunTime(job, time.Now())
-		if err != nil {
-			L_error("cron: failed to calculate next run", "job", job.Name, "error", err)
-		}
-		job.SetNextRun(next)
-		if next != nil {
-			L_info("cron: next run scheduled", "job", job.Name, "nextRun", next.Format(time.RFC3339))
-		}
+	case next != nil && job.State.RetryAttempt > 0:
+		L_warn("cron: retry scheduled", "job", job.Name, "attempt", job.State.RetryAttempt, "maxRetries", job.Retry.MaxRetries, "nextRun", next.Format(time.RFC3339))
+	case next != nil:
+		L_info("cron: next run scheduled", "job", job.Name, "nextRun", next.Format(time.RFC3339), "pending", job.State.PendingRuns)
 	}
 
 	if err := s.store.UpdateJob(job); err != nil {
 		L_error("cron: failed to save job state", "job", job.Name, "error", err)
 	}
+	s.runMu.Unlock()
 
 	// Deliver to channels if enabled
 	if job.Payload.Deliver && finalContent != "" {
 		s.deliverToChannels(ctx, job, finalContent)
 	}
+
+	// Start downstream jobs chained on this one
+	depth := 0
+	if trig != nil {
+		depth = trig.Depth
+	}
+	s.fireJobTriggers(job, status, finalContent, errStr, depth)
 }
 
 // deliverToChannels sends the job output to all available channels.
@@ -793,7 +919,7 @@ func (s *Service) deliverToChannels(ctx context.Context, job *CronJob, content s
 		return
 	}
 
-	channels := s.channelProvider.Channels()
+	channels := s.channelProvider.ChannelsForAgent(job.AgentID)
 	if len(channels) == 0 {
 		L_debug("cron: no channels available for delivery", "job", job.Name)
 		return
@@ -824,8 +950,15 @@ func (s *Service) History() *HistoryManager {
 
 // AddJob adds a new job and schedules it.
 func (s *Service) AddJob(job *CronJob) error {
+	if err := job.ValidatePolicy(); err != nil {
+		return err
+	}
+	if err := job.ValidateTrigger(); err != nil {
+		return err
+	}
+
 	// Calculate initial next run
-	next, err := NextRunTime(job, time.Now())
+	next, err := PlanNextRun(job, time.Now())
 	if err != nil {
 		return fmt.Errorf("invalid schedule: %w", err)
 	}
@@ -842,6 +975,39 @@ func (s *Service) AddJob(job *CronJob) error {
 
 	// Wake scheduler to recalculate
 	s.triggerReschedule()
+	if job.Schedule.IsEvent() {
+		s.SyncTriggers()
+	}
+	return nil
+}
+
+// UpdateJob validates an edited job, replans its next run and replaces the
+// stored job with the same ID.
+func (s *Service) UpdateJob(job *CronJob) error {
+	if err := job.ValidatePolicy(); err != nil {
+		return err
+	}
+	if err := job.ValidateTrigger(); err != nil {
+		return err
+	}
+
+	next, err := PlanNextRun(job, time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	job.SetNextRun(next)
+
+	// Suppress file watcher for our own write
+	s.ignoreWatchUntil = time.Now().Add(200 * time.Millisecond)
+
+	if err := s.store.UpdateJob(job); err != nil {
+		return err
+	}
+
+	L_info("cron: job updated", "job", job.Name, "id", job.ID, "enabled", job.Enabled, "nextRun", next)
+
+	s.triggerReschedule()
+	s.SyncTriggers()
 	return nil
 }
 
@@ -866,22 +1032,26 @@ func (s *Service) RunNow(ctx context.Context, id string) error {
 		return fmt.Errorf("job not found: %s", id)
 	}
 
-	go s.executeJob(ctx, job)
-	return nil
+	return s.startJob(ctx, job, nil)
 }
 
-// runHeartbeat executes the periodic heartbeat check.
+// runHeartbeat executes the periodic heartbeat check for the main agent.
 func (s *Service) runHeartbeat(ctx context.Context) {
 	if s.heartbeatConfig == nil || !s.heartbeatConfig.Enabled {
 		return
 	}
 
 	s.lastHeartbeat = time.Now()
-	L_info("heartbeat: starting")
+	s.runHeartbeatFor(ctx, s.heartbeatConfig)
+}
+
+// runHeartbeatFor executes a heartbeat check with the given config.
+func (s *Service) runHeartbeatFor(ctx context.Context, hb *HeartbeatState) {
+	L_info("heartbeat: starting", "agent", hb.AgentID)
 
 	// Check if HEARTBEAT.md has content
-	if s.heartbeatConfig.WorkspaceDir != "" {
-		heartbeatFile := filepath.Join(s.heartbeatConfig.WorkspaceDir, "HEARTBEAT.md")
+	if hb.WorkspaceDir != "" {
+		heartbeatFile := filepath.Join(hb.WorkspaceDir, "HEARTBEAT.md")
 		content, err := os.ReadFile(heartbeatFile)
 		if err != nil {
 			if os.IsNotExist(err) {
@@ -917,7 +1087,7 @@ func (s *Service) runHeartbeat(ctx context.Context) {
 	}
 
 	// Build the prompt
-	prompt := s.heartbeatConfig.Prompt
+	prompt := hb.Prompt
 	if prompt == "" {
 		prompt = DefaultHeartbeatPrompt
 	}
@@ -932,6 +1102,7 @@ func (s *Service) runHeartbeat(ctx context.Context) {
 		IsHeartbeat:  true,        // Ephemeral - don't persist to session
 		SkipMirror:   true,        // We handle delivery via ch.Send()
 		Purpose:      "heartbeat", // Use heartbeat model chain (falls back to agent)
+		AgentID:      hb.AgentID,
 	}
 
 	L_debug("heartbeat: invoking agent", "prompt", truncateLog(prompt, 100))
@@ -958,7 +1129,7 @@ func (s *Service) runHeartbeat(ctx context.Context) {
 
 	// Deliver response to channels
 	if finalContent != "" && s.channelProvider != nil {
-		channels := s.channelProvider.Channels()
+		channels := s.channelProvider.ChannelsForAgent(hb.AgentID)
 		if len(channels) > 0 {
 			msg := fmt.Sprintf("**[Heartbeat]**\n\n%s", finalContent)
 			for name, ch := range channels {
@@ -0,0 +1,461 @@
+package cron
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/roelfdiedericks/goclaw/internal/hass"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/security"
+)
+
+// Trigger limits
+const (
+	MaxTriggerDataChars = 8000            // Upstream output / event payload passed to the prompt
+	MaxTriggerDepth     = 8               // Longest job chain before triggers stop (guards against loops)
+	FileTriggerDebounce = 2 * time.Second // Quiet period before a file change fires
+	FileTriggerCooldown = 5 * time.Second // Changes this soon after a file job's run are ignored
+)
+
+// TriggerContext describes the event that started a triggered run.
+type TriggerContext struct {
+	Kind   string `json:"kind"`             // Schedule kind that fired
+	Source string `json:"source,omitempty"` // Entity ID, upstream job name or file path
+	Status string `json:"status,omitempty"` // Upstream job status (kind "job")
+	Data   string `json:"data,omitempty"`   // Event payload, webhook body or upstream output
+	Depth  int    `json:"depth,omitempty"`  // Position in a job chain (0 = not chained)
+}
+
+// Prompt appends the trigger details to the job's prompt. The payload comes
+// from outside (webhook callers, Home Assistant, another job's output), so it
+// is wrapped as untrusted external content like fetched web pages.
+func (t *TriggerContext) Prompt(base string) string {
+	var header string
+	switch t.Kind {
+	case ScheduleKindHass:
+		header = fmt.Sprintf("[Trigger: Home Assistant %s]", t.Source)
+	case ScheduleKindJob:
+		header = fmt.Sprintf("[Trigger: job %q finished with status %s. Its output follows.]", t.Source, t.Status)
+	case ScheduleKindWebhook:
+		header = "[Trigger: webhook. Request body follows.]"
+	case ScheduleKindFile:
+		header = fmt.Sprintf("[Trigger: file changed: %s]", t.Source)
+	default:
+		header = fmt.Sprintf("[Trigger: %s]", t.Kind)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(base)
+	sb.WriteString("\n\n")
+	sb.WriteString(header)
+	if t.Data != "" {
+		wrapped, spoofed := security.WrapExternalContent(truncateTriggerData(t.Data), t.dataSource(), "cron")
+		if spoofed {
+			L_warn("cron: trigger payload blocked", "kind", t.Kind, "source", t.Source)
+		}
+		sb.WriteString("\n")
+		sb.WriteString(wrapped)
+	}
+	return sb.String()
+}
+
+// dataSource names where a trigger's payload came from, for the content wrapper.
+func (t *TriggerContext) dataSource() string {
+	if t.Source == "" {
+		return t.Kind
+	}
+	return t.Kind + ":" + t.Source
+}
+
+// FormatTrigger describes an event trigger for logs and tool output.
+func FormatTrigger(s *Schedule) string {
+	switch s.Kind {
+	case ScheduleKindHass:
+		entity := s.Entity
+		if entity == "" {
+			entity = "/" + s.EntityRegex + "/"
+		}
+		if s.State != "" {
+			return fmt.Sprintf("hass %s → %s", entity, s.State)
+		}
+		return "hass " + entity
+	case ScheduleKindJob:
+		on := s.On
+		if on == "" {
+			on = TriggerOnSuccess
+		}
+		return fmt.Sprintf("job %s (%s)", s.AfterJob, on)
+	case ScheduleKindWebhook:
+		return "webhook"
+	case ScheduleKindFile:
+		return "file " + s.Path
+	default:
+		return s.Kind
+	}
+}
+
+func truncateTriggerData(s string) string {
+	if len(s) <= MaxTriggerDataChars {
+		return s
+	}
+	return s[:MaxTriggerDataChars] + "\n...(truncated)"
+}
+
+// ValidateTrigger checks event trigger fields and fills defaults
+// (a webhook secret is generated if none is set).
+func (j *CronJob) ValidateTrigger() error {
+	s := &j.Schedule
+	switch s.Kind {
+	case ScheduleKindHass:
+		if s.Entity == "" && s.EntityRegex == "" {
+			return fmt.Errorf("hass trigger requires entity or entityRegex")
+		}
+		if s.EntityRegex != "" {
+			if _, err := regexp.Compile(s.EntityRegex); err != nil {
+				return fmt.Errorf("invalid entityRegex: %w", err)
+			}
+		}
+	case ScheduleKindJob:
+		if s.AfterJob == "" {
+			return fmt.Errorf("job trigger requires afterJob")
+		}
+		if s.AfterJob == j.ID || s.AfterJob == j.Name {
+			return fmt.Errorf("job cannot trigger itself")
+		}
+		switch s.On {
+		case "", TriggerOnSuccess, TriggerOnFailure, TriggerOnAny:
+		default:
+			return fmt.Errorf("invalid on %q (must be success, failure or any)", s.On)
+		}
+	case ScheduleKindWebhook:
+		if s.Secret == "" {
+			secret, err := generateSecret()
+			if err != nil {
+				return err
+			}
+			s.Secret = secret
+		}
+	case ScheduleKindFile:
+		if s.Path == "" {
+			return fmt.Errorf("file trigger requires path")
+		}
+		if filepath.IsAbs(s.Path) || strings.HasPrefix(filepath.Clean(s.Path), "..") {
+			return fmt.Errorf("file trigger path must be relative to the workspace")
+		}
+		if strings.ContainsAny(filepath.Dir(s.Path), "*?[") {
+			return fmt.Errorf("file trigger path may only use wildcards in the file name")
+		}
+		if _, err := filepath.Match(s.Path, ""); err != nil {
+			return fmt.Errorf("invalid file trigger path: %w", err)
+		}
+	}
+	return nil
+}
+
+// generateSecret returns a random hex token for webhook triggers.
+func generateSecret() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// SetHassManager connects the service to Home Assistant state changes for "hass" triggers.
+func (s *Service) SetHassManager(m *hass.Manager) {
+	s.hassManager = m
+	m.AddListener(s)
+}
+
+// SetWorkspaceResolver sets how a job's agent maps to a workspace (for "file" triggers).
+func (s *Service) SetWorkspaceResolver(fn func(agentID string) string) {
+	s.workspaceFor = fn
+}
+
+// WantsHassEvents reports whether any enabled job is triggered by Home Assistant.
+func (s *Service) WantsHassEvents() bool {
+	if !s.IsRunning() {
+		return false
+	}
+	for _, job := range s.store.GetEnabledJobs() {
+		if job.Schedule.Kind == ScheduleKindHass {
+			return true
+		}
+	}
+	return false
+}
+
+// HandleHassEvent fires "hass" jobs whose entity filter matches a state change.
+// Attribute-only updates (same state) are ignored.
+func (s *Service) HandleHassEvent(event *hass.HAEvent) {
+	entityID := event.Data.EntityID
+	oldState, newState := "", ""
+	if event.Data.OldState != nil {
+		oldState = event.Data.OldState.State
+	}
+	if event.Data.NewState != nil {
+		newState = event.Data.NewState.State
+	}
+	if oldState == newState {
+		return
+	}
+
+	for _, job := range s.store.GetEnabledJobs() {
+		sched := &job.Schedule
+		if sched.Kind != ScheduleKindHass {
+			continue
+		}
+		if !hass.MatchSubscription(&hass.Subscription{Pattern: sched.Entity, Regex: sched.EntityRegex}, entityID) {
+			continue
+		}
+		if sched.State != "" && sched.State != newState {
+			continue
+		}
+
+		payload, _ := json.Marshal(hass.BriefEventPayload{
+			EntityID:  entityID,
+			State:     newState,
+			OldState:  oldState,
+			TimeFired: event.TimeFired,
+		})
+		s.fireTrigger(job, &TriggerContext{ //nolint:errcheck // logged in fireTrigger
+			Kind:   ScheduleKindHass,
+			Source: fmt.Sprintf("%s → %s", entityID, newState),
+			Data:   string(payload),
+		})
+	}
+}
+
+// HandleWebhook fires a "webhook" job. Returns an error if the job is unknown,
+// disabled or the secret does not match.
+func (s *Service) HandleWebhook(id, secret string, body []byte) error {
+	job := s.store.GetJob(id)
+	if job == nil || job.Schedule.Kind != ScheduleKindWebhook {
+		return fmt.Errorf("job not found: %s", id)
+	}
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(job.Schedule.Secret)) != 1 {
+		return fmt.Errorf("invalid secret")
+	}
+	return s.TriggerWebhook(id, body)
+}
+
+// TriggerWebhook fires a webhook job for a caller already authenticated
+// another way (an API token with the webhook scope), skipping the secret.
+func (s *Service) TriggerWebhook(id string, body []byte) error {
+	job := s.store.GetJob(id)
+	if job == nil || job.Schedule.Kind != ScheduleKindWebhook {
+		return fmt.Errorf("job not found: %s", id)
+	}
+	if !job.Enabled {
+		return fmt.Errorf("job %q is disabled", job.Name)
+	}
+	// Accepted even if the concurrency policy drops the run
+	s.fireTrigger(job, &TriggerContext{ //nolint:errcheck // logged in fireTrigger
+		Kind: ScheduleKindWebhook,
+		Data: string(body),
+	})
+	return nil
+}
+
+// fireJobTriggers fires "job" jobs chained after an upstream job finished.
+func (s *Service) fireJobTriggers(upstream *CronJob, status, output, errStr string, depth int) {
+	if depth >= MaxTriggerDepth {
+		L_warn("cron: job chain too deep, not triggering downstream jobs", "job", upstream.Name, "depth", depth)
+		return
+	}
+
+	data := output
+	if status == StatusError {
+		data = "Error: " + errStr
+		if output != "" {
+			data += "\n\n" + output
+		}
+	}
+
+	for _, job := range s.store.GetEnabledJobs() {
+		sched := &job.Schedule
+		if sched.Kind != ScheduleKindJob || job.ID == upstream.ID {
+			continue
+		}
+		if sched.AfterJob != upstream.ID && sched.AfterJob != upstream.Name {
+			continue
+		}
+		switch sched.On {
+		case TriggerOnAny:
+		case TriggerOnFailure:
+			if status != StatusError {
+				continue
+			}
+		default:
+			if status != StatusOK {
+				continue
+			}
+		}
+
+		s.fireTrigger(job, &TriggerContext{ //nolint:errcheck // logged in fireTrigger
+			Kind:   ScheduleKindJob,
+			Source: upstream.Name,
+			Status: status,
+			Data:   data,
+			Depth:  depth + 1,
+		})
+	}
+}
+
+// fireTrigger starts an event-triggered job, honouring its concurrency policy.
+func (s *Service) fireTrigger(job *CronJob, trig *TriggerContext) error {
+	if !s.IsRunning() {
+		return fmt.Errorf("cron service is not running")
+	}
+	L_info("cron: job triggered", "job", job.Name, "id", job.ID, "trigger", trig.Kind, "source", trig.Source)
+	if err := s.startJob(s.runCtx, job, trig); err != nil {
+		L_info("cron: triggered run not started", "job", job.Name, "error", err)
+		return err
+	}
+	return nil
+}
+
+// SyncTriggers updates Home Assistant and file watches after jobs change.
+func (s *Service) SyncTriggers() {
+	if s.hassManager != nil {
+		s.hassManager.Refresh()
+	}
+	s.syncFileTriggers()
+}
+
+// syncFileTriggers watches the directories of all enabled "file" jobs.
+func (s *Service) syncFileTriggers() {
+	s.triggerMu.Lock()
+	defer s.triggerMu.Unlock()
+
+	if s.fileWatcher == nil {
+		return
+	}
+
+	wanted := make(map[string]bool)
+	for _, job := range s.store.GetEnabledJobs() {
+		if job.Schedule.Kind != ScheduleKindFile {
+			continue
+		}
+		wanted[filepath.Dir(filepath.Join(s.jobWorkspace(job), job.Schedule.Path))] = true
+	}
+
+	for dir := range s.fileWatches {
+		if !wanted[dir] {
+			if err := s.fileWatcher.Remove(dir); err != nil {
+				L_debug("cron: failed to unwatch trigger dir", "dir", dir, "error", err)
+			}
+			delete(s.fileWatches, dir)
+		}
+	}
+	for dir := range wanted {
+		if s.fileWatches[dir] {
+			continue
+		}
+		if err := s.fileWatcher.Add(dir); err != nil {
+			L_warn("cron: failed to watch trigger dir", "dir", dir, "error", err)
+			continue
+		}
+		s.fileWatches[dir] = true
+		L_debug("cron: watching for file triggers", "dir", dir)
+	}
+}
+
+// jobWorkspace returns the workspace a job's file trigger is relative to.
+func (s *Service) jobWorkspace(job *CronJob) string {
+	if s.workspaceFor != nil {
+		return s.workspaceFor(job.AgentID)
+	}
+	if s.heartbeatConfig != nil {
+		return s.heartbeatConfig.WorkspaceDir
+	}
+	return "."
+}
+
+// fileTriggerLoop dispatches file changes to "file" jobs, debounced per job.
+func (s *Service) fileTriggerLoop(ctx context.Context, watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+			s.handleFileChange(event.Name)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			L_warn("cron: file trigger watcher error", "error", err)
+		}
+	}
+}
+
+// ranRecently reports whether a job is running or finished less than
+// FileTriggerCooldown ago. A file job that writes into its own watched
+// pattern would otherwise trigger itself forever.
+func (s *Service) ranRecently(job *CronJob, now time.Time) bool {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	if job.IsRunning() {
+		return true
+	}
+	if job.State.LastRunAtMs == nil {
+		return false
+	}
+	end := time.UnixMilli(*job.State.LastRunAtMs + job.State.LastDurationMs)
+	return now.Sub(end) < FileTriggerCooldown
+}
+
+// handleFileChange fires "file" jobs whose pattern matches the changed path
+// once the file has been quiet for FileTriggerDebounce.
+func (s *Service) handleFileChange(path string) {
+	for _, job := range s.store.GetEnabledJobs() {
+		if job.Schedule.Kind != ScheduleKindFile {
+			continue
+		}
+		rel, err := filepath.Rel(s.jobWorkspace(job), path)
+		if err != nil {
+			continue
+		}
+		if matched, _ := filepath.Match(job.Schedule.Path, rel); !matched {
+			continue
+		}
+		if s.ranRecently(job, time.Now()) {
+			L_debug("cron: ignoring file change during or just after the job's run", "job", job.Name, "path", rel)
+			continue
+		}
+
+		jobID := job.ID
+		s.triggerMu.Lock()
+		if t, ok := s.fileDebounce[jobID]; ok {
+			t.Stop()
+		}
+		s.fileDebounce[jobID] = time.AfterFunc(FileTriggerDebounce, func() {
+			s.triggerMu.Lock()
+			delete(s.fileDebounce, jobID)
+			s.triggerMu.Unlock()
+
+			if job := s.store.GetJob(jobID); job != nil && job.Enabled {
+				s.fireTrigger(job, &TriggerContext{Kind: ScheduleKindFile, Source: rel}) //nolint:errcheck // logged in fireTrigger
+			}
+		})
+		s.triggerMu.Unlock()
+	}
+}
@@ -23,16 +23,39 @@ type CronJob struct {
 	Payload        Payload    `json:"payload"`
 	DeleteAfterRun bool       `json:"deleteAfterRun,omitempty"`
 	Isolation      *Isolation `json:"isolation,omitempty"`
-	State          JobState   `json:"state"`
+
+	// Execution policy (GoClaw extensions, all optional)
+	Retry         *RetryPolicy `json:"retry,omitempty"`         // Retry failed runs with exponential backoff
+	Misfire       string       `json:"misfire,omitempty"`       // Missed runs after downtime: "skip" (default), "run_once", "run_all"
+	JitterSeconds int          `json:"jitterSeconds,omitempty"` // Random delay added to each scheduled run (0 = none)
+	Concurrency   string       `json:"concurrency,omitempty"`   // Overlapping runs: "forbid" (default) or "queue"
+
+	State JobState `json:"state"`
+}
+
+// RetryPolicy controls how failed runs are retried.
+type RetryPolicy struct {
+	MaxRetries        int `json:"maxRetries"`                  // Retries after a failed run (0 = none)
+	BackoffSeconds    int `json:"backoffSeconds,omitempty"`    // Delay before the first retry, doubled each attempt (default: 30)
+	MaxBackoffSeconds int `json:"maxBackoffSeconds,omitempty"` // Upper bound on the retry delay (default: 3600)
 }
 
 // Schedule defines when a job should run.
 type Schedule struct {
-	Kind    string `json:"kind"`              // "at", "every", "cron"
+	Kind    string `json:"kind"`              // "at", "every", "cron", or event triggers "hass", "job", "webhook", "file"
 	AtMs    int64  `json:"atMs,omitempty"`    // for "at": unix ms timestamp
 	EveryMs int64  `json:"everyMs,omitempty"` // for "every": interval in ms
 	Expr    string `json:"expr,omitempty"`    // for "cron": 5-field cron expression
 	Tz      string `json:"tz,omitempty"`      // for "cron": IANA timezone
+
+	// Event triggers
+	Entity      string `json:"entity,omitempty"`      // for "hass": entity_id glob (e.g. "binary_sensor.door_*")
+	EntityRegex string `json:"entityRegex,omitempty"` // for "hass": entity_id regex (instead of entity)
+	State       string `json:"state,omitempty"`       // for "hass": only fire when the new state equals this
+	AfterJob    string `json:"afterJob,omitempty"`    // for "job": upstream job ID or name
+	On          string `json:"on,omitempty"`          // for "job": "success" (default), "failure" or "any"
+	Secret      string `json:"secret,omitempty"`      // for "webhook": shared secret required by the endpoint
+	Path        string `json:"path,omitempty"`        // for "file": workspace-relative glob (e.g. "inbox/*.md")
 }
 
 // Payload defines what the job should do.
@@ -64,6 +87,11 @@ type JobState struct {
 	LastStatus     string `json:"lastStatus,omitempty"` // "ok", "error"
 	LastError      string `json:"lastError,omitempty"`
 	LastDurationMs int64  `json:"lastDurationMs,omitempty"`
+	RetryAttempt   int    `json:"retryAttempt,omitempty"` // Retries used since the last successful run
+	PendingRuns    int    `json:"pendingRuns,omitempty"`  // Runs owed from queued overlaps or missed catch-up
+
+	Trigger        *TriggerContext   `json:"trigger,omitempty"`        // Event that fired the current or last run (reused by retries)
+	QueuedTriggers []*TriggerContext `json:"queuedTriggers,omitempty"` // Events of queued runs, oldest first
 }
 
 // StoreFile is the root structure of the jobs.json file.
@@ -86,6 +114,19 @@ const (
 	ScheduleKindAt    = "at"
 	ScheduleKindEvery = "every"
 	ScheduleKindCron  = "cron"
+
+	// Event triggers: the job runs when something happens instead of on a clock
+	ScheduleKindHass    = "hass"    // Home Assistant state change
+	ScheduleKindJob     = "job"     // Completion of another job
+	ScheduleKindWebhook = "webhook" // POST to /api/cron/webhook/<id>
+	ScheduleKindFile    = "file"    // File change in the workspace
+)
+
+// Job trigger filters (Schedule.On)
+const (
+	TriggerOnSuccess = "success"
+	TriggerOnFailure = "failure"
+	TriggerOnAny     = "any"
 )
 
 // Session target constants
@@ -100,6 +141,19 @@ const (
 	PayloadKindAgentTurn   = "agentTurn"
 )
 
+// Misfire policy constants
+const (
+	MisfireSkip    = "skip"
+	MisfireRunOnce = "run_once"
+	MisfireRunAll  = "run_all"
+)
+
+// Concurrency policy constants
+const (
+	ConcurrencyForbid = "forbid"
+	ConcurrencyQueue  = "queue"
+)
+
 // Job status constants
 const (
 	StatusOK    = "ok"
@@ -119,6 +173,15 @@ func (j *CronJob) IsIsolated() bool {
 	return j.SessionTarget == SessionTargetIsolated
 }
 
+// IsEvent returns true if the schedule is an event trigger rather than a clock.
+func (s *Schedule) IsEvent() bool {
+	switch s.Kind {
+	case ScheduleKindHass, ScheduleKindJob, ScheduleKindWebhook, ScheduleKindFile:
+		return true
+	}
+	return false
+}
+
 // IsOneShot returns true if this is a one-shot job (at schedule).
 func (j *CronJob) IsOneShot() bool {
 	return j.Schedule.Kind == ScheduleKindAt
@@ -0,0 +1,338 @@
+package gateway
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/roelfdiedericks/goclaw/internal/agents"
+	gcontext "github.com/roelfdiedericks/goclaw/internal/context"
+	"github.com/roelfdiedericks/goclaw/internal/llm"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/memory"
+	"github.com/roelfdiedericks/goclaw/internal/paths"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox"
+	"github.com/roelfdiedericks/goclaw/internal/session"
+	"github.com/roelfdiedericks/goclaw/internal/skills"
+	"github.com/roelfdiedericks/goclaw/internal/tools"
+	"github.com/roelfdiedericks/goclaw/internal/types"
+	"github.com/roelfdiedericks/goclaw/internal/user"
+)
+
+// agentRuntime holds the resolved state for one agent persona.
+// The main agent shares the gateway's workspace, prompt cache, memory and LLM.
+type agentRuntime struct {
+	id            string
+	identity      AgentIdentityConfig
+	workspace     string
+	ownWorkspace  bool            // true if the agent has its own workspace (not the gateway's)
+	llmPurpose    string          // "agent" or "agent:<id>" when the agent has its own model chain
+	tools         map[string]bool // nil = no restriction beyond the user's role
+	skills        map[string]bool // nil = no restriction beyond the user's role
+	promptCache   *gcontext.PromptCache
+	memoryManager *memory.Manager
+}
+
+// isMain returns true for the implicit default agent
+func (a *agentRuntime) isMain() bool {
+	return a.id == agents.MainAgentID
+}
+
+// initAgents builds runtimes for the main agent and every configured named agent.
+// Called from New after the shared prompt cache and memory manager exist.
+func (g *Gateway) initAgents() error {
+	cfg := &g.config.Agents
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	g.agents = map[string]*agentRuntime{
+		agents.MainAgentID: {
+			id:            agents.MainAgentID,
+			identity:      g.config.Agent,
+			workspace:     g.config.Gateway.WorkingDir,
+			llmPurpose:    "agent",
+			promptCache:   g.promptCache,
+			memoryManager: g.memoryManager,
+		},
+	}
+
+	for id, agentCfg := range cfg.List {
+		rt, err := g.newAgentRuntime(id, agentCfg)
+		if err != nil {
+			return err
+		}
+		g.agents[id] = rt
+		L_info("agents: agent ready",
+			"id", id,
+			"name", rt.identity.Name,
+			"workspace", rt.workspace,
+			"purpose", rt.llmPurpose,
+			"tools", len(rt.tools),
+			"skills", len(rt.skills))
+	}
+
+	if len(cfg.List) > 0 {
+		L_info("agents: routing configured", "agents", len(g.agents), "routes", len(cfg.Routes), "default", cfg.DefaultAgent())
+	}
+	return nil
+}
+
+// newAgentRuntime resolves a named agent, inheriting unset fields from main.
+func (g *Gateway) newAgentRuntime(id string, agentCfg agents.AgentConfig) (*agentRuntime, error) {
+	rt := &agentRuntime{
+		id: id,
+		identity: AgentIdentityConfig{
+			Name:   agentCfg.Name,
+			Emoji:  agentCfg.Emoji,
+			Typing: agentCfg.Typing,
+		},
+		workspace:     g.config.Gateway.WorkingDir,
+		llmPurpose:    "agent",
+		tools:         toSet(agentCfg.Tools),
+		skills:        toSet(agentCfg.Skills),
+		promptCache:   g.promptCache,
+		memoryManager: g.memoryManager,
+	}
+	if rt.identity.Name == "" {
+		rt.identity.Name = id
+	}
+
+	// Own model chain, registered as a scoped purpose so failover and cooldowns apply
+	if len(agentCfg.Models) > 0 {
+		purpose := "agent:" + id
+		if err := g.registry.RegisterPurpose(purpose, llm.LLMPurposeConfig{Models: agentCfg.Models}); err != nil {
+			return nil, err
+		}
+		rt.llmPurpose = purpose
+	}
+
+	if agentCfg.Workspace == "" {
+		return rt, nil
+	}
+
+	// Own workspace: separate prompt files, memory index and sandbox root
+	workspace, err := paths.ExpandTilde(agentCfg.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err := filepath.Abs(workspace); err == nil {
+		workspace = abs
+	}
+	if err := os.MkdirAll(workspace, 0750); err != nil {
+		return nil, err
+	}
+	rt.workspace = workspace
+	rt.ownWorkspace = true
+
+	if err := sandbox.GetManager().RegisterWorkspaceRoot(workspace); err != nil {
+		L_warn("agents: failed to register workspace with sandbox", "agent", id, "error", err)
+	}
+
+	promptCache, err := gcontext.NewPromptCache(workspace, g.config.PromptCache.PollInterval)
+	if err != nil {
+		L_warn("agents: failed to create prompt cache", "agent", id, "error", err)
+	} else {
+		rt.promptCache = promptCache
+	}
+
+	if g.config.Memory.Enabled {
+		memCfg := g.config.Memory
+		memCfg.DbPath = agentCfg.MemoryDB
+		if memCfg.DbPath == "" {
+			memCfg.DbPath = filepath.Join(workspace, ".goclaw", "memory.db")
+		}
+		memMgr, err := memory.NewManager(memCfg, workspace)
+		if err != nil {
+			L_warn("agents: failed to create memory manager", "agent", id, "error", err)
+		} else {
+			rt.memoryManager = memMgr
+		}
+	}
+
+	return rt, nil
+}
+
+// closeAgents releases resources owned by named agents (not the shared ones).
+func (g *Gateway) closeAgents() {
+	for _, rt := range g.agents {
+		if rt.isMain() {
+			continue
+		}
+		if rt.promptCache != nil && rt.promptCache != g.promptCache {
+			rt.promptCache.Close()
+		}
+		if rt.memoryManager != nil && rt.memoryManager != g.memoryManager {
+			rt.memoryManager.Close() //nolint:errcheck // shutdown cleanup
+		}
+	}
+}
+
+// agentFor returns the runtime for a request, routing by channel/bot/chat/user
+// unless the request names an agent explicitly.
+func (g *Gateway) agentFor(req AgentRequest) *agentRuntime {
+	id := req.AgentID
+	if id == "" {
+		in := agents.RouteInput{Channel: req.Source, Bot: req.Bot, ChatID: req.ChatID}
+		if req.User != nil {
+			in.UserID = req.User.ID
+		}
+		id = g.config.Agents.Resolve(in)
+	}
+	return g.agentByID(id)
+}
+
+// agentByID returns the runtime for an agent ID, falling back to main.
+func (g *Gateway) agentByID(id string) *agentRuntime {
+	if rt, ok := g.agents[id]; ok {
+		return rt
+	}
+	if id != "" && id != agents.MainAgentID {
+		L_warn("agents: unknown agent, using main", "agent", id)
+	}
+	return g.agents[agents.MainAgentID]
+}
+
+// HasAgent returns true if the agent ID is configured (main is always present).
+func (g *Gateway) HasAgent(id string) bool {
+	_, ok := g.agents[id]
+	return ok
+}
+
+// AgentIDs returns all configured agent IDs, main first.
+func (g *Gateway) AgentIDs() []string {
+	return g.config.Agents.IDs()
+}
+
+// ResolveAgent returns the agent ID that would handle a message from the given source.
+func (g *Gateway) ResolveAgent(channel, bot, chatID string, u *user.User) string {
+	in := agents.RouteInput{Channel: channel, Bot: bot, ChatID: chatID}
+	if u != nil {
+		in.UserID = u.ID
+	}
+	return g.config.Agents.Resolve(in)
+}
+
+// AgentIdentityFor returns the display identity of an agent (main if unknown).
+func (g *Gateway) AgentIdentityFor(agentID string) *AgentIdentityConfig {
+	rt := g.agentByID(agentID)
+	if rt.isMain() {
+		return &g.config.Agent
+	}
+	return &rt.identity
+}
+
+// AgentWorkspace returns the workspace directory of an agent (main if unknown).
+func (g *Gateway) AgentWorkspace(agentID string) string {
+	return g.agentByID(agentID).workspace
+}
+
+// MemoryManagerFor returns the memory manager for the agent of the current run.
+// Used by the memory tools; returns the shared manager outside agent runs.
+func (g *Gateway) MemoryManagerFor(ctx context.Context) *memory.Manager {
+	if sc := types.GetSessionContext(ctx); sc != nil && sc.AgentID != "" {
+		return g.agentByID(sc.AgentID).memoryManager
+	}
+	return g.memoryManager
+}
+
+// agentWorkspaceDir returns the workspace override for tool calls: empty for
+// agents sharing the gateway workspace, so tools keep their configured dir.
+func agentWorkspaceDir(rt *agentRuntime) string {
+	if !rt.ownWorkspace {
+		return ""
+	}
+	return rt.workspace
+}
+
+// agentSessionKey namespaces a session key for named agents so each persona
+// keeps its own history. The main agent keeps the historical keys.
+func agentSessionKey(agentID, key string) string {
+	if agentID == "" || agentID == agents.MainAgentID {
+		return key
+	}
+	return "agent:" + agentID + ":" + key
+}
+
+// channelServesAgent reports whether a registered channel may carry messages
+// for an agent. Channel names take the form "telegram" or "telegram:<bot>".
+func (g *Gateway) channelServesAgent(channelName, agentID string) bool {
+	if len(g.config.Agents.List) == 0 {
+		return true
+	}
+	channel, bot := splitChannelName(channelName)
+	return g.config.Agents.ServesChannel(channel, bot, agentID)
+}
+
+// splitChannelName splits "telegram:work" into ("telegram", "work").
+func splitChannelName(name string) (channel, bot string) {
+	channel, bot, _ = strings.Cut(name, ":")
+	return channel, bot
+}
+
+// allowsTool reports whether the agent's tool allowlist permits a tool
+func (a *agentRuntime) allowsTool(name string) bool {
+	return a.tools == nil || a.tools[name]
+}
+
+// filterToolsForAgent applies an agent's tool allowlist on top of role filtering.
+func filterToolsForAgent(defs []tools.ToolDefinition, rt *agentRuntime) []tools.ToolDefinition {
+	if rt.tools == nil {
+		return defs
+	}
+	filtered := make([]tools.ToolDefinition, 0, len(defs))
+	for _, def := range defs {
+		if rt.allowsTool(def.Name) {
+			filtered = append(filtered, def)
+		}
+	}
+	return filtered
+}
+
+// skillsPromptFor returns the skills section for a user, limited to the agent's skills.
+func (g *Gateway) skillsPromptFor(rt *agentRuntime, u *user.User, hasSkillsTool bool) string {
+	if rt.skills == nil {
+		return g.GetSkillsPromptForUser(u, hasSkillsTool)
+	}
+	if g.skillManager == nil {
+		return ""
+	}
+	var allowed []*skills.Skill
+	for _, s := range g.skillManager.GetEligibleSkills(u, g.users.GetRolesConfig()) {
+		if rt.skills[s.Name] {
+			allowed = append(allowed, s)
+		}
+	}
+	return skills.FormatSkillsPrompt(allowed, hasSkillsTool)
+}
+
+// providerFor returns the primary LLM provider for an agent.
+func (g *Gateway) providerFor(rt *agentRuntime) llm.Provider {
+	if rt.llmPurpose == "agent" {
+		return g.llm
+	}
+	p, err := g.registry.GetProvider(rt.llmPurpose)
+	if err != nil {
+		L_warn("agents: no provider for agent, using main", "agent", rt.id, "error", err)
+		return g.llm
+	}
+	return p
+}
+
+// PrimarySessionKeyFor returns the owner's session key for an agent.
+func PrimarySessionKeyFor(agentID string) string {
+	return agentSessionKey(agentID, session.PrimarySession)
+}
+
+// toSet converts a list to a lookup set; nil/empty input returns nil (no restriction).
+func toSet(items []string) map[string]bool {
+	if len(items) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
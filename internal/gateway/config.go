@@ -12,6 +12,7 @@ import (
 // Re-export types from gateway/types for convenience
 type (
 	GatewayConfig       = gwtypes.GatewayConfig
+	RunQueueConfig      = gwtypes.RunQueueConfig
 	PromptCacheConfig   = gwtypes.PromptCacheConfig
 	AgentIdentityConfig = gwtypes.AgentIdentityConfig
 	SupervisionConfig   = gwtypes.SupervisionConfig
@@ -36,6 +37,20 @@ func ConfigFormDef() forms.FormDef {
 					{Name: "Gateway.WorkingDir", Title: "Working Directory", Type: forms.Text, Desc: "Working directory for sessions"},
 				},
 			},
+			{
+				Title:     "Run Queue",
+				Collapsed: true,
+				Fields: []forms.Field{
+					{Name: "Gateway.RunQueue.Mode", Title: "Mode", Type: forms.Select, Default: "queue", Desc: "Messages arriving during a run: queue after it, steer into it, or coalesce rapid-fire messages",
+						Options: []forms.Option{
+							{Label: "Queue", Value: "queue"},
+							{Label: "Steer", Value: "steer"},
+							{Label: "Coalesce", Value: "coalesce"},
+						}},
+					{Name: "Gateway.RunQueue.CoalesceSeconds", Title: "Coalesce Window (seconds)", Type: forms.Number, Default: 2, Desc: "Merge messages sent within this many seconds (coalesce mode)"},
+					{Name: "Gateway.RunQueue.MaxDepth", Title: "Max Queue Depth", Type: forms.Number, Default: 20, Desc: "Waiting runs per session before new ones are rejected"},
+				},
+			},
 			{
 				Title: "Agent Identity",
 				Fields: []forms.Field{
@@ -54,6 +54,24 @@ type EventAgentEnd struct {
 
 func (EventAgentEnd) agentEvent() {}
 
+// EventAgentMerged is emitted instead of a run when the message was steered
+// into, or coalesced with, another run on the same session. The reply is
+// delivered through that run.
+type EventAgentMerged struct {
+	SessionKey string `json:"sessionKey"`
+	Mode       string `json:"mode"` // "steer" or "coalesce"
+}
+
+func (EventAgentMerged) agentEvent() {}
+
+// Notice is the short line channels show the sender in place of a reply
+func (e EventAgentMerged) Notice() string {
+	if e.Mode == RunQueueModeCoalesce {
+		return "Combined with your previous message; one reply will follow."
+	}
+	return "Added to the reply already in progress."
+}
+
 // EventAgentError is emitted when an agent run fails
 type EventAgentError struct {
 	RunID string `json:"runId"`
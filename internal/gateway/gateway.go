@@ -14,6 +14,9 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/roelfdiedericks/goclaw/internal/agents"
+	"github.com/roelfdiedericks/goclaw/internal/audit"
+	"github.com/roelfdiedericks/goclaw/internal/bus"
 	"github.com/roelfdiedericks/goclaw/internal/commands"
 	"github.com/roelfdiedericks/goclaw/internal/config"
 	gcontext "github.com/roelfdiedericks/goclaw/internal/context"
@@ -28,6 +31,7 @@ import (
 	"github.com/roelfdiedericks/goclaw/internal/memorygraph"
 	"github.com/roelfdiedericks/goclaw/internal/metrics"
 	"github.com/roelfdiedericks/goclaw/internal/paths"
+	"github.com/roelfdiedericks/goclaw/internal/retrieval"
 	"github.com/roelfdiedericks/goclaw/internal/sandbox"
 	"github.com/roelfdiedericks/goclaw/internal/security"
 	"github.com/roelfdiedericks/goclaw/internal/session"
@@ -35,6 +39,7 @@ import (
 	"github.com/roelfdiedericks/goclaw/internal/stt"
 	"github.com/roelfdiedericks/goclaw/internal/tokens"
 	"github.com/roelfdiedericks/goclaw/internal/tools"
+	"github.com/roelfdiedericks/goclaw/internal/transcript"
 	"github.com/roelfdiedericks/goclaw/internal/types"
 	"github.com/roelfdiedericks/goclaw/internal/user"
 )
@@ -80,11 +85,15 @@ type Gateway struct {
 	mediaStore          *media.MediaStore
 	memoryManager       *memory.Manager
 	memoryGraphManager  *memorygraph.Manager
+	retriever           *retrieval.Retriever // Pre-turn context retrieval
+	transcripts         *transcript.Manager  // Transcript index (set after startup)
 	commandHandler      *commands.Handler
 	skillManager        *skills.Manager
 	cronService         *cron.Service
-	hassManager         *hass.Manager // Home Assistant event subscription manager
-	lastOpenClawUserMsg string        // Track user messages for mirroring
+	hassManager         *hass.Manager            // Home Assistant event subscription manager
+	agents              map[string]*agentRuntime // Agent personas keyed by ID ("main" always present)
+	runQueue            *runQueue                // Serializes agent runs per session
+	lastOpenClawUserMsg string                   // Track user messages for mirroring
 }
 
 // providerStateAccessor implements llm.ProviderStateAccessor using session store.
@@ -130,8 +139,15 @@ func New(cfg *config.Config, users *user.Registry, registry *llm.Registry, tools
 		channels:  make(map[string]Channel),
 		config:    cfg,
 		startTime: time.Now(),
+		runQueue:  newRunQueue(cfg.Gateway.RunQueue),
 	}
 
+	bus.SubscribeEvent(configPath+".config.applied", func(e bus.Event) {
+		if bundle, ok := e.Data.(*GatewayConfigBundle); ok {
+			g.runQueue.setConfig(bundle.Gateway.RunQueue)
+		}
+	})
+
 	// Determine store type
 	storeType := cfg.Session.Store
 	if storeType == "" {
@@ -323,6 +339,8 @@ func New(cfg *config.Config, users *user.Registry, registry *llm.Registry, tools
 		}
 	}
 
+	g.initRetriever()
+
 	// Initialize prompt cache
 	promptCache, err := gcontext.NewPromptCache(cfg.Gateway.WorkingDir, cfg.PromptCache.PollInterval)
 	if err != nil {
@@ -441,6 +459,11 @@ func New(cfg *config.Config, users *user.Registry, registry *llm.Registry, tools
 		L_info("skills: disabled by configuration")
 	}
 
+	// Initialize agent personas (after prompt cache and memory, which main shares)
+	if err := g.initAgents(); err != nil {
+		return nil, fmt.Errorf("failed to initialize agents: %w", err)
+	}
+
 	return g, nil
 }
 
@@ -1005,6 +1028,12 @@ func (g *Gateway) StartCron(ctx context.Context) error {
 	// Set up channel provider for delivery
 	g.cronService.SetChannelProvider(&gatewayCronChannelProvider{g: g})
 
+	// Event triggers: Home Assistant state changes and workspace file changes
+	g.cronService.SetWorkspaceResolver(g.AgentWorkspace)
+	if g.hassManager != nil {
+		g.cronService.SetHassManager(g.hassManager)
+	}
+
 	// Set job timeout if configured
 	if g.config.Cron.JobTimeoutMinutes > 0 {
 		g.cronService.SetJobTimeout(g.config.Cron.JobTimeoutMinutes)
@@ -1021,6 +1050,21 @@ func (g *Gateway) StartCron(ctx context.Context) error {
 		g.cronService.SetHeartbeatConfig(heartbeatCfg)
 	}
 
+	// Named agents may run their own heartbeat against their own workspace
+	for _, id := range g.config.Agents.IDs() {
+		agentCfg, ok := g.config.Agents.List[id]
+		if !ok || agentCfg.Heartbeat == nil || !agentCfg.Heartbeat.Enabled {
+			continue
+		}
+		g.cronService.AddAgentHeartbeat(&cron.HeartbeatState{
+			Enabled:         true,
+			IntervalMinutes: agentCfg.Heartbeat.IntervalMinutes,
+			Prompt:          agentCfg.Heartbeat.Prompt,
+			WorkspaceDir:    g.AgentWorkspace(id),
+			AgentID:         id,
+		})
+	}
+
 	if err := g.cronService.Start(ctx); err != nil {
 		g.cronService = nil
 		return err
@@ -1042,6 +1086,19 @@ func (p *gatewayCronChannelProvider) Channels() map[string]cron.Channel {
 	return result
 }
 
+func (p *gatewayCronChannelProvider) ChannelsForAgent(agentID string) map[string]cron.Channel {
+	if agentID == "" {
+		agentID = agents.MainAgentID
+	}
+	result := make(map[string]cron.Channel)
+	for name, ch := range p.g.channels {
+		if p.g.channelServesAgent(name, agentID) {
+			result[name] = &cronChannelAdapter{ch: ch}
+		}
+	}
+	return result
+}
+
 // cronChannelAdapter wraps a gateway.Channel as a cron.Channel.
 type cronChannelAdapter struct {
 	ch Channel
@@ -1089,7 +1146,14 @@ func (g *Gateway) RunAgentForCron(ctx context.Context, cronReq cron.AgentRequest
 			jobDesc = cronReq.Source
 		}
 		statusMsg := fmt.Sprintf("💭 Running cron: %s...", jobDesc)
-		for _, ch := range g.channels {
+		agentID := cronReq.AgentID
+		if agentID == "" {
+			agentID = agents.MainAgentID
+		}
+		for name, ch := range g.channels {
+			if !g.channelServesAgent(name, agentID) {
+				continue
+			}
 			ch.Send(ctx, statusMsg) //nolint:errcheck // fire-and-forget status broadcast
 		}
 	}
@@ -1097,6 +1161,7 @@ func (g *Gateway) RunAgentForCron(ctx context.Context, cronReq cron.AgentRequest
 	// Convert cron request to gateway request
 	req := AgentRequest{
 		Source:         cronReq.Source,
+		AgentID:        cronReq.AgentID,
 		Purpose:        cronReq.Purpose,
 		UserMsg:        cronReq.UserMsg,
 		SessionID:      cronReq.SessionID,
@@ -1161,18 +1226,18 @@ func (g *Gateway) InjectSystemEvent(ctx context.Context, text string) error {
 //   - Text empty + RunAgent=true: process existing session (supervision case)
 //   - Text empty + RunAgent=false: nothing to do
 func (g *Gateway) ProcessMessage(ctx context.Context, msg *types.InboundMessage, events chan<- AgentEvent) (*types.DeliveryReport, error) {
-	// Validate AgentID (only "main" or empty supported for now)
-	if msg.AgentID != "" && msg.AgentID != "main" {
-		return nil, fmt.Errorf("unsupported agent ID: %s (only 'main' supported)", msg.AgentID)
+	// Validate AgentID ("main" or a configured named agent)
+	if msg.AgentID != "" && !g.HasAgent(msg.AgentID) {
+		return nil, fmt.Errorf("unknown agent ID: %s", msg.AgentID)
 	}
 
 	// Resolve session key
 	sessionKey := msg.SessionKey
 	if sessionKey == "" {
 		if msg.User != nil {
-			sessionKey = "user:" + msg.User.ID
+			sessionKey = agentSessionKey(msg.AgentID, "user:"+msg.User.ID)
 		} else {
-			sessionKey = session.PrimarySession
+			sessionKey = agentSessionKey(msg.AgentID, session.PrimarySession)
 		}
 	}
 
@@ -1216,6 +1281,7 @@ func (g *Gateway) ProcessMessage(ctx context.Context, msg *types.InboundMessage,
 	req := AgentRequest{
 		User:           msg.User,
 		Source:         msg.Source,
+		AgentID:        msg.AgentID,
 		Purpose:        msg.Purpose,
 		UserMsg:        msg.Text,
 		SessionID:      sessionKey,
@@ -1387,6 +1453,9 @@ func (g *Gateway) Shutdown() {
 		g.compactor.Stop()
 	}
 
+	// Close named agent prompt caches and memory managers
+	g.closeAgents()
+
 	if g.promptCache != nil {
 		g.promptCache.Close()
 	}
@@ -1406,6 +1475,10 @@ func (g *Gateway) Shutdown() {
 		g.memoryGraphManager.Close() //nolint:errcheck // shutdown cleanup
 	}
 
+	if g.retriever != nil {
+		g.retriever.Close()
+	}
+
 	if g.sessions != nil {
 		g.sessions.Close() //nolint:errcheck // shutdown cleanup
 	}
@@ -1450,6 +1523,15 @@ func (g *Gateway) filterToolsForUser(u *user.User) []tools.ToolDefinition {
 		return allDefs
 	}
 
+	// Tools that restrict roles themselves (e.g. workspace script tools)
+	restricted := make([]tools.ToolDefinition, 0, len(allDefs))
+	for _, def := range allDefs {
+		if g.tools.AllowedForRole(def.Name, string(u.Role)) {
+			restricted = append(restricted, def)
+		}
+	}
+	allDefs = restricted
+
 	// AllTools = no filtering needed
 	if resolvedRole.AllTools {
 		return allDefs
@@ -1556,8 +1638,38 @@ func (g *Gateway) CanUserUseCommands(u *user.User) bool {
 	return resolvedRole.CanUseCommands()
 }
 
-// RunAgent executes an agent turn, streaming events to the channel
+// RunAgent executes an agent turn, streaming events to the channel.
+// Runs on the same session are serialized by the run queue: a message arriving
+// mid-run waits, is steered into the run, or is coalesced, depending on
+// gateway.runQueue.mode.
 func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<- AgentEvent) error {
+	if req.User == nil {
+		return g.runAgent(ctx, req, events) // rejected there
+	}
+
+	req.AgentID = g.agentFor(req).id
+	sessionKey := g.sessionKeyFor(req)
+
+	run, merged, err := g.runQueue.acquire(ctx, sessionKey, req)
+	if err != nil {
+		L_warn("gateway: run not started", "session", sessionKey, "source", req.Source, "error", err)
+		events <- EventAgentError{Error: err.Error()}
+		close(events)
+		return err
+	}
+	if merged {
+		// Delivered through another run; the reply arrives there
+		events <- EventAgentMerged{SessionKey: sessionKey, Mode: g.runQueue.status(sessionKey).Mode}
+		close(events)
+		return nil
+	}
+	defer g.runQueue.release(sessionKey, run)
+
+	return g.runAgent(ctx, g.runQueue.request(run), events)
+}
+
+// runAgent executes an agent turn while holding the session's run queue slot.
+func (g *Gateway) runAgent(ctx context.Context, req AgentRequest, events chan<- AgentEvent) error {
 	defer close(events)
 
 	// Validate request
@@ -1566,6 +1678,11 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 		return fmt.Errorf("no authenticated user")
 	}
 
+	// Resolve agent persona (explicit AgentID or routing rules)
+	agent := g.agentFor(req)
+	req.AgentID = agent.id
+	agentLLM := g.providerFor(agent)
+
 	runID := uuid.New().String()
 	runStart := time.Now()
 	sessionKey := g.sessionKeyFor(req)
@@ -1575,7 +1692,7 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 	if req.FreshContext {
 		sess = g.sessions.GetFresh(sessionKey)
 	} else {
-		sess = g.sessions.Get(sessionKey)
+		sess = g.sessions.GetOrLoad(sessionKey)
 	}
 
 	// Set user on session so CancelAllForUser can find it for emergency stop
@@ -1613,8 +1730,8 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 	})
 
 	// Ensure session has the model's context window size set
-	if sess.GetMaxTokens() == 0 && g.llm != nil {
-		sess.SetMaxTokens(g.llm.ContextTokens())
+	if sess.GetMaxTokens() == 0 && agentLLM != nil {
+		sess.SetMaxTokens(agentLLM.ContextTokens())
 	}
 
 	// For heartbeat: snapshot message count so we can rollback after (ephemeral)
@@ -1648,13 +1765,13 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 
 	// Build system prompt
 	var workspaceFiles []gcontext.WorkspaceFile
-	if g.promptCache != nil {
-		workspaceFiles = g.promptCache.GetWorkspaceFiles()
+	if agent.promptCache != nil {
+		workspaceFiles = agent.promptCache.GetWorkspaceFiles()
 	}
 
-	// Get skills prompt (filtered by user's role, with skills tool awareness)
+	// Get skills prompt (filtered by user's role and agent, with skills tool awareness)
 	hasSkillsTool := sess.HasToolAccess("skills")
-	skillsPrompt := g.GetSkillsPromptForUser(req.User, hasSkillsTool)
+	skillsPrompt := g.skillsPromptFor(agent, req.User, hasSkillsTool)
 
 	// Determine memory access and role prompts from resolved role (reuse session's cached role)
 	includeMemory := true
@@ -1666,9 +1783,9 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 	}
 
 	systemPrompt := gcontext.BuildSystemPrompt(gcontext.PromptParams{
-		WorkspaceDir:         g.config.Gateway.WorkingDir,
+		WorkspaceDir:         agent.workspace,
 		Tools:                g.tools,
-		Model:                g.llm.Model(),
+		Model:                agentLLM.Model(),
 		Channel:              req.Source,
 		User:                 req.User,
 		TotalTokens:          sess.GetTotalTokens(),
@@ -1701,6 +1818,14 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 		return nil
 	}
 
+	// Retrieve related memories for the latest message (only for roles with memory access)
+	var retrieved, retrievedFor string
+	if includeMemory {
+		if retrieved = g.retrievedContext(ctx, req, agent, sess, runID, sessionKey); retrieved != "" {
+			retrievedFor = lastUserMessageID(sess.GetMessages())
+		}
+	}
+
 	// Consume pending guidance and inject as system messages
 	if supervision := sess.GetSupervision(); supervision != nil && supervision.HasPendingGuidance() {
 		guidance := supervision.ConsumePendingGuidance()
@@ -1770,6 +1895,13 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 	if purpose == "" {
 		purpose = "agent"
 	}
+	auditPurpose := audit.DescribePurpose(purpose, req.Source, req.UserMsg)
+
+	// Named agents with their own model chain use a scoped LLM purpose
+	llmPurpose := purpose
+	if purpose == "agent" {
+		llmPurpose = agent.llmPurpose
+	}
 
 	// Agent loop - keep going until no more tool use
 	for {
@@ -1789,10 +1921,28 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 			sendEvent(EventAgentEnd{RunID: runID, FinalText: ""})
 			return nil
 		}
+
+		// Steer mode: messages that arrived mid-run join the conversation here
+		for _, steered := range g.runQueue.takeSteered(sessionKey) {
+			var msgID string
+			if len(steered.ContentBlocks) > 0 {
+				msgID = sess.AddUserMessageWithContent(steered.UserMsg, steered.Source, steered.ContentBlocks)
+			} else {
+				msgID = sess.AddUserMessage(steered.UserMsg, steered.Source)
+			}
+			if supervision := sess.GetSupervision(); supervision != nil {
+				supervision.SendEvent(EventUserMessage{Content: steered.UserMsg, Source: steered.Source})
+			}
+			if !req.IsHeartbeat {
+				g.persistMessage(ctx, msgID, sessionKey, userID, "user", steered.UserMsg, steered.Source, "", "", nil, "", "", "", "")
+			}
+			L_info("runqueue: message steered into run", "session", sessionKey, "runID", runID, "source", steered.Source, "msgLen", len(steered.UserMsg))
+		}
 		// Build context from session (messages and tool definitions)
 		messages := sess.GetMessages()
 		toolDefs := g.filterToolsForUser(req.User)
 		toolDefs = g.filterToolsForPurpose(toolDefs, purpose)
+		toolDefs = filterToolsForAgent(toolDefs, agent)
 
 		// Pre-flight check: estimate if we're approaching context limit
 		estimatedTokens := sess.GetTotalTokens()
@@ -1824,7 +1974,7 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 		contextWindow := sess.GetMaxTokens()
 		contextUsage := sess.GetContextUsage() * 100.0
 		// Resolve thinking level using priority hierarchy
-		thinkingLevel := g.resolveThinkingLevel(req, g.llm.Name())
+		thinkingLevel := g.resolveThinkingLevel(req, agentLLM.Name())
 		enableThinking := req.EnableThinking || thinkingLevel.IsEnabled()
 
 		// Show user message preview in green for easy spotting
@@ -1849,11 +1999,12 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 			L_info("user", "msg", "\033[1;30;102m "+preview+" \033[0m")
 		}
 
-		estimatedInputCost := llm.EstimateInputCost(g.llm.MetadataProvider(), g.llm.Model(), contextTokens)
+		estimatedInputCost := llm.EstimateInputCost(agentLLM.MetadataProvider(), agentLLM.Model(), contextTokens)
 		systemPromptTokens := tokens.Estimate(systemPrompt)
 		L_debug("invoking LLM",
-			"provider", g.llm.Name(),
-			"model", g.llm.Model(),
+			"agent", agent.id,
+			"provider", agentLLM.Name(),
+			"model", agentLLM.Model(),
 			"messages", len(messages),
 			"tools", len(toolDefs),
 			"systemPromptTokens", systemPromptTokens,
@@ -1895,7 +2046,8 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 		}
 
 		// Resolve media content (FilePath -> base64 Data) before sending to LLM
-		resolvedMessages := g.resolveMediaContent(messages, g.llm)
+		resolvedMessages := g.resolveMediaContent(messages, agentLLM)
+		resolvedMessages = injectRetrievedContext(resolvedMessages, retrievedFor, retrieved)
 
 		// Inject timestamp into last user message (ephemeral — not stored in session or SQLite)
 		if g.config.PromptCache.GetTimeInUserMessage() {
@@ -1908,7 +2060,7 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 		for retry := 0; retry <= maxOverflowRetries; retry++ {
 			failoverResult, llmErr = g.registry.StreamMessageWithFailover(
 				agentCtx,
-				purpose,
+				llmPurpose,
 				stateAccessor,
 				resolvedMessages,
 				toolDefs,
@@ -1942,7 +2094,8 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 
 					// Refresh messages after compaction
 					messages = sess.GetMessages()
-					resolvedMessages = g.resolveMediaContent(messages, g.llm)
+					resolvedMessages = g.resolveMediaContent(messages, agentLLM)
+					resolvedMessages = injectRetrievedContext(resolvedMessages, retrievedFor, retrieved)
 					L_info("recovery compaction completed, retrying API call",
 						"newTokens", sess.GetTotalTokens(),
 						"newMessages", len(messages))
@@ -2009,7 +2162,7 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 		// Handle tool use
 		if response.HasToolUse() {
 			// Check permissions
-			if !req.User.CanUseTool(response.ToolName) {
+			if !req.User.CanUseTool(response.ToolName) || !g.tools.AllowedForRole(response.ToolName, string(req.User.Role)) {
 				result := fmt.Sprintf("Permission denied: %s cannot use tool %s", req.User.Name, response.ToolName)
 			sendEvent(EventToolEnd{
 				RunID:    runID,
@@ -2047,6 +2200,26 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 				continue
 			}
 
+			// Runtime safety net: deny tools outside the agent's allowlist
+			if !agent.allowsTool(response.ToolName) {
+				L_warn("gateway: tool denied for agent", "tool", response.ToolName, "agent", agent.id)
+				result := fmt.Sprintf("Permission denied: tool %s is not available to agent %q", response.ToolName, agent.id)
+				sendEvent(EventToolEnd{
+					RunID:    runID,
+					ToolName: response.ToolName,
+					ToolID:   response.ToolUseID,
+					Result:   result,
+					Error:    "agent_denied",
+				})
+				toolUseID := sess.AddToolUse(response.ToolUseID, response.ToolName, response.ToolInput, response.Thinking)
+				toolResultID := sess.AddToolResult(response.ToolUseID, result, nil)
+				if !req.IsHeartbeat {
+					g.persistMessage(ctx, toolUseID, sessionKey, userID, "tool_use", "", req.Source, response.ToolUseID, response.ToolName, response.ToolInput, "", response.Thinking, "", "")
+					g.persistMessage(ctx, toolResultID, sessionKey, userID, "tool_result", result, req.Source, response.ToolUseID, "", nil, "", "", "", "")
+				}
+				continue
+			}
+
 			sendEvent(EventToolStart{
 				RunID:    runID,
 				ToolName: response.ToolName,
@@ -2065,13 +2238,23 @@ func (g *Gateway) RunAgent(ctx context.Context, req AgentRequest, events chan<-
 			if resolvedRole, err := g.users.ResolveUserRole(req.User); err == nil {
 				transcriptScope = resolvedRole.GetTranscriptScope()
 			}
+			toolChannel := req.Source
+			if req.Bot != "" {
+				toolChannel = req.Source + ":" + req.Bot // Named bot, e.g. "telegram:work"
+			}
 			toolCtx := tools.WithSessionContext(agentCtx, &tools.SessionContext{
-				Channel:         req.Source,
+				Channel:         toolChannel,
 				ChatID:          req.ChatID,
 				OwnerChatID:     ownerChatID,
 				User:            req.User,
 				TranscriptScope: transcriptScope,
 				Session:         sess,
+				AgentID:         agent.id,
+				WorkspaceDir:    agentWorkspaceDir(agent),
+				SessionKey:      sessionKey,
+				RunID:           runID,
+				ToolCallID:      response.ToolUseID,
+				Purpose:         auditPurpose,
 			})
 			toolResult, err := g.tools.Execute(toolCtx, response.ToolName, response.ToolInput)
 			toolDuration := time.Since(toolStartTime)
@@ -2321,6 +2504,7 @@ func (g *Gateway) GetSessionInfoForCommands(ctx context.Context, sessionKey stri
 	if err != nil {
 		return nil, err
 	}
+	queue := g.runQueue.status(sessionKey)
 	return &commands.SessionInfo{
 		SessionKey:      info.SessionKey,
 		Messages:        info.Messages,
@@ -2329,6 +2513,9 @@ func (g *Gateway) GetSessionInfoForCommands(ctx context.Context, sessionKey stri
 		UsagePercent:    info.UsagePercent,
 		CompactionCount: info.CompactionCount,
 		LastCompaction:  info.LastCompaction,
+		QueueMode:       queue.Mode,
+		QueueState:      queue.String(),
+		QueueDepth:      queue.Waiting,
 	}, nil
 }
 
@@ -2546,15 +2733,15 @@ func (g *Gateway) sessionKeyFor(req AgentRequest) string {
 		return req.SessionID
 	}
 	if req.IsGroup {
-		return fmt.Sprintf("group:%s", req.ChatID)
+		return agentSessionKey(req.AgentID, fmt.Sprintf("group:%s", req.ChatID))
 	}
-	// Owner uses "primary" session (shared across all channels)
+	// Owner uses "primary" session (shared across all channels of the agent)
 	if req.User != nil && req.User.IsOwner() {
-		return session.PrimarySession
+		return agentSessionKey(req.AgentID, session.PrimarySession)
 	}
 	// Non-owner users get their own session keyed by username
 	if req.User != nil {
-		return fmt.Sprintf("user:%s", req.User.ID)
+		return agentSessionKey(req.AgentID, fmt.Sprintf("user:%s", req.User.ID))
 	}
 	// Fallback (shouldn't happen - requests without user should be rejected earlier)
 	return session.PrimarySession
@@ -2575,6 +2762,10 @@ func (g *Gateway) mirrorToOthers(ctx context.Context, req AgentRequest, response
 			continue // skip channels user isn't connected to
 		}
 
+		if !g.channelServesAgent(name, req.AgentID) {
+			continue // don't leak one agent's conversation into another's channel
+		}
+
 		L_debug("mirror: sending", "from", req.Source, "to", name)
 		ch.SendMirror(ctx, req.Source, req.UserMsg, response) //nolint:errcheck // fire-and-forget mirror
 	}
@@ -2602,8 +2793,7 @@ func (g *Gateway) InjectMessage(ctx context.Context, sessionKey, message string,
 	if sessionKey == session.PrimarySession {
 		u = g.users.Owner()
 	} else if strings.HasPrefix(sessionKey, "user:") {
-		userID := strings.TrimPrefix(sessionKey, "user:")
-		u = g.users.Get(userID)
+		u = g.users.Get(session.KeyUserID(sessionKey)) // also "user:<id>:web:<name>"
 	} else {
 		u = g.users.Owner()
 	}
@@ -2634,6 +2824,22 @@ func (g *Gateway) InjectMessage(ctx context.Context, sessionKey, message string,
 		"supervisor", supervisorName,
 		"messageLen", len(message))
 
+	intervention := "ghostwrite"
+	if invokeLLM {
+		intervention = "guidance"
+	}
+	supervisorID := ""
+	if supervisor != nil {
+		supervisorID = supervisor.ID
+	}
+	audit.Record(ctx, audit.Event{
+		Type:    "supervision." + intervention,
+		Actor:   supervisorID,
+		Session: sessionKey,
+		Purpose: "supervision of " + u.ID,
+		Inputs:  map[string]any{"message": message},
+	})
+
 	if invokeLLM {
 		// GUIDANCE: Add message to session, run agent ONCE, fan out to channels
 
@@ -2778,6 +2984,24 @@ func (g *Gateway) ResetSession(sessionID string) error {
 	return nil
 }
 
+// DeleteSession deletes a session: its messages, compactions and
+// transcript index entries. The primary session can't be deleted.
+func (g *Gateway) DeleteSession(ctx context.Context, sessionKey string) error {
+	if sessionKey == session.PrimarySession {
+		return fmt.Errorf("the primary session can't be deleted")
+	}
+	if err := g.sessions.DeleteSession(ctx, sessionKey); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	if g.transcripts != nil {
+		if err := g.transcripts.DeleteSession(ctx, sessionKey); err != nil {
+			L_warn("gateway: failed to delete session transcripts", "session", sessionKey, "error", err)
+		}
+	}
+	L_info("gateway: session deleted", "session", sessionKey)
+	return nil
+}
+
 // StopAllUserSessions cancels all running agent sessions for a user.
 func (g *Gateway) StopAllUserSessions(userID string) (int, error) {
 	cancelled := g.sessions.CancelAllForUser(userID)
@@ -2835,7 +3059,7 @@ func (g *Gateway) RunAgentForSession(ctx context.Context, sessionKey string, eve
 		L_debug("RunAgentForSession: using owner for primary session", "session", sessionKey)
 	} else if strings.HasPrefix(sessionKey, "user:") {
 		// User session - extract user ID and look up
-		userID := strings.TrimPrefix(sessionKey, "user:")
+		userID := session.KeyUserID(sessionKey)
 		reqUser = g.users.Get(userID)
 		L_debug("RunAgentForSession: looked up user", "session", sessionKey, "userID", userID, "found", reqUser != nil)
 	} else if strings.HasPrefix(sessionKey, "group:") {
@@ -18,6 +18,10 @@ type AgentRequest struct {
 	ContentBlocks []types.ContentBlock // content blocks (images, audio, etc.)
 	OnMediaToSend MediaCallback        // optional callback for sending media to channel
 
+	// Agent routing
+	AgentID string // Agent persona to run (empty = resolve via agents.routes)
+	Bot     string // Named bot within the channel (e.g. second Telegram bot), used for routing
+
 	// LLM purpose routing
 	Purpose string // LLM purpose (e.g., "heartbeat", "cron", "hass"). Empty = "agent"
 
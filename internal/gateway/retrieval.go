@@ -0,0 +1,104 @@
+package gateway
+
+import (
+	"context"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/retrieval"
+	"github.com/roelfdiedericks/goclaw/internal/session"
+	"github.com/roelfdiedericks/goclaw/internal/transcript"
+	"github.com/roelfdiedericks/goclaw/internal/types"
+)
+
+// initRetriever creates the pre-turn retriever over the managers the gateway
+// owns. The transcript manager is added later via SetTranscriptManager.
+func (g *Gateway) initRetriever() {
+	g.retriever = retrieval.New(g.config.Retrieval)
+	if g.memoryGraphManager != nil {
+		g.retriever.SetMemoryGraph(g.memoryGraphManager)
+	}
+	if db := g.SessionDB(); db != nil {
+		if log, err := retrieval.NewInjectionLog(db); err != nil {
+			L_warn("retrieval: injection log disabled", "error", err)
+		} else {
+			g.retriever.SetInjectionLog(log)
+		}
+	}
+}
+
+// SetTranscriptManager makes past transcripts available to pre-turn retrieval
+// and session search
+func (g *Gateway) SetTranscriptManager(m *transcript.Manager) {
+	g.transcripts = m
+	if g.retriever != nil {
+		g.retriever.SetTranscripts(m)
+	}
+}
+
+// TranscriptManager returns the transcript index (nil if disabled)
+func (g *Gateway) TranscriptManager() *transcript.Manager {
+	return g.transcripts
+}
+
+// retrievedContext runs pre-turn retrieval for the latest user message and
+// returns the context block to inject with it ("" if none).
+// Injected items are recorded against the run.
+func (g *Gateway) retrievedContext(ctx context.Context, req AgentRequest, agent *agentRuntime, sess *session.Session, runID, sessionKey string) string {
+	if g.retriever == nil || !g.retriever.Enabled() || req.IsHeartbeat || req.UserMsg == "" {
+		return ""
+	}
+
+	scope := "own"
+	if sess.ResolvedRole != nil {
+		scope = sess.ResolvedRole.GetTranscriptScope()
+	}
+	items := g.retriever.Retrieve(ctx, retrieval.Request{
+		Query:           req.UserMsg,
+		UserID:          req.User.ID,
+		IsOwner:         req.User.IsOwner(),
+		TranscriptScope: scope,
+		SessionKey:      sessionKey,
+		Memory:          agent.memoryManager,
+	})
+	if len(items) == 0 {
+		return ""
+	}
+
+	if log := g.retriever.InjectionLog(); log != nil {
+		if err := log.Record(ctx, runID, sessionKey, req.User.ID, items); err != nil {
+			L_warn("retrieval: failed to record injections", "runID", runID, "error", err)
+		}
+	}
+	L_info("retrieval: context injected", "session", sessionKey, "items", len(items))
+	return retrieval.Format(items)
+}
+
+// lastUserMessageID returns the ID of the latest user message ("" if none)
+func lastUserMessageID(messages []types.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].ID
+		}
+	}
+	return ""
+}
+
+// injectRetrievedContext prepends the retrieved context block to the user
+// message it was retrieved for. Like the injected timestamp it is ephemeral
+// (not stored in the session or SQLite), and it stays out of the system
+// prompt so the prompt and earlier history remain cacheable across turns.
+func injectRetrievedContext(messages []types.Message, msgID, block string) []types.Message {
+	if block == "" || msgID == "" {
+		return messages
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].ID != msgID {
+			continue
+		}
+		result := make([]types.Message, len(messages))
+		copy(result, messages)
+		result[i].Content = block + "\n\n" + result[i].Content
+		return result
+	}
+	return messages // Compacted away
+}
@@ -0,0 +1,343 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/metrics"
+)
+
+// Run queue modes
+const (
+	RunQueueModeQueue    = "queue"    // wait for the in-flight run, then run
+	RunQueueModeSteer    = "steer"    // inject into the in-flight run at the next tool boundary
+	RunQueueModeCoalesce = "coalesce" // merge messages sent within the coalesce window
+)
+
+const (
+	defaultCoalesceSeconds = 2
+	defaultMaxQueueDepth   = 20
+)
+
+// runQueue serializes agent runs per session key. Only one run per session
+// is in flight; others wait in FIFO order, or are steered/coalesced depending
+// on the configured mode.
+type runQueue struct {
+	mu    sync.Mutex
+	cfg   RunQueueConfig
+	lanes map[string]*sessionLane
+}
+
+// sessionLane tracks the in-flight and waiting runs of one session.
+type sessionLane struct {
+	running *queuedRun   // holds the lane (may still be in its coalesce window)
+	waiting []*queuedRun // FIFO
+	steered []*queuedRun // waiting to be picked up by the running run
+}
+
+// queuedRun is one RunAgent call waiting for, or holding, its session lane.
+type queuedRun struct {
+	req      AgentRequest
+	ready    chan struct{} // closed when the run gets the lane
+	steerAck chan bool     // steer mode: true = injected, false = run normally
+	started  bool          // past the coalesce window, no more merging
+	lastMsg  time.Time     // last message merged in (coalesce)
+	merged   int           // messages merged into this run
+	since    time.Time
+}
+
+// RunQueueStatus describes a session's queue for /status.
+type RunQueueStatus struct {
+	Mode    string
+	State   string // "idle", "collecting" (coalesce window), "running"
+	Running string // source of the in-flight run
+	Since   time.Time
+	Waiting int
+	Steered int // messages waiting for the next tool boundary
+}
+
+func newRunQueue(cfg RunQueueConfig) *runQueue {
+	return &runQueue{cfg: cfg, lanes: make(map[string]*sessionLane)}
+}
+
+// setConfig applies a new config to subsequent runs.
+func (q *runQueue) setConfig(cfg RunQueueConfig) {
+	q.mu.Lock()
+	q.cfg = cfg
+	q.mu.Unlock()
+	L_info("gateway: run queue config applied", "mode", cfg.Mode, "coalesceSeconds", cfg.CoalesceSeconds, "maxDepth", cfg.MaxDepth)
+}
+
+// modeLocked returns the effective mode. Caller holds q.mu.
+func (q *runQueue) modeLocked() string {
+	switch q.cfg.Mode {
+	case RunQueueModeSteer, RunQueueModeCoalesce:
+		return q.cfg.Mode
+	}
+	return RunQueueModeQueue
+}
+
+func (q *runQueue) coalesceWindowLocked() time.Duration {
+	secs := q.cfg.CoalesceSeconds
+	if secs <= 0 {
+		secs = defaultCoalesceSeconds
+	}
+	return time.Duration(secs) * time.Second
+}
+
+func (q *runQueue) maxDepthLocked() int {
+	if q.cfg.MaxDepth > 0 {
+		return q.cfg.MaxDepth
+	}
+	return defaultMaxQueueDepth
+}
+
+// isInteractive returns true for plain user messages that may be steered or
+// coalesced. Heartbeats, cron, HA wake-ups and supervision runs always queue.
+func isInteractive(req *AgentRequest) bool {
+	return !req.IsHeartbeat && !req.FreshContext && !req.SkipAddMessage &&
+		(req.Purpose == "" || req.Purpose == "agent") && req.UserMsg != ""
+}
+
+// acquire waits until the caller may run on the session. It returns the
+// (possibly merged) request to run, or merged=true if the message was handed
+// to another run and the caller has nothing left to do.
+func (q *runQueue) acquire(ctx context.Context, key string, req AgentRequest) (run *queuedRun, merged bool, err error) {
+	now := time.Now()
+
+	q.mu.Lock()
+	lane := q.lanes[key]
+	if lane == nil {
+		lane = &sessionLane{}
+		q.lanes[key] = lane
+	}
+	mode := q.modeLocked()
+	interactive := isInteractive(&req)
+
+	// Coalesce: merge into a run that hasn't started yet, if the last message was recent
+	if mode == RunQueueModeCoalesce && interactive {
+		if target := lane.coalesceTarget(); target != nil && now.Sub(target.lastMsg) < q.coalesceWindowLocked() &&
+			isInteractive(&target.req) && sameSender(&target.req, &req) {
+			target.req.UserMsg += "\n\n" + req.UserMsg
+			target.req.ContentBlocks = append(target.req.ContentBlocks, req.ContentBlocks...)
+			target.lastMsg = now
+			target.merged++
+			q.mu.Unlock()
+			L_debug("runqueue: message coalesced", "session", key, "merged", target.merged)
+			metrics.MetricInc("runqueue", "coalesced")
+			return nil, true, nil
+		}
+	}
+
+	if len(lane.waiting) >= q.maxDepthLocked() {
+		q.mu.Unlock()
+		metrics.MetricInc("runqueue", "rejected")
+		return nil, false, fmt.Errorf("session busy: %d runs already queued", len(lane.waiting))
+	}
+
+	run = &queuedRun{req: req, ready: make(chan struct{}), lastMsg: now, since: now}
+
+	// Steer: hand the message to the in-flight run if it accepts interjections
+	if mode == RunQueueModeSteer && interactive && lane.running != nil && lane.running.started &&
+		isInteractive(&lane.running.req) && sameSender(&lane.running.req, &req) {
+		run.steerAck = make(chan bool, 1)
+		lane.steered = append(lane.steered, run)
+		q.mu.Unlock()
+
+		L_debug("runqueue: message queued for steering", "session", key)
+		select {
+		case injected := <-run.steerAck:
+			if injected {
+				metrics.MetricInc("runqueue", "steered")
+				return nil, true, nil
+			}
+			// Run ended before a tool boundary; release moved us to the front of the queue
+		case <-ctx.Done():
+			q.cancel(key, run)
+			return nil, false, ctx.Err()
+		}
+	} else if lane.running == nil {
+		lane.running = run
+		close(run.ready)
+		q.mu.Unlock()
+	} else {
+		lane.waiting = append(lane.waiting, run)
+		depth := len(lane.waiting)
+		q.mu.Unlock()
+		L_debug("runqueue: run queued", "session", key, "source", req.Source, "depth", depth)
+		metrics.MetricInc("runqueue", "queued")
+	}
+
+	select {
+	case <-run.ready:
+	case <-ctx.Done():
+		q.cancel(key, run)
+		return nil, false, ctx.Err()
+	}
+
+	// Coalesce: hold the lane until the sender has been quiet for the window
+	if mode == RunQueueModeCoalesce && interactive {
+		for {
+			q.mu.Lock()
+			wait := time.Until(run.lastMsg.Add(q.coalesceWindowLocked()))
+			q.mu.Unlock()
+			if wait <= 0 {
+				break
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				q.release(key, run)
+				return nil, false, ctx.Err()
+			}
+		}
+	}
+
+	q.mu.Lock()
+	run.started = true
+	run.since = time.Now()
+	q.mu.Unlock()
+	return run, false, nil
+}
+
+// coalesceTarget returns the newest run that can still take merged messages.
+func (l *sessionLane) coalesceTarget() *queuedRun {
+	if n := len(l.waiting); n > 0 {
+		return l.waiting[n-1]
+	}
+	if l.running != nil && !l.running.started {
+		return l.running
+	}
+	return nil
+}
+
+// sameSender reports whether two requests come from the same user and chat,
+// so messages are never merged across people or conversations.
+func sameSender(a, b *AgentRequest) bool {
+	if a.User == nil || b.User == nil {
+		return false
+	}
+	return a.User.ID == b.User.ID && a.Source == b.Source && a.ChatID == b.ChatID
+}
+
+// request returns a snapshot of the run's request (merges may still mutate it
+// until the run starts).
+func (q *runQueue) request(run *queuedRun) AgentRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return run.req
+}
+
+// takeSteered returns messages steered into the session's in-flight run and
+// acknowledges them. Called by the running agent loop at each tool boundary.
+func (q *runQueue) takeSteered(key string) []AgentRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	lane := q.lanes[key]
+	if lane == nil || len(lane.steered) == 0 {
+		return nil
+	}
+	reqs := make([]AgentRequest, 0, len(lane.steered))
+	for _, run := range lane.steered {
+		reqs = append(reqs, run.req)
+		run.steerAck <- true
+	}
+	lane.steered = nil
+	return reqs
+}
+
+// release hands the lane to the next waiting run. Steered messages the run
+// didn't pick up go first, so nothing is lost.
+func (q *runQueue) release(key string, run *queuedRun) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	lane := q.lanes[key]
+	if lane == nil || lane.running != run {
+		return
+	}
+	lane.running = nil
+
+	if len(lane.steered) > 0 {
+		lane.waiting = append(lane.steered, lane.waiting...)
+		for _, r := range lane.steered {
+			r.steerAck <- false
+		}
+		lane.steered = nil
+	}
+
+	if len(lane.waiting) > 0 {
+		next := lane.waiting[0]
+		lane.waiting = lane.waiting[1:]
+		lane.running = next
+		close(next.ready)
+		return
+	}
+	delete(q.lanes, key)
+}
+
+// cancel removes a run whose caller gave up, releasing the lane if it held it.
+func (q *runQueue) cancel(key string, run *queuedRun) {
+	q.mu.Lock()
+	lane := q.lanes[key]
+	if lane == nil {
+		q.mu.Unlock()
+		return
+	}
+	if lane.running == run {
+		q.mu.Unlock()
+		q.release(key, run)
+		return
+	}
+	lane.waiting = removeRun(lane.waiting, run)
+	lane.steered = removeRun(lane.steered, run)
+	q.mu.Unlock()
+}
+
+func removeRun(runs []*queuedRun, run *queuedRun) []*queuedRun {
+	for i, r := range runs {
+		if r == run {
+			return append(runs[:i:i], runs[i+1:]...)
+		}
+	}
+	return runs
+}
+
+// status returns a session's queue state.
+func (q *runQueue) status(key string) RunQueueStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	st := RunQueueStatus{Mode: q.modeLocked(), State: "idle"}
+	lane := q.lanes[key]
+	if lane == nil || lane.running == nil {
+		return st
+	}
+	st.State = "running"
+	if !lane.running.started {
+		st.State = "collecting"
+	}
+	st.Running = lane.running.req.Source
+	st.Since = lane.running.since
+	st.Waiting = len(lane.waiting)
+	st.Steered = len(lane.steered)
+	return st
+}
+
+// String formats the status for /status output.
+func (s RunQueueStatus) String() string {
+	var b strings.Builder
+	b.WriteString(s.State)
+	if s.State != "idle" {
+		fmt.Fprintf(&b, " (%s, %s)", s.Running, time.Since(s.Since).Round(time.Second))
+	}
+	if s.Waiting > 0 {
+		fmt.Fprintf(&b, ", %d queued", s.Waiting)
+	}
+	if s.Steered > 0 {
+		fmt.Fprintf(&b, ", %d to steer", s.Steered)
+	}
+	return b.String()
+}
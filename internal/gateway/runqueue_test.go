@@ -0,0 +1,159 @@
+package gateway
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/user"
+)
+
+var testOwner = &user.User{ID: "owner", Role: user.RoleOwner}
+
+func msg(text string) AgentRequest {
+	return AgentRequest{User: testOwner, Source: "telegram", ChatID: "1", UserMsg: text}
+}
+
+type acquireResult struct {
+	run    *queuedRun
+	merged bool
+	err    error
+}
+
+func acquireAsync(q *runQueue, req AgentRequest) <-chan acquireResult {
+	ch := make(chan acquireResult, 1)
+	go func() {
+		run, merged, err := q.acquire(context.Background(), "primary", req)
+		ch <- acquireResult{run, merged, err}
+	}()
+	return ch
+}
+
+// waitStatus polls until the queue reaches the expected waiting/steered counts.
+func waitStatus(t *testing.T, q *runQueue, waiting, steered int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		st := q.status("primary")
+		if st.Waiting == waiting && st.Steered == steered {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status = %+v, want waiting=%d steered=%d", st, waiting, steered)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRunQueueSerializes(t *testing.T) {
+	q := newRunQueue(RunQueueConfig{})
+	first, merged, err := q.acquire(context.Background(), "primary", msg("one"))
+	if err != nil || merged {
+		t.Fatalf("first acquire: merged=%v err=%v", merged, err)
+	}
+
+	second := acquireAsync(q, msg("two"))
+	waitStatus(t, q, 1, 0)
+	if st := q.status("primary"); st.State != "running" || st.Mode != RunQueueModeQueue {
+		t.Errorf("status = %+v", st)
+	}
+
+	q.release("primary", first)
+	res := <-second
+	if res.err != nil || res.merged || res.run.req.UserMsg != "two" {
+		t.Fatalf("second run = %+v", res)
+	}
+	q.release("primary", res.run)
+	if st := q.status("primary"); st.State != "idle" {
+		t.Errorf("queue not idle after release: %+v", st)
+	}
+}
+
+func TestRunQueueSteer(t *testing.T) {
+	q := newRunQueue(RunQueueConfig{Mode: RunQueueModeSteer})
+	first, _, _ := q.acquire(context.Background(), "primary", msg("research flights"))
+
+	steered := acquireAsync(q, msg("only direct ones"))
+	waitStatus(t, q, 0, 1)
+
+	// Picked up at the next tool boundary
+	got := q.takeSteered("primary")
+	if len(got) != 1 || got[0].UserMsg != "only direct ones" {
+		t.Fatalf("takeSteered = %+v", got)
+	}
+	if res := <-steered; !res.merged {
+		t.Errorf("steered message should be merged, got %+v", res)
+	}
+
+	// Not picked up before the run ends: runs next instead
+	late := acquireAsync(q, msg("and cheap"))
+	waitStatus(t, q, 0, 1)
+	q.release("primary", first)
+	res := <-late
+	if res.merged || res.run == nil || res.run.req.UserMsg != "and cheap" {
+		t.Fatalf("unclaimed steer should run on its own, got %+v", res)
+	}
+	q.release("primary", res.run)
+
+	// Background runs are never steered into
+	cron, _, _ := q.acquire(context.Background(), "primary", AgentRequest{User: testOwner, Source: "cron", UserMsg: "job", Purpose: "cron"})
+	queued := acquireAsync(q, msg("hello"))
+	waitStatus(t, q, 1, 0)
+	q.release("primary", cron)
+	q.release("primary", (<-queued).run)
+}
+
+func TestRunQueueCoalesce(t *testing.T) {
+	q := newRunQueue(RunQueueConfig{Mode: RunQueueModeCoalesce, CoalesceSeconds: 1})
+
+	first := acquireAsync(q, msg("hi"))
+	time.Sleep(50 * time.Millisecond)
+	_, merged, err := q.acquire(context.Background(), "primary", msg("are you there?"))
+	if err != nil || !merged {
+		t.Fatalf("second message should coalesce: merged=%v err=%v", merged, err)
+	}
+	if st := q.status("primary"); st.State != "collecting" {
+		t.Errorf("state = %q, want collecting", st.State)
+	}
+
+	res := <-first
+	if res.run.req.UserMsg != "hi\n\nare you there?" {
+		t.Errorf("coalesced message = %q", res.run.req.UserMsg)
+	}
+
+	// Another sender is never merged in
+	other := msg("from someone else")
+	other.User = &user.User{ID: "guest"}
+	_, merged, _ = q.acquire(context.Background(), "other", other)
+	if merged {
+		t.Error("first message on a session must not be merged")
+	}
+}
+
+func TestRunQueueMaxDepth(t *testing.T) {
+	q := newRunQueue(RunQueueConfig{MaxDepth: 1})
+	first, _, _ := q.acquire(context.Background(), "primary", msg("one"))
+	second := acquireAsync(q, msg("two"))
+	waitStatus(t, q, 1, 0)
+
+	if _, _, err := q.acquire(context.Background(), "primary", msg("three")); err == nil {
+		t.Error("expected error when queue is full")
+	}
+
+	// A cancelled waiter leaves the queue
+	ctx, cancel := context.WithCancel(context.Background())
+	q.release("primary", first)
+	res := <-second
+	waiter := make(chan error, 1)
+	go func() {
+		_, _, err := q.acquire(ctx, "primary", msg("four"))
+		waiter <- err
+	}()
+	waitStatus(t, q, 1, 0)
+	cancel()
+	if err := <-waiter; err == nil {
+		t.Error("cancelled waiter should return an error")
+	}
+	waitStatus(t, q, 0, 0)
+	q.release("primary", res.run)
+}
@@ -4,9 +4,18 @@ package types
 
 // GatewayConfig contains gateway server settings
 type GatewayConfig struct {
-	LogFile    string `json:"logFile"`
-	PIDFile    string `json:"pidFile"`
-	WorkingDir string `json:"workingDir"`
+	LogFile    string         `json:"logFile"`
+	PIDFile    string         `json:"pidFile"`
+	WorkingDir string         `json:"workingDir"`
+	RunQueue   RunQueueConfig `json:"runQueue"`
+}
+
+// RunQueueConfig controls what happens when a message arrives for a session
+// that already has an agent run in flight
+type RunQueueConfig struct {
+	Mode            string `json:"mode"`            // queue (default), steer or coalesce
+	CoalesceSeconds int    `json:"coalesceSeconds"` // Quiet window for coalesce mode (default: 2)
+	MaxDepth        int    `json:"maxDepth"`        // Max waiting runs per session, 0 = default (20)
 }
 
 // PromptCacheConfig configures system prompt caching and time injection
@@ -0,0 +1,218 @@
+package hass
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Conditions narrow down when a matching state change wakes the agent.
+// All set conditions must hold. A nil *Conditions always passes.
+type Conditions struct {
+	From       []string             `json:"from,omitempty"`        // old state must be one of these
+	To         []string             `json:"to,omitempty"`          // new state must be one of these
+	Attributes []AttributeCondition `json:"attributes,omitempty"`  // comparisons on attributes (or "state")
+	ForSeconds int                  `json:"for_seconds,omitempty"` // conditions must hold this long before firing
+	After      string               `json:"after,omitempty"`       // time-of-day window start "HH:MM" (local time)
+	Before     string               `json:"before,omitempty"`      // time-of-day window end "HH:MM", may wrap midnight
+}
+
+// AttributeCondition compares an entity attribute against a value.
+// Ordering operators compare numerically; == and != compare numerically when
+// both sides are numbers and as strings otherwise.
+type AttributeCondition struct {
+	Attribute string `json:"attribute"` // attribute name, or "state" for the new state itself
+	Op        string `json:"op"`        // >, >=, <, <=, ==, !=
+	Value     string `json:"value"`
+}
+
+// conditionOps lists supported operators, longest first for parsing.
+var conditionOps = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// ParseAttributeCondition parses "temperature > 28" or "battery<15".
+func ParseAttributeCondition(s string) (AttributeCondition, error) {
+	for _, op := range conditionOps {
+		if idx := strings.Index(s, op); idx > 0 {
+			c := AttributeCondition{
+				Attribute: strings.TrimSpace(s[:idx]),
+				Op:        op,
+				Value:     strings.Trim(strings.TrimSpace(s[idx+len(op):]), `"'`),
+			}
+			if c.Attribute == "" || c.Value == "" {
+				break
+			}
+			return c, c.Validate()
+		}
+	}
+	return AttributeCondition{}, fmt.Errorf("invalid condition %q (expected e.g. \"temperature > 28\")", s)
+}
+
+// String formats the condition as "attribute op value".
+func (c AttributeCondition) String() string {
+	return c.Attribute + " " + c.Op + " " + c.Value
+}
+
+// Validate checks the operator and that ordering comparisons use a number.
+func (c AttributeCondition) Validate() error {
+	if !slices.Contains(conditionOps, c.Op) {
+		return fmt.Errorf("invalid operator %q in condition on %s", c.Op, c.Attribute)
+	}
+	if c.Op != "==" && c.Op != "!=" {
+		if _, err := strconv.ParseFloat(c.Value, 64); err != nil {
+			return fmt.Errorf("condition %q needs a numeric value", c.String())
+		}
+	}
+	return nil
+}
+
+// Validate checks all conditions.
+func (c *Conditions) Validate() error {
+	if c == nil {
+		return nil
+	}
+	for _, ac := range c.Attributes {
+		if err := ac.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.ForSeconds < 0 {
+		return fmt.Errorf("for duration must not be negative")
+	}
+	for _, t := range []string{c.After, c.Before} {
+		if t == "" {
+			continue
+		}
+		if _, err := parseClock(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsEmpty returns true if no condition is set.
+func (c *Conditions) IsEmpty() bool {
+	return c == nil || (len(c.From) == 0 && len(c.To) == 0 && len(c.Attributes) == 0 &&
+		c.ForSeconds == 0 && c.After == "" && c.Before == "")
+}
+
+// Hold returns how long conditions must hold before the event fires (0 = fire immediately).
+func (c *Conditions) Hold() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return time.Duration(c.ForSeconds) * time.Second
+}
+
+// Evaluate checks the conditions against a state change at the given time.
+// checkFrom is false while a "for" hold is pending, because follow-up events
+// for the same state no longer carry the original old state.
+// Returns false and the first failing condition.
+func (c *Conditions) Evaluate(event *HAEvent, now time.Time, checkFrom bool) (bool, string) {
+	if c == nil {
+		return true, ""
+	}
+
+	oldState, newState := "", ""
+	if event.Data.OldState != nil {
+		oldState = event.Data.OldState.State
+	}
+	var attrs map[string]interface{}
+	if event.Data.NewState != nil {
+		newState = event.Data.NewState.State
+		attrs = event.Data.NewState.Attributes
+	}
+
+	if checkFrom && len(c.From) > 0 && !slices.Contains(c.From, oldState) {
+		return false, fmt.Sprintf("from %q not in %v", oldState, c.From)
+	}
+	if len(c.To) > 0 && !slices.Contains(c.To, newState) {
+		return false, fmt.Sprintf("to %q not in %v", newState, c.To)
+	}
+
+	for _, ac := range c.Attributes {
+		var actual interface{}
+		var ok bool
+		if ac.Attribute == "state" {
+			actual, ok = newState, true
+		} else {
+			actual, ok = attrs[ac.Attribute]
+		}
+		if !ok || !ac.matches(actual) {
+			return false, ac.String()
+		}
+	}
+
+	if !c.inWindow(now) {
+		return false, fmt.Sprintf("outside %s-%s", c.After, c.Before)
+	}
+	return true, ""
+}
+
+// matches compares an attribute value against the condition.
+func (c AttributeCondition) matches(actual interface{}) bool {
+	actualStr := fmt.Sprint(actual)
+	actualNum, actualErr := strconv.ParseFloat(actualStr, 64)
+	wantNum, wantErr := strconv.ParseFloat(c.Value, 64)
+	numeric := actualErr == nil && wantErr == nil
+
+	switch c.Op {
+	case "==":
+		if numeric {
+			return actualNum == wantNum
+		}
+		return actualStr == c.Value
+	case "!=":
+		if numeric {
+			return actualNum != wantNum
+		}
+		return actualStr != c.Value
+	}
+
+	if !numeric {
+		return false // e.g. "unavailable" never satisfies temperature > 28
+	}
+	switch c.Op {
+	case ">":
+		return actualNum > wantNum
+	case ">=":
+		return actualNum >= wantNum
+	case "<":
+		return actualNum < wantNum
+	case "<=":
+		return actualNum <= wantNum
+	}
+	return false
+}
+
+// inWindow reports whether now falls inside the After/Before time-of-day window.
+// Either bound may be empty. A window with After > Before wraps midnight.
+func (c *Conditions) inWindow(now time.Time) bool {
+	if c.After == "" && c.Before == "" {
+		return true
+	}
+	minutes := now.Hour()*60 + now.Minute()
+
+	after, errA := parseClock(c.After)
+	before, errB := parseClock(c.Before)
+	switch {
+	case c.Before == "" || errB != nil:
+		return errA != nil || minutes >= after
+	case c.After == "" || errA != nil:
+		return minutes < before
+	case after <= before:
+		return minutes >= after && minutes < before
+	default:
+		return minutes >= after || minutes < before
+	}
+}
+
+// parseClock parses "HH:MM" into minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (expected HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,128 @@
+package hass
+
+import (
+	"testing"
+	"time"
+)
+
+func stateEvent(oldState, newState string, attrs map[string]interface{}) *HAEvent {
+	return &HAEvent{
+		EventType: "state_changed",
+		Data: HAEventData{
+			EntityID: "sensor.test",
+			OldState: &HAState{State: oldState},
+			NewState: &HAState{State: newState, Attributes: attrs},
+		},
+	}
+}
+
+func TestParseAttributeCondition(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    AttributeCondition
+		wantErr bool
+	}{
+		{"temperature > 28", AttributeCondition{"temperature", ">", "28"}, false},
+		{"battery<=15", AttributeCondition{"battery", "<=", "15"}, false},
+		{"hvac_mode == 'heat'", AttributeCondition{"hvac_mode", "==", "heat"}, false},
+		{"battery < low", AttributeCondition{}, true},
+		{"> 5", AttributeCondition{}, true},
+		{"temperature", AttributeCondition{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAttributeCondition(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAttributeCondition(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseAttributeCondition(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestConditionsEvaluate(t *testing.T) {
+	noon := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	late := time.Date(2026, 1, 1, 23, 30, 0, 0, time.Local)
+	hot := map[string]interface{}{"temperature": 30.5, "unit": "C"}
+
+	tests := []struct {
+		name  string
+		conds *Conditions
+		event *HAEvent
+		now   time.Time
+		want  bool
+	}{
+		{"nil passes", nil, stateEvent("off", "on", nil), noon, true},
+		{"from/to match", &Conditions{From: []string{"off"}, To: []string{"on"}}, stateEvent("off", "on", nil), noon, true},
+		{"from mismatch", &Conditions{From: []string{"unavailable"}}, stateEvent("off", "on", nil), noon, false},
+		{"to mismatch", &Conditions{To: []string{"off"}}, stateEvent("off", "on", nil), noon, false},
+		{"attribute above", &Conditions{Attributes: []AttributeCondition{{"temperature", ">", "28"}}}, stateEvent("", "on", hot), noon, true},
+		{"attribute below", &Conditions{Attributes: []AttributeCondition{{"temperature", "<", "28"}}}, stateEvent("", "on", hot), noon, false},
+		{"attribute missing", &Conditions{Attributes: []AttributeCondition{{"humidity", ">", "50"}}}, stateEvent("", "on", hot), noon, false},
+		{"attribute string equal", &Conditions{Attributes: []AttributeCondition{{"unit", "==", "C"}}}, stateEvent("", "on", hot), noon, true},
+		{"numeric state", &Conditions{Attributes: []AttributeCondition{{"state", "<", "15"}}}, stateEvent("16", "12", nil), noon, true},
+		{"non-numeric state", &Conditions{Attributes: []AttributeCondition{{"state", "<", "15"}}}, stateEvent("16", "unavailable", nil), noon, false},
+		{"inside window", &Conditions{After: "08:00", Before: "18:00"}, stateEvent("off", "on", nil), noon, true},
+		{"outside window", &Conditions{After: "08:00", Before: "18:00"}, stateEvent("off", "on", nil), late, false},
+		{"overnight window late", &Conditions{After: "22:00", Before: "06:00"}, stateEvent("off", "on", nil), late, true},
+		{"overnight window noon", &Conditions{After: "22:00", Before: "06:00"}, stateEvent("off", "on", nil), noon, false},
+		{"after only", &Conditions{After: "23:00"}, stateEvent("off", "on", nil), late, true},
+		{"before only", &Conditions{Before: "11:00"}, stateEvent("off", "on", nil), noon, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, failed := tt.conds.Evaluate(tt.event, tt.now, true)
+			if got != tt.want {
+				t.Errorf("Evaluate() = %v (failed: %q), want %v", got, failed, tt.want)
+			}
+		})
+	}
+
+	// While holding, "from" no longer applies
+	conds := &Conditions{From: []string{"off"}, To: []string{"on"}}
+	if ok, _ := conds.Evaluate(stateEvent("on", "on", nil), noon, false); !ok {
+		t.Error("held state should pass without checking from")
+	}
+}
+
+func TestConditionsValidate(t *testing.T) {
+	if err := (&Conditions{After: "25:00"}).Validate(); err == nil {
+		t.Error("expected error for invalid time")
+	}
+	if err := (&Conditions{Attributes: []AttributeCondition{{"temp", "=>", "5"}}}).Validate(); err == nil {
+		t.Error("expected error for invalid operator")
+	}
+	if err := (&Conditions{To: []string{"on"}, ForSeconds: 600, After: "22:00", Before: "06:00"}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestExplainMatch(t *testing.T) {
+	noon := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	sub := &Subscription{Pattern: "sensor.*", Conditions: &Conditions{To: []string{"on"}, ForSeconds: 60}}
+
+	got := ExplainMatch(sub, stateEvent("off", "on", nil), noon)
+	if !got.Matched || !got.Fires || got.HoldSeconds != 60 || got.OldState != "off" {
+		t.Errorf("matching event = %+v", got)
+	}
+
+	got = ExplainMatch(sub, stateEvent("on", "off", nil), noon)
+	if !got.Matched || got.Fires || got.Failed == "" {
+		t.Errorf("failing condition = %+v", got)
+	}
+
+	// Without an old state the "from" condition is not judged
+	sub.Conditions.From = []string{"unavailable"}
+	current := &HAEvent{Data: HAEventData{EntityID: "sensor.test", NewState: &HAState{State: "on"}}}
+	if got = ExplainMatch(sub, current, noon); !got.Fires {
+		t.Errorf("current state = %+v", got)
+	}
+
+	sub.Pattern = "light.*"
+	if got = ExplainMatch(sub, stateEvent("off", "on", nil), noon); got.Matched || got.Fires {
+		t.Errorf("pattern mismatch = %+v", got)
+	}
+}
@@ -19,6 +19,7 @@ type HomeAssistantConfig struct {
 	EventPrefix      string `json:"eventPrefix,omitempty"`      // Prefix for injected events (default: "[HomeAssistant Event]")
 	SubscriptionFile string `json:"subscriptionFile,omitempty"` // Subscription persistence file (default: "hass-subscriptions.json")
 	ReconnectDelay   string `json:"reconnectDelay,omitempty"`   // WebSocket reconnect delay (default: "5s")
+	StateCache       bool   `json:"stateCache,omitempty"`       // Keep the event connection open to cache entity states
 }
 
 // HConfig is an alias for HomeAssistantConfig for convenience
@@ -94,6 +95,12 @@ func ConfigFormDef(cfg HConfig) forms.FormDef {
 						Type:    forms.Text,
 						Default: "5s",
 					},
+					{
+						Name:  "stateCache",
+						Title: "Always Cache States",
+						Desc:  "Stay connected without subscriptions so state lookups are served locally",
+						Type:  forms.Toggle,
+					},
 				},
 			},
 		},
@@ -0,0 +1,540 @@
+// Package hasstest provides an in-process fake Home Assistant for tests and
+// development. It speaks enough of the WebSocket and REST APIs for the hass
+// manager and tool: auth, subscribe_events, get_states, call_service and history.
+// State changes are scripted with SetState and pushed to subscribers as
+// state_changed events.
+package hasstest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/roelfdiedericks/goclaw/internal/hass"
+)
+
+// ServiceCall records a service call received over REST or WebSocket.
+type ServiceCall struct {
+	Domain  string
+	Service string
+	Data    map[string]any
+}
+
+// ServiceHandler scripts the effect of a service call, typically via SetState.
+type ServiceHandler func(s *Server, call ServiceCall) error
+
+// Server is a fake Home Assistant instance.
+type Server struct {
+	token    string
+	srv      *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu       sync.Mutex
+	states   map[string]*hass.HAState
+	history  map[string][]hass.HAState
+	calls    []ServiceCall
+	handlers map[string]ServiceHandler
+	conns    map[*wsConn]struct{}
+}
+
+// wsConn is one client WebSocket connection.
+type wsConn struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	subsMu  sync.Mutex
+	subs    map[int]string // subscription ID -> event type ("" = all)
+}
+
+// NewServer starts a fake Home Assistant accepting the given access token.
+func NewServer(token string) *Server {
+	s := &Server{
+		token:    token,
+		states:   make(map[string]*hass.HAState),
+		history:  make(map[string][]hass.HAState),
+		handlers: make(map[string]ServiceHandler),
+		conns:    make(map[*wsConn]struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/websocket", s.handleWebSocket)
+	mux.HandleFunc("GET /api/{$}", s.authed(s.handleAPIRoot))
+	mux.HandleFunc("GET /api/states", s.authed(s.handleStates))
+	mux.HandleFunc("GET /api/states/{entity}", s.authed(s.handleState))
+	mux.HandleFunc("POST /api/states/{entity}", s.authed(s.handleSetState))
+	mux.HandleFunc("GET /api/services", s.authed(s.handleServices))
+	mux.HandleFunc("POST /api/services/{domain}/{service}", s.authed(s.handleCallService))
+	mux.HandleFunc("GET /api/history/period/{start}", s.authed(s.handleHistory))
+
+	s.srv = httptest.NewServer(mux)
+	return s
+}
+
+// URL returns the base URL, e.g. http://127.0.0.1:41234
+func (s *Server) URL() string {
+	return s.srv.URL
+}
+
+// Config returns a HomeAssistantConfig pointing at this server, with short
+// timeouts so reconnect tests run quickly.
+func (s *Server) Config() hass.HomeAssistantConfig {
+	return hass.HomeAssistantConfig{
+		Enabled:        true,
+		URL:            s.srv.URL,
+		Token:          s.token,
+		Timeout:        "5s",
+		EventTimeout:   "5s",
+		ReconnectDelay: "50ms",
+	}
+}
+
+// Close drops all connections and stops the server.
+func (s *Server) Close() {
+	s.DropConnections()
+	s.srv.Close()
+}
+
+// DropConnections closes every WebSocket connection, simulating an HA restart.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	conns := make([]*wsConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		c.conn.Close()
+	}
+}
+
+// Subscribers returns the number of connections subscribed to state_changed.
+// Tests use it to wait until a client is ready before scripting changes.
+func (s *Server) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for c := range s.conns {
+		c.subsMu.Lock()
+		for _, eventType := range c.subs {
+			if eventType == "" || eventType == "state_changed" {
+				n++
+				break
+			}
+		}
+		c.subsMu.Unlock()
+	}
+	return n
+}
+
+// SetState sets an entity's state and attributes, records history and pushes
+// a state_changed event to subscribers. nil attrs keeps the current attributes.
+func (s *Server) SetState(entityID, state string, attrs map[string]any) hass.HAState {
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+
+	s.mu.Lock()
+	old := s.states[entityID]
+	next := &hass.HAState{
+		EntityID:    entityID,
+		State:       state,
+		Attributes:  attrs,
+		LastChanged: now,
+		LastUpdated: now,
+	}
+	if old != nil {
+		if attrs == nil {
+			next.Attributes = old.Attributes
+		}
+		if old.State == state {
+			next.LastChanged = old.LastChanged
+		}
+	}
+	s.states[entityID] = next
+	s.history[entityID] = append(s.history[entityID], *next)
+	s.mu.Unlock()
+
+	s.broadcast(&hass.HAEvent{
+		EventType: "state_changed",
+		Data:      hass.HAEventData{EntityID: entityID, OldState: old, NewState: next},
+		TimeFired: now,
+		Origin:    "LOCAL",
+	})
+	return *next
+}
+
+// RemoveState deletes an entity and pushes a state_changed event with no new state.
+func (s *Server) RemoveState(entityID string) {
+	s.mu.Lock()
+	old, ok := s.states[entityID]
+	delete(s.states, entityID)
+	s.mu.Unlock()
+
+	if ok {
+		s.broadcast(&hass.HAEvent{
+			EventType: "state_changed",
+			Data:      hass.HAEventData{EntityID: entityID, OldState: old},
+			TimeFired: time.Now().UTC().Format(time.RFC3339Nano),
+			Origin:    "LOCAL",
+		})
+	}
+}
+
+// State returns an entity's current state.
+func (s *Server) State(entityID string) (hass.HAState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.states[entityID]
+	if !ok {
+		return hass.HAState{}, false
+	}
+	return *st, true
+}
+
+// Calls returns all service calls received so far.
+func (s *Server) Calls() []ServiceCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.calls)
+}
+
+// HandleService scripts a service ("domain.service"). Without a handler,
+// turn_on/turn_off/toggle flip the targeted entities and other services only
+// get recorded.
+func (s *Server) HandleService(service string, h ServiceHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[service] = h
+}
+
+// callService records and applies a service call.
+func (s *Server) callService(call ServiceCall) error {
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	h := s.handlers[call.Domain+"."+call.Service]
+	s.mu.Unlock()
+
+	if h != nil {
+		return h(s, call)
+	}
+
+	for _, entityID := range targetEntities(call.Data) {
+		switch call.Service {
+		case "turn_on":
+			s.SetState(entityID, "on", nil)
+		case "turn_off":
+			s.SetState(entityID, "off", nil)
+		case "toggle":
+			if st, _ := s.State(entityID); st.State == "on" {
+				s.SetState(entityID, "off", nil)
+			} else {
+				s.SetState(entityID, "on", nil)
+			}
+		}
+	}
+	return nil
+}
+
+// targetEntities extracts entity_id (string or list) from service data.
+func targetEntities(data map[string]any) []string {
+	switch v := data["entity_id"].(type) {
+	case string:
+		return strings.Split(v, ",")
+	case []any:
+		var ids []string
+		for _, id := range v {
+			if s, ok := id.(string); ok {
+				ids = append(ids, s)
+			}
+		}
+		return ids
+	}
+	return nil
+}
+
+// sortedStates returns a snapshot of all states ordered by entity ID.
+func (s *Server) sortedStates() []hass.HAState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]hass.HAState, 0, len(s.states))
+	for _, st := range s.states {
+		list = append(list, *st)
+	}
+	slices.SortFunc(list, func(a, b hass.HAState) int { return strings.Compare(a.EntityID, b.EntityID) })
+	return list
+}
+
+// broadcast sends an event to every matching subscription.
+func (s *Server) broadcast(event *hass.HAEvent) {
+	s.mu.Lock()
+	conns := make([]*wsConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.mu.Unlock()
+
+	for _, c := range conns {
+		c.subsMu.Lock()
+		var ids []int
+		for id, eventType := range c.subs {
+			if eventType == "" || eventType == event.EventType {
+				ids = append(ids, id)
+			}
+		}
+		c.subsMu.Unlock()
+
+		for _, id := range ids {
+			c.write(hass.HAMessage{ID: id, Type: "event", Event: event}) //nolint:errcheck
+		}
+	}
+}
+
+func (c *wsConn) write(v any) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(v)
+}
+
+// wsRequest is an incoming WebSocket command.
+type wsRequest struct {
+	ID             int            `json:"id"`
+	Type           string         `json:"type"`
+	AccessToken    string         `json:"access_token"`
+	EventType      string         `json:"event_type"`
+	Subscription   int            `json:"subscription"`
+	Domain         string         `json:"domain"`
+	Service        string         `json:"service"`
+	ServiceData    map[string]any `json:"service_data"`
+	Target         map[string]any `json:"target"`
+	ReturnResponse bool           `json:"return_response"`
+}
+
+// handleWebSocket runs the HA WebSocket protocol for one client.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &wsConn{conn: conn, subs: make(map[int]string)}
+	defer conn.Close()
+
+	if err := c.write(map[string]string{"type": "auth_required", "ha_version": "2025.1.0"}); err != nil {
+		return
+	}
+	var auth wsRequest
+	if err := conn.ReadJSON(&auth); err != nil {
+		return
+	}
+	if auth.Type != "auth" || auth.AccessToken != s.token {
+		c.write(map[string]string{"type": "auth_invalid", "message": "Invalid access token or password"}) //nolint:errcheck
+		return
+	}
+	if err := c.write(map[string]string{"type": "auth_ok", "ha_version": "2025.1.0"}); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.conns[c] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+
+	for {
+		var req wsRequest
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		if err := c.write(s.handleCommand(c, req)); err != nil {
+			return
+		}
+	}
+}
+
+// handleCommand executes one WebSocket command and returns the result message.
+func (s *Server) handleCommand(c *wsConn, req wsRequest) hass.HAMessage {
+	var result any
+	switch req.Type {
+	case "subscribe_events":
+		c.subsMu.Lock()
+		c.subs[req.ID] = req.EventType
+		c.subsMu.Unlock()
+	case "unsubscribe_events":
+		c.subsMu.Lock()
+		_, ok := c.subs[req.Subscription]
+		delete(c.subs, req.Subscription)
+		c.subsMu.Unlock()
+		if !ok {
+			return errorMessage(req.ID, "not_found", "Subscription not found.")
+		}
+	case "get_states":
+		result = s.sortedStates()
+	case "call_service":
+		data := map[string]any{}
+		for k, v := range req.ServiceData {
+			data[k] = v
+		}
+		for k, v := range req.Target {
+			data[k] = v
+		}
+		if err := s.callService(ServiceCall{Domain: req.Domain, Service: req.Service, Data: data}); err != nil {
+			return errorMessage(req.ID, "home_assistant_error", err.Error())
+		}
+		result = map[string]any{"context": map[string]string{"id": fmt.Sprintf("fake-%d", req.ID)}}
+	case "config/device_registry/list", "config/area_registry/list", "config/entity_registry/list":
+		result = []any{}
+	case "ping":
+		return hass.HAMessage{ID: req.ID, Type: "pong"}
+	default:
+		return errorMessage(req.ID, "unknown_command", "Unknown command.")
+	}
+
+	raw, _ := json.Marshal(result)
+	success := true
+	return hass.HAMessage{ID: req.ID, Type: "result", Success: &success, Result: raw}
+}
+
+func errorMessage(id int, code, message string) hass.HAMessage {
+	success := false
+	return hass.HAMessage{ID: id, Type: "result", Success: &success, Error: &hass.HAError{Code: code, Message: message}}
+}
+
+// authed checks the bearer token on REST requests.
+func (s *Server) authed(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+s.token {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"message": "401: Unauthorized"})
+			return
+		}
+		h(w, r)
+	}
+}
+
+func (s *Server) handleAPIRoot(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"message": "API running."})
+}
+
+func (s *Server) handleStates(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.sortedStates())
+}
+
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	st, ok := s.State(r.PathValue("entity"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Entity not found."})
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+func (s *Server) handleSetState(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		State      string         `json:"state"`
+		Attributes map[string]any `json:"attributes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.State == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "No state specified."})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.SetState(r.PathValue("entity"), body.State, body.Attributes))
+}
+
+func (s *Server) handleServices(w http.ResponseWriter, r *http.Request) {
+	domains := map[string]map[string]any{}
+	for _, st := range s.sortedStates() {
+		domain, _, _ := strings.Cut(st.EntityID, ".")
+		domains[domain] = map[string]any{"turn_on": map[string]any{}, "turn_off": map[string]any{}, "toggle": map[string]any{}}
+	}
+	s.mu.Lock()
+	for name := range s.handlers {
+		domain, service, _ := strings.Cut(name, ".")
+		if domains[domain] == nil {
+			domains[domain] = map[string]any{}
+		}
+		domains[domain][service] = map[string]any{}
+	}
+	s.mu.Unlock()
+
+	list := make([]map[string]any, 0, len(domains))
+	for domain, services := range domains {
+		list = append(list, map[string]any{"domain": domain, "services": services})
+	}
+	slices.SortFunc(list, func(a, b map[string]any) int { return strings.Compare(a["domain"].(string), b["domain"].(string)) })
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleCallService(w http.ResponseWriter, r *http.Request) {
+	data := map[string]any{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": "Data should be valid JSON."})
+			return
+		}
+	}
+
+	call := ServiceCall{Domain: r.PathValue("domain"), Service: r.PathValue("service"), Data: data}
+	if err := s.callService(call); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+
+	// HA returns the states that changed; approximate with the targeted entities
+	changed := []hass.HAState{}
+	for _, id := range targetEntities(data) {
+		if st, ok := s.State(id); ok {
+			changed = append(changed, st)
+		}
+	}
+	writeJSON(w, http.StatusOK, changed)
+}
+
+// handleHistory returns one list of states per requested entity within the period.
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	start, err := time.Parse(time.RFC3339, r.PathValue("start"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid datetime"})
+		return
+	}
+	end := time.Now().Add(time.Second)
+	if v := r.URL.Query().Get("end_time"); v != "" {
+		if end, err = time.Parse(time.RFC3339, v); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": "Invalid end_time"})
+			return
+		}
+	}
+	filter := r.URL.Query().Get("filter_entity_id")
+	if filter == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": "filter_entity_id is missing"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := [][]hass.HAState{}
+	for _, id := range strings.Split(filter, ",") {
+		var entries []hass.HAState
+		for _, st := range s.history[id] {
+			changed, _ := time.Parse(time.RFC3339Nano, st.LastUpdated)
+			// Query times have second precision, so compare at that precision
+			if !changed.Before(start) && !changed.Truncate(time.Second).After(end) {
+				entries = append(entries, st)
+			}
+		}
+		if len(entries) > 0 {
+			result = append(result, entries)
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
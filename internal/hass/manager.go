@@ -24,8 +24,9 @@ type Manager struct {
 	injector       types.EventInjector
 	dataDir        string
 	subscriptions  map[string]*Subscription
-	debounce       map[string]time.Time // "entity_id:state" -> last fired (same state suppression)
-	interval       map[string]time.Time // "entity_id" -> last fired (per-entity rate limit)
+	debounce       map[string]time.Time    // "entity_id:state" -> last fired (same state suppression)
+	interval       map[string]time.Time    // "entity_id" -> last fired (per-entity rate limit)
+	holds          map[string]*pendingHold // "subID|entity_id" -> waiting for a "for" duration
 	conn           *websocket.Conn
 	msgID          int
 	subscriptionID int // HA subscription ID for state_changed
@@ -44,6 +45,22 @@ type Manager struct {
 
 	// Debug mode for status messages
 	debug bool
+
+	// In-process consumers of state changes (e.g. cron event triggers)
+	listeners []EventListener
+
+	// Entity state cache (see statecache.go)
+	states         map[string]*HAState
+	statesMsgID    int       // ID of the pending get_states request
+	statesSynced   bool      // cache seeded from get_states at least once
+	disconnectedAt time.Time // when the event connection was last lost
+}
+
+// EventListener receives every state_changed event, independent of subscriptions.
+// The manager stays connected while any listener wants events.
+type EventListener interface {
+	WantsHassEvents() bool
+	HandleHassEvent(event *HAEvent)
 }
 
 // NewManager creates a new HASS event subscription manager.
@@ -55,6 +72,8 @@ func NewManager(cfg HomeAssistantConfig, injector types.EventInjector, dataDir s
 		subscriptions: make(map[string]*Subscription),
 		debounce:      make(map[string]time.Time),
 		interval:      make(map[string]time.Time),
+		holds:         make(map[string]*pendingHold),
+		states:        make(map[string]*HAState),
 	}
 }
 
@@ -79,8 +98,8 @@ func (m *Manager) Start(ctx context.Context) error {
 
 	L_info("hass: manager started", "subscriptions", len(subs), "path", path)
 
-	// If we have subscriptions, connect
-	if len(subs) > 0 {
+	// If we have subscriptions (or listeners), connect
+	if m.needsConnection() {
 		L_debug("hass: starting connection loop for persisted subscriptions")
 		go m.connectLoop()
 	}
@@ -102,6 +121,11 @@ func (m *Manager) Stop() {
 	}
 	m.connected = false
 	m.connState = "disconnected"
+	m.disconnectedAt = time.Now()
+	for key, hold := range m.holds {
+		hold.timer.Stop()
+		delete(m.holds, key)
+	}
 	m.mu.Unlock()
 
 	m.wg.Wait()
@@ -144,6 +168,7 @@ func (m *Manager) Unsubscribe(id string) error {
 		return fmt.Errorf("subscription not found: %s", id)
 	}
 	delete(m.subscriptions, id)
+	m.cancelHoldsLocked(id)
 	remaining := len(m.subscriptions)
 	m.mu.Unlock()
 
@@ -155,8 +180,8 @@ func (m *Manager) Unsubscribe(id string) error {
 
 	L_info("hass: subscription removed", "id", id, "remaining", remaining)
 
-	// If no subscriptions left, disconnect
-	if remaining == 0 {
+	// If no subscriptions left (and no listener needs events), disconnect
+	if remaining == 0 && !m.needsConnection() {
 		m.mu.Lock()
 		if m.conn != nil {
 			m.conn.Close()
@@ -164,12 +189,54 @@ func (m *Manager) Unsubscribe(id string) error {
 		}
 		m.connected = false
 		m.connState = "disconnected"
+		m.disconnectedAt = time.Now()
 		m.mu.Unlock()
 	}
 
 	return nil
 }
 
+// AddListener registers an in-process consumer of state changes.
+func (m *Manager) AddListener(l EventListener) {
+	m.mu.Lock()
+	m.listeners = append(m.listeners, l)
+	m.mu.Unlock()
+	m.Refresh()
+}
+
+// Refresh connects if subscriptions or listeners need events and the manager
+// is not connected. Listeners call this when their interest changes.
+func (m *Manager) Refresh() {
+	if m.ctx == nil || m.ctx.Err() != nil {
+		return // Not started
+	}
+	m.mu.RLock()
+	needsConnect := !m.connected && !m.reconnecting
+	m.mu.RUnlock()
+	if needsConnect && m.needsConnection() {
+		L_debug("hass: starting connection loop for listeners")
+		go m.connectLoop()
+	}
+}
+
+// needsConnection returns true if any subscription exists or a listener wants events.
+func (m *Manager) needsConnection() bool {
+	m.mu.RLock()
+	hasSubs := len(m.subscriptions) > 0
+	listeners := append([]EventListener(nil), m.listeners...)
+	m.mu.RUnlock()
+
+	if hasSubs || m.cfg.StateCache {
+		return true
+	}
+	for _, l := range listeners {
+		if l.WantsHassEvents() {
+			return true
+		}
+	}
+	return false
+}
+
 // GetSubscriptions returns all active subscriptions.
 func (m *Manager) GetSubscriptions() []Subscription {
 	m.mu.RLock()
@@ -230,6 +297,33 @@ func (m *Manager) UpdateSubscription(id string, updates SubscriptionUpdates) (*S
 	}
 
 	// Apply updates - only change fields that are explicitly set
+	if updates.Pattern != nil || updates.Regex != nil {
+		pattern, regex := sub.Pattern, sub.Regex
+		if updates.Pattern != nil {
+			pattern = *updates.Pattern
+			if pattern != "" {
+				regex = ""
+			}
+		}
+		if updates.Regex != nil {
+			regex = *updates.Regex
+			if regex != "" && updates.Pattern == nil {
+				pattern = ""
+			}
+		}
+		if pattern != "" && regex != "" {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("pattern and regex are mutually exclusive")
+		}
+		if regex != "" {
+			if _, err := MatchRegex(regex, ""); err != nil {
+				m.mu.Unlock()
+				return nil, fmt.Errorf("invalid regex: %w", err)
+			}
+		}
+		sub.Pattern, sub.Regex = pattern, regex
+		m.cancelHoldsLocked(id)
+	}
 	if updates.Prompt != nil {
 		sub.Prompt = *updates.Prompt
 	}
@@ -251,6 +345,19 @@ func (m *Manager) UpdateSubscription(id string, updates SubscriptionUpdates) (*S
 	if updates.Enabled != nil {
 		sub.Enabled = *updates.Enabled
 	}
+	if updates.Conditions != nil {
+		if err := updates.Conditions.Validate(); err != nil {
+			m.mu.Unlock()
+			return nil, err
+		}
+		if updates.Conditions.IsEmpty() {
+			sub.Conditions = nil
+		} else {
+			conds := *updates.Conditions
+			sub.Conditions = &conds
+		}
+		m.cancelHoldsLocked(id)
+	}
 
 	// Copy for return before releasing lock
 	result := *sub
@@ -309,11 +416,11 @@ func (m *Manager) connectLoop() {
 		default:
 		}
 
-		// Check if we still have subscriptions
+		// Check if we still have subscriptions (or listeners wanting events)
 		m.mu.RLock()
 		subCount := len(m.subscriptions)
 		m.mu.RUnlock()
-		if subCount == 0 {
+		if !m.needsConnection() {
 			L_debug("hass: no subscriptions, stopping connect loop")
 			m.mu.Lock()
 			m.reconnecting = false
@@ -356,6 +463,7 @@ func (m *Manager) connectLoop() {
 		m.mu.Lock()
 		m.connected = false
 		m.connState = "disconnected"
+		m.disconnectedAt = time.Now()
 		m.reconnects++
 		if m.conn != nil {
 			m.conn.Close()
@@ -475,12 +583,26 @@ func (m *Manager) connect() error {
 		return fmt.Errorf("subscribe failed: %s", errMsg)
 	}
 
+	// Seed the state cache; the result is picked up by readLoop
+	m.mu.Lock()
+	m.msgID++
+	statesMsgID := m.msgID
+	m.mu.Unlock()
+	if err := conn.WriteJSON(HACommandMessage{ID: statesMsgID, Type: "get_states"}); err != nil {
+		conn.Close()
+		return fmt.Errorf("send get_states: %w", err)
+	}
+
 	m.mu.Lock()
 	m.conn = conn
 	m.connected = true
 	m.connState = "connected"
 	m.connSince = time.Now()
 	m.subscriptionID = subMsgID
+	m.statesMsgID = statesMsgID
+	if m.statesSynced && time.Since(m.disconnectedAt) >= stateCacheGrace {
+		m.statesSynced = false // too stale to serve until the new snapshot arrives
+	}
 	m.mu.Unlock()
 
 	L_info("hass: connected and subscribed to state_changed events")
@@ -515,6 +637,18 @@ func (m *Manager) readLoop() {
 		// Handle event
 		if msg.Type == "event" && msg.Event != nil {
 			m.handleEvent(msg.Event)
+			continue
+		}
+
+		m.mu.RLock()
+		statesMsgID := m.statesMsgID
+		m.mu.RUnlock()
+		if msg.Type == "result" && msg.ID == statesMsgID {
+			if msg.Success != nil && !*msg.Success {
+				L_warn("hass: get_states failed, state cache disabled until reconnect")
+				continue
+			}
+			m.seedStates(msg.Result)
 		}
 	}
 }
@@ -544,6 +678,18 @@ func (m *Manager) handleEvent(event *HAEvent) {
 
 	L_trace("hass: event received", "entity", entityID, "oldState", oldState, "newState", newState)
 
+	m.cacheEvent(event)
+
+	// Listeners see every state change
+	m.mu.RLock()
+	listeners := append([]EventListener(nil), m.listeners...)
+	m.mu.RUnlock()
+	for _, l := range listeners {
+		if l.WantsHassEvents() {
+			l.HandleHassEvent(event)
+		}
+	}
+
 	// Find matching subscriptions (skip disabled)
 	m.mu.RLock()
 	subCount := len(m.subscriptions)
@@ -571,9 +717,109 @@ func (m *Manager) handleEvent(event *HAEvent) {
 	}
 }
 
-// processMatch handles a subscription match with interval and debounce checks.
-// Interval is checked first (per-entity rate limit), then debounce (same state suppression).
+// processMatch handles a subscription match. Conditions are checked first; with a
+// "for" duration the event is held until the conditions have stayed true that long.
 func (m *Manager) processMatch(sub *Subscription, event *HAEvent, entityID, newState string) {
+	conds := sub.Conditions
+	holdKey := sub.ID + "|" + entityID
+
+	m.mu.Lock()
+	hold := m.holds[holdKey]
+	m.mu.Unlock()
+
+	// A follow-up event for a held state is judged without "from": the old state is now the held one
+	continuing := hold != nil && hold.state == newState
+	if ok, failed := conds.Evaluate(event, time.Now(), !continuing); !ok {
+		if hold != nil {
+			m.cancelHold(holdKey, hold)
+			L_debug("hass: hold cancelled", "entity", entityID, "state", newState, "subID", sub.ID, "condition", failed)
+		} else {
+			L_debug("hass: event filtered by condition", "entity", entityID, "state", newState, "subID", sub.ID, "condition", failed)
+		}
+		return
+	}
+
+	holdFor := conds.Hold()
+	if holdFor <= 0 {
+		m.fireMatch(sub, event, entityID, newState)
+		return
+	}
+
+	m.mu.Lock()
+	if continuing {
+		// Still holding - keep the original deadline, fire with the latest event
+		hold.event = event
+		m.mu.Unlock()
+		return
+	}
+	if hold != nil {
+		hold.timer.Stop()
+	}
+	hold = &pendingHold{state: newState, event: event}
+	h := hold
+	hold.timer = time.AfterFunc(holdFor, func() { m.releaseHold(sub.ID, holdKey, h) })
+	m.holds[holdKey] = hold
+	m.mu.Unlock()
+
+	L_debug("hass: holding event", "entity", entityID, "state", newState, "subID", sub.ID, "for", holdFor.String())
+}
+
+// pendingHold is a matched state change waiting for its "for" duration to elapse.
+type pendingHold struct {
+	state string
+	event *HAEvent // latest event seen while holding
+	timer *time.Timer
+}
+
+// releaseHold fires a held event once its duration has passed, if the
+// subscription still exists and the conditions still hold.
+func (m *Manager) releaseHold(subID, holdKey string, hold *pendingHold) {
+	m.mu.Lock()
+	if m.holds[holdKey] != hold {
+		m.mu.Unlock()
+		return // cancelled or replaced
+	}
+	delete(m.holds, holdKey)
+	sub, exists := m.subscriptions[subID]
+	event := hold.event
+	m.mu.Unlock()
+
+	if !exists || !sub.Enabled {
+		return
+	}
+	// Re-check non-transition conditions, e.g. the time window may have closed meanwhile
+	if ok, failed := sub.Conditions.Evaluate(event, time.Now(), false); !ok {
+		L_debug("hass: held event dropped", "entity", event.Data.EntityID, "subID", subID, "condition", failed)
+		return
+	}
+
+	L_debug("hass: hold elapsed", "entity", event.Data.EntityID, "state", hold.state, "subID", subID)
+	m.fireMatch(sub, event, event.Data.EntityID, hold.state)
+}
+
+// cancelHold stops a pending hold if it is still the current one.
+func (m *Manager) cancelHold(holdKey string, hold *pendingHold) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.holds[holdKey] == hold {
+		hold.timer.Stop()
+		delete(m.holds, holdKey)
+	}
+}
+
+// cancelHoldsLocked stops all pending holds for a subscription. Caller holds m.mu.
+func (m *Manager) cancelHoldsLocked(subID string) {
+	for key, hold := range m.holds {
+		if strings.HasPrefix(key, subID+"|") {
+			hold.timer.Stop()
+			delete(m.holds, key)
+		}
+	}
+}
+
+// fireMatch delivers a matched event, subject to interval and debounce checks.
+// Interval is checked first (per-entity rate limit), then debounce (same state suppression).
+func (m *Manager) fireMatch(sub *Subscription, event *HAEvent, entityID, newState string) {
 	now := time.Now()
 
 	m.mu.Lock()
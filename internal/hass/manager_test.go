@@ -0,0 +1,111 @@
+package hass_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/hass"
+	"github.com/roelfdiedericks/goclaw/internal/hass/hasstest"
+)
+
+// recordingInjector captures agent invocations.
+type recordingInjector struct {
+	mu      sync.Mutex
+	invoked []string
+}
+
+func (r *recordingInjector) InjectSystemEvent(ctx context.Context, text string) error {
+	return r.InvokeAgent(ctx, "", "", text, "")
+}
+
+func (r *recordingInjector) InvokeAgent(ctx context.Context, source, purpose, message, suppressPrefix string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invoked = append(r.invoked, message)
+	return nil
+}
+
+func (r *recordingInjector) messages() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.invoked...)
+}
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestManagerWithFakeServer(t *testing.T) {
+	srv := hasstest.NewServer("test-token")
+	defer srv.Close()
+	srv.SetState("sensor.living_room_temperature", "21.5", map[string]any{"unit_of_measurement": "°C"})
+	srv.SetState("binary_sensor.front_door", "off", nil)
+
+	injector := &recordingInjector{}
+	mgr := hass.NewManager(srv.Config(), injector, t.TempDir())
+	if err := mgr.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer mgr.Stop()
+
+	sub := hass.NewSubscription("door")
+	sub.Pattern = "binary_sensor.front_door"
+	sub.Conditions = &hass.Conditions{To: []string{"on"}}
+	if err := mgr.Subscribe(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	// Cache is seeded from get_states after subscribing
+	waitFor(t, "state cache", func() bool {
+		_, ok := mgr.CachedState("sensor.living_room_temperature")
+		return ok
+	})
+	if st, _ := mgr.CachedState("sensor.living_room_temperature"); st.State != "21.5" {
+		t.Errorf("cached state = %q, want 21.5", st.State)
+	}
+
+	// Events keep the cache current and wake the agent
+	srv.SetState("binary_sensor.front_door", "on", nil)
+	waitFor(t, "agent invocation", func() bool { return len(injector.messages()) == 1 })
+	if msg := injector.messages()[0]; !strings.Contains(msg, "binary_sensor.front_door") {
+		t.Errorf("agent message missing entity: %q", msg)
+	}
+	if st, _ := mgr.CachedState("binary_sensor.front_door"); st.State != "on" {
+		t.Errorf("cached door state = %q, want on", st.State)
+	}
+
+	// Condition filters the closing event
+	srv.SetState("binary_sensor.front_door", "off", nil)
+	waitFor(t, "cache update", func() bool {
+		st, _ := mgr.CachedState("binary_sensor.front_door")
+		return st.State == "off"
+	})
+	if n := len(injector.messages()); n != 1 {
+		t.Errorf("agent invoked %d times, want 1", n)
+	}
+
+	// Cache survives a dropped connection, and events resume after reconnect
+	srv.DropConnections()
+	waitFor(t, "disconnect", func() bool { return !mgr.IsConnected() || srv.Subscribers() == 0 })
+	if states, ok := mgr.CachedStates(); !ok || len(states) != 2 {
+		t.Errorf("CachedStates during reconnect = %d entries, ok=%v", len(states), ok)
+	}
+	waitFor(t, "reconnect", func() bool { return mgr.IsConnected() && srv.Subscribers() == 1 })
+
+	srv.RemoveState("sensor.living_room_temperature")
+	waitFor(t, "entity removal", func() bool {
+		_, ok := mgr.CachedState("sensor.living_room_temperature")
+		return !ok
+	})
+}
@@ -3,6 +3,7 @@ package hass
 import (
 	"regexp"
 	"strings"
+	"time"
 )
 
 // MatchGlob performs simple glob matching (* matches any characters).
@@ -87,3 +88,40 @@ func MatchSubscription(sub *Subscription, entityID string) bool {
 	// No pattern specified - match all
 	return true
 }
+
+// MatchResult explains how a subscription treats one state change.
+type MatchResult struct {
+	EntityID    string `json:"entityId"`
+	OldState    string `json:"oldState"`
+	NewState    string `json:"newState"`
+	Matched     bool   `json:"matched"`               // entity matches the pattern or regex
+	Fires       bool   `json:"fires"`                 // matched and every condition holds
+	Failed      string `json:"failed,omitempty"`      // first failing condition
+	HoldSeconds int    `json:"holdSeconds,omitempty"` // fires only once the conditions held this long
+}
+
+// ExplainMatch checks a state change against a subscription the way live
+// events are checked. Debounce, interval and the enabled flag are ignored.
+// An event without an old state (e.g. built from a current state) skips the
+// "from" condition.
+func ExplainMatch(sub *Subscription, event *HAEvent, now time.Time) MatchResult {
+	result := MatchResult{EntityID: event.Data.EntityID}
+	if event.Data.OldState != nil {
+		result.OldState = event.Data.OldState.State
+	}
+	if event.Data.NewState != nil {
+		result.NewState = event.Data.NewState.State
+	}
+
+	result.Matched = MatchSubscription(sub, result.EntityID)
+	if !result.Matched {
+		return result
+	}
+
+	ok, failed := sub.Conditions.Evaluate(event, now, event.Data.OldState != nil)
+	result.Fires, result.Failed = ok, failed
+	if ok {
+		result.HoldSeconds = int(sub.Conditions.Hold().Seconds())
+	}
+	return result
+}
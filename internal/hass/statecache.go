@@ -0,0 +1,101 @@
+package hass
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// stateCacheGrace is how long cached states are still served after the
+// event connection drops, covering short reconnects.
+const stateCacheGrace = 2 * time.Minute
+
+// The manager keeps a local copy of every entity state while its event
+// connection is up: seeded from get_states after subscribing, then kept
+// current from state_changed events.
+
+// cacheEvent applies a state_changed event to the cache.
+func (m *Manager) cacheEvent(event *HAEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if event.Data.NewState == nil {
+		delete(m.states, event.Data.EntityID) // entity removed
+		return
+	}
+	m.states[event.Data.EntityID] = event.Data.NewState
+}
+
+// seedStates loads a get_states result. Entries already updated by a newer
+// event since subscribing are kept.
+func (m *Manager) seedStates(result json.RawMessage) {
+	var states []HAState
+	if err := json.Unmarshal(result, &states); err != nil {
+		L_warn("hass: failed to parse get_states result", "error", err)
+		return
+	}
+
+	m.mu.Lock()
+	seen := make(map[string]bool, len(states))
+	for i := range states {
+		st := &states[i]
+		seen[st.EntityID] = true
+		if cur, ok := m.states[st.EntityID]; ok && cur.LastUpdated > st.LastUpdated {
+			continue
+		}
+		m.states[st.EntityID] = st
+	}
+	// Drop entities removed while we were disconnected
+	for id := range m.states {
+		if !seen[id] {
+			delete(m.states, id)
+		}
+	}
+	m.statesSynced = true
+	m.mu.Unlock()
+
+	L_debug("hass: state cache seeded", "entities", len(states))
+}
+
+// cacheUsableLocked reports whether cached states may be served. Caller holds m.mu.
+func (m *Manager) cacheUsableLocked() bool {
+	if !m.statesSynced {
+		return false
+	}
+	return m.connected || time.Since(m.disconnectedAt) < stateCacheGrace
+}
+
+// CachedState returns an entity's cached state. ok is false if the cache is
+// not usable or the entity is unknown; callers then fall back to REST.
+func (m *Manager) CachedState(entityID string) (HAState, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if !m.cacheUsableLocked() {
+		return HAState{}, false
+	}
+	st, ok := m.states[entityID]
+	if !ok {
+		return HAState{}, false
+	}
+	return *st, true
+}
+
+// CachedStates returns all cached states ordered by entity ID, in the same
+// shape as GET /api/states. ok is false if the cache is not usable.
+func (m *Manager) CachedStates() ([]HAState, bool) {
+	m.mu.RLock()
+	if !m.cacheUsableLocked() {
+		m.mu.RUnlock()
+		return nil, false
+	}
+	list := make([]HAState, 0, len(m.states))
+	for _, st := range m.states {
+		list = append(list, *st)
+	}
+	m.mu.RUnlock()
+
+	slices.SortFunc(list, func(a, b HAState) int { return strings.Compare(a.EntityID, b.EntityID) })
+	return list, true
+}
@@ -75,17 +75,18 @@ type HACommandMessage struct {
 
 // Subscription represents a persisted event subscription
 type Subscription struct {
-	ID              string    `json:"id"`               // UUID
-	Pattern         string    `json:"pattern"`          // glob pattern (mutually exclusive with Regex)
-	Regex           string    `json:"regex"`            // regex pattern (mutually exclusive with Pattern)
-	DebounceSeconds int       `json:"debounce_seconds"` // default: 5, suppress same entity+state
-	IntervalSeconds int       `json:"interval_seconds"` // default: 0 (disabled), per-entity rate limit
-	Prefix          string    `json:"prefix,omitempty"` // custom message prefix
-	Prompt          string    `json:"prompt,omitempty"` // instructions for agent when event fires
-	Full            bool      `json:"full"`             // true=full state object, false=brief (default)
-	Wake            bool      `json:"wake"`             // trigger immediate agent invocation (default: true)
-	Enabled         bool      `json:"enabled"`          // whether subscription is active (default: true)
-	CreatedAt       time.Time `json:"created_at"`
+	ID              string      `json:"id"`                   // UUID
+	Pattern         string      `json:"pattern"`              // glob pattern (mutually exclusive with Regex)
+	Regex           string      `json:"regex"`                // regex pattern (mutually exclusive with Pattern)
+	DebounceSeconds int         `json:"debounce_seconds"`     // default: 5, suppress same entity+state
+	IntervalSeconds int         `json:"interval_seconds"`     // default: 0 (disabled), per-entity rate limit
+	Prefix          string      `json:"prefix,omitempty"`     // custom message prefix
+	Prompt          string      `json:"prompt,omitempty"`     // instructions for agent when event fires
+	Full            bool        `json:"full"`                 // true=full state object, false=brief (default)
+	Wake            bool        `json:"wake"`                 // trigger immediate agent invocation (default: true)
+	Enabled         bool        `json:"enabled"`              // whether subscription is active (default: true)
+	Conditions      *Conditions `json:"conditions,omitempty"` // optional from/to, attribute, duration and time-window filters
+	CreatedAt       time.Time   `json:"created_at"`
 }
 
 // SubscriptionFile represents the structure of hass-subscriptions.json
@@ -124,6 +125,8 @@ func NewSubscription(id string) Subscription {
 // SubscriptionUpdates contains optional fields for updating a subscription.
 // Pointer fields: nil = not specified (keep current), non-nil = set to this value.
 type SubscriptionUpdates struct {
+	Pattern  *string // setting a non-empty pattern clears the regex, and vice versa
+	Regex    *string
 	Prompt   *string // nil = no change, "" = clear, "x" = set
 	Prefix   *string
 	Debounce *int
@@ -131,4 +134,7 @@ type SubscriptionUpdates struct {
 	Full     *bool
 	Wake     *bool
 	Enabled  *bool
+
+	// Conditions replaces the subscription's conditions; an empty value clears them
+	Conditions *Conditions
 }
@@ -0,0 +1,62 @@
+package history
+
+import (
+	"context"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/types"
+)
+
+// Change captures a file before a tool modifies it. Call Commit after a
+// successful write. A nil Change (history disabled) is a no-op.
+type Change struct {
+	store  *Store
+	entry  Entry
+	before []byte
+}
+
+// Begin snapshots path before tool changes it, tagging the change with the
+// session, run and tool call from ctx. Returns nil if history is disabled or
+// the file can't be read.
+func Begin(ctx context.Context, tool, path string) *Change {
+	s := Get()
+	if s == nil {
+		return nil
+	}
+	before, err := ReadFile(path)
+	if err != nil {
+		L_debug("history: cannot snapshot file", "path", path, "error", err)
+		return nil
+	}
+	return &Change{store: s, entry: EntryFromContext(ctx, tool, path), before: before}
+}
+
+// Commit records the file's new content.
+func (c *Change) Commit() {
+	if c == nil {
+		return
+	}
+	after, err := ReadFile(c.entry.Path)
+	if err != nil {
+		L_warn("history: cannot read changed file", "path", c.entry.Path, "error", err)
+		return
+	}
+	if _, err := c.store.Record(c.entry, c.before, after); err != nil {
+		L_warn("history: failed to record change", "path", c.entry.Path, "error", err)
+	}
+}
+
+// EntryFromContext builds entry metadata from the tool session context.
+func EntryFromContext(ctx context.Context, tool, path string) Entry {
+	e := Entry{Tool: tool, Path: path}
+	if sc := types.GetSessionContext(ctx); sc != nil {
+		if sc.User != nil {
+			e.User = sc.User.ID
+		}
+		e.AgentID = sc.AgentID
+		e.SessionKey = sc.SessionKey
+		e.RunID = sc.RunID
+		e.ToolCallID = sc.ToolCallID
+	}
+	return e
+}
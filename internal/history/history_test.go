@@ -0,0 +1,84 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	old := []byte("a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n")
+	new := []byte("a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\nl\nm\n")
+
+	want := "--- old\n+++ new\n" +
+		"@@ -1,7 +1,7 @@\n a\n b\n c\n-d\n+D\n e\n f\n g\n" +
+		"@@ -10,3 +10,4 @@\n j\n k\n l\n+m\n"
+	if got := UnifiedDiff("old", "new", old, new); got != want {
+		t.Errorf("diff mismatch:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := UnifiedDiff("old", "new", old, old); got != "" {
+		t.Errorf("equal content should give empty diff, got %q", got)
+	}
+
+	want = "--- /dev/null\n+++ new\n@@ -0,0 +1,1 @@\n+x\n\\ No newline at end of file\n"
+	if got := UnifiedDiff("old", "new", nil, []byte("x")); got != want {
+		t.Errorf("created file diff:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestStoreRecordAndUndo(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(filepath.Join(dir, "history"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "SOUL.md")
+
+	write := func(content string) {
+		before, _ := ReadFile(file)
+		if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Record(Entry{Tool: "write", Path: file, SessionKey: "s1"}, before, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("v1")
+	write("v2")
+	write("v2") // unchanged, not recorded
+
+	if n := len(store.List(Filter{Path: file})); n != 2 {
+		t.Fatalf("expected 2 entries, got %d", n)
+	}
+
+	// Undo twice walks back to before the file existed
+	for _, want := range []string{"v1", ""} {
+		e, ok := store.LastUndoable("s1")
+		if !ok {
+			t.Fatal("nothing to undo")
+		}
+		if _, err := store.Restore(e.ID, "before", Entry{Tool: "undo", SessionKey: "s1"}); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ReadFile(file)
+		if string(data) != want || (want == "" && data != nil) {
+			t.Errorf("after undo: got %q (nil=%v), want %q", data, data == nil, want)
+		}
+	}
+	if _, ok := store.LastUndoable("s1"); ok {
+		t.Error("expected nothing left to undo")
+	}
+
+	// Reopening restores the log and reverted state
+	reopened, err := Open(filepath.Join(dir, "history"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(reopened.List(Filter{})); n != 4 {
+		t.Errorf("expected 4 entries after reopen, got %d", n)
+	}
+	if _, ok := reopened.LastUndoable("s1"); ok {
+		t.Error("undone entries should stay undone after reopen")
+	}
+}
@@ -0,0 +1,373 @@
+// Package history records workspace file changes made by tools, so they can
+// be listed, diffed and undone.
+//
+// File contents are kept in a content-addressed object store (sha256) and
+// every change is appended to a JSONL log with the session, run and tool call
+// that made it.
+package history
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// Entry is one recorded change to a file.
+type Entry struct {
+	ID         int64     `json:"id"`
+	Time       time.Time `json:"time"`
+	Path       string    `json:"path"`             // absolute path
+	Before     string    `json:"before,omitempty"` // object hash; empty = file didn't exist
+	After      string    `json:"after,omitempty"`  // object hash; empty = file was deleted
+	Tool       string    `json:"tool"`             // "write", "edit", "undo", "restore", ...
+	User       string    `json:"user,omitempty"`
+	AgentID    string    `json:"agent,omitempty"`
+	SessionKey string    `json:"session,omitempty"`
+	RunID      string    `json:"run,omitempty"`
+	ToolCallID string    `json:"toolCall,omitempty"`
+	Reverts    int64     `json:"reverts,omitempty"` // entry undone by this one
+}
+
+// Store is the history store.
+type Store struct {
+	dir      string
+	mu       sync.RWMutex
+	entries  []Entry
+	reverted map[int64]bool // entries undone by a later entry
+	nextID   int64
+}
+
+var (
+	globalStore *Store
+	globalMu    sync.RWMutex
+)
+
+// InitStore opens the store in dir and makes it the global store used by tools.
+func InitStore(dir string) (*Store, error) {
+	s, err := Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	globalMu.Lock()
+	globalStore = s
+	globalMu.Unlock()
+	return s, nil
+}
+
+// Get returns the global store, or nil if history is disabled.
+func Get() *Store {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return globalStore
+}
+
+// Open opens (or creates) a store in dir.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "objects"), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	s := &Store{dir: dir, reverted: make(map[int64]bool), nextID: 1}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	L_debug("history: store opened", "dir", dir, "entries", len(s.entries))
+	return s, nil
+}
+
+func (s *Store) logPath() string {
+	return filepath.Join(s.dir, "log.jsonl")
+}
+
+func (s *Store) load() error {
+	f, err := os.Open(s.logPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			L_warn("history: skipping corrupt log line", "error", err)
+			continue
+		}
+		s.add(e)
+	}
+	return scanner.Err()
+}
+
+// add indexes an entry. Caller holds the lock (or is loading).
+func (s *Store) add(e Entry) {
+	s.entries = append(s.entries, e)
+	if e.Reverts != 0 {
+		s.reverted[e.Reverts] = true
+	}
+	if e.ID >= s.nextID {
+		s.nextID = e.ID + 1
+	}
+}
+
+// Record stores a change. before/after are the file contents, nil meaning
+// the file didn't exist. Unchanged content is not recorded.
+func (s *Store) Record(e Entry, before, after []byte) (Entry, error) {
+	beforeHash, err := s.putObject(before)
+	if err != nil {
+		return e, err
+	}
+	afterHash, err := s.putObject(after)
+	if err != nil {
+		return e, err
+	}
+	if beforeHash == afterHash {
+		return e, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.ID = s.nextID
+	e.Time = time.Now()
+	e.Before = beforeHash
+	e.After = afterHash
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+	f, err := os.OpenFile(s.logPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return e, err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return e, err
+	}
+
+	s.add(e)
+	L_debug("history: recorded change", "id", e.ID, "path", e.Path, "tool", e.Tool)
+	return e, nil
+}
+
+// putObject stores content and returns its hash ("" for nil).
+func (s *Store) putObject(data []byte) (string, error) {
+	if data == nil {
+		return "", nil
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	path := s.objectPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := writeAtomic(path, data, 0600); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+func (s *Store) objectPath(hash string) string {
+	return filepath.Join(s.dir, "objects", hash[:2], hash)
+}
+
+// Object returns stored content; nil for the empty hash (file absent).
+func (s *Store) Object(hash string) ([]byte, error) {
+	if hash == "" {
+		return nil, nil
+	}
+	if len(hash) != sha256.Size*2 || strings.Trim(hash, "0123456789abcdef") != "" {
+		return nil, fmt.Errorf("invalid object hash")
+	}
+	return os.ReadFile(s.objectPath(hash))
+}
+
+// Get returns an entry by ID.
+func (s *Store) Get(id int64) (Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if s.entries[i].ID == id {
+			return s.entries[i], true
+		}
+	}
+	return Entry{}, false
+}
+
+// IsReverted reports whether an entry has been undone.
+func (s *Store) IsReverted(id int64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reverted[id]
+}
+
+// Filter selects entries in List.
+type Filter struct {
+	Path       string // exact absolute path
+	Dir        string // only paths inside this directory
+	SessionKey string
+	Limit      int // 0 = no limit
+}
+
+// List returns matching entries, newest first.
+func (s *Store) List(f Filter) []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []Entry
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		e := s.entries[i]
+		if f.Path != "" && e.Path != f.Path {
+			continue
+		}
+		if f.Dir != "" && !inDir(e.Path, f.Dir) {
+			continue
+		}
+		if f.SessionKey != "" && e.SessionKey != f.SessionKey {
+			continue
+		}
+		out = append(out, e)
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out
+}
+
+// LastUndoable returns the newest change in a session that hasn't been
+// undone and isn't itself an undo.
+func (s *Store) LastUndoable(sessionKey string) (Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		e := s.entries[i]
+		if e.SessionKey != sessionKey || e.Reverts != 0 || s.reverted[e.ID] {
+			continue
+		}
+		return e, true
+	}
+	return Entry{}, false
+}
+
+// Restore writes a version of an entry's file back to disk and records the
+// restore. which is "before" or "after". If the restore undoes the entry
+// (restoring "before"), the new entry is marked as reverting it. meta supplies
+// the tool, user and session of the new entry.
+func (s *Store) Restore(id int64, which string, meta Entry) (Entry, error) {
+	e, ok := s.Get(id)
+	if !ok {
+		return Entry{}, fmt.Errorf("no history entry %d", id)
+	}
+
+	var hash string
+	switch which {
+	case "", "before":
+		hash = e.Before
+		meta.Reverts = e.ID
+	case "after":
+		hash = e.After
+	default:
+		return Entry{}, fmt.Errorf("invalid version %q (before or after)", which)
+	}
+
+	data, err := s.Object(hash)
+	if err != nil {
+		return Entry{}, fmt.Errorf("reading stored version: %w", err)
+	}
+	current, err := ReadFile(e.Path)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if data == nil {
+		if err := os.Remove(e.Path); err != nil && !os.IsNotExist(err) {
+			return Entry{}, err
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(e.Path), 0750); err != nil {
+			return Entry{}, err
+		}
+		if err := writeAtomic(e.Path, data, 0600); err != nil {
+			return Entry{}, err
+		}
+	}
+
+	meta.Path = e.Path
+	recorded, err := s.Record(meta, current, data)
+	if err != nil {
+		return Entry{}, err
+	}
+	if recorded.ID == 0 && meta.Reverts != 0 {
+		// Content already matched; still mark the entry as undone
+		s.mu.Lock()
+		s.reverted[meta.Reverts] = true
+		s.mu.Unlock()
+	}
+	L_info("history: restored", "id", id, "version", which, "path", e.Path, "by", meta.Tool)
+	return recorded, nil
+}
+
+// ReadFile returns a file's content, nil if it doesn't exist.
+func ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return data, nil
+}
+
+// writeAtomic writes via a temp file and rename, keeping existing permissions.
+func writeAtomic(path string, data []byte, perm os.FileMode) error {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".history-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+func inDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
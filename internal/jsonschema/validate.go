@@ -0,0 +1,353 @@
+// Package jsonschema validates decoded JSON values against the subset of JSON
+// Schema used by tool definitions and structured LLM output.
+//
+// Supported keywords: type, properties, required, additionalProperties, items,
+// enum, const, minimum, maximum, minLength, maxLength, minItems, maxItems,
+// pattern, anyOf and oneOf. Unknown keywords are ignored.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error describes the first violation found, with a JSONPath-style location.
+type Error struct {
+	Path    string // e.g. "$.memories[2].type"
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// Validate checks value (as produced by json.Unmarshal into any) against schema.
+// schema may be a map[string]any, json.RawMessage, []byte or string.
+func Validate(schema any, value any) error {
+	s, err := toSchema(schema)
+	if err != nil {
+		return err
+	}
+	return validate(s, value, "$")
+}
+
+// ValidateJSON decodes data and validates it against schema.
+func ValidateJSON(schema any, data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return &Error{Path: "$", Message: "invalid JSON: " + err.Error()}
+	}
+	return Validate(schema, value)
+}
+
+// toSchema normalises the accepted schema representations to a map.
+func toSchema(schema any) (map[string]any, error) {
+	switch s := schema.(type) {
+	case nil:
+		return nil, nil
+	case json.RawMessage:
+		return decodeSchema(s)
+	case []byte:
+		return decodeSchema(s)
+	case string:
+		return decodeSchema([]byte(s))
+	default:
+		// Round-trip anything else through JSON, so Go-built schemas using
+		// []string or typed structs decode to the same shapes as parsed ones
+		data, err := json.Marshal(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema: %w", err)
+		}
+		return decodeSchema(data)
+	}
+}
+
+func decodeSchema(data []byte) (map[string]any, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return m, nil
+}
+
+func validate(schema map[string]any, value any, path string) error {
+	if schema == nil {
+		return nil
+	}
+
+	if t, ok := schema["type"]; ok {
+		if !matchesType(t, value) {
+			return &Error{Path: path, Message: fmt.Sprintf("expected %s, got %s", typeString(t), jsonType(value))}
+		}
+	}
+
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			if equal(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return &Error{Path: path, Message: fmt.Sprintf("must be one of %s", formatEnum(enum))}
+		}
+	}
+
+	if c, ok := schema["const"]; ok && !equal(c, value) {
+		return &Error{Path: path, Message: fmt.Sprintf("must be %v", c)}
+	}
+
+	if anyOf, ok := schema["anyOf"].([]any); ok {
+		if err := validateAnyOf(anyOf, value, path); err != nil {
+			return err
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		if err := validateOneOf(oneOf, value, path); err != nil {
+			return err
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		return validateObject(schema, v, path)
+	case []any:
+		return validateArray(schema, v, path)
+	case string:
+		return validateString(schema, v, path)
+	case float64:
+		return validateNumber(schema, v, path)
+	}
+	return nil
+}
+
+func validateObject(schema map[string]any, obj map[string]any, path string) error {
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, present := obj[name]; name != "" && !present {
+				return &Error{Path: path, Message: fmt.Sprintf("missing required property %q", name)}
+			}
+		}
+	}
+
+	props, _ := schema["properties"].(map[string]any)
+
+	// Deterministic order so the reported violation is stable
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		childPath := path + "." + k
+		if ps, ok := props[k].(map[string]any); ok {
+			if err := validate(ps, obj[k], childPath); err != nil {
+				return err
+			}
+			continue
+		}
+		switch ap := schema["additionalProperties"].(type) {
+		case bool:
+			if !ap {
+				return &Error{Path: path, Message: fmt.Sprintf("unknown property %q", k)}
+			}
+		case map[string]any:
+			if err := validate(ap, obj[k], childPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateArray(schema map[string]any, arr []any, path string) error {
+	if n, ok := number(schema["minItems"]); ok && float64(len(arr)) < n {
+		return &Error{Path: path, Message: fmt.Sprintf("must have at least %d items", int(n))}
+	}
+	if n, ok := number(schema["maxItems"]); ok && float64(len(arr)) > n {
+		return &Error{Path: path, Message: fmt.Sprintf("must have at most %d items", int(n))}
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		for i, item := range arr {
+			if err := validate(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateString(schema map[string]any, s string, path string) error {
+	length := utf8.RuneCountInString(s)
+	if n, ok := number(schema["minLength"]); ok && float64(length) < n {
+		return &Error{Path: path, Message: fmt.Sprintf("must be at least %d characters", int(n))}
+	}
+	if n, ok := number(schema["maxLength"]); ok && float64(length) > n {
+		return &Error{Path: path, Message: fmt.Sprintf("must be at most %d characters", int(n))}
+	}
+	if p, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(p)
+		if err == nil && !re.MatchString(s) {
+			return &Error{Path: path, Message: fmt.Sprintf("must match pattern %q", p)}
+		}
+	}
+	return nil
+}
+
+func validateNumber(schema map[string]any, f float64, path string) error {
+	if n, ok := number(schema["minimum"]); ok && f < n {
+		return &Error{Path: path, Message: fmt.Sprintf("must be >= %v", n)}
+	}
+	if n, ok := number(schema["maximum"]); ok && f > n {
+		return &Error{Path: path, Message: fmt.Sprintf("must be <= %v", n)}
+	}
+	return nil
+}
+
+func validateAnyOf(options []any, value any, path string) error {
+	var first error
+	for _, o := range options {
+		s, _ := o.(map[string]any)
+		err := validate(s, value, path)
+		if err == nil {
+			return nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	if first == nil {
+		return nil
+	}
+	return &Error{Path: path, Message: "does not match any allowed schema (" + first.Error() + ")"}
+}
+
+func validateOneOf(options []any, value any, path string) error {
+	matches := 0
+	for _, o := range options {
+		s, _ := o.(map[string]any)
+		if validate(s, value, path) == nil {
+			matches++
+		}
+	}
+	if matches != 1 {
+		return &Error{Path: path, Message: fmt.Sprintf("must match exactly one schema, matched %d", matches)}
+	}
+	return nil
+}
+
+// matchesType checks a "type" keyword, which may be a string or list of strings.
+func matchesType(t any, value any) bool {
+	switch tt := t.(type) {
+	case string:
+		return matchesSingleType(tt, value)
+	case []any:
+		for _, x := range tt {
+			if s, ok := x.(string); ok && matchesSingleType(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesSingleType(t string, value any) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f))
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func typeString(t any) string {
+	if list, ok := t.([]any); ok {
+		parts := make([]string, 0, len(list))
+		for _, x := range list {
+			parts = append(parts, fmt.Sprint(x))
+		}
+		return strings.Join(parts, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+func formatEnum(enum []any) string {
+	parts := make([]string, 0, len(enum))
+	for _, e := range enum {
+		data, _ := json.Marshal(e)
+		parts = append(parts, string(data))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// equal compares two decoded JSON values.
+func equal(a, b any) bool {
+	if ai, ok := number(a); ok {
+		bi, ok := number(b)
+		return ok && ai == bi
+	}
+	ad, err1 := json.Marshal(a)
+	bd, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && string(ad) == string(bd)
+}
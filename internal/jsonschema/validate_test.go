@@ -0,0 +1,79 @@
+package jsonschema
+
+import (
+	"strings"
+	"testing"
+)
+
+const memorySchema = `{
+  "type": "object",
+  "properties": {
+    "memories": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "content": {"type": "string", "minLength": 1},
+          "type": {"type": "string", "enum": ["fact", "preference"]},
+          "importance": {"type": "number", "minimum": 0, "maximum": 1}
+        },
+        "required": ["content", "type"],
+        "additionalProperties": false
+      }
+    }
+  },
+  "required": ["memories"]
+}`
+
+func TestValidateJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{"valid", `{"memories": [{"content": "likes tea", "type": "preference", "importance": 0.6}]}`, ""},
+		{"empty list", `{"memories": []}`, ""},
+		{"missing required", `{}`, `$: missing required property "memories"`},
+		{"wrong type", `{"memories": {}}`, "$.memories: expected array, got object"},
+		{"enum", `{"memories": [{"content": "x", "type": "rumour"}]}`, `$.memories[0].type: must be one of ["fact", "preference"]`},
+		{"maximum", `{"memories": [{"content": "x", "type": "fact", "importance": 3}]}`, "$.memories[0].importance: must be <= 1"},
+		{"minLength", `{"memories": [{"content": "", "type": "fact"}]}`, "$.memories[0].content: must be at least 1 characters"},
+		{"additional", `{"memories": [{"content": "x", "type": "fact", "extra": 1}]}`, `$.memories[0]: unknown property "extra"`},
+		{"invalid JSON", `{"memories": [`, "$: invalid JSON"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateJSON(memorySchema, []byte(tt.doc))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateGoSchema(t *testing.T) {
+	// Schemas built in Go use []string for required and enum
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{"type": "string", "enum": []string{"get", "set"}},
+			"count":  map[string]any{"type": "integer"},
+		},
+		"required": []string{"action"},
+	}
+	if err := Validate(schema, map[string]any{"action": "get", "count": float64(2)}); err != nil {
+		t.Errorf("valid input rejected: %v", err)
+	}
+	if err := Validate(schema, map[string]any{"count": float64(2)}); err == nil {
+		t.Error("missing action accepted")
+	}
+	if err := Validate(schema, map[string]any{"action": "get", "count": 1.5}); err == nil {
+		t.Error("non-integer count accepted")
+	}
+}
@@ -242,6 +242,32 @@ func (c *AnthropicProvider) SimpleMessage(ctx context.Context, userMessage, syst
 	return result, nil
 }
 
+// StructuredMessage returns a JSON document matching schema.
+// Uses a forced tool call whose input schema is the output schema. If the
+// model answers in text instead, that text is validated like prompt mode and
+// retried with the schema in the prompt.
+func (c *AnthropicProvider) StructuredMessage(ctx context.Context, userMessage, systemPrompt string, schema json.RawMessage) (json.RawMessage, error) {
+	tool, err := structuredTool(schema)
+	if err != nil {
+		return nil, err
+	}
+	return runStructured(ctx, c, StructuredModeTool, schema, userMessage, systemPrompt,
+		func(ctx context.Context, msg, sys string) (string, error) {
+			var text strings.Builder
+			resp, err := c.StreamMessage(ctx, []types.Message{{Role: "user", Content: msg}},
+				[]types.ToolDefinition{tool}, sys, collectText(&text), &StreamOptions{ForceTool: tool.Name})
+			if err != nil {
+				return "", err
+			}
+			if resp.ToolName != tool.Name {
+				L_warn("llm: structured tool not called, validating text reply",
+					"provider", c.name, "model", c.model, "tool", tool.Name)
+				return text.String(), nil
+			}
+			return string(resp.ToolInput), nil
+		})
+}
+
 // StreamMessage sends a message to the LLM and streams the response
 // onDelta is called for each text chunk received
 // opts can be nil for default behavior
@@ -339,6 +365,17 @@ func (c *AnthropicProvider) StreamMessage(
 		c.trace("tools attached", "count", len(anthropicTools))
 	}
 
+	// Force a specific tool (structured output). Not allowed together with
+	// thinking: the model may then answer in text, which the caller validates.
+	if opts != nil && opts.ForceTool != "" {
+		if enableThinking {
+			L_warn("llm: forced tool call not allowed with thinking, model may answer in text",
+				"provider", c.name, "model", c.model, "tool", opts.ForceTool)
+		} else {
+			params.ToolChoice = anthropic.ToolChoiceParamOfTool(opts.ForceTool)
+		}
+	}
+
 	// Estimate input tokens from full serialized request (includes tools, messages, all metadata)
 	reqBytes, _ := json.Marshal(params)
 	reqSizeKB := len(reqBytes) / 1024
@@ -809,6 +846,16 @@ func convertTools(defs []types.ToolDefinition) []anthropic.ToolUnionParam {
 		param := anthropic.ToolInputSchemaParam{
 			Properties: properties,
 		}
+		switch required := def.InputSchema["required"].(type) {
+		case []string:
+			param.Required = required
+		case []any: // schema decoded from JSON
+			for _, r := range required {
+				if name, ok := r.(string); ok {
+					param.Required = append(param.Required, name)
+				}
+			}
+		}
 
 		result = append(result, anthropic.ToolUnionParam{
 			OfTool: &anthropic.ToolParam{
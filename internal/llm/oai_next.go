@@ -325,6 +325,21 @@ func (p *OaiNextProvider) SimpleMessage(ctx context.Context, userMessage, system
 	return result, nil
 }
 
+// StructuredMessage returns a JSON document matching schema, using the
+// json_schema text format when the model supports it.
+func (p *OaiNextProvider) StructuredMessage(ctx context.Context, userMessage, systemPrompt string, schema json.RawMessage) (json.RawMessage, error) {
+	if !SupportsStructuredOutput(p) {
+		return runStructured(ctx, p, StructuredModePrompt, schema, userMessage, systemPrompt, p.SimpleMessage)
+	}
+	return runStructured(ctx, p, StructuredModeNative, schema, userMessage, systemPrompt,
+		func(ctx context.Context, msg, sys string) (string, error) {
+			var text strings.Builder
+			_, err := p.StreamMessage(ctx, []types.Message{{Role: "user", Content: msg}}, nil, sys,
+				collectText(&text), &StreamOptions{ResponseSchema: schema})
+			return text.String(), err
+		})
+}
+
 // =============================================================================
 // StatefulProvider Interface
 // =============================================================================
@@ -498,6 +513,14 @@ func (p *OaiNextProvider) buildRequest(
 	// Add client-side tools (with conflict prefixing)
 	p.addClientTools(req, toolDefs, serverToolNames)
 
+	if opts != nil && len(opts.ResponseSchema) > 0 {
+		schema, strict := strictSchema(opts.ResponseSchema)
+		if !strict {
+			L_debug("oai-next: response schema has optional properties, strict mode off", "model", p.Model())
+		}
+		req.Text = &oaiTextConfig{Format: oaiTextFormat{Type: "json_schema", Name: "response", Schema: schema, Strict: strict}}
+	}
+
 	return req
 }
 
@@ -17,6 +17,20 @@ type oaiRequest struct {
 	PreviousResponseID string         `json:"previous_response_id,omitempty"`
 	MaxOutputTokens    int            `json:"max_output_tokens,omitempty"`
 	Generate           *bool          `json:"generate,omitempty"` // false = warmup only
+	Text               *oaiTextConfig `json:"text,omitempty"`     // structured output format
+}
+
+// oaiTextConfig configures the text output format (JSON schema for structured output).
+type oaiTextConfig struct {
+	Format oaiTextFormat `json:"format"`
+}
+
+// oaiTextFormat is a response text format; Type "json_schema" constrains output to Schema.
+type oaiTextFormat struct {
+	Type   string          `json:"type"`             // "text", "json_schema"
+	Name   string          `json:"name,omitempty"`   // for json_schema
+	Schema json.RawMessage `json:"schema,omitempty"` // for json_schema
+	Strict bool            `json:"strict,omitempty"` // for json_schema: enforce the schema exactly
 }
 
 // oaiInputItem represents an item in the input array (message, function_call, function_call_output).
@@ -62,6 +62,7 @@ type ollamaChatRequest struct {
 	Messages []ollamaChatMessage `json:"messages"`
 	Stream   bool                `json:"stream"`
 	Options  *ollamaOptions      `json:"options,omitempty"`
+	Format   json.RawMessage     `json:"format,omitempty"` // JSON schema for structured output
 }
 
 // ollamaOptions contains model options like context size
@@ -329,6 +330,21 @@ func (c *OllamaClient) Available() bool {
 // This is the interface used by compaction/checkpoint summarization.
 // If the message exceeds the model's context window, it will be truncated with a warning.
 func (c *OllamaClient) SimpleMessage(ctx context.Context, userMessage, systemPrompt string) (string, error) {
+	return c.chat(ctx, userMessage, systemPrompt, nil)
+}
+
+// StructuredMessage returns a JSON document matching schema, using Ollama's
+// native format parameter to constrain output.
+func (c *OllamaClient) StructuredMessage(ctx context.Context, userMessage, systemPrompt string, schema json.RawMessage) (json.RawMessage, error) {
+	return runStructured(ctx, c, StructuredModeNative, schema, userMessage, systemPrompt,
+		func(ctx context.Context, msg, sys string) (string, error) {
+			return c.chat(ctx, msg, sys, schema)
+		})
+}
+
+// chat sends a non-streaming chat request. format, if set, is passed as the
+// JSON schema the response must follow.
+func (c *OllamaClient) chat(ctx context.Context, userMessage, systemPrompt string, format json.RawMessage) (string, error) {
 	startTime := time.Now()
 
 	// Estimate chars limit from tokens (rough: 1 token ≈ 3 chars for English)
@@ -402,6 +418,7 @@ func (c *OllamaClient) SimpleMessage(ctx context.Context, userMessage, systemPro
 			NumCtx:     contextTokens, // Use detected context window
 			NumPredict: configuredMax, // Will be capped below
 		},
+		Format: format,
 	}
 
 	// Estimate input tokens from full serialized request
@@ -645,6 +645,22 @@ func (p *OpenAIProvider) SimpleMessage(ctx context.Context, userMessage, systemP
 	return result, nil
 }
 
+// StructuredMessage returns a JSON document matching schema.
+// Uses response_format json_schema when models.json marks the model as
+// supporting structured output, otherwise the schema goes in the prompt.
+func (p *OpenAIProvider) StructuredMessage(ctx context.Context, userMessage, systemPrompt string, schema json.RawMessage) (json.RawMessage, error) {
+	if !SupportsStructuredOutput(p) {
+		return runStructured(ctx, p, StructuredModePrompt, schema, userMessage, systemPrompt, p.SimpleMessage)
+	}
+	return runStructured(ctx, p, StructuredModeNative, schema, userMessage, systemPrompt,
+		func(ctx context.Context, msg, sys string) (string, error) {
+			var text strings.Builder
+			_, err := p.StreamMessage(ctx, []types.Message{{Role: "user", Content: msg}}, nil, sys,
+				collectText(&text), &StreamOptions{ResponseSchema: schema})
+			return text.String(), err
+		})
+}
+
 // StreamMessage sends a message to the LLM and streams the response
 // onDelta is called for each text chunk received
 // opts controls thinking level and provides callback for thinking deltas
@@ -745,6 +761,28 @@ func (p *OpenAIProvider) StreamMessage(
 		p.trace("tools attached", "count", len(openaiTools), "names", toolNames)
 	}
 
+	// Structured output: native JSON schema or a forced tool call
+	if opts != nil && len(opts.ResponseSchema) > 0 {
+		schema, strict := strictSchema(opts.ResponseSchema)
+		if !strict {
+			L_debug("openai: response schema has optional properties, strict mode off", "provider", p.name, "model", p.Model())
+		}
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   "response",
+				Schema: schema,
+				Strict: strict,
+			},
+		}
+	}
+	if opts != nil && opts.ForceTool != "" {
+		req.ToolChoice = openai.ToolChoice{
+			Type:     openai.ToolTypeFunction,
+			Function: openai.ToolFunction{Name: opts.ForceTool},
+		}
+	}
+
 	// Estimate input tokens from full serialized request (includes tools, messages, all metadata)
 	reqBytes, _ := json.Marshal(req)
 	reqSizeKB := len(reqBytes) / 1024
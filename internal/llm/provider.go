@@ -35,6 +35,9 @@ type Provider interface {
 	// Chat - Simple (no tools, no streaming, for summarization)
 	SimpleMessage(ctx context.Context, userMessage, systemPrompt string) (string, error)
 
+	// Chat - Structured (JSON document validated against schema, for checkpoints/extraction)
+	StructuredMessage(ctx context.Context, userMessage, systemPrompt string, schema json.RawMessage) (json.RawMessage, error)
+
 	// Chat - Full streaming with tools
 	StreamMessage(
 		ctx context.Context,
@@ -174,6 +177,16 @@ type StreamOptions struct {
 	// name, args (JSON), status (pending/completed/failed), errMsg (non-empty when status=failed).
 	// Gateway emits EventToolStart/EventToolEnd.
 	OnServerToolCall func(name, args, status, errMsg string)
+
+	// ForceTool requires the model to call the named tool (must be in toolDefs).
+	// Used for structured output on providers without native JSON-schema mode.
+	// Anthropic can't force a tool with thinking on; it logs a warning and the
+	// model may answer in text, so callers must validate the result.
+	ForceTool string
+
+	// ResponseSchema requests native JSON-schema constrained output.
+	// Ignored by providers that don't support it.
+	ResponseSchema json.RawMessage
 }
 
 // Note: Response type is currently defined in anthropic.go
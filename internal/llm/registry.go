@@ -3,6 +3,8 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"strings"
@@ -247,7 +249,7 @@ func (r *Registry) validatePurposeModels(purpose string) error {
 // requirements using models.json metadata. Returns a reason string if the
 // model should be removed, or empty string if it passes (or is unknown).
 func checkMetadataCapabilities(cfg LLMProviderConfig, modelName, purpose string) string {
-	reqs, ok := purposeCapabilities[purpose]
+	reqs, ok := purposeCapabilities[basePurpose(purpose)]
 	if !ok {
 		return ""
 	}
@@ -296,6 +298,34 @@ func checkMetadataCapabilities(cfg LLMProviderConfig, modelName, purpose string)
 	return ""
 }
 
+// basePurpose strips a scope suffix from a purpose name, so scoped purposes
+// like "agent:home" inherit the capability requirements of "agent".
+func basePurpose(purpose string) string {
+	if i := strings.IndexByte(purpose, ':'); i >= 0 {
+		return purpose[:i]
+	}
+	return purpose
+}
+
+// RegisterPurpose adds (or replaces) a model chain for an additional purpose,
+// e.g. "agent:home" for a named agent with its own models. The chain is
+// validated like the built-in purposes; the agent chain remains the fallback.
+func (r *Registry) RegisterPurpose(purpose string, cfg LLMPurposeConfig) error {
+	r.mu.Lock()
+	r.purposes[purpose] = cfg
+	r.mu.Unlock()
+
+	if err := r.validatePurposeModels(purpose); err != nil {
+		r.mu.Lock()
+		delete(r.purposes, purpose)
+		r.mu.Unlock()
+		return err
+	}
+
+	L_info("llm: purpose registered", "purpose", purpose, "models", len(cfg.Models))
+	return nil
+}
+
 // GetProvider returns the first available provider for a purpose.
 // Iterates through the model chain until one is available.
 // Falls back to the agent chain if the purpose has no models configured.
@@ -845,6 +875,35 @@ func (r *Registry) SimpleMessageWithFailover(
 	stateAccessor ProviderStateAccessor,
 	userMessage string,
 	systemPrompt string,
+) (*SimpleMessageResult, error) {
+	return r.messageWithFailover(ctx, purpose, stateAccessor, func(ctx context.Context, p Provider) (string, error) {
+		return p.SimpleMessage(ctx, userMessage, systemPrompt)
+	})
+}
+
+// StructuredMessageWithFailover tries models in the chain for a purpose using
+// StructuredMessage. result.Text holds a JSON document valid against schema.
+// A model that never produces valid output is skipped (no cooldown).
+func (r *Registry) StructuredMessageWithFailover(
+	ctx context.Context,
+	purpose string,
+	stateAccessor ProviderStateAccessor,
+	userMessage string,
+	systemPrompt string,
+	schema json.RawMessage,
+) (*SimpleMessageResult, error) {
+	return r.messageWithFailover(ctx, purpose, stateAccessor, func(ctx context.Context, p Provider) (string, error) {
+		doc, err := p.StructuredMessage(ctx, userMessage, systemPrompt, schema)
+		return string(doc), err
+	})
+}
+
+// messageWithFailover runs call against each model in the purpose chain until one succeeds.
+func (r *Registry) messageWithFailover(
+	ctx context.Context,
+	purpose string,
+	stateAccessor ProviderStateAccessor,
+	call func(ctx context.Context, p Provider) (string, error),
 ) (*SimpleMessageResult, error) {
 	r.mu.RLock()
 	purposeCfg, ok := r.purposes[purpose]
@@ -908,7 +967,7 @@ func (r *Registry) SimpleMessageWithFailover(
 
 		// Try the call (inject purpose into context for per-purpose metrics)
 		purposeCtx := ContextWithPurpose(ctx, purpose)
-		text, err := p.SimpleMessage(purposeCtx, userMessage, systemPrompt)
+		text, err := call(purposeCtx, p)
 
 		// Save state after call (even on error - state may have changed)
 		if sp, ok := p.(StatefulProvider); ok && stateAccessor != nil {
@@ -941,6 +1000,15 @@ func (r *Registry) SimpleMessageWithFailover(
 			return result, nil
 		}
 
+		// Model answered but never matched the schema: try the next one
+		var structuredErr *StructuredOutputError
+		if errors.As(err, &structuredErr) {
+			L_warn("failover: invalid structured output, trying next model",
+				"model", modelRef, "error", err, "purpose", purpose)
+			lastErr = err
+			continue
+		}
+
 		// Classify the error
 		errType := ClassifyError(err.Error())
 
@@ -0,0 +1,289 @@
+// Package llm - Structured (JSON schema) output
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/roelfdiedericks/goclaw/internal/jsonschema"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/metadata"
+	"github.com/roelfdiedericks/goclaw/internal/types"
+)
+
+// Structured output modes. Every mode validates the result against the schema
+// and retries with the validation error as feedback.
+const (
+	StructuredModeNative = "native" // provider enforces the JSON schema (response_format, Ollama format)
+	StructuredModeJSON   = "json"   // provider guarantees JSON but not the schema (xAI JSON mode)
+	StructuredModeTool   = "tool"   // single forced tool call whose input schema is the output schema
+	StructuredModePrompt = "prompt" // schema described in the prompt, JSON scraped from the reply
+)
+
+// structuredToolName is the tool forced in StructuredModeTool.
+const structuredToolName = "respond"
+
+// structuredMaxAttempts bounds validate-and-retry per model.
+const structuredMaxAttempts = 3
+
+// StructuredOutputError is returned when a model never produced a document
+// matching the schema. The registry fails over to the next model on it.
+type StructuredOutputError struct {
+	Provider string
+	Model    string
+	Mode     string
+	Attempts int
+	Err      error // last validation error
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("%s/%s: no valid structured output after %d attempts (%s mode): %v",
+		e.Provider, e.Model, e.Attempts, e.Mode, e.Err)
+}
+
+func (e *StructuredOutputError) Unwrap() error { return e.Err }
+
+// SupportsStructuredOutput returns true if models.json marks the model as
+// supporting native JSON-schema output.
+func SupportsStructuredOutput(p Provider) bool {
+	if mp := p.MetadataProvider(); mp != "" {
+		if model, ok := metadata.Get().GetModel(mp, p.Model()); ok {
+			return model.Capabilities.StructuredOutput
+		}
+	}
+	return false
+}
+
+// structuredAttempt performs one model call and returns the raw candidate document.
+type structuredAttempt func(ctx context.Context, userMessage, systemPrompt string) (string, error)
+
+// runStructured drives a structured call: it calls attempt, extracts and
+// validates the JSON, and retries with the validation error as feedback.
+// Provider errors are returned as-is so the registry can classify them.
+func runStructured(
+	ctx context.Context,
+	p Provider,
+	mode string,
+	schema json.RawMessage,
+	userMessage, systemPrompt string,
+	attempt structuredAttempt,
+) (json.RawMessage, error) {
+	if !json.Valid(schema) {
+		return nil, fmt.Errorf("structured output: invalid schema")
+	}
+
+	prompt := userMessage
+	if mode == StructuredModeJSON || mode == StructuredModePrompt {
+		prompt = structuredPrompt(userMessage, schema)
+	}
+
+	var lastErr error
+	for i := 1; i <= structuredMaxAttempts; i++ {
+		raw, err := attempt(ctx, prompt, systemPrompt)
+		if err != nil {
+			return nil, err
+		}
+
+		doc, err := ExtractJSON(raw)
+		if err == nil {
+			err = jsonschema.ValidateJSON(schema, doc)
+		}
+		if err == nil {
+			L_debug("llm: structured output ok", "provider", p.Name(), "model", p.Model(), "mode", mode, "attempt", i)
+			return doc, nil
+		}
+
+		lastErr = err
+		L_warn("llm: structured output invalid, retrying",
+			"provider", p.Name(), "model", p.Model(), "mode", mode, "attempt", i, "error", err)
+		prompt = structuredRetryPrompt(userMessage, schema, raw, err)
+	}
+
+	return nil, &StructuredOutputError{
+		Provider: p.Name(),
+		Model:    p.Model(),
+		Mode:     mode,
+		Attempts: structuredMaxAttempts,
+		Err:      lastErr,
+	}
+}
+
+// structuredPrompt appends the schema to the prompt for modes where the
+// provider doesn't receive it.
+func structuredPrompt(userMessage string, schema json.RawMessage) string {
+	return fmt.Sprintf("%s\n\nRespond ONLY with a JSON document matching this JSON schema, no other text:\n%s",
+		userMessage, schema)
+}
+
+// structuredRetryPrompt asks the model to correct its previous reply.
+func structuredRetryPrompt(userMessage string, schema json.RawMessage, previous string, verr error) string {
+	const maxEcho = 2000
+	if len(previous) > maxEcho {
+		previous = previous[:maxEcho] + "..."
+	}
+	return fmt.Sprintf("%s\n\nYour previous reply was rejected: %v\n\nPrevious reply:\n%s\n\n"+
+		"Respond again with ONLY a JSON document matching this JSON schema, no other text:\n%s",
+		userMessage, verr, previous, schema)
+}
+
+// structuredTool builds the tool definition forced in StructuredModeTool.
+func structuredTool(schema json.RawMessage) (types.ToolDefinition, error) {
+	var input map[string]any
+	if err := json.Unmarshal(schema, &input); err != nil {
+		return types.ToolDefinition{}, fmt.Errorf("structured output: invalid schema: %w", err)
+	}
+	if input["type"] != "object" {
+		return types.ToolDefinition{}, fmt.Errorf("structured output: tool mode requires an object schema")
+	}
+	return types.ToolDefinition{
+		Name:        structuredToolName,
+		Description: "Return the requested result. The input is the complete answer.",
+		InputSchema: input,
+	}, nil
+}
+
+// strictSchema prepares a schema for OpenAI strict mode, which requires
+// every object to list all its properties as required and to forbid
+// additional ones. additionalProperties: false is added where missing; ok is
+// false when an object has optional properties or allows extra ones, in
+// which case the schema can only be sent non-strict.
+func strictSchema(schema json.RawMessage) (json.RawMessage, bool) {
+	var root map[string]any
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return schema, false
+	}
+	if !tightenSchema(root) {
+		return schema, false
+	}
+	out, err := json.Marshal(root)
+	if err != nil {
+		return schema, false
+	}
+	return out, true
+}
+
+// tightenSchema applies strictSchema to one schema node and its children.
+func tightenSchema(node map[string]any) bool {
+	if props, ok := node["properties"].(map[string]any); ok {
+		required := make(map[string]bool)
+		if list, ok := node["required"].([]any); ok {
+			for _, name := range list {
+				if s, ok := name.(string); ok {
+					required[s] = true
+				}
+			}
+		}
+		for name, child := range props {
+			if !required[name] {
+				return false
+			}
+			if m, ok := child.(map[string]any); ok && !tightenSchema(m) {
+				return false
+			}
+		}
+		switch ap := node["additionalProperties"].(type) {
+		case nil:
+			node["additionalProperties"] = false
+		case bool:
+			if ap {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	if items, ok := node["items"].(map[string]any); ok && !tightenSchema(items) {
+		return false
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if list, ok := node[key].([]any); ok {
+			for _, alt := range list {
+				if m, ok := alt.(map[string]any); ok && !tightenSchema(m) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// ExtractJSON returns the JSON document in a model reply. It accepts bare
+// JSON, JSON in a markdown code fence, and JSON surrounded by prose.
+func ExtractJSON(text string) (json.RawMessage, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("empty response")
+	}
+	if json.Valid([]byte(text)) {
+		return json.RawMessage(text), nil
+	}
+
+	// Strip a markdown code fence
+	if i := strings.Index(text, "```"); i >= 0 {
+		inner := text[i+3:]
+		if nl := strings.IndexByte(inner, '\n'); nl >= 0 {
+			inner = inner[nl+1:]
+		}
+		if end := strings.Index(inner, "```"); end >= 0 {
+			if candidate := strings.TrimSpace(inner[:end]); json.Valid([]byte(candidate)) {
+				return json.RawMessage(candidate), nil
+			}
+		}
+	}
+
+	// Scan for the first balanced object or array that parses
+	for start := 0; start < len(text); start++ {
+		if text[start] != '{' && text[start] != '[' {
+			continue
+		}
+		if end := matchingBracket(text, start); end > start {
+			if candidate := text[start : end+1]; json.Valid([]byte(candidate)) {
+				return json.RawMessage(candidate), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no JSON document found in response")
+}
+
+// matchingBracket returns the index of the bracket closing the one at start,
+// skipping brackets inside strings, or -1.
+func matchingBracket(s string, start int) int {
+	depth := 0
+	inString := false
+	escaped := false
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		if escaped {
+			escaped = false
+			continue
+		}
+		if inString {
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// collectText returns an onDelta callback that accumulates into b.
+func collectText(b *strings.Builder) func(string) {
+	return func(delta string) { b.WriteString(delta) }
+}
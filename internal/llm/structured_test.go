@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"bare", `{"a": 1}`, `{"a": 1}`},
+		{"fenced", "Here you go:\n```json\n{\"a\": [1, 2]}\n```\nDone.", `{"a": [1, 2]}`},
+		{"prose", `Sure! {"summary": "uses {braces} in text", "n": 2} Hope that helps.`, `{"summary": "uses {braces} in text", "n": 2}`},
+		{"array", `Result: [{"x": "]"}]`, `[{"x": "]"}]`},
+		{"skips invalid", `{not json} then {"ok": true}`, `{"ok": true}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractJSON(tt.in)
+			if err != nil {
+				t.Fatalf("ExtractJSON: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ExtractJSON("no json here"); err == nil {
+		t.Error("expected error for text without JSON")
+	}
+}
+
+func TestStrictSchema(t *testing.T) {
+	schema := json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "summary": {"type": "string"},
+    "items": {"type": "array", "items": {
+      "type": "object",
+      "properties": {"n": {"type": ["number", "null"]}},
+      "required": ["n"]
+    }}
+  },
+  "required": ["summary", "items"]
+}`)
+	out, ok := strictSchema(schema)
+	if !ok {
+		t.Fatal("fully required schema should be strict-compatible")
+	}
+	var got map[string]any
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["additionalProperties"] != false {
+		t.Errorf("top-level additionalProperties = %v, want false", got["additionalProperties"])
+	}
+	item := got["properties"].(map[string]any)["items"].(map[string]any)["items"].(map[string]any)
+	if item["additionalProperties"] != false {
+		t.Errorf("nested additionalProperties = %v, want false", item["additionalProperties"])
+	}
+
+	optional := json.RawMessage(`{"type": "object", "properties": {"a": {"type": "string"}, "b": {"type": "string"}}, "required": ["a"]}`)
+	if out, ok := strictSchema(optional); ok || string(out) != string(optional) {
+		t.Errorf("schema with optional property: ok=%v, schema changed=%v", ok, string(out) != string(optional))
+	}
+
+	open := json.RawMessage(`{"type": "object", "properties": {"a": {"type": "string"}}, "required": ["a"], "additionalProperties": true}`)
+	if _, ok := strictSchema(open); ok {
+		t.Error("schema allowing additional properties should not be strict")
+	}
+}
@@ -523,6 +523,20 @@ func toolCallStatusString(s xai.ToolCallStatus) string {
 // SimpleMessage sends a single message without streaming (for summarization).
 // This is a simpler interface used for tasks like compaction/summarization.
 func (p *XAIProvider) SimpleMessage(ctx context.Context, userMessage, systemPrompt string) (string, error) {
+	return p.completeSimple(ctx, userMessage, systemPrompt, false)
+}
+
+// StructuredMessage returns a JSON document matching schema.
+// xAI's JSON mode guarantees JSON; the schema is checked locally.
+func (p *XAIProvider) StructuredMessage(ctx context.Context, userMessage, systemPrompt string, schema json.RawMessage) (json.RawMessage, error) {
+	return runStructured(ctx, p, StructuredModeJSON, schema, userMessage, systemPrompt,
+		func(ctx context.Context, msg, sys string) (string, error) {
+			return p.completeSimple(ctx, msg, sys, true)
+		})
+}
+
+// completeSimple performs a non-streaming completion, optionally in JSON mode.
+func (p *XAIProvider) completeSimple(ctx context.Context, userMessage, systemPrompt string, jsonMode bool) (string, error) {
 	// Get or create client
 	client, err := p.getClient()
 	if err != nil {
@@ -542,6 +556,10 @@ func (p *XAIProvider) SimpleMessage(ctx context.Context, userMessage, systemProm
 	// Add user message
 	req.UserMessage(xai.UserContent{Text: userMessage})
 
+	if jsonMode {
+		req.WithResponseFormat(xai.ResponseFormatJSON)
+	}
+
 	// No tools for simple messages (summarization doesn't need them)
 	// No reasoning effort for simple messages (keep it fast)
 
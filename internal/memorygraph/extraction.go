@@ -165,16 +165,32 @@ func normalizeContent(s string) string {
 }
 
 // callLLM calls the LLM provider for extraction
-func (e *Extractor) callLLM(ctx context.Context, prompt string) (string, error) {
-	// Use SimpleMessage for extraction (no tools, no streaming)
-	response, err := e.provider.SimpleMessage(ctx, prompt, extractionSystemPrompt)
-	if err != nil {
-		return "", err
-	}
-
-	return response, nil
+func (e *Extractor) callLLM(ctx context.Context, prompt string) (json.RawMessage, error) {
+	// Structured output: the provider enforces or validates extractionSchema
+	return e.provider.StructuredMessage(ctx, prompt, extractionSystemPrompt, extractionSchema)
 }
 
+// extractionSchema is the JSON schema for extraction responses
+var extractionSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "memories": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "content": {"type": "string", "minLength": 1},
+          "type": {"type": "string", "enum": ["identity", "fact", "preference", "decision", "event", "observation", "goal", "todo", "routine", "feedback", "anomaly", "correlation", "prediction"]},
+          "importance": {"type": "number", "minimum": 0, "maximum": 1},
+          "confidence": {"type": ["number", "null"], "minimum": 0, "maximum": 1}
+        },
+        "required": ["content", "type", "importance", "confidence"]
+      }
+    }
+  },
+  "required": ["memories"]
+}`)
+
 const extractionSystemPrompt = `You are a memory extraction assistant. Your task is to identify important information about the HUMAN USER that should be remembered long-term.
 
 IMPORTANT DISTINCTION:
@@ -200,14 +216,16 @@ Do NOT extract:
 - Sensitive information (passwords, API keys) unless explicitly asked to remember
 - Information that describes what the AI is or does
 
-If the text is primarily instructions FOR an AI assistant rather than information ABOUT a human, return an empty array [].
+If the text is primarily instructions FOR an AI assistant rather than information ABOUT a human, return an empty memories list.
 
 For each extracted memory, determine:
 1. content: A clear, standalone statement about the human (use "The user..." or their name)
 2. type: One of: identity, fact, preference, decision, event, observation, goal, todo, routine, feedback, anomaly, correlation, prediction
 3. importance: 0.0-1.0 based on how important this is to remember about the human
 
-Respond with a JSON array of extracted memories. If nothing worth extracting about the human, return an empty array [].`
+4. confidence: 0.0-1.0 for pattern types (routine, correlation, prediction, anomaly), null for all others
+
+Return the extracted memories in the memories list. If nothing is worth extracting about the human, return an empty list.`
 
 // buildExtractionPrompt creates the user prompt for extraction
 func buildExtractionPrompt(conversation string, sourceFile string, sourceType string) string {
@@ -258,36 +276,18 @@ File type hints:
 </content>
 
 Remember: Extract facts ABOUT the human, not instructions FOR the AI.
-If this is primarily AI configuration or instructions, return an empty array [].
-
-Respond with a JSON array of objects, each with: content, type, importance (0.0-1.0), and optionally confidence (0.0-1.0 for pattern types).
-Example: [{"content": "The user prefers dark mode", "type": "preference", "importance": 0.7}]`, sourceContext, conversation)
+If this is primarily AI configuration or instructions, return an empty memories list.`, sourceContext, conversation)
 }
 
-// parseExtractionResponse parses the LLM's JSON response
-func parseExtractionResponse(response string) ([]ExtractedMemory, error) {
-	// Clean up the response - find JSON array
-	response = strings.TrimSpace(response)
-	
-	// Try to find JSON array in response
-	start := strings.Index(response, "[")
-	end := strings.LastIndex(response, "]")
-	
-	if start == -1 || end == -1 || end < start {
-		// Try parsing as-is
-		var memories []ExtractedMemory
-		if err := json.Unmarshal([]byte(response), &memories); err != nil {
-			return nil, fmt.Errorf("no valid JSON array found in response")
-		}
-		return memories, nil
+// parseExtractionResponse decodes a schema-validated extraction document
+func parseExtractionResponse(doc json.RawMessage) ([]ExtractedMemory, error) {
+	var response struct {
+		Memories []ExtractedMemory `json:"memories"`
 	}
-
-	jsonStr := response[start : end+1]
-	
-	var memories []ExtractedMemory
-	if err := json.Unmarshal([]byte(jsonStr), &memories); err != nil {
+	if err := json.Unmarshal(doc, &response); err != nil {
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
+	memories := response.Memories
 
 	// Validate and fix up extracted memories
 	for i := range memories {
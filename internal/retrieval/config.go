@@ -0,0 +1,122 @@
+package retrieval
+
+import (
+	"fmt"
+
+	"github.com/roelfdiedericks/goclaw/internal/bus"
+	"github.com/roelfdiedericks/goclaw/internal/config/forms"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// Config configures pre-turn retrieval
+type Config struct {
+	Enabled        bool    `json:"enabled"`        // Inject retrieved context before each turn (default: false)
+	MaxResults     int     `json:"maxResults"`     // Top-K items injected (default: 5)
+	TokenBudget    int     `json:"tokenBudget"`    // Max tokens for the injected block (default: 1500)
+	MinScore       float64 `json:"minScore"`       // Minimum score for memory file and transcript hits (default: 0.35)
+	MinQueryLength int     `json:"minQueryLength"` // Skip retrieval for shorter messages, e.g. "ok" (default: 12)
+	Memory         bool    `json:"memory"`         // Search memory files (default: true)
+	MemoryGraph    bool    `json:"memoryGraph"`    // Search the memory graph (default: true)
+	Transcripts    bool    `json:"transcripts"`    // Search past conversations (default: true)
+}
+
+// DefaultConfig returns the default retrieval configuration (disabled)
+func DefaultConfig() Config {
+	return Config{
+		Enabled:        false,
+		MaxResults:     5,
+		TokenBudget:    1500,
+		MinScore:       0.35,
+		MinQueryLength: 12,
+		Memory:         true,
+		MemoryGraph:    true,
+		Transcripts:    true,
+	}
+}
+
+// withDefaults fills zero numeric values with defaults
+func (c Config) withDefaults() Config {
+	def := DefaultConfig()
+	if c.MaxResults <= 0 {
+		c.MaxResults = def.MaxResults
+	}
+	if c.TokenBudget <= 0 {
+		c.TokenBudget = def.TokenBudget
+	}
+	if c.MinScore <= 0 {
+		c.MinScore = def.MinScore
+	}
+	if c.MinQueryLength <= 0 {
+		c.MinQueryLength = def.MinQueryLength
+	}
+	return c
+}
+
+const configPath = "retrieval"
+
+// ConfigFormDef returns the form definition for Config
+func ConfigFormDef() forms.FormDef {
+	return forms.FormDef{
+		Title:       "Context Retrieval",
+		Description: "Automatically search memory before each turn and inject the best matches",
+		Sections: []forms.Section{
+			{
+				Title: "General",
+				Fields: []forms.Field{
+					{Name: "Enabled", Title: "Enabled", Type: forms.Toggle, Default: false, Desc: "Inject retrieved context before each turn"},
+					{Name: "MaxResults", Title: "Max Results", Type: forms.Number, Default: 5, Desc: "Maximum items injected per turn"},
+					{Name: "TokenBudget", Title: "Token Budget", Type: forms.Number, Default: 1500, Desc: "Maximum tokens for the injected block"},
+					{Name: "MinScore", Title: "Min Score", Type: forms.Number, Default: 0.35, Desc: "Minimum score for memory file and transcript matches (0-1)"},
+					{Name: "MinQueryLength", Title: "Min Message Length", Type: forms.Number, Default: 12, Desc: "Skip retrieval for shorter messages"},
+				},
+			},
+			{
+				Title: "Sources",
+				Fields: []forms.Field{
+					{Name: "Memory", Title: "Memory Files", Type: forms.Toggle, Default: true, Desc: "Search MEMORY.md and memory/*.md"},
+					{Name: "MemoryGraph", Title: "Memory Graph", Type: forms.Toggle, Default: true, Desc: "Search the memory graph"},
+					{Name: "Transcripts", Title: "Transcripts", Type: forms.Toggle, Default: true, Desc: "Search past conversations"},
+				},
+			},
+		},
+		Actions: []forms.ActionDef{
+			{Name: "apply", Label: "Apply"},
+		},
+	}
+}
+
+// RegisterCommands registers config commands for retrieval.
+func RegisterCommands() {
+	bus.RegisterCommand(configPath, "apply", handleApply)
+}
+
+// UnregisterCommands unregisters config commands.
+func UnregisterCommands() {
+	bus.UnregisterCommand(configPath, "apply")
+}
+
+// handleApply publishes the config.applied event for listeners to react
+func handleApply(cmd bus.Command) bus.CommandResult {
+	cfg, ok := cmd.Payload.(Config)
+	if !ok {
+		cfgPtr, okPtr := cmd.Payload.(*Config)
+		if okPtr {
+			cfg = *cfgPtr
+			ok = true
+		}
+	}
+	if !ok {
+		return bus.CommandResult{
+			Error:   fmt.Errorf("expected retrieval.Config, got %T", cmd.Payload),
+			Message: "invalid payload type",
+		}
+	}
+
+	L_info("retrieval: config applied", "enabled", cfg.Enabled, "maxResults", cfg.MaxResults)
+	bus.PublishEvent(configPath+".config.applied", cfg)
+
+	return bus.CommandResult{
+		Success: true,
+		Message: "Config applied",
+	}
+}
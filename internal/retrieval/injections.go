@@ -0,0 +1,108 @@
+package retrieval
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// InjectionLog records which items were injected into which run, so later
+// feedback (was the answer good, did the model use it) can be scored
+// against them.
+type InjectionLog struct {
+	db *sql.DB
+}
+
+// Injection is one injected item
+type Injection struct {
+	RunID      string
+	SessionKey string
+	UserID     string
+	Rank       int
+	Source     string
+	ItemID     string
+	Score      float64
+	Tokens     int
+	InjectedAt time.Time
+}
+
+// NewInjectionLog creates the injection table in db if needed
+func NewInjectionLog(db *sql.DB) (*InjectionLog, error) {
+	if _, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS retrieval_injections (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_id TEXT NOT NULL,
+			session_key TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			rank INTEGER NOT NULL,
+			source TEXT NOT NULL,
+			item_id TEXT NOT NULL,
+			score REAL NOT NULL,
+			tokens INTEGER NOT NULL,
+			injected_at INTEGER NOT NULL
+		)
+	`); err != nil {
+		return nil, fmt.Errorf("create retrieval_injections table: %w", err)
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_retrieval_injections_run ON retrieval_injections(run_id)`); err != nil {
+		return nil, fmt.Errorf("create idx_retrieval_injections_run: %w", err)
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_retrieval_injections_item ON retrieval_injections(source, item_id)`); err != nil {
+		return nil, fmt.Errorf("create idx_retrieval_injections_item: %w", err)
+	}
+	return &InjectionLog{db: db}, nil
+}
+
+// Record stores the items injected into a run
+func (l *InjectionLog) Record(ctx context.Context, runID, sessionKey, userID string, items []Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after commit
+
+	now := time.Now().UnixMilli()
+	for i, item := range items {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO retrieval_injections (run_id, session_key, user_id, rank, source, item_id, score, tokens, injected_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, runID, sessionKey, userID, i+1, item.Source, item.ID, item.Score, item.Tokens, now); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	L_debug("retrieval: injections recorded", "runID", runID, "items", len(items))
+	return nil
+}
+
+// ForRun returns the items injected into a run, in rank order
+func (l *InjectionLog) ForRun(ctx context.Context, runID string) ([]Injection, error) {
+	rows, err := l.db.QueryContext(ctx, `
+		SELECT run_id, session_key, user_id, rank, source, item_id, score, tokens, injected_at
+		FROM retrieval_injections WHERE run_id = ? ORDER BY rank
+	`, runID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Injection
+	for rows.Next() {
+		var inj Injection
+		var injectedAt int64
+		if err := rows.Scan(&inj.RunID, &inj.SessionKey, &inj.UserID, &inj.Rank, &inj.Source, &inj.ItemID, &inj.Score, &inj.Tokens, &injectedAt); err != nil {
+			return nil, err
+		}
+		inj.InjectedAt = time.UnixMilli(injectedAt)
+		out = append(out, inj)
+	}
+	return out, rows.Err()
+}
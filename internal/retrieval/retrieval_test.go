@@ -0,0 +1,82 @@
+package retrieval
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestFuseInterleavesAndDedupes(t *testing.T) {
+	memory := []Item{
+		{Source: SourceMemory, ID: "m1", Text: "Alice prefers the heater at 21 degrees in winter"},
+		{Source: SourceMemory, ID: "m2", Text: "The garage door code changed in March"},
+	}
+	graph := []Item{
+		{Source: SourceGraph, ID: "g1", Text: "alice prefers the heater at 21 degrees in winter."},
+		{Source: SourceGraph, ID: "g2", Text: "Bob is allergic to cats"},
+	}
+
+	fused := Fuse([][]Item{memory, graph})
+	var ids []string
+	for _, item := range fused {
+		ids = append(ids, item.ID)
+	}
+	if got := strings.Join(ids, ","); got != "m1,m2,g2" {
+		t.Fatalf("fused order = %s, want m1,m2,g2 (g1 duplicates m1)", got)
+	}
+}
+
+func TestSelectRespectsBudget(t *testing.T) {
+	items := []Item{
+		{ID: "big", Text: strings.Repeat("word ", 400)},
+		{ID: "a", Text: "short one"},
+		{ID: "b", Text: "short two"},
+		{ID: "c", Text: "short three"},
+	}
+	got := Select(items, 2, 100)
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "b" {
+		t.Fatalf("Select = %+v, want a,b (big skipped, capped at 2)", got)
+	}
+	if got[0].Tokens == 0 {
+		t.Error("selected items should carry token estimates")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if Format(nil) != "" {
+		t.Error("no items should produce no block")
+	}
+	block := Format([]Item{{Label: "memory file MEMORY.md#L1-3", Text: "likes tea"}})
+	if !strings.Contains(block, "<retrieved_context>") || !strings.Contains(block, "[1] memory file MEMORY.md#L1-3\nlikes tea") {
+		t.Fatalf("unexpected block:\n%s", block)
+	}
+}
+
+func TestInjectionLog(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	log, err := NewInjectionLog(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	items := []Item{{Source: SourceGraph, ID: "g1", Score: 0.5, Tokens: 10}, {Source: SourceMemory, ID: "m1", Score: 0.4, Tokens: 7}}
+	if err := log.Record(ctx, "run-1", "primary", "alice", items); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := log.ForRun(ctx, "run-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ItemID != "g1" || got[0].Rank != 1 || got[1].Source != SourceMemory {
+		t.Fatalf("ForRun = %+v", got)
+	}
+}
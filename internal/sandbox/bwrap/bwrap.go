@@ -10,6 +10,7 @@ import (
 	"runtime"
 
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox"
 )
 
 // Builder constructs bwrap command arguments using a fluent interface.
@@ -18,6 +19,7 @@ type Builder struct {
 	bwrapPath   string
 	command     string
 	commandArgs []string
+	limits      sandbox.Limits
 	err         error
 }
 
@@ -308,7 +310,7 @@ func (b *Builder) ShellCommand(cmd string) *Builder {
 }
 
 // Build returns the complete argument list for exec.Command.
-// Returns (bwrapPath, args, error).
+// Returns (path, args, error); path is bwrap, or the limit wrapper in front of it.
 func (b *Builder) Build() (string, []string, error) {
 	if b.err != nil {
 		return "", nil, b.err
@@ -331,7 +333,8 @@ func (b *Builder) Build() (string, []string, error) {
 	args = append(args, b.command)
 	args = append(args, b.commandArgs...)
 
-	return bwrapPath, args, nil
+	path, args := wrapLimits(b.limits, bwrapPath, args)
+	return path, args, nil
 }
 
 // BuildCommand builds and returns an exec.Cmd ready to run.
@@ -340,7 +343,7 @@ func (b *Builder) BuildCommand() (*exec.Cmd, error) {
 	if err != nil {
 		return nil, err
 	}
-	return exec.Command(bwrapPath, args...), nil //nolint:gosec // G204: bwrapPath validated by FindBwrap()
+	return exec.Command(bwrapPath, args...), nil //nolint:gosec // G204: bwrapPath validated by FindBwrap() (or a limit wrapper in front of it)
 }
 
 // FindBwrap locates the bwrap binary.
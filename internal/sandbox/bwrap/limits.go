@@ -0,0 +1,93 @@
+package bwrap
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox"
+)
+
+// Limits sets resource limits for the sandboxed process tree.
+// Applied in Build() by wrapping bwrap with prlimit and, when l.Cgroup is set,
+// systemd-run --scope. Both exec the next command, so the sandbox keeps its pid.
+func (b *Builder) Limits(l sandbox.Limits) *Builder {
+	b.limits = l
+	return b
+}
+
+// wrapLimits prefixes a bwrap invocation with the configured limit wrappers.
+// Missing helpers are logged and skipped rather than failing the command.
+func wrapLimits(l sandbox.Limits, bwrapPath string, args []string) (string, []string) {
+	if l.IsZero() {
+		return bwrapPath, args
+	}
+
+	var prefix []string
+	memory, procs := l.MemoryMB, l.MaxProcs
+
+	if l.Cgroup && (memory > 0 || procs > 0) {
+		if systemdRun, ok := findSystemdRun(); ok {
+			prefix = append(prefix, systemdRun, "--user", "--scope", "--quiet", "--collect")
+			if memory > 0 {
+				prefix = append(prefix, "-p", "MemoryMax="+strconv.Itoa(memory)+"M", "-p", "MemorySwapMax=0")
+			}
+			if procs > 0 {
+				prefix = append(prefix, "-p", "TasksMax="+strconv.Itoa(procs))
+			}
+			prefix = append(prefix, "--")
+			memory, procs = 0, 0 // enforced by the cgroup
+		} else {
+			L_warn("bwrap: cgroup limits unavailable (no systemd user session), using rlimits")
+		}
+	}
+
+	var rlimits []string
+	if memory > 0 {
+		rlimits = append(rlimits, "--as="+strconv.FormatInt(int64(memory)<<20, 10))
+	}
+	if l.CPUSeconds > 0 {
+		rlimits = append(rlimits, "--cpu="+strconv.Itoa(l.CPUSeconds))
+	}
+	if procs > 0 {
+		rlimits = append(rlimits, "--nproc="+strconv.Itoa(procs))
+	}
+	if l.MaxFileSizeMB > 0 {
+		rlimits = append(rlimits, "--fsize="+strconv.FormatInt(int64(l.MaxFileSizeMB)<<20, 10))
+	}
+	if len(rlimits) > 0 {
+		if prlimit, err := exec.LookPath("prlimit"); err == nil {
+			prefix = append(prefix, prlimit)
+			prefix = append(prefix, rlimits...)
+			prefix = append(prefix, "--")
+		} else {
+			L_warn("bwrap: prlimit not found, resource limits not applied", "limits", rlimits)
+		}
+	}
+
+	if len(prefix) == 0 {
+		return bwrapPath, args
+	}
+
+	L_debug("bwrap: applying resource limits", "wrapper", prefix)
+	wrapped := make([]string, 0, len(prefix)+len(args))
+	wrapped = append(wrapped, prefix[1:]...)
+	wrapped = append(wrapped, bwrapPath)
+	wrapped = append(wrapped, args...)
+	return prefix[0], wrapped
+}
+
+// findSystemdRun returns systemd-run if a systemd user manager is reachable.
+func findSystemdRun() (string, bool) {
+	path, err := exec.LookPath("systemd-run")
+	if err != nil {
+		return "", false
+	}
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" || !pathExists(filepath.Join(runtimeDir, "systemd")) {
+		return "", false
+	}
+	return path, true
+}
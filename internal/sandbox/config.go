@@ -15,9 +15,61 @@ const (
 	ModeHome      = "home"      // Full isolated home directory - everything persists
 )
 
+// Profile names with special meaning. Other keys are user role names ("owner", "user", "guest").
+const (
+	ProfileDefault = "default" // Fallback for roles without their own profile
+	ProfileBrowser = "browser" // Used for the sandboxed browser (shared by all users)
+)
+
 // Config holds top-level sandbox configuration.
 type Config struct {
-	Bubblewrap BubblewrapConfig `json:"bubblewrap"`
+	Bubblewrap BubblewrapConfig   `json:"bubblewrap"`
+	Profiles   map[string]Profile `json:"profiles,omitempty"` // Per-role resource limits and egress policy
+}
+
+// Profile holds the resource limits and network egress policy applied to
+// sandboxed commands run on behalf of a role.
+type Profile struct {
+	Limits Limits       `json:"limits"`
+	Egress EgressPolicy `json:"egress"`
+}
+
+// Limits holds resource limits for sandboxed processes. Zero means unlimited.
+// Limits are applied as rlimits (via prlimit); with Cgroup set, memory and
+// process counts are enforced by a cgroup v2 scope instead (via systemd-run).
+type Limits struct {
+	MemoryMB      int  `json:"memoryMB"`      // Address space (rlimit) or memory.max (cgroup)
+	CPUSeconds    int  `json:"cpuSeconds"`    // CPU time per process
+	MaxProcs      int  `json:"maxProcs"`      // pids.max (cgroup) or RLIMIT_NPROC (counts all processes of the user)
+	MaxFileSizeMB int  `json:"maxFileSizeMB"` // Largest file a process may write
+	Cgroup        bool `json:"cgroup"`        // Use a cgroup v2 scope for memory and pids (needs systemd user session)
+}
+
+// IsZero reports whether no limit is set.
+func (l Limits) IsZero() bool {
+	return l.MemoryMB <= 0 && l.CPUSeconds <= 0 && l.MaxProcs <= 0 && l.MaxFileSizeMB <= 0
+}
+
+// EgressPolicy restricts outbound network access from sandboxed commands.
+// When enabled, exec sandboxes get no network of their own and reach a local
+// filtering proxy only through a bound unix socket, relayed to loopback and
+// injected as HTTP(S)_PROXY.
+type EgressPolicy struct {
+	Enabled      bool     `json:"enabled"`
+	Allow        []string `json:"allow"`        // Domain patterns: "example.com", "*.example.com", "*"
+	AllowPrivate bool     `json:"allowPrivate"` // Allow private, loopback and cloud metadata addresses (default: denied)
+}
+
+// Profile returns the profile for a role, falling back to the "default"
+// profile. The returned name is the profile actually used ("" if none).
+func (c *Config) Profile(role string) (string, Profile) {
+	if p, ok := c.Profiles[role]; ok && role != "" {
+		return role, p
+	}
+	if p, ok := c.Profiles[ProfileDefault]; ok {
+		return ProfileDefault, p
+	}
+	return "", Profile{}
 }
 
 // BubblewrapConfig holds global bubblewrap settings shared by all sandboxed tools.
@@ -122,7 +174,11 @@ func handleApply(cmd bus.Command) bus.CommandResult {
 		}
 	}
 
-	L_info("sandbox: config applied", "bwrapPath", cfg.Bubblewrap.Path, "volumes", len(cfg.Bubblewrap.Volumes))
+	if instance != nil {
+		instance.setProfiles(cfg.Profiles)
+	}
+
+	L_info("sandbox: config applied", "bwrapPath", cfg.Bubblewrap.Path, "volumes", len(cfg.Bubblewrap.Volumes), "profiles", len(cfg.Profiles))
 	bus.PublishEvent(configPath+".config.applied", cfg)
 
 	return bus.CommandResult{Success: true, Message: "Config applied"}
@@ -0,0 +1,371 @@
+// Package egress provides the filtering HTTP(S) proxy that sandboxed commands
+// reach the network through when their profile has an egress allowlist.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/roelfdiedericks/goclaw/internal/audit"
+	"github.com/roelfdiedericks/goclaw/internal/browser"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox"
+)
+
+// Proxy is a local forward proxy enforcing one profile's egress policy.
+// Plain HTTP requests are forwarded; HTTPS goes through CONNECT tunnels.
+// It listens on a loopback port (for the browser) and on a unix socket that
+// is bound into network-less exec sandboxes.
+type Proxy struct {
+	profile  string
+	listener net.Listener
+	socket   string // unix socket path; its directory is removed on Close
+	server   *http.Server
+	dialer   *net.Dialer
+
+	mu     sync.RWMutex
+	policy sandbox.EgressPolicy
+}
+
+var (
+	proxies   = make(map[string]*Proxy)
+	proxiesMu sync.Mutex
+)
+
+// ProxyURL returns the URL of the running proxy for a profile, starting it
+// on first use. A changed policy is applied to the running proxy.
+func ProxyURL(profile string, policy sandbox.EgressPolicy) (string, error) {
+	p, err := proxyFor(profile, policy)
+	if err != nil {
+		return "", err
+	}
+	return p.URL(), nil
+}
+
+// ProxySocket returns the unix socket path of the running proxy for a
+// profile, starting it on first use.
+func ProxySocket(profile string, policy sandbox.EgressPolicy) (string, error) {
+	p, err := proxyFor(profile, policy)
+	if err != nil {
+		return "", err
+	}
+	return p.SocketPath(), nil
+}
+
+func proxyFor(profile string, policy sandbox.EgressPolicy) (*Proxy, error) {
+	proxiesMu.Lock()
+	defer proxiesMu.Unlock()
+
+	if p, ok := proxies[profile]; ok {
+		p.SetPolicy(policy)
+		return p, nil
+	}
+
+	p, err := Start(profile, policy)
+	if err != nil {
+		return nil, err
+	}
+	proxies[profile] = p
+	return p, nil
+}
+
+// CloseAll stops every proxy started by ProxyURL.
+func CloseAll() {
+	proxiesMu.Lock()
+	defer proxiesMu.Unlock()
+	for name, p := range proxies {
+		p.Close()
+		delete(proxies, name)
+	}
+}
+
+// Start starts a proxy for a profile on a random loopback port and on a
+// unix socket in a private temporary directory.
+func Start(profile string, policy sandbox.EgressPolicy) (*Proxy, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("egress proxy: failed to listen: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "goclaw-egress-")
+	if err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("egress proxy: failed to create socket dir: %w", err)
+	}
+	socket := filepath.Join(dir, "proxy.sock")
+	unixLn, err := net.Listen("unix", socket)
+	if err != nil {
+		ln.Close()
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("egress proxy: failed to listen on socket: %w", err)
+	}
+
+	p := &Proxy{
+		profile:  profile,
+		listener: ln,
+		socket:   socket,
+		policy:   policy,
+	}
+	p.dialer = &net.Dialer{Timeout: 30 * time.Second, Control: p.checkDialAddr}
+	p.server = &http.Server{
+		Handler:           p,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+
+	for _, l := range []net.Listener{ln, unixLn} {
+		go func(l net.Listener) {
+			if err := p.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				L_error("egress proxy: serve failed", "profile", profile, "error", err)
+			}
+		}(l)
+	}
+
+	L_info("egress proxy: started", "profile", profile, "addr", ln.Addr().String(), "socket", socket, "allow", policy.Allow)
+	return p, nil
+}
+
+// URL returns the proxy URL for HTTP_PROXY / HTTPS_PROXY.
+func (p *Proxy) URL() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+// SocketPath returns the proxy's unix socket path.
+func (p *Proxy) SocketPath() string {
+	return p.socket
+}
+
+// SetPolicy replaces the proxy's policy.
+func (p *Proxy) SetPolicy(policy sandbox.EgressPolicy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.policy = policy
+}
+
+// Close stops the proxy. Open tunnels are closed by their peers.
+func (p *Proxy) Close() {
+	p.server.Close()                     //nolint:errcheck // shutdown cleanup
+	os.RemoveAll(filepath.Dir(p.socket)) //nolint:errcheck // shutdown cleanup
+}
+
+func (p *Proxy) currentPolicy() sandbox.EgressPolicy {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.policy
+}
+
+// ServeHTTP handles CONNECT tunnels and absolute-URI HTTP requests.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.handleConnect(w, r)
+		return
+	}
+	if r.URL.Host == "" {
+		http.Error(w, "goclaw egress proxy: absolute URL required", http.StatusBadRequest)
+		return
+	}
+	p.handleHTTP(w, r)
+}
+
+func (p *Proxy) handleConnect(w http.ResponseWriter, r *http.Request) {
+	host, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		http.Error(w, "goclaw egress proxy: invalid CONNECT target", http.StatusBadRequest)
+		return
+	}
+	if reason := p.check(host, port, "https"); reason != "" {
+		p.deny(w, host, port, reason)
+		return
+	}
+
+	upstream, err := p.dialer.DialContext(r.Context(), "tcp", r.Host)
+	if err != nil {
+		p.dialFailed(w, host, port, err)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "goclaw egress proxy: hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buf, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+
+	L_debug("egress proxy: tunnel opened", "profile", p.profile, "host", host, "port", port)
+
+	// Bytes the client sent after the CONNECT request are already buffered
+	if n := buf.Reader.Buffered(); n > 0 {
+		pending, _ := buf.Reader.Peek(n)
+		if _, err := upstream.Write(pending); err != nil {
+			client.Close()
+			upstream.Close()
+			return
+		}
+	}
+
+	go func() {
+		io.Copy(upstream, client) //nolint:errcheck // tunnel ends on either side closing
+		upstream.Close()
+	}()
+	io.Copy(client, upstream) //nolint:errcheck // tunnel ends on either side closing
+	client.Close()
+}
+
+func (p *Proxy) handleHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Scheme != "http" {
+		http.Error(w, "goclaw egress proxy: only http:// requests can be proxied directly", http.StatusBadRequest)
+		return
+	}
+	host, port := r.URL.Hostname(), r.URL.Port()
+	if port == "" {
+		port = "80"
+	}
+	if reason := p.check(host, port, "http"); reason != "" {
+		p.deny(w, host, port, reason)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.RequestURI = ""
+	removeHopHeaders(out.Header)
+
+	transport := &http.Transport{
+		DialContext:           p.dialer.DialContext,
+		ResponseHeaderTimeout: 60 * time.Second,
+	}
+	defer transport.CloseIdleConnections()
+
+	resp, err := transport.RoundTrip(out)
+	if err != nil {
+		p.dialFailed(w, host, port, err)
+		return
+	}
+	defer resp.Body.Close()
+
+	removeHopHeaders(resp.Header)
+	for k, vv := range resp.Header {
+		for _, v := range vv {
+			w.Header().Add(k, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body) //nolint:errcheck // client may disconnect
+}
+
+// check returns a reason if the destination is not allowed, "" if it is.
+func (p *Proxy) check(host, port, scheme string) string {
+	policy := p.currentPolicy()
+	if !MatchAllowed(policy.Allow, host) {
+		return "host not in egress allowlist"
+	}
+	if policy.AllowPrivate {
+		return ""
+	}
+	target := &url.URL{Scheme: scheme, Host: net.JoinHostPort(host, port)}
+	if err := browser.ValidateURLSafety(target.String()); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// checkDialAddr re-checks the address actually dialed, so a hostname that
+// re-resolves to a private address after check() is still refused.
+func (p *Proxy) checkDialAddr(network, address string, _ syscall.RawConn) error {
+	if p.currentPolicy().AllowPrivate {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	target := &url.URL{Scheme: "http", Host: net.JoinHostPort(host, port)}
+	return browser.ValidateURLSafety(target.String())
+}
+
+func (p *Proxy) deny(w http.ResponseWriter, host, port, reason string) {
+	L_warn("egress proxy: blocked", "profile", p.profile, "host", host, "port", port, "reason", reason)
+	audit.Record(context.Background(), audit.Event{
+		Type:  "sandbox.egress.blocked",
+		Actor: "system",
+		Inputs: map[string]any{
+			"profile": p.profile,
+			"host":    host,
+			"port":    port,
+		},
+		Error: reason,
+	})
+	http.Error(w, "goclaw egress proxy: "+host+" blocked: "+reason, http.StatusForbidden)
+}
+
+func (p *Proxy) dialFailed(w http.ResponseWriter, host, port string, err error) {
+	var safetyErr *browser.URLSafetyError
+	if errors.As(err, &safetyErr) {
+		p.deny(w, host, port, safetyErr.Error())
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	L_debug("egress proxy: upstream failed", "profile", p.profile, "host", host, "port", port, "error", err)
+	http.Error(w, "goclaw egress proxy: "+err.Error(), http.StatusBadGateway)
+}
+
+// MatchAllowed reports whether host matches any allowlist pattern.
+// "example.com" matches only that host, "*.example.com" any subdomain of it,
+// and "*" everything.
+func MatchAllowed(patterns []string, host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), ".")
+		switch {
+		case pattern == "*":
+			return true
+		case strings.HasPrefix(pattern, "*."):
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+		case pattern == host:
+			return true
+		}
+	}
+	return false
+}
+
+// hopHeaders are connection-level headers a proxy must not forward.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+func removeHopHeaders(h http.Header) {
+	for _, k := range hopHeaders {
+		h.Del(k)
+	}
+}
@@ -0,0 +1,155 @@
+package egress
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/roelfdiedericks/goclaw/internal/sandbox"
+)
+
+func TestMatchAllowed(t *testing.T) {
+	patterns := []string{"example.com", "*.github.com"}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"example.com", true},
+		{"EXAMPLE.com.", true},
+		{"www.example.com", false},
+		{"api.github.com", true},
+		{"github.com", false},
+		{"evilgithub.com", false},
+		{"other.org", false},
+	}
+	for _, tt := range tests {
+		if got := MatchAllowed(patterns, tt.host); got != tt.want {
+			t.Errorf("MatchAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	if !MatchAllowed([]string{"*"}, "anything.test") {
+		t.Error("wildcard should match everything")
+	}
+	if MatchAllowed(nil, "example.com") {
+		t.Error("empty allowlist should match nothing")
+	}
+}
+
+func proxyClient(t *testing.T, p *Proxy) *http.Client {
+	t.Helper()
+	proxyURL, err := url.Parse(p.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+}
+
+func TestProxyAllowlist(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello") //nolint:errcheck
+	}))
+	defer upstream.Close()
+
+	p, err := Start("test", sandbox.EgressPolicy{Enabled: true, Allow: []string{"127.0.0.1"}, AllowPrivate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	client := proxyClient(t, p)
+
+	resp, err := client.Get(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Fatalf("allowed request: status %d body %q", resp.StatusCode, body)
+	}
+
+	p.SetPolicy(sandbox.EgressPolicy{Enabled: true, Allow: []string{"example.com"}, AllowPrivate: true})
+	resp, err = client.Get(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("host outside allowlist: status %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestProxyDeniesPrivateRanges(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+
+	p, err := Start("test", sandbox.EgressPolicy{Enabled: true, Allow: []string{"*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	client := proxyClient(t, p)
+
+	// Plain HTTP to loopback
+	resp, err := client.Get("http://127.0.0.1:1/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("loopback http: status %d, want 403", resp.StatusCode)
+	}
+
+	// CONNECT to loopback fails at the tunnel
+	if _, err := client.Get(upstream.URL); err == nil {
+		t.Fatal("expected CONNECT to loopback to be refused")
+	}
+}
+
+func TestRelayThroughSocket(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello") //nolint:errcheck
+	}))
+	defer upstream.Close()
+
+	p, err := Start("test", sandbox.EgressPolicy{Enabled: true, Allow: []string{"127.0.0.1"}, AllowPrivate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go ServeRelay(ln, p.SocketPath())
+
+	relayURL, _ := url.Parse("http://" + ln.Addr().String())
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(relayURL)}}
+
+	resp, err := client.Get(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Fatalf("relayed request: status %d body %q", resp.StatusCode, body)
+	}
+
+	// The allowlist still applies behind the relay
+	p.SetPolicy(sandbox.EgressPolicy{Enabled: true, Allow: []string{"example.com"}, AllowPrivate: true})
+	resp, err = client.Get(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("relayed host outside allowlist: status %d, want 403", resp.StatusCode)
+	}
+}
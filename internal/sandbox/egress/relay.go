@@ -0,0 +1,105 @@
+package egress
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+)
+
+// Paths inside an exec sandbox whose egress is filtered. The sandbox has its
+// own network namespace with only loopback; the goclaw binary and the proxy
+// socket are bound in, and the relay exposes the socket on loopback.
+const (
+	RelayBinary = "/run/goclaw/goclaw"
+	RelaySocket = "/run/goclaw/egress.sock"
+	RelayListen = "127.0.0.1:3128"
+)
+
+// RelayArgs returns the command line that runs argv behind the relay, for
+// use as the sandbox command.
+func RelayArgs(argv ...string) []string {
+	args := []string{"egress-relay", "--socket", RelaySocket, "--listen", RelayListen, "--"}
+	return append(args, argv...)
+}
+
+// Relay listens on a TCP address and forwards every connection to the proxy
+// socket, runs argv with the caller's stdio and returns its exit code.
+// Signals received by the relay are passed on to the command.
+func Relay(socket, listen string, argv []string) int {
+	if len(argv) == 0 {
+		fmt.Fprintln(os.Stderr, "egress-relay: no command")
+		return 2
+	}
+
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "egress-relay: %v\n", err)
+		return 1
+	}
+	defer ln.Close()
+	go ServeRelay(ln, socket)
+
+	cmd := exec.Command(argv[0], argv[1:]...) //nolint:gosec // G204: runs the sandboxed command it was given
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "egress-relay: %v\n", err)
+		return 127
+	}
+
+	sigs := make(chan os.Signal, 4)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	go func() {
+		for sig := range sigs {
+			cmd.Process.Signal(sig) //nolint:errcheck // process may have exited
+		}
+	}()
+
+	err = cmd.Wait()
+	signal.Stop(sigs)
+	close(sigs)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		return exitErr.ExitCode()
+	}
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+// ServeRelay accepts connections on ln and pipes each one to the unix socket
+// until ln is closed.
+func ServeRelay(ln net.Listener, socket string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			upstream, err := net.Dial("unix", socket)
+			if err != nil {
+				return
+			}
+			defer upstream.Close()
+			go func() {
+				io.Copy(upstream, conn) //nolint:errcheck // relay ends on either side closing
+				if uc, ok := upstream.(*net.UnixConn); ok {
+					uc.CloseWrite() //nolint:errcheck // half-close so the proxy sees EOF
+				}
+			}()
+			io.Copy(conn, upstream) //nolint:errcheck // relay ends on either side closing
+		}()
+	}
+}
@@ -29,6 +29,7 @@ type Manager struct {
 	volumes       []SandboxVolume
 	protectedDirs map[string]string // relative -> absolute
 	extraPaths    []string
+	agentRoots    []string // Additional workspace roots (named agents with their own workspace)
 }
 
 var (
@@ -204,6 +205,20 @@ func (m *Manager) GetVolumes() []SandboxVolume {
 	return result
 }
 
+// Profile returns the sandbox profile for a role (see Config.Profile).
+func (m *Manager) Profile(role string) (string, Profile) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config.Profile(role)
+}
+
+// setProfiles replaces the per-role profiles (applied live from config).
+func (m *Manager) setProfiles(profiles map[string]Profile) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config.Profiles = profiles
+}
+
 // GetHomeDir returns the sandbox home backing directory, or empty if not in home mode.
 func (m *Manager) GetHomeDir() string {
 	m.mu.RLock()
@@ -223,6 +238,65 @@ func (m *Manager) GetWorkspaceRoot() string {
 	return m.workspaceRoot
 }
 
+// RegisterWorkspaceRoot registers a named agent's own workspace. File tools
+// working in it are confined to it (see ValidatePath).
+func (m *Manager) RegisterWorkspaceRoot(dir string) error {
+	absRoot, err := filepath.Abs(expandHomePath(dir))
+	if err != nil {
+		return fmt.Errorf("resolve workspace root: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.agentRoots {
+		if existing == absRoot {
+			return nil
+		}
+	}
+	m.agentRoots = append(m.agentRoots, absRoot)
+	L_debug("sandbox: registered workspace root", "path", absRoot)
+	return nil
+}
+
+// workspaceRootFor returns the workspace root a caller working in workingDir
+// may access: the most specific registered root containing it. Agents with
+// their own workspace get only that root, never the main workspace or
+// another agent's. Anything else gets the main workspace.
+func (m *Manager) workspaceRootFor(workingDir string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	dir := filepath.Clean(workingDir)
+	best := filepath.Clean(m.workspaceRoot)
+	for _, root := range m.agentRoots {
+		if isWithin(root, dir) && (!isWithin(best, dir) || len(root) > len(best)) {
+			best = root
+		}
+	}
+	return best
+}
+
+// otherAgentRoot returns the agent workspace nested inside root that
+// contains path ("" if none). The main workspace may hold agent
+// workspaces, which stay off-limits to callers using the main root.
+func (m *Manager) otherAgentRoot(root, path string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, agentRoot := range m.agentRoots {
+		if agentRoot != root && isWithin(root, agentRoot) && isWithin(agentRoot, path) {
+			return agentRoot
+		}
+	}
+	return ""
+}
+
+// isWithin reports whether path is root or below it.
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && !strings.HasPrefix(rel, "..") && !filepath.IsAbs(rel)
+}
+
 // IsRegisteredVolume checks if a home path is covered by sandbox isolation.
 // In home mode, any ~/path is safe. In volumes mode, only listed volumes.
 func (m *Manager) IsRegisteredVolume(path string) bool {
@@ -79,7 +79,9 @@ func expandSandboxPath(filePath string, sandboxHomeDir string) string {
 
 // ValidatePath validates that a path is within allowed roots and contains no symlinks.
 // In "home" mode, ~ paths are expanded to the sandbox home directory and validated against it.
-// Workspace paths are validated against the workspace root.
+// Workspace paths are validated against the caller's workspace root: workingDir
+// is the agent workspace from the session context, so a named agent with its
+// own workspace can't reach the main workspace or another agent's.
 func (m *Manager) ValidatePath(inputPath, workingDir string) (string, error) {
 	expanded := expandSandboxPath(inputPath, m.homeDir)
 
@@ -91,14 +93,18 @@ func (m *Manager) ValidatePath(inputPath, workingDir string) (string, error) {
 	}
 
 	// Determine which root to validate against
-	// In home mode, paths under homeDir are valid; paths under workspaceRoot are also valid
-	workspaceResolved := filepath.Clean(m.workspaceRoot)
+	// In home mode, paths under homeDir are valid; paths under the caller's workspace root are also valid
+	workspaceResolved := m.workspaceRootFor(workingDir)
 	homeResolved := ""
 	if m.homeDir != "" {
 		homeResolved = filepath.Clean(m.homeDir)
 	}
 
-	// Try validating against workspace root first
+	if other := m.otherAgentRoot(workspaceResolved, resolved); other != "" {
+		L_warn("sandbox: path in another agent's workspace", "path", inputPath, "resolved", resolved, "workspace", other)
+		return "", fmt.Errorf("path escapes sandbox root (%s): %s", m.shortPath(workspaceResolved), inputPath)
+	}
+
 	relative, err := filepath.Rel(workspaceResolved, resolved)
 	rootUsed := workspaceResolved
 	if err != nil || strings.HasPrefix(relative, "..") || filepath.IsAbs(relative) {
@@ -240,7 +246,7 @@ func (m *Manager) ValidateWritePath(inputPath, workingDir string) (string, error
 		return "", err
 	}
 
-	rootResolved := filepath.Clean(m.workspaceRoot)
+	rootResolved := m.workspaceRootFor(workingDir)
 	relative, _ := filepath.Rel(rootResolved, resolved)
 
 	if m.IsPathProtected(relative) {
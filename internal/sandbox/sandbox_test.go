@@ -0,0 +1,52 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidatePathAgentIsolation(t *testing.T) {
+	main := t.TempDir()
+	agentA := filepath.Join(t.TempDir(), "a")
+	agentB := filepath.Join(main, "agents", "b") // Nested in the main workspace
+	for _, dir := range []string{agentA, agentB} {
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "notes.md"), []byte("secret"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(main, "MEMORY.md"), []byte("main"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Manager{workspaceRoot: main, protectedDirs: make(map[string]string)}
+	for _, dir := range []string{agentA, agentB} {
+		if err := m.RegisterWorkspaceRoot(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name, path, workingDir string
+		ok                     bool
+	}{
+		{"own file", "notes.md", agentA, true},
+		{"other agent", filepath.Join(agentB, "notes.md"), agentA, false},
+		{"main workspace", filepath.Join(main, "MEMORY.md"), agentA, false},
+		{"nested agent reaching main", "../../MEMORY.md", agentB, false},
+		{"nested agent own file", "notes.md", agentB, true},
+		{"main reaching nested agent", "agents/b/notes.md", main, false},
+		{"main own file", "MEMORY.md", main, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := m.ReadFile(tt.path, tt.workingDir)
+			if (err == nil) != tt.ok {
+				t.Errorf("ReadFile(%q) in %s: err = %v, want ok %v", tt.path, tt.workingDir, err, tt.ok)
+			}
+		})
+	}
+}
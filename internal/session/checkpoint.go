@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -187,118 +188,62 @@ func (g *CheckpointGenerator) Generate(ctx context.Context, sess *Session, sessi
 	return nil
 }
 
-// CheckpointPrompt is the prompt template for generating structured summaries
+// CheckpointPrompt is the prompt template for generating structured summaries.
+// The output shape is enforced by CheckpointSchema via StructuredMessage.
 const CheckpointPrompt = `Analyze this conversation and provide a structured summary for context preservation.
 
-Return a JSON object with this exact structure:
-{
-  "summary": "A comprehensive 2-4 paragraph summary of the conversation so far",
-  "topics": ["topic1", "topic2", ...],
-  "keyDecisions": ["decision1", "decision2", ...],
-  "openQuestions": ["question1", "question2", ...]
-}
-
 Guidelines:
-- summary: Cover the main discussion points, what was accomplished, and current context
+- summary: A comprehensive 2-4 paragraph summary covering the main discussion points, what was accomplished, and current context
 - topics: List 3-7 main topics discussed
 - keyDecisions: List important decisions or conclusions reached (if any)
-- openQuestions: List unresolved questions or pending items (if any)
-
-Respond ONLY with the JSON object, no other text.`
-
-// ParseCheckpointResponse parses the LLM response into CheckpointData
-func ParseCheckpointResponse(response string) (*CheckpointData, error) {
+- openQuestions: List unresolved questions or pending items (if any)`
+
+// CheckpointSchema is the JSON schema for checkpoint and compaction summaries.
+var CheckpointSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "summary": {"type": "string", "minLength": 1},
+    "topics": {"type": "array", "items": {"type": "string"}},
+    "keyDecisions": {"type": "array", "items": {"type": "string"}},
+    "openQuestions": {"type": "array", "items": {"type": "string"}}
+  },
+  "required": ["summary", "topics", "keyDecisions", "openQuestions"]
+}`)
+
+// checkpointSystemPrompt is the system prompt for structured summaries
+const checkpointSystemPrompt = "You are a helpful assistant that creates structured conversation summaries."
+
+// parseCheckpointDocument decodes a schema-validated checkpoint document
+func parseCheckpointDocument(doc string) (*CheckpointData, error) {
 	var data CheckpointData
-	if err := json.Unmarshal([]byte(response), &data); err != nil {
-		// Try to extract JSON from response if it has extra text
-		start := findJSONStart(response)
-		end := findJSONEnd(response, start)
-		if start >= 0 && end > start {
-			jsonStr := response[start : end+1]
-			if err := json.Unmarshal([]byte(jsonStr), &data); err != nil {
-				// JSON extraction failed - fall back to using raw response as summary
-				L_info("checkpoint: JSON extraction failed, using raw response as summary",
-					"jsonError", err,
-					"responseLen", len(response))
-				return &CheckpointData{
-					Summary: truncateForLog(response, 4000),
-				}, nil
-			}
-			L_debug("checkpoint: extracted JSON from mixed response", "jsonStart", start)
-		} else {
-			// No JSON found - model probably returned plain text summary
-			// Use it as-is rather than failing
-			L_info("checkpoint: no JSON found, using raw response as summary",
-				"responseLen", len(response))
-			return &CheckpointData{
-				Summary: truncateForLog(response, 4000),
-			}, nil
-		}
+	if err := json.Unmarshal([]byte(doc), &data); err != nil {
+		return nil, err
 	}
 	return &data, nil
 }
 
-// truncateForLog truncates a string for logging, adding ellipsis if needed
-func truncateForLog(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen] + "..."
-}
+// FormatCheckpointSummary renders checkpoint data as the plain-text summary
+// used for compaction.
+func FormatCheckpointSummary(cp *CheckpointData) string {
+	var parts []string
 
-// findJSONStart finds the start of a JSON object in a string
-func findJSONStart(s string) int {
-	for i, c := range s {
-		if c == '{' {
-			return i
-		}
+	if cp.Summary != "" {
+		parts = append(parts, cp.Summary)
 	}
-	return -1
-}
 
-// findJSONEnd finds the end of a JSON object starting from the given position
-func findJSONEnd(s string, start int) int {
-	if start < 0 || start >= len(s) {
-		return -1
+	if len(cp.Topics) > 0 {
+		parts = append(parts, fmt.Sprintf("\nTopics discussed: %s", strings.Join(cp.Topics, ", ")))
 	}
 
-	depth := 0
-	inString := false
-	escaped := false
-
-	for i := start; i < len(s); i++ {
-		c := s[i]
-
-		if escaped {
-			escaped = false
-			continue
-		}
-
-		if c == '\\' && inString {
-			escaped = true
-			continue
-		}
-
-		if c == '"' {
-			inString = !inString
-			continue
-		}
-
-		if inString {
-			continue
-		}
+	if len(cp.KeyDecisions) > 0 {
+		parts = append(parts, fmt.Sprintf("\nKey decisions:\n- %s", strings.Join(cp.KeyDecisions, "\n- ")))
+	}
 
-		if c == '{' {
-			depth++
-		} else if c == '}' {
-			depth--
-			if depth == 0 {
-				return i
-			}
-		}
+	if len(cp.OpenQuestions) > 0 {
+		parts = append(parts, fmt.Sprintf("\nOpen questions:\n- %s", strings.Join(cp.OpenQuestions, "\n- ")))
 	}
 
-	return -1
+	return strings.Join(parts, "\n")
 }
 
 // BuildMessagesForCheckpoint builds a condensed message list for checkpoint generation
@@ -408,12 +353,12 @@ func BuildMessagesForSummary(messages []Message, maxTokens int) string {
 	return result
 }
 
-// CompactionSummaryPrompt is the prompt for generating compaction summaries
-const CompactionSummaryPrompt = `Summarize this conversation for context preservation. Include:
-1. Main topics discussed
-2. Key decisions or conclusions
-3. Current state/context
-4. Any pending items or open questions
+// CompactionSummaryPrompt is the prompt for generating compaction summaries.
+// Uses CheckpointSchema; the result is rendered with FormatCheckpointSummary.
+const CompactionSummaryPrompt = `Summarize this conversation for context preservation.
 
-Keep the summary concise but comprehensive (max 2000 tokens).
-Format as plain text, not JSON.`
+Guidelines:
+- summary: Main discussion points and current state/context, concise but comprehensive (max 2000 tokens)
+- topics: Main topics discussed
+- keyDecisions: Key decisions or conclusions (if any)
+- openQuestions: Pending items or open questions (if any)`
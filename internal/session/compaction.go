@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -538,25 +537,7 @@ func (m *CompactionManager) retryPendingSummary(ctx context.Context) {
 
 // buildSummaryFromCheckpoint builds a summary string from a checkpoint record
 func (m *CompactionManager) buildSummaryFromCheckpoint(cp *CheckpointRecord) string {
-	var parts []string
-
-	if cp.Checkpoint.Summary != "" {
-		parts = append(parts, cp.Checkpoint.Summary)
-	}
-
-	if len(cp.Checkpoint.Topics) > 0 {
-		parts = append(parts, fmt.Sprintf("\nTopics discussed: %s", strings.Join(cp.Checkpoint.Topics, ", ")))
-	}
-
-	if len(cp.Checkpoint.KeyDecisions) > 0 {
-		parts = append(parts, fmt.Sprintf("\nKey decisions:\n- %s", strings.Join(cp.Checkpoint.KeyDecisions, "\n- ")))
-	}
-
-	if len(cp.Checkpoint.OpenQuestions) > 0 {
-		parts = append(parts, fmt.Sprintf("\nOpen questions:\n- %s", strings.Join(cp.Checkpoint.OpenQuestions, "\n- ")))
-	}
-
-	return strings.Join(parts, "\n")
+	return FormatCheckpointSummary(&cp.Checkpoint)
 }
 
 // extractFileDetails extracts read/modified files from session messages
@@ -42,10 +42,9 @@ func GenerateCheckpointWithClient(ctx context.Context, client SummarizationClien
 		conversationText := BuildMessagesForSummary(messages, currentLimit)
 
 		userMessage := fmt.Sprintf("%s\n\nConversation to summarize:\n%s", CheckpointPrompt, conversationText)
-		systemPrompt := "You are a helpful assistant that creates structured conversation summaries. Respond only with valid JSON."
 
 		// Call LLM
-		responseText, err := client.SimpleMessage(ctx, userMessage, systemPrompt)
+		doc, err := client.StructuredMessage(ctx, userMessage, checkpointSystemPrompt, CheckpointSchema)
 		if err != nil {
 			// Check if this is a context overflow - retry with reduced limit
 			if llm.IsContextOverflowError(err) && attempt < maxRetries {
@@ -63,8 +62,7 @@ func GenerateCheckpointWithClient(ctx context.Context, client SummarizationClien
 			return nil, fmt.Errorf("LLM call failed: %w", err)
 		}
 
-		// Parse the response
-		checkpoint, err := ParseCheckpointResponse(responseText)
+		checkpoint, err := parseCheckpointDocument(string(doc))
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse checkpoint response: %w", err)
 		}
@@ -121,12 +119,15 @@ func GenerateSummaryWithClient(ctx context.Context, client SummarizationClient,
 			"inputLimit", currentLimit)
 
 		userMessage := fmt.Sprintf("%s\n\nConversation to summarize:\n%s", CompactionSummaryPrompt, conversationText)
-		systemPrompt := "You are a helpful assistant that creates concise conversation summaries."
 
 		// Call LLM
-		responseText, err := client.SimpleMessage(ctx, userMessage, systemPrompt)
+		doc, err := client.StructuredMessage(ctx, userMessage, checkpointSystemPrompt, CheckpointSchema)
 		if err == nil {
-			return responseText, nil
+			summary, parseErr := parseCheckpointDocument(string(doc))
+			if parseErr != nil {
+				return "", fmt.Errorf("failed to parse summary response: %w", parseErr)
+			}
+			return FormatCheckpointSummary(summary), nil
 		}
 
 		// Check if this is a context overflow - retry with reduced limit
@@ -192,15 +193,18 @@ func GenerateSummaryWithRegistry(ctx context.Context, registry *llm.Registry, me
 			"inputLimit", currentLimit)
 
 		userMessage := fmt.Sprintf("%s\n\nConversation to summarize:\n%s", CompactionSummaryPrompt, conversationText)
-		systemPrompt := "You are a helpful assistant that creates concise conversation summaries."
 
 		// Call LLM with failover
-		result, err := registry.SimpleMessageWithFailover(ctx, "summarization", nil, userMessage, systemPrompt)
+		result, err := registry.StructuredMessageWithFailover(ctx, "summarization", nil, userMessage, checkpointSystemPrompt, CheckpointSchema)
 		if err == nil {
 			if result.FailedOver {
 				L_info("compaction: used fallback model", "model", result.ModelUsed)
 			}
-			return result.Text, result.ModelUsed, nil
+			summary, parseErr := parseCheckpointDocument(result.Text)
+			if parseErr != nil {
+				return "", result.ModelUsed, fmt.Errorf("failed to parse summary response: %w", parseErr)
+			}
+			return FormatCheckpointSummary(summary), result.ModelUsed, nil
 		}
 
 		// Check if this is a context overflow - retry with reduced limit
@@ -248,13 +252,11 @@ func GenerateCheckpointWithRegistry(ctx context.Context, registry *llm.Registry,
 		conversationText := BuildMessagesForSummary(messages, currentLimit)
 
 		userMessage := fmt.Sprintf("%s\n\nConversation to summarize:\n%s", CheckpointPrompt, conversationText)
-		systemPrompt := "You are a helpful assistant that creates structured conversation summaries. Respond only with valid JSON."
 
 		// Call LLM with failover
-		result, err := registry.SimpleMessageWithFailover(ctx, "summarization", nil, userMessage, systemPrompt)
+		result, err := registry.StructuredMessageWithFailover(ctx, "summarization", nil, userMessage, checkpointSystemPrompt, CheckpointSchema)
 		if err == nil {
-			// Parse the response
-			checkpoint, parseErr := ParseCheckpointResponse(result.Text)
+			checkpoint, parseErr := parseCheckpointDocument(result.Text)
 			if parseErr != nil {
 				return nil, result.ModelUsed, fmt.Errorf("failed to parse checkpoint response: %w", parseErr)
 			}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +15,23 @@ import (
 // All primary session operations use this key - no exceptions
 const PrimarySession = "primary"
 
+// KeyUserID returns the user a session key belongs to: "user:<id>" and
+// sessions named under it ("user:<id>:web:<name>"), with or without an
+// "agent:<agent>:" prefix. Returns "" for owner, group and cron sessions.
+func KeyUserID(key string) string {
+	if rest, ok := strings.CutPrefix(key, "agent:"); ok {
+		if _, after, found := strings.Cut(rest, ":"); found {
+			key = after
+		}
+	}
+	rest, ok := strings.CutPrefix(key, "user:")
+	if !ok {
+		return ""
+	}
+	id, _, _ := strings.Cut(rest, ":")
+	return id
+}
+
 // Default OpenClaw session paths
 const (
 	DefaultOpenClawSessionsDir = "~/.openclaw/agents/main/sessions"
@@ -30,6 +48,7 @@ type SessionInfo struct {
 	TotalTokens  int     `json:"totalTokens"`
 	MaxTokens    int     `json:"maxTokens"`
 	ContextUsage float64 `json:"contextUsage"` // 0.0 to 1.0
+	Compactions  int     `json:"compactions"`
 	CreatedAt    string  `json:"createdAt"`
 	UpdatedAt    string  `json:"updatedAt"`
 }
@@ -107,13 +126,13 @@ func NewManagerWithConfig(cfg *ManagerConfig) (*Manager, error) {
 func (m *Manager) LoadPrimarySession() error {
 	sess := NewSession("goclaw-primary")
 
-	goclawMsgs, latestCompaction := m.loadSQLiteMessages()
+	goclawMsgs, latestCompaction := m.loadSQLiteMessages(PrimarySession)
 
 	if len(goclawMsgs) > 0 {
 		sess.Messages = storedToMessages(goclawMsgs)
 	}
 
-	m.applyCompactionContext(sess, latestCompaction)
+	m.applyCompactionContext(sess, latestCompaction, PrimarySession)
 
 	sess.Key = PrimarySession
 
@@ -160,7 +179,7 @@ func (m *Manager) InheritOpenClawSession(sessionsDir, inheritKey string) error {
 
 	openclawMsgCount := len(sess.Messages)
 
-	goclawMsgs, latestCompaction := m.loadSQLiteMessages()
+	goclawMsgs, latestCompaction := m.loadSQLiteMessages(PrimarySession)
 
 	// Store OpenClaw messages in SQLite for transcript indexing
 	if m.store != nil && openclawMsgCount > 0 {
@@ -181,7 +200,7 @@ func (m *Manager) InheritOpenClawSession(sessionsDir, inheritKey string) error {
 			"merged", len(sess.Messages))
 	}
 
-	m.applyCompactionContext(sess, latestCompaction)
+	m.applyCompactionContext(sess, latestCompaction, PrimarySession)
 
 	// Set up the session for GoClaw use
 	sess.Key = PrimarySession
@@ -356,15 +375,15 @@ func (m *Manager) importOpenClawMessages(openclawMsgs []Message, goclawMsgs []St
 	return imported
 }
 
-// loadSQLiteMessages loads GoClaw messages from SQLite, respecting compaction boundaries.
-func (m *Manager) loadSQLiteMessages() ([]StoredMessage, *StoredCompaction) {
+// loadSQLiteMessages loads a session's GoClaw messages from SQLite, respecting compaction boundaries.
+func (m *Manager) loadSQLiteMessages(sessionKey string) ([]StoredMessage, *StoredCompaction) {
 	if m.store == nil {
 		return nil, nil
 	}
 
 	ctx := context.Background()
 
-	latestCompaction, err := m.store.GetLatestCompaction(ctx, PrimarySession)
+	latestCompaction, err := m.store.GetLatestCompaction(ctx, sessionKey)
 	if err != nil {
 		L_warn("session: failed to check compaction boundary", "error", err)
 	}
@@ -380,7 +399,7 @@ func (m *Manager) loadSQLiteMessages() ([]StoredMessage, *StoredCompaction) {
 		L_debug("session: no compaction boundary, loading all messages")
 	}
 
-	msgs, err := m.store.GetMessages(ctx, PrimarySession, opts)
+	msgs, err := m.store.GetMessages(ctx, sessionKey, opts)
 	if err != nil {
 		L_warn("session: failed to load GoClaw messages from SQLite", "error", err)
 		return nil, latestCompaction
@@ -393,7 +412,7 @@ func (m *Manager) loadSQLiteMessages() ([]StoredMessage, *StoredCompaction) {
 }
 
 // applyCompactionContext prepends the compaction summary and sets compaction metadata on the session.
-func (m *Manager) applyCompactionContext(sess *Session, comp *StoredCompaction) {
+func (m *Manager) applyCompactionContext(sess *Session, comp *StoredCompaction, sessionKey string) {
 	if comp == nil {
 		return
 	}
@@ -415,7 +434,7 @@ func (m *Manager) applyCompactionContext(sess *Session, comp *StoredCompaction)
 	compID := comp.ID
 	sess.LastRecordID = &compID
 	if m.store != nil {
-		if compactions, err := m.store.GetCompactions(context.Background(), PrimarySession); err == nil {
+		if compactions, err := m.store.GetCompactions(context.Background(), sessionKey); err == nil {
 			sess.CompactionCount = len(compactions)
 		}
 	}
@@ -560,6 +579,34 @@ func (m *Manager) Get(id string) *Session {
 	return s
 }
 
+// GetOrLoad returns a session by ID. A session that isn't in memory is
+// loaded from the store (e.g. after a restart), or created if it has no
+// stored messages.
+func (m *Manager) GetOrLoad(id string) *Session {
+	if s := m.GetIfExists(id); s != nil {
+		return s
+	}
+
+	msgs, latestCompaction := m.loadSQLiteMessages(id)
+	sess := NewSession(id)
+	if len(msgs) > 0 {
+		sess.Messages = storedToMessages(msgs)
+	}
+	m.applyCompactionContext(sess, latestCompaction, id)
+	sess.TotalTokens = GetTokenEstimator().EstimateSessionTokens(sess)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sessions[id]; ok {
+		return s // created concurrently
+	}
+	m.sessions[id] = sess
+	if len(msgs) > 0 {
+		L_info("session: loaded from SQLite", "sessionKey", id, "messages", len(msgs), "compactionCount", sess.CompactionCount)
+	}
+	return sess
+}
+
 // GetPrimary returns the primary session (shorthand for Get("primary"))
 func (m *Manager) GetPrimary() *Session {
 	return m.Get(PrimarySession)
@@ -591,13 +638,22 @@ func (m *Manager) List() []SessionInfo {
 	defer m.mu.RUnlock()
 
 	infos := make([]SessionInfo, 0, len(m.sessions))
-	for _, s := range m.sessions {
+	for key, s := range m.sessions {
 		s.mu.RLock()
+		usage := 0.0
+		if s.MaxTokens > 0 {
+			usage = float64(s.TotalTokens) / float64(s.MaxTokens)
+		}
 		infos = append(infos, SessionInfo{
 			ID:           s.ID,
+			Key:          key,
 			MessageCount: len(s.Messages),
 			InputTokens:  s.InputTokens,
 			OutputTokens: s.OutputTokens,
+			TotalTokens:  s.TotalTokens,
+			MaxTokens:    s.MaxTokens,
+			ContextUsage: usage,
+			Compactions:  s.CompactionCount,
 			CreatedAt:    s.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 			UpdatedAt:    s.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		})
@@ -630,6 +686,23 @@ func (m *Manager) Delete(id string) bool {
 	return false
 }
 
+// DeleteSession removes a session from memory and storage, cancelling any
+// run in progress
+func (m *Manager) DeleteSession(ctx context.Context, id string) error {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	delete(m.sessions, id)
+	m.mu.Unlock()
+
+	if ok && s.IsRunning() {
+		s.Cancel()
+	}
+	if m.store == nil {
+		return nil
+	}
+	return m.store.DeleteSession(ctx, id)
+}
+
 // Reset clears all messages in a session but keeps the session
 func (m *Manager) Reset(id string) bool {
 	m.mu.RLock()
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/google/uuid"
 	_ "github.com/mattn/go-sqlite3"
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
 )
@@ -20,7 +21,7 @@ type SQLiteStore struct {
 }
 
 // Schema version for migrations
-const currentSchemaVersion = 6
+const currentSchemaVersion = 7
 
 // NewSQLiteStore creates a new SQLite store
 func NewSQLiteStore(cfg StoreConfig) (*SQLiteStore, error) {
@@ -89,6 +90,7 @@ func (s *SQLiteStore) Migrate() error {
 		migrateV4,
 		migrateV5,
 		migrateV6,
+		migrateV7,
 	}
 
 	for i := version; i < len(migrations); i++ {
@@ -315,6 +317,20 @@ func migrateV6(db *sql.DB) error {
 	return err
 }
 
+// migrateV7 adds session titles and archiving (web session browser)
+func migrateV7(db *sql.DB) error {
+	schema := `
+	ALTER TABLE sessions ADD COLUMN title TEXT DEFAULT '';
+	ALTER TABLE sessions ADD COLUMN archived INTEGER DEFAULT 0;
+
+	-- Update schema version
+	INSERT INTO schema_version (version, applied_at) VALUES (7, ?);
+	`
+
+	_, err := db.Exec(schema, time.Now().Unix())
+	return err
+}
+
 // Close closes the database connection
 func (s *SQLiteStore) Close() error {
 	L_debug("sqlite: closing store")
@@ -414,13 +430,20 @@ func (s *SQLiteStore) UpdateSession(ctx context.Context, sess *StoredSession) er
 	return nil
 }
 
-// ListSessions returns all sessions
+// ListSessions returns all sessions, including sessions that only have
+// messages (messages are appended without a session row)
 func (s *SQLiteStore) ListSessions(ctx context.Context) ([]StoredSessionInfo, error) {
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT s.key, s.id, s.created_at, s.updated_at, s.compaction_count, s.total_tokens,
+		       COALESCE(s.title, ''), COALESCE(s.archived, 0),
 		       (SELECT COUNT(*) FROM messages WHERE session_key = s.key) as msg_count
 		FROM sessions s
-		ORDER BY s.updated_at DESC
+		UNION ALL
+		SELECT m.session_key, '', MIN(m.timestamp), MAX(m.timestamp), 0, 0, '', 0, COUNT(*)
+		FROM messages m
+		WHERE m.session_key NOT IN (SELECT key FROM sessions)
+		GROUP BY m.session_key
+		ORDER BY 4 DESC
 	`)
 	if err != nil {
 		return nil, err
@@ -431,7 +454,8 @@ func (s *SQLiteStore) ListSessions(ctx context.Context) ([]StoredSessionInfo, er
 	for rows.Next() {
 		var si StoredSessionInfo
 		var createdAt, updatedAt int64
-		if err := rows.Scan(&si.Key, &si.ID, &createdAt, &updatedAt, &si.CompactionCount, &si.TotalTokens, &si.MessageCount); err != nil {
+		if err := rows.Scan(&si.Key, &si.ID, &createdAt, &updatedAt, &si.CompactionCount, &si.TotalTokens,
+			&si.Title, &si.Archived, &si.MessageCount); err != nil {
 			return nil, err
 		}
 		si.CreatedAt = time.Unix(createdAt, 0)
@@ -442,6 +466,59 @@ func (s *SQLiteStore) ListSessions(ctx context.Context) ([]StoredSessionInfo, er
 	return sessions, rows.Err()
 }
 
+// SetSessionTitle sets a session's display title, creating the session row if needed
+func (s *SQLiteStore) SetSessionTitle(ctx context.Context, key, title string) error {
+	return s.upsertSessionMeta(ctx, key, "title", title)
+}
+
+// SetSessionArchived archives or unarchives a session, creating the session row if needed
+func (s *SQLiteStore) SetSessionArchived(ctx context.Context, key string, archived bool) error {
+	return s.upsertSessionMeta(ctx, key, "archived", archived)
+}
+
+// upsertSessionMeta sets one metadata column on a session row.
+// column is always a literal from this file, never user input.
+func (s *SQLiteStore) upsertSessionMeta(ctx context.Context, key, column string, value any) error {
+	now := time.Now().Unix()
+	//nolint:gosec // G201: column is an internal constant
+	query := fmt.Sprintf(`
+		INSERT INTO sessions (key, id, created_at, updated_at, %[1]s)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET %[1]s = excluded.%[1]s
+	`, column)
+	if _, err := s.db.ExecContext(ctx, query, key, uuid.New().String(), now, now, value); err != nil {
+		return fmt.Errorf("update session %s: %w", column, err)
+	}
+	return nil
+}
+
+// DeleteSession deletes a session with its messages, checkpoints,
+// compactions and provider state
+func (s *SQLiteStore) DeleteSession(ctx context.Context, key string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after commit
+
+	// Foreign keys aren't enforced, so cascade by hand
+	for _, table := range []string{"messages", "checkpoints", "compactions", "provider_state"} {
+		//nolint:gosec // G201: table names are internal constants
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE session_key = ?", table), key); err != nil {
+			return fmt.Errorf("delete from %s: %w", table, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE key = ?", key); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	L_info("sqlite: session deleted", "key", key)
+	return nil
+}
+
 // AppendMessage appends a message to a session
 func (s *SQLiteStore) AppendMessage(ctx context.Context, sessionKey string, msg *StoredMessage) error {
 	_, err := s.db.ExecContext(ctx, `
@@ -15,6 +15,9 @@ type Store interface {
 	CreateSession(ctx context.Context, session *StoredSession) error
 	UpdateSession(ctx context.Context, session *StoredSession) error
 	ListSessions(ctx context.Context) ([]StoredSessionInfo, error)
+	SetSessionTitle(ctx context.Context, key, title string) error
+	SetSessionArchived(ctx context.Context, key string, archived bool) error
+	DeleteSession(ctx context.Context, key string) error // Session with its messages, checkpoints and compactions
 
 	// Message operations
 	AppendMessage(ctx context.Context, sessionKey string, msg *StoredMessage) error
@@ -70,6 +73,8 @@ type StoredSessionInfo struct {
 	MessageCount    int
 	CompactionCount int
 	TotalTokens     int
+	Title           string // Display title (empty = none)
+	Archived        bool   // Hidden from the session list by default
 }
 
 // StoredSession represents a session in storage
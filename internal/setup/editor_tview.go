@@ -16,6 +16,7 @@ import (
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
 	"github.com/roelfdiedericks/goclaw/internal/media"
 	"github.com/roelfdiedericks/goclaw/internal/paths"
+	"github.com/roelfdiedericks/goclaw/internal/retrieval"
 	"github.com/roelfdiedericks/goclaw/internal/sandbox"
 	"github.com/roelfdiedericks/goclaw/internal/session"
 	"github.com/roelfdiedericks/goclaw/internal/skills"
@@ -59,6 +60,7 @@ func (e *EditorTview) Run() error {
 	auth.RegisterCommands()
 	gateway.RegisterCommands()
 	transcript.RegisterCommands()
+	retrieval.RegisterCommands()
 	stt.RegisterCommands()
 
 	for {
@@ -119,6 +121,7 @@ func (e *EditorTview) createMenu() *forms.MenuListResult {
 		{Label: "HTTP Server", OnSelect: e.editHTTP},
 		{IsSeparator: true, Label: "Services"},
 		{Label: "Transcript Indexing", OnSelect: e.editTranscript},
+		{Label: "Context Retrieval", OnSelect: e.editRetrieval},
 		{Label: "Speech-to-Text (STT)", OnSelect: e.editSTT},
 		{Label: "Skills", OnSelect: e.editSkills},
 		{Label: "Cron Jobs", OnSelect: e.editCron},
@@ -381,6 +384,32 @@ func (e *EditorTview) editSkills() {
 	e.app.SetFormContent(content)
 }
 
+// editRetrieval opens the pre-turn retrieval configuration form
+func (e *EditorTview) editRetrieval() {
+	L_info("editor: opening retrieval config")
+
+	retrievalCfg := e.cfg.Retrieval
+	formDef := retrieval.ConfigFormDef()
+
+	content, err := forms.BuildFormContent(formDef, &retrievalCfg, "retrieval", func(result forms.TviewResult) {
+		if result == forms.ResultAccepted {
+			e.cfg.Retrieval = retrievalCfg
+			e.dirty = true
+			L_info("editor: retrieval config updated")
+		} else {
+			L_info("editor: retrieval config cancelled")
+		}
+		e.showMainMenu()
+	}, e.app.App())
+	if err != nil {
+		L_error("editor: retrieval form error", "error", err)
+		return
+	}
+
+	e.app.SetBreadcrumbs([]string{"GoClaw Configuration", "Context Retrieval"})
+	e.app.SetFormContent(content)
+}
+
 // editCron opens the cron jobs configuration form
 func (e *EditorTview) editCron() {
 	L_info("editor: opening cron config")
@@ -157,6 +157,15 @@ func (m *Manager) onConfigApplied(e bus.Event) {
 	}
 	m.extraDirs = cfg.ExtraDirs
 
+	// Per-skill entries carry the whitelist for flagged skills
+	skillConfigs := make(map[string]*SkillEntryConfig, len(cfg.Entries))
+	for name, entry := range cfg.Entries {
+		skillConfigs[name] = &entry
+	}
+	m.mu.Lock()
+	m.skillConfigs = skillConfigs
+	m.mu.Unlock()
+
 	// Reload skills
 	if err := m.Reload(); err != nil {
 		L_error("skills: reload failed after config change", "error", err)
@@ -8,6 +8,9 @@ type ToolsConfig struct {
 	Browser    BrowserToolsConfig `json:"browser"`
 	Exec       ExecToolsConfig    `json:"exec"`
 	XAIImagine XAIImagineConfig   `json:"xaiImagine"`
+	Script     ScriptToolsConfig  `json:"script"`
+	Spillover  SpilloverConfig    `json:"spillover"`
+	History    HistoryConfig      `json:"history"`
 }
 
 // WebToolsConfig contains web tool settings
@@ -46,6 +49,14 @@ type BrowserBubblewrapConfig struct {
 type ExecToolsConfig struct {
 	Timeout    int                  `json:"timeout"`    // Timeout in seconds (default: 1800 = 30 min, 0 = no timeout)
 	Bubblewrap ExecBubblewrapConfig `json:"bubblewrap"` // Sandbox settings
+	Sessions   ExecSessionsConfig   `json:"sessions"`   // Persistent shell sessions
+}
+
+// ExecSessionsConfig contains settings for persistent exec shell sessions
+type ExecSessionsConfig struct {
+	Enabled     bool `json:"enabled"`     // Allow exec's session parameter (default: true)
+	IdleTimeout int  `json:"idleTimeout"` // Minutes before an unused shell is closed (default: 30)
+	MaxSessions int  `json:"maxSessions"` // Maximum open shells across all sessions (default: 8)
 }
 
 // ExecBubblewrapConfig contains bubblewrap settings for exec tool
@@ -66,3 +77,26 @@ type XAIImagineConfig struct {
 	Resolution  string `json:"resolution,omitempty"`  // Default resolution: "1K" (~1024px) or "2K" (~2048px)
 	SaveToMedia bool   `json:"saveToMedia,omitempty"` // Save generated images to media store (default: true)
 }
+
+// ScriptToolsConfig contains settings for declarative workspace script tools
+type ScriptToolsConfig struct {
+	Enabled    bool     `json:"enabled"`              // Load tools/*.yaml from the workspace (default: false)
+	Dir        string   `json:"dir"`                  // Definitions directory, relative to the workspace (default: tools)
+	Timeout    int      `json:"timeout"`              // Default per-call timeout in seconds (default: 60)
+	AllowRoles []string `json:"allowRoles,omitempty"` // Roles besides owner that definitions may grant ("*" = any)
+}
+
+// SpilloverConfig contains settings for storing oversized tool results
+type SpilloverConfig struct {
+	Enabled      bool           `json:"enabled"`         // Spill results over budget (default: true)
+	Budget       int            `json:"budget"`          // Token budget per result (default: 8000)
+	Tools        map[string]int `json:"tools,omitempty"` // Per-tool budget overrides (negative = never spill)
+	PreviewBytes int            `json:"previewBytes"`    // Head + tail preview size (default: 4000)
+	TTL          int            `json:"ttl"`             // Hours to keep spilled results (default: 24)
+}
+
+// HistoryConfig contains settings for workspace change history
+type HistoryConfig struct {
+	Enabled bool   `json:"enabled"` // Record write/edit changes for undo (default: true)
+	Dir     string `json:"dir"`     // Store directory (default: ~/.goclaw/history)
+}
@@ -7,17 +7,20 @@ import (
 	"strings"
 	"time"
 
+	"github.com/roelfdiedericks/goclaw/internal/agents"
 	cronpkg "github.com/roelfdiedericks/goclaw/internal/cron"
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
 	"github.com/roelfdiedericks/goclaw/internal/types"
 )
 
 // Tool allows the agent to manage scheduled tasks.
-type Tool struct{}
+type Tool struct {
+	agents *agents.Config // Known agents, for jobs' agentId
+}
 
 // NewTool creates a new cron tool.
-func NewTool() *Tool {
-	return &Tool{}
+func NewTool(agentsCfg *agents.Config) *Tool {
+	return &Tool{agents: agentsCfg}
 }
 
 func (t *Tool) Name() string {
@@ -64,8 +67,8 @@ func (t *Tool) Schema() map[string]interface{} {
 			},
 			"scheduleType": map[string]interface{}{
 				"type":        "string",
-				"enum":        []string{"at", "every", "cron"},
-				"description": "Schedule type: 'at' for one-shot, 'every' for interval, 'cron' for cron expression",
+				"enum":        []string{"at", "every", "cron", "hass", "job", "webhook", "file"},
+				"description": "Schedule type: 'at' for one-shot, 'every' for interval, 'cron' for cron expression. Event triggers: 'hass' on Home Assistant state change, 'job' after another job finishes, 'webhook' on POST to /api/cron/webhook/<id>, 'file' on workspace file change",
 			},
 			"at": map[string]interface{}{
 				"type":        "string",
@@ -83,6 +86,31 @@ func (t *Tool) Schema() map[string]interface{} {
 				"type":        "string",
 				"description": "IANA timezone for cron schedule (e.g., 'America/New_York')",
 			},
+			"entity": map[string]interface{}{
+				"type":        "string",
+				"description": "For 'hass' trigger: entity_id glob pattern (e.g., 'binary_sensor.door_*')",
+			},
+			"entityRegex": map[string]interface{}{
+				"type":        "string",
+				"description": "For 'hass' trigger: entity_id regex (alternative to entity)",
+			},
+			"state": map[string]interface{}{
+				"type":        "string",
+				"description": "For 'hass' trigger: only fire when the entity changes to this state (e.g., 'on')",
+			},
+			"afterJob": map[string]interface{}{
+				"type":        "string",
+				"description": "For 'job' trigger: ID or name of the upstream job. Its output is passed into this job's prompt",
+			},
+			"on": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"success", "failure", "any"},
+				"description": "For 'job' trigger: which upstream outcome fires this job (default: success)",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "For 'file' trigger: workspace-relative path, wildcards allowed in the file name (e.g., 'inbox/*.md')",
+			},
 			"sessionTarget": map[string]interface{}{
 				"type":        "string",
 				"enum":        []string{"main", "isolated"},
@@ -96,6 +124,36 @@ func (t *Tool) Schema() map[string]interface{} {
 				"type":        "boolean",
 				"description": "Whether to deliver output to channels",
 			},
+			"agentId": map[string]interface{}{
+				"type":        "string",
+				"description": "Agent persona that runs the job (for add/update). Defaults to the current agent; only the main agent can choose another",
+			},
+			"maxRetries": map[string]interface{}{
+				"type":        "integer",
+				"description": "Retries after a failed run, with exponential backoff (for add/update, 0 = none)",
+			},
+			"retryBackoff": map[string]interface{}{
+				"type":        "string",
+				"description": "Delay before the first retry, doubled each attempt (30s, 5m). Default: 30s",
+			},
+			"misfire": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"skip", "run_once", "run_all"},
+				"description": "What to do with runs missed while the gateway was down: 'skip' (default), 'run_once' at startup, or 'run_all' missed occurrences",
+			},
+			"jitter": map[string]interface{}{
+				"type":        "string",
+				"description": "Random delay up to this duration added to each scheduled run (30s, 5m)",
+			},
+			"concurrency": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"forbid", "queue"},
+				"description": "Overlapping runs: 'forbid' (default) skips a run while the job is running, 'queue' runs it afterwards",
+			},
+			"timeout": map[string]interface{}{
+				"type":        "string",
+				"description": "Job timeout (10m, 1h), overrides cron.jobTimeoutMinutes",
+			},
 			"mode": map[string]interface{}{
 				"type":        "string",
 				"enum":        []string{"now", "next-heartbeat"},
@@ -121,9 +179,22 @@ type cronInput struct {
 	Every         string `json:"every"`
 	CronExpr      string `json:"cronExpr"`
 	Timezone      string `json:"timezone"`
+	Entity        string `json:"entity"`
+	EntityRegex   string `json:"entityRegex"`
+	State         string `json:"state"`
+	AfterJob      string `json:"afterJob"`
+	On            string `json:"on"`
+	Path          string `json:"path"`
 	SessionTarget string `json:"sessionTarget"`
 	Message       string `json:"message"`
 	Deliver       *bool  `json:"deliver"`
+	AgentID       string `json:"agentId"`
+	MaxRetries    *int   `json:"maxRetries"`
+	RetryBackoff  string `json:"retryBackoff"`
+	Misfire       string `json:"misfire"`
+	Jitter        string `json:"jitter"`
+	Concurrency   string `json:"concurrency"`
+	Timeout       string `json:"timeout"`
 	Mode          string `json:"mode"`
 	Text          string `json:"text"`
 }
@@ -161,11 +232,19 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 	case "list":
 		result, err = t.handleList(service)
 	case "add":
-		result, err = t.handleAdd(service, in)
+		// Jobs created by a named agent run as that agent unless told otherwise
+		if in.AgentID, err = t.resolveAgentID(ctx, in.AgentID); err == nil {
+			result, err = t.handleAdd(service, in)
+		}
 	case "update":
-		result, err = t.handleUpdate(service, in)
+		if in.AgentID != "" {
+			in.AgentID, err = t.resolveAgentID(ctx, in.AgentID)
+		}
+		if err == nil {
+			result, err = t.handleUpdate(ctx, service, in)
+		}
 	case "remove":
-		result, err = t.handleRemove(service, in)
+		result, err = t.handleRemove(ctx, service, in)
 	case "run":
 		result, err = t.handleRun(ctx, service, in)
 	case "runs":
@@ -187,6 +266,47 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 	return types.TextResult(result), nil
 }
 
+// callerAgent returns the agent making the call (main if unknown).
+func callerAgent(ctx context.Context) string {
+	if sessCtx := types.GetSessionContext(ctx); sessCtx != nil && sessCtx.AgentID != "" {
+		return sessCtx.AgentID
+	}
+	return agents.MainAgentID
+}
+
+// resolveAgentID checks the agent a job should run as. Only the main agent
+// may pick another agent: a named agent handing a job to another agent would
+// escape its own tool allowlist and workspace. Empty means the caller.
+func (t *Tool) resolveAgentID(ctx context.Context, requested string) (string, error) {
+	caller := callerAgent(ctx)
+	if requested == "" {
+		if caller == agents.MainAgentID {
+			return "", nil
+		}
+		return caller, nil
+	}
+	if t.agents == nil || !t.agents.Has(requested) {
+		return "", fmt.Errorf("unknown agent: %s", requested)
+	}
+	if caller != agents.MainAgentID && requested != caller {
+		return "", fmt.Errorf("agent %q can only schedule jobs for itself", caller)
+	}
+	return requested, nil
+}
+
+// checkJobAgent rejects a named agent changing or running another agent's job.
+func checkJobAgent(ctx context.Context, job *cronpkg.CronJob) error {
+	caller := callerAgent(ctx)
+	owner := job.AgentID
+	if owner == "" {
+		owner = agents.MainAgentID
+	}
+	if caller != agents.MainAgentID && owner != caller {
+		return fmt.Errorf("job %s belongs to agent %q", job.ID, owner)
+	}
+	return nil
+}
+
 func truncate(s string, max int) string {
 	if len(s) <= max {
 		return s
@@ -258,22 +378,30 @@ func (t *Tool) handleAdd(service *cronpkg.Service, in cronInput) (string, error)
 		Enabled:       enabled,
 		Schedule:      schedule,
 		SessionTarget: sessionTarget,
+		AgentID:       in.AgentID,
 		Payload: cronpkg.Payload{
 			Kind:    cronpkg.PayloadKindAgentTurn,
 			Message: in.Message,
 			Deliver: deliver,
 		},
 	}
+	if err := t.applyPolicy(job, in); err != nil {
+		return "", err
+	}
 
 	if err := service.AddJob(job); err != nil {
 		return "", fmt.Errorf("failed to add job: %w", err)
 	}
 
-	return fmt.Sprintf("Job created successfully.\nID: %s\nName: %s\nSchedule: %s",
-		job.ID, job.Name, formatSchedule(&job.Schedule)), nil
+	result := fmt.Sprintf("Job created successfully.\nID: %s\nName: %s\nSchedule: %s",
+		job.ID, job.Name, formatSchedule(&job.Schedule))
+	if job.Schedule.Kind == cronpkg.ScheduleKindWebhook {
+		result += fmt.Sprintf("\nWebhook: POST /api/cron/webhook/%s with header X-Webhook-Secret: %s", job.ID, job.Schedule.Secret)
+	}
+	return result, nil
 }
 
-func (t *Tool) handleUpdate(service *cronpkg.Service, in cronInput) (string, error) {
+func (t *Tool) handleUpdate(ctx context.Context, service *cronpkg.Service, in cronInput) (string, error) {
 	if in.ID == "" {
 		return "", fmt.Errorf("id is required")
 	}
@@ -283,6 +411,9 @@ func (t *Tool) handleUpdate(service *cronpkg.Service, in cronInput) (string, err
 	if job == nil {
 		return "", fmt.Errorf("job not found: %s", in.ID)
 	}
+	if err := checkJobAgent(ctx, job); err != nil {
+		return "", err
+	}
 
 	if in.Name != "" {
 		job.Name = in.Name
@@ -306,16 +437,35 @@ func (t *Tool) handleUpdate(service *cronpkg.Service, in cronInput) (string, err
 	if in.Deliver != nil {
 		job.Payload.Deliver = *in.Deliver
 	}
+	if in.AgentID != "" {
+		job.AgentID = in.AgentID
+	}
+	if err := t.applyPolicy(job, in); err != nil {
+		return "", err
+	}
+	if err := job.ValidatePolicy(); err != nil {
+		return "", err
+	}
 
-	if in.ScheduleType != "" || in.At != "" || in.Every != "" || in.CronExpr != "" {
+	triggerChanged := in.Entity != "" || in.EntityRegex != "" || in.State != "" || in.AfterJob != "" || in.On != "" || in.Path != ""
+	if in.ScheduleType != "" || in.At != "" || in.Every != "" || in.CronExpr != "" || triggerChanged {
+		if in.ScheduleType == "" {
+			in.ScheduleType = job.Schedule.Kind
+		}
 		schedule, err := t.buildSchedule(in)
 		if err != nil {
 			return "", fmt.Errorf("invalid schedule: %w", err)
 		}
+		if schedule.Kind == job.Schedule.Kind && schedule.IsEvent() {
+			mergeTrigger(&schedule, &job.Schedule)
+		}
 		job.Schedule = schedule
 	}
+	if err := job.ValidateTrigger(); err != nil {
+		return "", err
+	}
 
-	next, err := cronpkg.NextRunTime(job, time.Now())
+	next, err := cronpkg.PlanNextRun(job, time.Now())
 	if err != nil {
 		return "", fmt.Errorf("failed to calculate next run: %w", err)
 	}
@@ -324,11 +474,12 @@ func (t *Tool) handleUpdate(service *cronpkg.Service, in cronInput) (string, err
 	if err := store.UpdateJob(job); err != nil {
 		return "", fmt.Errorf("failed to update job: %w", err)
 	}
+	service.SyncTriggers()
 
 	return fmt.Sprintf("Job updated successfully.\nID: %s\nName: %s", job.ID, job.Name), nil
 }
 
-func (t *Tool) handleRemove(service *cronpkg.Service, in cronInput) (string, error) {
+func (t *Tool) handleRemove(ctx context.Context, service *cronpkg.Service, in cronInput) (string, error) {
 	if in.ID == "" {
 		return "", fmt.Errorf("id is required")
 	}
@@ -338,6 +489,9 @@ func (t *Tool) handleRemove(service *cronpkg.Service, in cronInput) (string, err
 	if job == nil {
 		return "", fmt.Errorf("job not found: %s", in.ID)
 	}
+	if err := checkJobAgent(ctx, job); err != nil {
+		return "", err
+	}
 
 	name := job.Name
 	if err := service.RemoveJob(in.ID); err != nil {
@@ -357,6 +511,9 @@ func (t *Tool) handleRun(ctx context.Context, service *cronpkg.Service, in cronI
 	if job == nil {
 		return "", fmt.Errorf("job not found: %s", in.ID)
 	}
+	if err := checkJobAgent(ctx, job); err != nil {
+		return "", err
+	}
 
 	if err := service.RunNow(ctx, in.ID); err != nil {
 		return "", fmt.Errorf("failed to run job: %w", err)
@@ -447,6 +604,50 @@ func (t *Tool) handleWake(service *cronpkg.Service, in cronInput) (string, error
 	return fmt.Sprintf("Wake event sent (mode: next-heartbeat). Text will be processed on next heartbeat.\nText: %s", truncate(in.Text, 100)), nil
 }
 
+// applyPolicy copies retry, misfire, jitter, concurrency and timeout settings onto a job.
+// Only fields present in the input are changed.
+func (t *Tool) applyPolicy(job *cronpkg.CronJob, in cronInput) error {
+	if in.MaxRetries != nil || in.RetryBackoff != "" {
+		if job.Retry == nil {
+			job.Retry = &cronpkg.RetryPolicy{}
+		}
+		if in.MaxRetries != nil {
+			job.Retry.MaxRetries = *in.MaxRetries
+		}
+		if in.RetryBackoff != "" {
+			dur, err := cronpkg.ParseDuration(in.RetryBackoff)
+			if err != nil {
+				return fmt.Errorf("invalid retryBackoff: %w", err)
+			}
+			job.Retry.BackoffSeconds = int(dur.Seconds())
+		}
+		if job.Retry.MaxRetries == 0 {
+			job.Retry = nil
+		}
+	}
+	if in.Misfire != "" {
+		job.Misfire = in.Misfire
+	}
+	if in.Concurrency != "" {
+		job.Concurrency = in.Concurrency
+	}
+	if in.Jitter != "" {
+		dur, err := cronpkg.ParseDuration(in.Jitter)
+		if err != nil {
+			return fmt.Errorf("invalid jitter: %w", err)
+		}
+		job.JitterSeconds = int(dur.Seconds())
+	}
+	if in.Timeout != "" {
+		dur, err := cronpkg.ParseDuration(in.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %w", err)
+		}
+		job.Payload.TimeoutSeconds = int(dur.Seconds())
+	}
+	return nil
+}
+
 func (t *Tool) buildSchedule(in cronInput) (cronpkg.Schedule, error) {
 	L_debug("cron buildSchedule", "type", in.ScheduleType, "at", in.At, "every", in.Every, "cronExpr", in.CronExpr)
 
@@ -495,12 +696,56 @@ func (t *Tool) buildSchedule(in cronInput) (cronpkg.Schedule, error) {
 			Tz:   in.Timezone,
 		}, nil
 
+	case "hass":
+		return cronpkg.Schedule{
+			Kind:        cronpkg.ScheduleKindHass,
+			Entity:      in.Entity,
+			EntityRegex: in.EntityRegex,
+			State:       in.State,
+		}, nil
+
+	case "job":
+		return cronpkg.Schedule{
+			Kind:     cronpkg.ScheduleKindJob,
+			AfterJob: in.AfterJob,
+			On:       in.On,
+		}, nil
+
+	case "webhook":
+		return cronpkg.Schedule{Kind: cronpkg.ScheduleKindWebhook}, nil
+
+	case "file":
+		return cronpkg.Schedule{
+			Kind: cronpkg.ScheduleKindFile,
+			Path: in.Path,
+		}, nil
+
 	default:
 		L_error("cron buildSchedule: unknown schedule type", "type", in.ScheduleType)
 		return cronpkg.Schedule{}, fmt.Errorf("unknown schedule type: %s", in.ScheduleType)
 	}
 }
 
+// mergeTrigger keeps event trigger fields that an update did not set.
+func mergeTrigger(dst, src *cronpkg.Schedule) {
+	if dst.Entity == "" && dst.EntityRegex == "" {
+		dst.Entity, dst.EntityRegex = src.Entity, src.EntityRegex
+	}
+	if dst.State == "" {
+		dst.State = src.State
+	}
+	if dst.AfterJob == "" {
+		dst.AfterJob = src.AfterJob
+	}
+	if dst.On == "" {
+		dst.On = src.On
+	}
+	if dst.Path == "" {
+		dst.Path = src.Path
+	}
+	dst.Secret = src.Secret // Keep the existing webhook secret
+}
+
 func formatSchedule(s *cronpkg.Schedule) string {
 	switch s.Kind {
 	case cronpkg.ScheduleKindAt:
@@ -512,6 +757,8 @@ func formatSchedule(s *cronpkg.Schedule) string {
 			return fmt.Sprintf("cron '%s' (%s)", s.Expr, s.Tz)
 		}
 		return fmt.Sprintf("cron '%s'", s.Expr)
+	case cronpkg.ScheduleKindHass, cronpkg.ScheduleKindJob, cronpkg.ScheduleKindWebhook, cronpkg.ScheduleKindFile:
+		return "on " + cronpkg.FormatTrigger(s)
 	default:
 		return "unknown"
 	}
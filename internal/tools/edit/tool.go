@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/roelfdiedericks/goclaw/internal/history"
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
 	"github.com/roelfdiedericks/goclaw/internal/sandbox"
 	"github.com/roelfdiedericks/goclaw/internal/types"
@@ -80,6 +81,9 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 		return nil, fmt.Errorf("old_string cannot be empty")
 	}
 
+	// Named agents resolve paths against their own workspace
+	workingDir := types.WorkspaceDirFromContext(ctx, t.workingDir)
+
 	// Check if user has sandbox disabled
 	sandboxed := true
 	if sessCtx := types.GetSessionContext(ctx); sessCtx != nil && sessCtx.User != nil {
@@ -94,19 +98,19 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 
 	if sandboxed {
 		// Validate path for write (sandbox check + write-protection)
-		resolved, err = sandbox.GetManager().ValidateWritePath(params.Path, t.workingDir)
+		resolved, err = sandbox.GetManager().ValidateWritePath(params.Path, workingDir)
 		if err != nil {
 			L_warn("edit tool: path validation failed", "path", params.Path, "error", err)
 			return nil, err
 		}
 
 		// Read file using sandbox-validated path
-		content, err = sandbox.GetManager().ReadFile(params.Path, t.workingDir)
+		content, err = sandbox.GetManager().ReadFile(params.Path, workingDir)
 	} else {
 		// No sandbox: resolve relative paths from workingDir, allow any absolute path
 		resolved = params.Path
 		if !filepath.IsAbs(resolved) {
-			resolved = filepath.Join(t.workingDir, resolved)
+			resolved = filepath.Join(workingDir, resolved)
 		}
 		content, err = os.ReadFile(resolved)
 	}
@@ -132,6 +136,7 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 	newText := strings.Replace(text, params.OldString, params.NewString, 1)
 
 	// Write back atomically (preserves permissions)
+	change := history.Begin(ctx, "edit", resolved)
 	if sandboxed {
 		err = sandbox.GetManager().AtomicWriteFile(resolved, []byte(newText), 0600)
 	} else {
@@ -141,6 +146,7 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 		L_error("edit tool: failed to write", "path", params.Path, "error", err)
 		return nil, err
 	}
+	change.Commit()
 
 	L_info("edit tool: file edited", "path", params.Path, "sizeBefore", len(text), "sizeAfter", len(newText))
 	return types.TextResult(fmt.Sprintf("Successfully edited %s", params.Path)), nil
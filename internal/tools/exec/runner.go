@@ -9,6 +9,7 @@ import (
 	"time"
 
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/types"
 )
 
 // Runner handles sandboxed command execution.
@@ -30,8 +31,9 @@ func NewRunner(cfg RunnerConfig) *Runner {
 // Returns error with exit code if command fails (non-zero exit).
 // Stderr is discarded (not returned).
 // The useSandbox parameter allows the caller to override sandbox settings (e.g., for user sandbox=false).
+// Runs in the agent workspace from the session context, if any.
 func (r *Runner) Run(ctx context.Context, command string, useSandbox bool) ([]byte, error) {
-	result, err := r.RunFull(ctx, command, "", useSandbox)
+	result, err := r.RunFull(ctx, command, types.WorkspaceDirFromContext(ctx, ""), useSandbox)
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +69,7 @@ func (r *Runner) RunFull(ctx context.Context, command, workDir string, useSandbo
 	// Build command - sandboxed or unsandboxed
 	var cmd *exec.Cmd
 	if useSandbox && r.config.Bubblewrap.Enabled {
-		sandboxedCmd, err := r.buildSandboxedCommand(execCtx, command, workDir)
+		sandboxedCmd, err := r.buildSandboxedCommand(execCtx, command, workDir, roleFromContext(ctx))
 		if err != nil {
 			L_error("exec runner: sandbox failed", "error", err)
 			return nil, fmt.Errorf("sandbox error: %w", err)
@@ -119,6 +121,15 @@ func (r *Runner) RunFull(ctx context.Context, command, workDir string, useSandbo
 	}, nil
 }
 
+// roleFromContext returns the session user's role, which selects the sandbox
+// profile (resource limits and egress policy). Empty outside a session.
+func roleFromContext(ctx context.Context) string {
+	if sessCtx := types.GetSessionContext(ctx); sessCtx != nil && sessCtx.User != nil {
+		return string(sessCtx.User.Role)
+	}
+	return ""
+}
+
 // Config returns the runner's configuration (read-only access for tools)
 func (r *Runner) Config() RunnerConfig {
 	return r.config
@@ -4,24 +4,37 @@ package exec
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/exec"
 
-	"github.com/roelfdiedericks/goclaw/internal/sandbox/bwrap"
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox/bwrap"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox/egress"
 )
 
-// buildSandboxedCommand creates a sandboxed exec.Cmd using bubblewrap.
+// proxyEnvVars are the variables pointed at the egress proxy (both cases,
+// since tools disagree on which they read).
+var proxyEnvVars = []string{"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY", "http_proxy", "https_proxy", "all_proxy"}
+
+// buildSandboxedCommand creates a sandboxed exec.Cmd using bubblewrap, with the
+// resource limits and egress policy of the sandbox profile for role.
 // Returns nil if sandboxing is disabled or not available.
-func (r *Runner) buildSandboxedCommand(ctx context.Context, command, workDir string) (*exec.Cmd, error) {
+func (r *Runner) buildSandboxedCommand(ctx context.Context, command, workDir, role string) (*exec.Cmd, error) {
 	if !r.config.Bubblewrap.Enabled {
 		return nil, nil
 	}
 
 	home, _ := os.UserHomeDir()
 
+	// With an egress allowlist the sandbox gets no network of its own; it
+	// reaches the filtering proxy only through a bound unix socket.
+	profileName, profile := sandbox.GetManager().Profile(role)
+	filtered := r.config.Bubblewrap.AllowNetwork && profile.Egress.Enabled
+
 	// Build base sandbox config
-	b := bwrap.ExecSandbox(r.config.WorkingDir, home, r.config.Bubblewrap.AllowNetwork, r.config.Bubblewrap.ClearEnv)
+	b := bwrap.ExecSandbox(r.config.WorkingDir, home, r.config.Bubblewrap.AllowNetwork && !filtered, r.config.Bubblewrap.ClearEnv)
 
 	// Set custom bwrap path if provided
 	if r.config.BubblewrapPath != "" {
@@ -43,14 +56,39 @@ func (r *Runner) buildSandboxedCommand(ctx context.Context, command, workDir str
 		b.SetEnv(k, v)
 	}
 
+	// Per-role resource limits and egress filtering
+	b.Limits(profile.Limits)
+	if filtered {
+		socket, err := egress.ProxySocket(profileName, profile.Egress)
+		if err != nil {
+			return nil, err
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			return nil, fmt.Errorf("egress relay: %w", err)
+		}
+		b.RoBindTo(exe, egress.RelayBinary)
+		b.BindTo(socket, egress.RelaySocket)
+		proxyURL := "http://" + egress.RelayListen
+		for _, key := range proxyEnvVars {
+			b.SetEnv(key, proxyURL)
+		}
+		b.SetEnv("NO_PROXY", "")
+		b.SetEnv("no_proxy", "")
+	}
+
 	// Set working directory inside sandbox (if different from workspace root)
 	if workDir != "" && workDir != r.config.WorkingDir {
 		b.Bind(workDir)
 		b.Chdir(workDir)
 	}
 
-	// Set the shell command to run
-	b.ShellCommand(command)
+	// Set the shell command to run, behind the egress relay if filtered
+	if filtered {
+		b.Command(egress.RelayBinary, egress.RelayArgs("sh", "-c", command)...)
+	} else {
+		b.ShellCommand(command)
+	}
 
 	// Build the command
 	cmd, err := b.BuildCommand()
@@ -66,6 +104,8 @@ func (r *Runner) buildSandboxedCommand(ctx context.Context, command, workDir str
 		"command", truncate(command, 50),
 		"allowNetwork", r.config.Bubblewrap.AllowNetwork,
 		"clearEnv", r.config.Bubblewrap.ClearEnv,
+		"profile", profileName,
+		"egress", filtered,
 	)
 
 	return cmd, nil
@@ -11,7 +11,7 @@ import (
 
 // buildSandboxedCommand is not available on non-Linux platforms.
 // Returns nil to indicate unsandboxed execution should be used.
-func (r *Runner) buildSandboxedCommand(ctx context.Context, command, workDir string) (*exec.Cmd, error) {
+func (r *Runner) buildSandboxedCommand(ctx context.Context, command, workDir, role string) (*exec.Cmd, error) {
 	if r.config.Bubblewrap.Enabled {
 		L_warn("exec runner: bubblewrap not available on this platform, running unsandboxed")
 	}
@@ -0,0 +1,553 @@
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+const (
+	defaultShellIdle = 30 * time.Minute
+	defaultMaxShells = 8
+	shellGrace       = 2 * time.Second // wait between interrupt, kill and giving up
+	maxShellOutput   = 1 << 20         // per stream, per command
+)
+
+// shellSignal is a platform-neutral signal for the processes a shell runs
+type shellSignal int
+
+const (
+	sigInterrupt shellSignal = iota
+	sigKill
+)
+
+// ShellConfig holds settings for persistent shell sessions
+type ShellConfig struct {
+	IdleTimeout time.Duration // close shells unused for this long (default 30m)
+	MaxShells   int           // maximum concurrent shells (default 8)
+}
+
+// ShellResult is the outcome of one command in a persistent shell
+type ShellResult struct {
+	Stdout    []byte
+	Stderr    []byte
+	ExitCode  int
+	Cwd       string // working directory after the command
+	TimedOut  bool   // command was interrupted; the shell is still running
+	Closed    bool   // the shell exited (e.g. the command ran `exit`)
+	Truncated bool   // output exceeded maxShellOutput
+}
+
+// streamResult is the output of one stream up to a command's end marker
+type streamResult struct {
+	data    []byte
+	dropped int
+	trailer string // text after the marker; empty at EOF
+	eof     bool
+}
+
+// Shell is a long-running bash process that runs commands one at a time,
+// keeping cwd and environment between them.
+//
+// Each command is sent as `eval '<command>'` followed by printf of a random
+// marker with the exit code and $PWD, on both stdout and stderr, so output can
+// be split per command without a pty.
+type Shell struct {
+	name      string
+	sandboxed bool
+	role      string // sandbox profile role the shell was started under
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	marker    string
+	stdout    chan streamResult
+	stderr    chan streamResult
+	done      chan struct{} // closed when the process has exited
+	exitCode  int
+
+	mu       sync.Mutex // one command at a time
+	seq      int64
+	lastUsed atomic.Int64 // unix nanos
+	cwd      string
+}
+
+// startShell starts a bash process in workDir, sandboxed like RunFull with
+// the sandbox profile of role.
+func startShell(r *Runner, name, workDir string, sandboxed bool, role string) (*Shell, error) {
+	var cmd *exec.Cmd
+	if sandboxed && r.config.Bubblewrap.Enabled {
+		sandboxedCmd, err := r.buildSandboxedCommand(context.Background(), "exec bash --noprofile --norc", workDir, role)
+		if err != nil {
+			return nil, fmt.Errorf("sandbox error: %w", err)
+		}
+		cmd = sandboxedCmd
+	}
+	if cmd == nil {
+		cmd = exec.Command("bash", "--noprofile", "--norc")
+		cmd.Dir = workDir
+	}
+	cmd.SysProcAttr = shellSysProcAttr()
+
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	s := &Shell{
+		name:      name,
+		sandboxed: sandboxed,
+		role:      role,
+		cmd:       cmd,
+		marker:    "__GOCLAW_" + hex.EncodeToString(nonce) + "__",
+		stdout:    make(chan streamResult, 4),
+		stderr:    make(chan streamResult, 4),
+		done:      make(chan struct{}),
+		cwd:       workDir,
+	}
+
+	var err error
+	if s.stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start shell: %w", err)
+	}
+	s.touch()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); readStream(stdout, s.marker, s.stdout) }()
+	go func() { defer wg.Done(); readStream(stderr, s.marker, s.stderr) }()
+	go func() {
+		wg.Wait()
+		err := cmd.Wait()
+		s.exitCode = 0
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			s.exitCode = exitErr.ExitCode()
+		}
+		close(s.done)
+		L_debug("exec shell: exited", "name", name, "exitCode", s.exitCode)
+	}()
+
+	L_info("exec shell: started", "name", name, "workDir", workDir, "sandboxed", sandboxed, "pid", cmd.Process.Pid)
+	return s, nil
+}
+
+// readStream splits a stream at marker lines and sends each command's output.
+func readStream(r io.Reader, marker string, ch chan<- streamResult) {
+	defer close(ch)
+	br := bufio.NewReaderSize(r, 64*1024)
+	prefix := []byte(marker + " ")
+	var buf bytes.Buffer
+	dropped := 0
+	atLineStart := true
+
+	for {
+		line, err := br.ReadSlice('\n')
+		if len(line) > 0 {
+			if atLineStart && bytes.HasPrefix(line, prefix) && line[len(line)-1] == '\n' {
+				// The script prints a newline before the marker; drop it
+				data := bytes.Clone(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+				ch <- streamResult{data: data, dropped: dropped, trailer: strings.TrimSpace(string(line[len(prefix):]))}
+				buf.Reset()
+				dropped = 0
+			} else if buf.Len()+len(line) <= maxShellOutput {
+				buf.Write(line)
+			} else {
+				dropped += len(line)
+			}
+			atLineStart = line[len(line)-1] == '\n'
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			ch <- streamResult{data: bytes.Clone(buf.Bytes()), dropped: dropped, eof: true}
+			return
+		}
+	}
+}
+
+// script builds the input sent to the shell for one command.
+func (s *Shell) script(command, workDir string, seq int64) string {
+	var sb strings.Builder
+	if workDir != "" {
+		fmt.Fprintf(&sb, "cd %s && ", shellQuote(workDir))
+	}
+	fmt.Fprintf(&sb, "eval %s </dev/null\n", shellQuote(command))
+	sb.WriteString("__goclaw_rc=$?\n")
+	fmt.Fprintf(&sb, "printf '\\n%s %d %%d %%s\\n' \"$__goclaw_rc\" \"$PWD\"\n", s.marker, seq)
+	fmt.Fprintf(&sb, "printf '\\n%s %d\\n' >&2\n", s.marker, seq)
+	return sb.String()
+}
+
+// Run executes a command in the shell. workDir, if set, is cd'd into first
+// (and stays the shell's cwd). On timeout or cancellation the command's
+// processes are interrupted, then killed; the shell itself keeps running
+// unless they refuse to die.
+func (s *Shell) Run(ctx context.Context, command, workDir string, timeout time.Duration) (*ShellResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.touch()
+	defer s.touch()
+
+	if s.exited() {
+		return nil, fmt.Errorf("shell %q has exited", s.name)
+	}
+
+	s.seq++
+	seq := s.seq
+	if _, err := io.WriteString(s.stdin, s.script(command, workDir, seq)); err != nil {
+		return nil, fmt.Errorf("failed to send command to shell: %w", err)
+	}
+
+	res := &ShellResult{ExitCode: -1, Cwd: s.cwd}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	ctxDone := ctx.Done()
+	stage := 0
+
+	// escalate interrupts, then kills, the running command; finally the shell
+	escalate := func() error {
+		res.TimedOut = true
+		stage++
+		switch stage {
+		case 1:
+			L_warn("exec shell: interrupting command", "name", s.name, "timeout", timeout)
+			if s.signalCommand(sigInterrupt) {
+				timer.Reset(shellGrace)
+				return nil
+			}
+		case 2:
+			L_warn("exec shell: killing command", "name", s.name)
+			if s.signalCommand(sigKill) {
+				timer.Reset(shellGrace)
+				return nil
+			}
+		}
+		s.Close()
+		return fmt.Errorf("command did not stop after timeout; shell %q was closed", s.name)
+	}
+
+	var out streamResult
+	for {
+		select {
+		case r, ok := <-s.stdout:
+			if !ok {
+				return s.closedResult(res), nil
+			}
+			if r.eof {
+				res.Stdout = r.data
+				return s.closedResult(res), nil
+			}
+			if !matchSeq(r.trailer, seq) {
+				continue // left over from an earlier interrupted command
+			}
+			out = r
+		case <-ctxDone:
+			ctxDone = nil
+			if err := escalate(); err != nil {
+				return nil, err
+			}
+			continue
+		case <-timer.C:
+			if err := escalate(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		break
+	}
+
+	res.Stdout = out.data
+	res.Truncated = out.dropped > 0
+	// trailer: "<seq> <exit code> <cwd>"
+	if parts := strings.SplitN(out.trailer, " ", 3); len(parts) == 3 {
+		res.ExitCode, _ = strconv.Atoi(parts[1])
+		res.Cwd = parts[2]
+		s.cwd = res.Cwd
+	}
+
+	// The stderr marker is printed right after the stdout one
+	grace := time.NewTimer(shellGrace)
+	defer grace.Stop()
+	for {
+		select {
+		case r, ok := <-s.stderr:
+			if !ok || r.eof {
+				return res, nil
+			}
+			if !matchSeq(r.trailer, seq) {
+				continue
+			}
+			res.Stderr = r.data
+			res.Truncated = res.Truncated || r.dropped > 0
+			return res, nil
+		case <-grace.C:
+			L_warn("exec shell: stderr marker missing", "name", s.name)
+			return res, nil
+		}
+	}
+}
+
+// closedResult fills in the result for a shell that exited mid-command.
+func (s *Shell) closedResult(res *ShellResult) *ShellResult {
+	<-s.done
+	res.Closed = true
+	res.ExitCode = s.exitCode
+	select {
+	case r, ok := <-s.stderr:
+		if ok {
+			res.Stderr = r.data
+		}
+	default:
+	}
+	return res
+}
+
+func matchSeq(trailer string, seq int64) bool {
+	first, _, _ := strings.Cut(trailer, " ")
+	n, err := strconv.ParseInt(first, 10, 64)
+	return err == nil && n == seq
+}
+
+// signalCommand signals the processes started by the shell, not the shell.
+// Returns false if that isn't supported on this platform.
+func (s *Shell) signalCommand(sig shellSignal) bool {
+	return signalShellChildren(s.cmd.Process.Pid, sig)
+}
+
+// Close terminates the shell and anything it started.
+func (s *Shell) Close() {
+	if s.exited() {
+		return
+	}
+	s.stdin.Close() //nolint:errcheck // bash exits on EOF
+	select {
+	case <-s.done:
+		return
+	case <-time.After(shellGrace):
+	}
+	signalShellChildren(s.cmd.Process.Pid, sigKill)
+	s.cmd.Process.Kill() //nolint:errcheck // may already be gone
+	<-s.done
+}
+
+func (s *Shell) exited() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Shell) touch() {
+	s.lastUsed.Store(time.Now().UnixNano())
+}
+
+func (s *Shell) idleSince() time.Time {
+	return time.Unix(0, s.lastUsed.Load())
+}
+
+// ShellManager owns the persistent shells, keyed by session and name.
+type ShellManager struct {
+	runner *Runner
+	cfg    ShellConfig
+
+	mu     sync.Mutex
+	shells map[string]*Shell
+	stop   chan struct{}
+	once   sync.Once
+}
+
+var (
+	globalShells   *ShellManager
+	globalShellsMu sync.RWMutex
+)
+
+// InitShellManager creates the global shell manager and starts its idle reaper.
+func InitShellManager(runner *Runner, cfg ShellConfig) *ShellManager {
+	m := NewShellManager(runner, cfg)
+	globalShellsMu.Lock()
+	globalShells = m
+	globalShellsMu.Unlock()
+	return m
+}
+
+// GetShellManager returns the global shell manager, or nil if persistent
+// shells are disabled.
+func GetShellManager() *ShellManager {
+	globalShellsMu.RLock()
+	defer globalShellsMu.RUnlock()
+	return globalShells
+}
+
+// NewShellManager creates a shell manager and starts its idle reaper.
+func NewShellManager(runner *Runner, cfg ShellConfig) *ShellManager {
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = defaultShellIdle
+	}
+	if cfg.MaxShells <= 0 {
+		cfg.MaxShells = defaultMaxShells
+	}
+	m := &ShellManager{
+		runner: runner,
+		cfg:    cfg,
+		shells: make(map[string]*Shell),
+		stop:   make(chan struct{}),
+	}
+	go m.reapLoop()
+	return m
+}
+
+// Get returns the named shell for an owner (session), starting it in workDir
+// if needed. A shell started under a different sandbox setting or role is
+// replaced. created reports whether a new shell was started.
+func (m *ShellManager) Get(owner, name, workDir string, sandboxed bool, role string) (shell *Shell, created bool, err error) {
+	key := owner + "\x00" + name
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s, ok := m.shells[key]; ok {
+		if !s.exited() && s.sandboxed == sandboxed && s.role == role {
+			return s, false, nil
+		}
+		delete(m.shells, key)
+		go s.Close()
+	}
+
+	if len(m.shells) >= m.cfg.MaxShells && !m.evictLocked() {
+		return nil, false, fmt.Errorf("too many shell sessions (max %d); close one with `exit`", m.cfg.MaxShells)
+	}
+
+	s, err := startShell(m.runner, name, workDir, sandboxed, role)
+	if err != nil {
+		return nil, false, err
+	}
+	m.shells[key] = s
+	return s, true, nil
+}
+
+// evictLocked closes the least recently used idle shell. Caller holds m.mu.
+func (m *ShellManager) evictLocked() bool {
+	var oldestKey string
+	var oldest *Shell
+	for k, s := range m.shells {
+		if oldest == nil || s.idleSince().Before(oldest.idleSince()) {
+			oldestKey, oldest = k, s
+		}
+	}
+	if oldest == nil || !oldest.mu.TryLock() {
+		return false
+	}
+	oldest.mu.Unlock()
+	delete(m.shells, oldestKey)
+	L_info("exec shell: evicted", "name", oldest.name)
+	go oldest.Close()
+	return true
+}
+
+// Remove forgets a shell that has exited.
+func (m *ShellManager) Remove(owner, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := owner + "\x00" + name
+	if s, ok := m.shells[key]; ok && s.exited() {
+		delete(m.shells, key)
+	}
+}
+
+// Count returns the number of open shells.
+func (m *ShellManager) Count() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.shells)
+}
+
+func (m *ShellManager) reapLoop() {
+	ticker := time.NewTicker(min(m.cfg.IdleTimeout/4, time.Minute))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.reap()
+		}
+	}
+}
+
+// reap closes shells that have exited or been idle too long.
+func (m *ShellManager) reap() {
+	cutoff := time.Now().Add(-m.cfg.IdleTimeout)
+	m.mu.Lock()
+	var closing []*Shell
+	for k, s := range m.shells {
+		if s.exited() {
+			delete(m.shells, k)
+			continue
+		}
+		if s.idleSince().After(cutoff) || !s.mu.TryLock() {
+			continue // recently used or running a command
+		}
+		s.mu.Unlock()
+		delete(m.shells, k)
+		closing = append(closing, s)
+	}
+	m.mu.Unlock()
+
+	for _, s := range closing {
+		L_info("exec shell: closing idle shell", "name", s.name, "idleSince", s.idleSince())
+		s.Close()
+	}
+}
+
+// CloseAll stops the reaper and closes every shell (gateway shutdown).
+func (m *ShellManager) CloseAll() {
+	m.once.Do(func() { close(m.stop) })
+	m.mu.Lock()
+	shells := m.shells
+	m.shells = make(map[string]*Shell)
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, s := range shells {
+		wg.Add(1)
+		go func(s *Shell) {
+			defer wg.Done()
+			s.Close()
+		}(s)
+	}
+	wg.Wait()
+	if len(shells) > 0 {
+		L_info("exec shell: closed all shells", "count", len(shells))
+	}
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,84 @@
+//go:build linux
+
+package exec
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// shellSysProcAttr kills an unsandboxed shell if GoClaw dies (bwrap has
+// --die-with-parent for sandboxed ones).
+func shellSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+}
+
+// signalShellChildren signals every process below the bash started as (or
+// under, when sandboxed) pid, leaving bash itself running.
+func signalShellChildren(pid int, sig shellSignal) bool {
+	children := processChildren()
+
+	// Sandboxed shells run as bwrap -> bwrap -> bash; find the bash
+	shell := pid
+	for procComm(shell) != "bash" {
+		kids := children[shell]
+		if len(kids) != 1 {
+			return false
+		}
+		shell = kids[0]
+	}
+
+	signal := syscall.SIGINT
+	if sig == sigKill {
+		signal = syscall.SIGKILL
+	}
+	queue := append([]int(nil), children[shell]...)
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = append(queue[1:], children[p]...)
+		syscall.Kill(p, signal) //nolint:errcheck // process may have exited
+	}
+	return true
+}
+
+// processChildren maps each pid to its children, from /proc.
+func processChildren() map[int][]int {
+	children := make(map[int][]int)
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return children
+	}
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile("/proc/" + e.Name() + "/stat")
+		if err != nil {
+			continue
+		}
+		// Format: pid (comm) state ppid ...; comm may contain spaces
+		i := strings.LastIndexByte(string(data), ')')
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(string(data[i+1:]))
+		if len(fields) < 2 {
+			continue
+		}
+		if ppid, err := strconv.Atoi(fields[1]); err == nil {
+			children[ppid] = append(children[ppid], pid)
+		}
+	}
+	return children
+}
+
+func procComm(pid int) string {
+	data, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/comm")
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
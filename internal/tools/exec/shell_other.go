@@ -0,0 +1,14 @@
+//go:build !linux
+
+package exec
+
+import "syscall"
+
+func shellSysProcAttr() *syscall.SysProcAttr {
+	return nil
+}
+
+// signalShellChildren is not supported here; timed-out commands close the shell.
+func signalShellChildren(pid int, sig shellSignal) bool {
+	return false
+}
@@ -0,0 +1,63 @@
+package exec
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShellKeepsState(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	dir := t.TempDir()
+	m := NewShellManager(NewRunner(RunnerConfig{WorkingDir: dir}), ShellConfig{})
+	defer m.CloseAll()
+
+	shell, created, err := m.Get("s1", "main", dir, false, "")
+	if err != nil || !created {
+		t.Fatalf("Get: created=%v err=%v", created, err)
+	}
+	ctx := context.Background()
+	run := func(cmd string, timeout time.Duration) *ShellResult {
+		t.Helper()
+		res, err := shell.Run(ctx, cmd, "", timeout)
+		if err != nil {
+			t.Fatalf("Run(%q): %v", cmd, err)
+		}
+		return res
+	}
+
+	run("mkdir sub && cd sub && export GREETING=hi", 5*time.Second)
+	res := run(`printf '%s' "$GREETING"; echo oops >&2; false`, 5*time.Second)
+	if string(res.Stdout) != "hi" || string(res.Stderr) != "oops\n" || res.ExitCode != 1 {
+		t.Errorf("got stdout=%q stderr=%q exit=%d", res.Stdout, res.Stderr, res.ExitCode)
+	}
+	if !strings.HasSuffix(res.Cwd, "/sub") {
+		t.Errorf("cwd not kept: %q", res.Cwd)
+	}
+
+	// Syntax errors don't kill the shell
+	if res := run("if then", 5*time.Second); res.ExitCode != 2 {
+		t.Errorf("syntax error exit = %d", res.ExitCode)
+	}
+
+	// A timeout interrupts the command but keeps the shell
+	res = run("sleep 30", 300*time.Millisecond)
+	if !res.TimedOut {
+		t.Error("expected timeout")
+	}
+	if res := run("echo $GREETING", 5*time.Second); string(res.Stdout) != "hi\n" {
+		t.Errorf("shell state lost after timeout: %q", res.Stdout)
+	}
+
+	// exit closes the shell
+	if res := run("exit 3", 5*time.Second); !res.Closed || res.ExitCode != 3 {
+		t.Errorf("exit: closed=%v code=%d", res.Closed, res.ExitCode)
+	}
+	if _, created, _ := m.Get("s1", "main", dir, false, ""); !created {
+		t.Error("expected a new shell after exit")
+	}
+}
@@ -47,7 +47,8 @@ func (t *Tool) Name() string {
 }
 
 func (t *Tool) Description() string {
-	return "Execute a shell command. Returns stdout and stderr. Use with caution."
+	return "Execute a shell command. Returns stdout and stderr. Use with caution. " +
+		"Set 'session' to run in a named persistent shell that keeps the working directory, exported variables and activated virtualenvs between calls; run `exit` to close it."
 }
 
 func (t *Tool) Schema() map[string]any {
@@ -66,6 +67,10 @@ func (t *Tool) Schema() map[string]any {
 				"type":        "integer",
 				"description": "Optional: Timeout in seconds. Defaults to 1800 (30 minutes).",
 			},
+			"session": map[string]any{
+				"type":        "string",
+				"description": "Optional: Name of a persistent shell session (e.g. \"main\"). Started on first use; with working_dir, cds there before the command.",
+			},
 		},
 		"required": []string{"command"},
 	}
@@ -75,6 +80,7 @@ type execInput struct {
 	Command        string `json:"command"`
 	WorkingDir     string `json:"working_dir,omitempty"`
 	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+	Session        string `json:"session,omitempty"`
 }
 
 func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolResult, error) {
@@ -86,7 +92,7 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 	// Set working directory
 	workDir := params.WorkingDir
 	if workDir == "" {
-		workDir = t.runner.Config().WorkingDir
+		workDir = types.WorkspaceDirFromContext(ctx, t.runner.Config().WorkingDir)
 	}
 
 	// Check if user has sandbox disabled
@@ -105,6 +111,10 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 		cmdPreview = cmdPreview[:30] + "..."
 	}
 
+	if params.Session != "" {
+		return t.executeInShell(ctx, params, useSandbox, cmdPreview)
+	}
+
 	L_info("exec tool: running", "cmd", cmdPreview, "workDir", workDir, "sandboxed", useSandbox)
 
 	// Handle custom timeout if specified
@@ -122,20 +132,7 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 	}
 
 	// Build formatted result (ExecTool shows both stdout and stderr with headers)
-	var output strings.Builder
-
-	if len(result.Stdout) > 0 {
-		output.WriteString("STDOUT:\n")
-		output.Write(result.Stdout)
-	}
-
-	if len(result.Stderr) > 0 {
-		if output.Len() > 0 {
-			output.WriteString("\n")
-		}
-		output.WriteString("STDERR:\n")
-		output.Write(result.Stderr)
-	}
+	output := formatOutput(result.Stdout, result.Stderr)
 
 	if result.ExitCode != 0 {
 		if output.Len() > 0 {
@@ -152,6 +149,84 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 	return types.ExternalTextResult(output.String(), "exec"), nil
 }
 
+// executeInShell runs the command in the caller's named persistent shell
+func (t *Tool) executeInShell(ctx context.Context, params execInput, useSandbox bool, cmdPreview string) (*types.ToolResult, error) {
+	shells := GetShellManager()
+	if shells == nil {
+		return types.ErrorResult("persistent shell sessions are disabled (tools.exec.sessions.enabled)"), nil
+	}
+
+	// Shells belong to the conversation session (or the user, outside one)
+	owner := ""
+	if sessCtx := types.GetSessionContext(ctx); sessCtx != nil {
+		owner = sessCtx.SessionKey
+		if owner == "" && sessCtx.User != nil {
+			owner = "user:" + sessCtx.User.ID
+		}
+	}
+
+	startDir := params.WorkingDir
+	if startDir == "" {
+		startDir = types.WorkspaceDirFromContext(ctx, t.runner.Config().WorkingDir)
+	}
+	shell, created, err := shells.Get(owner, params.Session, startDir, useSandbox, roleFromContext(ctx))
+	if err != nil {
+		return types.ErrorResult(err.Error()), nil
+	}
+
+	timeout := t.runner.Config().Timeout
+	if params.TimeoutSeconds > 0 {
+		timeout = time.Duration(params.TimeoutSeconds) * time.Second
+	}
+
+	L_info("exec tool: running in shell", "cmd", cmdPreview, "session", params.Session, "new", created, "sandboxed", useSandbox)
+
+	result, err := shell.Run(ctx, params.Command, params.WorkingDir, timeout)
+	if err != nil {
+		shells.Remove(owner, params.Session)
+		return nil, err
+	}
+
+	output := formatOutput(result.Stdout, result.Stderr)
+	if output.Len() > 0 {
+		output.WriteString("\n")
+	}
+	if result.Truncated {
+		output.WriteString(fmt.Sprintf("[output truncated at %d bytes per stream]\n", maxShellOutput))
+	}
+	switch {
+	case result.Closed:
+		shells.Remove(owner, params.Session)
+		output.WriteString(fmt.Sprintf("[shell %q closed, exit code %d]", params.Session, result.ExitCode))
+	case result.TimedOut:
+		output.WriteString(fmt.Sprintf("[shell %q: command interrupted after %v; exit code %d, cwd %s; the shell is still running]", params.Session, timeout, result.ExitCode, result.Cwd))
+		L_warn("exec tool: shell command timed out", "cmd", cmdPreview, "session", params.Session)
+	default:
+		output.WriteString(fmt.Sprintf("[shell %q: exit code %d, cwd %s]", params.Session, result.ExitCode, result.Cwd))
+	}
+
+	return types.ExternalTextResult(output.String(), "exec"), nil
+}
+
+// formatOutput renders stdout and stderr with headers
+func formatOutput(stdout, stderr []byte) *strings.Builder {
+	var output strings.Builder
+
+	if len(stdout) > 0 {
+		output.WriteString("STDOUT:\n")
+		output.Write(stdout)
+	}
+
+	if len(stderr) > 0 {
+		if output.Len() > 0 {
+			output.WriteString("\n")
+		}
+		output.WriteString("STDERR:\n")
+		output.Write(stderr)
+	}
+	return &output
+}
+
 // ToolConfig holds configuration for the exec tool
 type ToolConfig struct {
 	WorkingDir     string
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/roelfdiedericks/goclaw/internal/audit"
 	hasspkg "github.com/roelfdiedericks/goclaw/internal/hass"
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
 	"github.com/roelfdiedericks/goclaw/internal/media"
@@ -59,6 +60,14 @@ type hassInput struct {
 	Wake           *bool  `json:"wake,omitempty"`            // trigger immediate agent invocation (default: true)
 	Enabled        *bool  `json:"enabled,omitempty"`         // enable/disable subscription (for update action)
 	SubscriptionID string `json:"subscription_id,omitempty"` // subscription ID for unsubscribe/update
+
+	// Subscription conditions (nil = not specified, empty = clear on update)
+	From       []string `json:"from,omitempty"`       // old state must be one of these
+	To         []string `json:"to,omitempty"`         // new state must be one of these
+	Conditions []string `json:"conditions,omitempty"` // attribute comparisons, e.g. "temperature > 28"
+	For        *string  `json:"for,omitempty"`        // conditions must hold this long, e.g. "10m"
+	After      *string  `json:"after,omitempty"`      // time window start "HH:MM"
+	Before     *string  `json:"before,omitempty"`     // time window end "HH:MM"
 }
 
 // NewTool creates a new Home Assistant tool.
@@ -102,9 +111,9 @@ Registry Actions (WebSocket):
 - entities: List entities with metadata (optional pattern/regex filter on entity_id/name/device_class/area_id)
 
 Subscription Actions:
-- subscribe: Subscribe to state_changed events (pattern OR regex, optional debounce/interval/prompt/prefix/full/wake)
+- subscribe: Subscribe to state_changed events (pattern OR regex, optional debounce/interval/prompt/prefix/full/wake and conditions)
 - unsubscribe: Cancel a subscription (requires subscription_id)
-- update: Modify subscription parameters (requires subscription_id, optional prompt/debounce/interval/prefix/full/wake/enabled)
+- update: Modify subscription parameters (requires subscription_id, optional prompt/debounce/interval/prefix/full/wake/enabled and conditions; pass [] or "" to clear a condition)
 - enable: Enable a disabled subscription (requires subscription_id)
 - disable: Disable a subscription without removing it (requires subscription_id)
 - subscriptions: List all subscriptions with enabled/disabled status
@@ -113,6 +122,12 @@ Rate limiting:
 - debounce: Suppress same entity:state events within window (default 5s)
 - interval: Per-entity rate limit regardless of state (default 0 = disabled)
 
+Conditions (all must hold before the agent is woken):
+- from/to: Old/new state must be one of the listed values
+- conditions: Attribute comparisons like "temperature > 28" or "battery < 15" (use "state" for the numeric state)
+- for: Conditions must hold continuously this long before firing, e.g. "10m"
+- after/before: Only fire within a local time-of-day window "HH:MM" (may wrap midnight)
+
 Examples:
 - hass(action="state", entity="light.kitchen")
 - hass(action="states", filter="*kitchen*")
@@ -125,6 +140,8 @@ Examples:
 - hass(action="entities", regex="^light\\.")
 - hass(action="subscribe", pattern="binary_sensor.driveway*", prompt="Notify me someone is at the driveway")
 - hass(action="subscribe", pattern="sensor.load*", interval=60, prompt="Alert if load exceeds 1500W")
+- hass(action="subscribe", pattern="sensor.*_battery", conditions=["state < 15"], prompt="Tell me which battery is low")
+- hass(action="subscribe", pattern="binary_sensor.garage_door", to=["on"], for="10m", after="22:00", before="06:00", prompt="Garage door left open at night")
 - hass(action="unsubscribe", subscription_id="550e8400-e29b-41d4-a716-446655440000")`
 }
 
@@ -226,6 +243,33 @@ func (t *Tool) Schema() map[string]any {
 				"type":        "string",
 				"description": "Subscription ID for unsubscribe/update action",
 			},
+			"from": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Subscription condition: old state must be one of these (e.g., [\"off\"])",
+			},
+			"to": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Subscription condition: new state must be one of these (e.g., [\"on\"])",
+			},
+			"conditions": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Subscription attribute comparisons, all must hold (e.g., [\"temperature > 28\"], [\"state < 15\"]). Operators: > >= < <= == !=",
+			},
+			"for": map[string]any{
+				"type":        "string",
+				"description": "Subscription condition: conditions must hold this long before firing (e.g., 10m)",
+			},
+			"after": map[string]any{
+				"type":        "string",
+				"description": "Subscription condition: only fire after this local time (HH:MM)",
+			},
+			"before": map[string]any{
+				"type":        "string",
+				"description": "Subscription condition: only fire before this local time (HH:MM), may wrap midnight",
+			},
 		},
 		"required": []string{"action"},
 	}
@@ -338,13 +382,27 @@ func (t *Tool) getState(ctx context.Context, in hassInput) (json.RawMessage, err
 		return nil, fmt.Errorf("entity is required for state action")
 	}
 
+	// Served from the subscription manager's cache when it is current
+	if t.manager != nil {
+		if st, ok := t.manager.CachedState(in.Entity); ok {
+			L_debug("hass: state served from cache", "entity", in.Entity)
+			return json.Marshal(st)
+		}
+	}
+
 	path := fmt.Sprintf("/api/states/%s", url.PathEscape(in.Entity))
 	return t.client.Get(ctx, path)
 }
 
 // getStates retrieves all entity states with optional filtering.
 func (t *Tool) getStates(ctx context.Context, in hassInput) (json.RawMessage, error) {
-	result, err := t.client.Get(ctx, "/api/states")
+	var result json.RawMessage
+	var err error
+	if cached, ok := t.cachedStates(); ok {
+		result, err = json.Marshal(cached)
+	} else {
+		result, err = t.client.Get(ctx, "/api/states")
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -382,6 +440,18 @@ func (t *Tool) getStates(ctx context.Context, in hassInput) (json.RawMessage, er
 	return json.Marshal(filtered)
 }
 
+// cachedStates returns all states from the subscription manager's cache, if current.
+func (t *Tool) cachedStates() ([]hasspkg.HAState, bool) {
+	if t.manager == nil {
+		return nil, false
+	}
+	states, ok := t.manager.CachedStates()
+	if ok {
+		L_debug("hass: states served from cache", "count", len(states))
+	}
+	return states, ok
+}
+
 // matchStateFilter checks if a state object matches the filter pattern.
 // Matches case-insensitively against: entity_id, friendly_name, device_class.
 func (t *Tool) matchStateFilter(state map[string]any, filter string) bool {
@@ -458,14 +528,30 @@ func (t *Tool) callService(ctx context.Context, in hassInput) (json.RawMessage,
 		// If 400 error, retry without return_response (service doesn't support it)
 		if hassErr, ok := err.(*Error); ok && hassErr.StatusCode == 400 {
 			L_debug("hass: retrying without return_response", "service", in.Service)
-			return t.client.Post(ctx, basePath, data)
+			result, err = t.client.Post(ctx, basePath, data)
 		}
+	}
+	recordServiceCall(ctx, in.Service, data, result, err)
+	if err != nil {
 		return nil, err
 	}
 
 	return result, nil
 }
 
+// recordServiceCall writes a service call to the audit log.
+func recordServiceCall(ctx context.Context, service string, data map[string]any, result json.RawMessage, err error) {
+	event := audit.Event{
+		Type:   "hass.call_service",
+		Inputs: map[string]any{"service": service, "data": data},
+		Result: string(result),
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	audit.Record(ctx, event)
+}
+
 // getCamera captures a camera snapshot and saves it to media storage.
 // Returns absolute path (for ContentBlock), relative path (for {{media:...}}), and MIME type.
 func (t *Tool) getCamera(ctx context.Context, in hassInput) (absPath, relPath, mimeType string, err error) {
@@ -795,6 +881,12 @@ func (t *Tool) subscribe(ctx context.Context, in hassInput) (string, error) {
 		sub.Wake = *in.Wake
 	}
 
+	conds, _, err := buildConditions(nil, in)
+	if err != nil {
+		return t.errorResult("error", err.Error())
+	}
+	sub.Conditions = conds
+
 	if err := t.manager.Subscribe(sub); err != nil {
 		return t.errorResult("error", err.Error())
 	}
@@ -814,6 +906,9 @@ func (t *Tool) subscribe(ctx context.Context, in hassInput) (string, error) {
 		"connected":  t.manager.IsConnected(),
 		"created_at": sub.CreatedAt.Format(time.RFC3339),
 	}
+	if sub.Conditions != nil {
+		result["conditions"] = sub.Conditions
+	}
 	jsonResult, _ := json.Marshal(result)
 	return string(jsonResult), nil
 }
@@ -920,6 +1015,24 @@ func (t *Tool) updateSubscription(ctx context.Context, in hassInput) (string, er
 	updates.Wake = in.Wake
 	updates.Enabled = in.Enabled
 
+	// Conditions: merge provided fields into the current ones
+	var current *hasspkg.Conditions
+	for _, existing := range t.manager.GetSubscriptions() {
+		if existing.ID == in.SubscriptionID {
+			current = existing.Conditions
+		}
+	}
+	conds, changed, err := buildConditions(current, in)
+	if err != nil {
+		return t.errorResult("error", err.Error())
+	}
+	if changed {
+		if conds == nil {
+			conds = &hasspkg.Conditions{}
+		}
+		updates.Conditions = conds
+	}
+
 	sub, err := t.manager.UpdateSubscription(in.SubscriptionID, updates)
 	if err != nil {
 		return t.errorResult("error", err.Error())
@@ -935,6 +1048,57 @@ func (t *Tool) updateSubscription(ctx context.Context, in hassInput) (string, er
 	return string(jsonResult), nil
 }
 
+// buildConditions applies the condition fields from the input on top of base.
+// Returns nil when no condition remains, and whether any condition field was given.
+func buildConditions(base *hasspkg.Conditions, in hassInput) (*hasspkg.Conditions, bool, error) {
+	conds := hasspkg.Conditions{}
+	if base != nil {
+		conds = *base
+	}
+	changed := false
+
+	if in.From != nil {
+		conds.From, changed = in.From, true
+	}
+	if in.To != nil {
+		conds.To, changed = in.To, true
+	}
+	if in.Conditions != nil {
+		conds.Attributes, changed = nil, true
+		for _, expr := range in.Conditions {
+			ac, err := hasspkg.ParseAttributeCondition(expr)
+			if err != nil {
+				return nil, false, err
+			}
+			conds.Attributes = append(conds.Attributes, ac)
+		}
+	}
+	if in.For != nil {
+		conds.ForSeconds, changed = 0, true
+		if *in.For != "" {
+			d, err := time.ParseDuration(*in.For)
+			if err != nil || d < 0 {
+				return nil, false, fmt.Errorf("invalid for duration %q (expected e.g. 10m)", *in.For)
+			}
+			conds.ForSeconds = int(d.Seconds())
+		}
+	}
+	if in.After != nil {
+		conds.After, changed = *in.After, true
+	}
+	if in.Before != nil {
+		conds.Before, changed = *in.Before, true
+	}
+
+	if err := conds.Validate(); err != nil {
+		return nil, false, err
+	}
+	if conds.IsEmpty() {
+		return nil, changed, nil
+	}
+	return &conds, changed, nil
+}
+
 // listSubscriptions returns all active subscriptions.
 func (t *Tool) listSubscriptions(ctx context.Context) (string, error) {
 	if t.manager == nil {
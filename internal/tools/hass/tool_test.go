@@ -0,0 +1,61 @@
+package hass
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/roelfdiedericks/goclaw/internal/hass/hasstest"
+)
+
+func execute(t *testing.T, tool *Tool, input string) string {
+	t.Helper()
+	result, err := tool.Execute(context.Background(), json.RawMessage(input))
+	if err != nil {
+		t.Fatalf("Execute(%s): %v", input, err)
+	}
+	return result.Content[0].Text
+}
+
+func TestToolAgainstFakeServer(t *testing.T) {
+	srv := hasstest.NewServer("test-token")
+	defer srv.Close()
+	srv.SetState("light.kitchen", "off", map[string]any{"friendly_name": "Kitchen Light"})
+	srv.SetState("sensor.outdoor_temperature", "14.2", map[string]any{"device_class": "temperature"})
+
+	tool, err := NewTool(srv.Config(), nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var state struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal([]byte(execute(t, tool, `{"action":"state","entity":"light.kitchen"}`)), &state); err != nil || state.State != "off" {
+		t.Fatalf("state = %+v, err %v", state, err)
+	}
+
+	out := execute(t, tool, `{"action":"states","class":"temperature"}`)
+	if !strings.Contains(out, "sensor.outdoor_temperature") || strings.Contains(out, "light.kitchen") {
+		t.Errorf("states filtered by class = %s", out)
+	}
+
+	execute(t, tool, `{"action":"call","service":"light.turn_on","entity":"light.kitchen"}`)
+	if st, _ := srv.State("light.kitchen"); st.State != "on" {
+		t.Errorf("light.kitchen = %q after turn_on, want on", st.State)
+	}
+	if calls := srv.Calls(); len(calls) != 1 || calls[0].Service != "turn_on" {
+		t.Errorf("calls = %+v", calls)
+	}
+
+	out = execute(t, tool, `{"action":"history","entity":"light.kitchen"}`)
+	if strings.Count(out, `"light.kitchen"`) != 2 {
+		t.Errorf("history should contain off and on entries: %s", out)
+	}
+
+	out = execute(t, tool, `{"action":"state","entity":"light.missing"}`)
+	if !strings.Contains(out, "404") {
+		t.Errorf("missing entity should report 404: %s", out)
+	}
+}
@@ -121,7 +121,7 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 	isExecMode := false
 
 	if params.File != "" {
-		data, err = t.readFile(params.File, sandboxed)
+		data, err = t.readFile(ctx, params.File, sandboxed)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read file: %w", err)
 		}
@@ -156,9 +156,9 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 }
 
 // readFile reads a JSON file, respecting sandbox settings
-func (t *Tool) readFile(path string, sandboxed bool) ([]byte, error) {
+func (t *Tool) readFile(ctx context.Context, path string, sandboxed bool) ([]byte, error) {
 	if sandboxed {
-		return sandbox.GetManager().ReadFile(path, t.workingDir)
+		return sandbox.GetManager().ReadFile(path, types.WorkspaceDirFromContext(ctx, t.workingDir))
 	}
 
 	expandedPath := expandPath(path)
@@ -171,6 +171,11 @@ func (t *Tool) execCommand(ctx context.Context, command string, sandboxed bool)
 	return t.runner.Run(ctx, command, useSandbox)
 }
 
+// Query runs a jq query on JSON data and formats the results like the jq tool.
+func Query(query string, data []byte, raw bool, compact bool) (string, error) {
+	return executeJQ(query, data, raw, compact)
+}
+
 // executeJQ parses and executes a jq query on JSON data
 func executeJQ(query string, data []byte, raw bool, compact bool) (string, error) {
 	var input interface{}
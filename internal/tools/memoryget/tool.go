@@ -13,6 +13,7 @@ import (
 // Tool reads memory file content
 type Tool struct {
 	manager *memory.Manager
+	resolve func(ctx context.Context) *memory.Manager
 }
 
 // NewTool creates a new memory get tool
@@ -20,6 +21,22 @@ func NewTool(manager *memory.Manager) *Tool {
 	return &Tool{manager: manager}
 }
 
+// NewToolWithResolver creates the tool with a per-run manager lookup, so named
+// agents with their own workspace read their own memory files.
+func NewToolWithResolver(manager *memory.Manager, resolve func(ctx context.Context) *memory.Manager) *Tool {
+	return &Tool{manager: manager, resolve: resolve}
+}
+
+// managerFor returns the memory manager for the current run
+func (t *Tool) managerFor(ctx context.Context) *memory.Manager {
+	if t.resolve != nil {
+		if m := t.resolve(ctx); m != nil {
+			return m
+		}
+	}
+	return t.manager
+}
+
 func (t *Tool) Name() string {
 	return "memory_get"
 }
@@ -73,7 +90,8 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 
 	L_debug("memory_get: executing", "path", params.Path, "from", params.From, "lines", params.Lines)
 
-	if t.manager == nil {
+	manager := t.managerFor(ctx)
+	if manager == nil {
 		L_warn("memory_get: manager not available")
 		output := memoryGetOutput{
 			Path:  params.Path,
@@ -82,7 +100,7 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 		return marshalOutput(output)
 	}
 
-	text, err := t.manager.ReadFile(params.Path, params.From, params.Lines)
+	text, err := manager.ReadFile(params.Path, params.From, params.Lines)
 	if err != nil {
 		L_warn("memory_get: read failed", "path", params.Path, "error", err)
 		output := memoryGetOutput{
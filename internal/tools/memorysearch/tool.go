@@ -13,6 +13,7 @@ import (
 // Tool searches memory files semantically
 type Tool struct {
 	manager *memory.Manager
+	resolve func(ctx context.Context) *memory.Manager
 }
 
 // NewTool creates a new memory search tool
@@ -20,6 +21,22 @@ func NewTool(manager *memory.Manager) *Tool {
 	return &Tool{manager: manager}
 }
 
+// NewToolWithResolver creates the tool with a per-run manager lookup, so named
+// agents with their own workspace search their own memory index.
+func NewToolWithResolver(manager *memory.Manager, resolve func(ctx context.Context) *memory.Manager) *Tool {
+	return &Tool{manager: manager, resolve: resolve}
+}
+
+// managerFor returns the memory manager for the current run
+func (t *Tool) managerFor(ctx context.Context) *memory.Manager {
+	if t.resolve != nil {
+		if m := t.resolve(ctx); m != nil {
+			return m
+		}
+	}
+	return t.manager
+}
+
 func (t *Tool) Name() string {
 	return "memory_search"
 }
@@ -73,7 +90,8 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 
 	L_debug("memory_search: executing", "query", truncate(params.Query, 50), "maxResults", params.MaxResults)
 
-	if t.manager == nil {
+	manager := t.managerFor(ctx)
+	if manager == nil {
 		L_warn("memory_search: manager not available")
 		output := memorySearchOutput{
 			Results: []memory.SearchResult{},
@@ -82,7 +100,7 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 		return marshalOutput(output)
 	}
 
-	results, err := t.manager.Search(ctx, params.Query, params.MaxResults, params.MinScore)
+	results, err := manager.Search(ctx, params.Query, params.MaxResults, params.MinScore)
 	if err != nil {
 		L_error("memory_search: search failed", "error", err)
 		output := memorySearchOutput{
@@ -92,7 +110,7 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 		return marshalOutput(output)
 	}
 
-	_, _, provider, _ := t.manager.Stats()
+	_, _, provider, _ := manager.Stats()
 
 	L_info("memory_search: completed", "query", truncate(params.Query, 30), "results", len(results), "provider", provider)
 
@@ -70,6 +70,9 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 		return nil, fmt.Errorf("invalid input: %w", err)
 	}
 
+	// Named agents resolve paths against their own workspace
+	workingDir := types.WorkspaceDirFromContext(ctx, t.workingDir)
+
 	// Check if user has sandbox disabled
 	sandboxed := true
 	if sessCtx := types.GetSessionContext(ctx); sessCtx != nil && sessCtx.User != nil {
@@ -84,17 +87,17 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 
 	if sandboxed {
 		// Validate path and read file (sandbox validation)
-		content, err = sandbox.GetManager().ReadFile(params.Path, t.workingDir)
+		content, err = sandbox.GetManager().ReadFile(params.Path, workingDir)
 		// Resolve path for image reference
 		resolvedPath = params.Path
 		if !filepath.IsAbs(resolvedPath) {
-			resolvedPath = filepath.Join(t.workingDir, resolvedPath)
+			resolvedPath = filepath.Join(workingDir, resolvedPath)
 		}
 	} else {
 		// No sandbox: resolve relative paths from workingDir, allow any absolute path
 		resolvedPath = params.Path
 		if !filepath.IsAbs(resolvedPath) {
-			resolvedPath = filepath.Join(t.workingDir, resolvedPath)
+			resolvedPath = filepath.Join(workingDir, resolvedPath)
 		}
 		content, err = os.ReadFile(resolvedPath)
 	}
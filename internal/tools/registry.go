@@ -8,12 +8,14 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/roelfdiedericks/goclaw/internal/audit"
 	"github.com/roelfdiedericks/goclaw/internal/types"
 )
 
 // Registry holds all registered tools
 type Registry struct {
 	tools map[string]Tool
+	spill *Spillover // optional large-result policy
 	mu    sync.RWMutex
 }
 
@@ -31,6 +33,20 @@ func (r *Registry) Register(tool Tool) {
 	r.tools[tool.Name()] = tool
 }
 
+// SetSpillover enables spill-over of results exceeding their token budget
+func (r *Registry) SetSpillover(s *Spillover) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spill = s
+}
+
+// Unregister removes a tool from the registry
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tools, name)
+}
+
 // Get returns a tool by name
 func (r *Registry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
@@ -47,17 +63,73 @@ func (r *Registry) Has(name string) bool {
 	return ok
 }
 
-// Execute runs a tool by name with the given input
+// RoleRestricted is implemented by tools that limit which user roles may use
+// them, on top of the role's own tool allowlist.
+type RoleRestricted interface {
+	AllowedRoles() []string
+}
+
+// AllowedForRole returns false if the named tool restricts roles and role is
+// not among them. Unknown and unrestricted tools are allowed.
+func (r *Registry) AllowedForRole(name, role string) bool {
+	r.mu.RLock()
+	tool, ok := r.tools[name]
+	r.mu.RUnlock()
+
+	rr, restricted := tool.(RoleRestricted)
+	if !ok || !restricted {
+		return true
+	}
+	for _, allowed := range rr.AllowedRoles() {
+		if allowed == role || allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute runs a tool by name with the given input. The input is validated
+// against the tool's schema first; violations return an *InputError without
+// running the tool. Results over their token budget are spilled (see Spillover).
 func (r *Registry) Execute(ctx context.Context, name string, input json.RawMessage) (*types.ToolResult, error) {
 	r.mu.RLock()
 	tool, ok := r.tools[name]
+	spill := r.spill
 	r.mu.RUnlock()
 
 	if !ok {
 		return nil, fmt.Errorf("unknown tool: %s", name)
 	}
 
-	return tool.Execute(ctx, input)
+	input, err := ValidateInput(tool, input)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tool.Execute(ctx, input)
+	recordExecution(ctx, name, input, result, err)
+	if err != nil || spill == nil {
+		return result, err
+	}
+	return spill.Apply(ctx, name, result), nil
+}
+
+// recordExecution writes a tool call to the audit log.
+func recordExecution(ctx context.Context, name string, input json.RawMessage, result *types.ToolResult, err error) {
+	event := audit.Event{
+		Type:   "tool." + name,
+		Inputs: audit.InputsFromJSON(input),
+	}
+	if result != nil {
+		event.Result = result.GetText()
+		if result.IsError && err == nil {
+			event.Error = "tool reported error"
+		}
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	audit.Record(ctx, event)
 }
 
 // List returns all registered tool names
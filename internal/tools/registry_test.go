@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/roelfdiedericks/goclaw/internal/types"
+)
+
+type fakeTool struct {
+	calls int
+	input json.RawMessage
+}
+
+func (f *fakeTool) Name() string        { return "fake" }
+func (f *fakeTool) Description() string { return "fake tool" }
+func (f *fakeTool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{"type": "string", "enum": []string{"get", "set"}},
+			"count":  map[string]any{"type": "integer"},
+		},
+		"required":             []string{"action"},
+		"additionalProperties": false,
+	}
+}
+func (f *fakeTool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolResult, error) {
+	f.calls++
+	f.input = input
+	return types.TextResult("ok"), nil
+}
+
+func TestExecuteValidatesInput(t *testing.T) {
+	tool := &fakeTool{}
+	reg := NewRegistry()
+	reg.Register(tool)
+
+	invalid := map[string]string{
+		`{}`:                              `missing required property "action"`,
+		`{"action":"delete"}`:             `$.action: must be one of ["get", "set"]`,
+		`{"action":"get","count":"3"}`:    `$.count: expected integer, got string`,
+		`{"action":"get","verbose":true}`: `unknown property "verbose"`,
+		`not json`:                        `invalid JSON`,
+	}
+	for input, want := range invalid {
+		_, err := reg.Execute(context.Background(), "fake", json.RawMessage(input))
+		var inputErr *InputError
+		if !errors.As(err, &inputErr) {
+			t.Fatalf("%s: expected InputError, got %v", input, err)
+		}
+		if !strings.Contains(err.Error(), want) || !strings.HasPrefix(err.Error(), "invalid input for tool fake: ") {
+			t.Errorf("%s: error %q does not contain %q", input, err, want)
+		}
+	}
+	if tool.calls != 0 {
+		t.Fatalf("tool executed %d times with invalid input", tool.calls)
+	}
+
+	if _, err := reg.Execute(context.Background(), "fake", json.RawMessage(`{"action":"set","count":2}`)); err != nil {
+		t.Fatalf("valid input rejected: %v", err)
+	}
+	if tool.calls != 1 {
+		t.Fatalf("expected 1 call, got %d", tool.calls)
+	}
+}
@@ -0,0 +1,312 @@
+// Package script provides declarative tools defined in workspace YAML files.
+//
+// Each tools/*.yaml file declares one tool: a name, description, JSON schema
+// for its input, and either a shell command template (run through the exec
+// Runner, sandboxed like the exec tool) or an HTTP request template.
+package script
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output types
+const (
+	OutputText  = "text"  // stdout / response body as text (default)
+	OutputImage = "image" // command prints an image path; HTTP returns image bytes
+)
+
+// Definition is a tool declared in a workspace YAML file.
+type Definition struct {
+	Name        string         `yaml:"name"`
+	Description string         `yaml:"description"`
+	Schema      map[string]any `yaml:"schema"`  // JSON schema for the input (type: object)
+	Command     string         `yaml:"command"` // shell command template
+	HTTP        *HTTPSpec      `yaml:"http"`    // HTTP request template
+	Output      string         `yaml:"output"`  // "text" (default) or "image"
+	Roles       []string       `yaml:"roles"`   // roles allowed to use the tool (default: owner)
+	Timeout     int            `yaml:"timeout"` // seconds (0 = config default)
+
+	File string `yaml:"-"` // source file
+}
+
+// HTTPSpec is an HTTP request template.
+type HTTPSpec struct {
+	Method  string            `yaml:"method"` // default GET
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+}
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ParseDefinition parses and validates a tool definition.
+func ParseDefinition(data []byte, file string) (*Definition, error) {
+	var def Definition
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	def.File = file
+	if err := def.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return &def, nil
+}
+
+// Validate checks the definition and applies defaults.
+func (d *Definition) Validate() error {
+	if !namePattern.MatchString(d.Name) {
+		return fmt.Errorf("invalid name %q (lowercase letters, digits and underscores)", d.Name)
+	}
+	if strings.TrimSpace(d.Description) == "" {
+		return fmt.Errorf("description is required")
+	}
+	if (d.Command == "") == (d.HTTP == nil) {
+		return fmt.Errorf("exactly one of command or http is required")
+	}
+	if d.HTTP != nil {
+		if d.HTTP.URL == "" {
+			return fmt.Errorf("http.url is required")
+		}
+		if d.HTTP.Method == "" {
+			d.HTTP.Method = "GET"
+		}
+		d.HTTP.Method = strings.ToUpper(d.HTTP.Method)
+	}
+
+	if d.Schema == nil {
+		d.Schema = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	if t, _ := d.Schema["type"].(string); t != "object" {
+		return fmt.Errorf("schema.type must be object")
+	}
+	if _, ok := d.Schema["properties"]; !ok {
+		d.Schema["properties"] = map[string]any{}
+	}
+
+	switch d.Output {
+	case "":
+		d.Output = OutputText
+	case OutputText, OutputImage:
+	default:
+		return fmt.Errorf("invalid output %q (text or image)", d.Output)
+	}
+	if d.Timeout < 0 {
+		return fmt.Errorf("timeout must be positive")
+	}
+
+	// Parse templates up front so errors surface at load time
+	for field, text := range d.templates() {
+		if _, err := parseTemplate(field, text); err != nil {
+			return err
+		}
+	}
+	return checkShellQuoting(d.Command)
+}
+
+// checkShellQuoting rejects placeholders inside shell quotes. Values are
+// rendered as single-quoted words; inside "..." or '...' the quote they
+// add ends the author's, leaving the value unquoted.
+func checkShellQuoting(command string) error {
+	var quote byte // 0, '\'' or '"'
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case strings.HasPrefix(command[i:], "{{"):
+			if quote != 0 {
+				return fmt.Errorf("command: placeholder inside %c quotes at offset %d (values are quoted already)", quote, i)
+			}
+			end := strings.Index(command[i:], "}}")
+			if end < 0 {
+				return nil // Reported by the parser
+			}
+			i += end + 1
+		case c == '\\' && quote != '\'':
+			i++
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case c == quote:
+			quote = 0
+		}
+	}
+	return nil
+}
+
+// templates returns all template strings in the definition, keyed by field.
+func (d *Definition) templates() map[string]string {
+	t := map[string]string{}
+	if d.Command != "" {
+		t["command"] = d.Command
+	}
+	if d.HTTP != nil {
+		t["http.url"] = d.HTTP.URL
+		if d.HTTP.Body != "" {
+			t["http.body"] = d.HTTP.Body
+		}
+		for k, v := range d.HTTP.Headers {
+			t["http.headers."+k] = v
+		}
+	}
+	return t
+}
+
+// AllowsRole reports whether a role may use the tool. Without a roles list
+// only the owner may.
+func (d *Definition) AllowsRole(role string) bool {
+	if len(d.Roles) == 0 {
+		return role == "owner"
+	}
+	for _, r := range d.Roles {
+		if r == role || r == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckRoles returns an error if the definition grants the tool to a role
+// other than owner that isn't in allowed. "*" in roles needs "*" in allowed.
+func (d *Definition) CheckRoles(allowed []string) error {
+	for _, r := range d.Roles {
+		if r == "owner" || slices.Contains(allowed, "*") || (r != "*" && slices.Contains(allowed, r)) {
+			continue
+		}
+		return fmt.Errorf("%s: role %q not permitted by tools.script.allowRoles", d.File, r)
+	}
+	return nil
+}
+
+// LoadDir loads all *.yaml / *.yml definitions in dir. Invalid files are
+// returned as errors alongside the valid definitions.
+func LoadDir(dir string) ([]*Definition, []error) {
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	var defs []*Definition
+	var errs []error
+	seen := map[string]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		def, err := ParseDefinition(data, filepath.Base(file))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if prev, dup := seen[def.Name]; dup {
+			errs = append(errs, fmt.Errorf("%s: tool %q already defined in %s", def.File, def.Name, prev))
+			continue
+		}
+		seen[def.Name] = def.File
+		defs = append(defs, def)
+	}
+	return defs, errs
+}
+
+// Template escaping contexts
+const (
+	escapeShell  = "shell"  // single-quoted shell word
+	escapeURL    = "url"    // percent-encoded (safe in path and query)
+	escapeHeader = "header" // raw, CR/LF stripped
+	escapeJSON   = "json"   // JSON literal
+)
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid template: %w", name, err)
+	}
+	return t, nil
+}
+
+// render executes a template with every schema property escaped for the
+// target context. {{.input}} is the whole input as JSON, escaped likewise.
+func (d *Definition) render(name, text string, input map[string]any, escape string) (string, error) {
+	t, err := parseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+
+	data := map[string]string{}
+	if props, ok := d.Schema["properties"].(map[string]any); ok {
+		for prop := range props {
+			data[prop] = escapeValue(nil, escape)
+		}
+	}
+	for k, v := range input {
+		data[k] = escapeValue(v, escape)
+	}
+	whole, _ := json.Marshal(input)
+	data["input"] = escapeString(string(whole), escape)
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// escapeValue formats a decoded JSON value for a template context.
+func escapeValue(v any, escape string) string {
+	if escape == escapeJSON {
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+	return escapeString(valueString(v), escape)
+}
+
+func escapeString(s, escape string) string {
+	switch escape {
+	case escapeShell:
+		return shellQuote(s)
+	case escapeURL:
+		return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+	case escapeHeader:
+		return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+	case escapeJSON:
+		data, _ := json.Marshal(s)
+		return string(data)
+	}
+	return s
+}
+
+// valueString renders a scalar as plain text; objects and arrays as JSON.
+func valueString(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	default:
+		data, _ := json.Marshal(x)
+		return string(data)
+	}
+}
+
+// shellQuote wraps s in single quotes for bash.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,135 @@
+package script
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDefinition(t *testing.T) {
+	def, err := ParseDefinition([]byte(`
+name: weather
+description: Current weather for a city
+schema:
+  type: object
+  properties:
+    city: {type: string}
+  required: [city]
+http:
+  url: https://wttr.in/{{.city}}?format=3
+`), "weather.yaml")
+	if err != nil {
+		t.Fatalf("ParseDefinition: %v", err)
+	}
+	if def.HTTP.Method != "GET" || def.Output != OutputText {
+		t.Errorf("defaults not applied: method=%q output=%q", def.HTTP.Method, def.Output)
+	}
+	if !def.AllowsRole("owner") || def.AllowsRole("user") {
+		t.Error("default roles should be owner only")
+	}
+
+	invalid := map[string]string{
+		"bad name":      "name: Bad-Name\ndescription: x\ncommand: echo",
+		"no body":       "name: x\ndescription: x",
+		"both":          "name: x\ndescription: x\ncommand: echo\nhttp: {url: http://x}",
+		"bad template":  "name: x\ndescription: x\ncommand: echo {{.a",
+		"bad output":    "name: x\ndescription: x\ncommand: echo\noutput: video",
+		"schema type":   "name: x\ndescription: x\ncommand: echo\nschema: {type: string}",
+		"no descripton": "name: x\ncommand: echo",
+		"double quoted": "name: x\ndescription: x\ncommand: echo \"hi {{.a}}\"",
+		"single quoted": "name: x\ndescription: x\ncommand: echo 'hi {{.a}}'",
+	}
+	for name, src := range invalid {
+		if _, err := ParseDefinition([]byte(src), name); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCheckShellQuoting(t *testing.T) {
+	ok := []string{
+		`echo {{.a}} "b" 'c'`,
+		`echo "a" {{.a}}`,
+		`echo \"{{.a}}`,
+		`echo "\"" {{.a}}`,
+		`echo 'a\' {{.a}}`,
+		`printf '%s' {{printf "%q" .a}}`,
+	}
+	for _, cmd := range ok {
+		if err := checkShellQuoting(cmd); err != nil {
+			t.Errorf("%s: %v", cmd, err)
+		}
+	}
+	bad := []string{
+		`echo "{{.a}}"`,
+		`echo '{{.a}}'`,
+		`echo "x $(cat {{.a}})"`,
+		`echo "a\"{{.a}}"`,
+	}
+	for _, cmd := range bad {
+		if err := checkShellQuoting(cmd); err == nil {
+			t.Errorf("%s: expected error", cmd)
+		}
+	}
+}
+
+func TestCheckRoles(t *testing.T) {
+	tests := []struct {
+		roles, allowed []string
+		ok             bool
+	}{
+		{nil, nil, true},
+		{[]string{"owner"}, nil, true},
+		{[]string{"user"}, nil, false},
+		{[]string{"*"}, nil, false},
+		{[]string{"owner", "user"}, []string{"user"}, true},
+		{[]string{"*"}, []string{"user"}, false},
+		{[]string{"guest"}, []string{"*"}, true},
+		{[]string{"*"}, []string{"*"}, true},
+	}
+	for _, tt := range tests {
+		def := &Definition{Roles: tt.roles}
+		if err := def.CheckRoles(tt.allowed); (err == nil) != tt.ok {
+			t.Errorf("roles %v allowed %v: err = %v", tt.roles, tt.allowed, err)
+		}
+	}
+}
+
+func TestRenderEscaping(t *testing.T) {
+	def := &Definition{
+		Name:        "t",
+		Description: "t",
+		Command:     "echo",
+		Schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"q":     map[string]any{"type": "string"},
+				"n":     map[string]any{"type": "integer"},
+				"unset": map[string]any{"type": "string"},
+			},
+		},
+	}
+	input := map[string]any{"q": "it's $(rm -rf /) a b", "n": float64(3)}
+
+	tests := []struct {
+		escape, tmpl, want string
+	}{
+		{escapeShell, "grep {{.q}} -n {{.n}} {{.unset}}", `grep 'it'\''s $(rm -rf /) a b' -n '3' ''`},
+		{escapeURL, "https://x/s?q={{.q}}", "https://x/s?q=it%27s%20%24%28rm%20-rf%20%2F%29%20a%20b"},
+		{escapeJSON, `{"q": {{.q}}, "n": {{.n}}, "u": {{.unset}}}`, `{"q": "it's $(rm -rf /) a b", "n": 3, "u": null}`},
+		{escapeHeader, "Bearer {{.q}}", "Bearer it's $(rm -rf /) a b"},
+	}
+	for _, tt := range tests {
+		got, err := def.render("t", tt.tmpl, input, tt.escape)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.escape, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s:\n got %s\nwant %s", tt.escape, got, tt.want)
+		}
+	}
+
+	got, _ := def.render("t", "{{.q}}", map[string]any{"q": "a\r\nX-Evil: 1"}, escapeHeader)
+	if strings.ContainsAny(got, "\r\n") {
+		t.Errorf("header not stripped: %q", got)
+	}
+}
@@ -0,0 +1,120 @@
+package script
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/media"
+	"github.com/roelfdiedericks/goclaw/internal/tools"
+	"github.com/roelfdiedericks/goclaw/internal/tools/exec"
+)
+
+// Manager loads script tools from a directory into the tool registry and
+// keeps them in sync as the files change.
+type Manager struct {
+	dir        string
+	allowRoles []string // roles besides owner definitions may grant
+	registry   *tools.Registry
+	runner     *exec.Runner
+	mediaStore *media.MediaStore
+	timeout    time.Duration
+
+	mu      sync.Mutex
+	loaded  map[string]*Tool // tools currently registered by this manager
+	watcher *Watcher
+}
+
+// NewManager creates a script tool manager. allowRoles lists the roles
+// besides owner that definitions may grant the tool to. timeout is the
+// default per-call timeout for definitions that don't set one.
+func NewManager(dir string, allowRoles []string, registry *tools.Registry, runner *exec.Runner, mediaStore *media.MediaStore, timeout time.Duration) *Manager {
+	return &Manager{
+		dir:        dir,
+		allowRoles: allowRoles,
+		registry:   registry,
+		runner:     runner,
+		mediaStore: mediaStore,
+		timeout:    timeout,
+		loaded:     make(map[string]*Tool),
+	}
+}
+
+// Load (re)reads the directory and updates the registry: new and changed
+// definitions are registered, removed ones unregistered. Names that clash
+// with built-in tools are skipped.
+func (m *Manager) Load() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	defs, errs := LoadDir(m.dir)
+	for _, err := range errs {
+		L_warn("script tools: invalid definition", "dir", m.dir, "error", err)
+	}
+
+	next := make(map[string]*Tool, len(defs))
+	for _, def := range defs {
+		if _, ours := m.loaded[def.Name]; !ours && m.registry.Has(def.Name) {
+			L_warn("script tools: name clashes with built-in tool, skipping", "tool", def.Name, "file", def.File)
+			continue
+		}
+		if err := def.CheckRoles(m.allowRoles); err != nil {
+			L_warn("script tools: definition grants a role not allowed, skipping", "tool", def.Name, "error", err)
+			continue
+		}
+		tool := NewTool(def, m.runner, m.mediaStore, m.timeout)
+		m.registry.Register(tool)
+		next[def.Name] = tool
+	}
+
+	for name := range m.loaded {
+		if _, ok := next[name]; !ok {
+			m.registry.Unregister(name)
+			L_info("script tools: removed", "tool", name)
+		}
+	}
+	m.loaded = next
+
+	L_info("script tools: loaded", "dir", m.dir, "count", len(next), "invalid", len(errs))
+}
+
+// Names returns the names of the loaded script tools, sorted.
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.loaded))
+	for name := range m.loaded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StartWatching reloads tools when files in the directory change.
+func (m *Manager) StartWatching(debounceMs int) error {
+	w, err := NewWatcher(m.dir, debounceMs, m.Load)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.watcher = w
+	m.mu.Unlock()
+	w.Start()
+	return nil
+}
+
+// Stop stops the file watcher, if running.
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	w := m.watcher
+	m.watcher = nil
+	m.mu.Unlock()
+
+	if w != nil {
+		if err := w.Stop(); err != nil {
+			L_debug("script tools: watcher stop error", "error", err)
+		}
+	}
+}
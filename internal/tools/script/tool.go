@@ -0,0 +1,290 @@
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/roelfdiedericks/goclaw/internal/browser"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/media"
+	"github.com/roelfdiedericks/goclaw/internal/tools/exec"
+	"github.com/roelfdiedericks/goclaw/internal/types"
+)
+
+// fallbackTimeout bounds calls when no timeout is configured.
+const fallbackTimeout = 60 * time.Second
+
+// maxOutputBytes bounds text returned to the agent.
+const maxOutputBytes = 100 * 1024
+
+// maxImageBytes bounds images fetched over HTTP.
+const maxImageBytes = 20 * 1024 * 1024
+
+// Tool is a tool backed by a workspace Definition.
+type Tool struct {
+	def        *Definition
+	runner     *exec.Runner
+	httpClient *http.Client
+	mediaStore *media.MediaStore
+	timeout    time.Duration
+}
+
+// NewTool creates a tool for a definition. defaultTimeout applies when the
+// definition has none.
+func NewTool(def *Definition, runner *exec.Runner, mediaStore *media.MediaStore, defaultTimeout time.Duration) *Tool {
+	timeout := defaultTimeout
+	if def.Timeout > 0 {
+		timeout = time.Duration(def.Timeout) * time.Second
+	}
+	if timeout <= 0 {
+		timeout = fallbackTimeout
+	}
+	return &Tool{
+		def:    def,
+		runner: runner,
+		httpClient: &http.Client{
+			Timeout: timeout,
+			// Redirects could lead anywhere; each target gets the same check
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 10 {
+					return fmt.Errorf("stopped after 10 redirects")
+				}
+				return browser.ValidateURLSafety(req.URL.String())
+			},
+		},
+		mediaStore: mediaStore,
+		timeout:    timeout,
+	}
+}
+
+func (t *Tool) Name() string {
+	return t.def.Name
+}
+
+func (t *Tool) Description() string {
+	return t.def.Description
+}
+
+func (t *Tool) Schema() map[string]any {
+	return t.def.Schema
+}
+
+// AllowedRoles implements tools.RoleRestricted.
+func (t *Tool) AllowedRoles() []string {
+	if len(t.def.Roles) == 0 {
+		return []string{"owner"}
+	}
+	return t.def.Roles
+}
+
+// Definition returns the tool's definition.
+func (t *Tool) Definition() *Definition {
+	return t.def
+}
+
+func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolResult, error) {
+	// Input has been validated against the schema by the registry
+	var params map[string]any
+	if err := json.Unmarshal(input, &params); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	if t.def.HTTP != nil {
+		return t.executeHTTP(execCtx, params)
+	}
+	return t.executeCommand(execCtx, params)
+}
+
+func (t *Tool) executeCommand(ctx context.Context, params map[string]any) (*types.ToolResult, error) {
+	if t.runner == nil {
+		return types.ErrorResult("exec runner not available"), nil
+	}
+	command, err := t.def.render("command", t.def.Command, params, escapeShell)
+	if err != nil {
+		return types.ErrorResult(err.Error()), nil
+	}
+
+	// Same sandbox policy as the exec tool
+	useSandbox := t.runner.Config().Bubblewrap.Enabled
+	if sessCtx := types.GetSessionContext(ctx); sessCtx != nil && sessCtx.User != nil && !sessCtx.User.Sandbox {
+		useSandbox = false
+	}
+	workDir := types.WorkspaceDirFromContext(ctx, t.runner.Config().WorkingDir)
+
+	L_info("script tool: running", "tool", t.def.Name, "workDir", workDir, "sandboxed", useSandbox)
+
+	result, err := t.runner.RunFull(ctx, command, workDir, useSandbox)
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		L_warn("script tool: non-zero exit", "tool", t.def.Name, "exitCode", result.ExitCode)
+		msg := fmt.Sprintf("Exit code: %d", result.ExitCode)
+		if out := strings.TrimSpace(string(result.Stderr) + "\n" + string(result.Stdout)); out != "" {
+			msg = truncate(out, maxOutputBytes) + "\n" + msg
+		}
+		return types.ErrorResult(msg), nil
+	}
+
+	if t.def.Output == OutputImage {
+		path := strings.TrimSpace(string(result.Stdout))
+		if path == "" {
+			return types.ErrorResult("command printed no image path"), nil
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workDir, path)
+		}
+		if !withinDir(path, workDir) {
+			return types.ErrorResult(fmt.Sprintf("image path outside workspace: %s", path)), nil
+		}
+		return t.imageFromFile(path)
+	}
+
+	output := string(result.Stdout)
+	if len(result.Stderr) > 0 {
+		output += "\nSTDERR:\n" + string(result.Stderr)
+	}
+	if strings.TrimSpace(output) == "" {
+		output = "Command completed successfully (no output)"
+	}
+	return types.ExternalTextResult(truncate(output, maxOutputBytes), t.def.Name), nil
+}
+
+func (t *Tool) executeHTTP(ctx context.Context, params map[string]any) (*types.ToolResult, error) {
+	spec := t.def.HTTP
+
+	url, err := t.def.render("http.url", spec.URL, params, escapeURL)
+	if err != nil {
+		return types.ErrorResult(err.Error()), nil
+	}
+	var body io.Reader
+	if spec.Body != "" {
+		rendered, err := t.def.render("http.body", spec.Body, params, escapeJSON)
+		if err != nil {
+			return types.ErrorResult(err.Error()), nil
+		}
+		body = strings.NewReader(rendered)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, spec.Method, url, body)
+	if err != nil {
+		return types.ErrorResult(fmt.Sprintf("invalid request: %v", err)), nil
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range spec.Headers {
+		rendered, err := t.def.render("http.headers."+name, value, params, escapeHeader)
+		if err != nil {
+			return types.ErrorResult(err.Error()), nil
+		}
+		req.Header.Set(name, rendered)
+	}
+
+	// Templates take model input, so the URL may point anywhere: keep requests
+	// off loopback, private, link-local and cloud metadata addresses
+	if err := browser.ValidateURLSafety(req.URL.String()); err != nil {
+		L_warn("script tool: request blocked", "tool", t.def.Name, "host", req.URL.Host, "error", err)
+		return types.ErrorResult(err.Error()), nil
+	}
+
+	L_info("script tool: request", "tool", t.def.Name, "method", spec.Method, "host", req.URL.Host)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return types.ErrorResult(fmt.Sprintf("request failed: %v", err)), nil
+	}
+	defer resp.Body.Close()
+
+	limit := int64(maxOutputBytes)
+	if t.def.Output == OutputImage {
+		limit = maxImageBytes
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return types.ErrorResult(fmt.Sprintf("reading response: %v", err)), nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		L_warn("script tool: http error", "tool", t.def.Name, "status", resp.StatusCode)
+		return types.ErrorResult(fmt.Sprintf("HTTP %d: %s", resp.StatusCode, truncate(string(data), 2000))), nil
+	}
+
+	if t.def.Output == OutputImage {
+		if int64(len(data)) > limit {
+			return types.ErrorResult("image exceeds size limit"), nil
+		}
+		return t.imageFromBytes(data)
+	}
+	return types.ExternalTextResult(truncate(string(data), maxOutputBytes), t.def.Name), nil
+}
+
+// imageFromFile copies a command-produced image into the media store.
+func (t *Tool) imageFromFile(path string) (*types.ToolResult, error) {
+	mime, err := mimetype.DetectFile(path)
+	if err != nil {
+		return types.ErrorResult(fmt.Sprintf("reading image: %v", err)), nil
+	}
+	if !strings.HasPrefix(mime.String(), "image/") {
+		return types.ErrorResult(fmt.Sprintf("not an image: %s (%s)", path, mime.String())), nil
+	}
+	if t.mediaStore == nil {
+		return types.ImageRefResult(path, mime.String(), imageCaption(path)), nil
+	}
+	absPath, relPath, err := t.mediaStore.SaveFile(path, "tools")
+	if err != nil {
+		return nil, fmt.Errorf("saving image: %w", err)
+	}
+	return types.ImageRefResult(absPath, mime.String(), imageCaption(relPath)), nil
+}
+
+// imageFromBytes saves an HTTP image response into the media store.
+func (t *Tool) imageFromBytes(data []byte) (*types.ToolResult, error) {
+	mime := mimetype.Detect(data)
+	if !strings.HasPrefix(mime.String(), "image/") {
+		return types.ErrorResult(fmt.Sprintf("response is not an image (%s)", mime.String())), nil
+	}
+	if t.mediaStore == nil {
+		return types.ErrorResult("media store not available"), nil
+	}
+	absPath, relPath, err := t.mediaStore.Save(data, "tools", mime.Extension())
+	if err != nil {
+		return nil, fmt.Errorf("saving image: %w", err)
+	}
+	return types.ImageRefResult(absPath, mime.String(), imageCaption(relPath)), nil
+}
+
+// withinDir reports whether path (after resolving symlinks) is inside dir.
+func withinDir(path, dir string) bool {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// imageCaption is the JSON caption used by image tools.
+func imageCaption(path string) string {
+	data, _ := json.Marshal(map[string]any{"images": []string{path}})
+	return string(data)
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "\n... (truncated)"
+}
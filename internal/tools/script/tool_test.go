@@ -0,0 +1,44 @@
+package script
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPBlocksPrivateAddresses(t *testing.T) {
+	hit := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer srv.Close()
+
+	def, err := ParseDefinition([]byte(`
+name: fetch
+description: Fetch a path
+schema:
+  type: object
+  properties:
+    path: {type: string}
+http:
+  url: `+srv.URL+`/{{.path}}
+`), "fetch.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := NewTool(def, nil, nil, time.Second)
+
+	result, err := tool.Execute(context.Background(), []byte(`{"path":"admin"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].Text, "loopback") {
+		t.Errorf("request to loopback not blocked: %+v", result)
+	}
+	if hit {
+		t.Error("request reached the server")
+	}
+}
@@ -0,0 +1,162 @@
+package script
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+)
+
+// Watcher monitors the script tools directory for changes.
+// If the directory doesn't exist yet, its parent is watched until it's created.
+type Watcher struct {
+	watcher      *fsnotify.Watcher
+	dir          string
+	debounceMs   int
+	onChange     func()
+	stopCh       chan struct{}
+	mu           sync.Mutex
+	pendingTimer *time.Timer
+}
+
+// NewWatcher creates a watcher for dir.
+func NewWatcher(dir string, debounceMs int, onChange func()) (*Watcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if debounceMs <= 0 {
+		debounceMs = 500 // Default 500ms debounce
+	}
+
+	w := &Watcher{
+		watcher:    fsWatcher,
+		dir:        filepath.Clean(dir),
+		debounceMs: debounceMs,
+		onChange:   onChange,
+		stopCh:     make(chan struct{}),
+	}
+
+	if err := fsWatcher.Add(w.dir); err != nil {
+		if !os.IsNotExist(err) {
+			fsWatcher.Close()
+			return nil, err
+		}
+		// Wait for the directory to appear
+		if err := fsWatcher.Add(filepath.Dir(w.dir)); err != nil {
+			fsWatcher.Close()
+			return nil, err
+		}
+		L_debug("script tools: directory missing, watching parent", "path", w.dir)
+	} else {
+		L_debug("watching script tools directory", "path", w.dir)
+	}
+
+	return w, nil
+}
+
+// Start begins watching for file changes.
+// This spawns a goroutine internally.
+func (w *Watcher) Start() {
+	go w.run()
+}
+
+// run is the main event loop.
+func (w *Watcher) run() {
+	for {
+		select {
+		case <-w.stopCh:
+			return
+
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(event)
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			L_warn("script tools watcher error", "error", err)
+		}
+	}
+}
+
+// handleEvent processes a file system event.
+func (w *Watcher) handleEvent(event fsnotify.Event) {
+	path := filepath.Clean(event.Name)
+
+	// The tools directory itself was created (or removed)
+	if path == w.dir {
+		if event.Op&fsnotify.Create != 0 {
+			if err := w.watcher.Add(w.dir); err == nil {
+				L_debug("watching script tools directory", "path", w.dir)
+			}
+		}
+		w.triggerReload()
+		return
+	}
+
+	if filepath.Dir(path) != w.dir {
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".yaml" && ext != ".yml" {
+		return
+	}
+
+	isRelevant := event.Op&fsnotify.Write != 0 ||
+		event.Op&fsnotify.Create != 0 ||
+		event.Op&fsnotify.Remove != 0 ||
+		event.Op&fsnotify.Rename != 0
+
+	if !isRelevant {
+		return
+	}
+
+	L_debug("script tool file changed",
+		"path", event.Name,
+		"op", event.Op.String())
+
+	w.triggerReload()
+}
+
+// triggerReload schedules a reload with debouncing.
+func (w *Watcher) triggerReload() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.pendingTimer != nil {
+		w.pendingTimer.Stop()
+	}
+
+	w.pendingTimer = time.AfterFunc(time.Duration(w.debounceMs)*time.Millisecond, func() {
+		w.mu.Lock()
+		w.pendingTimer = nil
+		w.mu.Unlock()
+
+		L_info("script tools changed, reloading")
+		if w.onChange != nil {
+			w.onChange()
+		}
+	})
+}
+
+// Stop stops watching for changes.
+func (w *Watcher) Stop() error {
+	close(w.stopCh)
+
+	w.mu.Lock()
+	if w.pendingTimer != nil {
+		w.pendingTimer.Stop()
+	}
+	w.mu.Unlock()
+
+	return w.watcher.Close()
+}
@@ -0,0 +1,283 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/metrics"
+	"github.com/roelfdiedericks/goclaw/internal/tokens"
+	"github.com/roelfdiedericks/goclaw/internal/types"
+)
+
+// SpillToolName is the tool that retrieves spilled results. Its own output
+// is bounded and never spilled.
+const SpillToolName = "tool_output"
+
+// maxSpillBytes bounds what is stored for a single result.
+const maxSpillBytes = 32 * 1024 * 1024
+
+// SpillConfig configures large-result spill-over.
+type SpillConfig struct {
+	Budget       int            // Token budget per result (default 8000)
+	Budgets      map[string]int // Per-tool budget overrides (negative = never spill)
+	PreviewBytes int            // Head + tail preview size (default 4000)
+}
+
+// SpillMeta describes a stored result.
+type SpillMeta struct {
+	Handle   string    `json:"handle"`
+	Tool     string    `json:"tool"`
+	User     string    `json:"user,omitempty"`
+	External bool      `json:"external,omitempty"`
+	Source   string    `json:"source,omitempty"`
+	Tokens   int       `json:"tokens"`
+	Bytes    int       `json:"bytes"`
+	Lines    int       `json:"lines"`
+	Created  time.Time `json:"created"`
+}
+
+// SpillStore keeps spilled tool results on disk as <handle>.txt with a
+// <handle>.json sidecar. Entries older than ttl are pruned.
+type SpillStore struct {
+	dir       string
+	ttl       time.Duration
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+var spillHandlePattern = regexp.MustCompile(`^[0-9a-f]{12}$`)
+
+// NewSpillStore creates a store in dir.
+func NewSpillStore(dir string, ttl time.Duration) (*SpillStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	return &SpillStore{dir: dir, ttl: ttl}, nil
+}
+
+// Save stores text and returns meta with the new handle filled in.
+func (s *SpillStore) Save(text string, meta SpillMeta) (SpillMeta, error) {
+	s.prune()
+
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return meta, err
+	}
+	meta.Handle = hex.EncodeToString(id)
+	meta.Created = time.Now()
+
+	if len(text) > maxSpillBytes {
+		text = text[:maxSpillBytes] + "\n... (truncated at storage limit)"
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, meta.Handle+".txt"), []byte(text), 0600); err != nil {
+		return meta, err
+	}
+	data, _ := json.Marshal(meta)
+	if err := os.WriteFile(filepath.Join(s.dir, meta.Handle+".json"), data, 0600); err != nil {
+		return meta, err
+	}
+	return meta, nil
+}
+
+// Load returns a stored result and its metadata.
+func (s *SpillStore) Load(handle string) (string, *SpillMeta, error) {
+	if !spillHandlePattern.MatchString(handle) {
+		return "", nil, fmt.Errorf("invalid handle %q", handle)
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, handle+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, fmt.Errorf("unknown or expired handle %q", handle)
+		}
+		return "", nil, err
+	}
+	var meta SpillMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return "", nil, fmt.Errorf("corrupt metadata for %q: %w", handle, err)
+	}
+	text, err := os.ReadFile(filepath.Join(s.dir, handle+".txt"))
+	if err != nil {
+		return "", nil, fmt.Errorf("unknown or expired handle %q", handle)
+	}
+	return string(text), &meta, nil
+}
+
+// prune removes expired entries, at most once per hour.
+func (s *SpillStore) prune() {
+	s.mu.Lock()
+	if time.Since(s.lastPrune) < time.Hour {
+		s.mu.Unlock()
+		return
+	}
+	s.lastPrune = time.Now()
+	s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-s.ttl)
+	removed := 0
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || e.IsDir() || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if os.Remove(filepath.Join(s.dir, e.Name())) == nil {
+			removed++
+		}
+	}
+	if removed > 0 {
+		L_debug("tools: pruned spilled results", "removed", removed)
+	}
+}
+
+// Spillover replaces tool results over their token budget with a head/tail
+// preview and a handle for the tool_output tool.
+type Spillover struct {
+	cfg       SpillConfig
+	store     *SpillStore
+	estimator *tokens.Estimator
+}
+
+// NewSpillover creates a spill-over policy backed by store.
+func NewSpillover(cfg SpillConfig, store *SpillStore) *Spillover {
+	if cfg.Budget <= 0 {
+		cfg.Budget = 8000
+	}
+	if cfg.PreviewBytes <= 0 {
+		cfg.PreviewBytes = 4000
+	}
+	return &Spillover{cfg: cfg, store: store, estimator: tokens.Get()}
+}
+
+// Store returns the backing store.
+func (s *Spillover) Store() *SpillStore {
+	return s.store
+}
+
+// budget returns the token budget for a tool; <= 0 means never spill.
+func (s *Spillover) budget(tool string) int {
+	if tool == SpillToolName {
+		return 0
+	}
+	if b, ok := s.cfg.Budgets[tool]; ok && b != 0 {
+		return b
+	}
+	return s.cfg.Budget
+}
+
+// Apply returns result, or a preview of it if it exceeds the tool's budget.
+// Results carrying media are left alone.
+func (s *Spillover) Apply(ctx context.Context, tool string, result *types.ToolResult) *types.ToolResult {
+	budget := s.budget(tool)
+	if result == nil || budget <= 0 || result.HasMedia() {
+		return result
+	}
+
+	text := result.GetText()
+	// A token is at least one byte, so short text can't be over budget
+	if len(text) <= budget {
+		return result
+	}
+	count := s.estimator.Count(text)
+	if count <= budget {
+		return result
+	}
+
+	meta := SpillMeta{
+		Tool:     tool,
+		External: result.ExternalContent,
+		Source:   result.ExternalSource,
+		Tokens:   count,
+		Bytes:    len(text),
+		Lines:    strings.Count(text, "\n") + 1,
+	}
+	if sc := types.GetSessionContext(ctx); sc != nil && sc.User != nil {
+		meta.User = sc.User.ID
+	}
+
+	meta, err := s.store.Save(text, meta)
+	if err != nil {
+		L_warn("tools: failed to spill large result, returning in full", "tool", tool, "tokens", count, "error", err)
+		return result
+	}
+
+	metrics.MetricInc("tool_spillover", tool)
+	L_info("tools: spilled large result", "tool", tool, "tokens", count, "budget", budget, "handle", meta.Handle)
+
+	return &types.ToolResult{
+		Content:         []types.ContentBlock{types.TextBlock(spillPreview(text, meta, s.cfg.PreviewBytes))},
+		IsError:         result.IsError,
+		ExternalContent: result.ExternalContent,
+		ExternalSource:  result.ExternalSource,
+	}
+}
+
+// spillPreview renders the head and tail of text with retrieval instructions.
+func spillPreview(text string, meta SpillMeta, previewBytes int) string {
+	headBytes := previewBytes * 2 / 3
+	tailBytes := previewBytes - headBytes
+
+	headEnd := cutBefore(text, headBytes)
+	tailStart := cutAfter(text, len(text)-tailBytes)
+	if tailStart < headEnd {
+		tailStart = headEnd
+	}
+	head, tail := text[:headEnd], text[tailStart:]
+	omitted := strings.Count(text, "\n") - strings.Count(head, "\n") - strings.Count(tail, "\n")
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[Output too large: ~%d tokens, %d lines, %d bytes. Full output stored as handle %q. "+
+		"Use the %s tool with this handle to page through, grep or jq it.]\n\n",
+		meta.Tokens, meta.Lines, meta.Bytes, meta.Handle, SpillToolName)
+	sb.WriteString(head)
+	fmt.Fprintf(&sb, "\n\n... [%d lines omitted] ...\n\n", omitted)
+	sb.WriteString(tail)
+	return sb.String()
+}
+
+// cutBefore returns an index <= n to end the head at: the last newline in the
+// second half of the window, else a rune boundary.
+func cutBefore(text string, n int) int {
+	if n >= len(text) {
+		return len(text)
+	}
+	if i := strings.LastIndexByte(text[:n], '\n'); i > n/2 {
+		return i
+	}
+	for n > 0 && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return n
+}
+
+// cutAfter returns an index >= n to start the tail at: just after the first
+// newline in the first half of the window, else a rune boundary.
+func cutAfter(text string, n int) int {
+	if n <= 0 {
+		return 0
+	}
+	window := len(text) - n
+	if i := strings.IndexByte(text[n:], '\n'); i >= 0 && i < window/2 {
+		return n + i + 1
+	}
+	for n < len(text) && !utf8.RuneStart(text[n]) {
+		n++
+	}
+	return n
+}
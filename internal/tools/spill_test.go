@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/roelfdiedericks/goclaw/internal/types"
+)
+
+func TestSpilloverApply(t *testing.T) {
+	store, err := NewSpillStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spill := NewSpillover(SpillConfig{Budget: 200, Budgets: map[string]int{"read": -1}, PreviewBytes: 300}, store)
+
+	var sb strings.Builder
+	for i := 1; i <= 500; i++ {
+		fmt.Fprintf(&sb, "line %d of the output\n", i)
+	}
+	big := sb.String()
+
+	// Under budget and disabled tools pass through
+	small := types.TextResult("short")
+	if got := spill.Apply(context.Background(), "exec", small); got != small {
+		t.Error("small result should pass through")
+	}
+	if got := spill.Apply(context.Background(), "read", types.TextResult(big)); got.GetText() != big {
+		t.Error("tool with negative budget should never spill")
+	}
+
+	got := spill.Apply(context.Background(), "exec", types.ExternalTextResult(big, "exec"))
+	preview := got.GetText()
+	if !got.ExternalContent || got.ExternalSource != "exec" {
+		t.Error("external flags not preserved")
+	}
+	if !strings.Contains(preview, "line 1 of") || !strings.Contains(preview, "line 500 of") {
+		t.Errorf("preview missing head or tail:\n%s", preview)
+	}
+	if strings.Contains(preview, "line 250 of") {
+		t.Error("preview contains middle of output")
+	}
+
+	start := strings.Index(preview, `handle "`) + len(`handle "`)
+	handle := preview[start : start+12]
+	text, meta, err := store.Load(handle)
+	if err != nil {
+		t.Fatalf("Load(%q): %v", handle, err)
+	}
+	if text != big || meta.Tool != "exec" || !meta.External || meta.Lines != 501 {
+		t.Errorf("stored result mismatch: tool=%q external=%v lines=%d", meta.Tool, meta.External, meta.Lines)
+	}
+
+	if _, _, err := store.Load("../../etc/pa"); err == nil {
+		t.Error("invalid handle accepted")
+	}
+}
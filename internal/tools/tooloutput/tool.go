@@ -0,0 +1,237 @@
+// Package tooloutput provides the tool_output tool, which retrieves tool
+// results that were too large for the context and were spilled to disk.
+package tooloutput
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/tools"
+	"github.com/roelfdiedericks/goclaw/internal/tools/jq"
+	"github.com/roelfdiedericks/goclaw/internal/types"
+)
+
+const (
+	defaultPageLines  = 200
+	defaultMaxMatches = 100
+	maxResponseBytes  = 24 * 1024 // keeps each response well under the spill budget
+	maxLineBytes      = 2000      // long lines are clipped in page and grep output
+)
+
+// Tool pages, greps or queries a spilled tool result
+type Tool struct {
+	store *tools.SpillStore
+}
+
+// NewTool creates a new tool_output tool
+func NewTool(store *tools.SpillStore) *Tool {
+	return &Tool{store: store}
+}
+
+func (t *Tool) Name() string {
+	return tools.SpillToolName
+}
+
+func (t *Tool) Description() string {
+	return "Retrieve a tool result that was too large to return in full. Takes the handle from the truncated result. " +
+		"Actions: 'page' returns lines from an offset, 'grep' returns lines matching a regex with context, 'jq' runs a jq query on JSON output."
+}
+
+func (t *Tool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"handle": map[string]any{
+				"type":        "string",
+				"description": "Handle from the truncated tool result",
+			},
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"page", "grep", "jq"},
+				"description": "page (default), grep or jq",
+			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"minimum":     1,
+				"description": "page: first line to return (1-indexed, default 1)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"minimum":     1,
+				"description": "page: number of lines (default 200); grep: max matches (default 100)",
+			},
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "grep: regular expression (Go syntax, use (?i) for case-insensitive)",
+			},
+			"context": map[string]any{
+				"type":        "integer",
+				"minimum":     0,
+				"description": "grep: lines of context around each match (default 0)",
+			},
+			"query": map[string]any{
+				"type":        "string",
+				"description": "jq: query expression, e.g. '.items[] | .name'",
+			},
+			"raw": map[string]any{
+				"type":        "boolean",
+				"description": "jq: output raw strings (like jq -r)",
+			},
+		},
+		"required": []string{"handle"},
+	}
+}
+
+type toolOutputInput struct {
+	Handle  string `json:"handle"`
+	Action  string `json:"action,omitempty"`
+	Offset  int    `json:"offset,omitempty"`
+	Limit   int    `json:"limit,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Context int    `json:"context,omitempty"`
+	Query   string `json:"query,omitempty"`
+	Raw     bool   `json:"raw,omitempty"`
+}
+
+func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolResult, error) {
+	var params toolOutputInput
+	if err := json.Unmarshal(input, &params); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+
+	text, meta, err := t.store.Load(params.Handle)
+	if err != nil {
+		return types.ErrorResult(err.Error()), nil
+	}
+
+	// Results are private to the user whose run produced them (owner sees all)
+	if sc := types.GetSessionContext(ctx); sc != nil && sc.User != nil && meta.User != "" {
+		if meta.User != sc.User.ID && !sc.User.IsOwner() {
+			return types.ErrorResult(fmt.Sprintf("unknown or expired handle %q", params.Handle)), nil
+		}
+	}
+
+	L_debug("tool_output: executing", "handle", params.Handle, "action", params.Action, "tool", meta.Tool)
+
+	var out string
+	switch params.Action {
+	case "", "page":
+		out = page(text, params.Offset, params.Limit)
+	case "grep":
+		out, err = grep(text, params.Pattern, params.Context, params.Limit)
+	case "jq":
+		if params.Query == "" {
+			return types.ErrorResult("query is required for action jq"), nil
+		}
+		out, err = jq.Query(params.Query, []byte(text), params.Raw, false)
+		out = clip(out, maxResponseBytes)
+	}
+	if err != nil {
+		return types.ErrorResult(err.Error()), nil
+	}
+
+	if meta.External {
+		return types.ExternalTextResult(out, meta.Source), nil
+	}
+	return types.TextResult(out), nil
+}
+
+// page returns limit lines starting at offset, with a position header.
+func page(text string, offset, limit int) string {
+	if offset <= 0 {
+		offset = 1
+	}
+	if limit <= 0 {
+		limit = defaultPageLines
+	}
+	lines := strings.Split(text, "\n")
+	total := len(lines)
+	if offset > total {
+		return fmt.Sprintf("[offset %d is past the end: output has %d lines]", offset, total)
+	}
+
+	var sb strings.Builder
+	end := offset - 1
+	for end < total && end-(offset-1) < limit {
+		line := clip(lines[end], maxLineBytes)
+		if sb.Len()+len(line) > maxResponseBytes && end > offset-1 {
+			break
+		}
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+		end++
+	}
+
+	header := fmt.Sprintf("[lines %d-%d of %d", offset, end, total)
+	if end < total {
+		header += fmt.Sprintf("; next offset %d", end+1)
+	}
+	return header + "]\n" + sb.String()
+}
+
+// grep returns matching lines prefixed with their line numbers. Context lines
+// use "-" instead of ":" after the number, like grep -n.
+func grep(text, pattern string, context, limit int) (string, error) {
+	if pattern == "" {
+		return "", fmt.Errorf("pattern is required for action grep")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+	if limit <= 0 {
+		limit = defaultMaxMatches
+	}
+
+	lines := strings.Split(text, "\n")
+	var sb strings.Builder
+	matches := 0
+	lastPrinted := -1
+	truncated := false
+
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
+		}
+		if matches == limit || sb.Len() > maxResponseBytes {
+			truncated = true
+			break
+		}
+		matches++
+
+		from := max(i-context, lastPrinted+1)
+		to := min(i+context, len(lines)-1)
+		if lastPrinted >= 0 && from > lastPrinted+1 {
+			sb.WriteString("--\n")
+		}
+		for j := from; j <= to; j++ {
+			sep := "-"
+			if j == i || re.MatchString(lines[j]) {
+				sep = ":"
+			}
+			fmt.Fprintf(&sb, "%d%s%s\n", j+1, sep, clip(lines[j], maxLineBytes))
+		}
+		lastPrinted = to
+	}
+
+	if matches == 0 {
+		return fmt.Sprintf("[no lines match %q in %d lines]", pattern, len(lines)), nil
+	}
+	header := fmt.Sprintf("[%d matches", matches)
+	if truncated {
+		header += " (limit reached, refine the pattern or raise limit)"
+	}
+	return header + "]\n" + sb.String(), nil
+}
+
+// clip shortens s to at most n bytes.
+func clip(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "") + "... (clipped)"
+}
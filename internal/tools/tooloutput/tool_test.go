@@ -0,0 +1,43 @@
+package tooloutput
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPage(t *testing.T) {
+	text := "a\nb\nc\nd\ne"
+
+	got := page(text, 2, 2)
+	if got != "[lines 2-3 of 5; next offset 4]\nb\nc\n" {
+		t.Errorf("page(2,2) = %q", got)
+	}
+	if got := page(text, 4, 10); got != "[lines 4-5 of 5]\nd\ne\n" {
+		t.Errorf("page(4,10) = %q", got)
+	}
+	if got := page(text, 9, 1); !strings.Contains(got, "past the end") {
+		t.Errorf("page(9,1) = %q", got)
+	}
+}
+
+func TestGrep(t *testing.T) {
+	text := "alpha\nbeta\ngamma\ndelta\nepsilon\nzeta"
+
+	got, err := grep(text, "ta$", 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[3 matches]\n1-alpha\n2:beta\n3-gamma\n4:delta\n5-epsilon\n6:zeta\n"
+	if got != want {
+		t.Errorf("grep context:\n got %q\nwant %q", got, want)
+	}
+
+	got, _ = grep(text, "a", 0, 2)
+	if !strings.Contains(got, "limit reached") {
+		t.Errorf("grep limit: %q", got)
+	}
+
+	if _, err := grep(text, "(", 0, 0); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/roelfdiedericks/goclaw/internal/audit"
 	"github.com/roelfdiedericks/goclaw/internal/auth"
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
 	"github.com/roelfdiedericks/goclaw/internal/types"
@@ -153,18 +154,21 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 	// Security: Cannot elevate to owner
 	if strings.ToLower(result.User.Role) == "owner" {
 		L_warn("user_auth: attempted elevation to owner blocked", "user", result.User.Username)
+		recordElevation(ctx, result, "elevation to owner blocked")
 		return types.TextResult(t.formatResult(false, "", "Cannot elevate to owner role.")), nil
 	}
 
 	// Security: Role must be in allowedRoles
 	if len(t.config.AllowedRoles) > 0 && !slices.Contains(t.config.AllowedRoles, result.User.Role) {
 		L_warn("user_auth: role not in allowedRoles", "role", result.User.Role, "allowed", t.config.AllowedRoles)
+		recordElevation(ctx, result, "role not in allowedRoles")
 		return types.TextResult(t.formatResult(false, "", fmt.Sprintf("Role '%s' is not permitted for elevation.", result.User.Role))), nil
 	}
 
 	// Security: Role must exist in roles config (unless empty = no roles defined = fail)
 	if _, ok := t.rolesConfig[result.User.Role]; !ok {
 		L_warn("user_auth: role not defined in config", "role", result.User.Role)
+		recordElevation(ctx, result, "role not defined in configuration")
 		return types.TextResult(t.formatResult(false, "", fmt.Sprintf("Role '%s' is not defined in configuration.", result.User.Role))), nil
 	}
 
@@ -177,6 +181,7 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 			"role", result.User.Role,
 			"id", result.User.ID,
 		)
+		recordElevation(ctx, result, "")
 	} else {
 		L_warn("user_auth: no session in context, elevation not applied")
 	}
@@ -184,6 +189,25 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 	return types.TextResult(t.formatSuccessResult(result)), nil
 }
 
+// recordElevation writes an elevation (or refused elevation) to the audit log.
+// Credentials are never included.
+func recordElevation(ctx context.Context, result *AuthResult, errMsg string) {
+	event := audit.Event{
+		Type: "auth.elevate",
+		Inputs: map[string]any{
+			"name":     result.User.Name,
+			"username": result.User.Username,
+			"role":     result.User.Role,
+			"id":       result.User.ID,
+		},
+		Error: errMsg,
+	}
+	if errMsg == "" {
+		event.Result = "session elevated to " + result.User.Role
+	}
+	audit.Record(ctx, event)
+}
+
 // runScript executes the auth script with credentials.
 func (t *Tool) runScript(ctx context.Context, credentials map[string]string) (*AuthResult, error) {
 	if t.config.Script == "" {
@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/roelfdiedericks/goclaw/internal/jsonschema"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/metrics"
+)
+
+// InputError is returned by Registry.Execute when tool input doesn't match
+// the tool's schema. The tool is not run. Its message is phrased for the
+// model, which receives it as the tool result.
+type InputError struct {
+	Tool string
+	Err  error // *jsonschema.Error for schema violations
+}
+
+func (e *InputError) Error() string {
+	return fmt.Sprintf("invalid input for tool %s: %v. Fix the arguments to match the tool's input schema and call it again.", e.Tool, e.Err)
+}
+
+func (e *InputError) Unwrap() error { return e.Err }
+
+// ValidateInput checks input against the tool's schema. Empty input is
+// treated as an empty object. Violations are counted per tool under the
+// "tool_schema_violations" metrics topic.
+func ValidateInput(tool Tool, input json.RawMessage) (json.RawMessage, error) {
+	if len(bytes.TrimSpace(input)) == 0 || bytes.Equal(bytes.TrimSpace(input), []byte("null")) {
+		input = json.RawMessage("{}")
+	}
+
+	schema := tool.Schema()
+	if schema == nil {
+		return input, nil
+	}
+
+	if err := jsonschema.ValidateJSON(schema, input); err != nil {
+		metrics.MetricInc("tool_schema_violations", tool.Name())
+		L_warn("tools: input failed schema validation", "tool", tool.Name(), "error", err)
+		return nil, &InputError{Tool: tool.Name(), Err: err}
+	}
+	return input, nil
+}
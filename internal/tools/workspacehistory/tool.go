@@ -0,0 +1,240 @@
+// Package workspacehistory provides the workspace_history tool, which lists,
+// diffs and restores file changes recorded by the history store.
+package workspacehistory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/roelfdiedericks/goclaw/internal/history"
+	. "github.com/roelfdiedericks/goclaw/internal/logging"
+	"github.com/roelfdiedericks/goclaw/internal/sandbox"
+	"github.com/roelfdiedericks/goclaw/internal/types"
+)
+
+const (
+	defaultListLimit = 20
+	maxListLimit     = 200
+	maxOutputBytes   = 24 * 1024
+)
+
+// Tool lists, shows, diffs and restores workspace file changes
+type Tool struct {
+	workingDir string
+}
+
+// NewTool creates a new workspace_history tool
+func NewTool(workingDir string) *Tool {
+	return &Tool{workingDir: workingDir}
+}
+
+func (t *Tool) Name() string {
+	return "workspace_history"
+}
+
+func (t *Tool) Description() string {
+	return "History of file changes made by write and edit in the workspace. " +
+		"Actions: 'list' shows recent changes (optionally for one path), 'diff' shows what a change did, " +
+		"'show' returns a file version, 'restore' writes a version back to disk (before = undo the change)."
+}
+
+func (t *Tool) Schema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"list", "diff", "show", "restore"},
+				"description": "list (default), diff, show or restore",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "list: only changes to this file (relative to workspace or absolute)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"minimum":     1,
+				"description": "list: number of changes (default 20)",
+			},
+			"id": map[string]any{
+				"type":        "integer",
+				"minimum":     1,
+				"description": "diff/show/restore: change ID from list",
+			},
+			"version": map[string]any{
+				"type":        "string",
+				"enum":        []string{"before", "after"},
+				"description": "show/restore: file content before (default) or after the change",
+			},
+		},
+	}
+}
+
+type historyInput struct {
+	Action  string `json:"action,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Limit   int    `json:"limit,omitempty"`
+	ID      int64  `json:"id,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolResult, error) {
+	var params historyInput
+	if err := json.Unmarshal(input, &params); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+
+	store := history.Get()
+	if store == nil {
+		return types.ErrorResult("workspace history is disabled"), nil
+	}
+
+	workingDir := types.WorkspaceDirFromContext(ctx, t.workingDir)
+	L_debug("workspace_history: executing", "action", params.Action, "id", params.ID, "path", params.Path)
+
+	if params.Action == "" || params.Action == "list" {
+		return t.list(store, workingDir, params), nil
+	}
+
+	if params.ID == 0 {
+		return types.ErrorResult(fmt.Sprintf("id is required for action %s", params.Action)), nil
+	}
+	entry, ok := store.Get(params.ID)
+	if !ok || !visible(ctx, entry, workingDir) {
+		return types.ErrorResult(fmt.Sprintf("no change with id %d in this workspace", params.ID)), nil
+	}
+	version := params.Version
+	if version == "" {
+		version = "before"
+	}
+
+	switch params.Action {
+	case "diff":
+		before, err := store.Object(entry.Before)
+		if err != nil {
+			return types.ErrorResult(err.Error()), nil
+		}
+		after, err := store.Object(entry.After)
+		if err != nil {
+			return types.ErrorResult(err.Error()), nil
+		}
+		name := relPath(entry.Path, workingDir)
+		diff := history.UnifiedDiff("a/"+name, "b/"+name, before, after)
+		return types.TextResult(clip(describe(entry, workingDir, store)+"\n"+diff, maxOutputBytes)), nil
+
+	case "show":
+		hash := entry.Before
+		if version == "after" {
+			hash = entry.After
+		}
+		data, err := store.Object(hash)
+		if err != nil {
+			return types.ErrorResult(err.Error()), nil
+		}
+		if data == nil {
+			return types.TextResult(fmt.Sprintf("%s did not exist %s change %d", relPath(entry.Path, workingDir), version, entry.ID)), nil
+		}
+		return types.TextResult(clip(string(data), maxOutputBytes)), nil
+
+	case "restore":
+		if sandboxed(ctx) {
+			if _, err := sandbox.GetManager().ValidateWritePath(entry.Path, workingDir); err != nil {
+				return types.ErrorResult(err.Error()), nil
+			}
+		}
+		recorded, err := store.Restore(entry.ID, version, history.EntryFromContext(ctx, t.Name(), entry.Path))
+		if err != nil {
+			return types.ErrorResult(fmt.Sprintf("restore failed: %s", err)), nil
+		}
+		msg := fmt.Sprintf("Restored %s to its content %s change %d", relPath(entry.Path, workingDir), version, entry.ID)
+		if recorded.ID != 0 {
+			msg += fmt.Sprintf(" (recorded as change %d)", recorded.ID)
+		} else {
+			msg += " (file already had that content)"
+		}
+		return types.TextResult(msg), nil
+
+	default:
+		return types.ErrorResult(fmt.Sprintf("unknown action %q", params.Action)), nil
+	}
+}
+
+func (t *Tool) list(store *history.Store, workingDir string, params historyInput) *types.ToolResult {
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	limit = min(limit, maxListLimit)
+
+	filter := history.Filter{Dir: workingDir, Limit: limit}
+	if params.Path != "" {
+		path := params.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workingDir, path)
+		}
+		filter.Path = filepath.Clean(path)
+	}
+
+	entries := store.List(filter)
+	if len(entries) == 0 {
+		return types.TextResult("No recorded changes")
+	}
+	var sb strings.Builder
+	for _, e := range entries {
+		sb.WriteString(describe(e, workingDir, store))
+	}
+	return types.TextResult(sb.String())
+}
+
+// describe formats one entry as a single line
+func describe(e history.Entry, workingDir string, store *history.Store) string {
+	kind := "modified"
+	switch {
+	case e.Before == "":
+		kind = "created"
+	case e.After == "":
+		kind = "deleted"
+	}
+	line := fmt.Sprintf("#%d %s %s %s by %s", e.ID, e.Time.Format("2006-01-02 15:04:05"), relPath(e.Path, workingDir), kind, e.Tool)
+	if e.Reverts != 0 {
+		line += fmt.Sprintf(" (undoes #%d)", e.Reverts)
+	}
+	if store.IsReverted(e.ID) {
+		line += " [undone]"
+	}
+	return line + "\n"
+}
+
+// visible reports whether the caller may see an entry: it must be in their
+// workspace, unless they are the owner
+func visible(ctx context.Context, e history.Entry, workingDir string) bool {
+	if rel, err := filepath.Rel(workingDir, e.Path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return true
+	}
+	sc := types.GetSessionContext(ctx)
+	return sc != nil && sc.User != nil && sc.User.IsOwner()
+}
+
+func sandboxed(ctx context.Context) bool {
+	if sc := types.GetSessionContext(ctx); sc != nil && sc.User != nil {
+		return sc.User.Sandbox
+	}
+	return true
+}
+
+func relPath(path, workingDir string) string {
+	if rel, err := filepath.Rel(workingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+func clip(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + fmt.Sprintf("\n[... clipped, %d more bytes]", len(s)-n)
+}
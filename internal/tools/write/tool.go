@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/roelfdiedericks/goclaw/internal/history"
 	. "github.com/roelfdiedericks/goclaw/internal/logging"
 	"github.com/roelfdiedericks/goclaw/internal/sandbox"
 	"github.com/roelfdiedericks/goclaw/internal/types"
@@ -67,6 +68,9 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 		return nil, fmt.Errorf("path is required")
 	}
 
+	// Named agents resolve paths against their own workspace
+	workingDir := types.WorkspaceDirFromContext(ctx, t.workingDir)
+
 	// Check if user has sandbox disabled
 	sandboxed := true
 	if sessCtx := types.GetSessionContext(ctx); sessCtx != nil && sessCtx.User != nil {
@@ -75,27 +79,39 @@ func (t *Tool) Execute(ctx context.Context, input json.RawMessage) (*types.ToolR
 
 	L_debug("write tool: writing file", "path", params.Path, "bytes", len(params.Content), "sandboxed", sandboxed)
 
+	var resolved string
 	var err error
 	if sandboxed {
-		// Validate path and write atomically (sandbox validation + atomic write)
-		err = sandbox.GetManager().WriteFileValidated(params.Path, t.workingDir, []byte(params.Content), 0600)
+		// Validate path for write (sandbox check + write-protection)
+		resolved, err = sandbox.GetManager().ValidateWritePath(params.Path, workingDir)
+		if err != nil {
+			L_error("write tool failed", "path", params.Path, "error", err)
+			return nil, err
+		}
 	} else {
 		// No sandbox: resolve relative paths from workingDir, allow any absolute path
-		resolved := params.Path
+		resolved = params.Path
 		if !filepath.IsAbs(resolved) {
-			resolved = filepath.Join(t.workingDir, resolved)
+			resolved = filepath.Join(workingDir, resolved)
 		}
 		// Create parent directories if needed
 		if err := os.MkdirAll(filepath.Dir(resolved), 0750); err != nil {
 			L_error("write tool: failed to create parent dirs", "path", params.Path, "error", err)
 			return nil, err
 		}
+	}
+
+	change := history.Begin(ctx, "write", resolved)
+	if sandboxed {
+		err = sandbox.GetManager().AtomicWriteFile(resolved, []byte(params.Content), 0600)
+	} else {
 		err = os.WriteFile(resolved, []byte(params.Content), 0600)
 	}
 	if err != nil {
 		L_error("write tool failed", "path", params.Path, "error", err)
 		return nil, err
 	}
+	change.Commit()
 
 	L_info("write tool: file written", "path", params.Path, "bytes", len(params.Content))
 	return types.TextResult(fmt.Sprintf("Successfully wrote %d bytes to %s", len(params.Content), params.Path)), nil
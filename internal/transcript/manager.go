@@ -90,6 +90,14 @@ func (m *Manager) TriggerIndex() {
 	m.indexer.TriggerSync()
 }
 
+// DeleteSession removes a session's chunks from the index
+func (m *Manager) DeleteSession(ctx context.Context, sessionKey string) error {
+	if _, err := m.db.ExecContext(ctx, `DELETE FROM transcript_chunks WHERE session_key = ?`, sessionKey); err != nil {
+		return fmt.Errorf("delete transcript chunks: %w", err)
+	}
+	return nil
+}
+
 // Stats returns indexing statistics
 func (m *Manager) Stats() TranscriptStats {
 	chunksIndexed, lastSync := m.indexer.Stats()
@@ -20,6 +20,12 @@ type SessionContext struct {
 	User            *user.User      // Current user (for permission checks in tools)
 	TranscriptScope string          // Transcript access scope: "all", "own", or "none"
 	Session         SessionElevator // Session for role elevation (user_auth tool)
+	AgentID         string          // Agent handling the run ("main" or a named agent)
+	WorkspaceDir    string          // Agent workspace (empty = tool's configured working dir)
+	SessionKey      string          // Session the run belongs to
+	RunID           string          // Agent run ID
+	ToolCallID      string          // Tool use ID of the current call
+	Purpose         string          // Why the run is happening (run purpose and trigger), for the audit log
 }
 
 // sessionContextKey is used to store SessionContext in context.Context
@@ -37,3 +43,12 @@ func GetSessionContext(ctx context.Context) *SessionContext {
 	}
 	return nil
 }
+
+// WorkspaceDirFromContext returns the agent workspace for the current run,
+// or fallback if the run uses the default workspace.
+func WorkspaceDirFromContext(ctx context.Context, fallback string) string {
+	if sc := GetSessionContext(ctx); sc != nil && sc.WorkspaceDir != "" {
+		return sc.WorkspaceDir
+	}
+	return fallback
+}
@@ -31,7 +31,7 @@ type InboundMessage struct {
 
 	// === Agent Targeting ===
 	RunAgent bool   // true = run agent, false = inject to context only
-	AgentID  string // "main" (default), future: "subagent:xyz"
+	AgentID  string // "main" (default) or a named agent from agents.list
 
 	// === Behavior Flags ===
 	SkipMirror     bool // Don't mirror response to other channels
@@ -1,11 +1,15 @@
 package types
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+)
 
 // SummarizationClient is the interface for LLM clients used in checkpoint/compaction.
 // Implemented by llm.AnthropicProvider, llm.OllamaProvider, and llm.OpenAIProvider.
 type SummarizationClient interface {
 	SimpleMessage(ctx context.Context, userMessage, systemPrompt string) (string, error)
+	StructuredMessage(ctx context.Context, userMessage, systemPrompt string, schema json.RawMessage) (json.RawMessage, error)
 	Model() string
 	IsAvailable() bool
 	ContextTokens() int // Model's context window size for input truncation
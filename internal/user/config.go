@@ -80,14 +80,21 @@ type UsersConfig map[string]*UserEntry
 // UserEntry represents a single user in users.json
 // The map key (username) is used for HTTP auth and non-owner session keys
 type UserEntry struct {
-	Name             string  `json:"name"`                         // Display name
-	Role             string  `json:"role"`                         // "owner" or "user"
-	TelegramID       string  `json:"telegram_id,omitempty"`        // Telegram user ID (numeric string)
-	WhatsAppID       string  `json:"whatsapp_id,omitempty"`        // WhatsApp JID (phone number, e.g. "27821234567")
-	HTTPPasswordHash string  `json:"http_password_hash,omitempty"` // Argon2id hash of HTTP password
-	Thinking         *bool   `json:"thinking,omitempty"`           // Default /thinking toggle state (nil = role default)
-	ThinkingLevel    *string `json:"thinking_level,omitempty"`     // Preferred thinking level: off/minimal/low/medium/high/xhigh
-	Sandbox          *bool   `json:"sandbox,omitempty"`            // Enable file sandboxing (nil = default true)
+	Name             string            `json:"name"`                         // Display name
+	Role             string            `json:"role"`                         // "owner" or "user"
+	TelegramID       string            `json:"telegram_id,omitempty"`        // Telegram user ID (numeric string)
+	WhatsAppID       string            `json:"whatsapp_id,omitempty"`        // WhatsApp JID (phone number, e.g. "27821234567")
+	MatrixID         string            `json:"matrix_id,omitempty"`          // Matrix user ID (e.g. "@alice:example.org")
+	DiscordID        string            `json:"discord_id,omitempty"`         // Discord user ID (snowflake, numeric string)
+	Email            string            `json:"email,omitempty"`              // Email address (sender address for the email channel)
+	Identities       map[string]string `json:"identities,omitempty"`         // Bridge channel name -> user ID on that bridge (e.g. "signal": "+27821234567")
+	HTTPPasswordHash string            `json:"http_password_hash,omitempty"` // Argon2id hash of HTTP password
+	Thinking         *bool             `json:"thinking,omitempty"`           // Default /thinking toggle state (nil = role default)
+	ThinkingLevel    *string           `json:"thinking_level,omitempty"`     // Preferred thinking level: off/minimal/low/medium/high/xhigh
+	Sandbox          *bool             `json:"sandbox,omitempty"`            // Enable file sandboxing (nil = default true)
+
+	APITokens    []*APIToken `json:"api_tokens,omitempty"`    // Scoped HTTP API tokens (hashes only)
+	CertSubjects []string    `json:"cert_subjects,omitempty"` // TLS client certificate subjects ("CN=alice" or a full DN)
 }
 
 // applyDefaults sets defaults for nil Thinking and Sandbox fields.
@@ -176,7 +183,7 @@ func LoadUsers() (UsersConfig, error) {
 			return nil, fmt.Errorf("user %q has no role defined", username)
 		}
 		// Warn about users without credentials (but don't fail - allows CLI setup flow)
-		if entry.TelegramID == "" && entry.WhatsAppID == "" && entry.HTTPPasswordHash == "" {
+		if entry.TelegramID == "" && entry.WhatsAppID == "" && entry.MatrixID == "" && entry.DiscordID == "" && entry.Email == "" && len(entry.Identities) == 0 && entry.HTTPPasswordHash == "" && len(entry.CertSubjects) == 0 {
 			usersWithoutCredentials++
 		}
 		// Apply role-based defaults for thinking/sandbox
@@ -200,7 +207,13 @@ func SaveUsers(users UsersConfig, path string) error {
 		return fmt.Errorf("failed to marshal users: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0600); err != nil {
+	// Write atomically via temp file, so readers never see a partial file
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write users.json: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
 		return fmt.Errorf("failed to write users.json: %w", err)
 	}
 
@@ -1,34 +1,62 @@
 package user
 
 import (
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/roelfdiedericks/goclaw/internal/logging"
 )
 
 // Registry maintains the set of known users and provides lookup by identity
 type Registry struct {
-	users       map[string]*User  // by username (user ID)
-	telegramID  map[string]string // telegram user ID -> username
-	whatsappID  map[string]string // whatsapp JID -> username
-	ownerID     string            // cached owner username
-	rolesConfig RolesConfig       // role definitions from goclaw.json
+	users       map[string]*User             // by username (user ID)
+	telegramID  map[string]string            // telegram user ID -> username
+	whatsappID  map[string]string            // whatsapp JID -> username
+	matrixID    map[string]string            // matrix user ID -> username
+	discordID   map[string]string            // discord user ID -> username
+	email       map[string]string            // normalized email address -> username
+	identities  map[string]map[string]string // bridge name -> bridge user ID -> username
+	apiTokens   map[string]apiTokenRef       // API token ID -> owner and token
+	certSubject map[string]string            // TLS client certificate subject -> username
+	ownerID     string                       // cached owner username
+	rolesConfig RolesConfig                  // role definitions from goclaw.json
 	mu          sync.RWMutex
 }
 
+// apiTokenRef locates an API token in the registry
+type apiTokenRef struct {
+	username string
+	token    *APIToken
+}
+
 // NewRegistryFromUsers creates a user registry from UsersConfig
 // The rolesConfig is used to validate user roles and resolve permissions
 func NewRegistryFromUsers(users UsersConfig, rolesConfig RolesConfig) *Registry {
-	r := &Registry{
-		users:       make(map[string]*User),
-		telegramID:  make(map[string]string),
-		whatsappID:  make(map[string]string),
-		rolesConfig: rolesConfig,
-	}
+	r := &Registry{rolesConfig: rolesConfig}
+	r.Reload(users)
+	return r
+}
+
+// Reload replaces the registry's users in place, so everyone holding the
+// registry sees edits to users.json without a restart.
+// Users whose role can't be resolved are skipped.
+func (r *Registry) Reload(users UsersConfig) {
+	byID := make(map[string]*User)
+	telegramID := make(map[string]string)
+	whatsappID := make(map[string]string)
+	matrixID := make(map[string]string)
+	discordID := make(map[string]string)
+	email := make(map[string]string)
+	identities := make(map[string]map[string]string)
+	apiTokens := make(map[string]apiTokenRef)
+	certSubject := make(map[string]string)
+	certConflicts := make(map[string]bool) // Subjects mapped to more than one user
+	ownerID := ""
 
 	for username, entry := range users {
 		// Validate that the user's role can be resolved
-		_, err := ResolveRole(entry.Role, rolesConfig)
+		_, err := ResolveRole(entry.Role, r.rolesConfig)
 		if err != nil {
 			logging.L_error("user: skipping user with unresolvable role",
 				"username", username,
@@ -49,13 +77,18 @@ func NewRegistryFromUsers(users UsersConfig, rolesConfig RolesConfig) *Registry
 			Role:             Role(entry.Role),
 			TelegramID:       entry.TelegramID,
 			WhatsAppID:       entry.WhatsAppID,
+			MatrixID:         entry.MatrixID,
+			DiscordID:        entry.DiscordID,
+			Email:            entry.Email,
+			Identities:       entry.Identities,
 			HTTPPasswordHash: entry.HTTPPasswordHash,
+			CertSubjects:     entry.CertSubjects,
 			Thinking:         entry.Thinking != nil && *entry.Thinking,
 			ThinkingLevel:    thinkingLevel,
 			Sandbox:          entry.Sandbox == nil || *entry.Sandbox, // default true if nil
 		}
 
-		r.users[username] = user
+		byID[username] = user
 
 		if !user.Sandbox {
 			logging.L_warn("users: user running with sandbox disabled",
@@ -64,19 +97,64 @@ func NewRegistryFromUsers(users UsersConfig, rolesConfig RolesConfig) *Registry
 
 		// Build identity lookup maps
 		if entry.TelegramID != "" {
-			r.telegramID[entry.TelegramID] = username
+			telegramID[entry.TelegramID] = username
 		}
 		if entry.WhatsAppID != "" {
-			r.whatsappID[entry.WhatsAppID] = username
+			whatsappID[entry.WhatsAppID] = username
+		}
+		if entry.MatrixID != "" {
+			matrixID[entry.MatrixID] = username
+		}
+		if entry.DiscordID != "" {
+			discordID[entry.DiscordID] = username
+		}
+		if entry.Email != "" {
+			email[NormalizeEmail(entry.Email)] = username
+		}
+		for provider, value := range entry.Identities {
+			if value == "" {
+				continue
+			}
+			if identities[provider] == nil {
+				identities[provider] = make(map[string]string)
+			}
+			identities[provider][value] = username
+		}
+		for _, t := range entry.APITokens {
+			apiTokens[t.ID] = apiTokenRef{username: username, token: t}
+		}
+		for _, subject := range entry.CertSubjects {
+			if other, ok := certSubject[subject]; ok && other != username {
+				logging.L_error("users: certificate subject mapped to more than one user, ignoring it for all of them",
+					"subject", subject, "users", other+","+username)
+				certConflicts[subject] = true
+			}
+			certSubject[subject] = username
 		}
 
 		// Track owner
 		if user.Role == RoleOwner {
-			r.ownerID = username
+			ownerID = username
 		}
 	}
 
-	return r
+	// Which user an ambiguous subject logs in as would depend on map order
+	for subject := range certConflicts {
+		delete(certSubject, subject)
+	}
+
+	r.mu.Lock()
+	r.users = byID
+	r.telegramID = telegramID
+	r.whatsappID = whatsappID
+	r.matrixID = matrixID
+	r.discordID = discordID
+	r.email = email
+	r.identities = identities
+	r.apiTokens = apiTokens
+	r.certSubject = certSubject
+	r.ownerID = ownerID
+	r.mu.Unlock()
 }
 
 // GetRolesConfig returns the roles configuration
@@ -93,7 +171,8 @@ func (r *Registry) ResolveUserRole(u *User) (*ResolvedRole, error) {
 }
 
 // FromIdentity looks up a user by their external identity
-// Supported providers: "telegram", "whatsapp"
+// Supported providers: "telegram", "whatsapp", "matrix", "discord", "email", "cert",
+// and any bridge channel name (looked up in the users' identities)
 // Returns nil if no user is found with that identity
 func (r *Registry) FromIdentity(provider, value string) *User {
 	r.mu.RLock()
@@ -108,6 +187,26 @@ func (r *Registry) FromIdentity(provider, value string) *User {
 		if username, ok := r.whatsappID[value]; ok {
 			return r.users[username]
 		}
+	case "matrix":
+		if username, ok := r.matrixID[value]; ok {
+			return r.users[username]
+		}
+	case "discord":
+		if username, ok := r.discordID[value]; ok {
+			return r.users[username]
+		}
+	case "email":
+		if username, ok := r.email[NormalizeEmail(value)]; ok {
+			return r.users[username]
+		}
+	case "cert":
+		if username, ok := r.certSubject[value]; ok {
+			return r.users[username]
+		}
+	default:
+		if username, ok := r.identities[provider][value]; ok {
+			return r.users[username]
+		}
 	}
 
 	return nil
@@ -123,6 +222,33 @@ func (r *Registry) FromWhatsAppID(whatsappID string) *User {
 	return r.FromIdentity("whatsapp", whatsappID)
 }
 
+// FromMatrixID looks up a user by their Matrix user ID
+func (r *Registry) FromMatrixID(matrixID string) *User {
+	return r.FromIdentity("matrix", matrixID)
+}
+
+// FromDiscordID looks up a user by their Discord user ID
+func (r *Registry) FromDiscordID(discordID string) *User {
+	return r.FromIdentity("discord", discordID)
+}
+
+// FromEmail looks up a user by their email address (case-insensitive)
+func (r *Registry) FromEmail(address string) *User {
+	return r.FromIdentity("email", address)
+}
+
+// FromCertSubject looks up a user by a verified TLS client certificate.
+// The full subject DN (e.g. "CN=alice,O=Home") is tried first, then "CN=<commonName>".
+func (r *Registry) FromCertSubject(subject, commonName string) *User {
+	if u := r.FromIdentity("cert", subject); u != nil {
+		return u
+	}
+	if commonName == "" {
+		return nil
+	}
+	return r.FromIdentity("cert", "CN="+commonName)
+}
+
 // Owner returns the owner user (first user with owner role)
 // Returns nil if no owner is configured
 func (r *Registry) Owner() *User {
@@ -135,6 +261,31 @@ func (r *Registry) Owner() *User {
 	return r.users[r.ownerID]
 }
 
+// FromAPIToken authenticates a plain-text API token and returns its user
+// and token. Unknown, mismatched and expired tokens are errors.
+func (r *Registry) FromAPIToken(token string) (*User, *APIToken, error) {
+	id := APITokenID(token)
+	if id == "" {
+		return nil, nil, fmt.Errorf("not an API token")
+	}
+
+	r.mu.RLock()
+	ref, ok := r.apiTokens[id]
+	var u *User
+	if ok {
+		u = r.users[ref.username]
+	}
+	r.mu.RUnlock()
+
+	if !ok || u == nil || !ref.token.Verify(token) {
+		return nil, nil, fmt.Errorf("invalid API token")
+	}
+	if ref.token.Expired(time.Now()) {
+		return nil, nil, fmt.Errorf("API token %q has expired", ref.token.Name)
+	}
+	return u, ref.token, nil
+}
+
 // Get returns a user by their ID
 // Returns nil if not found
 func (r *Registry) Get(id string) *User {
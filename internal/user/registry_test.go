@@ -0,0 +1,78 @@
+package user
+
+import "testing"
+
+func TestFromCertSubject(t *testing.T) {
+	reg := NewRegistryFromUsers(UsersConfig{
+		"owner": {Name: "Owner", Role: "owner", CertSubjects: []string{"CN=laptop,O=Home"}},
+		"alice": {Name: "Alice", Role: "user", CertSubjects: []string{"CN=alice", "CN=shared"}},
+		"bob":   {Name: "Bob", Role: "user", CertSubjects: []string{"CN=shared"}},
+	}, RolesConfig{"user": {Memory: "none", Transcripts: "own"}})
+
+	tests := []struct {
+		subject, cn, want string
+	}{
+		{"CN=laptop,O=Home", "laptop", "owner"},
+		{"CN=laptop", "laptop", ""}, // Full DN mapping doesn't match on CN alone
+		{"CN=alice,OU=Phones,O=Home", "alice", "alice"},
+		{"CN=mallory", "mallory", ""},
+		{"CN=shared", "shared", ""}, // Mapped to two users: ignored for both
+		{"O=Home", "", ""},
+	}
+	for _, tt := range tests {
+		got := reg.FromCertSubject(tt.subject, tt.cn)
+		gotID := ""
+		if got != nil {
+			gotID = got.ID
+		}
+		if gotID != tt.want {
+			t.Errorf("FromCertSubject(%q, %q) = %q, want %q", tt.subject, tt.cn, gotID, tt.want)
+		}
+	}
+
+	if u := reg.Get("alice"); !u.HasHTTPAuth() {
+		t.Error("certificate-only user should count as having HTTP auth")
+	}
+}
+
+func TestFromEmail(t *testing.T) {
+	reg := NewRegistryFromUsers(UsersConfig{
+		"alice": {Name: "Alice", Role: "owner", Email: "Alice@Example.org"},
+	}, nil)
+
+	for _, addr := range []string{"alice@example.org", "ALICE@example.org", " alice@example.org "} {
+		if u := reg.FromEmail(addr); u == nil || u.ID != "alice" {
+			t.Errorf("FromEmail(%q) = %v, want alice", addr, u)
+		}
+	}
+	if u := reg.FromEmail("bob@example.org"); u != nil {
+		t.Errorf("FromEmail(bob) = %v, want nil", u)
+	}
+	if u := reg.Get("alice"); !u.HasEmailAuth() || !u.HasIdentity("email", "alice@EXAMPLE.org") {
+		t.Error("alice should have email identity")
+	}
+}
+
+func TestFromBridgeIdentity(t *testing.T) {
+	reg := NewRegistryFromUsers(UsersConfig{
+		"alice": {Name: "Alice", Role: "owner", Identities: map[string]string{"signal": "+27821234567", "sms": ""}},
+		"bob":   {Name: "Bob", Role: "owner", TelegramID: "42"},
+	}, nil)
+
+	if u := reg.FromIdentity("signal", "+27821234567"); u == nil || u.ID != "alice" {
+		t.Errorf("FromIdentity(signal) = %v, want alice", u)
+	}
+	if u := reg.FromIdentity("sms", "+27821234567"); u != nil {
+		t.Errorf("FromIdentity(sms) = %v, want nil", u)
+	}
+	if u := reg.FromIdentity("sms", ""); u != nil {
+		t.Errorf("FromIdentity(sms, empty) = %v, want nil", u)
+	}
+	alice := reg.Get("alice")
+	if !alice.HasIdentity("signal", "+27821234567") || alice.HasIdentity("sms", "") || alice.Identity("signal") != "+27821234567" {
+		t.Error("alice should have only the signal identity")
+	}
+	if reg.Get("bob").Identity("signal") != "" {
+		t.Error("bob has no signal identity")
+	}
+}
@@ -0,0 +1,86 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// tokenUsageFileName is the state file holding API token last-used times,
+// next to users.json. Authenticating a request only writes this file, never
+// users.json.
+const tokenUsageFileName = "api-token-usage.json"
+
+// TokenUsage tracks when API tokens were last used. Times are kept in memory
+// and saved to a state file with an atomic temp+rename write.
+type TokenUsage struct {
+	path string
+	mu   sync.Mutex
+	used map[string]time.Time // token ID -> last use
+}
+
+// TokenUsagePath returns the state file path next to users.json
+func TokenUsagePath() string {
+	return filepath.Join(filepath.Dir(GetUsersFilePath()), tokenUsageFileName)
+}
+
+// LoadTokenUsage reads the state file at path. A missing or unreadable file
+// gives an empty set.
+func LoadTokenUsage(path string) *TokenUsage {
+	u := &TokenUsage{path: path, used: make(map[string]time.Time)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return u
+	}
+	json.Unmarshal(data, &u.used) //nolint:errcheck // a damaged state file only loses last-used times
+	return u
+}
+
+// Touch records a token's use and saves the state file
+func (u *TokenUsage) Touch(id string, at time.Time) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.used[id] = at
+	return u.save()
+}
+
+// Forget drops a revoked token
+func (u *TokenUsage) Forget(id string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, ok := u.used[id]; !ok {
+		return nil
+	}
+	delete(u.used, id)
+	return u.save()
+}
+
+// LastUsed returns when a token was last used, falling back to the time
+// stored in users.json by older versions. nil means never.
+func (u *TokenUsage) LastUsed(t *APIToken) *time.Time {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if at, ok := u.used[t.ID]; ok {
+		return &at
+	}
+	return t.LastUsedAt
+}
+
+func (u *TokenUsage) save() error {
+	data, err := json.MarshalIndent(u.used, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal token usage: %w", err)
+	}
+	tmpPath := u.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, u.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// API token scopes. A token can only reach the /api/* routes its scopes
+// cover; the user's role still applies on top (e.g. admin-config on a
+// non-owner's token grants nothing).
+const (
+	ScopeChat        = "chat"         // Send messages, events, WebSocket, session list
+	ScopeReadHistory = "read-history" // Session history and transcript search
+	ScopeSupervise   = "supervise"    // Supervision of live sessions
+	ScopeAdminConfig = "admin-config" // Settings, cron, Home Assistant, users, skills, workspace history
+	ScopeMetrics     = "metrics"      // Metrics API
+	ScopeWebhook     = "webhook"      // Fire webhook cron jobs without the per-job secret
+)
+
+// AllScopes lists the valid API token scopes
+var AllScopes = []string{ScopeChat, ScopeReadHistory, ScopeSupervise, ScopeAdminConfig, ScopeMetrics, ScopeWebhook}
+
+// apiTokenPrefix marks GoClaw API tokens: "gct_<id>_<secret>"
+const apiTokenPrefix = "gct_"
+
+// APIToken is a per-user token for HTTP API access, stored in users.json.
+// Only the SHA-256 hash of the token is kept; the token is shown once.
+type APIToken struct {
+	ID         string     `json:"id"`   // Public part of the token, used for lookup and revocation
+	Name       string     `json:"name"` // What the token is for
+	Hash       string     `json:"hash"` // Hex SHA-256 of the full token
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`   // nil = never
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"` // Written by older versions; see TokenUsage
+}
+
+// HasScope reports whether the token grants a scope
+func (t *APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Expired reports whether the token has expired at the given time
+func (t *APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// ParseScopes parses a comma-separated scope list ("chat,metrics", or "all")
+func ParseScopes(s string) ([]string, error) {
+	if strings.TrimSpace(s) == "all" {
+		return slices.Clone(AllScopes), nil
+	}
+	var scopes []string
+	for _, scope := range strings.Split(s, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+		if !slices.Contains(AllScopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q (valid: %s)", scope, strings.Join(AllScopes, ", "))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("at least one scope is required (valid: %s)", strings.Join(AllScopes, ", "))
+	}
+	return scopes, nil
+}
+
+// AddAPIToken creates a token for the user and returns it in plain text
+// (the only time it is available). ttl <= 0 means no expiry.
+func (e *UserEntry) AddAPIToken(name string, scopes []string, ttl time.Duration) (string, *APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("token name is required")
+	}
+	for _, t := range e.APITokens {
+		if t.Name == name {
+			return "", nil, fmt.Errorf("a token named %q already exists", name)
+		}
+	}
+	if len(scopes) == 0 {
+		return "", nil, fmt.Errorf("at least one scope is required")
+	}
+
+	idBytes := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	id := hex.EncodeToString(idBytes)
+	plain := apiTokenPrefix + id + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	token := &APIToken{
+		ID:        id,
+		Name:      name,
+		Hash:      hashAPIToken(plain),
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if ttl > 0 {
+		expires := token.CreatedAt.Add(ttl)
+		token.ExpiresAt = &expires
+	}
+	e.APITokens = append(e.APITokens, token)
+	return plain, token, nil
+}
+
+// RevokeAPIToken removes a token by ID or name
+func (e *UserEntry) RevokeAPIToken(idOrName string) (*APIToken, error) {
+	for i, t := range e.APITokens {
+		if t.ID == idOrName || t.Name == idOrName {
+			e.APITokens = slices.Delete(e.APITokens, i, i+1)
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("token %q not found", idOrName)
+}
+
+// APITokenID extracts the ID from a token, or "" if it isn't one
+func APITokenID(token string) string {
+	rest, ok := strings.CutPrefix(token, apiTokenPrefix)
+	if !ok {
+		return ""
+	}
+	id, _, ok := strings.Cut(rest, "_")
+	if !ok || len(id) != 16 {
+		return ""
+	}
+	return id
+}
+
+// Verify checks a plain-text token against the stored hash
+func (t *APIToken) Verify(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashAPIToken(token)), []byte(t.Hash)) == 1
+}
+
+// hashAPIToken hashes a token for storage. Tokens are 256-bit random
+// values, so a fast hash is enough (unlike passwords).
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
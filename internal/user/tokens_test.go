@@ -0,0 +1,121 @@
+package user
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPITokenLifecycle(t *testing.T) {
+	entry := &UserEntry{Name: "Owner", Role: "owner"}
+	plain, tok, err := entry.AddAPIToken("dashboard", []string{ScopeChat, ScopeMetrics}, 0)
+	if err != nil {
+		t.Fatalf("AddAPIToken: %v", err)
+	}
+	if !strings.HasPrefix(plain, "gct_"+tok.ID+"_") {
+		t.Errorf("token %q does not carry its ID %q", plain, tok.ID)
+	}
+	if strings.Contains(tok.Hash, plain) || tok.Hash == "" {
+		t.Errorf("stored hash looks wrong: %q", tok.Hash)
+	}
+	if _, _, err := entry.AddAPIToken("dashboard", []string{ScopeChat}, 0); err == nil {
+		t.Error("duplicate token name accepted")
+	}
+
+	reg := NewRegistryFromUsers(UsersConfig{"owner": entry}, nil)
+
+	u, got, err := reg.FromAPIToken(plain)
+	if err != nil {
+		t.Fatalf("FromAPIToken: %v", err)
+	}
+	if u.ID != "owner" || got.ID != tok.ID {
+		t.Errorf("got user %q token %q, want owner/%s", u.ID, got.ID, tok.ID)
+	}
+	if !got.HasScope(ScopeMetrics) || got.HasScope(ScopeAdminConfig) {
+		t.Errorf("unexpected scopes %v", got.Scopes)
+	}
+
+	// Right ID, wrong secret
+	forged := plain[:len(plain)-4] + "AAAA"
+	if _, _, err := reg.FromAPIToken(forged); err == nil {
+		t.Error("forged token accepted")
+	}
+	if _, _, err := reg.FromAPIToken("not-a-token"); err == nil {
+		t.Error("garbage accepted")
+	}
+
+	if _, err := entry.RevokeAPIToken("dashboard"); err != nil {
+		t.Fatalf("RevokeAPIToken: %v", err)
+	}
+	reg.Reload(UsersConfig{"owner": entry})
+	if _, _, err := reg.FromAPIToken(plain); err == nil {
+		t.Error("revoked token accepted")
+	}
+}
+
+func TestAPITokenExpiry(t *testing.T) {
+	entry := &UserEntry{Name: "Owner", Role: "owner"}
+	plain, tok, err := entry.AddAPIToken("short", []string{ScopeChat}, time.Hour)
+	if err != nil {
+		t.Fatalf("AddAPIToken: %v", err)
+	}
+	if tok.ExpiresAt == nil || tok.Expired(time.Now()) {
+		t.Fatalf("token should expire in an hour, got %v", tok.ExpiresAt)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	tok.ExpiresAt = &past
+	reg := NewRegistryFromUsers(UsersConfig{"owner": entry}, nil)
+	if _, _, err := reg.FromAPIToken(plain); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expired token: err = %v", err)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes("chat, metrics,chat")
+	if err != nil {
+		t.Fatalf("ParseScopes: %v", err)
+	}
+	if len(scopes) != 2 || scopes[0] != ScopeChat || scopes[1] != ScopeMetrics {
+		t.Errorf("got %v", scopes)
+	}
+	if all, _ := ParseScopes("all"); len(all) != len(AllScopes) {
+		t.Errorf("all = %v", all)
+	}
+	for _, bad := range []string{"", "chat,root"} {
+		if _, err := ParseScopes(bad); err == nil {
+			t.Errorf("ParseScopes(%q) accepted", bad)
+		}
+	}
+}
+
+func TestTokenUsage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), tokenUsageFileName)
+	usage := LoadTokenUsage(path)
+
+	legacy := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tok := &APIToken{ID: "0123456789abcdef", LastUsedAt: &legacy}
+	if got := usage.LastUsed(tok); got == nil || !got.Equal(legacy) {
+		t.Fatalf("LastUsed before touch = %v, want legacy %v", got, legacy)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := usage.Touch(tok.ID, now); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	if got := LoadTokenUsage(path).LastUsed(tok); got == nil || !got.Equal(now) {
+		t.Fatalf("LastUsed after reload = %v, want %v", got, now)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temp file left behind: %v", err)
+	}
+
+	if err := usage.Forget(tok.ID); err != nil {
+		t.Fatalf("Forget: %v", err)
+	}
+	if got := LoadTokenUsage(path).LastUsed(tok); got == nil || !got.Equal(legacy) {
+		t.Errorf("LastUsed after forget = %v, want legacy %v", got, legacy)
+	}
+}
@@ -4,6 +4,7 @@ package user
 import (
 	"fmt"
 	"slices"
+	"strings"
 
 	"github.com/roelfdiedericks/goclaw/internal/logging"
 )
@@ -137,16 +138,21 @@ func (r *ResolvedRole) GetTranscriptScope() string {
 
 // User represents an authenticated user who can interact with the agent
 type User struct {
-	ID               string          // unique identifier (username from users.json key)
-	Name             string          // display name
-	Role             Role            // owner or user
-	TelegramID       string          // Telegram user ID (for telegram auth)
-	WhatsAppID       string          // WhatsApp JID (phone number, for whatsapp auth)
-	HTTPPasswordHash string          // Argon2id hash of HTTP password
-	Permissions      map[string]bool // tool whitelist (nil = use role defaults)
-	Thinking         bool            // default /thinking toggle state
-	ThinkingLevel    string          // preferred thinking level: off/minimal/low/medium/high/xhigh
-	Sandbox          bool            // enable file sandboxing
+	ID               string            // unique identifier (username from users.json key)
+	Name             string            // display name
+	Role             Role              // owner or user
+	TelegramID       string            // Telegram user ID (for telegram auth)
+	WhatsAppID       string            // WhatsApp JID (phone number, for whatsapp auth)
+	MatrixID         string            // Matrix user ID (for matrix auth)
+	DiscordID        string            // Discord user ID (for discord auth)
+	Email            string            // Email address (for email auth)
+	Identities       map[string]string // Bridge channel name -> user ID on that bridge
+	HTTPPasswordHash string            // Argon2id hash of HTTP password
+	CertSubjects     []string          // TLS client certificate subjects (for mTLS auth)
+	Permissions      map[string]bool   // tool whitelist (nil = use role defaults)
+	Thinking         bool              // default /thinking toggle state
+	ThinkingLevel    string            // preferred thinking level: off/minimal/low/medium/high/xhigh
+	Sandbox          bool              // enable file sandboxing
 }
 
 // VerifyHTTPPassword checks if the password matches the stored hash
@@ -158,8 +164,9 @@ func (u *User) VerifyHTTPPassword(password string) bool {
 }
 
 // HasHTTPAuth returns true if user has HTTP authentication configured
+// (a password or a client certificate subject)
 func (u *User) HasHTTPAuth() bool {
-	return u != nil && u.HTTPPasswordHash != ""
+	return u != nil && (u.HTTPPasswordHash != "" || len(u.CertSubjects) > 0)
 }
 
 // HasTelegramAuth returns true if user has Telegram authentication configured
@@ -172,6 +179,34 @@ func (u *User) HasWhatsAppAuth() bool {
 	return u != nil && u.WhatsAppID != ""
 }
 
+// HasMatrixAuth returns true if user has Matrix authentication configured
+func (u *User) HasMatrixAuth() bool {
+	return u != nil && u.MatrixID != ""
+}
+
+// HasDiscordAuth returns true if user has Discord authentication configured
+func (u *User) HasDiscordAuth() bool {
+	return u != nil && u.DiscordID != ""
+}
+
+// HasEmailAuth returns true if user has an email address configured
+func (u *User) HasEmailAuth() bool {
+	return u != nil && u.Email != ""
+}
+
+// Identity returns the user's ID on a bridge channel, or "" if none
+func (u *User) Identity(provider string) string {
+	if u == nil {
+		return ""
+	}
+	return u.Identities[provider]
+}
+
+// NormalizeEmail lowercases and trims an email address for comparison
+func NormalizeEmail(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
 // Default tool permissions by role
 var defaultPermissions = map[Role][]string{
 	RoleOwner: {"*"},                                             // everything
@@ -220,8 +255,16 @@ func (u *User) HasIdentity(provider, value string) bool {
 		return u.TelegramID == value
 	case "whatsapp":
 		return u.WhatsAppID == value
+	case "matrix":
+		return u.MatrixID == value
+	case "discord":
+		return u.DiscordID == value
+	case "email":
+		return u.Email != "" && NormalizeEmail(u.Email) == NormalizeEmail(value)
 	case "http":
 		return u.ID == value && u.HTTPPasswordHash != ""
+	case "cert":
+		return slices.Contains(u.CertSubjects, value)
 	}
-	return false
+	return value != "" && u.Identities[provider] == value
 }